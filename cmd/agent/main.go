@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,8 +14,11 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/admin"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/kube"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/lifecycle"
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/supervisor"
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+	"github.com/payram/payram-analytics-mcp-server/internal/httplimits"
 	"github.com/payram/payram-analytics-mcp-server/internal/logging"
 )
 
@@ -23,7 +29,11 @@ func main() {
 		addr = ":9900"
 	}
 
-	if seeded, version, err := update.EnsureSeedRelease(context.Background(), update.HomeDir()); err != nil {
+	// Containerized children start from PAYRAM_AGENT_<CHILD>_IMAGE (or, in a cluster, run
+	// whatever their Deployment names) until the first update, so there are no binaries to seed.
+	if supervisor.DockerMode() || kube.Enabled() {
+		log.Printf("running children with the %s driver", os.Getenv("PAYRAM_AGENT_CHILD_DRIVER"))
+	} else if seeded, version, err := update.EnsureSeedRelease(context.Background(), update.HomeDir()); err != nil {
 		log.Fatalf("failed to seed release: %v", err)
 	} else if seeded {
 		log.Printf("seeded initial release %s", version)
@@ -32,19 +42,57 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	sup, err := supervisor.NewFromEnv()
-	if err != nil {
-		log.Fatalf("failed to configure supervisor: %v", err)
+	// Children only accept log level changes carrying this per-run token.
+	if os.Getenv(logging.ControlTokenEnv) == "" {
+		_ = os.Setenv(logging.ControlTokenEnv, randomToken())
+	}
+
+	if webhook, ok := lifecycle.WebhookFromEnv(); ok {
+		go webhook.Run(ctx, lifecycle.Default)
+	}
+
+	var sup agentSupervisor
+	if kube.Enabled() {
+		ks, err := kube.NewFromEnv()
+		if err != nil {
+			log.Fatalf("failed to configure kubernetes mode: %v", err)
+		}
+		sup = ks
+	} else {
+		ps, err := supervisor.NewFromEnv()
+		if err != nil {
+			log.Fatalf("failed to configure supervisor: %v", err)
+		}
+		if err := ps.Start(ctx); err != nil {
+			log.Fatalf("failed to start supervisor: %v", err)
+		}
+		sup = ps
 	}
-	if err := sup.Start(ctx); err != nil {
-		log.Fatalf("failed to start supervisor: %v", err)
+
+	if hb, ok, err := admin.HeartbeatFromEnv(sup); err != nil {
+		log.Printf("fleet heartbeat disabled: %v", err)
+	} else if ok {
+		log.Printf("sending fleet heartbeats as agent %s", hb.AgentID())
+		go hb.Run(ctx)
+	}
+	if q, ok, err := admin.CommandQueueFromEnv(sup); err != nil {
+		log.Printf("fleet commands disabled: %v", err)
+	} else if ok {
+		log.Printf("polling fleet commands from %s", q.URL)
+		go q.Run(ctx)
 	}
 
+	limits, err := httplimits.FromEnv("PAYRAM_AGENT", httplimits.Default)
+	if err != nil {
+		log.Fatalf("server limits: %v", err)
+	}
 	handler := admin.NewMux(sup)
+	admin.ResumeCanary(sup)
 	srv := &http.Server{
 		Addr:    addr,
 		Handler: handler,
 	}
+	limits.Apply(srv)
 
 	logger, cleanup, err := logging.New("agent")
 	useLogger := err == nil
@@ -66,8 +114,29 @@ func main() {
 		}
 	}()
 
+	grpcSrv, grpcAddr, grpcOK, err := admin.GRPCServerFromEnv(sup)
+	if err != nil {
+		log.Fatalf("grpc admin: %v", err)
+	}
+	if grpcOK {
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatalf("grpc admin: %v", err)
+		}
+		log.Printf("grpc admin listening on %s", grpcAddr)
+		go func() {
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Printf("grpc admin: %v", err)
+				stop()
+			}
+		}()
+	}
+
 	<-ctx.Done()
 
+	if grpcOK {
+		grpcSrv.GracefulStop()
+	}
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -81,3 +150,16 @@ func main() {
 
 	sup.Wait()
 }
+
+// agentSupervisor is what the agent runs the children with: the process supervisor, or the
+// Kubernetes one when they run in a cluster.
+type agentSupervisor interface {
+	admin.Supervisor
+	Wait()
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,861 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/cliconfig"
+)
+
+// call runs one admin request. With --json it prints the response data and reports
+// printed; otherwise it decodes the data into out for the command to format.
+func (e *env) call(ctx context.Context, method, path string, query url.Values, body, out any) (printed bool, err error) {
+	if !e.jsonOut {
+		return false, e.client.do(ctx, method, path, query, body, out)
+	}
+	var raw json.RawMessage
+	if err := e.client.do(ctx, method, path, query, body, &raw); err != nil {
+		return false, err
+	}
+	if len(raw) == 0 {
+		raw = json.RawMessage("null")
+	}
+	return true, e.printJSON(raw)
+}
+
+func (e *env) table() *tabwriter.Writer {
+	return tabwriter.NewWriter(e.out, 0, 0, 2, ' ', 0)
+}
+
+type respError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type versionInfo struct {
+	Version string `json:"version"`
+}
+
+type historyEntry struct {
+	Time          time.Time `json:"time"`
+	Action        string    `json:"action"`
+	Result        string    `json:"result"`
+	FromVersion   string    `json:"from_version"`
+	ToVersion     string    `json:"to_version"`
+	Channel       string    `json:"channel"`
+	ForcedChannel bool      `json:"forced_channel"`
+	ErrorCode     string    `json:"error_code"`
+	Message       string    `json:"message"`
+}
+
+type overview struct {
+	Agent   versionInfo `json:"agent"`
+	Current struct {
+		Version     string    `json:"version"`
+		Channel     string    `json:"channel"`
+		InstalledAt time.Time `json:"installed_at"`
+	} `json:"current"`
+	Previous struct {
+		Version string `json:"version"`
+		Channel string `json:"channel"`
+	} `json:"previous"`
+	Available struct {
+		Checked       bool       `json:"checked"`
+		Channel       string     `json:"channel"`
+		Available     bool       `json:"available"`
+		TargetVersion string     `json:"target_version"`
+		Compatible    bool       `json:"compatible"`
+		Problems      []string   `json:"problems"`
+		Error         *respError `json:"error"`
+	} `json:"available"`
+	Update struct {
+		InProgress       bool   `json:"in_progress"`
+		LastErrorCode    string `json:"last_error_code"`
+		LastErrorMessage string `json:"last_error_message"`
+	} `json:"update"`
+	History  []historyEntry `json:"history"`
+	Children map[string]struct {
+		Info        *versionInfo `json:"info"`
+		Error       *respError   `json:"error"`
+		Ready       bool         `json:"ready"`
+		Drift       bool         `json:"drift"`
+		DriftReason string       `json:"drift_reason"`
+		PID         int          `json:"pid"`
+		StartTime   time.Time    `json:"start_time"`
+		Restarts    int          `json:"restarts"`
+		WaitingFor  []string     `json:"waiting_for"`
+	} `json:"children"`
+	Healthy bool `json:"healthy"`
+	Drift   bool `json:"drift"`
+}
+
+func runStatus(ctx context.Context, e *env, args []string) error {
+	if _, err := flags("status", args, 0, nil); err != nil {
+		return err
+	}
+	var o overview
+	if printed, err := e.call(ctx, http.MethodGet, "/admin/overview", url.Values{"history": {"5"}}, nil, &o); err != nil || printed {
+		return err
+	}
+
+	health := "healthy"
+	if !o.Healthy {
+		health = "UNHEALTHY"
+	}
+	if o.Drift {
+		health += ", version drift"
+	}
+	fmt.Fprintf(e.out, "Agent %s: %s\n\n", o.Agent.Version, health)
+
+	tw := e.table()
+	fmt.Fprintf(tw, "Current release\t%s\n", release(o.Current.Version, o.Current.Channel))
+	if !o.Current.InstalledAt.IsZero() {
+		fmt.Fprintf(tw, "Installed\t%s\n", o.Current.InstalledAt.Local().Format(time.DateTime))
+	}
+	fmt.Fprintf(tw, "Previous release\t%s\n", release(o.Previous.Version, o.Previous.Channel))
+	a := o.Available
+	switch {
+	case a.Error != nil:
+		fmt.Fprintf(tw, "Available\tcheck failed: %s\n", a.Error.Message)
+	case !a.Checked:
+		fmt.Fprintf(tw, "Available\tnot checked\n")
+	case a.Available && !a.Compatible:
+		fmt.Fprintf(tw, "Available\t%s on %s, incompatible: %s\n", a.TargetVersion, a.Channel, strings.Join(a.Problems, "; "))
+	case a.Available:
+		fmt.Fprintf(tw, "Available\t%s on %s\n", a.TargetVersion, a.Channel)
+	default:
+		fmt.Fprintf(tw, "Available\tup to date on %s\n", a.Channel)
+	}
+	if o.Update.InProgress {
+		fmt.Fprintf(tw, "Update\tin progress\n")
+	} else if o.Update.LastErrorCode != "" {
+		fmt.Fprintf(tw, "Last update error\t%s: %s\n", o.Update.LastErrorCode, o.Update.LastErrorMessage)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.out)
+	tw = e.table()
+	fmt.Fprintln(tw, "CHILD\tPID\tVERSION\tREADY\tRESTARTS\tUP SINCE\tNOTE")
+	names := make([]string, 0, len(o.Children))
+	for name := range o.Children {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		c := o.Children[name]
+		ver, note := "-", c.DriftReason
+		if c.Info != nil {
+			ver = c.Info.Version
+		}
+		if len(c.WaitingFor) > 0 {
+			note = "waiting for " + strings.Join(c.WaitingFor, ", ")
+		} else if c.Error != nil && note == "" {
+			note = c.Error.Message
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%d\t%s\t%s\n", name, c.PID, ver, yesNo(c.Ready), c.Restarts, since(c.StartTime), note)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(o.History) > 0 {
+		fmt.Fprintln(e.out)
+		return e.printHistory(o.History)
+	}
+	return nil
+}
+
+func runLogs(ctx context.Context, e *env, args []string) error {
+	var tail int
+	pos, err := flags("logs", args, 1, func(fs *flag.FlagSet) {
+		fs.IntVar(&tail, "tail", 200, "number of lines")
+	})
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return fmt.Errorf("%w: agentctl logs <chat|mcp> [--tail N]", errUsage)
+	}
+	var resp struct {
+		Lines []string `json:"lines"`
+	}
+	q := url.Values{"component": {pos[0]}, "tail": {strconv.Itoa(tail)}}
+	if printed, err := e.call(ctx, http.MethodGet, "/admin/logs", q, nil, &resp); err != nil || printed {
+		return err
+	}
+	for _, line := range resp.Lines {
+		fmt.Fprintln(e.out, line)
+	}
+	return nil
+}
+
+func runEvents(ctx context.Context, e *env, args []string) error {
+	var (
+		types string
+		after int64
+	)
+	if _, err := flags("events", args, 0, func(fs *flag.FlagSet) {
+		fs.StringVar(&types, "types", "", "comma-separated event type prefixes, such as update.,child.exited")
+		fs.Int64Var(&after, "after", 0, "first replay the kept events after this seq")
+	}); err != nil {
+		return err
+	}
+	q := query("types", types)
+	if after > 0 {
+		q.Set("after", strconv.FormatInt(after, 10))
+	}
+	return e.client.stream(ctx, "/admin/events", q, func(data []byte) error {
+		if e.jsonOut {
+			_, err := fmt.Fprintf(e.out, "%s\n", data)
+			return err
+		}
+		var ev struct {
+			Seq       int64          `json:"seq"`
+			Time      time.Time      `json:"time"`
+			Type      string         `json:"type"`
+			Component string         `json:"component"`
+			Data      map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return fmt.Errorf("decode event: %w", err)
+		}
+		fields := make([]string, 0, len(ev.Data))
+		for k, v := range ev.Data {
+			if v != nil && v != "" {
+				fields = append(fields, fmt.Sprintf("%s=%v", k, v))
+			}
+		}
+		slices.Sort(fields)
+		_, err := fmt.Fprintf(e.out, "%s  %-16s %-8s %s\n", ev.Time.Local().Format(time.DateTime), ev.Type,
+			ev.Component, strings.Join(fields, " "))
+		return err
+	})
+}
+
+func runRestart(ctx context.Context, e *env, args []string) error {
+	if _, err := flags("restart", args, 0, nil); err != nil {
+		return err
+	}
+	var resp struct {
+		Restarts []restartResult `json:"restarts"`
+	}
+	if printed, err := e.call(ctx, http.MethodPost, "/admin/child/restart", nil, nil, &resp); err != nil || printed {
+		return err
+	}
+	fmt.Fprintln(e.out, "Restarted chat API and MCP server.")
+	return e.printRestarts(resp.Restarts)
+}
+
+type restartResult struct {
+	Name      string `json:"name"`
+	PID       int    `json:"pid"`
+	Ready     bool   `json:"ready"`
+	ElapsedMS int64  `json:"elapsedMs"`
+	Error     string `json:"error"`
+}
+
+// printRestarts lists how each child came back from a restart, in restart order.
+func (e *env) printRestarts(results []restartResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	fmt.Fprintln(e.out)
+	tw := e.table()
+	fmt.Fprintln(tw, "CHILD\tPID\tREADY\tTOOK\tERROR")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", r.Name, r.PID, yesNo(r.Ready), millis(r.ElapsedMS), r.Error)
+	}
+	return tw.Flush()
+}
+
+func runUpdateCheck(ctx context.Context, e *env, args []string) error {
+	var channel string
+	if _, err := flags("update check", args, 0, func(fs *flag.FlagSet) {
+		fs.StringVar(&channel, "channel", e.cfg.Agent.Channel, "update channel (default the config file's, else the agent's)")
+	}); err != nil {
+		return err
+	}
+	var resp struct {
+		Available      bool   `json:"available"`
+		CurrentVersion string `json:"current_version"`
+		TargetVersion  string `json:"target_version"`
+		Notes          string `json:"notes"`
+		Revoked        bool   `json:"revoked"`
+		Compat         struct {
+			Compatible bool   `json:"compatible"`
+			Reason     string `json:"reason"`
+		} `json:"compat"`
+		Components struct {
+			Compatible bool     `json:"compatible"`
+			Problems   []string `json:"problems"`
+			Warnings   []string `json:"warnings"`
+		} `json:"components"`
+	}
+	if printed, err := e.call(ctx, http.MethodGet, "/admin/update/available", query("channel", channel), nil, &resp); err != nil || printed {
+		return err
+	}
+
+	switch {
+	case resp.Revoked:
+		fmt.Fprintf(e.out, "Release %s is revoked; staying on %s.\n", resp.TargetVersion, resp.CurrentVersion)
+	case resp.Available:
+		fmt.Fprintf(e.out, "Update available: %s -> %s\n", resp.CurrentVersion, resp.TargetVersion)
+	default:
+		fmt.Fprintf(e.out, "Up to date: %s\n", resp.CurrentVersion)
+	}
+	if !resp.Compat.Compatible {
+		fmt.Fprintf(e.out, "Incompatible with PayRam core: %s\n", resp.Compat.Reason)
+	} else if strings.HasPrefix(resp.Compat.Reason, "compatibility ignored") {
+		fmt.Fprintf(e.out, "Note: %s\n", resp.Compat.Reason)
+	}
+	for _, p := range resp.Components.Problems {
+		fmt.Fprintf(e.out, "Incompatible: %s\n", p)
+	}
+	for _, w := range resp.Components.Warnings {
+		fmt.Fprintf(e.out, "Warning: %s\n", w)
+	}
+	if resp.Available && resp.Notes != "" {
+		fmt.Fprintf(e.out, "\n%s\n", strings.TrimSpace(resp.Notes))
+	}
+	return nil
+}
+
+func runUpdateApply(ctx context.Context, e *env, args []string) error {
+	var (
+		channel      string
+		dryRun       bool
+		forceChannel bool
+		canary       string
+	)
+	if _, err := flags("update apply", args, 0, func(fs *flag.FlagSet) {
+		fs.StringVar(&channel, "channel", e.cfg.Agent.Channel, "update channel (default the config file's, else the agent's)")
+		fs.BoolVar(&dryRun, "dry-run", false, "verify and show the plan without installing")
+		fs.BoolVar(&forceChannel, "force-channel", false, "allow a channel other than the one the agent is pinned to")
+		fs.StringVar(&canary, "canary", "", "soak period such as 10m before the release counts as good; 0 skips the agent's default soak")
+	}); err != nil {
+		return err
+	}
+	q := query("channel", channel)
+	if canary != "" {
+		soak, err := time.ParseDuration(canary)
+		if err != nil || soak < 0 {
+			return fmt.Errorf("%w: agentctl update apply: --canary: invalid duration %q", errUsage, canary)
+		}
+		q.Set("canary_soak_ms", strconv.FormatInt(soak.Milliseconds(), 10))
+	}
+	if dryRun {
+		q.Set("dry_run", "1")
+	}
+	if forceChannel {
+		q.Set("force_channel", "1")
+	}
+	var resp struct {
+		UpdatedTo       string            `json:"updated_to"`
+		Restarts        []restartResult   `json:"restarts"`
+		Warnings        []string          `json:"warnings"`
+		DryRun          bool              `json:"dry_run"`
+		Channel         string            `json:"channel"`
+		CurrentVersion  string            `json:"current_version"`
+		TargetVersion   string            `json:"target_version"`
+		UpToDate        bool              `json:"up_to_date"`
+		ReleaseDir      string            `json:"release_dir"`
+		ReplacesRelease bool              `json:"replaces_release"`
+		Symlinks        map[string]string `json:"symlinks"`
+		Canary          *canaryState      `json:"canary"`
+	}
+	if printed, err := e.call(ctx, http.MethodPost, "/admin/update/apply", q, nil, &resp); err != nil || printed {
+		return err
+	}
+
+	if resp.DryRun {
+		fmt.Fprintf(e.out, "Dry run on %s: %s -> %s", resp.Channel, resp.CurrentVersion, resp.TargetVersion)
+		if resp.UpToDate {
+			fmt.Fprint(e.out, " (already installed)")
+		}
+		fmt.Fprintln(e.out)
+		tw := e.table()
+		fmt.Fprintf(tw, "Release dir\t%s\n", resp.ReleaseDir)
+		if resp.ReplacesRelease {
+			fmt.Fprintf(tw, "\t(replaces the existing directory)\n")
+		}
+		for _, name := range []string{"current", "previous"} {
+			if target, ok := resp.Symlinks[name]; ok {
+				fmt.Fprintf(tw, "%s ->\t%s\n", name, target)
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(e.out, "Updated to %s.\n", resp.UpdatedTo)
+	}
+	if c := resp.Canary; c != nil {
+		fmt.Fprintf(e.out, "Soaking until %s; rolls back to %s after more than %d failed health probes or %d crashes.\n",
+			c.Until.Local().Format(time.DateTime), c.PreviousVersion, c.MaxHealthFailures, c.MaxCrashes)
+	}
+	for _, w := range resp.Warnings {
+		fmt.Fprintf(e.out, "Warning: %s\n", w)
+	}
+	return e.printRestarts(resp.Restarts)
+}
+
+type canaryState struct {
+	Version           string    `json:"version"`
+	PreviousVersion   string    `json:"previous_version"`
+	Until             time.Time `json:"until"`
+	MaxHealthFailures int       `json:"max_health_failures"`
+	MaxCrashes        int       `json:"max_crashes"`
+}
+
+func runUpdateRollback(ctx context.Context, e *env, args []string) error {
+	if _, err := flags("update rollback", args, 0, nil); err != nil {
+		return err
+	}
+	var resp struct {
+		RolledBackTo string          `json:"rolled_back_to"`
+		Restarts     []restartResult `json:"restarts"`
+	}
+	if printed, err := e.call(ctx, http.MethodPost, "/admin/update/rollback", nil, nil, &resp); err != nil || printed {
+		return err
+	}
+	fmt.Fprintf(e.out, "Rolled back to %s.\n", resp.RolledBackTo)
+	return e.printRestarts(resp.Restarts)
+}
+
+func runUpdateStatus(ctx context.Context, e *env, args []string) error {
+	if _, err := flags("update status", args, 0, nil); err != nil {
+		return err
+	}
+	var st struct {
+		CurrentVersion     string    `json:"current_version"`
+		PreviousVersion    string    `json:"previous_version"`
+		CurrentChannel     string    `json:"current_channel"`
+		PreviousChannel    string    `json:"previous_channel"`
+		LastSuccessVersion string    `json:"last_success_version"`
+		LastSuccessAt      time.Time `json:"last_success_at"`
+		LastAttemptVersion string    `json:"last_attempt_version"`
+		LastAttemptAt      time.Time `json:"last_attempt_at"`
+		LastErrorCode      string    `json:"last_error_code"`
+		LastErrorMessage   string    `json:"last_error_message"`
+		LastErrorAt        time.Time `json:"last_error_at"`
+		InProgress         bool      `json:"in_progress"`
+		RecentTimings      []struct {
+			Action     string    `json:"action"`
+			Version    string    `json:"version"`
+			Result     string    `json:"result"`
+			StartedAt  time.Time `json:"started_at"`
+			TotalMS    int64     `json:"total_ms"`
+			Bottleneck string    `json:"bottleneck"`
+			Stages     []struct {
+				Stage      string `json:"stage"`
+				DurationMS int64  `json:"duration_ms"`
+			} `json:"stages"`
+		} `json:"recent_timings"`
+		Canary *canaryState `json:"canary"`
+	}
+	if printed, err := e.call(ctx, http.MethodGet, "/admin/update/status", nil, nil, &st); err != nil || printed {
+		return err
+	}
+	tw := e.table()
+	fmt.Fprintf(tw, "Current\t%s\n", release(st.CurrentVersion, st.CurrentChannel))
+	fmt.Fprintf(tw, "Previous\t%s\n", release(st.PreviousVersion, st.PreviousChannel))
+	fmt.Fprintf(tw, "Last success\t%s\n", event(st.LastSuccessVersion, st.LastSuccessAt))
+	fmt.Fprintf(tw, "Last attempt\t%s\n", event(st.LastAttemptVersion, st.LastAttemptAt))
+	if st.LastErrorCode != "" {
+		fmt.Fprintf(tw, "Last error\t%s: %s (%s)\n", st.LastErrorCode, st.LastErrorMessage, st.LastErrorAt.Local().Format(time.DateTime))
+	}
+	fmt.Fprintf(tw, "In progress\t%s\n", yesNo(st.InProgress))
+	if c := st.Canary; c != nil {
+		fmt.Fprintf(tw, "Canary\t%s soaking until %s\n", c.Version, c.Until.Local().Format(time.DateTime))
+	}
+	if err := tw.Flush(); err != nil || len(st.RecentTimings) == 0 {
+		return err
+	}
+
+	fmt.Fprintln(e.out)
+	tw = e.table()
+	fmt.Fprintln(tw, "STARTED\tACTION\tVERSION\tRESULT\tTOTAL\tBOTTLENECK\tSTAGES")
+	for _, t := range st.RecentTimings {
+		stages := make([]string, len(t.Stages))
+		for i, s := range t.Stages {
+			stages[i] = s.Stage + "=" + millis(s.DurationMS)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.StartedAt.Local().Format(time.DateTime), t.Action,
+			t.Version, t.Result, millis(t.TotalMS), t.Bottleneck, strings.Join(stages, " "))
+	}
+	return tw.Flush()
+}
+
+func millis(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+func runUpdateHistory(ctx context.Context, e *env, args []string) error {
+	var limit int
+	if _, err := flags("update history", args, 0, func(fs *flag.FlagSet) {
+		fs.IntVar(&limit, "limit", 20, "maximum entries")
+	}); err != nil {
+		return err
+	}
+	var resp struct {
+		Entries []historyEntry `json:"entries"`
+	}
+	if printed, err := e.call(ctx, http.MethodGet, "/admin/update/history", url.Values{"limit": {strconv.Itoa(limit)}}, nil, &resp); err != nil || printed {
+		return err
+	}
+	if len(resp.Entries) == 0 {
+		fmt.Fprintln(e.out, "No updates recorded.")
+		return nil
+	}
+	return e.printHistory(resp.Entries)
+}
+
+func (e *env) printHistory(entries []historyEntry) error {
+	tw := e.table()
+	fmt.Fprintln(tw, "TIME\tACTION\tRESULT\tFROM\tTO\tCHANNEL\tMESSAGE")
+	for _, h := range entries {
+		channel := h.Channel
+		if h.ForcedChannel {
+			channel += " (forced)"
+		}
+		msg := h.Message
+		if h.ErrorCode != "" {
+			msg = strings.TrimSpace(h.ErrorCode + ": " + msg)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", h.Time.Local().Format(time.DateTime), h.Action, h.Result,
+			dash(h.FromVersion), dash(h.ToVersion), dash(channel), msg)
+	}
+	return tw.Flush()
+}
+
+func runUpdateChannels(ctx context.Context, e *env, args []string) error {
+	if _, err := flags("update channels", args, 0, nil); err != nil {
+		return err
+	}
+	var resp struct {
+		CurrentChannel    string `json:"current_channel"`
+		ConfiguredChannel string `json:"configured_channel"`
+		Channels          []struct {
+			Name       string    `json:"name"`
+			Version    string    `json:"version"`
+			ReleasedAt time.Time `json:"released_at"`
+			Notes      string    `json:"notes"`
+			Revoked    bool      `json:"revoked"`
+			Error      string    `json:"error"`
+		} `json:"channels"`
+	}
+	if printed, err := e.call(ctx, http.MethodGet, "/admin/update/channels", nil, nil, &resp); err != nil || printed {
+		return err
+	}
+	tw := e.table()
+	fmt.Fprintln(tw, "CHANNEL	VERSION	RELEASED	NOTES")
+	for _, c := range resp.Channels {
+		name := c.Name
+		switch {
+		case c.Name == resp.CurrentChannel:
+			name += " (installed)"
+		case c.Name == resp.ConfiguredChannel:
+			name += " (default)"
+		}
+		if c.Error != "" {
+			fmt.Fprintf(tw, "%s\t-\t-\tunavailable: %s\n", name, c.Error)
+			continue
+		}
+		version, released := c.Version, "-"
+		if c.Revoked {
+			version += " (revoked)"
+		}
+		if !c.ReleasedAt.IsZero() {
+			released = c.ReleasedAt.Local().Format(time.DateOnly)
+		}
+		notes, _, _ := strings.Cut(strings.TrimSpace(c.Notes), "\n")
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, version, released, notes)
+	}
+	return tw.Flush()
+}
+
+func runUpdateVerify(ctx context.Context, e *env, args []string) error {
+	if _, err := flags("update verify", args, 0, nil); err != nil {
+		return err
+	}
+	var rep struct {
+		Intact     bool   `json:"intact"`
+		Version    string `json:"version"`
+		ReleaseDir string `json:"release_dir"`
+		Checks     []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+			Detail string `json:"detail"`
+		} `json:"checks"`
+	}
+	if printed, err := e.call(ctx, http.MethodGet, "/admin/update/verify", nil, nil, &rep); err != nil || printed {
+		return err
+	}
+	verdict := "intact"
+	if !rep.Intact {
+		verdict = "NOT INTACT"
+	}
+	fmt.Fprintf(e.out, "Release %s: %s\n", dash(rep.Version), verdict)
+	if rep.ReleaseDir != "" {
+		fmt.Fprintf(e.out, "Directory: %s\n", rep.ReleaseDir)
+	}
+	fmt.Fprintln(e.out)
+	tw := e.table()
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+	for _, c := range rep.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, c.Status, c.Detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if !rep.Intact {
+		return fmt.Errorf("release %s failed verification", dash(rep.Version))
+	}
+	return nil
+}
+
+func runLogLevel(ctx context.Context, e *env, args []string) error {
+	var component string
+	pos, err := flags("loglevel", args, 1, func(fs *flag.FlagSet) {
+		fs.StringVar(&component, "component", "all", "agent, chat-api, mcp, or all")
+	})
+	if err != nil {
+		return err
+	}
+	method, body := http.MethodGet, any(nil)
+	if len(pos) == 1 {
+		method, body = http.MethodPut, map[string]string{"component": component, "level": pos[0]}
+	}
+	var resp map[string]struct {
+		Levels map[string]string `json:"levels"`
+		Error  *respError        `json:"error"`
+	}
+	if printed, err := e.call(ctx, method, "/admin/loglevel", nil, body, &resp); err != nil || printed {
+		return err
+	}
+	tw := e.table()
+	fmt.Fprintln(tw, "PROCESS\tLOGGER\tLEVEL")
+	for _, proc := range []string{"agent", "chat-api", "mcp"} {
+		r, ok := resp[proc]
+		if !ok {
+			continue
+		}
+		if r.Error != nil {
+			fmt.Fprintf(tw, "%s\t-\tunreachable: %s\n", proc, r.Error.Message)
+			continue
+		}
+		names := make([]string, 0, len(r.Levels))
+		for name := range r.Levels {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		if len(names) == 0 {
+			fmt.Fprintf(tw, "%s\t-\t-\n", proc)
+		}
+		for _, name := range names {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", proc, dash(name), r.Levels[name])
+		}
+	}
+	return tw.Flush()
+}
+
+func runSecretsSet(ctx context.Context, e *env, args []string) error {
+	if _, err := flags("secrets set", args, 0, nil); err != nil {
+		return err
+	}
+	key, err := readSecret(e.stdin, e.errOut, "OpenAI API key")
+	if err != nil {
+		return err
+	}
+	if printed, err := e.call(ctx, http.MethodPut, "/admin/secrets/openai", nil, map[string]string{"openai_api_key": key}, nil); err != nil || printed {
+		return err
+	}
+	fmt.Fprintln(e.out, "Stored the OpenAI API key; restart to apply it: agentctl restart")
+	return nil
+}
+
+func runSecretsDelete(ctx context.Context, e *env, args []string) error {
+	if _, err := flags("secrets delete", args, 0, nil); err != nil {
+		return err
+	}
+	if printed, err := e.call(ctx, http.MethodDelete, "/admin/secrets/openai", nil, nil, nil); err != nil || printed {
+		return err
+	}
+	fmt.Fprintln(e.out, "Removed the stored OpenAI API key.")
+	return nil
+}
+
+func runSecretsStatus(ctx context.Context, e *env, args []string) error {
+	if _, err := flags("secrets status", args, 0, nil); err != nil {
+		return err
+	}
+	var resp struct {
+		Set    bool   `json:"openai_api_key_set"`
+		Source string `json:"source"`
+	}
+	if printed, err := e.call(ctx, http.MethodGet, "/admin/secrets/status", nil, nil, &resp); err != nil || printed {
+		return err
+	}
+	if resp.Set {
+		fmt.Fprintf(e.out, "OpenAI API key: set (from %s)\n", resp.Source)
+	} else {
+		fmt.Fprintln(e.out, "OpenAI API key: not set")
+	}
+	return nil
+}
+
+func runConfigSet(_ context.Context, e *env, args []string) error {
+	var (
+		baseURL    string
+		token      string
+		tokenStdin bool
+		channel    string
+	)
+	_, err := flags("config set", args, 0, func(fs *flag.FlagSet) {
+		fs.StringVar(&baseURL, "url", "", "agent base URL")
+		fs.StringVar(&token, "token", "", "admin token (prefer --token-stdin)")
+		fs.BoolVar(&tokenStdin, "token-stdin", false, "read the admin token from stdin")
+		fs.StringVar(&channel, "channel", "", "default update channel")
+	})
+	if err != nil {
+		return err
+	}
+	if baseURL == "" && token == "" && !tokenStdin && channel == "" {
+		return fmt.Errorf("%w: agentctl config set [--url U] [--token T | --token-stdin] [--channel C]", errUsage)
+	}
+	if tokenStdin {
+		if token, err = readSecret(e.stdin, e.errOut, "admin token"); err != nil {
+			return err
+		}
+	}
+	cfg := e.cfg
+	if baseURL != "" {
+		if u, err := url.Parse(baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%w: --url must be an absolute URL such as %s", errUsage, cliconfig.DefaultAgentURL)
+		}
+		cfg.Agent.URL = baseURL
+	}
+	if token != "" {
+		cfg.Agent.AdminToken = token
+	}
+	if channel != "" {
+		cfg.Agent.Channel = channel
+	}
+	if err := cliconfig.Save(e.configPath, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "Saved %s\n", e.configPath)
+	return nil
+}
+
+func runConfigShow(_ context.Context, e *env, args []string) error {
+	if _, err := flags("config show", args, 0, nil); err != nil {
+		return err
+	}
+	tokenSource := "none"
+	switch {
+	case e.client.token == "":
+	case e.client.token == e.cfg.Agent.AdminToken:
+		tokenSource = "config file"
+	default:
+		tokenSource = "flag or PAYRAM_AGENT_ADMIN_TOKEN"
+	}
+	if e.jsonOut {
+		return e.printJSON(map[string]string{"config": e.configPath, "url": e.client.baseURL, "token": tokenSource, "channel": e.cfg.Agent.Channel})
+	}
+	tw := e.table()
+	fmt.Fprintf(tw, "Config file\t%s\n", e.configPath)
+	fmt.Fprintf(tw, "URL\t%s\n", e.client.baseURL)
+	fmt.Fprintf(tw, "Token\t%s\n", tokenSource)
+	fmt.Fprintf(tw, "Channel\t%s\n", firstNonEmpty(e.cfg.Agent.Channel, "the agent's"))
+	return tw.Flush()
+}
+
+func runInit(ctx context.Context, e *env, args []string) error {
+	if _, err := flags("init", args, 0, nil); err != nil {
+		return err
+	}
+	cfg, err := cliconfig.RunWizard(ctx, e.stdin, e.out, e.cfg)
+	if err != nil {
+		return err
+	}
+	if err := cliconfig.Save(e.configPath, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out, "Saved %s\n", e.configPath)
+	return nil
+}
+
+func runCompletion(_ context.Context, e *env, args []string) error {
+	pos, err := flags("completion", args, 1, nil)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return fmt.Errorf("%w: agentctl completion <bash|zsh>", errUsage)
+	}
+	script, err := cliconfig.Completion(pos[0], "agentctl", e.globals, completionCommands())
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	_, err = fmt.Fprint(e.out, script)
+	return err
+}
+
+// query is a single-parameter query string, empty when value is.
+func query(key, value string) url.Values {
+	q := url.Values{}
+	if value != "" {
+		q.Set(key, value)
+	}
+	return q
+}
+
+func release(version, channel string) string {
+	if version == "" {
+		return "-"
+	}
+	if channel == "" {
+		return version
+	}
+	return version + " (" + channel + ")"
+}
+
+func event(version string, at time.Time) string {
+	if version == "" || at.IsZero() {
+		return dash(version)
+	}
+	return version + " at " + at.Local().Format(time.DateTime)
+}
+
+func since(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,258 @@
+// Command agentctl drives the agent's admin API from a shell: status, child logs and restarts,
+// update check/apply/rollback, log levels, and the OpenAI key. It sends the admin token the way
+// the API expects and prints tables, or the response data as JSON with --json.
+//
+// The agent URL and token come from --url/--token, else PAYRAM_AGENT_URL and
+// PAYRAM_AGENT_ADMIN_TOKEN, else the config file shared with payramctl, which "agentctl init"
+// and "agentctl config set" write.
+//
+//	agentctl init
+//	agentctl config set --url http://agent:9900 --token-stdin < token.txt
+//	agentctl status
+//	agentctl update apply --dry-run
+//	agentctl secrets set < openai-key.txt
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/cliconfig"
+)
+
+// errUsage marks errors caused by how the command was invoked; they exit with status 2.
+var errUsage = errors.New("usage")
+
+// env is what every command runs with.
+type env struct {
+	client     *client
+	cfg        cliconfig.Config
+	configPath string
+	jsonOut    bool
+	globals    *flag.FlagSet // for completion
+	stdin      io.Reader
+	out        io.Writer
+	errOut     io.Writer
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, e *env, args []string) error
+}
+
+var commands []command
+
+// The table is filled in init because "completion" reads it.
+func init() {
+	commands = []command{
+		{"status", "", "releases, available update, and child health", runStatus},
+		{"logs", "<chat|mcp> [--tail N]", "recent log lines of a child", runLogs},
+		{"events", "[--types T] [--after N]", "follow agent lifecycle events", runEvents},
+		{"restart", "", "restart the chat API and MCP server", runRestart},
+		{"update check", "[--channel C]", "check the channel for a newer release", runUpdateCheck},
+		{"update apply", "[--channel C] [--dry-run] [--force-channel] [--canary D]", "install the channel's release", runUpdateApply},
+		{"update channels", "", "list the hosted channels and their latest releases", runUpdateChannels},
+		{"update rollback", "", "switch back to the previous release", runUpdateRollback},
+		{"update status", "", "recorded update state", runUpdateStatus},
+		{"update history", "[--limit N]", "update audit log, newest first", runUpdateHistory},
+		{"update verify", "", "re-check the installed release against its manifest", runUpdateVerify},
+		{"loglevel", "[--component C] [LEVEL]", "show log levels, or set them", runLogLevel},
+		{"secrets set", "", "store the OpenAI API key, read from stdin", runSecretsSet},
+		{"secrets delete", "", "remove the stored OpenAI API key", runSecretsDelete},
+		{"secrets status", "", "whether an OpenAI API key is configured", runSecretsStatus},
+		{"config set", "[--url U] [--token T | --token-stdin] [--channel C]", "remember the agent URL, admin token and update channel", runConfigSet},
+		{"config show", "", "print the effective URL and where the token comes from", runConfigShow},
+		{"init", "", "set up the config file interactively and check the connection", runInit},
+		{"completion", "<bash|zsh>", "print a shell completion script", runCompletion},
+	}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Getenv, os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run parses the global flags, runs the command they name and returns the exit code: 0 on
+// success, 1 when the command fails and 2 for usage errors.
+func run(ctx context.Context, argv []string, getenv func(string) string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("agentctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		urlFlag   = fs.String("url", "", "agent base URL (default PAYRAM_AGENT_URL, the config file, or "+cliconfig.DefaultAgentURL+")")
+		tokenFlag = fs.String("token", "", "admin token (default PAYRAM_AGENT_ADMIN_TOKEN, or the config file)")
+		jsonOut   = fs.Bool("json", false, "print the response data as JSON")
+		timeout   = fs.Duration("timeout", 30*time.Second, "request timeout; update apply and rollback allow at least 5m")
+	)
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(argv); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		usage(fs)
+		return 2
+	}
+
+	cmd, args, ok := lookup(fs.Args())
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", strings.Join(fs.Args(), " "))
+		usage(fs)
+		return 2
+	}
+
+	path, err := cliconfig.Path()
+	if err != nil {
+		return fail(stderr, err)
+	}
+	cfg, err := cliconfig.Load(path)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	base := firstNonEmpty(*urlFlag, getenv("PAYRAM_AGENT_URL"), cfg.Agent.URL, cliconfig.DefaultAgentURL)
+	token := firstNonEmpty(*tokenFlag, getenv("PAYRAM_AGENT_ADMIN_TOKEN"), cfg.Agent.AdminToken)
+	if (cmd.name == "update apply" || cmd.name == "update rollback") && *timeout < 5*time.Minute {
+		// Applying downloads, switches releases and waits for both children to come back up.
+		*timeout = 5 * time.Minute
+	}
+
+	e := &env{
+		client:     newClient(base, token, *timeout),
+		cfg:        cfg,
+		configPath: path,
+		jsonOut:    *jsonOut,
+		globals:    fs,
+		stdin:      stdin,
+		out:        stdout,
+		errOut:     stderr,
+	}
+	if err := cmd.run(ctx, e, args); err != nil {
+		return fail(stderr, err)
+	}
+	return 0
+}
+
+// lookup finds the command named by the first one or two words of argv.
+func lookup(argv []string) (command, []string, bool) {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(argv) >= len(words) && strings.Join(argv[:len(words)], " ") == c.name {
+			return c, argv[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+func usage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintf(out, "usage: agentctl [flags] <command> [args]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-58s %s\n", strings.TrimSpace(c.name+" "+c.args), c.summary)
+	}
+	fmt.Fprintf(out, "\nflags:\n")
+	fs.PrintDefaults()
+}
+
+// fail reports err on stderr and returns its exit code: 2 for usage errors, else 1.
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintln(stderr, strings.TrimPrefix(err.Error(), errUsage.Error()+": "))
+	var apiErr *apiError
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.As(err, &apiErr) && apiErr.Code == "UNAUTHORIZED":
+		fmt.Fprintln(stderr, "set the admin token with --token, PAYRAM_AGENT_ADMIN_TOKEN, agentctl init, or agentctl config set --token-stdin")
+	}
+	return 1
+}
+
+// flags parses a command's own flags, which may come before or after its positional
+// arguments, and returns the positional ones, rejecting more than maxArgs.
+func flags(name string, args []string, maxArgs int, define func(fs *flag.FlagSet)) ([]string, error) {
+	fs := flag.NewFlagSet("agentctl "+name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if define != nil {
+		define(fs)
+	}
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: agentctl %s: %v", errUsage, name, err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(positional) > maxArgs {
+		return nil, fmt.Errorf("%w: agentctl %s: unexpected argument %q", errUsage, name, positional[maxArgs])
+	}
+	return positional, nil
+}
+
+// readSecret reads the first line of r, so secrets stay out of shell history and ps output.
+// A terminal gets a prompt on prompt.
+func readSecret(r io.Reader, prompt io.Writer, what string) (string, error) {
+	if f, ok := r.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprintf(prompt, "%s (input is shown): ", what)
+		}
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("read %s: %w", what, err)
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", fmt.Errorf("%w: no %s on stdin", errUsage, what)
+	}
+	return line, nil
+}
+
+func (e *env) printJSON(v any) error {
+	enc := json.NewEncoder(e.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+var (
+	argFlag   = regexp.MustCompile(`--[a-z][a-z-]*`)
+	argChoice = regexp.MustCompile(`<([a-z-]+(?:\|[a-z-]+)+)>`)
+)
+
+// completionCommands describes the command table for cliconfig.Completion, taking each
+// command's flags and argument choices from its usage string.
+func completionCommands() []cliconfig.Command {
+	out := make([]cliconfig.Command, 0, len(commands))
+	for _, c := range commands {
+		cc := cliconfig.Command{Name: c.name, Flags: argFlag.FindAllString(c.args, -1)}
+		if m := argChoice.FindStringSubmatch(c.args); m != nil {
+			cc.Args = strings.Split(m[1], "|")
+		}
+		out = append(out, cc)
+	}
+	return out
+}
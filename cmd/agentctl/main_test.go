@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/cliconfig"
+)
+
+// adminRequest is one request the fake admin API received.
+type adminRequest struct {
+	Method, Path, Query, Token string
+	Body                       map[string]any
+}
+
+// fakeAdmin is an agent admin API answering each path with the data in replies, inside the
+// {"ok", "data", "error"} envelope. Requests without the token "secret" are refused.
+type fakeAdmin struct {
+	*httptest.Server
+	replies map[string]any
+
+	mu       sync.Mutex
+	requests []adminRequest
+}
+
+func newFakeAdmin(t *testing.T, replies map[string]any) *fakeAdmin {
+	f := &fakeAdmin{replies: replies}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := adminRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Token: r.Header.Get(adminKeyHeader)}
+		_ = json.NewDecoder(r.Body).Decode(&req.Body)
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if req.Token != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"ok":false,"error":{"code":"UNAUTHORIZED","message":"admin token required"}}`))
+			return
+		}
+		data, ok := f.replies[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"ok":false,"error":{"code":"NOT_FOUND","message":"no such route"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "data": data})
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeAdmin) last() adminRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		return adminRequest{}
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+// agentctl runs the command line with env as the environment and stdin as standard input.
+// The config file is env[cliconfig.PathEnv], else a new one.
+func agentctl(t *testing.T, env map[string]string, stdin string, argv ...string) (int, string, string) {
+	t.Helper()
+	config := env[cliconfig.PathEnv]
+	if config == "" {
+		config = filepath.Join(t.TempDir(), "cli.json")
+	}
+	t.Setenv(cliconfig.PathEnv, config)
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), argv, func(k string) string { return env[k] }, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestLookup(t *testing.T) {
+	cases := []struct {
+		argv []string
+		name string
+		rest []string
+	}{
+		{[]string{"status"}, "status", []string{}},
+		{[]string{"update", "apply", "--dry-run"}, "update apply", []string{"--dry-run"}},
+		{[]string{"logs", "mcp", "--tail", "5"}, "logs", []string{"mcp", "--tail", "5"}},
+		{[]string{"update"}, "", nil},
+		{[]string{"update", "frobnicate"}, "", nil},
+		{[]string{"stat"}, "", nil},
+	}
+	for _, c := range cases {
+		cmd, rest, ok := lookup(c.argv)
+		if ok != (c.name != "") || cmd.name != c.name || strings.Join(rest, " ") != strings.Join(c.rest, " ") {
+			t.Errorf("lookup(%q) = %q %q %t, want %q %q", c.argv, cmd.name, rest, ok, c.name, c.rest)
+		}
+	}
+}
+
+func TestFlags(t *testing.T) {
+	var tail int
+	define := func(fs *flag.FlagSet) { fs.IntVar(&tail, "tail", 200, "") }
+	pos, err := flags("logs", []string{"--tail", "5", "mcp"}, 1, define)
+	if err != nil || strings.Join(pos, " ") != "mcp" || tail != 5 {
+		t.Fatalf("flags before: %q, tail %d, %v", pos, tail, err)
+	}
+	pos, err = flags("logs", []string{"chat", "--tail=7"}, 1, define)
+	if err != nil || strings.Join(pos, " ") != "chat" || tail != 7 {
+		t.Fatalf("flags after: %q, tail %d, %v", pos, tail, err)
+	}
+	for argv, want := range map[string]string{
+		"chat mcp":     `unexpected argument "mcp"`,
+		"--tail x":     `invalid value "x"`,
+		"--lines 3":    "flag provided but not defined: -lines",
+		"chat --tail":  "flag needs an argument",
+		"--help chat":  "help requested",
+		"mcp -- extra": `unexpected argument "extra"`,
+	} {
+		_, err := flags("logs", strings.Fields(argv), 1, define)
+		if err == nil || !errors.Is(err, errUsage) || !strings.Contains(err.Error(), want) {
+			t.Errorf("flags(%q): err = %v, want a usage error with %q", argv, err, want)
+		}
+	}
+}
+
+func TestCommandsCallTheAdminAPI(t *testing.T) {
+	f := newFakeAdmin(t, map[string]any{
+		"/admin/logs":           map[string]any{"lines": []string{"line one", "line two"}},
+		"/admin/loglevel":       map[string]any{"mcp": map[string]any{"levels": map[string]string{"": "debug", "tools": "info"}}, "chat-api": map[string]any{"error": map[string]string{"code": "UNREACHABLE", "message": "connection refused"}}},
+		"/admin/secrets/openai": nil,
+		"/admin/secrets/status": map[string]any{"openai_api_key_set": true, "source": "secrets file"},
+		"/admin/child/restart":  map[string]any{"restarts": []map[string]any{{"name": "mcp", "pid": 42, "ready": true, "elapsedMs": 1500}}},
+	})
+	env := map[string]string{"PAYRAM_AGENT_URL": f.URL, "PAYRAM_AGENT_ADMIN_TOKEN": "secret"}
+	cases := []struct {
+		name  string
+		argv  []string
+		stdin string
+		req   adminRequest
+		out   string
+	}{
+		{"logs", []string{"logs", "mcp", "--tail", "5"}, "", adminRequest{Method: "GET", Path: "/admin/logs", Query: "component=mcp&tail=5"}, "line one\nline two\n"},
+		{"loglevel show", []string{"loglevel"}, "", adminRequest{Method: "GET", Path: "/admin/loglevel"},
+			"PROCESS   LOGGER  LEVEL\nchat-api  -       unreachable: connection refused\nmcp       -       debug\nmcp       tools   info\n"},
+		{"loglevel set", []string{"loglevel", "--component", "mcp", "debug"}, "", adminRequest{Method: "PUT", Path: "/admin/loglevel", Body: map[string]any{"component": "mcp", "level": "debug"}}, ""},
+		{"secrets set", []string{"secrets", "set"}, "sk-new\n", adminRequest{Method: "PUT", Path: "/admin/secrets/openai", Body: map[string]any{"openai_api_key": "sk-new"}},
+			"Stored the OpenAI API key; restart to apply it: agentctl restart\n"},
+		{"secrets delete", []string{"secrets", "delete"}, "", adminRequest{Method: "DELETE", Path: "/admin/secrets/openai"}, "Removed the stored OpenAI API key.\n"},
+		{"secrets status", []string{"secrets", "status"}, "", adminRequest{Method: "GET", Path: "/admin/secrets/status"}, "OpenAI API key: set (from secrets file)\n"},
+		{"json", []string{"--json", "secrets", "status"}, "", adminRequest{Method: "GET", Path: "/admin/secrets/status"}, "{\n  \"openai_api_key_set\": true,\n  \"source\": \"secrets file\"\n}\n"},
+		{"restart", []string{"restart"}, "", adminRequest{Method: "POST", Path: "/admin/child/restart"},
+			"Restarted chat API and MCP server.\n\nCHILD  PID  READY  TOOK  ERROR\nmcp    42   yes    1.5s  \n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			code, out, stderr := agentctl(t, env, c.stdin, c.argv...)
+			if code != 0 {
+				t.Fatalf("exit %d: %s", code, stderr)
+			}
+			got := f.last()
+			body, _ := json.Marshal(got.Body)
+			want, _ := json.Marshal(c.req.Body)
+			if got.Method != c.req.Method || got.Path != c.req.Path || got.Query != c.req.Query || got.Token != "secret" || string(body) != string(want) {
+				t.Fatalf("sent %+v, want %+v", got, c.req)
+			}
+			if c.out != "" && out != c.out {
+				t.Fatalf("output:\n%q\nwant:\n%q", out, c.out)
+			}
+		})
+	}
+}
+
+func TestURLAndTokenPrecedence(t *testing.T) {
+	f := newFakeAdmin(t, map[string]any{"/admin/secrets/status": map[string]any{}})
+	config := filepath.Join(t.TempDir(), "cli.json")
+	if err := cliconfig.Save(config, cliconfig.Config{Agent: cliconfig.Agent{URL: f.URL, AdminToken: "from-config"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	agentctl(t, map[string]string{cliconfig.PathEnv: config}, "", "secrets", "status")
+	if got := f.last().Token; got != "from-config" {
+		t.Fatalf("token %q, want the config file's", got)
+	}
+	agentctl(t, map[string]string{cliconfig.PathEnv: config, "PAYRAM_AGENT_ADMIN_TOKEN": "from-env"}, "", "secrets", "status")
+	if got := f.last().Token; got != "from-env" {
+		t.Fatalf("token %q, want the environment's", got)
+	}
+	code, _, _ := agentctl(t, map[string]string{cliconfig.PathEnv: config, "PAYRAM_AGENT_ADMIN_TOKEN": "from-env", "PAYRAM_AGENT_URL": "http://127.0.0.1:1"},
+		"", "--url", f.URL, "--token", "secret", "secrets", "status")
+	if got := f.last().Token; code != 0 || got != "secret" {
+		t.Fatalf("exit %d with token %q, want the flags to win", code, got)
+	}
+
+	_, out, _ := agentctl(t, map[string]string{cliconfig.PathEnv: config}, "", "config", "show")
+	if !strings.Contains(out, "URL          "+f.URL) || !strings.Contains(out, "Token        config file") {
+		t.Fatalf("config show:\n%s", out)
+	}
+}
+
+func TestConfigSet(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "cli.json")
+	env := map[string]string{cliconfig.PathEnv: config}
+	code, out, stderr := agentctl(t, env, "tok-123\n", "config", "set", "--url", "http://agent:9900", "--token-stdin", "--channel", "beta")
+	if code != 0 || out != "Saved "+config+"\n" {
+		t.Fatalf("exit %d: %s%s", code, out, stderr)
+	}
+	cfg, err := cliconfig.Load(config)
+	if err != nil || cfg.Agent.URL != "http://agent:9900" || cfg.Agent.AdminToken != "tok-123" || cfg.Agent.Channel != "beta" {
+		t.Fatalf("saved %+v, %v", cfg.Agent, err)
+	}
+	if code, _, stderr := agentctl(t, env, "", "config", "set", "--url", "agent:9900"); code != 2 || !strings.Contains(stderr, "--url must be an absolute URL") {
+		t.Fatalf("relative URL: exit %d, %s", code, stderr)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	f := newFakeAdmin(t, map[string]any{})
+	env := map[string]string{"PAYRAM_AGENT_URL": f.URL, "PAYRAM_AGENT_ADMIN_TOKEN": "secret"}
+	cases := []struct {
+		name   string
+		env    map[string]string
+		argv   []string
+		code   int
+		stderr string
+	}{
+		{"no command", env, nil, 2, "usage: agentctl"},
+		{"unknown flag", env, []string{"--verbose", "status"}, 2, "flag provided but not defined"},
+		{"unknown command", env, []string{"update", "now"}, 2, `unknown command "update now"`},
+		{"missing argument", env, []string{"logs"}, 2, "agentctl logs <chat|mcp>"},
+		{"extra argument", env, []string{"status", "now"}, 2, `agentctl status: unexpected argument "now"`},
+		{"empty secret", env, []string{"secrets", "set"}, 2, "no OpenAI API key on stdin"},
+		{"bad completion shell", env, []string{"completion", "fish"}, 2, "fish"},
+		{"api error", env, []string{"secrets", "status"}, 1, "NOT_FOUND: no such route (HTTP 404)"},
+		{"unauthorized", map[string]string{"PAYRAM_AGENT_URL": f.URL}, []string{"secrets", "status"}, 1, "set the admin token with --token"},
+		{"unreachable", map[string]string{"PAYRAM_AGENT_URL": "http://127.0.0.1:1"}, []string{"secrets", "status"}, 1, "call agent"},
+	}
+	for _, c := range cases {
+		code, _, stderr := agentctl(t, c.env, "", c.argv...)
+		if code != c.code || !strings.Contains(stderr, c.stderr) {
+			t.Errorf("%s: exit %d, stderr %q; want %d with %q", c.name, code, stderr, c.code, c.stderr)
+		}
+	}
+}
+
+func TestCompletionCommands(t *testing.T) {
+	code, out, _ := agentctl(t, nil, "", "completion", "bash")
+	if code != 0 || !strings.Contains(out, "agentctl") || !strings.Contains(out, "--timeout") {
+		t.Fatalf("completion exit %d:\n%s", code, out)
+	}
+	for _, c := range completionCommands() {
+		if c.Name == "update apply" {
+			if strings.Join(c.Flags, " ") != "--channel --dry-run --force-channel --canary" {
+				t.Fatalf("update apply flags %q", c.Flags)
+			}
+		}
+		if c.Name == "logs" && strings.Join(c.Args, " ") != "chat mcp" {
+			t.Fatalf("logs args %q", c.Args)
+		}
+	}
+}
@@ -1,15 +1,27 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/payram/payram-analytics-mcp-server/internal/chatapi"
+	"github.com/payram/payram-analytics-mcp-server/internal/compress"
+	"github.com/payram/payram-analytics-mcp-server/internal/handover"
+	"github.com/payram/payram-analytics-mcp-server/internal/httplimits"
 	"github.com/payram/payram-analytics-mcp-server/internal/logging"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
 	"github.com/payram/payram-analytics-mcp-server/internal/version"
 	"github.com/sirupsen/logrus"
 )
@@ -28,20 +40,235 @@ func main() {
 	openaiModel := envOr("OPENAI_MODEL", "gpt-4o-mini")
 	openaiBase := envOr("OPENAI_BASE_URL", "https://api.openai.com/v1")
 	mcpURL := envOr("MCP_SERVER_URL", "http://localhost:3333/")
+	modelPolicy := envOr("CHAT_API_MODEL_POLICY", "")
+	outputLimits := envOr("CHAT_API_TOOL_OUTPUT_LIMITS", "")
+	summaryModel := envOr("CHAT_API_SUMMARY_MODEL", "")
+	summaryStore := envOr("CHAT_API_SUMMARY_STORE", "")
+	conversationBudget := envOr("CHAT_API_CONVERSATION_BUDGET_USD", "")
+	budgetFile := envOr("CHAT_API_BUDGET_FILE", "")
+	routerModel := envOr("CHAT_API_TOOL_ROUTER_MODEL", "")
+	routerTopK := envOr("CHAT_API_TOOL_ROUTER_TOP_K", "5")
+	fastPath, err := strconv.ParseBool(envOr("CHAT_API_FAST_PATH", "false"))
+	if err != nil {
+		logger.Fatalf("invalid CHAT_API_FAST_PATH: %v", err)
+	}
+	savedQueries := envOr("CHAT_API_SAVED_QUERIES", "")
+	feedbackFile := envOr("CHAT_API_FEEDBACK_FILE", "")
+	usersFile := envOr("CHAT_API_USERS_FILE", "")
+	sessionTTL := envOr("CHAT_API_SESSION_TTL", "12h")
+	hashPassword := false
+	oidcIssuer := envOr("CHAT_API_OIDC_ISSUER", "")
+	oidcAudience := envOr("CHAT_API_OIDC_AUDIENCE", "")
+	oidcToolScopes := envOr("CHAT_API_OIDC_TOOL_SCOPES", "")
+	tenantsFile := envOr("CHAT_API_TENANTS", "")
+	usageFile := envOr("CHAT_API_USAGE_FILE", "")
+	dailyLLMCalls := envOr("CHAT_API_DAILY_LLM_CALLS", "0")
+	recordDir := envOr("CHAT_API_RECORD_DIR", "")
+	recordMax := envOr("CHAT_API_RECORD_MAX", "200")
+	language := envOr("CHAT_API_LANGUAGE", "")
+	moderation := envOr("CHAT_API_MODERATION", "")
+	promptExperiment := envOr("CHAT_API_PROMPT_EXPERIMENT", "")
+	branding := envOr("CHAT_API_BRANDING", "")
+	liveInterval, err := time.ParseDuration(envOr("CHAT_API_LIVE_INTERVAL", "15s"))
+	if err != nil {
+		logger.Fatalf("invalid CHAT_API_LIVE_INTERVAL: %v", err)
+	}
+	llmFallbacks := envOr("CHAT_API_LLM_FALLBACKS", "")
+	llmProbe, err := time.ParseDuration(envOr("CHAT_API_LLM_PROBE_INTERVAL", "30s"))
+	if err != nil {
+		logger.Fatalf("invalid CHAT_API_LLM_PROBE_INTERVAL: %v", err)
+	}
+	limits, err := httplimits.FromEnv("CHAT_API", httplimits.Default)
+	if err != nil {
+		logger.Fatalf("server limits: %v", err)
+	}
+	compression, err := compress.FromEnv("CHAT_API", compress.Default)
+	if err != nil {
+		logger.Fatalf("compression: %v", err)
+	}
 
 	flag.StringVar(&port, "port", port, "port to listen on")
 	flag.StringVar(&apiKey, "api-key", apiKey, "chat API bearer key")
 	flag.StringVar(&openaiKey, "openai-key", openaiKey, "OpenAI API key")
 	flag.StringVar(&openaiModel, "openai-model", openaiModel, "OpenAI model")
 	flag.StringVar(&openaiBase, "openai-base", openaiBase, "OpenAI base URL")
+	flag.StringVar(&llmFallbacks, "llm-fallbacks", llmFallbacks, "JSON file of OpenAI-compatible providers to fail over to (empty disables)")
+	flag.DurationVar(&llmProbe, "llm-probe-interval", llmProbe, "how often LLM providers are health-checked for /readyz and failover (0 disables)")
 	flag.StringVar(&mcpURL, "mcp", mcpURL, "MCP server URL (HTTP)")
+	flag.StringVar(&modelPolicy, "model-policy", modelPolicy, "JSON file with per-model parameter rules")
+	flag.StringVar(&outputLimits, "tool-output-limits", outputLimits, "JSON file capping tool results given to the model (empty caps them at 40000 chars)")
+	flag.StringVar(&summaryModel, "summary-model", summaryModel, "model used to summarize long conversations (empty disables)")
+	flag.StringVar(&summaryStore, "summary-store", summaryStore, "JSON file persisting conversation summaries (empty keeps them in memory)")
+	flag.StringVar(&routerModel, "tool-router-model", routerModel, "embedding model used to offer only the most relevant tools (empty offers all)")
+	flag.StringVar(&routerTopK, "tool-router-top-k", routerTopK, "number of tools the tool router offers")
+	flag.BoolVar(&fastPath, "fast-path", fastPath, "answer simple questions such as \"total payments last 7 days\" with a direct tool call, without OpenAI")
+	flag.StringVar(&savedQueries, "saved-queries", savedQueries, "JSON file persisting saved queries (empty keeps them in memory)")
+	flag.StringVar(&feedbackFile, "feedback-file", feedbackFile, "JSON file persisting answer feedback (empty keeps it in memory)")
+	flag.StringVar(&usersFile, "users", usersFile, "JSON users file enabling web UI sign-in (empty disables)")
+	flag.StringVar(&sessionTTL, "session-ttl", sessionTTL, "web UI session lifetime")
+	flag.StringVar(&oidcIssuer, "oidc-issuer", oidcIssuer, "OIDC issuer URL enabling JWT bearer auth (empty disables)")
+	flag.StringVar(&oidcAudience, "oidc-audience", oidcAudience, "audience required in OIDC tokens")
+	flag.StringVar(&oidcToolScopes, "oidc-tool-scopes", oidcToolScopes, "JSON file mapping token scopes to allowed tool patterns (empty allows all tools)")
+	flag.StringVar(&tenantsFile, "tenants", tenantsFile, "tenant registry (same format as PAYRAM_MCP_TENANTS) for per-tenant LLM quotas")
+	flag.StringVar(&usageFile, "usage-file", usageFile, "JSON file persisting daily usage counters (empty keeps them in memory)")
+	flag.StringVar(&dailyLLMCalls, "daily-llm-calls", dailyLLMCalls, "daily OpenAI call quota per user for callers without a tenant (0 = unlimited)")
+	flag.StringVar(&recordDir, "record-dir", recordDir, "directory for debug recordings of every chat request (empty disables)")
+	flag.StringVar(&recordMax, "record-max", recordMax, "number of recordings to keep")
+	flag.StringVar(&moderation, "moderation", moderation, "JSON file configuring the content filter on final replies (empty disables)")
+	flag.StringVar(&promptExperiment, "prompt-experiment", promptExperiment, "JSON file splitting conversations between system prompt variants (empty uses the built-in prompt)")
+	flag.StringVar(&branding, "branding", branding, "JSON file with the assistant name, greeting, tone and formatting preferences (empty keeps PayRam's)")
+	flag.DurationVar(&liveInterval, "live-interval", liveInterval, "how often the /api/live WebSocket polls for new payment events")
+	flag.StringVar(&language, "language", language, "default answer language, e.g. es (empty answers in English)")
+	flag.DurationVar(&limits.ReadHeaderTimeout, "read-header-timeout", limits.ReadHeaderTimeout, "time allowed to read request headers (0 = no limit)")
+	flag.DurationVar(&limits.ReadTimeout, "read-timeout", limits.ReadTimeout, "time allowed to read a whole request (0 = no limit)")
+	flag.DurationVar(&limits.WriteTimeout, "write-timeout", limits.WriteTimeout, "time allowed to answer a request (0 = no limit)")
+	flag.DurationVar(&limits.IdleTimeout, "idle-timeout", limits.IdleTimeout, "how long an idle keep-alive connection stays open (0 = no limit)")
+	flag.IntVar(&limits.MaxHeaderBytes, "max-header-bytes", limits.MaxHeaderBytes, "maximum size of request headers")
+	flag.BoolVar(&hashPassword, "hash-password", false, "read a password from stdin, print its users-file hash, and exit")
 	flag.Parse()
 
+	if hashPassword {
+		printPasswordHash()
+		return
+	}
+
 	if openaiKey == "" {
 		logger.Fatal("OPENAI_API_KEY is required")
 	}
 
+	policy, err := chatapi.LoadModelPolicy(modelPolicy)
+	if err != nil {
+		logger.Fatalf("model policy: %v", err)
+	}
+
 	h := chatapi.NewHandler(logger, apiKey, openaiKey, openaiModel, openaiBase, mcpURL)
+	h.SetModelPolicy(policy)
+	fallbacks, err := chatapi.LoadLLMFallbacks(llmFallbacks)
+	if err != nil {
+		logger.Fatalf("llm fallbacks: %v", err)
+	}
+	h.SetLLMFallbacks(fallbacks)
+	caps, err := chatapi.LoadOutputLimits(outputLimits)
+	if err != nil {
+		logger.Fatalf("tool output limits: %v", err)
+	}
+	h.SetOutputLimits(caps)
+	h.SetMCPTenantKey(envOr("MCP_TENANT_KEY", ""))
+	if err := h.SetLanguage(language); err != nil {
+		logger.Fatalf("language: %v", err)
+	}
+	mod, err := chatapi.LoadModeration(moderation)
+	if err != nil {
+		logger.Fatalf("moderation: %v", err)
+	}
+	h.SetModeration(mod)
+	experiment, err := chatapi.LoadPromptExperiment(promptExperiment)
+	if err != nil {
+		logger.Fatalf("prompt experiment: %v", err)
+	}
+	h.SetPromptExperiment(experiment)
+	brand, err := chatapi.LoadBranding(branding)
+	if err != nil {
+		logger.Fatalf("branding: %v", err)
+	}
+	h.SetBranding(brand)
+	h.SetLiveInterval(liveInterval)
+	h.SetFastPath(fastPath)
+	saved, err := chatapi.OpenSavedQueryStore(savedQueries)
+	if err != nil {
+		logger.Fatalf("saved queries: %v", err)
+	}
+	h.SetSavedQueries(saved)
+	feedback, err := chatapi.OpenFeedbackStore(feedbackFile)
+	if err != nil {
+		logger.Fatalf("feedback: %v", err)
+	}
+	h.SetFeedback(feedback)
+	llmQuota, err := strconv.Atoi(dailyLLMCalls)
+	if err != nil || llmQuota < 0 {
+		logger.Fatalf("invalid daily LLM call quota %q", dailyLLMCalls)
+	}
+	usage, err := tenant.OpenUsage(usageFile)
+	if err != nil {
+		logger.Fatalf("usage: %v", err)
+	}
+	var tenants *tenant.Registry
+	if tenantsFile != "" {
+		if tenants, err = tenant.Load(tenantsFile); err != nil {
+			logger.Fatalf("tenants: %v", err)
+		}
+	}
+	h.SetUsage(usage, tenants, llmQuota)
+	if recordDir != "" {
+		keep, err := strconv.Atoi(recordMax)
+		if err != nil || keep <= 0 {
+			logger.Fatalf("invalid recording cap %q", recordMax)
+		}
+		rec, err := chatapi.OpenRecorder(recordDir, keep)
+		if err != nil {
+			logger.Fatalf("recorder: %v", err)
+		}
+		h.SetRecorder(rec)
+		logger.Warnf("flight recorder enabled: saving chat requests to %s", recordDir)
+	}
+	if usersFile != "" {
+		ttl, err := time.ParseDuration(sessionTTL)
+		if err != nil {
+			logger.Fatalf("invalid session ttl %q: %v", sessionTTL, err)
+		}
+		users, err := chatapi.LoadUsers(usersFile, ttl)
+		if err != nil {
+			logger.Fatalf("users: %v", err)
+		}
+		h.SetUsers(users)
+	}
+	if oidcIssuer != "" {
+		cfg := chatapi.OIDCConfig{
+			Issuer:        oidcIssuer,
+			Audience:      oidcAudience,
+			UsernameClaim: envOr("CHAT_API_OIDC_USERNAME_CLAIM", ""),
+			ScopesClaim:   envOr("CHAT_API_OIDC_SCOPES_CLAIM", ""),
+		}
+		if oidcToolScopes != "" {
+			if cfg.ToolScopes, err = chatapi.LoadToolScopes(oidcToolScopes); err != nil {
+				logger.Fatalf("oidc tool scopes: %v", err)
+			}
+		}
+		verifier, err := chatapi.NewOIDCVerifier(cfg)
+		if err != nil {
+			logger.Fatalf("oidc: %v", err)
+		}
+		h.SetOIDC(verifier)
+	}
+	if summaryModel != "" {
+		store, err := chatapi.OpenSummaryStore(summaryStore)
+		if err != nil {
+			logger.Fatalf("summary store: %v", err)
+		}
+		h.SetSummarizer(summaryModel, store)
+	}
+	if conversationBudget != "" {
+		limit, err := strconv.ParseFloat(conversationBudget, 64)
+		if err != nil {
+			logger.Fatalf("invalid CHAT_API_CONVERSATION_BUDGET_USD: %v", err)
+		}
+		costs, err := chatapi.OpenCostTracker(budgetFile, limit)
+		if err != nil {
+			logger.Fatalf("conversation budget: %v", err)
+		}
+		h.SetConversationBudget(costs)
+	}
+	if routerModel != "" {
+		topK, err := strconv.Atoi(routerTopK)
+		if err != nil {
+			logger.Fatalf("invalid tool router top-k %q", routerTopK)
+		}
+		router, err := chatapi.NewToolRouter(routerModel, topK)
+		if err != nil {
+			logger.Fatalf("tool router: %v", err)
+		}
+		h.SetToolRouter(router)
+	}
 	mux := http.NewServeMux()
 	h.Register(mux)
 	mux.HandleFunc("/version", func(w http.ResponseWriter, _ *http.Request) {
@@ -49,20 +276,47 @@ func main() {
 		_ = json.NewEncoder(w).Encode(version.Get())
 	})
 
-	handler := logRequests(logger, mux)
+	mux.Handle(logging.LevelPath, logging.LevelHandler())
+
+	handler := compression.Handler(logRequests(logger, mux))
 
 	srv := &http.Server{
-		Addr:              ":" + port,
-		Handler:           handler,
-		ReadHeaderTimeout: 5 * time.Second,
+		Addr:    ":" + port,
+		Handler: handler,
+	}
+	limits.Apply(srv)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if llmProbe > 0 {
+		go h.ProbeLLM(ctx, llmProbe)
 	}
 
-	logger.Infof("Chat API listening on :%s (model=%s mcp=%s)", port, openaiModel, mcpURL)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	logger.Infof("Chat API listening on :%s (model=%s mcp=%s fallbacks=%d %s %s)", port, openaiModel, mcpURL, len(fallbacks), limits, compression)
+	if err := handover.ListenAndServe(ctx, srv, 10*time.Second); err != nil {
 		logger.Fatalf("server error: %v", err)
 	}
 }
 
+func printPasswordHash() {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		fmt.Fprintf(os.Stderr, "read password: %v\n", err)
+		os.Exit(1)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		fmt.Fprintln(os.Stderr, "empty password")
+		os.Exit(1)
+	}
+	hash, err := chatapi.HashPassword(password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hash password: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(hash)
+}
+
 func envOr(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -87,6 +341,11 @@ func (r *responseRecorder) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap lets http.ResponseController reach the connection, e.g. to upgrade /api/live.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func logRequests(logger *logrus.Entry, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
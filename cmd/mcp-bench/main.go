@@ -0,0 +1,329 @@
+// Command mcp-bench measures the MCP server under load. It serves the analytics fixtures from
+// internal/tools/testdata as a mock PayRam API, runs the MCP HTTP handler in process against
+// it, and fires tools/call requests from concurrent workers. The report gives latency
+// percentiles per tool and allocations per call, so a regression in the dispatcher or in a
+// tool shows up as a number rather than a feeling.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/app"
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+	"github.com/payram/payram-analytics-mcp-server/internal/mcp"
+	"github.com/sirupsen/logrus"
+)
+
+// benchToken is the bearer token the mock API expects; it never leaves the process.
+const benchToken = "bench"
+
+// workload is one tools/call the workers cycle through.
+type workload struct {
+	Tool string
+	Args string
+}
+
+// defaultWorkloads call every tool that queries the analytics API, with arguments the
+// fixtures can answer.
+var defaultWorkloads = []workload{
+	{"payram_discover_analytics", `{}`},
+	{"payram_fetch_graph_data", `{"group_id":3,"graph_id":31,"date_filter":"this_month","group_by":"currency_code"}`},
+	{"payram_payments_summary", `{"date_filter":"last_month"}`},
+	{"payram_numbers_summary", `{}`},
+	{"payram_transaction_counts", `{"date_filter":"last_7_days"}`},
+	{"payram_daily_stats", `{"days":7}`},
+	{"payram_deposit_distribution", `{"date_filter":"this_month"}`},
+	{"payram_currency_breakdown", `{"date_filter":"last_30_days"}`},
+	{"payram_paying_users", `{"date_filter":"last_30_days"}`},
+	{"payram_user_growth", `{"date_filter":"last_6_months"}`},
+	{"payram_recent_transactions", `{"limit":2}`},
+	{"payram_projects_summary", `{"date_filter":"forever"}`},
+	{"payram_compare_periods", `{"period1":"this_month","period2":"last_month"}`},
+}
+
+type options struct {
+	requests    int
+	concurrency int
+	warmup      int
+	tools       string
+	fixtures    string
+	jsonOut     bool
+	verbose     bool
+}
+
+// toolStats is the latency summary for one tool.
+type toolStats struct {
+	Tool   string  `json:"tool"`
+	Calls  int     `json:"calls"`
+	Errors int     `json:"errors"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// report is the outcome of a run. Allocation figures cover the whole process, mock API
+// included, so compare them between runs rather than reading them as absolutes.
+type report struct {
+	Requests       int         `json:"requests"`
+	Concurrency    int         `json:"concurrency"`
+	DurationMs     float64     `json:"duration_ms"`
+	RequestsPerSec float64     `json:"requests_per_sec"`
+	AllocsPerCall  float64     `json:"allocs_per_call"`
+	BytesPerCall   float64     `json:"bytes_per_call"`
+	GCCycles       uint32      `json:"gc_cycles"`
+	Overall        toolStats   `json:"overall"`
+	Tools          []toolStats `json:"tools"`
+}
+
+func main() {
+	var opts options
+	flag.IntVar(&opts.requests, "n", 2000, "total tools/call requests to measure")
+	flag.IntVar(&opts.concurrency, "c", 8, "concurrent workers")
+	flag.IntVar(&opts.warmup, "warmup", 100, "requests to run before measuring")
+	flag.StringVar(&opts.tools, "tools", "", "comma-separated tools to include (default: all analytics tools)")
+	flag.StringVar(&opts.fixtures, "fixtures", filepath.Join("internal", "tools", "testdata", "analytics"), "directory of mock analytics API responses")
+	flag.BoolVar(&opts.jsonOut, "json", false, "print the report as JSON")
+	flag.BoolVar(&opts.verbose, "v", false, "keep tool log output")
+	flag.Parse()
+	if opts.requests <= 0 || opts.concurrency <= 0 {
+		fmt.Fprintln(os.Stderr, "-n and -c must be positive")
+		os.Exit(2)
+	}
+
+	workloads, err := selectWorkloads(opts.tools)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if _, err := os.Stat(filepath.Join(opts.fixtures, "groups.json")); err != nil {
+		fmt.Fprintf(os.Stderr, "fixtures: %v (run from the repository root or pass -fixtures)\n", err)
+		os.Exit(2)
+	}
+	if !opts.verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	rep, err := run(opts, workloads)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if opts.jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+	} else {
+		printReport(os.Stdout, rep)
+	}
+	if rep.Overall.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+func selectWorkloads(names string) ([]workload, error) {
+	if strings.TrimSpace(names) == "" {
+		return defaultWorkloads, nil
+	}
+	var out []workload
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		n := len(out)
+		for _, w := range defaultWorkloads {
+			if w.Tool == name {
+				out = append(out, w)
+			}
+		}
+		if len(out) == n {
+			return nil, fmt.Errorf("no workload for tool %q", name)
+		}
+	}
+	return out, nil
+}
+
+type sample struct {
+	tool    string
+	elapsed time.Duration
+	failed  bool
+}
+
+func run(opts options, workloads []workload) (report, error) {
+	api := httptest.NewServer(mockAPI(opts.fixtures))
+	defer api.Close()
+	os.Setenv("PAYRAM_ANALYTICS_BASE_URL", api.URL)
+	os.Setenv("PAYRAM_ANALYTICS_TOKEN", benchToken)
+
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	tb := app.NewToolbox()
+	// A fixed clock keeps relative ranges, and so the request bodies, identical across runs.
+	tb.SetClock(clock.Fixed(time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)))
+	handler := mcp.NewHTTPHandler(mcp.NewServer(tb), logrus.NewEntry(quiet))
+
+	bodies := make([][]byte, len(workloads))
+	for i, w := range workloads {
+		bodies[i] = []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q,"arguments":%s}}`, i+1, w.Tool, w.Args))
+	}
+	call := func(i int) (sample, *httptest.ResponseRecorder) {
+		w := workloads[i%len(workloads)]
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bodies[i%len(workloads)]))
+		start := time.Now()
+		handler.ServeHTTP(rec, req)
+		return sample{tool: w.Tool, elapsed: time.Since(start), failed: callFailed(rec)}, rec
+	}
+
+	for i := range opts.warmup {
+		if s, rec := call(i); s.failed {
+			return report{}, fmt.Errorf("warmup call to %s failed: %d %s", s.tool, rec.Code, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+
+	samples := make([]sample, opts.requests)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for range opts.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= opts.requests {
+					return
+				}
+				samples[i], _ = call(i)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	byTool := map[string][]sample{}
+	for _, s := range samples {
+		byTool[s.tool] = append(byTool[s.tool], s)
+	}
+	rep := report{
+		Requests:       opts.requests,
+		Concurrency:    opts.concurrency,
+		DurationMs:     ms(elapsed),
+		RequestsPerSec: float64(opts.requests) / elapsed.Seconds(),
+		AllocsPerCall:  float64(after.Mallocs-before.Mallocs) / float64(opts.requests),
+		BytesPerCall:   float64(after.TotalAlloc-before.TotalAlloc) / float64(opts.requests),
+		GCCycles:       after.NumGC - before.NumGC,
+		Overall:        summarize("all", samples),
+	}
+	for _, tool := range slices.Sorted(maps.Keys(byTool)) {
+		rep.Tools = append(rep.Tools, summarize(tool, byTool[tool]))
+	}
+	return rep, nil
+}
+
+// callFailed reports whether the response is anything but a JSON-RPC result.
+func callFailed(rec *httptest.ResponseRecorder) bool {
+	if rec.Code != http.StatusOK {
+		return true
+	}
+	var resp struct {
+		Error json.RawMessage `json:"error"`
+	}
+	return json.Unmarshal(rec.Body.Bytes(), &resp) != nil || len(resp.Error) > 0
+}
+
+func summarize(tool string, samples []sample) toolStats {
+	st := toolStats{Tool: tool, Calls: len(samples)}
+	d := make([]time.Duration, 0, len(samples))
+	for _, s := range samples {
+		if s.failed {
+			st.Errors++
+		}
+		d = append(d, s.elapsed)
+	}
+	slices.Sort(d)
+	st.P50Ms = ms(percentile(d, 50))
+	st.P90Ms = ms(percentile(d, 90))
+	st.P99Ms = ms(percentile(d, 99))
+	st.MaxMs = ms(percentile(d, 100))
+	return st
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func printReport(w io.Writer, rep report) {
+	fmt.Fprintf(w, "%d calls, %d workers, %.0f ms, %.0f calls/s\n", rep.Requests, rep.Concurrency, rep.DurationMs, rep.RequestsPerSec)
+	fmt.Fprintf(w, "%.0f allocs/call, %.1f KiB/call, %d GC cycles\n\n", rep.AllocsPerCall, rep.BytesPerCall/1024, rep.GCCycles)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "tool\tcalls\terrors\tp50 ms\tp90 ms\tp99 ms\tmax ms\t")
+	for _, st := range append(rep.Tools, rep.Overall) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t\n", st.Tool, st.Calls, st.Errors, st.P50Ms, st.P90Ms, st.P99Ms, st.MaxMs)
+	}
+	_ = tw.Flush()
+}
+
+var graphDataPath = regexp.MustCompile(`^/api/v1/external-platform/all/analytics/groups/\d+/graph/(\d+)/data$`)
+
+// mockAPI answers the analytics endpoints the tools call from dir: groups.json for the group
+// list and graph_<id>.json for each graph's data, read once up front.
+func mockAPI(dir string) http.Handler {
+	files := map[string][]byte{}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, m := range matches {
+		if data, err := os.ReadFile(m); err == nil {
+			files[filepath.Base(m)] = data
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+benchToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var name string
+		switch m := graphDataPath.FindStringSubmatch(r.URL.Path); {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/external-platform/all/analytics/groups":
+			name = "groups.json"
+		case r.Method == http.MethodPost && m != nil:
+			_, _ = io.Copy(io.Discard, r.Body)
+			name = "graph_" + m[1] + ".json"
+		}
+		data, ok := files[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
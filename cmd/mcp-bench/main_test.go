@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func durations(ms ...int) []time.Duration {
+	out := make([]time.Duration, len(ms))
+	for i, m := range ms {
+		out[i] = time.Duration(m) * time.Millisecond
+	}
+	return out
+}
+
+func TestPercentile(t *testing.T) {
+	ten := durations(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	cases := []struct {
+		sorted []time.Duration
+		p      int
+		want   time.Duration
+	}{
+		{nil, 50, 0},
+		{durations(7), 1, 7 * time.Millisecond},
+		{durations(7), 100, 7 * time.Millisecond},
+		{ten, 0, 1 * time.Millisecond},
+		{ten, 10, 1 * time.Millisecond},
+		{ten, 11, 2 * time.Millisecond},
+		{ten, 50, 5 * time.Millisecond},
+		{ten, 90, 9 * time.Millisecond},
+		{ten, 99, 10 * time.Millisecond},
+		{ten, 100, 10 * time.Millisecond},
+		{durations(1, 2, 3), 50, 2 * time.Millisecond},
+	}
+	for _, c := range cases {
+		if got := percentile(c.sorted, c.p); got != c.want {
+			t.Errorf("percentile(%v, %d) = %v, want %v", c.sorted, c.p, got, c.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var samples []sample
+	for i, d := range durations(40, 10, 30, 20, 100) {
+		samples = append(samples, sample{tool: "t", elapsed: d + 500*time.Microsecond, failed: i == 1})
+	}
+	got := summarize("t", samples)
+	want := toolStats{Tool: "t", Calls: 5, Errors: 1, P50Ms: 30.5, P90Ms: 100.5, P99Ms: 100.5, MaxMs: 100.5}
+	if got != want {
+		t.Fatalf("summarize = %+v, want %+v", got, want)
+	}
+	if got := summarize("none", nil); got != (toolStats{Tool: "none"}) {
+		t.Fatalf("summarize(nil) = %+v", got)
+	}
+}
+
+func TestSelectWorkloads(t *testing.T) {
+	all, err := selectWorkloads(" ")
+	if err != nil || len(all) != len(defaultWorkloads) {
+		t.Fatalf("default selection: %d, %v", len(all), err)
+	}
+	got, err := selectWorkloads("payram_daily_stats, payram_numbers_summary")
+	if err != nil || len(got) != 2 || got[0].Tool != "payram_daily_stats" || got[1].Tool != "payram_numbers_summary" {
+		t.Fatalf("selection %+v, %v", got, err)
+	}
+	if _, err := selectWorkloads("payram_daily_stats,nope"); err == nil || !strings.Contains(err.Error(), `"nope"`) {
+		t.Fatalf("unknown tool: %v", err)
+	}
+}
+
+func TestCallFailed(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{200, `{"jsonrpc":"2.0","id":1,"result":{"content":[]}}`, false},
+		{200, `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"x"}}`, true},
+		{200, `not json`, true},
+		{500, `{"result":{}}`, true},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(c.status)
+		rec.WriteString(c.body)
+		if got := callFailed(rec); got != c.want {
+			t.Errorf("callFailed(%d %s) = %t, want %t", c.status, c.body, got, c.want)
+		}
+	}
+}
+
+func TestPrintReport(t *testing.T) {
+	var out bytes.Buffer
+	printReport(&out, report{
+		Requests: 100, Concurrency: 4, DurationMs: 250, RequestsPerSec: 400, AllocsPerCall: 812.4, BytesPerCall: 2048, GCCycles: 3,
+		Overall: toolStats{Tool: "all", Calls: 100, Errors: 1, P50Ms: 1.5, P90Ms: 2, P99Ms: 3.25, MaxMs: 4},
+		Tools:   []toolStats{{Tool: "payram_daily_stats", Calls: 100, Errors: 1, P50Ms: 1.5, P90Ms: 2, P99Ms: 3.25, MaxMs: 4}},
+	})
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 6 || lines[0] != "100 calls, 4 workers, 250 ms, 400 calls/s" || lines[1] != "812 allocs/call, 2.0 KiB/call, 3 GC cycles" {
+		t.Fatalf("report:\n%s", out.String())
+	}
+	want := [][]string{
+		{"tool", "calls", "errors", "p50", "ms", "p90", "ms", "p99", "ms", "max", "ms"},
+		{"payram_daily_stats", "100", "1", "1.50", "2.00", "3.25", "4.00"},
+		{"all", "100", "1", "1.50", "2.00", "3.25", "4.00"},
+	}
+	for i, fields := range want {
+		if got := strings.Fields(lines[3+i]); strings.Join(got, " ") != strings.Join(fields, " ") {
+			t.Errorf("row %d = %q, want %q", i, got, fields)
+		}
+	}
+}
+
+func TestRunAgainstFixtures(t *testing.T) {
+	t.Setenv("PAYRAM_ANALYTICS_BASE_URL", "")
+	t.Setenv("PAYRAM_ANALYTICS_TOKEN", "")
+	opts := options{requests: 2 * len(defaultWorkloads), concurrency: 3, warmup: len(defaultWorkloads), fixtures: filepath.Join("..", "..", "internal", "tools", "testdata", "analytics")}
+	rep, err := run(opts, defaultWorkloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Overall.Calls != opts.requests || rep.Overall.Errors != 0 || len(rep.Tools) != len(defaultWorkloads) {
+		t.Fatalf("report %+v", rep.Overall)
+	}
+	for _, st := range rep.Tools {
+		if st.Calls != 2 || st.P50Ms > st.MaxMs {
+			t.Errorf("stats %+v", st)
+		}
+	}
+}
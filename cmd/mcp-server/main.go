@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
 	"github.com/payram/payram-analytics-mcp-server/internal/app"
@@ -12,10 +15,21 @@ func main() {
 	_ = godotenv.Load()
 
 	httpAddr := flag.String("http", ":3333", "MCP HTTP listen address (e.g., :3333)")
+	stdio := flag.Bool("stdio", false, "Serve one MCP session on stdin/stdout instead of HTTP")
 	flag.Parse()
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *stdio {
+		if err := app.RunMCPStdio(ctx); err != nil {
+			log.Fatalf("MCP server error: %v", err)
+		}
+		return
+	}
+
 	log.Printf("mcp-server server listening on %s", *httpAddr)
-	if err := app.RunMCPHTTP(*httpAddr); err != nil {
+	if err := app.RunMCPHTTPContext(ctx, *httpAddr); err != nil {
 		log.Fatalf("MCP server error: %v", err)
 	}
 }
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// param is one tool argument as a flag.
+type param struct {
+	name     string
+	schema   protocol.JSONSchema
+	required bool
+}
+
+// schemaParams lists the properties of s, required ones first, each group by name.
+func schemaParams(s *protocol.JSONSchema) []param {
+	if s == nil {
+		return nil
+	}
+	out := make([]param, 0, len(s.Properties))
+	for name, prop := range s.Properties {
+		out = append(out, param{name: name, schema: prop, required: slices.Contains(s.Required, name)})
+	}
+	slices.SortFunc(out, func(a, b param) int {
+		if a.required != b.required {
+			if a.required {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	return out
+}
+
+func typeName(s protocol.JSONSchema) string {
+	switch {
+	case s.Type == "array" && s.Items != nil && s.Items.Type != "":
+		return s.Items.Type + "[]"
+	case s.Type == "":
+		return "any"
+	}
+	return s.Type
+}
+
+// parseToolArgs maps argv to the tool's arguments. Each schema property is a flag of the same
+// name, also spelled with dashes for underscores; values are converted to the property's type.
+// --args takes a JSON object (or - for stdin) that individual flags then override.
+func parseToolArgs(tool protocol.ToolDescriptor, argv []string) (map[string]any, error) {
+	fs := flag.NewFlagSet("payramctl call "+tool.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	base := fs.String("args", "", "arguments as a JSON object, or - to read it from stdin")
+
+	args := map[string]any{}
+	params := schemaParams(tool.InputSchema)
+	for _, p := range params {
+		v := &argValue{name: p.name, schema: p.schema, args: args}
+		fs.Var(v, p.name, p.schema.Description)
+		if alias := strings.ReplaceAll(p.name, "_", "-"); alias != p.name {
+			fs.Var(v, alias, p.schema.Description)
+		}
+	}
+	if err := fs.Parse(argv); err != nil {
+		if err == flag.ErrHelp {
+			return nil, fmt.Errorf("%w: run payramctl describe %s for its flags", errUsage, tool.Name)
+		}
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("%w: unexpected argument %q; tool arguments are flags", errUsage, fs.Arg(0))
+	}
+
+	if *base != "" {
+		raw := []byte(*base)
+		if *base == "-" {
+			var err error
+			if raw, err = io.ReadAll(os.Stdin); err != nil {
+				return nil, fmt.Errorf("read --args from stdin: %w", err)
+			}
+		}
+		var merged map[string]any
+		if err := json.Unmarshal(raw, &merged); err != nil {
+			return nil, fmt.Errorf("%w: --args must be a JSON object: %v", errUsage, err)
+		}
+		for k, v := range args {
+			merged[k] = v
+		}
+		args = merged
+	}
+
+	for _, p := range params {
+		if _, ok := args[p.name]; p.required && !ok {
+			return nil, fmt.Errorf("%w: --%s is required", errUsage, p.name)
+		}
+	}
+	return args, nil
+}
+
+// argValue sets one argument, converting the flag text to the property's JSON type. Array
+// properties accept comma-separated values and repeated flags.
+type argValue struct {
+	name   string
+	schema protocol.JSONSchema
+	args   map[string]any
+}
+
+func (v *argValue) String() string { return "" }
+
+// IsBoolFlag lets boolean properties be given as a bare --flag.
+func (v *argValue) IsBoolFlag() bool { return v.schema.Type == "boolean" }
+
+func (v *argValue) Set(s string) error {
+	if v.schema.Type == "array" {
+		item := protocol.JSONSchema{Type: "string"}
+		if v.schema.Items != nil {
+			item = *v.schema.Items
+		}
+		list, _ := v.args[v.name].([]any)
+		for _, part := range strings.Split(s, ",") {
+			x, err := convert(strings.TrimSpace(part), item)
+			if err != nil {
+				return err
+			}
+			list = append(list, x)
+		}
+		v.args[v.name] = list
+		return nil
+	}
+	x, err := convert(s, v.schema)
+	if err != nil {
+		return err
+	}
+	v.args[v.name] = x
+	return nil
+}
+
+func convert(s string, schema protocol.JSONSchema) (any, error) {
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+		return nil, fmt.Errorf("must be one of %s", strings.Join(schema.Enum, ", "))
+	}
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", s)
+		}
+		return n, nil
+	case "number":
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return f, nil
+	case "boolean":
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", s)
+		}
+		return b, nil
+	case "object":
+		var m map[string]any
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			return nil, fmt.Errorf("must be a JSON object: %v", err)
+		}
+		return m, nil
+	}
+	return s, nil
+}
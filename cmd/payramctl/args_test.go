@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// statsTool takes one argument of each kind payramctl converts.
+var statsTool = protocol.ToolDescriptor{
+	Name: "payram_daily_stats",
+	InputSchema: &protocol.JSONSchema{
+		Type:     "object",
+		Required: []string{"days"},
+		Properties: map[string]protocol.JSONSchema{
+			"days":         {Type: "integer", Description: "Days back."},
+			"currency":     {Type: "string", Enum: []string{"USDC", "USDT"}},
+			"group_ids":    {Type: "array", Items: &protocol.JSONSchema{Type: "integer"}},
+			"include_zero": {Type: "boolean"},
+			"threshold":    {Type: "number"},
+			"filters":      {Type: "object"},
+			"tags":         {Type: "array"},
+			"token":        {Type: "string"},
+		},
+	},
+}
+
+func TestParseToolArgs(t *testing.T) {
+	cases := []struct {
+		name string
+		argv []string
+		want map[string]any
+		err  string
+	}{
+		{"integer", []string{"--days", "7"}, map[string]any{"days": int64(7)}, ""},
+		{"dashed alias", []string{"--days=7", "--include-zero"}, map[string]any{"days": int64(7), "include_zero": true}, ""},
+		{"explicit false", []string{"--days", "1", "--include_zero=false"}, map[string]any{"days": int64(1), "include_zero": false}, ""},
+		{"array by comma and repeat", []string{"--days", "1", "--group-ids", "2, 3", "--group_ids", "5"}, map[string]any{"days": int64(1), "group_ids": []any{int64(2), int64(3), int64(5)}}, ""},
+		{"untyped array is strings", []string{"--days", "1", "--tags", "a,b"}, map[string]any{"days": int64(1), "tags": []any{"a", "b"}}, ""},
+		{"number enum object", []string{"--days", "1", "--threshold", "2.5", "--currency", "USDC", "--filters", `{"state":"FILLED"}`}, map[string]any{"days": int64(1), "threshold": 2.5, "currency": "USDC", "filters": map[string]any{"state": "FILLED"}}, ""},
+		{"flags override --args", []string{"--args", `{"days":30,"currency":"USDT"}`, "--days", "7"}, map[string]any{"days": int64(7), "currency": "USDT"}, ""},
+		{"--args satisfies required", []string{"--args", `{"days":30}`}, map[string]any{"days": float64(30)}, ""},
+		{"missing required", []string{"--currency", "USDC"}, nil, "--days is required"},
+		{"not an integer", []string{"--days", "seven"}, nil, `"seven" is not an integer`},
+		{"not in enum", []string{"--days", "1", "--currency", "EUR"}, nil, "must be one of USDC, USDT"},
+		{"bad array item", []string{"--days", "1", "--group-ids", "2,x"}, nil, `"x" is not an integer`},
+		{"bad object", []string{"--days", "1", "--filters", "state=FILLED"}, nil, "must be a JSON object"},
+		{"bad --args", []string{"--args", "[1]"}, nil, "--args must be a JSON object"},
+		{"unknown flag", []string{"--days", "1", "--weeks", "2"}, nil, "flag provided but not defined: -weeks"},
+		{"positional argument", []string{"--days", "1", "extra"}, nil, `unexpected argument "extra"`},
+		{"help", []string{"-h"}, nil, "run payramctl describe payram_daily_stats"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseToolArgs(statsTool, c.argv)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) || !errors.Is(err, errUsage) {
+					t.Fatalf("err = %v, want a usage error with %q", err, c.err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %#v, %v; want %#v", got, err, c.want)
+			}
+		})
+	}
+}
+
+func TestSchemaParams(t *testing.T) {
+	var names []string
+	for _, p := range schemaParams(statsTool.InputSchema) {
+		names = append(names, p.name)
+	}
+	want := "days currency filters group_ids include_zero tags threshold token"
+	if got := strings.Join(names, " "); got != want {
+		t.Fatalf("params %q, want required first then by name: %q", got, want)
+	}
+	if schemaParams(nil) != nil {
+		t.Fatal("params for a tool without a schema")
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	props := statsTool.InputSchema.Properties
+	cases := map[string]protocol.JSONSchema{
+		"integer[]": props["group_ids"],
+		"array":     props["tags"],
+		"boolean":   props["include_zero"],
+		"any":       {},
+	}
+	for want, s := range cases {
+		if got := typeName(s); got != want {
+			t.Errorf("typeName(%+v) = %q, want %q", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+// Command payramctl talks to a running MCP server over HTTP without involving the LLM: it
+// lists the tools, describes their arguments, and calls one with flags derived from its input
+// schema, printing the result as text (charts become tables) or as raw JSON. It is meant for
+// scripting and for checking a deployment from a shell.
+//
+// Flags default to the environment, then to the config file shared with agentctl, which
+// "payramctl init" writes.
+//
+//	payramctl init
+//	payramctl tools
+//	payramctl describe payram_daily_stats
+//	payramctl call payram_daily_stats --days 7
+//	payramctl --json call payram_fetch_graph_data --group_id 3 --graph_id 31 --date_filter this_month
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+	"github.com/payram/payram-analytics-mcp-server/internal/cliconfig"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+type options struct {
+	url       string
+	token     string
+	tenantID  string
+	tenantKey string
+	language  string
+	jsonOut   bool
+}
+
+// errUsage marks errors caused by how the command was invoked; they exit with status 2.
+var errUsage = errors.New("usage")
+
+func main() {
+	_ = godotenv.Load()
+
+	configPath, err := cliconfig.Path()
+	var cfg cliconfig.Config
+	if err == nil {
+		cfg, err = cliconfig.Load(configPath)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], cfg, configPath, os.Getenv, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run parses the flags, runs the command and returns the exit code: 0 on success, 1 when the
+// command fails and 2 for usage errors. cfg and configPath are the loaded config file.
+func run(ctx context.Context, argv []string, cfg cliconfig.Config, configPath string, getenv func(string) string, stdout, stderr io.Writer) int {
+	var opts options
+	fs := flag.NewFlagSet("payramctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.url, "url", envOr(getenv, "MCP_SERVER_URL", firstNonEmpty(cfg.MCP.URL, cliconfig.DefaultMCPURL)), "MCP server HTTP endpoint (default MCP_SERVER_URL, else the config file)")
+	fs.StringVar(&opts.token, "token", envOr(getenv, "PAYRAM_ANALYTICS_TOKEN", cfg.MCP.Token), "analytics token passed to payram_* tools that take one (default PAYRAM_ANALYTICS_TOKEN, else the config file)")
+	fs.StringVar(&opts.tenantID, "tenant", cfg.MCP.TenantID, "tenant ID, for a multi-tenant server")
+	fs.StringVar(&opts.tenantKey, "tenant-key", envOr(getenv, "MCP_TENANT_KEY", cfg.MCP.TenantKey), "tenant key, for a multi-tenant server (default MCP_TENANT_KEY, else the config file)")
+	fs.StringVar(&opts.language, "language", "", "language for tool output, e.g. es or pt-BR")
+	fs.BoolVar(&opts.jsonOut, "json", false, "print raw JSON instead of text")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "usage: payramctl [flags] <command> [args]\n\n")
+		fmt.Fprintf(out, "commands:\n")
+		fmt.Fprintf(out, "  tools                      list the server's tools\n")
+		fmt.Fprintf(out, "  describe <tool>            show a tool's arguments\n")
+		fmt.Fprintf(out, "  call <tool> [--arg value]  call a tool; see describe for its flags\n")
+		fmt.Fprintf(out, "  init                       set up the config file interactively and check the connection\n")
+		fmt.Fprintf(out, "  completion <bash|zsh>      print a shell completion script\n\n")
+		fmt.Fprintf(out, "flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(argv); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	ctx = chatserver.WithTenant(ctx, opts.tenantID, opts.tenantKey)
+	if opts.language != "" {
+		lang, ok := i18n.Parse(opts.language)
+		if !ok {
+			fmt.Fprintf(stderr, "invalid --language %q\n", opts.language)
+			return 2
+		}
+		ctx = i18n.WithLanguage(ctx, lang)
+	}
+	client := chatserver.NewMCPClient(opts.url)
+
+	var err error
+	switch cmd, args := fs.Arg(0), fs.Args()[1:]; cmd {
+	case "tools":
+		err = listTools(ctx, client, opts, stdout)
+	case "describe":
+		if len(args) != 1 {
+			err = fmt.Errorf("%w: payramctl describe <tool>", errUsage)
+			break
+		}
+		err = describeTool(ctx, client, args[0], opts, stdout)
+	case "call":
+		if len(args) == 0 {
+			err = fmt.Errorf("%w: payramctl call <tool> [--arg value]...", errUsage)
+			break
+		}
+		err = callTool(ctx, client, args[0], args[1:], opts, stdout)
+	case "init":
+		if len(args) != 0 {
+			err = fmt.Errorf("%w: payramctl init", errUsage)
+			break
+		}
+		err = runInit(ctx, configPath, cfg, stdout)
+	case "completion":
+		if len(args) != 1 {
+			err = fmt.Errorf("%w: payramctl completion <bash|zsh>", errUsage)
+			break
+		}
+		var script string
+		if script, err = cliconfig.Completion(args[0], "payramctl", fs, completionCommands); err != nil {
+			err = fmt.Errorf("%w: %v", errUsage, err)
+			break
+		}
+		fmt.Fprint(stdout, script)
+	default:
+		err = fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, describeError(err))
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+func listTools(ctx context.Context, client *chatserver.MCPClient, opts options, out io.Writer) error {
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(tools, func(a, b protocol.ToolDescriptor) int { return strings.Compare(a.Name, b.Name) })
+	if opts.jsonOut {
+		return printJSON(out, tools)
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOOL\tDESCRIPTION")
+	for _, t := range tools {
+		fmt.Fprintf(tw, "%s\t%s\n", t.Name, firstSentence(t.Description))
+	}
+	return tw.Flush()
+}
+
+func describeTool(ctx context.Context, client *chatserver.MCPClient, name string, opts options, out io.Writer) error {
+	tool, err := findTool(ctx, client, name)
+	if err != nil {
+		return err
+	}
+	if opts.jsonOut {
+		return printJSON(out, tool)
+	}
+	fmt.Fprintf(out, "%s\n\n%s\n", tool.Name, tool.Description)
+	params := schemaParams(tool.InputSchema)
+	if len(params) == 0 {
+		fmt.Fprintln(out, "\nNo arguments.")
+		return nil
+	}
+	fmt.Fprintln(out)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FLAG\tTYPE\tREQUIRED\tDESCRIPTION")
+	for _, p := range params {
+		desc := p.schema.Description
+		if len(p.schema.Enum) > 0 {
+			desc = strings.TrimSpace(desc + " (one of " + strings.Join(p.schema.Enum, ", ") + ")")
+		}
+		required := ""
+		if p.required {
+			required = "yes"
+		}
+		fmt.Fprintf(tw, "--%s\t%s\t%s\t%s\n", p.name, typeName(p.schema), required, desc)
+	}
+	return tw.Flush()
+}
+
+func callTool(ctx context.Context, client *chatserver.MCPClient, name string, argv []string, opts options, out io.Writer) error {
+	tool, err := findTool(ctx, client, name)
+	if err != nil {
+		return err
+	}
+	args, err := parseToolArgs(tool, argv)
+	if err != nil {
+		return err
+	}
+	if opts.token != "" && strings.HasPrefix(tool.Name, "payram_") && tool.InputSchema != nil {
+		if _, takes := tool.InputSchema.Properties["token"]; takes {
+			if _, set := args["token"]; !set {
+				args["token"] = opts.token
+			}
+		}
+	}
+	result, err := client.CallTool(ctx, tool.Name, args)
+	if err != nil {
+		return err
+	}
+	if opts.jsonOut {
+		return printJSON(out, result)
+	}
+	return printResult(out, result)
+}
+
+// runInit asks for the settings of both CLIs, checks them against the servers, and saves them.
+func runInit(ctx context.Context, path string, cfg cliconfig.Config, out io.Writer) error {
+	cfg, err := cliconfig.RunWizard(ctx, os.Stdin, out, cfg)
+	if err != nil {
+		return err
+	}
+	if err := cliconfig.Save(path, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Saved %s\n", path)
+	return nil
+}
+
+// completionCommands feeds "payramctl completion"; tool names and their flags come from the
+// server, through the tools and describe commands.
+var completionCommands = []cliconfig.Command{
+	{Name: "tools"},
+	{Name: "describe", ArgsFrom: toolNames},
+	{Name: "call", Flags: []string{"--args"}, ArgsFrom: toolNames, FlagsFrom: `payramctl describe "$arg" | awk '$1 ~ /^--/ {print $1}'`},
+	{Name: "init"},
+	{Name: "completion", Args: []string{"bash", "zsh"}},
+}
+
+const toolNames = `payramctl tools | awk 'NR > 1 {print $1}'`
+
+// findTool looks name up in the server's tool list.
+func findTool(ctx context.Context, client *chatserver.MCPClient, name string) (protocol.ToolDescriptor, error) {
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return protocol.ToolDescriptor{}, err
+	}
+	for _, t := range tools {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return protocol.ToolDescriptor{}, fmt.Errorf("%w: unknown tool %q; run payramctl tools for the list", errUsage, name)
+}
+
+// printResult writes text parts as they are, followed by any charts as aligned tables.
+func printResult(out io.Writer, result protocol.CallResult) error {
+	for i, part := range result.Content {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, strings.TrimRight(part.Text, "\n"))
+	}
+	if result.Meta == nil {
+		return nil
+	}
+	for _, c := range result.Meta.Charts {
+		fmt.Fprintln(out)
+		if err := printChart(out, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printChart(out io.Writer, c protocol.ChartData) error {
+	if c.Title != "" {
+		fmt.Fprintf(out, "%s (%s)\n", c.Title, c.Kind)
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := []string{""}
+	for _, s := range c.Series {
+		header = append(header, s.Name)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+	for i, label := range c.Labels {
+		row := []string{label}
+		for _, s := range c.Series {
+			v := ""
+			if i < len(s.Values) {
+				v = fmt.Sprintf("%g", s.Values[i])
+			}
+			row = append(row, v)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t")+"\t")
+	}
+	return tw.Flush()
+}
+
+// describeError renders err for stderr, adding the category of tool errors.
+func describeError(err error) string {
+	var rpcErr *chatserver.RPCError
+	if errors.As(err, &rpcErr) {
+		if d, ok := protocol.DataOf(&protocol.ResponseError{Code: rpcErr.Code, Message: rpcErr.Message, Data: rpcErr.Data}); ok && d.Category != "" {
+			return fmt.Sprintf("error [%s]: %s", d.Category, rpcErr.Message)
+		}
+		return "error: " + rpcErr.Message
+	}
+	return strings.TrimPrefix(err.Error(), errUsage.Error()+": ")
+}
+
+func printJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// firstSentence shortens a tool description for the tool list.
+func firstSentence(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	// A sentence ends at ". " before a capital, so abbreviations such as "e.g. " do not count.
+	for i := 0; i+2 < len(s); i++ {
+		if s[i] == '.' && s[i+1] == ' ' && 'A' <= s[i+2] && s[i+2] <= 'Z' {
+			return s[:i+1]
+		}
+	}
+	return s
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func envOr(getenv func(string) string, key, def string) string {
+	if v := strings.TrimSpace(getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
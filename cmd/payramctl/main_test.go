@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/cliconfig"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// fakeServer is an MCP server listing tools and answering tools/call with call.
+type fakeServer struct {
+	*httptest.Server
+	tools []protocol.ToolDescriptor
+	call  func(name string, args map[string]any) (protocol.CallResult, *protocol.ResponseError)
+
+	mu    sync.Mutex
+	calls []map[string]any
+	langs []string
+}
+
+func newFakeServer(t *testing.T, tools ...protocol.ToolDescriptor) *fakeServer {
+	f := &fakeServer{tools: tools}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req protocol.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := protocol.Response{JSONRPC: "2.0", ID: req.ID}
+		switch req.Method {
+		case "tools/list":
+			resp.Result = protocol.ListResult{Tools: f.tools}
+		case "tools/call":
+			var p protocol.CallParams
+			_ = json.Unmarshal(req.Params, &p)
+			args := map[string]any{}
+			_ = json.Unmarshal(p.Args, &args)
+			f.mu.Lock()
+			f.calls = append(f.calls, args)
+			f.langs = append(f.langs, r.Header.Get("X-Language"))
+			f.mu.Unlock()
+			result, rerr := f.call(p.Name, args)
+			if rerr != nil {
+				resp.Error = rerr
+			} else {
+				resp.Result = result
+			}
+		default:
+			resp.Error = &protocol.ResponseError{Code: -32601, Message: "method not found"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// payramctl runs the command line against f and returns the exit code and output.
+func (f *fakeServer) payramctl(t *testing.T, argv ...string) (int, string, string) {
+	t.Helper()
+	env := map[string]string{"MCP_SERVER_URL": f.URL, "PAYRAM_ANALYTICS_TOKEN": "env-token"}
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), argv, cliconfig.Config{}, "", func(k string) string { return env[k] }, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func textResult(text string) protocol.CallResult {
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: text}}}
+}
+
+func TestToolsAndDescribe(t *testing.T) {
+	summary := protocol.ToolDescriptor{Name: "payram_numbers_summary", Description: "Totals, e.g. volume. More detail here.", InputSchema: &protocol.JSONSchema{Type: "object"}}
+	stats := statsTool
+	stats.Description = "Daily stats.\nSecond line."
+	f := newFakeServer(t, stats, summary)
+
+	code, out, _ := f.payramctl(t, "tools")
+	want := "TOOL                    DESCRIPTION\n" +
+		"payram_daily_stats      Daily stats.\n" +
+		"payram_numbers_summary  Totals, e.g. volume.\n"
+	if code != 0 || out != want {
+		t.Fatalf("tools exit %d:\n%s\nwant:\n%s", code, out, want)
+	}
+
+	code, out, _ = f.payramctl(t, "describe", "payram_daily_stats")
+	for _, line := range []string{
+		"FLAG            TYPE       REQUIRED  DESCRIPTION",
+		"--days          integer    yes       Days back.",
+		"--currency      string               (one of USDC, USDT)",
+		"--group_ids     integer[]",
+	} {
+		if code != 0 || !strings.Contains(out, line) {
+			t.Fatalf("describe exit %d lacks %q:\n%s", code, line, out)
+		}
+	}
+	if _, out, _ = f.payramctl(t, "describe", "payram_numbers_summary"); !strings.HasSuffix(out, "\nNo arguments.\n") {
+		t.Fatalf("describe without arguments:\n%s", out)
+	}
+
+	code, out, _ = f.payramctl(t, "--json", "tools")
+	var listed []protocol.ToolDescriptor
+	if err := json.Unmarshal([]byte(out), &listed); code != 0 || err != nil || len(listed) != 2 || listed[0].Name != "payram_daily_stats" {
+		t.Fatalf("--json tools exit %d: %s", code, out)
+	}
+}
+
+func TestCallPrintsTextAndCharts(t *testing.T) {
+	f := newFakeServer(t, statsTool)
+	f.call = func(string, map[string]any) (protocol.CallResult, *protocol.ResponseError) {
+		r := textResult("Payments by day\n")
+		r.Content = append(r.Content, protocol.ContentPart{Type: "text", Text: "2 days"})
+		r.Meta = &protocol.CallMeta{Charts: []protocol.ChartData{{
+			Kind: "timeseries", Title: "Volume", Labels: []string{"2025-01-01", "2025-01-02"},
+			Series: []protocol.ChartSeries{{Name: "USDC", Values: []float64{1204.5, 30}}, {Name: "USDT", Values: []float64{7}}},
+		}}}
+		return r, nil
+	}
+
+	code, out, stderr := f.payramctl(t, "--language", "es", "call", "payram_daily_stats", "--days", "7")
+	want := "Payments by day\n\n2 days\n\nVolume (timeseries)\n" +
+		"                USDC  USDT\n" +
+		"  2025-01-01  1204.5     7\n" +
+		"  2025-01-02      30      \n"
+	if code != 0 || out != want {
+		t.Fatalf("call exit %d (%s):\n%q\nwant:\n%q", code, stderr, out, want)
+	}
+	// The configured token fills in the tool's token argument, and the language is sent.
+	if f.calls[0]["token"] != "env-token" || f.calls[0]["days"] != float64(7) || f.langs[0] != "es" {
+		t.Fatalf("called with %v, language %q", f.calls[0], f.langs[0])
+	}
+	f.payramctl(t, "call", "payram_daily_stats", "--days", "7", "--token", "mine")
+	if f.calls[1]["token"] != "mine" {
+		t.Fatalf("--token flag overridden: %v", f.calls[1])
+	}
+
+	code, out, _ = f.payramctl(t, "--json", "call", "payram_daily_stats", "--days", "7")
+	var result protocol.CallResult
+	if err := json.Unmarshal([]byte(out), &result); code != 0 || err != nil || len(result.Meta.Charts) != 1 {
+		t.Fatalf("--json call exit %d: %s", code, out)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	f := newFakeServer(t, statsTool)
+	f.call = func(string, map[string]any) (protocol.CallResult, *protocol.ResponseError) {
+		return protocol.CallResult{}, protocol.NewError(protocol.CategoryUpstreamUnavailable, "analytics API returned 503")
+	}
+	cases := []struct {
+		argv   []string
+		code   int
+		stderr string
+	}{
+		{nil, 2, "usage: payramctl"},
+		{[]string{"--bogus"}, 2, "flag provided but not defined"},
+		{[]string{"frobnicate"}, 2, `unknown command "frobnicate"`},
+		{[]string{"describe"}, 2, "payramctl describe <tool>"},
+		{[]string{"call"}, 2, "payramctl call <tool>"},
+		{[]string{"init", "now"}, 2, "payramctl init"},
+		{[]string{"completion", "fish"}, 2, "fish"},
+		{[]string{"--language", "xx-invalid-1", "tools"}, 2, "invalid --language"},
+		{[]string{"describe", "nope"}, 2, `unknown tool "nope"`},
+		{[]string{"call", "payram_daily_stats"}, 2, "--days is required"},
+		{[]string{"call", "payram_daily_stats", "--days", "1"}, 1, "error [UPSTREAM_UNAVAILABLE]: analytics API returned 503"},
+		{[]string{"--url", "http://127.0.0.1:1", "tools"}, 1, ""},
+	}
+	for _, c := range cases {
+		code, _, stderr := f.payramctl(t, c.argv...)
+		if code != c.code || !strings.Contains(stderr, c.stderr) {
+			t.Errorf("%q: exit %d, stderr %q; want %d with %q", c.argv, code, stderr, c.code, c.stderr)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	code, out, _ := newFakeServer(t).payramctl(t, "completion", "bash")
+	if code != 0 || !strings.Contains(out, "payramctl") || !strings.Contains(out, "--tenant-key") {
+		t.Fatalf("completion exit %d:\n%s", code, out)
+	}
+}
+
+func TestFirstSentence(t *testing.T) {
+	cases := map[string]string{
+		"Lists groups. Then more.":          "Lists groups.",
+		"Totals, e.g. volume. More.":        "Totals, e.g. volume.",
+		"  One line\nsecond line":           "One line",
+		"No full stop":                      "No full stop",
+		"Ends with a stop.":                 "Ends with a stop.",
+		"Version 1.2 is out. Details below": "Version 1.2 is out.",
+	}
+	for in, want := range cases {
+		if got := firstSentence(in); got != want {
+			t.Errorf("firstSentence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatapi"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/recording"
+)
+
+// compare lists the differences between a recorded run and its replay.
+func compare(want, got *chatapi.Bundle, verbose bool) []string {
+	var diffs []string
+	if got.Status != want.Status {
+		diffs = append(diffs, fmt.Sprintf("status %d, recorded %d", got.Status, want.Status))
+	}
+	if len(got.Tools) != len(want.Tools) {
+		diffs = append(diffs, fmt.Sprintf("%d tool calls (%s), recorded %d (%s)",
+			len(got.Tools), toolNames(got.Tools), len(want.Tools), toolNames(want.Tools)))
+	}
+	for i := range min(len(got.Tools), len(want.Tools)) {
+		w, g := want.Tools[i], got.Tools[i]
+		label := fmt.Sprintf("tool %d (%s)", i+1, w.Name)
+		if g.Name != w.Name {
+			diffs = append(diffs, fmt.Sprintf("%s: called %s instead", label, g.Name))
+			continue
+		}
+		if ga, wa := callArguments(g.Arguments), callArguments(w.Arguments); !reflect.DeepEqual(ga, wa) {
+			diffs = append(diffs, fmt.Sprintf("%s: arguments %s, recorded %s", label, compactJSON(ga), compactJSON(wa)))
+		}
+		if g.Error != w.Error {
+			diffs = append(diffs, fmt.Sprintf("%s: error %q, recorded %q", label, g.Error, w.Error))
+		}
+		if d := diffOutput(w.Result, g.Result, verbose); d != "" {
+			diffs = append(diffs, label+": "+d)
+		}
+	}
+	return diffs
+}
+
+func toolNames(tools []chatapi.ToolExchange) string {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// callArguments decodes tool arguments without credentials, which depend on how the
+// request was authenticated rather than on what the model asked for.
+func callArguments(raw json.RawMessage) map[string]any {
+	var args map[string]any
+	_ = json.Unmarshal(raw, &args)
+	for k := range args {
+		if recording.IsSensitive(k) {
+			delete(args, k)
+		}
+	}
+	return args
+}
+
+func compactJSON(v any) string {
+	out, _ := json.Marshal(v)
+	return string(out)
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// diffOutput describes how a tool's replayed output differs from the recorded one, or
+// returns "" when they match.
+func diffOutput(want, got *protocol.CallResult, verbose bool) string {
+	wc, wcharts := resultParts(want)
+	gc, gcharts := resultParts(got)
+	wj, _ := json.Marshal(wc)
+	gj, _ := json.Marshal(gc)
+	wcj, _ := json.Marshal(wcharts)
+	gcj, _ := json.Marshal(gcharts)
+	if jsonEqual(wj, gj) && jsonEqual(wcj, gcj) {
+		return ""
+	}
+	wt, gt := contentText(wc), contentText(gc)
+	if wt == gt {
+		return "chart data differs"
+	}
+	if verbose {
+		return fmt.Sprintf("output differs\n--- recorded\n%s\n+++ replayed\n%s", wt, gt)
+	}
+	wl, gl := strings.Split(wt, "\n"), strings.Split(gt, "\n")
+	for i := range max(len(wl), len(gl)) {
+		var a, b string
+		if i < len(wl) {
+			a = wl[i]
+		}
+		if i < len(gl) {
+			b = gl[i]
+		}
+		if a != b {
+			return fmt.Sprintf("output differs at line %d\n- %s\n+ %s", i+1, a, b)
+		}
+	}
+	return "output differs"
+}
+
+// resultParts returns the content and charts of r, which may be nil.
+func resultParts(r *protocol.CallResult) ([]protocol.ContentPart, []protocol.ChartData) {
+	if r == nil {
+		return nil, nil
+	}
+	if r.Meta == nil {
+		return r.Content, nil
+	}
+	return r.Content, r.Meta.Charts
+}
+
+func contentText(parts []protocol.ContentPart) string {
+	var sb strings.Builder
+	for _, p := range parts {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(p.Text)
+	}
+	return sb.String()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatapi"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+func text(s string) *protocol.CallResult {
+	return &protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: s}}}
+}
+
+func withChart(r *protocol.CallResult, values ...float64) *protocol.CallResult {
+	r.Meta = &protocol.CallMeta{Charts: []protocol.ChartData{{Kind: protocol.ChartBar, Labels: []string{"a"}, Series: []protocol.ChartSeries{{Name: "n", Values: values}}}}}
+	return r
+}
+
+func TestCompare(t *testing.T) {
+	recorded := func() *chatapi.Bundle {
+		return &chatapi.Bundle{Status: 200, Tools: []chatapi.ToolExchange{
+			{Name: "payram_daily_stats", Arguments: json.RawMessage(`{"days":7,"token":"abc"}`), Result: withChart(text("day 1: 3\nday 2: 4"), 3)},
+		}}
+	}
+	cases := []struct {
+		name string
+		edit func(*chatapi.Bundle)
+		want []string
+	}{
+		{"identical", func(*chatapi.Bundle) {}, nil},
+		{"credentials are ignored", func(b *chatapi.Bundle) { b.Tools[0].Arguments = json.RawMessage(`{"token":"other","days":7}`) }, nil},
+		{"status", func(b *chatapi.Bundle) { b.Status = 502 }, []string{"status 502, recorded 200"}},
+		{"tool name", func(b *chatapi.Bundle) { b.Tools[0].Name = "payram_payments_summary" }, []string{"tool 1 (payram_daily_stats): called payram_payments_summary instead"}},
+		{"arguments", func(b *chatapi.Bundle) { b.Tools[0].Arguments = json.RawMessage(`{"days":30}`) }, []string{`tool 1 (payram_daily_stats): arguments {"days":30}, recorded {"days":7}`}},
+		{"error", func(b *chatapi.Bundle) { b.Tools[0].Error = "boom" }, []string{`tool 1 (payram_daily_stats): error "boom", recorded ""`}},
+		{"output", func(b *chatapi.Bundle) { b.Tools[0].Result = withChart(text("day 1: 3\nday 2: 5"), 3) }, []string{"tool 1 (payram_daily_stats): output differs at line 2\n- day 2: 4\n+ day 2: 5"}},
+		{"chart only", func(b *chatapi.Bundle) { b.Tools[0].Result = withChart(text("day 1: 3\nday 2: 4"), 9) }, []string{"tool 1 (payram_daily_stats): chart data differs"}},
+		{"extra tool call", func(b *chatapi.Bundle) {
+			b.Tools = append(b.Tools, chatapi.ToolExchange{Name: "payram_changes", Arguments: json.RawMessage(`{}`)})
+		}, []string{"2 tool calls (payram_daily_stats, payram_changes), recorded 1 (payram_daily_stats)"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := recorded()
+			c.edit(got)
+			diffs := compare(recorded(), got, false)
+			if strings.Join(diffs, "|") != strings.Join(c.want, "|") {
+				t.Fatalf("diffs %q, want %q", diffs, c.want)
+			}
+		})
+	}
+}
+
+func TestDiffOutput(t *testing.T) {
+	if d := diffOutput(nil, nil, false); d != "" {
+		t.Fatalf("nil results differ: %q", d)
+	}
+	if d := diffOutput(text("a"), nil, false); d != "output differs at line 1\n- a\n+ " {
+		t.Fatalf("missing result: %q", d)
+	}
+	if d := diffOutput(text("a\nb"), text("a"), false); d != "output differs at line 2\n- b\n+ " {
+		t.Fatalf("shorter output: %q", d)
+	}
+	if d := diffOutput(text("a"), text("b"), true); d != "output differs\n--- recorded\na\n+++ replayed\nb" {
+		t.Fatalf("verbose: %q", d)
+	}
+	// Key order and spacing of JSON text do not matter, only the values.
+	if d := diffOutput(text(`{"a":1}`), text(`{"a":1}`), false); d != "" {
+		t.Fatalf("equal JSON: %q", d)
+	}
+}
+
+func TestCallArgumentsDropsCredentials(t *testing.T) {
+	got := callArguments(json.RawMessage(`{"days":7,"token":"t","authorization":"Bearer y"}`))
+	if len(got) != 1 || got["days"] != 7.0 {
+		t.Fatalf("callArguments = %v", got)
+	}
+	if got := callArguments(json.RawMessage(`not json`)); len(got) != 0 {
+		t.Fatalf("invalid arguments decoded to %v", got)
+	}
+}
@@ -0,0 +1,283 @@
+// Command replay re-runs chat requests saved by the chat API's flight recorder against the
+// current code. Analytics API responses are served from the recording, and by default so are
+// the LLM replies, so a run is deterministic and needs no network: any change in the tool
+// calls made or in the text tools render is reported as a regression. With --live the request
+// goes to a real model instead, which checks tool selection against the current prompts.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/payram/payram-analytics-mcp-server/internal/app"
+	"github.com/payram/payram-analytics-mcp-server/internal/chatapi"
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+	"github.com/payram/payram-analytics-mcp-server/internal/mcp"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/recording"
+	"github.com/sirupsen/logrus"
+)
+
+type options struct {
+	live       bool
+	model      string
+	openaiKey  string
+	openaiBase string
+	baseURL    string
+	verbose    bool
+}
+
+func main() {
+	_ = godotenv.Load()
+	os.Exit(run(os.Args[1:], os.Getenv, os.Stdout, os.Stderr))
+}
+
+// run replays the bundles named on the command line, reporting each to stdout, and returns
+// the exit code: 0 when every recording replayed cleanly, 1 when any differed or failed, and
+// 2 for usage errors.
+func run(args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	opts, paths, err := parseArgs(args, getenv, stderr)
+	if err != nil {
+		return 2
+	}
+	files, err := bundleFiles(paths)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	failed := 0
+	for _, file := range files {
+		b, err := readBundle(file)
+		if err != nil {
+			fmt.Fprintf(stdout, "ERROR %s: %v\n", file, err)
+			failed++
+			continue
+		}
+		diffs, err := replay(b, opts)
+		switch {
+		case err != nil:
+			fmt.Fprintf(stdout, "ERROR %s: %v\n", b.ID, err)
+			failed++
+		case len(diffs) > 0:
+			fmt.Fprintf(stdout, "FAIL  %s\n", b.ID)
+			for _, d := range diffs {
+				fmt.Fprintf(stdout, "      %s\n", strings.ReplaceAll(d, "\n", "\n      "))
+			}
+			failed++
+		default:
+			fmt.Fprintf(stdout, "ok    %s (%d tool calls)\n", b.ID, len(b.Tools))
+		}
+	}
+	fmt.Fprintf(stdout, "%d of %d recordings replayed cleanly\n", len(files)-failed, len(files))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// parseArgs parses the flags and returns the options and the bundle paths. Problems are
+// reported to stderr, along with the usage.
+func parseArgs(args []string, getenv func(string) string, stderr io.Writer) (options, []string, error) {
+	var opts options
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVar(&opts.live, "live", false, "send requests to a real model (OPENAI_API_KEY) instead of replaying recorded LLM replies")
+	fs.StringVar(&opts.model, "model", "", "model for --live runs (default: the recorded model)")
+	fs.StringVar(&opts.openaiBase, "openai-base", envOr(getenv, "OPENAI_BASE_URL", "https://api.openai.com/v1"), "OpenAI base URL for --live runs")
+	fs.StringVar(&opts.baseURL, "base-url", "", "analytics base URL the recording was made against (default: scheme and host of the first recorded request)")
+	fs.BoolVar(&opts.verbose, "v", false, "print full tool output on differences")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: replay [flags] <bundle.json|dir>...\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, nil, err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return opts, nil, errors.New("no recordings given")
+	}
+	opts.openaiKey = getenv("OPENAI_API_KEY")
+	if opts.live && opts.openaiKey == "" {
+		fmt.Fprintln(stderr, "--live needs OPENAI_API_KEY")
+		return opts, nil, errors.New("--live needs OPENAI_API_KEY")
+	}
+	return opts, fs.Args(), nil
+}
+
+// bundleFiles expands directories into the bundles they contain, oldest first.
+func bundleFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(arg, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func readBundle(file string) (*chatapi.Bundle, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var b chatapi.Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if len(b.Request) == 0 {
+		return nil, fmt.Errorf("not a recording bundle")
+	}
+	return &b, nil
+}
+
+// replay serves b's request through an in-process MCP server and chat handler and returns
+// how the new run differs from the recorded one.
+func replay(b *chatapi.Bundle, opts options) ([]string, error) {
+	var exchanges []protocol.HTTPExchange
+	for _, t := range b.Tools {
+		exchanges = append(exchanges, t.Exchanges...)
+	}
+	base := opts.baseURL
+	if base == "" {
+		base = recordedBase(exchanges)
+	}
+	// Tools fall back to these when the recorded arguments carry no overrides. The token is
+	// never sent anywhere: every analytics request is answered from the recording.
+	os.Setenv("PAYRAM_ANALYTICS_BASE_URL", base)
+	os.Setenv("PAYRAM_ANALYTICS_TOKEN", "replay")
+
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	logger := logrus.NewEntry(quiet)
+
+	rp := recording.NewReplay(exchanges)
+	// Relative ranges such as "last 7 days" resolve against the time of the recording, so the
+	// replayed tools ask for the same dates the recorded requests did.
+	tb := app.NewToolbox()
+	tb.SetClock(clock.Fixed(b.Time))
+	mcpHandler := mcp.NewHTTPHandler(mcp.NewServer(tb), logger)
+	mcpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mcpHandler.ServeHTTP(w, r.WithContext(recording.WithReplay(r.Context(), rp)))
+	}))
+	defer mcpSrv.Close()
+
+	model := recordedModel(b)
+	openaiKey, openaiBase := "replay", ""
+	if opts.live {
+		openaiKey, openaiBase = opts.openaiKey, opts.openaiBase
+		if opts.model != "" {
+			model = opts.model
+		}
+	} else {
+		llm := httptest.NewServer(recordedLLM(b.LLM))
+		defer llm.Close()
+		openaiBase = llm.URL
+	}
+
+	dir, err := os.MkdirTemp("", "replay-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	rec, err := chatapi.OpenRecorder(dir, 1)
+	if err != nil {
+		return nil, err
+	}
+	h := chatapi.NewHandler(logger, "", openaiKey, model, openaiBase, mcpSrv.URL+"/")
+	h.SetRecorder(rec)
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(b.Request))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	id := w.Header().Get("X-Recording-ID")
+	if id == "" {
+		return nil, fmt.Errorf("chat request was not recorded (status %d: %s)", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+	got, err := readBundle(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := compare(b, got, opts.verbose)
+	for _, m := range rp.Misses() {
+		diffs = append(diffs, "no recorded analytics response for "+m)
+	}
+	return diffs, nil
+}
+
+// recordedBase is the scheme and host of the first recorded analytics request.
+func recordedBase(exchanges []protocol.HTTPExchange) string {
+	for _, ex := range exchanges {
+		if u, err := url.Parse(ex.URL); err == nil && u.Host != "" {
+			return u.Scheme + "://" + u.Host
+		}
+	}
+	return "http://replay.invalid"
+}
+
+func recordedModel(b *chatapi.Bundle) string {
+	for _, ex := range b.LLM {
+		var req struct {
+			Model string `json:"model"`
+		}
+		if json.Unmarshal(ex.Request, &req) == nil && req.Model != "" {
+			return req.Model
+		}
+	}
+	return "gpt-4o-mini"
+}
+
+// recordedLLM answers chat completions with the recorded replies, in order.
+func recordedLLM(exchanges []chatapi.LLMExchange) http.Handler {
+	next := 0
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if next >= len(exchanges) {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, `{"error":{"message":"replay: only %d LLM calls were recorded","type":"api_error"}}`, len(exchanges))
+			return
+		}
+		ex := exchanges[next]
+		next++
+		if ex.Error != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = w.Write(ex.Response)
+	})
+}
+
+func envOr(getenv func(string) string, key, def string) string {
+	if v := strings.TrimSpace(getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
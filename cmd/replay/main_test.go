@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatapi"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(k string) string { return vars[k] }
+}
+
+func TestParseArgs(t *testing.T) {
+	cases := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		err   string
+		paths int
+		check func(options) bool
+	}{
+		{"defaults", []string{"a.json"}, nil, "", 1, func(o options) bool { return !o.live && o.openaiBase == "https://api.openai.com/v1" }},
+		{"base from env", []string{"a.json", "dir"}, map[string]string{"OPENAI_BASE_URL": "http://llm"}, "", 2, func(o options) bool { return o.openaiBase == "http://llm" }},
+		{"live", []string{"--live", "--model", "gpt-5", "a.json"}, map[string]string{"OPENAI_API_KEY": "sk"}, "", 1, func(o options) bool { return o.live && o.model == "gpt-5" && o.openaiKey == "sk" }},
+		{"live without key", []string{"--live", "a.json"}, nil, "--live needs OPENAI_API_KEY", 0, nil},
+		{"no recordings", nil, nil, "usage: replay", 0, nil},
+		{"unknown flag", []string{"--nope", "a.json"}, nil, "flag provided but not defined", 0, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			opts, paths, err := parseArgs(c.args, env(c.env), &stderr)
+			if c.err != "" {
+				if err == nil || !strings.Contains(stderr.String(), c.err) {
+					t.Fatalf("err %v, stderr %q, want %q", err, stderr.String(), c.err)
+				}
+				return
+			}
+			if err != nil || len(paths) != c.paths || !c.check(opts) {
+				t.Fatalf("got %+v %q %v", opts, paths, err)
+			}
+		})
+	}
+}
+
+func TestBundleFilesExpandsDirectoriesInOrder(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.json", "a.json", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	one := filepath.Join(dir, "notes.txt")
+	files, err := bundleFiles([]string{one, dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{one, filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Fatalf("files %q, want %q", files, want)
+	}
+	if _, err := bundleFiles([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Fatal("missing path accepted")
+	}
+}
+
+func TestRecordedDefaults(t *testing.T) {
+	exchanges := []protocol.HTTPExchange{{URL: "not a url\x7f"}, {URL: "https://core.example:8443/api/v1/x?y=1"}}
+	if got := recordedBase(exchanges); got != "https://core.example:8443" {
+		t.Fatalf("recordedBase = %q", got)
+	}
+	if got := recordedBase(nil); got != "http://replay.invalid" {
+		t.Fatalf("recordedBase(nil) = %q", got)
+	}
+	b := &chatapi.Bundle{LLM: []chatapi.LLMExchange{{Request: json.RawMessage(`{}`)}, {Request: json.RawMessage(`{"model":"gpt-4.1"}`)}}}
+	if got := recordedModel(b); got != "gpt-4.1" {
+		t.Fatalf("recordedModel = %q", got)
+	}
+	if got := recordedModel(&chatapi.Bundle{}); got != "gpt-4o-mini" {
+		t.Fatalf("recordedModel without exchanges = %q", got)
+	}
+}
+
+func TestRecordedLLMAnswersInOrder(t *testing.T) {
+	srv := httptest.NewServer(recordedLLM([]chatapi.LLMExchange{
+		{Response: json.RawMessage(`{"n":1}`)},
+		{Response: json.RawMessage(`{"error":{}}`), Error: "openai status 500"},
+	}))
+	defer srv.Close()
+	for _, want := range []struct {
+		status int
+		body   string
+	}{{200, `{"n":1}`}, {500, `{"error":{}}`}, {500, "only 2 LLM calls were recorded"}} {
+		resp, err := http.Post(srv.URL, "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != want.status || !strings.Contains(string(body), want.body) {
+			t.Fatalf("got %d %s, want %d %s", resp.StatusCode, body, want.status, want.body)
+		}
+	}
+}
+
+// writeBundle writes b into dir as <name>.json.
+func writeBundle(t *testing.T, dir, name string, b *chatapi.Bundle) {
+	t.Helper()
+	raw, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// answerBundle is a recording of a chat request the model answered without tools.
+func answerBundle(id string, status int) *chatapi.Bundle {
+	reply, _ := json.Marshal(chatapi.ChatCompletionResponse{
+		ID: "chatcmpl-1", Object: "chat.completion", Model: "gpt-4o-mini",
+		Choices: []chatapi.ChatChoice{{Message: chatapi.OAChatMessage{Role: "assistant", Content: "Hello."}, FinishReason: "stop"}},
+	})
+	return &chatapi.Bundle{
+		ID: id, Time: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), Status: status,
+		Request: json.RawMessage(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}]}`),
+		LLM:     []chatapi.LLMExchange{{Request: json.RawMessage(`{"model":"gpt-4o-mini"}`), Response: reply}},
+	}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	t.Setenv("PAYRAM_ANALYTICS_BASE_URL", "")
+	t.Setenv("PAYRAM_ANALYTICS_TOKEN", "")
+	dir := t.TempDir()
+	writeBundle(t, dir, "1-ok", answerBundle("rec-ok", http.StatusOK))
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{dir}, env(nil), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s%s", code, stdout.String(), stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, "ok    rec-ok (0 tool calls)") || !strings.Contains(out, "1 of 1 recordings replayed cleanly") {
+		t.Fatalf("output %q", out)
+	}
+
+	// A status change and an unreadable file both fail the run.
+	writeBundle(t, dir, "2-status", answerBundle("rec-status", http.StatusBadGateway))
+	if err := os.WriteFile(filepath.Join(dir, "3-bad.json"), []byte(`{"id":"x"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := run([]string{dir}, env(nil), &stdout, &stderr); code != 1 {
+		t.Fatalf("exit %d, want 1: %s", code, stdout.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{"FAIL  rec-status\n      status 200, recorded 502", "ERROR " + filepath.Join(dir, "3-bad.json") + ": not a recording bundle", "1 of 3 recordings replayed cleanly"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	if code := run(nil, env(nil), &stdout, &stderr); code != 2 {
+		t.Fatalf("no arguments: exit %d, want 2", code)
+	}
+	if code := run([]string{filepath.Join(dir, "missing")}, env(nil), &stdout, &stderr); code != 2 {
+		t.Fatalf("missing path: exit %d, want 2", code)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// agentClient reads the agent admin API, whose responses are {ok, data, error} envelopes.
+type agentClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newAgentClient(baseURL, token string) *agentClient {
+	return &agentClient{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, http: &http.Client{}}
+}
+
+// childVersion is one child in GET /admin/version.
+type childVersion struct {
+	Info *struct {
+		Version string `json:"version"`
+	} `json:"info"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Ready       bool   `json:"ready"`
+	Drift       bool   `json:"drift"`
+	DriftReason string `json:"drift_reason"`
+}
+
+func (c childVersion) version() string {
+	switch {
+	case c.Info != nil:
+		return c.Info.Version
+	case c.Error != nil:
+		return "unknown (" + c.Error.Message + ")"
+	}
+	return "unknown"
+}
+
+// get decodes the data of GET path into out.
+func (c *agentClient) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("X-MCP-Key", c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("call agent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var env struct {
+		Ok    bool            `json:"ok"`
+		Data  json.RawMessage `json:"data"`
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("agent returned HTTP %d without a JSON envelope: %w", resp.StatusCode, err)
+	}
+	if !env.Ok || env.Error != nil {
+		if env.Error != nil {
+			return fmt.Errorf("%s: %s (HTTP %d)", env.Error.Code, env.Error.Message, resp.StatusCode)
+		}
+		return fmt.Errorf("request failed (HTTP %d)", resp.StatusCode)
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("decode %s response: %w", path, err)
+	}
+	return nil
+}
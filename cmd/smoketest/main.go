@@ -0,0 +1,339 @@
+// Command smoketest checks a running deployment end to end: the MCP server lists and runs a
+// tool, the chat API answers a question by calling a tool, and the agent is healthy, runs the
+// release it recorded and can check for updates. It prints a report and exits 1 when any
+// check fails, so it can gate an install or an update.
+//
+//	smoketest
+//	smoketest --chat-url https://chat.example.com --agent-url http://10.0.0.5:9900
+//	smoketest --skip chat --json
+//
+// Flags default to the environment, then to the config file shared with payramctl and
+// agentctl.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+	"github.com/payram/payram-analytics-mcp-server/internal/cliconfig"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/pkg/chatclient"
+)
+
+// Check groups --skip accepts.
+var groups = []string{"mcp", "chat", "agent", "update"}
+
+const defaultPrompt = "How many transactions were there in the last 7 days?"
+
+type options struct {
+	mcpURL     string
+	token      string
+	tool       string
+	toolArgs   string
+	chatURL    string
+	chatKey    string
+	prompt     string
+	agentURL   string
+	agentToken string
+	channel    string
+	skip       string
+	timeout    time.Duration
+	jsonOut    bool
+}
+
+// result is the outcome of one check.
+type result struct {
+	Name       string `json:"name"`
+	Group      string `json:"group"`
+	Status     string `json:"status"` // pass, fail or skip
+	DurationMS int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+type report struct {
+	OK     bool     `json:"ok"`
+	Checks []result `json:"checks"`
+}
+
+// check is one probe; it returns a one-line summary of what it saw.
+type check struct {
+	name  string
+	group string
+	run   func(ctx context.Context) (string, error)
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	var cfg cliconfig.Config
+	configPath, err := cliconfig.Path()
+	if err == nil {
+		cfg, err = cliconfig.Load(configPath)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], cfg, os.Getenv, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run parses argv, runs the checks and prints the report to stdout. It returns the exit
+// code: 0 when every check that ran passed, 1 when any failed, and 2 for usage errors.
+func run(ctx context.Context, argv []string, cfg cliconfig.Config, getenv func(string) string, stdout, stderr io.Writer) int {
+	var opts options
+	fs := flag.NewFlagSet("smoketest", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.mcpURL, "mcp-url", envOr(getenv, "MCP_SERVER_URL", firstNonEmpty(cfg.MCP.URL, cliconfig.DefaultMCPURL)), "MCP server HTTP endpoint (default MCP_SERVER_URL, else the config file)")
+	fs.StringVar(&opts.token, "token", envOr(getenv, "PAYRAM_ANALYTICS_TOKEN", cfg.MCP.Token), "analytics token passed to the tool call (default PAYRAM_ANALYTICS_TOKEN, else the config file)")
+	fs.StringVar(&opts.tool, "tool", "payram_numbers_summary", "tool the MCP checks list and call")
+	fs.StringVar(&opts.toolArgs, "tool-args", `{}`, "JSON arguments for the tool call")
+	fs.StringVar(&opts.chatURL, "chat-url", envOr(getenv, "CHAT_API_URL", "http://localhost:2358"), "chat API base URL (default CHAT_API_URL)")
+	fs.StringVar(&opts.chatKey, "chat-key", envOr(getenv, "CHAT_API_KEY", ""), "chat API key (default CHAT_API_KEY)")
+	fs.StringVar(&opts.prompt, "prompt", defaultPrompt, "question for the chat check; it must make the assistant call a tool")
+	fs.StringVar(&opts.agentURL, "agent-url", envOr(getenv, "PAYRAM_AGENT_URL", firstNonEmpty(cfg.Agent.URL, cliconfig.DefaultAgentURL)), "agent admin API URL (default PAYRAM_AGENT_URL, else the config file)")
+	fs.StringVar(&opts.agentToken, "agent-token", envOr(getenv, "PAYRAM_AGENT_ADMIN_TOKEN", cfg.Agent.AdminToken), "agent admin token (default PAYRAM_AGENT_ADMIN_TOKEN, else the config file)")
+	fs.StringVar(&opts.channel, "channel", cfg.Agent.Channel, "update channel to check (default: the agent's)")
+	fs.StringVar(&opts.skip, "skip", "", "comma-separated check groups to skip: "+strings.Join(groups, ", "))
+	fs.DurationVar(&opts.timeout, "timeout", 2*time.Minute, "time limit for each check")
+	fs.BoolVar(&opts.jsonOut, "json", false, "print the report as JSON")
+	if err := fs.Parse(argv); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return 2
+	}
+
+	skip := map[string]bool{}
+	for _, g := range strings.Split(opts.skip, ",") {
+		if g = strings.TrimSpace(g); g == "" {
+			continue
+		}
+		if !slices.Contains(groups, g) {
+			fmt.Fprintf(stderr, "invalid --skip %q: groups are %s\n", g, strings.Join(groups, ", "))
+			return 2
+		}
+		skip[g] = true
+	}
+	var toolArgs map[string]any
+	if err := json.Unmarshal([]byte(opts.toolArgs), &toolArgs); err != nil {
+		fmt.Fprintf(stderr, "invalid --tool-args: %v\n", err)
+		return 2
+	}
+
+	rep := runChecks(ctx, checks(opts, toolArgs), skip, opts.timeout)
+	if opts.jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+	} else {
+		printReport(stdout, rep)
+	}
+	if !rep.OK {
+		return 1
+	}
+	return 0
+}
+
+// checks lists the probes in the order they run: each layer before the ones built on it.
+func checks(opts options, toolArgs map[string]any) []check {
+	mcp := chatserver.NewMCPClient(opts.mcpURL)
+	chat := chatclient.New(opts.chatURL, opts.chatKey)
+	agent := newAgentClient(opts.agentURL, opts.agentToken)
+
+	return []check{
+		{"agent health", "agent", func(ctx context.Context) (string, error) {
+			var data struct {
+				Status string `json:"status"`
+			}
+			if err := agent.get(ctx, "/health", &data); err != nil {
+				return "", err
+			}
+			if data.Status != "healthy" {
+				return "", fmt.Errorf("status %q", data.Status)
+			}
+			return data.Status, nil
+		}},
+		{"agent version", "agent", func(ctx context.Context) (string, error) {
+			var data struct {
+				Agent struct {
+					Version string `json:"version"`
+				} `json:"agent"`
+				Chat childVersion `json:"chat"`
+				MCP  childVersion `json:"mcp"`
+			}
+			if err := agent.get(ctx, "/admin/version", &data); err != nil {
+				return "", err
+			}
+			summary := fmt.Sprintf("agent %s, chat %s, mcp %s", data.Agent.Version, data.Chat.version(), data.MCP.version())
+			var problems []string
+			for _, c := range []struct {
+				name string
+				v    childVersion
+			}{{"chat", data.Chat}, {"mcp", data.MCP}} {
+				if !c.v.Ready {
+					problems = append(problems, c.name+" not ready")
+				}
+				if c.v.Drift {
+					problems = append(problems, fmt.Sprintf("%s drift: %s", c.name, c.v.DriftReason))
+				}
+			}
+			if len(problems) > 0 {
+				return "", fmt.Errorf("%s: %s", summary, strings.Join(problems, "; "))
+			}
+			return summary, nil
+		}},
+		{"update check", "update", func(ctx context.Context) (string, error) {
+			var data struct {
+				Available      bool   `json:"available"`
+				CurrentVersion string `json:"current_version"`
+				TargetVersion  string `json:"target_version"`
+				Revoked        bool   `json:"revoked"`
+			}
+			path := "/admin/update/available"
+			if opts.channel != "" {
+				path += "?channel=" + opts.channel
+			}
+			if err := agent.get(ctx, path, &data); err != nil {
+				return "", err
+			}
+			summary := fmt.Sprintf("installed %s, published %s", data.CurrentVersion, data.TargetVersion)
+			if data.Available {
+				summary += " (update available)"
+			}
+			if data.Revoked {
+				summary += " (revoked)"
+			}
+			return summary, nil
+		}},
+		{"mcp tools/list", "mcp", func(ctx context.Context) (string, error) {
+			tools, err := mcp.ListTools(ctx)
+			if err != nil {
+				return "", err
+			}
+			if !slices.ContainsFunc(tools, func(t protocol.ToolDescriptor) bool { return t.Name == opts.tool }) {
+				return "", fmt.Errorf("%d tools, but not %s", len(tools), opts.tool)
+			}
+			return fmt.Sprintf("%d tools", len(tools)), nil
+		}},
+		{"mcp tools/call", "mcp", func(ctx context.Context) (string, error) {
+			args := map[string]any{}
+			for k, v := range toolArgs {
+				args[k] = v
+			}
+			if _, set := args["token"]; !set && opts.token != "" {
+				args["token"] = opts.token
+			}
+			res, err := mcp.CallTool(ctx, opts.tool, args)
+			if err != nil {
+				return "", err
+			}
+			if len(res.Content) == 0 {
+				return "", errors.New("empty result")
+			}
+			return fmt.Sprintf("%s: %d content parts", opts.tool, len(res.Content)), nil
+		}},
+		{"chat completion", "chat", func(ctx context.Context) (string, error) {
+			resp, err := chat.CreateChatCompletion(ctx, chatclient.ChatCompletionRequest{
+				Messages:         []chatclient.Message{{Role: "user", Content: opts.prompt}},
+				IncludeToolTrace: true,
+			})
+			if err != nil {
+				return "", err
+			}
+			var names []string
+			for _, tr := range resp.ToolTrace {
+				if tr.Error != "" {
+					return "", fmt.Errorf("tool %s failed: %s", tr.Name, tr.Error)
+				}
+				names = append(names, tr.Name)
+			}
+			switch {
+			case len(names) == 0:
+				return "", errors.New("the answer called no tool")
+			case strings.TrimSpace(resp.Text()) == "":
+				return "", errors.New("empty answer")
+			}
+			return "called " + strings.Join(names, ", "), nil
+		}},
+	}
+}
+
+// runChecks executes the checks in order, each bounded by timeout. Later checks still run after a
+// failure, so the report shows every broken layer at once.
+func runChecks(ctx context.Context, checks []check, skip map[string]bool, timeout time.Duration) report {
+	rep := report{OK: true}
+	for _, c := range checks {
+		res := result{Name: c.name, Group: c.group}
+		if skip[c.group] {
+			res.Status = "skip"
+			rep.Checks = append(rep.Checks, res)
+			continue
+		}
+		cctx, cancel := context.WithTimeout(ctx, timeout)
+		started := time.Now()
+		detail, err := c.run(cctx)
+		cancel()
+		res.DurationMS = time.Since(started).Milliseconds()
+		res.Status, res.Detail = "pass", detail
+		if err != nil {
+			res.Status, res.Detail = "fail", err.Error()
+			rep.OK = false
+		}
+		rep.Checks = append(rep.Checks, res)
+	}
+	return rep
+}
+
+func printReport(out io.Writer, rep report) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tTIME\tDETAIL")
+	for _, r := range rep.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%dms\t%s\n", r.Name, strings.ToUpper(r.Status), r.DurationMS, r.Detail)
+	}
+	_ = tw.Flush()
+	if rep.OK {
+		fmt.Fprintln(out, "\nall checks passed")
+		return
+	}
+	var failed []string
+	for _, r := range rep.Checks {
+		if r.Status == "fail" {
+			failed = append(failed, r.Name)
+		}
+	}
+	fmt.Fprintf(out, "\nFAILED: %s\n", strings.Join(failed, ", "))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func envOr(getenv func(string) string, key, def string) string {
+	if v := strings.TrimSpace(getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
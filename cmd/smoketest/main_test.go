@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/cliconfig"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// deployment fakes the MCP server, chat API and agent a smoke test talks to. Tests break
+// one layer at a time by changing its fields before running the checks.
+type deployment struct {
+	mcp, chat, agent *httptest.Server
+
+	tools      []string
+	toolResult protocol.CallResult
+	chatReply  map[string]any
+	health     string
+	version    map[string]any
+	agentErr   bool
+	agentDelay time.Duration
+
+	mu        sync.Mutex
+	toolArgs  []map[string]any
+	chatReqs  []map[string]any
+	chatKeys  []string
+	agentReqs []*http.Request
+}
+
+func newDeployment(t *testing.T) *deployment {
+	d := &deployment{
+		tools:      []string{"payram_daily_stats", "payram_numbers_summary"},
+		toolResult: protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: "42 payments"}}},
+		chatReply: map[string]any{
+			"choices":    []any{map[string]any{"message": map[string]any{"role": "assistant", "content": "There were 42."}}},
+			"tool_trace": []any{map[string]any{"name": "payram_transaction_counts"}},
+		},
+		health: "healthy",
+		version: map[string]any{
+			"agent": map[string]any{"version": "1.4.0"},
+			"chat":  map[string]any{"info": map[string]any{"version": "1.4.0"}, "ready": true},
+			"mcp":   map[string]any{"info": map[string]any{"version": "1.4.0"}, "ready": true},
+		},
+	}
+	d.mcp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req protocol.Request
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		resp := protocol.Response{JSONRPC: "2.0", ID: req.ID}
+		switch req.Method {
+		case "tools/list":
+			var list protocol.ListResult
+			for _, name := range d.tools {
+				list.Tools = append(list.Tools, protocol.ToolDescriptor{Name: name})
+			}
+			resp.Result = list
+		case "tools/call":
+			var p protocol.CallParams
+			_ = json.Unmarshal(req.Params, &p)
+			args := map[string]any{}
+			_ = json.Unmarshal(p.Args, &args)
+			d.mu.Lock()
+			d.toolArgs = append(d.toolArgs, args)
+			d.mu.Unlock()
+			resp.Result = d.toolResult
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	d.chat = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		d.mu.Lock()
+		d.chatReqs = append(d.chatReqs, req)
+		d.chatKeys = append(d.chatKeys, r.Header.Get("X-MCP-Key"))
+		d.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(d.chatReply)
+	}))
+	d.agent = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		d.agentReqs = append(d.agentReqs, r)
+		d.mu.Unlock()
+		if d.agentDelay > 0 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(d.agentDelay):
+			}
+			return
+		}
+		if d.agentErr {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": map[string]any{"code": "unauthorized", "message": "bad token"}})
+			return
+		}
+		var data any
+		switch r.URL.Path {
+		case "/health":
+			data = map[string]any{"status": d.health}
+		case "/admin/version":
+			data = d.version
+		case "/admin/update/available":
+			data = map[string]any{"available": true, "current_version": "1.4.0", "target_version": "1.5.0"}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "data": data})
+	}))
+	for _, s := range []*httptest.Server{d.mcp, d.chat, d.agent} {
+		t.Cleanup(s.Close)
+	}
+	return d
+}
+
+// smoketest runs the command line against d, with the URLs in the environment, and returns
+// the exit code and output.
+func (d *deployment) smoketest(t *testing.T, argv ...string) (int, string, string) {
+	t.Helper()
+	env := map[string]string{
+		"MCP_SERVER_URL":           d.mcp.URL,
+		"CHAT_API_URL":             d.chat.URL,
+		"CHAT_API_KEY":             "chat-key",
+		"PAYRAM_AGENT_URL":         d.agent.URL,
+		"PAYRAM_AGENT_ADMIN_TOKEN": "admin-token",
+		"PAYRAM_ANALYTICS_TOKEN":   "env-token",
+	}
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), argv, cliconfig.Config{}, func(k string) string { return env[k] }, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// statuses maps each check in a text report to its status.
+func statuses(out string) map[string]string {
+	got := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		for _, c := range checks(options{}, nil) {
+			if rest, ok := strings.CutPrefix(line, c.name+" "); ok {
+				got[c.name] = strings.Fields(rest)[0]
+			}
+		}
+	}
+	return got
+}
+
+func TestAllChecksPass(t *testing.T) {
+	d := newDeployment(t)
+	code, out, errOut := d.smoketest(t, "--tool-args", `{"days":7}`, "--channel", "beta")
+	if code != 0 {
+		t.Fatalf("exit %d: %s%s", code, out, errOut)
+	}
+	want := map[string]string{"agent health": "PASS", "agent version": "PASS", "update check": "PASS", "mcp tools/list": "PASS", "mcp tools/call": "PASS", "chat completion": "PASS"}
+	if got := statuses(out); !maps.Equal(got, want) {
+		t.Fatalf("statuses %v\n%s", got, out)
+	}
+	for _, s := range []string{"agent 1.4.0, chat 1.4.0, mcp 1.4.0", "installed 1.4.0, published 1.5.0 (update available)", "2 tools", "payram_numbers_summary: 1 content parts", "called payram_transaction_counts", "all checks passed"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("report lacks %q:\n%s", s, out)
+		}
+	}
+
+	if len(d.toolArgs) != 1 || d.toolArgs[0]["days"] != float64(7) || d.toolArgs[0]["token"] != "env-token" {
+		t.Errorf("tool called with %v", d.toolArgs)
+	}
+	if len(d.chatReqs) != 1 || d.chatKeys[0] != "chat-key" || d.chatReqs[0]["include_tool_trace"] != true ||
+		!strings.Contains(mustJSON(d.chatReqs[0]["messages"]), defaultPrompt) {
+		t.Errorf("chat got %v with key %v", d.chatReqs, d.chatKeys)
+	}
+	for _, r := range d.agentReqs {
+		if r.Header.Get("X-MCP-Key") != "admin-token" {
+			t.Errorf("%s sent without the admin token", r.URL)
+		}
+		if r.URL.Path == "/admin/update/available" && r.URL.Query().Get("channel") != "beta" {
+			t.Errorf("update check for %s", r.URL)
+		}
+	}
+}
+
+func TestFailedChecksExitOne(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(d *deployment)
+		check  string
+		detail string
+	}{
+		{"agent unhealthy", func(d *deployment) { d.health = "degraded" }, "agent health", `status "degraded"`},
+		{"child not ready", func(d *deployment) {
+			d.version["chat"] = map[string]any{"error": map[string]any{"message": "connection refused"}}
+		}, "agent version", "chat unknown (connection refused), mcp 1.4.0: chat not ready"},
+		{"drift", func(d *deployment) {
+			d.version["mcp"] = map[string]any{"info": map[string]any{"version": "1.3.0"}, "ready": true, "drift": true, "drift_reason": "expected 1.4.0"}
+		}, "agent version", "mcp drift: expected 1.4.0"},
+		{"tool not listed", func(d *deployment) { d.tools = d.tools[:1] }, "mcp tools/list", "1 tools, but not payram_numbers_summary"},
+		{"empty tool result", func(d *deployment) { d.toolResult = protocol.CallResult{} }, "mcp tools/call", "empty result"},
+		{"no tool called", func(d *deployment) { delete(d.chatReply, "tool_trace") }, "chat completion", "the answer called no tool"},
+		{"tool failed", func(d *deployment) {
+			d.chatReply["tool_trace"] = []any{map[string]any{"name": "payram_transaction_counts", "error": "core returned 503"}}
+		}, "chat completion", "tool payram_transaction_counts failed: core returned 503"},
+		{"empty answer", func(d *deployment) {
+			d.chatReply["choices"] = []any{map[string]any{"message": map[string]any{"content": "  "}}}
+		}, "chat completion", "empty answer"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := newDeployment(t)
+			c.change(d)
+			code, out, _ := d.smoketest(t)
+			if code != 1 {
+				t.Fatalf("exit %d:\n%s", code, out)
+			}
+			// The other checks still run and pass.
+			for name, status := range statuses(out) {
+				if want := map[bool]string{true: "FAIL", false: "PASS"}[name == c.check]; status != want {
+					t.Errorf("%s: %s, want %s", name, status, want)
+				}
+			}
+			if !strings.Contains(out, c.detail) || !strings.HasSuffix(out, "\nFAILED: "+c.check+"\n") {
+				t.Fatalf("report lacks %q or the failure summary:\n%s", c.detail, out)
+			}
+		})
+	}
+}
+
+func TestAgentErrorsFailEveryAgentCheck(t *testing.T) {
+	d := newDeployment(t)
+	d.agentErr = true
+	code, out, _ := d.smoketest(t, "--json")
+	if code != 1 {
+		t.Fatalf("exit %d:\n%s", code, out)
+	}
+	var rep report
+	if err := json.Unmarshal([]byte(out), &rep); err != nil {
+		t.Fatalf("decode %s: %v", out, err)
+	}
+	if rep.OK || len(rep.Checks) != 6 {
+		t.Fatalf("report %+v", rep)
+	}
+	for _, r := range rep.Checks {
+		agent := r.Group == "agent" || r.Group == "update"
+		if agent && (r.Status != "fail" || r.Detail != "unauthorized: bad token (HTTP 401)") {
+			t.Errorf("%s: %+v", r.Name, r)
+		}
+		if !agent && r.Status != "pass" {
+			t.Errorf("%s: %+v", r.Name, r)
+		}
+	}
+}
+
+func TestChecksTimeOut(t *testing.T) {
+	d := newDeployment(t)
+	d.agentDelay = 5 * time.Second
+	start := time.Now()
+	code, out, _ := d.smoketest(t, "--timeout", "100ms", "--skip", "update,mcp,chat")
+	if code != 1 || !strings.Contains(out, "deadline exceeded") {
+		t.Fatalf("exit %d:\n%s", code, out)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("took %s with a 100ms timeout", elapsed)
+	}
+}
+
+func TestSkippedChecksDoNotRun(t *testing.T) {
+	d := newDeployment(t)
+	// A broken chat API does not matter when its checks are skipped.
+	delete(d.chatReply, "tool_trace")
+	code, out, errOut := d.smoketest(t, "--skip", " chat , update")
+	if code != 0 {
+		t.Fatalf("exit %d: %s%s", code, out, errOut)
+	}
+	got := statuses(out)
+	if got["chat completion"] != "SKIP" || got["update check"] != "SKIP" || got["agent health"] != "PASS" {
+		t.Fatalf("statuses %v", got)
+	}
+	if len(d.chatReqs) != 0 {
+		t.Fatalf("skipped chat check sent %d requests", len(d.chatReqs))
+	}
+	for _, r := range d.agentReqs {
+		if r.URL.Path == "/admin/update/available" {
+			t.Fatal("skipped update check ran")
+		}
+	}
+}
+
+func TestUsageErrorsExitTwo(t *testing.T) {
+	cases := []struct {
+		argv []string
+		err  string
+	}{
+		{[]string{"--skip", "mcp,bogus"}, `invalid --skip "bogus"`},
+		{[]string{"--tool-args", "{"}, "invalid --tool-args"},
+		{[]string{"extra"}, "unexpected arguments: extra"},
+		{[]string{"--no-such-flag"}, "flag provided but not defined"},
+	}
+	for _, c := range cases {
+		d := newDeployment(t)
+		code, out, errOut := d.smoketest(t, c.argv...)
+		if code != 2 || !strings.Contains(errOut, c.err) || out != "" {
+			t.Errorf("%v: exit %d, stdout %q, stderr %q", c.argv, code, out, errOut)
+		}
+		if len(d.toolArgs)+len(d.chatReqs)+len(d.agentReqs) != 0 {
+			t.Errorf("%v: checks ran after a usage error", c.argv)
+		}
+	}
+}
+
+func TestConfigFileFallback(t *testing.T) {
+	d := newDeployment(t)
+	cfg := cliconfig.Config{
+		MCP:   cliconfig.MCP{URL: d.mcp.URL, Token: "config-token"},
+		Agent: cliconfig.Agent{URL: d.agent.URL, AdminToken: "config-admin", Channel: "stable"},
+	}
+	env := map[string]string{"CHAT_API_URL": d.chat.URL}
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), nil, cfg, func(k string) string { return env[k] }, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s%s", code, stdout.String(), stderr.String())
+	}
+	if d.toolArgs[0]["token"] != "config-token" {
+		t.Errorf("tool called with %v", d.toolArgs[0])
+	}
+	for _, r := range d.agentReqs {
+		if r.Header.Get("X-MCP-Key") != "config-admin" {
+			t.Errorf("%s sent with %q", r.URL, r.Header.Get("X-MCP-Key"))
+		}
+		if r.URL.Path == "/admin/update/available" && r.URL.Query().Get("channel") != "stable" {
+			t.Errorf("update check for %s", r.URL)
+		}
+	}
+}
+
+func mustJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
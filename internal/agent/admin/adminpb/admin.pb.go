@@ -0,0 +1,1491 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: payram/agent/admin/v1/admin.proto
+
+// The agent's admin API over gRPC. Every call is served by the same code as its HTTP
+// endpoint under /admin (docs/agent/README.md), so the fields below carry the same names and
+// meaning as the JSON it returns. Errors carry the HTTP API's error code as the reason of a
+// google.rpc.ErrorInfo detail in the "agent.payram.com" domain.
+
+package adminpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{0}
+}
+
+type GetStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Update        *UpdateStatus          `protobuf:"bytes,1,opt,name=update,proto3" json:"update,omitempty"`
+	Children      []*ChildStatus         `protobuf:"bytes,2,rep,name=children,proto3" json:"children,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusResponse) ProtoMessage() {}
+
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetStatusResponse) GetUpdate() *UpdateStatus {
+	if x != nil {
+		return x.Update
+	}
+	return nil
+}
+
+func (x *GetStatusResponse) GetChildren() []*ChildStatus {
+	if x != nil {
+		return x.Children
+	}
+	return nil
+}
+
+type UpdateStatus struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	CurrentVersion      string                 `protobuf:"bytes,1,opt,name=current_version,json=currentVersion,proto3" json:"current_version,omitempty"`
+	PreviousVersion     string                 `protobuf:"bytes,2,opt,name=previous_version,json=previousVersion,proto3" json:"previous_version,omitempty"`
+	CurrentChannel      string                 `protobuf:"bytes,3,opt,name=current_channel,json=currentChannel,proto3" json:"current_channel,omitempty"`
+	PreviousChannel     string                 `protobuf:"bytes,4,opt,name=previous_channel,json=previousChannel,proto3" json:"previous_channel,omitempty"`
+	LastSuccessVersion  string                 `protobuf:"bytes,5,opt,name=last_success_version,json=lastSuccessVersion,proto3" json:"last_success_version,omitempty"`
+	LastSuccessAt       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=last_success_at,json=lastSuccessAt,proto3" json:"last_success_at,omitempty"`
+	LastAttemptVersion  string                 `protobuf:"bytes,7,opt,name=last_attempt_version,json=lastAttemptVersion,proto3" json:"last_attempt_version,omitempty"`
+	LastAttemptAt       *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=last_attempt_at,json=lastAttemptAt,proto3" json:"last_attempt_at,omitempty"`
+	LastErrorCode       string                 `protobuf:"bytes,9,opt,name=last_error_code,json=lastErrorCode,proto3" json:"last_error_code,omitempty"`
+	LastErrorMessage    string                 `protobuf:"bytes,10,opt,name=last_error_message,json=lastErrorMessage,proto3" json:"last_error_message,omitempty"`
+	LastErrorAt         *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=last_error_at,json=lastErrorAt,proto3" json:"last_error_at,omitempty"`
+	InProgress          bool                   `protobuf:"varint,12,opt,name=in_progress,json=inProgress,proto3" json:"in_progress,omitempty"`
+	InProgressStartedAt *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=in_progress_started_at,json=inProgressStartedAt,proto3" json:"in_progress_started_at,omitempty"`
+	Canary              *Canary                `protobuf:"bytes,14,opt,name=canary,proto3" json:"canary,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *UpdateStatus) Reset() {
+	*x = UpdateStatus{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateStatus) ProtoMessage() {}
+
+func (x *UpdateStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateStatus.ProtoReflect.Descriptor instead.
+func (*UpdateStatus) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UpdateStatus) GetCurrentVersion() string {
+	if x != nil {
+		return x.CurrentVersion
+	}
+	return ""
+}
+
+func (x *UpdateStatus) GetPreviousVersion() string {
+	if x != nil {
+		return x.PreviousVersion
+	}
+	return ""
+}
+
+func (x *UpdateStatus) GetCurrentChannel() string {
+	if x != nil {
+		return x.CurrentChannel
+	}
+	return ""
+}
+
+func (x *UpdateStatus) GetPreviousChannel() string {
+	if x != nil {
+		return x.PreviousChannel
+	}
+	return ""
+}
+
+func (x *UpdateStatus) GetLastSuccessVersion() string {
+	if x != nil {
+		return x.LastSuccessVersion
+	}
+	return ""
+}
+
+func (x *UpdateStatus) GetLastSuccessAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSuccessAt
+	}
+	return nil
+}
+
+func (x *UpdateStatus) GetLastAttemptVersion() string {
+	if x != nil {
+		return x.LastAttemptVersion
+	}
+	return ""
+}
+
+func (x *UpdateStatus) GetLastAttemptAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastAttemptAt
+	}
+	return nil
+}
+
+func (x *UpdateStatus) GetLastErrorCode() string {
+	if x != nil {
+		return x.LastErrorCode
+	}
+	return ""
+}
+
+func (x *UpdateStatus) GetLastErrorMessage() string {
+	if x != nil {
+		return x.LastErrorMessage
+	}
+	return ""
+}
+
+func (x *UpdateStatus) GetLastErrorAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastErrorAt
+	}
+	return nil
+}
+
+func (x *UpdateStatus) GetInProgress() bool {
+	if x != nil {
+		return x.InProgress
+	}
+	return false
+}
+
+func (x *UpdateStatus) GetInProgressStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.InProgressStartedAt
+	}
+	return nil
+}
+
+func (x *UpdateStatus) GetCanary() *Canary {
+	if x != nil {
+		return x.Canary
+	}
+	return nil
+}
+
+// Canary is an applied release still in its soak period.
+type Canary struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Version           string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	PreviousVersion   string                 `protobuf:"bytes,2,opt,name=previous_version,json=previousVersion,proto3" json:"previous_version,omitempty"`
+	StartedAt         *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	Until             *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=until,proto3" json:"until,omitempty"`
+	MaxHealthFailures int32                  `protobuf:"varint,5,opt,name=max_health_failures,json=maxHealthFailures,proto3" json:"max_health_failures,omitempty"`
+	MaxCrashes        int32                  `protobuf:"varint,6,opt,name=max_crashes,json=maxCrashes,proto3" json:"max_crashes,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Canary) Reset() {
+	*x = Canary{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Canary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Canary) ProtoMessage() {}
+
+func (x *Canary) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Canary.ProtoReflect.Descriptor instead.
+func (*Canary) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Canary) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Canary) GetPreviousVersion() string {
+	if x != nil {
+		return x.PreviousVersion
+	}
+	return ""
+}
+
+func (x *Canary) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Canary) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *Canary) GetMaxHealthFailures() int32 {
+	if x != nil {
+		return x.MaxHealthFailures
+	}
+	return 0
+}
+
+func (x *Canary) GetMaxCrashes() int32 {
+	if x != nil {
+		return x.MaxCrashes
+	}
+	return 0
+}
+
+type ChildStatus struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Name      string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Pid       int32                  `protobuf:"varint,2,opt,name=pid,proto3" json:"pid,omitempty"`
+	StartTime *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	Restarts  int32                  `protobuf:"varint,4,opt,name=restarts,proto3" json:"restarts,omitempty"`
+	LastExit  *ExitInfo              `protobuf:"bytes,5,opt,name=last_exit,json=lastExit,proto3" json:"last_exit,omitempty"`
+	// Dependencies the child is held for before it may start.
+	WaitingFor    []string `protobuf:"bytes,6,rep,name=waiting_for,json=waitingFor,proto3" json:"waiting_for,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChildStatus) Reset() {
+	*x = ChildStatus{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChildStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChildStatus) ProtoMessage() {}
+
+func (x *ChildStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChildStatus.ProtoReflect.Descriptor instead.
+func (*ChildStatus) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ChildStatus) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ChildStatus) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *ChildStatus) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *ChildStatus) GetRestarts() int32 {
+	if x != nil {
+		return x.Restarts
+	}
+	return 0
+}
+
+func (x *ChildStatus) GetLastExit() *ExitInfo {
+	if x != nil {
+		return x.LastExit
+	}
+	return nil
+}
+
+func (x *ChildStatus) GetWaitingFor() []string {
+	if x != nil {
+		return x.WaitingFor
+	}
+	return nil
+}
+
+type ExitInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,2,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExitInfo) Reset() {
+	*x = ExitInfo{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExitInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExitInfo) ProtoMessage() {}
+
+func (x *ExitInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExitInfo.ProtoReflect.Descriptor instead.
+func (*ExitInfo) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ExitInfo) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *ExitInfo) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *ExitInfo) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type StreamLogsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "chat" or "mcp".
+	Component string `protobuf:"bytes,1,opt,name=component,proto3" json:"component,omitempty"`
+	// Buffered lines to send first; 0 means 200.
+	Tail          int32 `protobuf:"varint,2,opt,name=tail,proto3" json:"tail,omitempty"`
+	Follow        bool  `protobuf:"varint,3,opt,name=follow,proto3" json:"follow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamLogsRequest) Reset() {
+	*x = StreamLogsRequest{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamLogsRequest) ProtoMessage() {}
+
+func (x *StreamLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamLogsRequest.ProtoReflect.Descriptor instead.
+func (*StreamLogsRequest) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StreamLogsRequest) GetComponent() string {
+	if x != nil {
+		return x.Component
+	}
+	return ""
+}
+
+func (x *StreamLogsRequest) GetTail() int32 {
+	if x != nil {
+		return x.Tail
+	}
+	return 0
+}
+
+func (x *StreamLogsRequest) GetFollow() bool {
+	if x != nil {
+		return x.Follow
+	}
+	return false
+}
+
+type StreamLogsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Component     string                 `protobuf:"bytes,1,opt,name=component,proto3" json:"component,omitempty"`
+	Line          string                 `protobuf:"bytes,2,opt,name=line,proto3" json:"line,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamLogsResponse) Reset() {
+	*x = StreamLogsResponse{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamLogsResponse) ProtoMessage() {}
+
+func (x *StreamLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamLogsResponse.ProtoReflect.Descriptor instead.
+func (*StreamLogsResponse) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StreamLogsResponse) GetComponent() string {
+	if x != nil {
+		return x.Component
+	}
+	return ""
+}
+
+func (x *StreamLogsResponse) GetLine() string {
+	if x != nil {
+		return x.Line
+	}
+	return ""
+}
+
+type CheckUpdateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Defaults to PAYRAM_AGENT_UPDATE_CHANNEL, else stable.
+	Channel       string `protobuf:"bytes,1,opt,name=channel,proto3" json:"channel,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckUpdateRequest) Reset() {
+	*x = CheckUpdateRequest{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckUpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckUpdateRequest) ProtoMessage() {}
+
+func (x *CheckUpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckUpdateRequest.ProtoReflect.Descriptor instead.
+func (*CheckUpdateRequest) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CheckUpdateRequest) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+type CheckUpdateResponse struct {
+	state          protoimpl.MessageState  `protogen:"open.v1"`
+	Available      bool                    `protobuf:"varint,1,opt,name=available,proto3" json:"available,omitempty"`
+	CurrentVersion string                  `protobuf:"bytes,2,opt,name=current_version,json=currentVersion,proto3" json:"current_version,omitempty"`
+	TargetVersion  string                  `protobuf:"bytes,3,opt,name=target_version,json=targetVersion,proto3" json:"target_version,omitempty"`
+	Notes          string                  `protobuf:"bytes,4,opt,name=notes,proto3" json:"notes,omitempty"`
+	Revoked        bool                    `protobuf:"varint,5,opt,name=revoked,proto3" json:"revoked,omitempty"`
+	PayramCore     *CoreCompatibility      `protobuf:"bytes,6,opt,name=payram_core,json=payramCore,proto3" json:"payram_core,omitempty"`
+	Compat         *Compatibility          `protobuf:"bytes,7,opt,name=compat,proto3" json:"compat,omitempty"`
+	Components     *ComponentCompatibility `protobuf:"bytes,8,opt,name=components,proto3" json:"components,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CheckUpdateResponse) Reset() {
+	*x = CheckUpdateResponse{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckUpdateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckUpdateResponse) ProtoMessage() {}
+
+func (x *CheckUpdateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckUpdateResponse.ProtoReflect.Descriptor instead.
+func (*CheckUpdateResponse) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CheckUpdateResponse) GetAvailable() bool {
+	if x != nil {
+		return x.Available
+	}
+	return false
+}
+
+func (x *CheckUpdateResponse) GetCurrentVersion() string {
+	if x != nil {
+		return x.CurrentVersion
+	}
+	return ""
+}
+
+func (x *CheckUpdateResponse) GetTargetVersion() string {
+	if x != nil {
+		return x.TargetVersion
+	}
+	return ""
+}
+
+func (x *CheckUpdateResponse) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
+func (x *CheckUpdateResponse) GetRevoked() bool {
+	if x != nil {
+		return x.Revoked
+	}
+	return false
+}
+
+func (x *CheckUpdateResponse) GetPayramCore() *CoreCompatibility {
+	if x != nil {
+		return x.PayramCore
+	}
+	return nil
+}
+
+func (x *CheckUpdateResponse) GetCompat() *Compatibility {
+	if x != nil {
+		return x.Compat
+	}
+	return nil
+}
+
+func (x *CheckUpdateResponse) GetComponents() *ComponentCompatibility {
+	if x != nil {
+		return x.Components
+	}
+	return nil
+}
+
+type CoreCompatibility struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Min           string                 `protobuf:"bytes,1,opt,name=min,proto3" json:"min,omitempty"`
+	Max           string                 `protobuf:"bytes,2,opt,name=max,proto3" json:"max,omitempty"`
+	Compatible    bool                   `protobuf:"varint,3,opt,name=compatible,proto3" json:"compatible,omitempty"`
+	Reason        string                 `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoreCompatibility) Reset() {
+	*x = CoreCompatibility{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoreCompatibility) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoreCompatibility) ProtoMessage() {}
+
+func (x *CoreCompatibility) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoreCompatibility.ProtoReflect.Descriptor instead.
+func (*CoreCompatibility) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CoreCompatibility) GetMin() string {
+	if x != nil {
+		return x.Min
+	}
+	return ""
+}
+
+func (x *CoreCompatibility) GetMax() string {
+	if x != nil {
+		return x.Max
+	}
+	return ""
+}
+
+func (x *CoreCompatibility) GetCompatible() bool {
+	if x != nil {
+		return x.Compatible
+	}
+	return false
+}
+
+func (x *CoreCompatibility) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type Compatibility struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Compatible    bool                   `protobuf:"varint,1,opt,name=compatible,proto3" json:"compatible,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Ignored       bool                   `protobuf:"varint,3,opt,name=ignored,proto3" json:"ignored,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Compatibility) Reset() {
+	*x = Compatibility{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Compatibility) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Compatibility) ProtoMessage() {}
+
+func (x *Compatibility) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Compatibility.ProtoReflect.Descriptor instead.
+func (*Compatibility) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Compatibility) GetCompatible() bool {
+	if x != nil {
+		return x.Compatible
+	}
+	return false
+}
+
+func (x *Compatibility) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Compatibility) GetIgnored() bool {
+	if x != nil {
+		return x.Ignored
+	}
+	return false
+}
+
+type ComponentCompatibility struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Compatible    bool                   `protobuf:"varint,1,opt,name=compatible,proto3" json:"compatible,omitempty"`
+	Problems      []string               `protobuf:"bytes,2,rep,name=problems,proto3" json:"problems,omitempty"`
+	Warnings      []string               `protobuf:"bytes,3,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	Ignored       bool                   `protobuf:"varint,4,opt,name=ignored,proto3" json:"ignored,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ComponentCompatibility) Reset() {
+	*x = ComponentCompatibility{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ComponentCompatibility) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ComponentCompatibility) ProtoMessage() {}
+
+func (x *ComponentCompatibility) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ComponentCompatibility.ProtoReflect.Descriptor instead.
+func (*ComponentCompatibility) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ComponentCompatibility) GetCompatible() bool {
+	if x != nil {
+		return x.Compatible
+	}
+	return false
+}
+
+func (x *ComponentCompatibility) GetProblems() []string {
+	if x != nil {
+		return x.Problems
+	}
+	return nil
+}
+
+func (x *ComponentCompatibility) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *ComponentCompatibility) GetIgnored() bool {
+	if x != nil {
+		return x.Ignored
+	}
+	return false
+}
+
+type ApplyUpdateRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Channel string                 `protobuf:"bytes,1,opt,name=channel,proto3" json:"channel,omitempty"`
+	// Run every check and download, then report the plan without switching releases.
+	DryRun bool `protobuf:"varint,2,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	// Apply from another channel than the one PAYRAM_AGENT_UPDATE_CHANNEL pins.
+	ForceChannel bool `protobuf:"varint,3,opt,name=force_channel,json=forceChannel,proto3" json:"force_channel,omitempty"`
+	// Soak period in milliseconds; unset uses PAYRAM_AGENT_CANARY_SOAK_MS, 0 skips it.
+	CanarySoakMs  *int64 `protobuf:"varint,4,opt,name=canary_soak_ms,json=canarySoakMs,proto3,oneof" json:"canary_soak_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApplyUpdateRequest) Reset() {
+	*x = ApplyUpdateRequest{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApplyUpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApplyUpdateRequest) ProtoMessage() {}
+
+func (x *ApplyUpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApplyUpdateRequest.ProtoReflect.Descriptor instead.
+func (*ApplyUpdateRequest) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ApplyUpdateRequest) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *ApplyUpdateRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *ApplyUpdateRequest) GetForceChannel() bool {
+	if x != nil {
+		return x.ForceChannel
+	}
+	return false
+}
+
+func (x *ApplyUpdateRequest) GetCanarySoakMs() int64 {
+	if x != nil && x.CanarySoakMs != nil {
+		return *x.CanarySoakMs
+	}
+	return 0
+}
+
+type ApplyUpdateResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	UpdatedTo string                 `protobuf:"bytes,1,opt,name=updated_to,json=updatedTo,proto3" json:"updated_to,omitempty"`
+	Restarts  []*RestartResult       `protobuf:"bytes,2,rep,name=restarts,proto3" json:"restarts,omitempty"`
+	Warnings  []string               `protobuf:"bytes,3,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	// Set when the release is soaking as a canary.
+	Canary *Canary `protobuf:"bytes,4,opt,name=canary,proto3" json:"canary,omitempty"`
+	// Set instead of the fields above for a dry run: the plan as /admin/update/apply?dry_run=1
+	// reports it.
+	Plan          *structpb.Struct `protobuf:"bytes,5,opt,name=plan,proto3" json:"plan,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApplyUpdateResponse) Reset() {
+	*x = ApplyUpdateResponse{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApplyUpdateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApplyUpdateResponse) ProtoMessage() {}
+
+func (x *ApplyUpdateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApplyUpdateResponse.ProtoReflect.Descriptor instead.
+func (*ApplyUpdateResponse) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ApplyUpdateResponse) GetUpdatedTo() string {
+	if x != nil {
+		return x.UpdatedTo
+	}
+	return ""
+}
+
+func (x *ApplyUpdateResponse) GetRestarts() []*RestartResult {
+	if x != nil {
+		return x.Restarts
+	}
+	return nil
+}
+
+func (x *ApplyUpdateResponse) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *ApplyUpdateResponse) GetCanary() *Canary {
+	if x != nil {
+		return x.Canary
+	}
+	return nil
+}
+
+func (x *ApplyUpdateResponse) GetPlan() *structpb.Struct {
+	if x != nil {
+		return x.Plan
+	}
+	return nil
+}
+
+type RollbackRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RollbackRequest) Reset() {
+	*x = RollbackRequest{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RollbackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollbackRequest) ProtoMessage() {}
+
+func (x *RollbackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RollbackRequest.ProtoReflect.Descriptor instead.
+func (*RollbackRequest) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{15}
+}
+
+type RollbackResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RolledBackTo  string                 `protobuf:"bytes,1,opt,name=rolled_back_to,json=rolledBackTo,proto3" json:"rolled_back_to,omitempty"`
+	Restarts      []*RestartResult       `protobuf:"bytes,2,rep,name=restarts,proto3" json:"restarts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RollbackResponse) Reset() {
+	*x = RollbackResponse{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RollbackResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollbackResponse) ProtoMessage() {}
+
+func (x *RollbackResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RollbackResponse.ProtoReflect.Descriptor instead.
+func (*RollbackResponse) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RollbackResponse) GetRolledBackTo() string {
+	if x != nil {
+		return x.RolledBackTo
+	}
+	return ""
+}
+
+func (x *RollbackResponse) GetRestarts() []*RestartResult {
+	if x != nil {
+		return x.Restarts
+	}
+	return nil
+}
+
+type RestartResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Pid           int32                  `protobuf:"varint,2,opt,name=pid,proto3" json:"pid,omitempty"`
+	Ready         bool                   `protobuf:"varint,3,opt,name=ready,proto3" json:"ready,omitempty"`
+	StartMs       int64                  `protobuf:"varint,4,opt,name=start_ms,json=startMs,proto3" json:"start_ms,omitempty"`
+	ElapsedMs     int64                  `protobuf:"varint,5,opt,name=elapsed_ms,json=elapsedMs,proto3" json:"elapsed_ms,omitempty"`
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestartResult) Reset() {
+	*x = RestartResult{}
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestartResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestartResult) ProtoMessage() {}
+
+func (x *RestartResult) ProtoReflect() protoreflect.Message {
+	mi := &file_payram_agent_admin_v1_admin_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestartResult.ProtoReflect.Descriptor instead.
+func (*RestartResult) Descriptor() ([]byte, []int) {
+	return file_payram_agent_admin_v1_admin_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RestartResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RestartResult) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *RestartResult) GetReady() bool {
+	if x != nil {
+		return x.Ready
+	}
+	return false
+}
+
+func (x *RestartResult) GetStartMs() int64 {
+	if x != nil {
+		return x.StartMs
+	}
+	return 0
+}
+
+func (x *RestartResult) GetElapsedMs() int64 {
+	if x != nil {
+		return x.ElapsedMs
+	}
+	return 0
+}
+
+func (x *RestartResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_payram_agent_admin_v1_admin_proto protoreflect.FileDescriptor
+
+const file_payram_agent_admin_v1_admin_proto_rawDesc = "" +
+	"\n" +
+	"!payram/agent/admin/v1/admin.proto\x12\x15payram.agent.admin.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x12\n" +
+	"\x10GetStatusRequest\"\x90\x01\n" +
+	"\x11GetStatusResponse\x12;\n" +
+	"\x06update\x18\x01 \x01(\v2#.payram.agent.admin.v1.UpdateStatusR\x06update\x12>\n" +
+	"\bchildren\x18\x02 \x03(\v2\".payram.agent.admin.v1.ChildStatusR\bchildren\"\xe1\x05\n" +
+	"\fUpdateStatus\x12'\n" +
+	"\x0fcurrent_version\x18\x01 \x01(\tR\x0ecurrentVersion\x12)\n" +
+	"\x10previous_version\x18\x02 \x01(\tR\x0fpreviousVersion\x12'\n" +
+	"\x0fcurrent_channel\x18\x03 \x01(\tR\x0ecurrentChannel\x12)\n" +
+	"\x10previous_channel\x18\x04 \x01(\tR\x0fpreviousChannel\x120\n" +
+	"\x14last_success_version\x18\x05 \x01(\tR\x12lastSuccessVersion\x12B\n" +
+	"\x0flast_success_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\rlastSuccessAt\x120\n" +
+	"\x14last_attempt_version\x18\a \x01(\tR\x12lastAttemptVersion\x12B\n" +
+	"\x0flast_attempt_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\rlastAttemptAt\x12&\n" +
+	"\x0flast_error_code\x18\t \x01(\tR\rlastErrorCode\x12,\n" +
+	"\x12last_error_message\x18\n" +
+	" \x01(\tR\x10lastErrorMessage\x12>\n" +
+	"\rlast_error_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\vlastErrorAt\x12\x1f\n" +
+	"\vin_progress\x18\f \x01(\bR\n" +
+	"inProgress\x12O\n" +
+	"\x16in_progress_started_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\x13inProgressStartedAt\x125\n" +
+	"\x06canary\x18\x0e \x01(\v2\x1d.payram.agent.admin.v1.CanaryR\x06canary\"\x8b\x02\n" +
+	"\x06Canary\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12)\n" +
+	"\x10previous_version\x18\x02 \x01(\tR\x0fpreviousVersion\x129\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x120\n" +
+	"\x05until\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12.\n" +
+	"\x13max_health_failures\x18\x05 \x01(\x05R\x11maxHealthFailures\x12\x1f\n" +
+	"\vmax_crashes\x18\x06 \x01(\x05R\n" +
+	"maxCrashes\"\xe9\x01\n" +
+	"\vChildStatus\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03pid\x18\x02 \x01(\x05R\x03pid\x129\n" +
+	"\n" +
+	"start_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x12\x1a\n" +
+	"\brestarts\x18\x04 \x01(\x05R\brestarts\x12<\n" +
+	"\tlast_exit\x18\x05 \x01(\v2\x1f.payram.agent.admin.v1.ExitInfoR\blastExit\x12\x1f\n" +
+	"\vwaiting_for\x18\x06 \x03(\tR\n" +
+	"waitingFor\"m\n" +
+	"\bExitInfo\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x1b\n" +
+	"\texit_code\x18\x02 \x01(\x05R\bexitCode\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"]\n" +
+	"\x11StreamLogsRequest\x12\x1c\n" +
+	"\tcomponent\x18\x01 \x01(\tR\tcomponent\x12\x12\n" +
+	"\x04tail\x18\x02 \x01(\x05R\x04tail\x12\x16\n" +
+	"\x06follow\x18\x03 \x01(\bR\x06follow\"F\n" +
+	"\x12StreamLogsResponse\x12\x1c\n" +
+	"\tcomponent\x18\x01 \x01(\tR\tcomponent\x12\x12\n" +
+	"\x04line\x18\x02 \x01(\tR\x04line\".\n" +
+	"\x12CheckUpdateRequest\x12\x18\n" +
+	"\achannel\x18\x01 \x01(\tR\achannel\"\x8b\x03\n" +
+	"\x13CheckUpdateResponse\x12\x1c\n" +
+	"\tavailable\x18\x01 \x01(\bR\tavailable\x12'\n" +
+	"\x0fcurrent_version\x18\x02 \x01(\tR\x0ecurrentVersion\x12%\n" +
+	"\x0etarget_version\x18\x03 \x01(\tR\rtargetVersion\x12\x14\n" +
+	"\x05notes\x18\x04 \x01(\tR\x05notes\x12\x18\n" +
+	"\arevoked\x18\x05 \x01(\bR\arevoked\x12I\n" +
+	"\vpayram_core\x18\x06 \x01(\v2(.payram.agent.admin.v1.CoreCompatibilityR\n" +
+	"payramCore\x12<\n" +
+	"\x06compat\x18\a \x01(\v2$.payram.agent.admin.v1.CompatibilityR\x06compat\x12M\n" +
+	"\n" +
+	"components\x18\b \x01(\v2-.payram.agent.admin.v1.ComponentCompatibilityR\n" +
+	"components\"o\n" +
+	"\x11CoreCompatibility\x12\x10\n" +
+	"\x03min\x18\x01 \x01(\tR\x03min\x12\x10\n" +
+	"\x03max\x18\x02 \x01(\tR\x03max\x12\x1e\n" +
+	"\n" +
+	"compatible\x18\x03 \x01(\bR\n" +
+	"compatible\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\"a\n" +
+	"\rCompatibility\x12\x1e\n" +
+	"\n" +
+	"compatible\x18\x01 \x01(\bR\n" +
+	"compatible\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x18\n" +
+	"\aignored\x18\x03 \x01(\bR\aignored\"\x8a\x01\n" +
+	"\x16ComponentCompatibility\x12\x1e\n" +
+	"\n" +
+	"compatible\x18\x01 \x01(\bR\n" +
+	"compatible\x12\x1a\n" +
+	"\bproblems\x18\x02 \x03(\tR\bproblems\x12\x1a\n" +
+	"\bwarnings\x18\x03 \x03(\tR\bwarnings\x12\x18\n" +
+	"\aignored\x18\x04 \x01(\bR\aignored\"\xaa\x01\n" +
+	"\x12ApplyUpdateRequest\x12\x18\n" +
+	"\achannel\x18\x01 \x01(\tR\achannel\x12\x17\n" +
+	"\adry_run\x18\x02 \x01(\bR\x06dryRun\x12#\n" +
+	"\rforce_channel\x18\x03 \x01(\bR\fforceChannel\x12)\n" +
+	"\x0ecanary_soak_ms\x18\x04 \x01(\x03H\x00R\fcanarySoakMs\x88\x01\x01B\x11\n" +
+	"\x0f_canary_soak_ms\"\xf6\x01\n" +
+	"\x13ApplyUpdateResponse\x12\x1d\n" +
+	"\n" +
+	"updated_to\x18\x01 \x01(\tR\tupdatedTo\x12@\n" +
+	"\brestarts\x18\x02 \x03(\v2$.payram.agent.admin.v1.RestartResultR\brestarts\x12\x1a\n" +
+	"\bwarnings\x18\x03 \x03(\tR\bwarnings\x125\n" +
+	"\x06canary\x18\x04 \x01(\v2\x1d.payram.agent.admin.v1.CanaryR\x06canary\x12+\n" +
+	"\x04plan\x18\x05 \x01(\v2\x17.google.protobuf.StructR\x04plan\"\x11\n" +
+	"\x0fRollbackRequest\"z\n" +
+	"\x10RollbackResponse\x12$\n" +
+	"\x0erolled_back_to\x18\x01 \x01(\tR\frolledBackTo\x12@\n" +
+	"\brestarts\x18\x02 \x03(\v2$.payram.agent.admin.v1.RestartResultR\brestarts\"\x9b\x01\n" +
+	"\rRestartResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03pid\x18\x02 \x01(\x05R\x03pid\x12\x14\n" +
+	"\x05ready\x18\x03 \x01(\bR\x05ready\x12\x19\n" +
+	"\bstart_ms\x18\x04 \x01(\x03R\astartMs\x12\x1d\n" +
+	"\n" +
+	"elapsed_ms\x18\x05 \x01(\x03R\telapsedMs\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error2\x81\x04\n" +
+	"\x11AgentAdminService\x12^\n" +
+	"\tGetStatus\x12'.payram.agent.admin.v1.GetStatusRequest\x1a(.payram.agent.admin.v1.GetStatusResponse\x12c\n" +
+	"\n" +
+	"StreamLogs\x12(.payram.agent.admin.v1.StreamLogsRequest\x1a).payram.agent.admin.v1.StreamLogsResponse0\x01\x12d\n" +
+	"\vCheckUpdate\x12).payram.agent.admin.v1.CheckUpdateRequest\x1a*.payram.agent.admin.v1.CheckUpdateResponse\x12d\n" +
+	"\vApplyUpdate\x12).payram.agent.admin.v1.ApplyUpdateRequest\x1a*.payram.agent.admin.v1.ApplyUpdateResponse\x12[\n" +
+	"\bRollback\x12&.payram.agent.admin.v1.RollbackRequest\x1a'.payram.agent.admin.v1.RollbackResponseBTZRgithub.com/payram/payram-analytics-mcp-server/internal/agent/admin/adminpb;adminpbb\x06proto3"
+
+var (
+	file_payram_agent_admin_v1_admin_proto_rawDescOnce sync.Once
+	file_payram_agent_admin_v1_admin_proto_rawDescData []byte
+)
+
+func file_payram_agent_admin_v1_admin_proto_rawDescGZIP() []byte {
+	file_payram_agent_admin_v1_admin_proto_rawDescOnce.Do(func() {
+		file_payram_agent_admin_v1_admin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_payram_agent_admin_v1_admin_proto_rawDesc), len(file_payram_agent_admin_v1_admin_proto_rawDesc)))
+	})
+	return file_payram_agent_admin_v1_admin_proto_rawDescData
+}
+
+var file_payram_agent_admin_v1_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_payram_agent_admin_v1_admin_proto_goTypes = []any{
+	(*GetStatusRequest)(nil),       // 0: payram.agent.admin.v1.GetStatusRequest
+	(*GetStatusResponse)(nil),      // 1: payram.agent.admin.v1.GetStatusResponse
+	(*UpdateStatus)(nil),           // 2: payram.agent.admin.v1.UpdateStatus
+	(*Canary)(nil),                 // 3: payram.agent.admin.v1.Canary
+	(*ChildStatus)(nil),            // 4: payram.agent.admin.v1.ChildStatus
+	(*ExitInfo)(nil),               // 5: payram.agent.admin.v1.ExitInfo
+	(*StreamLogsRequest)(nil),      // 6: payram.agent.admin.v1.StreamLogsRequest
+	(*StreamLogsResponse)(nil),     // 7: payram.agent.admin.v1.StreamLogsResponse
+	(*CheckUpdateRequest)(nil),     // 8: payram.agent.admin.v1.CheckUpdateRequest
+	(*CheckUpdateResponse)(nil),    // 9: payram.agent.admin.v1.CheckUpdateResponse
+	(*CoreCompatibility)(nil),      // 10: payram.agent.admin.v1.CoreCompatibility
+	(*Compatibility)(nil),          // 11: payram.agent.admin.v1.Compatibility
+	(*ComponentCompatibility)(nil), // 12: payram.agent.admin.v1.ComponentCompatibility
+	(*ApplyUpdateRequest)(nil),     // 13: payram.agent.admin.v1.ApplyUpdateRequest
+	(*ApplyUpdateResponse)(nil),    // 14: payram.agent.admin.v1.ApplyUpdateResponse
+	(*RollbackRequest)(nil),        // 15: payram.agent.admin.v1.RollbackRequest
+	(*RollbackResponse)(nil),       // 16: payram.agent.admin.v1.RollbackResponse
+	(*RestartResult)(nil),          // 17: payram.agent.admin.v1.RestartResult
+	(*timestamppb.Timestamp)(nil),  // 18: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),        // 19: google.protobuf.Struct
+}
+var file_payram_agent_admin_v1_admin_proto_depIdxs = []int32{
+	2,  // 0: payram.agent.admin.v1.GetStatusResponse.update:type_name -> payram.agent.admin.v1.UpdateStatus
+	4,  // 1: payram.agent.admin.v1.GetStatusResponse.children:type_name -> payram.agent.admin.v1.ChildStatus
+	18, // 2: payram.agent.admin.v1.UpdateStatus.last_success_at:type_name -> google.protobuf.Timestamp
+	18, // 3: payram.agent.admin.v1.UpdateStatus.last_attempt_at:type_name -> google.protobuf.Timestamp
+	18, // 4: payram.agent.admin.v1.UpdateStatus.last_error_at:type_name -> google.protobuf.Timestamp
+	18, // 5: payram.agent.admin.v1.UpdateStatus.in_progress_started_at:type_name -> google.protobuf.Timestamp
+	3,  // 6: payram.agent.admin.v1.UpdateStatus.canary:type_name -> payram.agent.admin.v1.Canary
+	18, // 7: payram.agent.admin.v1.Canary.started_at:type_name -> google.protobuf.Timestamp
+	18, // 8: payram.agent.admin.v1.Canary.until:type_name -> google.protobuf.Timestamp
+	18, // 9: payram.agent.admin.v1.ChildStatus.start_time:type_name -> google.protobuf.Timestamp
+	5,  // 10: payram.agent.admin.v1.ChildStatus.last_exit:type_name -> payram.agent.admin.v1.ExitInfo
+	18, // 11: payram.agent.admin.v1.ExitInfo.time:type_name -> google.protobuf.Timestamp
+	10, // 12: payram.agent.admin.v1.CheckUpdateResponse.payram_core:type_name -> payram.agent.admin.v1.CoreCompatibility
+	11, // 13: payram.agent.admin.v1.CheckUpdateResponse.compat:type_name -> payram.agent.admin.v1.Compatibility
+	12, // 14: payram.agent.admin.v1.CheckUpdateResponse.components:type_name -> payram.agent.admin.v1.ComponentCompatibility
+	17, // 15: payram.agent.admin.v1.ApplyUpdateResponse.restarts:type_name -> payram.agent.admin.v1.RestartResult
+	3,  // 16: payram.agent.admin.v1.ApplyUpdateResponse.canary:type_name -> payram.agent.admin.v1.Canary
+	19, // 17: payram.agent.admin.v1.ApplyUpdateResponse.plan:type_name -> google.protobuf.Struct
+	17, // 18: payram.agent.admin.v1.RollbackResponse.restarts:type_name -> payram.agent.admin.v1.RestartResult
+	0,  // 19: payram.agent.admin.v1.AgentAdminService.GetStatus:input_type -> payram.agent.admin.v1.GetStatusRequest
+	6,  // 20: payram.agent.admin.v1.AgentAdminService.StreamLogs:input_type -> payram.agent.admin.v1.StreamLogsRequest
+	8,  // 21: payram.agent.admin.v1.AgentAdminService.CheckUpdate:input_type -> payram.agent.admin.v1.CheckUpdateRequest
+	13, // 22: payram.agent.admin.v1.AgentAdminService.ApplyUpdate:input_type -> payram.agent.admin.v1.ApplyUpdateRequest
+	15, // 23: payram.agent.admin.v1.AgentAdminService.Rollback:input_type -> payram.agent.admin.v1.RollbackRequest
+	1,  // 24: payram.agent.admin.v1.AgentAdminService.GetStatus:output_type -> payram.agent.admin.v1.GetStatusResponse
+	7,  // 25: payram.agent.admin.v1.AgentAdminService.StreamLogs:output_type -> payram.agent.admin.v1.StreamLogsResponse
+	9,  // 26: payram.agent.admin.v1.AgentAdminService.CheckUpdate:output_type -> payram.agent.admin.v1.CheckUpdateResponse
+	14, // 27: payram.agent.admin.v1.AgentAdminService.ApplyUpdate:output_type -> payram.agent.admin.v1.ApplyUpdateResponse
+	16, // 28: payram.agent.admin.v1.AgentAdminService.Rollback:output_type -> payram.agent.admin.v1.RollbackResponse
+	24, // [24:29] is the sub-list for method output_type
+	19, // [19:24] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
+}
+
+func init() { file_payram_agent_admin_v1_admin_proto_init() }
+func file_payram_agent_admin_v1_admin_proto_init() {
+	if File_payram_agent_admin_v1_admin_proto != nil {
+		return
+	}
+	file_payram_agent_admin_v1_admin_proto_msgTypes[13].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_payram_agent_admin_v1_admin_proto_rawDesc), len(file_payram_agent_admin_v1_admin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_payram_agent_admin_v1_admin_proto_goTypes,
+		DependencyIndexes: file_payram_agent_admin_v1_admin_proto_depIdxs,
+		MessageInfos:      file_payram_agent_admin_v1_admin_proto_msgTypes,
+	}.Build()
+	File_payram_agent_admin_v1_admin_proto = out.File
+	file_payram_agent_admin_v1_admin_proto_goTypes = nil
+	file_payram_agent_admin_v1_admin_proto_depIdxs = nil
+}
@@ -0,0 +1,222 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/lifecycle"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// canaryPolicy is how long an applied release soaks before it counts as a success, and how
+// much misbehaviour during the soak triggers an automatic rollback.
+type canaryPolicy struct {
+	Soak              time.Duration
+	Interval          time.Duration
+	MaxHealthFailures int
+	MaxCrashes        int
+}
+
+// canaryWatchers tracks running canary watchers.
+var canaryWatchers sync.WaitGroup
+
+// canaryPolicyFor resolves the policy from the environment; ?canary_soak_ms overrides the
+// soak for one apply, and 0 turns canary mode off for it.
+func canaryPolicyFor(r *http.Request) (canaryPolicy, error) {
+	p := canaryPolicy{
+		Soak:              envMillis("PAYRAM_AGENT_CANARY_SOAK_MS", 0),
+		Interval:          envMillis("PAYRAM_AGENT_CANARY_INTERVAL_MS", 5*time.Second),
+		MaxHealthFailures: envCount("PAYRAM_AGENT_CANARY_MAX_HEALTH_FAILURES", 2),
+		MaxCrashes:        envCount("PAYRAM_AGENT_CANARY_MAX_CRASHES", 0),
+	}
+	if v := r.URL.Query().Get("canary_soak_ms"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 0 {
+			return p, fmt.Errorf("canary_soak_ms must be a non-negative integer")
+		}
+		p.Soak = time.Duration(ms) * time.Millisecond
+	}
+	return p, nil
+}
+
+// startCanary records version as a canary of previous and watches it in the background.
+func startCanary(sup Supervisor, status *update.UpdateStatus, version, previous string, p canaryPolicy) update.Canary {
+	now := time.Now()
+	c := update.Canary{
+		Version:           version,
+		PreviousVersion:   previous,
+		StartedAt:         now,
+		Until:             now.Add(p.Soak),
+		MaxHealthFailures: p.MaxHealthFailures,
+		MaxCrashes:        p.MaxCrashes,
+	}
+	status.Canary = &c
+	canaryWatchers.Add(1)
+	go watchCanary(sup, c, p.Interval)
+	return c
+}
+
+// ResumeCanary picks up a canary left in the update status by an earlier agent process, so
+// its soak still ends in a promotion or rollback. Crashes before the restart are not counted.
+func ResumeCanary(sup Supervisor) {
+	status, err := update.LoadStatus()
+	if err != nil || status.Canary == nil {
+		return
+	}
+	canaryWatchers.Add(1)
+	go watchCanary(sup, *status.Canary, envMillis("PAYRAM_AGENT_CANARY_INTERVAL_MS", 5*time.Second))
+}
+
+// watchCanary probes the children every interval until c's soak ends, then promotes or rolls
+// back the release. It gives up quietly once another apply or rollback replaces the canary.
+func watchCanary(sup Supervisor, c update.Canary, interval time.Duration) {
+	defer canaryWatchers.Done()
+	if interval <= 0 {
+		interval = time.Second
+	}
+	baseline := crashCount(sup)
+	failures := 0
+	reason := ""
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(time.Until(c.Until))
+	defer deadline.Stop()
+
+soak:
+	for {
+		select {
+		case <-deadline.C:
+			break soak
+		case <-ticker.C:
+		}
+		if !canaryCurrent(c) {
+			return
+		}
+		if err := probeChildren(); err != nil {
+			failures++
+			if failures > c.MaxHealthFailures {
+				reason = fmt.Sprintf("%d failed health probes during soak, last: %v", failures, err)
+				break
+			}
+		}
+		if n := crashCount(sup) - baseline; n > c.MaxCrashes {
+			reason = fmt.Sprintf("%d child crashes during soak", n)
+			break
+		}
+	}
+	finishCanary(sup, c, reason, interval)
+}
+
+// finishCanary waits for the update lock, then promotes c when reason is empty and rolls
+// it back otherwise, recording the decision in the update history.
+func finishCanary(sup Supervisor, c update.Canary, reason string, retry time.Duration) {
+	var unlock func() error
+	for {
+		var err error
+		if unlock, err = update.AcquireUpdateLock(); err == nil {
+			break
+		}
+		if !errors.Is(err, update.ErrUpdateInProgress) {
+			return
+		}
+		time.Sleep(retry)
+	}
+	defer func() { _ = unlock() }()
+
+	if !canaryCurrent(c) {
+		return
+	}
+	status, err := update.LoadStatus()
+	if err != nil {
+		return
+	}
+	status.Canary = nil
+	history := update.HistoryEntry{Action: "canary", FromVersion: c.PreviousVersion, ToVersion: c.Version, Channel: status.CurrentChannel}
+
+	if reason == "" {
+		status.MarkSuccess(c.Version, c.PreviousVersion)
+		_ = update.SaveStatus(status)
+		history.Message = "soak passed"
+		recordHistory(history, status, true)
+		return
+	}
+
+	rollbackTotal.Inc()
+	if prevTarget, err := os.Readlink(update.PreviousSymlink()); err == nil {
+		_, _ = update.UpdateSymlinks(prevTarget)
+		_ = sup.RestartAllAndWait(healthTimeout())
+		publishAutoRollback(c.Version, c.PreviousVersion, reason)
+		status.CurrentVersion, status.PreviousVersion = c.PreviousVersion, c.Version
+		status.CurrentChannel, status.PreviousChannel = status.PreviousChannel, status.CurrentChannel
+	} else {
+		reason += "; rollback impossible: " + err.Error()
+	}
+	status.MarkFailure("CANARY_ROLLED_BACK", reason)
+	_ = update.SaveStatus(status)
+	recordHistory(history, status, false)
+}
+
+// canaryCurrent reports whether c is still the canary in the update status.
+func canaryCurrent(c update.Canary) bool {
+	status, err := update.LoadStatus()
+	return err == nil && status.Canary != nil && status.Canary.Version == c.Version && status.Canary.StartedAt.Equal(c.StartedAt)
+}
+
+// crashCount is how often the supervisor has restarted children since they were started.
+func crashCount(sup Supervisor) int {
+	n := 0
+	for _, c := range sup.Status().Components {
+		n += c.Restarts
+	}
+	return n
+}
+
+// probeChildren checks both children's health endpoints once.
+func probeChildren() error {
+	client := &http.Client{Timeout: 2 * time.Second}
+	healthPath := childHealthPath()
+	for _, child := range []struct {
+		name string
+		port int
+	}{
+		{"chat-api", envPort("PAYRAM_CHAT_PORT", 2358)},
+		{"mcp", envPort("PAYRAM_MCP_PORT", 3333)},
+	} {
+		if err := pingOnce(client, fmt.Sprintf("http://127.0.0.1:%d%s", child.port, healthPath)); err != nil {
+			return fmt.Errorf("%s: %w", child.name, err)
+		}
+	}
+	return nil
+}
+
+// publishAutoRollback announces a rollback the agent did on its own, from a failed release to
+// the one before it.
+func publishAutoRollback(from, to, reason string) {
+	lifecycle.Publish(lifecycle.UpdateRollback, "", map[string]any{
+		"automatic": true, "from_version": from, "to_version": to, "reason": reason,
+	})
+}
+
+func envMillis(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return fallback
+}
+
+func envCount(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return fallback
+}
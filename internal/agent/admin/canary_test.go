@@ -0,0 +1,165 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// switchableChildren points the child health checks at servers that answer 200 while healthy
+// is set and 500 otherwise.
+func switchableChildren(t *testing.T) *atomic.Bool {
+	t.Helper()
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+	h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	for _, env := range []string{"PAYRAM_CHAT_PORT", "PAYRAM_MCP_PORT"} {
+		srv := httptest.NewServer(h)
+		t.Cleanup(srv.Close)
+		t.Setenv(env, portFromURL(srv.URL))
+	}
+	return &healthy
+}
+
+// applyCanary installs 1.0.0 outright, then applies 1.1.0 as a canary soaking for soak.
+func applyCanary(t *testing.T, sup Supervisor, soak string) update.Canary {
+	t.Helper()
+
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_IGNORE_COMPAT", "1")
+	t.Setenv("PAYRAM_CORE_URL", "")
+	t.Setenv("PAYRAM_AGENT_CANARY_SOAK_MS", soak)
+	t.Setenv("PAYRAM_AGENT_CANARY_INTERVAL_MS", "10")
+	// The watcher reads the agent home from the environment, so it must end with the test.
+	t.Cleanup(canaryWatchers.Wait)
+
+	fixture := newReleaseFixture(t, update.Manifest{Version: "1.0.0"})
+	if rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply?canary_soak_ms=0"); rr.Code != http.StatusOK {
+		t.Fatalf("apply 1.0.0: %d %s", rr.Code, rr.Body.String())
+	}
+
+	fixture.publish("stable", update.Manifest{Version: "1.1.0"})
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("apply 1.1.0: %d %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Canary *update.Canary `json:"canary"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp.Data.Canary == nil {
+		t.Fatalf("apply response lacks canary: %v %s", err, rr.Body.String())
+	}
+	if c := resp.Data.Canary; c.Version != "1.1.0" || c.PreviousVersion != "1.0.0" {
+		t.Fatalf("canary = %+v", c)
+	}
+	return *resp.Data.Canary
+}
+
+// awaitCanaryDecision waits for the canary to leave the update status and returns the
+// status and the newest history entry.
+func awaitCanaryDecision(t *testing.T) (update.UpdateStatus, update.HistoryEntry) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st, err := update.LoadStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Canary == nil {
+			entries, err := update.LoadHistory(1)
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("load history: %v %v", entries, err)
+			}
+			return st, entries[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("canary still pending: %+v", st.Canary)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCanaryPromotesAfterHealthySoak(t *testing.T) {
+	switchableChildren(t)
+	applyCanary(t, &fakeSupervisor{}, "100")
+
+	st, err := update.LoadStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.CurrentVersion != "1.1.0" || st.LastSuccessVersion != "1.0.0" {
+		t.Fatalf("during soak: current=%s last_success=%s", st.CurrentVersion, st.LastSuccessVersion)
+	}
+
+	st, entry := awaitCanaryDecision(t)
+	if st.LastSuccessVersion != "1.1.0" || st.CurrentVersion != "1.1.0" || st.PreviousVersion != "1.0.0" {
+		t.Fatalf("after soak: %+v", st)
+	}
+	if entry.Action != "canary" || entry.Result != "success" || entry.ToVersion != "1.1.0" {
+		t.Fatalf("history entry = %+v", entry)
+	}
+}
+
+func TestCanaryRollsBackOnFailedHealth(t *testing.T) {
+	healthy := switchableChildren(t)
+	t.Setenv("PAYRAM_AGENT_CANARY_MAX_HEALTH_FAILURES", "1")
+	t.Setenv("PAYRAM_AGENT_HEALTH_TIMEOUT_MS", "100")
+	applyCanary(t, &fakeSupervisor{}, "60000")
+	healthy.Store(false)
+
+	st, entry := awaitCanaryDecision(t)
+	if st.CurrentVersion != "1.0.0" || st.PreviousVersion != "1.1.0" || st.LastSuccessVersion != "1.0.0" {
+		t.Fatalf("after rollback: %+v", st)
+	}
+	if st.LastErrorCode != "CANARY_ROLLED_BACK" {
+		t.Fatalf("last_error_code = %q", st.LastErrorCode)
+	}
+	if entry.Action != "canary" || entry.Result != "failed" || entry.ErrorCode != "CANARY_ROLLED_BACK" {
+		t.Fatalf("history entry = %+v", entry)
+	}
+	target, _ := os.Readlink(update.CurrentSymlink())
+	if filepath.Base(target) != "1.0.0" {
+		t.Fatalf("current not rolled back: %s", target)
+	}
+}
+
+func TestRollbackEndsCanary(t *testing.T) {
+	switchableChildren(t)
+	applyCanary(t, &fakeSupervisor{}, "60000")
+
+	if rr := adminRequest(t, &fakeSupervisor{}, http.MethodPost, "/admin/update/rollback"); rr.Code != http.StatusOK {
+		t.Fatalf("rollback: %d %s", rr.Code, rr.Body.String())
+	}
+	st, entry := awaitCanaryDecision(t)
+	if st.CurrentVersion != "1.0.0" || entry.Action != "rollback" {
+		t.Fatalf("after manual rollback: current=%s history=%+v", st.CurrentVersion, entry)
+	}
+}
+
+func TestApplyRejectsBadCanarySoak(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	newReleaseFixture(t, update.Manifest{Version: "1.0.0"})
+
+	rr := adminRequest(t, &fakeSupervisor{}, http.MethodPost, "/admin/update/apply?canary_soak_ms=soon")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %s", rr.Code, rr.Body.String())
+	}
+	if code := errorCode(t, decodeBody(t, rr)); code != "INVALID_CANARY" {
+		t.Fatalf("expected INVALID_CANARY, got %s", code)
+	}
+}
@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// chaosInstall seeds an install running 1.0.0 with healthy children and publishes 2.0.0 on
+// a local artifact server.
+func chaosInstall(t *testing.T) *fakeSupervisor {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("PAYRAM_AGENT_HOME", home)
+	t.Setenv("PAYRAM_AGENT_HEALTH_TIMEOUT_MS", "200")
+	t.Setenv("PAYRAM_AGENT_IGNORE_COMPAT", "true")
+	t.Setenv("PAYRAM_CORE_URL", "")
+	healthyChildren(t)
+
+	oldDir := filepath.Join(home, "releases", "1.0.0")
+	if err := os.MkdirAll(oldDir, 0o755); err != nil {
+		t.Fatalf("mkdir old: %v", err)
+	}
+	if _, err := update.UpdateSymlinks(oldDir); err != nil {
+		t.Fatalf("seed symlinks: %v", err)
+	}
+	var st update.UpdateStatus
+	st.MarkSuccess("1.0.0", "")
+	if err := update.SaveStatus(st); err != nil {
+		t.Fatalf("seed status: %v", err)
+	}
+
+	newReleaseFixture(t, update.Manifest{Version: "2.0.0"})
+	return &fakeSupervisor{}
+}
+
+func loadStatus(t *testing.T) update.UpdateStatus {
+	t.Helper()
+
+	st, err := update.LoadStatus()
+	if err != nil {
+		t.Fatalf("load status: %v", err)
+	}
+	return st
+}
+
+func currentRelease(t *testing.T) string {
+	t.Helper()
+
+	target, err := os.Readlink(update.CurrentSymlink())
+	if err != nil {
+		t.Fatalf("read current: %v", err)
+	}
+	return filepath.Base(target)
+}
+
+// expectFailedAttempt checks the status and install after an apply that failed before the
+// switch: the error is recorded, the lock is released and 1.0.0 still runs.
+func expectFailedAttempt(t *testing.T, code string) {
+	t.Helper()
+
+	st := loadStatus(t)
+	if st.LastErrorCode != code || st.InProgress {
+		t.Fatalf("expected %s and no update in progress, got %+v", code, st)
+	}
+	if st.LastAttemptVersion != "2.0.0" || st.LastSuccessVersion != "1.0.0" || st.CurrentVersion != "1.0.0" {
+		t.Fatalf("unexpected versions: %+v", st)
+	}
+	if got := currentRelease(t); got != "1.0.0" {
+		t.Fatalf("current should still be 1.0.0, got %s", got)
+	}
+	if _, err := os.Stat(update.LockFilePath()); !os.IsNotExist(err) {
+		t.Fatalf("expected the update lock to be released: %v", err)
+	}
+}
+
+func expectApplied(t *testing.T, sup *fakeSupervisor) {
+	t.Helper()
+
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+	st := loadStatus(t)
+	if st.LastSuccessVersion != "2.0.0" || st.CurrentVersion != "2.0.0" || st.PreviousVersion != "1.0.0" || st.InProgress {
+		t.Fatalf("unexpected status after apply: %+v", st)
+	}
+	if got := currentRelease(t); got != "2.0.0" {
+		t.Fatalf("current should be 2.0.0, got %s", got)
+	}
+}
+
+func TestChaosDownloadFailureLeavesInstallAndRecovers(t *testing.T) {
+	sup := chaosInstall(t)
+	t.Setenv(update.ChaosFailDownloadEnv, "50")
+
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply")
+	if rr.Code != http.StatusInternalServerError || !strings.Contains(rr.Body.String(), "download failed at 50%") {
+		t.Fatalf("expected an injected download failure, got %d %s", rr.Code, rr.Body.String())
+	}
+	expectFailedAttempt(t, "UPDATE_DOWNLOAD_FAILED")
+	if sup.restarts != 0 {
+		t.Fatalf("expected no restart, got %d", sup.restarts)
+	}
+	parts, _ := filepath.Glob(filepath.Join(update.ReleasesDir(), "*", "*.part"))
+	if len(parts) != 0 {
+		t.Fatalf("partial downloads left behind: %v", parts)
+	}
+
+	t.Setenv(update.ChaosFailDownloadEnv, "")
+	expectApplied(t, sup)
+}
+
+func TestChaosCorruptHashFailsVerification(t *testing.T) {
+	sup := chaosInstall(t)
+	t.Setenv(update.ChaosCorruptHashEnv, "1")
+
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply")
+	if rr.Code != http.StatusInternalServerError || !strings.Contains(rr.Body.String(), "sha256 mismatch") {
+		t.Fatalf("expected a checksum failure, got %d %s", rr.Code, rr.Body.String())
+	}
+	expectFailedAttempt(t, "UPDATE_DOWNLOAD_FAILED")
+	if entries, _ := os.ReadDir(update.CacheDir()); len(entries) != 0 {
+		t.Fatalf("corrupt artifacts must not be cached: %v", entries)
+	}
+
+	t.Setenv(update.ChaosCorruptHashEnv, "")
+	expectApplied(t, sup)
+}
+
+func TestChaosHealthFailureRollsBackOnce(t *testing.T) {
+	sup := chaosInstall(t)
+	t.Setenv(update.ChaosFailHealthOnceEnv, "1")
+
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply")
+	if rr.Code != http.StatusInternalServerError || errorCode(t, decodeBody(t, rr)) != "UPDATE_FAILED_ROLLED_BACK" {
+		t.Fatalf("expected UPDATE_FAILED_ROLLED_BACK, got %d %s", rr.Code, rr.Body.String())
+	}
+	st := loadStatus(t)
+	if st.LastErrorCode != "UPDATE_FAILED_ROLLED_BACK" || st.InProgress {
+		t.Fatalf("unexpected status after rollback: %+v", st)
+	}
+	if st.CurrentVersion != "1.0.0" || st.PreviousVersion != "2.0.0" || st.LastSuccessVersion != "1.0.0" {
+		t.Fatalf("unexpected versions after rollback: %+v", st)
+	}
+	if got := currentRelease(t); got != "1.0.0" {
+		t.Fatalf("current not rolled back: %s", got)
+	}
+	if sup.restarts != 2 {
+		t.Fatalf("expected a restart for the update and one for the rollback, got %d", sup.restarts)
+	}
+
+	// The injected failure is spent, so the retry goes through.
+	expectApplied(t, sup)
+}
+
+func TestStaleUpdateLockIsRecovered(t *testing.T) {
+	sup := chaosInstall(t)
+	if err := os.MkdirAll(update.LockDir(), 0o755); err != nil {
+		t.Fatalf("mkdir lock: %v", err)
+	}
+
+	// A lock held by this process is live.
+	lock := fmt.Sprintf("pid=%d\nstarted=2020-01-01T00:00:00Z\n", os.Getpid())
+	if err := os.WriteFile(update.LockFilePath(), []byte(lock), 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply")
+	if rr.Code != http.StatusConflict || errorCode(t, decodeBody(t, rr)) != "UPDATE_IN_PROGRESS" {
+		t.Fatalf("expected UPDATE_IN_PROGRESS, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// One left by an agent that died mid-update is not.
+	lock = "pid=2147483646\nstarted=2020-01-01T00:00:00Z\n"
+	if err := os.WriteFile(update.LockFilePath(), []byte(lock), 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+	expectApplied(t, sup)
+	if _, err := os.Stat(update.LockFilePath()); !os.IsNotExist(err) {
+		t.Fatalf("expected the update lock to be released: %v", err)
+	}
+}
@@ -0,0 +1,352 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/secrets"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+const (
+	defaultCommandInterval = 30 * time.Second
+	// maxCommandLogLines caps the lines collect-logs returns per child.
+	maxCommandLogLines = 1000
+	maxCommandsBytes   = 1 << 20
+)
+
+// Command is one instruction from the fleet command queue. It is signed with the update key,
+// so only whoever can sign releases can command an agent. AgentID is the agent it is for, or
+// "*" for every agent polling the queue.
+type Command struct {
+	ID        string            `json:"id"`
+	AgentID   string            `json:"agent_id"`
+	Type      string            `json:"type"`
+	Args      map[string]string `json:"args,omitempty"`
+	IssuedAt  time.Time         `json:"issued_at"`
+	ExpiresAt time.Time         `json:"expires_at,omitzero"`
+}
+
+// SignedCommand is how the queue hands out a command: its exact JSON bytes and the base64
+// ed25519 signature over them.
+type SignedCommand struct {
+	Command   json.RawMessage `json:"command"`
+	Signature string          `json:"signature"`
+}
+
+// CommandResult is posted back to the queue once a command ran or was rejected.
+type CommandResult struct {
+	AgentID   string          `json:"agent_id"`
+	CommandID string          `json:"command_id"`
+	Result    string          `json:"result"`
+	Status    int             `json:"status,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Error     *respError      `json:"error,omitempty"`
+}
+
+// CommandQueue polls the fleet for commands and runs them through the same handlers as the
+// admin API, so agents behind NAT can be managed without inbound connections.
+type CommandQueue struct {
+	URL       string
+	Interval  time.Duration
+	Key       ed25519.PrivateKey
+	PubKeyB64 string
+	Client    *http.Client
+
+	sup Supervisor
+}
+
+// CommandQueueFromEnv configures a command queue from PAYRAM_AGENT_COMMANDS_URL and
+// PAYRAM_AGENT_COMMANDS_INTERVAL_MS; ok is false when no URL is set. Commands are verified
+// with PAYRAM_AGENT_UPDATE_PUBKEY_B64, which must then be set.
+func CommandQueueFromEnv(sup Supervisor) (q *CommandQueue, ok bool, err error) {
+	queueURL := strings.TrimSpace(os.Getenv("PAYRAM_AGENT_COMMANDS_URL"))
+	if queueURL == "" {
+		return nil, false, nil
+	}
+	pub := os.Getenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64")
+	if pub == "" {
+		return nil, false, errors.New("PAYRAM_AGENT_UPDATE_PUBKEY_B64 is required to verify commands")
+	}
+	key, err := secrets.IdentityKey(update.HomeDir())
+	if err != nil {
+		return nil, false, fmt.Errorf("identity key: %w", err)
+	}
+	return &CommandQueue{
+		URL:       queueURL,
+		Interval:  envMillis("PAYRAM_AGENT_COMMANDS_INTERVAL_MS", defaultCommandInterval),
+		Key:       key,
+		PubKeyB64: pub,
+		Client:    &http.Client{Timeout: 15 * time.Second},
+		sup:       sup,
+	}, true, nil
+}
+
+// Run polls right away and then every Interval until ctx is done. A failed poll is logged and
+// retried on schedule.
+func (q *CommandQueue) Run(ctx context.Context) {
+	interval := q.Interval
+	if interval <= 0 {
+		interval = defaultCommandInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := q.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("fleet commands: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *CommandQueue) agentID() string {
+	return secrets.AgentID(q.Key.Public().(ed25519.PublicKey))
+}
+
+// Poll fetches the pending commands and handles each in order.
+func (q *CommandQueue) Poll(ctx context.Context) error {
+	commands, err := q.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sc := range commands {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		q.handle(ctx, sc)
+	}
+	return nil
+}
+
+func (q *CommandQueue) fetch(ctx context.Context) ([]SignedCommand, error) {
+	query := url.Values{"agent_id": {q.agentID()}, "ts": {strconv.FormatInt(time.Now().Unix(), 10)}}.Encode()
+	sep := "?"
+	if strings.Contains(q.URL, "?") {
+		sep = "&"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, q.URL+sep+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	signAgentRequest(req, q.Key, []byte(query))
+
+	client := q.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("command queue returned HTTP %d", resp.StatusCode)
+	}
+	var body struct {
+		Commands []SignedCommand `json:"commands"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxCommandsBytes)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("command queue: %w", err)
+	}
+	return body.Commands, nil
+}
+
+// handle verifies one command and runs it, unless it is for another agent or already in the
+// audit log. Everything else ends up in the audit log and is reported back.
+func (q *CommandQueue) handle(ctx context.Context, sc SignedCommand) {
+	var cmd Command
+	if err := json.Unmarshal(sc.Command, &cmd); err != nil || cmd.ID == "" {
+		log.Printf("fleet commands: skipping malformed command")
+		return
+	}
+	if cmd.AgentID != "*" && cmd.AgentID != q.agentID() {
+		return
+	}
+	if seen, err := update.CommandSeen(cmd.ID); err != nil || seen {
+		return
+	}
+
+	record := update.CommandRecord{ID: cmd.ID, Type: cmd.Type, Args: cmd.Args, IssuedAt: cmd.IssuedAt}
+	result := CommandResult{AgentID: q.agentID(), CommandID: cmd.ID}
+	reject := func(code, msg string) {
+		record.Result, record.ErrorCode, record.Message = "rejected", code, msg
+		result.Result, result.Error = "rejected", &respError{Code: code, Message: msg}
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(sc.Signature)
+	switch {
+	case err != nil:
+		reject("SIGNATURE_INVALID", "signature is not base64")
+	case update.VerifyManifest(sc.Command, sig, q.PubKeyB64) != nil:
+		signatureFailures.Inc()
+		reject("SIGNATURE_INVALID", "command signature did not verify")
+	case !cmd.ExpiresAt.IsZero() && time.Now().After(cmd.ExpiresAt):
+		reject("COMMAND_EXPIRED", "command expired at "+cmd.ExpiresAt.Format(time.RFC3339))
+	default:
+		run, ok := q.runners()[cmd.Type]
+		if !ok {
+			reject("UNKNOWN_COMMAND", fmt.Sprintf("unknown command type %q", cmd.Type))
+			break
+		}
+		status, resp := run(ctx, cmd)
+		record.Status, result.Status = status, status
+		record.Result = attemptResult(resp.Ok)
+		result.Result, result.Data, result.Error = record.Result, resp.Data, resp.Error
+		if resp.Error != nil {
+			record.ErrorCode, record.Message = resp.Error.Code, resp.Error.Message
+		}
+	}
+
+	if err := update.AppendCommandRecord(record); err != nil {
+		log.Printf("fleet commands: audit %s: %v", cmd.ID, err)
+	}
+	if err := q.report(ctx, result); err != nil && ctx.Err() == nil {
+		log.Printf("fleet commands: reporting %s: %v", cmd.ID, err)
+	}
+}
+
+func (q *CommandQueue) report(ctx context.Context, result CommandResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signAgentRequest(req, q.Key, body)
+	return postFleet(q.Client, req)
+}
+
+// commandResponse is an admin API response with its data kept raw for the result.
+type commandResponse struct {
+	Ok    bool            `json:"ok"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error *respError      `json:"error,omitempty"`
+}
+
+type commandRunner func(ctx context.Context, cmd Command) (int, commandResponse)
+
+// runners maps each command type to what it does. check-update, apply and rollback go
+// through the admin handlers, with the command's args as query parameters.
+func (q *CommandQueue) runners() map[string]commandRunner {
+	return map[string]commandRunner{
+		"check-update": q.viaHandler(http.MethodGet, "/admin/update/available", updateAvailableHandler),
+		"apply":        q.viaHandler(http.MethodPost, "/admin/update/apply", updateApplyHandler(q.sup)),
+		"rollback":     q.viaHandler(http.MethodPost, "/admin/update/rollback", updateRollbackHandler(q.sup)),
+		"collect-logs": q.collectLogs,
+	}
+}
+
+func (q *CommandQueue) viaHandler(method, path string, h http.HandlerFunc) commandRunner {
+	return func(ctx context.Context, cmd Command) (int, commandResponse) {
+		query := url.Values{}
+		for k, v := range cmd.Args {
+			query.Set(k, v)
+		}
+		// The update history names the command an apply or rollback came from.
+		return callHandler(ctx, h, method, path+"?"+query.Encode(), "command:"+cmd.ID)
+	}
+}
+
+// callHandler serves one request with an admin handler in process, for callers that reach
+// the admin API other than over its HTTP listener. remoteAddr names the caller in the update
+// history.
+func callHandler(ctx context.Context, h http.HandlerFunc, method, target, remoteAddr string) (int, commandResponse) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return http.StatusInternalServerError, commandResponse{Error: &respError{Code: "INTERNAL", Message: err.Error()}}
+	}
+	req.RemoteAddr = remoteAddr
+
+	rec := &captureWriter{header: http.Header{}, status: http.StatusOK}
+	h(rec, req)
+	var resp commandResponse
+	if err := json.Unmarshal(rec.body.Bytes(), &resp); err != nil {
+		return rec.status, commandResponse{Error: &respError{Code: "INTERNAL", Message: "unreadable response: " + err.Error()}}
+	}
+	return rec.status, resp
+}
+
+// collectLogs returns the buffered log lines of the children, args component (chat or mcp,
+// default both) and tail (default 200).
+func (q *CommandQueue) collectLogs(_ context.Context, cmd Command) (int, commandResponse) {
+	tail := 200
+	if v := cmd.Args["tail"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return http.StatusBadRequest, commandResponse{Error: &respError{Code: "INVALID_ARGUMENT", Message: "tail must be a positive integer"}}
+		}
+		tail = min(n, maxCommandLogLines)
+	}
+	components := []string{"chat", "mcp"}
+	switch c := cmd.Args["component"]; c {
+	case "", "all":
+	case "chat", "mcp":
+		components = []string{c}
+	default:
+		return http.StatusBadRequest, commandResponse{Error: &respError{Code: "INVALID_COMPONENT", Message: "component must be chat, mcp or all"}}
+	}
+
+	logs := map[string][]string{}
+	for _, c := range components {
+		logs[c] = q.sup.Logs(c, tail)
+	}
+	data, _ := json.Marshal(map[string]any{"logs": logs})
+	return http.StatusOK, commandResponse{Ok: true, Data: data}
+}
+
+// captureWriter records a handler's response.
+type captureWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (c *captureWriter) Header() http.Header         { return c.header }
+func (c *captureWriter) Write(b []byte) (int, error) { return c.body.Write(b) }
+func (c *captureWriter) WriteHeader(status int)      { c.status = status }
+
+func commandsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET allowed")
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			RespondError(w, http.StatusBadRequest, "INVALID_LIMIT", "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+	records, err := update.LoadCommandRecords(limit)
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "COMMANDS_LOAD_FAILED", err.Error())
+		return
+	}
+	RespondOK(w, http.StatusOK, map[string]any{"commands": records})
+}
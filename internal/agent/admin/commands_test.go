@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+type logSupervisor struct{ noopSupervisor }
+
+func (logSupervisor) Logs(component string, tail int) []string {
+	if component != "chat" && component != "mcp" {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s line (tail %d)", component, tail)}
+}
+
+func TestCommandQueueRunsSignedCommands(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "")
+
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+	t.Setenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64", base64.StdEncoding.EncodeToString(pub))
+
+	sign := func(key ed25519.PrivateKey, cmd Command) SignedCommand {
+		raw, _ := json.Marshal(cmd)
+		return SignedCommand{Command: raw, Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, raw))}
+	}
+	issued := time.Now().UTC().Add(-time.Minute)
+	commands := []SignedCommand{
+		sign(priv, Command{ID: "logs-1", AgentID: "*", Type: "collect-logs", Args: map[string]string{"component": "mcp", "tail": "5"}, IssuedAt: issued}),
+		sign(otherPriv, Command{ID: "forged-1", AgentID: "*", Type: "rollback", IssuedAt: issued}),
+		sign(priv, Command{ID: "old-1", AgentID: "*", Type: "rollback", IssuedAt: issued, ExpiresAt: issued.Add(time.Second)}),
+		sign(priv, Command{ID: "other-1", AgentID: "someone-else", Type: "rollback", IssuedAt: issued}),
+		sign(priv, Command{ID: "odd-1", AgentID: "*", Type: "reboot", IssuedAt: issued}),
+	}
+
+	var mu sync.Mutex
+	var results []CommandResult
+	var polls []*http.Request
+	fleet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet {
+			polls = append(polls, r)
+			_ = json.NewEncoder(w).Encode(map[string]any{"commands": commands})
+			return
+		}
+		var res CommandResult
+		_ = json.NewDecoder(r.Body).Decode(&res)
+		results = append(results, res)
+	}))
+	defer fleet.Close()
+	t.Setenv("PAYRAM_AGENT_COMMANDS_URL", fleet.URL)
+
+	q, ok, err := CommandQueueFromEnv(&logSupervisor{})
+	if err != nil || !ok {
+		t.Fatalf("command queue: %v, %v", ok, err)
+	}
+	// The queue hands out the same commands twice; the second poll must not run them again.
+	for range 2 {
+		if err := q.Poll(context.Background()); err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	poll := polls[0]
+	key, _ := base64.StdEncoding.DecodeString(poll.Header.Get(FleetKeyHeader))
+	sig, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(poll.Header.Get(FleetSignatureHeader), "ed25519="))
+	if !ed25519.Verify(key, []byte(poll.URL.RawQuery), sig) || poll.URL.Query().Get("agent_id") != q.agentID() {
+		t.Fatalf("poll not signed for agent %s: %s", q.agentID(), poll.URL.RawQuery)
+	}
+
+	// Only the forged command is handled again: its id is not claimed by a signed command.
+	want := map[string]string{"logs-1": "success", "forged-1": "rejected", "old-1": "rejected", "odd-1": "rejected"}
+	if len(results) != len(want)+1 {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want)+1, results)
+	}
+	for _, res := range results {
+		if want[res.CommandID] != res.Result {
+			t.Fatalf("command %s: result %q, want %q", res.CommandID, res.Result, want[res.CommandID])
+		}
+	}
+	if !strings.Contains(string(results[0].Data), "mcp line (tail 5)") {
+		t.Fatalf("collect-logs data %s", results[0].Data)
+	}
+
+	records, err := update.LoadCommandRecords(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := map[string]string{}
+	for _, r := range records {
+		codes[r.ID] = r.ErrorCode
+	}
+	if len(records) != 5 || codes["forged-1"] != "SIGNATURE_INVALID" || codes["old-1"] != "COMMAND_EXPIRED" || codes["odd-1"] != "UNKNOWN_COMMAND" {
+		t.Fatalf("unexpected audit log %+v", records)
+	}
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/admin/commands?limit=1", nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	r.Header.Set(adminKeyHeader, "tok")
+	NewMux(&noopSupervisor{}).ServeHTTP(rr, r)
+	var resp struct {
+		Data struct {
+			Commands []update.CommandRecord `json:"commands"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil || len(resp.Data.Commands) != 1 || resp.Data.Commands[0].ID != "forged-1" {
+		t.Fatalf("unexpected /admin/commands response %d %+v", rr.Code, resp.Data)
+	}
+}
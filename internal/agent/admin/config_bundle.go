@@ -0,0 +1,136 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/supervisor"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// configBundleName is the bundle /admin/config/apply fetches unless ?name is given.
+func configBundleName(r *http.Request) string {
+	if name := strings.TrimSpace(r.URL.Query().Get("name")); name != "" {
+		return name
+	}
+	return strings.TrimSpace(os.Getenv("PAYRAM_AGENT_CONFIG_BUNDLE"))
+}
+
+// bundleView is an installed bundle without its signed bytes.
+func bundleView(b *update.AppliedBundle) map[string]any {
+	if b == nil {
+		return nil
+	}
+	return map[string]any{"name": b.Name, "version": b.Version, "applied_at": b.AppliedAt, "settings": b.Settings}
+}
+
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET allowed")
+		return
+	}
+	st, err := update.LoadBundleState()
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "CONFIG_LOAD_FAILED", err.Error())
+		return
+	}
+	RespondOK(w, http.StatusOK, map[string]any{"current": bundleView(st.Current), "previous": bundleView(st.Previous)})
+}
+
+// configApplyHandler fetches a signed config bundle, installs it and restarts the children
+// with it. A bundle that fails verification or validation is never installed; one whose
+// children do not come back healthy is replaced by the bundle before it.
+func configApplyHandler(sup Supervisor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only POST allowed")
+			return
+		}
+		if refuseReadOnly(w, "applying a config bundle") {
+			return
+		}
+
+		baseURL := os.Getenv("PAYRAM_AGENT_UPDATE_BASE_URL")
+		if baseURL == "" {
+			RespondError(w, http.StatusInternalServerError, "UPDATE_BASE_URL_MISSING", "update base URL not configured")
+			return
+		}
+		pub := os.Getenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64")
+		if pub == "" {
+			RespondError(w, http.StatusInternalServerError, "UPDATE_PUBKEY_MISSING", "update public key not configured")
+			return
+		}
+
+		// Restarts for a bundle must not interleave with an update's.
+		unlock, err := update.AcquireUpdateLock()
+		if err != nil {
+			if errors.Is(err, update.ErrUpdateInProgress) {
+				RespondError(w, http.StatusConflict, "UPDATE_IN_PROGRESS", "update already in progress")
+				return
+			}
+			RespondError(w, http.StatusInternalServerError, "LOCK_FAILED", err.Error())
+			return
+		}
+		defer func() { _ = unlock() }()
+
+		name := configBundleName(r)
+		history := update.HistoryEntry{Action: "config", RemoteAddr: r.RemoteAddr}
+		var failure update.UpdateStatus
+		succeeded := false
+		fail := func(status int, code, msg string) {
+			failure.MarkFailure(code, msg)
+			RespondError(w, status, code, msg)
+		}
+		defer func() { recordHistory(history, failure, succeeded) }()
+
+		before, err := update.LoadBundleState()
+		if err != nil {
+			fail(http.StatusInternalServerError, "CONFIG_LOAD_FAILED", err.Error())
+			return
+		}
+		if before.Current != nil {
+			history.FromVersion = before.Current.Version
+		}
+
+		bundle, raw, sig, err := update.FetchConfigBundle(r.Context(), baseURL, name, pub)
+		var sigErr *update.SignatureError
+		switch {
+		case errors.As(err, &sigErr):
+			signatureFailures.Inc()
+			fail(http.StatusInternalServerError, "SIGNATURE_INVALID", err.Error())
+			return
+		case errors.Is(err, update.ErrInvalidConfigBundle):
+			history.ToVersion = bundle.Version
+			fail(http.StatusBadRequest, "CONFIG_INVALID", err.Error())
+			return
+		case err != nil:
+			fail(http.StatusInternalServerError, "CONFIG_FETCH_FAILED", err.Error())
+			return
+		}
+		history.ToVersion = bundle.Version
+
+		if cur := before.Current; cur != nil && string(cur.Raw) == string(raw) {
+			succeeded = true
+			history.Message = "bundle unchanged"
+			RespondOK(w, http.StatusOK, map[string]any{"ok": true, "version": bundle.Version, "unchanged": true})
+			return
+		}
+
+		if _, err := update.InstallConfigBundle(name, bundle, raw, sig); err != nil {
+			fail(http.StatusInternalServerError, "CONFIG_SAVE_FAILED", err.Error())
+			return
+		}
+		restarts := sup.RestartAllAndWait(healthTimeout())
+		if healthErr := supervisor.RestartError(restarts); healthErr != nil {
+			_ = update.RestoreBundleState(before)
+			_ = sup.RestartAllAndWait(healthTimeout())
+			fail(http.StatusInternalServerError, "CONFIG_FAILED_ROLLED_BACK", healthErr.Error())
+			return
+		}
+
+		succeeded = true
+		RespondOK(w, http.StatusOK, map[string]any{"ok": true, "version": bundle.Version, "previous_version": history.FromVersion, "restarts": restarts})
+	}
+}
@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/supervisor"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// bundleSupervisor restarts into the installed bundle, failing health when it sets BREAK.
+type bundleSupervisor struct{ seen []string }
+
+func (b *bundleSupervisor) RestartAllAndWait(time.Duration) []supervisor.RestartResult {
+	settings := update.BundleSettings("mcp")
+	b.seen = append(b.seen, settings["PAYRAM_ANALYTICS_MAX_PAGES"])
+	res := supervisor.RestartResult{Name: "mcp", PID: 1, Ready: settings["BREAK"] == ""}
+	if !res.Ready {
+		res.Error = "health: connection refused"
+	}
+	return []supervisor.RestartResult{res}
+}
+
+func (b *bundleSupervisor) Status() supervisor.Status { return supervisor.Status{} }
+func (b *bundleSupervisor) Logs(string, int) []string { return nil }
+
+func TestConfigApplyInstallsAndRollsBack(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	bundles := map[string]update.ConfigBundle{
+		"good":     {Version: "1", Settings: map[string]map[string]string{"mcp": {"PAYRAM_ANALYTICS_MAX_PAGES": "50"}}},
+		"broken":   {Version: "2", Settings: map[string]map[string]string{"mcp": {"PAYRAM_ANALYTICS_MAX_PAGES": "5", "BREAK": "1"}}},
+		"reserved": {Version: "3", Settings: map[string]map[string]string{"chat": {"OPENAI_API_KEY": "sk-other"}}},
+	}
+	mux := http.NewServeMux()
+	for name, b := range bundles {
+		raw, _ := json.Marshal(b)
+		mux.HandleFunc("/config/"+name+".json", func(w http.ResponseWriter, _ *http.Request) { w.Write(raw) })
+		mux.HandleFunc("/config/"+name+".json.sig", func(w http.ResponseWriter, _ *http.Request) { w.Write(ed25519.Sign(priv, raw)) })
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "")
+	t.Setenv("PAYRAM_AGENT_UPDATE_BASE_URL", srv.URL)
+	t.Setenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64", base64.StdEncoding.EncodeToString(pub))
+
+	sup := &bundleSupervisor{}
+	handler := NewMux(sup)
+	apply := func(name string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/config/apply?name="+name, nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		req.Header.Set(adminKeyHeader, "tok")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		var body map[string]any
+		_ = json.NewDecoder(rr.Body).Decode(&body)
+		return rr.Code, body
+	}
+
+	if code, body := apply("good"); code != http.StatusOK {
+		t.Fatalf("good bundle: %d %v", code, body)
+	}
+	if code, body := apply("reserved"); code != http.StatusBadRequest || body["error"].(map[string]any)["code"] != "CONFIG_INVALID" {
+		t.Fatalf("reserved bundle: %d %v", code, body)
+	}
+	if code, body := apply("broken"); code != http.StatusInternalServerError || body["error"].(map[string]any)["code"] != "CONFIG_FAILED_ROLLED_BACK" {
+		t.Fatalf("broken bundle: %d %v", code, body)
+	}
+
+	// The broken bundle was restarted into, then the good one again; the reserved one never was.
+	if len(sup.seen) != 3 || sup.seen[0] != "50" || sup.seen[1] != "5" || sup.seen[2] != "50" {
+		t.Fatalf("restarts saw %v", sup.seen)
+	}
+	st, err := update.LoadBundleState()
+	if err != nil || st.Current == nil || st.Current.Version != "1" {
+		t.Fatalf("expected bundle 1 restored: %+v, %v", st.Current, err)
+	}
+
+	entries, err := update.LoadHistory(10)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("history: %+v, %v", entries, err)
+	}
+	if entries[0].Action != "config" || entries[0].Result != "failed" || entries[0].ErrorCode != "CONFIG_FAILED_ROLLED_BACK" || entries[0].FromVersion != "1" {
+		t.Fatalf("unexpected newest entry %+v", entries[0])
+	}
+}
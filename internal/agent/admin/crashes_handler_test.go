@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/supervisor"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+func TestCrashesHandler(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "127.0.0.1/32")
+
+	dir := update.CrashDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []supervisor.CrashReport{
+		{ID: "20260101T000000.000000000Z-mcp-10", Component: "mcp", PID: 10, ExitCode: 2, Stderr: []string{"panic: boom"}},
+		{ID: "20260102T000000.000000000Z-chat-11", Component: "chat", PID: 11, ExitCode: 1, Stderr: []string{}},
+	} {
+		raw, _ := json.Marshal(r)
+		if err := os.WriteFile(filepath.Join(dir, r.ID+".json"), raw, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := NewMux(&noopSupervisor{})
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/crashes"+query, nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		req.Header.Set(adminKeyHeader, "tok")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var list struct {
+		Data struct {
+			Crashes []supervisor.CrashReport `json:"crashes"`
+		} `json:"data"`
+	}
+	rr := get("")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list: %d %s", rr.Code, rr.Body.String())
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Data.Crashes) != 2 || list.Data.Crashes[0].Component != "chat" {
+		t.Fatalf("expected both reports, newest first: %+v", list.Data.Crashes)
+	}
+
+	rr = get("?component=mcp")
+	list.Data.Crashes = nil
+	_ = json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Data.Crashes) != 1 || list.Data.Crashes[0].PID != 10 {
+		t.Fatalf("component filter: %+v", list.Data.Crashes)
+	}
+
+	var one struct {
+		Data supervisor.CrashReport `json:"data"`
+	}
+	rr = get("?id=20260101T000000.000000000Z-mcp-10")
+	if err := json.Unmarshal(rr.Body.Bytes(), &one); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("get: %d %s", rr.Code, rr.Body.String())
+	}
+	if one.Data.ExitCode != 2 || len(one.Data.Stderr) != 1 {
+		t.Fatalf("unexpected report %+v", one.Data)
+	}
+
+	if rr := get("?id=../status"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a path id, got %d", rr.Code)
+	}
+	if rr := get("?component=bogus"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown component, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,115 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/lifecycle"
+)
+
+// eventsHeartbeat is how often an idle event stream sends a comment, so proxies keep it open.
+const eventsHeartbeat = 15 * time.Second
+
+// eventsHandler streams lifecycle events as server-sent events, starting with the next one.
+// Each event's id is its Seq; a client that reconnects with Last-Event-ID (or ?after=) first
+// receives the kept events it missed. ?types= takes comma-separated type prefixes such as
+// "update.,child.exited".
+func eventsHandler(bus *lifecycle.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET allowed")
+			return
+		}
+
+		after := r.Header.Get("Last-Event-ID")
+		if v := r.URL.Query().Get("after"); v != "" {
+			after = v
+		}
+		var seq int64
+		if recent := bus.Recent(1); len(recent) == 1 {
+			seq = recent[0].Seq
+		}
+		if after != "" {
+			n, err := strconv.ParseInt(after, 10, 64)
+			if err != nil || n < 0 {
+				RespondError(w, http.StatusBadRequest, "INVALID_AFTER", "after must be an event seq")
+				return
+			}
+			seq = n
+		}
+		var types []string
+		for _, t := range strings.Split(r.URL.Query().Get("types"), ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+
+		rc := http.NewResponseController(w)
+		// The stream outlives the server's write timeout.
+		_ = rc.SetWriteDeadline(time.Time{})
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		backlog, events, cancel := bus.Subscribe(seq)
+		defer cancel()
+
+		send := func(e lifecycle.Event) error {
+			if !matchesType(e.Type, types) {
+				return nil
+			}
+			raw, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, raw); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+		for _, e := range backlog {
+			if send(e) != nil {
+				return
+			}
+		}
+		if rc.Flush() != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(eventsHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || rc.Flush() != nil {
+					return
+				}
+			case e, open := <-events:
+				// A closed channel means the client fell behind; it reconnects with
+				// Last-Event-ID and catches up from the kept events.
+				if !open || send(e) != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func matchesType(typ string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(typ, p) {
+			return true
+		}
+	}
+	return false
+}
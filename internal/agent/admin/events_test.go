@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/lifecycle"
+)
+
+func TestEventsStreamReplaysAndFilters(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "")
+	srv := httptest.NewServer(NewMux(&fakeSupervisor{}))
+	defer srv.Close()
+
+	missed := lifecycle.Default.Publish(lifecycle.Event{Type: lifecycle.UpdateApply, Data: map[string]any{"result": "success"}})
+	lifecycle.Default.Publish(lifecycle.Event{Type: lifecycle.ChildStarted, Component: "chat"})
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/admin/events?types=update.", nil)
+	req.Header.Set(adminKeyHeader, "tok")
+	req.Header.Set("Last-Event-ID", fmt.Sprint(missed.Seq-1))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	next := func() lifecycle.Event {
+		t.Helper()
+		var id, typ string
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatal("stream ended")
+				}
+				switch {
+				case strings.HasPrefix(line, "id: "):
+					id = strings.TrimPrefix(line, "id: ")
+				case strings.HasPrefix(line, "event: "):
+					typ = strings.TrimPrefix(line, "event: ")
+				case strings.HasPrefix(line, "data: "):
+					var e lifecycle.Event
+					if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+						t.Fatal(err)
+					}
+					if id != fmt.Sprint(e.Seq) || typ != e.Type {
+						t.Fatalf("id %q / event %q do not match %+v", id, typ, e)
+					}
+					return e
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no event")
+			}
+		}
+	}
+
+	// The replayed apply comes first; the child event is filtered out.
+	if e := next(); e.Seq != missed.Seq || e.Data["result"] != "success" {
+		t.Fatalf("replayed event = %+v", e)
+	}
+	lifecycle.Default.Publish(lifecycle.Event{Type: lifecycle.ChildExited, Component: "mcp"})
+	live := lifecycle.Default.Publish(lifecycle.Event{Type: lifecycle.UpdateRollback})
+	if e := next(); e.Seq != live.Seq {
+		t.Fatalf("live event = %+v, want seq %d", e, live.Seq)
+	}
+}
+
+func TestEventsRejectsBadAfter(t *testing.T) {
+	rr := adminRequest(t, &fakeSupervisor{}, http.MethodGet, "/admin/events?after=x")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	if code := errorCode(t, decodeBody(t, rr)); code != "INVALID_AFTER" {
+		t.Fatalf("expected INVALID_AFTER, got %s", code)
+	}
+}
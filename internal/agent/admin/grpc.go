@@ -0,0 +1,321 @@
+package admin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/admin/adminpb"
+)
+
+// GRPCErrorDomain is the domain of the ErrorInfo detail on every gRPC error; its reason is
+// the error code the HTTP API would return.
+const GRPCErrorDomain = "agent.payram.com"
+
+const (
+	// logFollowWindow is how many lines StreamLogs compares between polls to find new ones.
+	logFollowWindow   = 1000
+	logFollowInterval = 500 * time.Millisecond
+)
+
+// GRPCServerFromEnv configures the gRPC admin server from PAYRAM_AGENT_GRPC_LISTEN_ADDR; ok
+// is false when it is unset. The server only accepts clients with a certificate signed by
+// PAYRAM_AGENT_GRPC_CLIENT_CA_FILE and presents PAYRAM_AGENT_GRPC_CERT_FILE and
+// PAYRAM_AGENT_GRPC_KEY_FILE, which are then all required.
+func GRPCServerFromEnv(sup Supervisor) (srv *grpc.Server, addr string, ok bool, err error) {
+	addr = strings.TrimSpace(os.Getenv("PAYRAM_AGENT_GRPC_LISTEN_ADDR"))
+	if addr == "" {
+		return nil, "", false, nil
+	}
+	tlsCfg, err := grpcTLSConfig(os.Getenv("PAYRAM_AGENT_GRPC_CERT_FILE"), os.Getenv("PAYRAM_AGENT_GRPC_KEY_FILE"), os.Getenv("PAYRAM_AGENT_GRPC_CLIENT_CA_FILE"))
+	if err != nil {
+		return nil, "", false, err
+	}
+	return NewGRPCServer(sup, tlsCfg), addr, true, nil
+}
+
+func grpcTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" || clientCAFile == "" {
+		return nil, errors.New("PAYRAM_AGENT_GRPC_CERT_FILE, PAYRAM_AGENT_GRPC_KEY_FILE and PAYRAM_AGENT_GRPC_CLIENT_CA_FILE are required")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("grpc certificate: %w", err)
+	}
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("grpc client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("grpc client CA: no certificates in %s", clientCAFile)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// NewGRPCServer returns a server for the AgentAdminService over mutual TLS. Calls are served
+// by the admin HTTP handlers, so both APIs behave alike; the update history names the
+// client by its certificate's common name.
+func NewGRPCServer(sup Supervisor, tlsCfg *tls.Config) *grpc.Server {
+	if sup == nil {
+		panic("supervisor must not be nil")
+	}
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsCfg)))
+	adminpb.RegisterAgentAdminServiceServer(srv, &grpcAdmin{sup: sup})
+	return srv
+}
+
+type grpcAdmin struct {
+	adminpb.UnimplementedAgentAdminServiceServer
+	sup Supervisor
+}
+
+func (g *grpcAdmin) GetStatus(ctx context.Context, _ *adminpb.GetStatusRequest) (*adminpb.GetStatusResponse, error) {
+	res := &adminpb.GetStatusResponse{Update: &adminpb.UpdateStatus{}}
+	if err := g.call(ctx, updateStatusHandler, http.MethodGet, "/admin/update/status", nil, res.Update); err != nil {
+		return nil, err
+	}
+	for _, c := range g.sup.Status().Components {
+		child := &adminpb.ChildStatus{
+			Name:       c.Name,
+			Pid:        int32(c.PID),
+			StartTime:  grpcTime(c.StartTime),
+			Restarts:   int32(c.Restarts),
+			WaitingFor: c.WaitingFor,
+		}
+		if c.LastExit != nil {
+			child.LastExit = &adminpb.ExitInfo{Time: grpcTime(c.LastExit.Time), ExitCode: int32(c.LastExit.ExitCode), Error: c.LastExit.Error}
+		}
+		res.Children = append(res.Children, child)
+	}
+	return res, nil
+}
+
+func (g *grpcAdmin) StreamLogs(req *adminpb.StreamLogsRequest, stream grpc.ServerStreamingServer[adminpb.StreamLogsResponse]) error {
+	if req.GetComponent() == "" {
+		return grpcError(http.StatusBadRequest, &respError{Code: "INVALID_ARGUMENT", Message: "component is required"})
+	}
+	tail := int(req.GetTail())
+	if tail <= 0 {
+		tail = 200
+	}
+	window := g.sup.Logs(req.GetComponent(), max(tail, logFollowWindow))
+	if window == nil {
+		return grpcError(http.StatusBadRequest, &respError{Code: "INVALID_COMPONENT", Message: "component must be chat or mcp"})
+	}
+	send := func(lines []string) error {
+		for _, line := range lines {
+			if err := stream.Send(&adminpb.StreamLogsResponse{Component: req.GetComponent(), Line: line}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := send(window[max(0, len(window)-tail):]); err != nil || !req.GetFollow() {
+		return err
+	}
+
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+		current := g.sup.Logs(req.GetComponent(), logFollowWindow)
+		if err := send(linesAfter(window, current)); err != nil {
+			return err
+		}
+		window = current
+	}
+}
+
+// linesAfter returns the lines of cur logged after prev, two snapshots of the end of the same
+// buffer: those past the longest end of prev that cur starts with.
+func linesAfter(prev, cur []string) []string {
+	for k := min(len(prev), len(cur)); k > 0; k-- {
+		if equalLines(prev[len(prev)-k:], cur[:k]) {
+			return cur[k:]
+		}
+	}
+	return cur
+}
+
+func equalLines(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *grpcAdmin) CheckUpdate(ctx context.Context, req *adminpb.CheckUpdateRequest) (*adminpb.CheckUpdateResponse, error) {
+	query := url.Values{}
+	if req.GetChannel() != "" {
+		query.Set("channel", req.GetChannel())
+	}
+	res := &adminpb.CheckUpdateResponse{}
+	if err := g.call(ctx, updateAvailableHandler, http.MethodGet, "/admin/update/available", query, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (g *grpcAdmin) ApplyUpdate(ctx context.Context, req *adminpb.ApplyUpdateRequest) (*adminpb.ApplyUpdateResponse, error) {
+	query := url.Values{}
+	if req.GetChannel() != "" {
+		query.Set("channel", req.GetChannel())
+	}
+	if req.GetDryRun() {
+		query.Set("dry_run", "1")
+	}
+	if req.GetForceChannel() {
+		query.Set("force_channel", "1")
+	}
+	if req.CanarySoakMs != nil {
+		query.Set("canary_soak_ms", strconv.FormatInt(req.GetCanarySoakMs(), 10))
+	}
+
+	res := &adminpb.ApplyUpdateResponse{}
+	if req.GetDryRun() {
+		res.Plan = &structpb.Struct{}
+		if err := g.call(ctx, updateApplyHandler(g.sup), http.MethodPost, "/admin/update/apply", query, res.Plan); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+	if err := g.call(ctx, updateApplyHandler(g.sup), http.MethodPost, "/admin/update/apply", query, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (g *grpcAdmin) Rollback(ctx context.Context, _ *adminpb.RollbackRequest) (*adminpb.RollbackResponse, error) {
+	res := &adminpb.RollbackResponse{}
+	if err := g.call(ctx, updateRollbackHandler(g.sup), http.MethodPost, "/admin/update/rollback", nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// call serves the request with an admin handler and decodes its data into out. Fields the
+// message does not have are dropped, and zero times are left unset.
+func (g *grpcAdmin) call(ctx context.Context, h http.HandlerFunc, method, path string, query url.Values, out proto.Message) error {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	code, resp := callHandler(ctx, h, method, path, grpcCaller(ctx))
+	if resp.Error != nil || !resp.Ok {
+		e := resp.Error
+		if e == nil {
+			e = &respError{Code: "INTERNAL", Message: fmt.Sprintf("handler answered HTTP %d", code)}
+		}
+		return grpcError(code, e)
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(resp.Data, out); err != nil {
+		return status.Errorf(codes.Internal, "decoding %s response: %v", path, err)
+	}
+	clearZeroTimes(out.ProtoReflect())
+	return nil
+}
+
+// grpcCaller names the client for the update history: "grpc:" and the common name of its
+// verified certificate, or its address.
+func grpcCaller(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "grpc"
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 && len(info.State.VerifiedChains[0]) > 0 {
+		if cn := info.State.VerifiedChains[0][0].Subject.CommonName; cn != "" {
+			return "grpc:" + cn
+		}
+	}
+	return "grpc:" + p.Addr.String()
+}
+
+// grpcError turns an admin API error into a gRPC status with the same code as its reason.
+func grpcError(httpStatus int, e *respError) error {
+	st := status.New(grpcCode(httpStatus), e.Message)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: e.Code, Domain: GRPCErrorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+func grpcTime(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// zeroTimeSeconds is Go's zero time.Time, which the HTTP API writes for times never set.
+var zeroTimeSeconds = time.Time{}.Unix()
+
+func clearZeroTimes(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			if fd.IsList() && fd.Kind() == protoreflect.MessageKind {
+				for i := 0; i < v.List().Len(); i++ {
+					clearZeroTimes(v.List().Get(i).Message())
+				}
+			}
+			return true
+		}
+		if ts, ok := v.Message().Interface().(*timestamppb.Timestamp); ok {
+			if ts.GetSeconds() == zeroTimeSeconds && ts.GetNanos() == 0 {
+				m.Clear(fd)
+			}
+			return true
+		}
+		clearZeroTimes(v.Message())
+		return true
+	})
+}
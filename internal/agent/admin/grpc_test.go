@@ -0,0 +1,187 @@
+package admin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/admin/adminpb"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// testPKI is a CA with a server certificate for 127.0.0.1 and a client certificate.
+type testPKI struct {
+	pool   *x509.CertPool
+	server tls.Certificate
+	client tls.Certificate
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	issue := func(serial int64, cn string, usage x509.ExtKeyUsage) tls.Certificate {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	return testPKI{
+		pool:   pool,
+		server: issue(2, "agent", x509.ExtKeyUsageServerAuth),
+		client: issue(3, "operator", x509.ExtKeyUsageClientAuth),
+	}
+}
+
+func startGRPC(t *testing.T, sup Supervisor, pki testPKI) string {
+	t.Helper()
+	srv := NewGRPCServer(sup, &tls.Config{
+		Certificates: []tls.Certificate{pki.server},
+		ClientCAs:    pki.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func dialGRPC(t *testing.T, addr string, cfg *tls.Config) adminpb.AgentAdminServiceClient {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return adminpb.NewAgentAdminServiceClient(conn)
+}
+
+func TestGRPCAdminOverMutualTLS(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_UPDATE_BASE_URL", "")
+
+	status0, _ := update.LoadStatus()
+	status0.CurrentVersion = "1.2.0"
+	status0.CurrentChannel = "beta"
+	if err := update.SaveStatus(status0); err != nil {
+		t.Fatal(err)
+	}
+
+	pki := newTestPKI(t)
+	addr := startGRPC(t, &logSupervisor{}, pki)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Without a client certificate the handshake fails.
+	anonymous := dialGRPC(t, addr, &tls.Config{RootCAs: pki.pool, ServerName: "127.0.0.1"})
+	if _, err := anonymous.GetStatus(ctx, &adminpb.GetStatusRequest{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("call without client certificate: %v", err)
+	}
+
+	client := dialGRPC(t, addr, &tls.Config{RootCAs: pki.pool, ServerName: "127.0.0.1", Certificates: []tls.Certificate{pki.client}})
+	st, err := client.GetStatus(ctx, &adminpb.GetStatusRequest{})
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if st.GetUpdate().GetCurrentVersion() != "1.2.0" || st.GetUpdate().GetCurrentChannel() != "beta" {
+		t.Fatalf("unexpected update status %v", st.GetUpdate())
+	}
+	if st.GetUpdate().GetLastSuccessAt() != nil {
+		t.Fatalf("unset time reported as %v", st.GetUpdate().GetLastSuccessAt())
+	}
+
+	// HTTP API errors keep their code as the ErrorInfo reason.
+	_, err = client.CheckUpdate(ctx, &adminpb.CheckUpdateRequest{})
+	if status.Code(err) != codes.Internal || grpcReason(err) != "UPDATE_BASE_URL_MISSING" {
+		t.Fatalf("CheckUpdate without base URL: %v (reason %q)", err, grpcReason(err))
+	}
+
+	stream, err := client.StreamLogs(ctx, &adminpb.StreamLogsRequest{Component: "mcp", Tail: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := stream.Recv()
+	if err != nil || line.GetLine() != "mcp line (tail 1000)" {
+		t.Fatalf("StreamLogs: %v, %v", line, err)
+	}
+
+	stream, err = client.StreamLogs(ctx, &adminpb.StreamLogsRequest{Component: "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument || grpcReason(err) != "INVALID_COMPONENT" {
+		t.Fatalf("StreamLogs for unknown component: %v", err)
+	}
+}
+
+func grpcReason(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetDomain() == GRPCErrorDomain {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
+
+func TestLinesAfter(t *testing.T) {
+	cases := []struct {
+		prev, cur, want []string
+	}{
+		{[]string{"a", "b"}, []string{"a", "b", "c"}, []string{"c"}},
+		{[]string{"a", "b", "c"}, []string{"b", "c", "d", "e"}, []string{"d", "e"}},
+		{[]string{"a", "b"}, []string{"a", "b"}, []string{}},
+		{[]string{"a"}, []string{"x", "y"}, []string{"x", "y"}},
+		{nil, []string{"x"}, []string{"x"}},
+	}
+	for _, c := range cases {
+		if got := linesAfter(c.prev, c.cur); !slices.Equal(got, c.want) {
+			t.Errorf("linesAfter(%v, %v) = %v, want %v", c.prev, c.cur, got, c.want)
+		}
+	}
+}
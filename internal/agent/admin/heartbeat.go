@@ -0,0 +1,269 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/secrets"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+	"github.com/payram/payram-analytics-mcp-server/internal/version"
+)
+
+// Headers identifying the agent to the fleet endpoints. The signature is "ed25519=" and the
+// base64 signature of the body under the agent's identity key, whose public half is sent in
+// FleetKeyHeader.
+const (
+	FleetIDHeader        = "X-Payram-Agent-Id"
+	FleetKeyHeader       = "X-Payram-Agent-Key"
+	FleetSignatureHeader = "X-Payram-Agent-Signature"
+)
+
+const defaultHeartbeatInterval = time.Minute
+
+// HeartbeatReport is what each heartbeat tells the fleet endpoint about the install.
+type HeartbeatReport struct {
+	AgentID      string                    `json:"agent_id"`
+	Hostname     string                    `json:"hostname,omitempty"`
+	SentAt       time.Time                 `json:"sent_at"`
+	Agent        version.Info              `json:"agent"`
+	Version      string                    `json:"version"`
+	Channel      string                    `json:"channel,omitempty"`
+	Healthy      bool                      `json:"healthy"`
+	Children     map[string]heartbeatChild `json:"children"`
+	Update       heartbeatUpdate           `json:"update"`
+	ConfigBundle string                    `json:"config_bundle,omitempty"`
+}
+
+type heartbeatChild struct {
+	Ready    bool   `json:"ready"`
+	Version  string `json:"version,omitempty"`
+	Drift    bool   `json:"drift"`
+	Restarts int    `json:"restarts"`
+}
+
+type heartbeatUpdate struct {
+	LastSuccessVersion string    `json:"last_success_version,omitempty"`
+	LastSuccessAt      time.Time `json:"last_success_at,omitzero"`
+	LastAttemptVersion string    `json:"last_attempt_version,omitempty"`
+	LastAttemptAt      time.Time `json:"last_attempt_at,omitzero"`
+	LastErrorCode      string    `json:"last_error_code,omitempty"`
+	LastErrorMessage   string    `json:"last_error_message,omitempty"`
+	LastErrorAt        time.Time `json:"last_error_at,omitzero"`
+	InProgress         bool      `json:"in_progress"`
+	Canary             bool      `json:"canary"`
+}
+
+// Heartbeat periodically posts a signed HeartbeatReport to a fleet endpoint.
+type Heartbeat struct {
+	URL      string
+	Interval time.Duration
+	Key      ed25519.PrivateKey
+	Client   *http.Client
+
+	sup Supervisor
+
+	mu       sync.Mutex
+	lastSent time.Time
+	lastErr  string
+}
+
+// heartbeat is the agent's configured heartbeat, reported on /admin/heartbeat; nil when off.
+var (
+	heartbeatMu sync.Mutex
+	heartbeat   *Heartbeat
+)
+
+// HeartbeatFromEnv configures a heartbeat from PAYRAM_AGENT_HEARTBEAT_URL and
+// PAYRAM_AGENT_HEARTBEAT_INTERVAL_MS, loading or creating the identity key; ok is false when
+// no URL is set.
+func HeartbeatFromEnv(sup Supervisor) (h *Heartbeat, ok bool, err error) {
+	url := strings.TrimSpace(os.Getenv("PAYRAM_AGENT_HEARTBEAT_URL"))
+	if url == "" {
+		return nil, false, nil
+	}
+	key, err := secrets.IdentityKey(update.HomeDir())
+	if err != nil {
+		return nil, false, fmt.Errorf("identity key: %w", err)
+	}
+	h = &Heartbeat{
+		URL:      url,
+		Interval: envMillis("PAYRAM_AGENT_HEARTBEAT_INTERVAL_MS", defaultHeartbeatInterval),
+		Key:      key,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		sup:      sup,
+	}
+	heartbeatMu.Lock()
+	heartbeat = h
+	heartbeatMu.Unlock()
+	return h, true, nil
+}
+
+// AgentID names the agent to the fleet endpoint.
+func (h *Heartbeat) AgentID() string {
+	return secrets.AgentID(h.Key.Public().(ed25519.PublicKey))
+}
+
+// Run sends a heartbeat right away and then every Interval until ctx is done. A failed
+// heartbeat is logged and counted; the next one is sent on schedule.
+func (h *Heartbeat) Run(ctx context.Context) {
+	interval := h.Interval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.beat(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Heartbeat) beat(ctx context.Context) {
+	err := h.Send(ctx)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		heartbeatFailures.Inc()
+		h.lastErr = err.Error()
+		log.Printf("fleet heartbeat: %v", err)
+		return
+	}
+	h.lastSent, h.lastErr = time.Now(), ""
+}
+
+// Send posts one heartbeat.
+func (h *Heartbeat) Send(ctx context.Context) error {
+	report := h.Report(ctx)
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signAgentRequest(req, h.Key, body)
+	return postFleet(h.Client, req)
+}
+
+// signAgentRequest identifies the agent on a request to the fleet, signing signed (the body,
+// or the query string of a GET) with its identity key.
+func signAgentRequest(req *http.Request, key ed25519.PrivateKey, signed []byte) {
+	pub := key.Public().(ed25519.PublicKey)
+	req.Header.Set(FleetIDHeader, secrets.AgentID(pub))
+	req.Header.Set(FleetKeyHeader, base64.StdEncoding.EncodeToString(pub))
+	req.Header.Set(FleetSignatureHeader, "ed25519="+base64.StdEncoding.EncodeToString(ed25519.Sign(key, signed)))
+}
+
+// postFleet sends req, failing on any status other than 2xx.
+func postFleet(client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("fleet endpoint returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Report gathers the current state of the install.
+func (h *Heartbeat) Report(ctx context.Context) HeartbeatReport {
+	hostname, _ := os.Hostname()
+	status, _ := update.LoadStatus()
+	chat, mcp, expected := childVersions(ctx, h.sup)
+	restarts := map[string]int{}
+	for _, c := range h.sup.Status().Components {
+		restarts[c.Name] = c.Restarts
+	}
+
+	report := HeartbeatReport{
+		AgentID:  h.AgentID(),
+		Hostname: hostname,
+		SentAt:   time.Now().UTC(),
+		Agent:    version.Get(),
+		Version:  expected,
+		Channel:  status.CurrentChannel,
+		Healthy:  chat.Ready && mcp.Ready,
+		Children: map[string]heartbeatChild{
+			"chat": newHeartbeatChild(chat, restarts["chat"]),
+			"mcp":  newHeartbeatChild(mcp, restarts["mcp"]),
+		},
+		Update: heartbeatUpdate{
+			LastSuccessVersion: status.LastSuccessVersion,
+			LastSuccessAt:      status.LastSuccessAt,
+			LastAttemptVersion: status.LastAttemptVersion,
+			LastAttemptAt:      status.LastAttemptAt,
+			LastErrorCode:      status.LastErrorCode,
+			LastErrorMessage:   status.LastErrorMessage,
+			LastErrorAt:        status.LastErrorAt,
+			InProgress:         status.InProgress,
+			Canary:             status.Canary != nil,
+		},
+	}
+	if bundles, err := update.LoadBundleState(); err == nil && bundles.Current != nil {
+		report.ConfigBundle = bundles.Current.Version
+	}
+	return report
+}
+
+func newHeartbeatChild(res childVersionResult, restarts int) heartbeatChild {
+	c := heartbeatChild{Ready: res.Ready, Drift: res.Drift, Restarts: restarts}
+	if res.Info != nil {
+		c.Version = res.Info.Version
+	}
+	return c
+}
+
+func heartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET allowed")
+		return
+	}
+	heartbeatMu.Lock()
+	h := heartbeat
+	heartbeatMu.Unlock()
+	if h == nil {
+		RespondOK(w, http.StatusOK, map[string]any{"enabled": false})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	res := map[string]any{
+		"enabled":     true,
+		"url":         h.URL,
+		"interval_ms": h.Interval.Milliseconds(),
+		"agent_id":    h.AgentID(),
+		"public_key":  base64.StdEncoding.EncodeToString(h.Key.Public().(ed25519.PublicKey)),
+	}
+	if !h.lastSent.IsZero() {
+		res["last_sent_at"] = h.lastSent
+	}
+	if h.lastErr != "" {
+		res["last_error"] = h.lastErr
+	}
+	RespondOK(w, http.StatusOK, res)
+}
@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+func TestHeartbeatPostsSignedReport(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "")
+	t.Setenv("PAYRAM_CHAT_PORT", "1")
+	t.Setenv("PAYRAM_MCP_PORT", "1")
+
+	status, _ := update.LoadStatus()
+	status.CurrentChannel = "beta"
+	status.MarkFailure("UPDATE_FAILED_ROLLED_BACK", "mcp unhealthy")
+	if err := update.SaveStatus(status); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	fleet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer fleet.Close()
+	t.Setenv("PAYRAM_AGENT_HEARTBEAT_URL", fleet.URL)
+	t.Setenv("PAYRAM_AGENT_HEARTBEAT_INTERVAL_MS", "3600000")
+
+	hb, ok, err := HeartbeatFromEnv(&fakeSupervisor{})
+	if err != nil || !ok {
+		t.Fatalf("heartbeat: %v, %v", ok, err)
+	}
+	t.Cleanup(func() { heartbeat = nil })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hb.Run(ctx)
+
+	var req *http.Request
+	var body []byte
+	select {
+	case req = <-received:
+		body = <-bodies
+	case <-time.After(5 * time.Second):
+		t.Fatal("no heartbeat sent")
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(req.Header.Get(FleetKeyHeader))
+	if err != nil {
+		t.Fatalf("key header: %v", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(req.Header.Get(FleetSignatureHeader), "ed25519="))
+	if err != nil || !ed25519.Verify(pub, body, sig) {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+
+	var report HeartbeatReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if report.AgentID != hb.AgentID() || req.Header.Get(FleetIDHeader) != report.AgentID {
+		t.Fatalf("agent id %q, header %q, want %q", report.AgentID, req.Header.Get(FleetIDHeader), hb.AgentID())
+	}
+	if report.Channel != "beta" || report.Healthy || report.Update.LastErrorCode != "UPDATE_FAILED_ROLLED_BACK" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	// The status endpoint reports the identity once a heartbeat went out.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/admin/heartbeat", nil)
+		r.RemoteAddr = "127.0.0.1:1234"
+		r.Header.Set(adminKeyHeader, "tok")
+		NewMux(&fakeSupervisor{}).ServeHTTP(rr, r)
+		var resp struct {
+			Data map[string]any `json:"data"`
+		}
+		_ = json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.Data["last_sent_at"] != nil {
+			if resp.Data["agent_id"] != hb.AgentID() || resp.Data["public_key"] != req.Header.Get(FleetKeyHeader) {
+				t.Fatalf("unexpected heartbeat status %v", resp.Data)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("heartbeat not marked sent: %v", resp.Data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
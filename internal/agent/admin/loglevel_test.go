@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/logging"
+)
+
+func TestLogLevelForwardsToChildWithControlToken(t *testing.T) {
+	t.Setenv(logging.ControlTokenEnv, "child-secret")
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "")
+
+	var gotMethod string
+	child := http.NewServeMux()
+	child.Handle(logging.LevelPath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		logging.LevelHandler().ServeHTTP(w, r)
+	}))
+	srv := httptest.NewServer(child)
+	defer srv.Close()
+	t.Setenv("PAYRAM_CHAT_PORT", portFromURL(srv.URL))
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/loglevel", bytes.NewBufferString(`{"component":"chat-api","level":"debug"}`))
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set(adminKeyHeader, "tok")
+	rr := httptest.NewRecorder()
+	NewMux(&fakeSupervisor{}).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+	data := decodeBody(t, rr)["data"].(map[string]any)
+	chat, ok := data["chat-api"].(map[string]any)
+	if !ok || chat["levels"] == nil {
+		t.Fatalf("expected chat-api levels, got %v", data)
+	}
+	if _, ok := data["mcp"]; ok {
+		t.Fatalf("expected only chat-api to be targeted, got %v", data)
+	}
+	if gotMethod != http.MethodPut {
+		t.Fatalf("expected PUT forwarded to child, got %q", gotMethod)
+	}
+
+	resp, err := http.Get(srv.URL + logging.LevelPath)
+	if err != nil {
+		t.Fatalf("direct get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected child endpoint hidden without token, got %d", resp.StatusCode)
+	}
+}
+
+func TestLogLevelRejectsUnknownLevel(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "")
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/loglevel", bytes.NewBufferString(`{"component":"agent","level":"loud"}`))
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set(adminKeyHeader, "tok")
+	rr := httptest.NewRecorder()
+	NewMux(&fakeSupervisor{}).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || errorCode(t, decodeBody(t, rr)) != "INVALID_ARGUMENT" {
+		t.Fatalf("expected INVALID_ARGUMENT, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+	"github.com/payram/payram-analytics-mcp-server/internal/metrics"
+)
+
+// Update subsystem metrics, exported on /metrics.
+var (
+	updateDownloadBytes = metrics.Default.Counter("update_download_bytes_total",
+		"Bytes of release artifacts downloaded by the updater.")
+	artifactCacheHits = metrics.Default.Counter("update_artifact_cache_hits_total",
+		"Release artifacts taken from the local cache instead of downloaded.")
+	updateDuration = metrics.Default.Histogram("update_duration_seconds",
+		"Wall time of /admin/update/apply runs, successful or not.",
+		[]float64{1, 5, 10, 30, 60, 120, 300, 600})
+	rollbackTotal = metrics.Default.Counter("rollback_total",
+		"Rollbacks performed, automatic (failed health after apply) and manual.")
+	signatureFailures = metrics.Default.Counter("signature_failures_total",
+		"Manifests rejected because their signature did not verify.")
+	heartbeatFailures = metrics.Default.Counter("heartbeat_failures_total",
+		"Fleet heartbeats that could not be delivered.")
+	checksNotModified = metrics.Default.Counter("update_checks_not_modified_total",
+		"Update checks answered 304 Not Modified, so the cached manifest was used.")
+
+	// lastCheckUnix is the time of the last manifest fetch whose signature verified.
+	lastCheckUnix atomic.Int64
+)
+
+func init() {
+	metrics.Default.GaugeFunc("update_seconds_since_last_check",
+		"Seconds since the last successful (fetched and verified) update check; -1 if none since start.",
+		func() float64 {
+			last := lastCheckUnix.Load()
+			if last == 0 {
+				return -1
+			}
+			return time.Since(time.Unix(0, last)).Seconds()
+		})
+}
+
+// markUpdateCheck notes a manifest that was fetched, or found unchanged, and verified.
+func markUpdateCheck(check update.ManifestCheck) {
+	lastCheckUnix.Store(time.Now().UnixNano())
+	if check.NotModified {
+		checksNotModified.Inc()
+	}
+}
+
+// checkErrorCode maps an update.CheckManifest error to its error code, counting signature
+// failures. It is empty for a nil error.
+func checkErrorCode(err error) string {
+	var sigErr *update.SignatureError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &sigErr):
+		signatureFailures.Inc()
+		return "SIGNATURE_INVALID"
+	default:
+		return "UPDATE_FETCH_FAILED"
+	}
+}
@@ -0,0 +1,53 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+func TestMetricsTrackUpdateActivity(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_IGNORE_COMPAT", "1")
+	t.Setenv("PAYRAM_CORE_URL", "")
+	healthyChildren(t)
+
+	fixture := newReleaseFixture(t, update.Manifest{Version: "1.0.0"})
+	downloaded := updateDownloadBytes.Value()
+	rollbacks := rollbackTotal.Value()
+
+	sup := &fakeSupervisor{}
+	if rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply"); rr.Code != http.StatusOK {
+		t.Fatalf("apply: %d %s", rr.Code, rr.Body.String())
+	}
+	if got, want := updateDownloadBytes.Value()-downloaded, float64(len(fixture.chatData)+len(fixture.mcpData)); got != want {
+		t.Fatalf("expected %v downloaded bytes, got %v", want, got)
+	}
+
+	fixture.publish("stable", update.Manifest{Version: "1.1.0"})
+	if rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply"); rr.Code != http.StatusOK {
+		t.Fatalf("second apply: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := adminRequest(t, sup, http.MethodPost, "/admin/update/rollback"); rr.Code != http.StatusOK {
+		t.Fatalf("rollback: %d %s", rr.Code, rr.Body.String())
+	}
+	if rollbackTotal.Value()-rollbacks != 1 {
+		t.Fatalf("expected one rollback counted")
+	}
+
+	rr := adminRequest(t, sup, http.MethodGet, "/metrics")
+	body := rr.Body.String()
+	for _, name := range []string{
+		"update_download_bytes_total", "update_duration_seconds_count", "rollback_total",
+		"signature_failures_total", "update_seconds_since_last_check",
+	} {
+		if !strings.Contains(body, "\n"+name+" ") {
+			t.Fatalf("missing %s in metrics:\n%s", name, body)
+		}
+	}
+	if strings.Contains(body, "update_seconds_since_last_check -1") {
+		t.Fatalf("expected a recorded update check")
+	}
+}
@@ -18,6 +18,12 @@ func NewAdminMiddlewareFromEnv() func(http.Handler) http.Handler {
 	return NewAdminMiddleware(token, allowlist)
 }
 
+// newNetworkGuardFromEnv is the IP-only counterpart of NewAdminMiddlewareFromEnv.
+func newNetworkGuardFromEnv() func(http.Handler) http.Handler {
+	guard := &adminMiddleware{token: os.Getenv("PAYRAM_AGENT_ADMIN_TOKEN"), allowed: parseAllowlist(os.Getenv("PAYRAM_AGENT_ADMIN_ALLOWLIST"))}
+	return guard.wrapNetworkOnly
+}
+
 type adminMiddleware struct {
 	token   string
 	allowed []*net.IPNet
@@ -53,6 +59,24 @@ func (m *adminMiddleware) wrap(next http.Handler) http.Handler {
 	})
 }
 
+// wrapNetworkOnly applies the token-configured and IP allowlist checks but not the key check,
+// for static assets a browser loads without custom headers.
+func (m *adminMiddleware) wrapNetworkOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.token == "" {
+			RespondError(w, http.StatusInternalServerError, "ADMIN_TOKEN_MISSING", "admin token not configured")
+			return
+		}
+
+		if !m.isAllowed(parseRemoteIP(r.RemoteAddr)) {
+			RespondError(w, http.StatusForbidden, "FORBIDDEN_IP", "request IP not allowed")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (m *adminMiddleware) isAllowed(ip net.IP) bool {
 	if ip == nil {
 		return false
@@ -0,0 +1,9 @@
+package admin
+
+import _ "embed"
+
+// openAPIDoc describes the routes NewMux serves; openapi_test.go checks every documented path
+// is routed.
+//
+//go:embed openapi.json
+var openAPIDoc []byte
@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/openapi"
+	"github.com/payram/payram-analytics-mcp-server/internal/version"
+)
+
+func TestOpenAPIDocumentsRoutedPaths(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	mux, ok := NewMux(&fakeSupervisor{}).(*http.ServeMux)
+	if !ok {
+		t.Fatal("NewMux no longer returns a *http.ServeMux")
+	}
+
+	paths, err := openapi.Paths(openAPIDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		req := httptest.NewRequest(http.MethodGet, p, nil)
+		if _, pattern := mux.Handler(req); pattern != p {
+			t.Errorf("documented path %s is routed to %q", p, pattern)
+		}
+	}
+	for _, p := range []string{"/admin/update/apply", "/admin/secrets/openai", "/admin/loglevel", "/metrics"} {
+		if !slices.Contains(paths, p) {
+			t.Errorf("route %s is not documented", p)
+		}
+	}
+}
+
+func TestOpenAPIServedWithBuildVersion(t *testing.T) {
+	old := version.Version
+	version.Version = "1.2.3"
+	t.Cleanup(func() { version.Version = old })
+
+	rr := httptest.NewRecorder()
+	NewMux(&fakeSupervisor{}).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, openapi.Path, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 without a key, got %d", rr.Code)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Info.Version != "1.2.3" {
+		t.Fatalf("unexpected document header %+v", doc)
+	}
+}
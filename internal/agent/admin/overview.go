@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/supervisor"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+	"github.com/payram/payram-analytics-mcp-server/internal/version"
+)
+
+const (
+	overviewHistoryLimit = 10
+	overviewFetchTimeout = 5 * time.Second
+)
+
+type overviewRelease struct {
+	Version     string    `json:"version"`
+	Channel     string    `json:"channel,omitempty"`
+	InstalledAt time.Time `json:"installed_at,omitzero"`
+}
+
+type overviewAvailable struct {
+	Checked       bool       `json:"checked"`
+	Channel       string     `json:"channel"`
+	Available     bool       `json:"available"`
+	TargetVersion string     `json:"target_version,omitempty"`
+	ReleasedAt    time.Time  `json:"released_at,omitzero"`
+	Changelog     string     `json:"changelog,omitempty"`
+	Revoked       bool       `json:"revoked"`
+	Compatible    bool       `json:"compatible"`
+	Problems      []string   `json:"problems,omitempty"`
+	Warnings      []string   `json:"warnings,omitempty"`
+	Error         *respError `json:"error,omitempty"`
+}
+
+type overviewChild struct {
+	childVersionResult
+	PID       int                  `json:"pid"`
+	StartTime time.Time            `json:"start_time,omitzero"`
+	Restarts  int                  `json:"restarts"`
+	LastExit  *supervisor.ExitInfo `json:"last_exit,omitempty"`
+	// WaitingFor lists the dependencies the child is held for before it may start.
+	WaitingFor []string `json:"waiting_for,omitempty"`
+}
+
+// overviewHandler consolidates what a dashboard needs in one call: installed and previous
+// release, the available update with its changelog, recent update history, and child health.
+// A failed manifest fetch is reported under available.error rather than failing the request.
+func overviewHandler(sup Supervisor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET allowed")
+			return
+		}
+
+		limit := overviewHistoryLimit
+		if v := r.URL.Query().Get("history"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				RespondError(w, http.StatusBadRequest, "INVALID_LIMIT", "history must be a non-negative integer")
+				return
+			}
+			limit = n
+		}
+
+		status, err := update.LoadStatus()
+		if err != nil {
+			RespondError(w, http.StatusInternalServerError, "STATUS_LOAD_FAILED", err.Error())
+			return
+		}
+
+		history := []update.HistoryEntry{}
+		if limit > 0 {
+			entries, err := update.LoadHistory(limit)
+			if err != nil {
+				RespondError(w, http.StatusInternalServerError, "HISTORY_LOAD_FAILED", err.Error())
+				return
+			}
+			history = append(history, entries...)
+		}
+
+		chat, mcp, expected := childVersions(r.Context(), sup)
+		_, switchedAt := recordedRelease()
+		components := map[string]supervisor.ComponentStatus{}
+		for _, c := range sup.Status().Components {
+			components[c.Name] = c
+		}
+
+		available := checkAvailable(r.Context(), status)
+
+		RespondOK(w, http.StatusOK, map[string]any{
+			"agent": version.Get(),
+			"current": overviewRelease{
+				Version:     expected,
+				Channel:     status.CurrentChannel,
+				InstalledAt: switchedAt,
+			},
+			"previous": overviewRelease{
+				Version: status.PreviousVersion,
+				Channel: status.PreviousChannel,
+			},
+			"available": available,
+			"update":    status,
+			"history":   history,
+			"children": map[string]overviewChild{
+				"chat": newOverviewChild(chat, components["chat"]),
+				"mcp":  newOverviewChild(mcp, components["mcp"]),
+			},
+			"healthy": chat.Ready && mcp.Ready,
+			"drift":   chat.Drift || mcp.Drift,
+		})
+	}
+}
+
+func newOverviewChild(res childVersionResult, comp supervisor.ComponentStatus) overviewChild {
+	return overviewChild{
+		childVersionResult: res,
+		PID:                comp.PID,
+		StartTime:          comp.StartTime,
+		Restarts:           comp.Restarts,
+		LastExit:           comp.LastExit,
+		WaitingFor:         comp.WaitingFor,
+	}
+}
+
+// checkAvailable fetches and verifies the manifest for the configured channel (or the channel
+// of the installed release). Core compatibility is left to /admin/update/available.
+func checkAvailable(ctx context.Context, status update.UpdateStatus) overviewAvailable {
+	channel := os.Getenv("PAYRAM_AGENT_UPDATE_CHANNEL")
+	if channel == "" {
+		channel = status.CurrentChannel
+	}
+	if channel == "" {
+		channel = configuredChannel()
+	}
+	res := overviewAvailable{Channel: channel}
+
+	baseURL := os.Getenv("PAYRAM_AGENT_UPDATE_BASE_URL")
+	pub := os.Getenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64")
+	switch {
+	case baseURL == "":
+		res.Error = &respError{Code: "UPDATE_BASE_URL_MISSING", Message: "update base URL not configured"}
+		return res
+	case pub == "":
+		res.Error = &respError{Code: "UPDATE_PUBKEY_MISSING", Message: "update public key not configured"}
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, overviewFetchTimeout)
+	defer cancel()
+	check, err := update.CheckManifest(ctx, baseURL, channel, pub)
+	if code := checkErrorCode(err); code != "" {
+		res.Error = &respError{Code: code, Message: err.Error()}
+		return res
+	}
+	markUpdateCheck(check)
+	manifest := check.Manifest
+
+	res.Checked = true
+	res.TargetVersion = manifest.Version
+	res.ReleasedAt = manifest.ReleasedAt
+	res.Changelog = manifest.Notes
+	res.Revoked = manifest.Revoked
+	res.Available = manifest.Version != "" && manifest.Version != status.CurrentVersion && !manifest.Revoked
+	res.Problems, res.Warnings = update.CheckComponents(manifest, version.Get().Version)
+	res.Compatible = len(res.Problems) == 0 || ignoreCompatEnabled()
+	return res
+}
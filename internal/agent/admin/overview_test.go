@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+func TestOverviewConsolidatesUpdateState(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_UPDATE_CHANNEL", "")
+	healthyChildren(t)
+	newReleaseFixture(t, update.Manifest{Version: "2.0.0", Channel: "stable", Notes: "faster charts"})
+
+	release := update.ReleaseDir("1.0.0")
+	if err := os.MkdirAll(release, 0o755); err != nil {
+		t.Fatalf("mkdir release: %v", err)
+	}
+	if _, err := update.UpdateSymlinks(release); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	if err := update.SaveStatus(update.UpdateStatus{CurrentVersion: "1.0.0", CurrentChannel: "stable", PreviousVersion: "0.9.0"}); err != nil {
+		t.Fatalf("save status: %v", err)
+	}
+	if err := update.AppendHistory(update.HistoryEntry{Action: "apply", Result: "success", ToVersion: "1.0.0"}); err != nil {
+		t.Fatalf("append history: %v", err)
+	}
+
+	rr := adminRequest(t, &fakeSupervisor{}, http.MethodGet, "/admin/overview")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+	data := decodeBody(t, rr)["data"].(map[string]any)
+
+	if cur := data["current"].(map[string]any); cur["version"] != "1.0.0" || cur["channel"] != "stable" {
+		t.Fatalf("unexpected current: %v", cur)
+	}
+	if prev := data["previous"].(map[string]any); prev["version"] != "0.9.0" {
+		t.Fatalf("unexpected previous: %v", prev)
+	}
+	avail := data["available"].(map[string]any)
+	if avail["checked"] != true || avail["available"] != true || avail["target_version"] != "2.0.0" || avail["changelog"] != "faster charts" {
+		t.Fatalf("unexpected available: %v", avail)
+	}
+	if history := data["history"].([]any); len(history) != 1 {
+		t.Fatalf("expected one history entry, got %v", history)
+	}
+	if data["healthy"] != true {
+		t.Fatalf("expected healthy children, got %v", data["children"])
+	}
+}
+
+func TestOverviewReportsManifestFailureInline(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_UPDATE_BASE_URL", "")
+	healthyChildren(t)
+
+	rr := adminRequest(t, &fakeSupervisor{}, http.MethodGet, "/admin/overview?history=0")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+	avail := decodeBody(t, rr)["data"].(map[string]any)["available"].(map[string]any)
+	if avail["checked"] != false || avail["error"].(map[string]any)["code"] != "UPDATE_BASE_URL_MISSING" {
+		t.Fatalf("expected inline fetch error, got %v", avail)
+	}
+}
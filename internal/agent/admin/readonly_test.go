@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadOnlyRefusesOperationalEndpoints(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "")
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("READ_ONLY", "true")
+
+	sup := &fakeSupervisor{}
+	mux := NewMux(sup)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "127.0.0.1:1234"
+		req.Header.Set(adminKeyHeader, "tok")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, c := range []struct{ method, path, body string }{
+		{http.MethodPost, "/admin/child/restart", ""},
+		{http.MethodPost, "/admin/update/apply", ""},
+		{http.MethodPost, "/admin/update/rollback", ""},
+		{http.MethodPost, "/admin/config/apply", ""},
+		{http.MethodPut, "/admin/loglevel", `{"level":"debug"}`},
+		{http.MethodDelete, "/admin/secrets/openai", ""},
+	} {
+		rr := serve(c.method, c.path, c.body)
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected 403 got %d body=%s", c.method, c.path, rr.Code, rr.Body.String())
+		}
+		if code := errorCode(t, decodeBody(t, rr)); code != "READ_ONLY" {
+			t.Fatalf("%s %s: expected READ_ONLY, got %s", c.method, c.path, code)
+		}
+	}
+	if sup.restarts != 0 {
+		t.Fatalf("expected no restarts, got %d", sup.restarts)
+	}
+
+	if rr := serve(http.MethodGet, "/admin/child/status", ""); rr.Code != http.StatusOK {
+		t.Fatalf("status: expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+}
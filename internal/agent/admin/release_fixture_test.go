@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// releaseFixture serves a signed manifest per channel plus the chat/mcp artifacts it references.
+type releaseFixture struct {
+	srv       *httptest.Server
+	pubB64    string
+	manifests map[string]update.Manifest
+	chatData  []byte
+	mcpData   []byte
+}
+
+// newReleaseFixture publishes manifest (version and compatibility as given; artifacts filled in)
+// on the stable channel and points the update env vars at it.
+func newReleaseFixture(t *testing.T, manifest update.Manifest) *releaseFixture {
+	t.Helper()
+
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	f := &releaseFixture{
+		pubB64:    base64.StdEncoding.EncodeToString(pub),
+		manifests: map[string]update.Manifest{},
+		chatData:  []byte("chat-binary-" + manifest.Version),
+		mcpData:   []byte("mcp-binary-" + manifest.Version),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{channel}/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		m, ok := f.manifests[r.PathValue("channel")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		raw, _ := json.Marshal(m)
+		w.Write(raw)
+	})
+	mux.HandleFunc("/{channel}/manifest.json.sig", func(w http.ResponseWriter, r *http.Request) {
+		m, ok := f.manifests[r.PathValue("channel")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		raw, _ := json.Marshal(m)
+		w.Write(ed25519.Sign(priv, raw))
+	})
+	mux.HandleFunc("/chat", func(w http.ResponseWriter, _ *http.Request) { w.Write(f.chatData) })
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, _ *http.Request) { w.Write(f.mcpData) })
+
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+
+	f.publish("stable", manifest)
+
+	t.Setenv("PAYRAM_AGENT_UPDATE_BASE_URL", f.srv.URL)
+	t.Setenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64", f.pubB64)
+	return f
+}
+
+// publish adds or replaces the manifest served for channel, filling in artifact URLs and hashes.
+func (f *releaseFixture) publish(channel string, manifest update.Manifest) update.Manifest {
+	chatHash := sha256.Sum256(f.chatData)
+	mcpHash := sha256.Sum256(f.mcpData)
+	manifest.Artifacts = update.Artifacts{
+		Chat: update.Artifact{URL: f.srv.URL + "/chat", SHA256: hex.EncodeToString(chatHash[:])},
+		MCP:  update.Artifact{URL: f.srv.URL + "/mcp", SHA256: hex.EncodeToString(mcpHash[:])},
+	}
+	f.manifests[channel] = manifest
+	return manifest
+}
+
+// healthyChildren points the child health checks at servers that always answer 200.
+func healthyChildren(t *testing.T) {
+	t.Helper()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	chat := httptest.NewServer(ok)
+	t.Cleanup(chat.Close)
+	mcp := httptest.NewServer(ok)
+	t.Cleanup(mcp.Close)
+
+	t.Setenv("PAYRAM_CHAT_PORT", portFromURL(chat.URL))
+	t.Setenv("PAYRAM_MCP_PORT", portFromURL(mcp.URL))
+}
+
+// adminRequest issues an authenticated admin request against a fresh mux for sup.
+func adminRequest(t *testing.T, sup Supervisor, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "")
+
+	req := httptest.NewRequest(method, target, nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set(adminKeyHeader, "tok")
+	rr := httptest.NewRecorder()
+	NewMux(sup).ServeHTTP(rr, req)
+	return rr
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/secrets"
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/supervisor"
@@ -13,9 +14,9 @@ import (
 
 type noopSupervisor struct{}
 
-func (n *noopSupervisor) RestartAll() error         { return nil }
-func (n *noopSupervisor) Status() supervisor.Status { return supervisor.Status{} }
-func (n *noopSupervisor) Logs(string, int) []string { return nil }
+func (n *noopSupervisor) RestartAllAndWait(time.Duration) []supervisor.RestartResult { return nil }
+func (n *noopSupervisor) Status() supervisor.Status                                  { return supervisor.Status{} }
+func (n *noopSupervisor) Logs(string, int) []string                                  { return nil }
 
 func TestSecretsHandlers(t *testing.T) {
 	home := t.TempDir()
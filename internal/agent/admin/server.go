@@ -1,12 +1,14 @@
 package admin
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -14,15 +16,23 @@ import (
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/kube"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/lifecycle"
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/secrets"
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/supervisor"
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+	"github.com/payram/payram-analytics-mcp-server/internal/logging"
+	"github.com/payram/payram-analytics-mcp-server/internal/metrics"
+	"github.com/payram/payram-analytics-mcp-server/internal/openapi"
 	"github.com/payram/payram-analytics-mcp-server/internal/version"
+	"github.com/sirupsen/logrus"
 )
 
 // Supervisor defines the minimal interface required from the supervisor.
+// RestartAllAndWait restarts every child and reports, per child, whether it came back
+// with a new PID and a passing health probe within the timeout.
 type Supervisor interface {
-	RestartAll() error
+	RestartAllAndWait(timeout time.Duration) []supervisor.RestartResult
 	Status() supervisor.Status
 	Logs(component string, tail int) []string
 }
@@ -36,19 +46,35 @@ func NewMux(sup Supervisor) http.Handler {
 
 	mux.HandleFunc("/health", healthHandler)
 	mux.HandleFunc("/version", versionHandler)
+	mux.Handle(openapi.Path, openapi.Handler(openAPIDoc))
 
 	adminGuard := NewAdminMiddlewareFromEnv()
-	mux.Handle("/admin/version", adminGuard(http.HandlerFunc(adminVersionHandler)))
+	mux.Handle("/metrics", adminGuard(metrics.Default.Handler()))
+	mux.Handle("/admin/version", adminGuard(http.HandlerFunc(adminVersionHandler(sup))))
+	mux.Handle("/admin/overview", adminGuard(http.HandlerFunc(overviewHandler(sup))))
 	mux.Handle("/admin/update/available", adminGuard(http.HandlerFunc(updateAvailableHandler)))
 	mux.Handle("/admin/update/apply", adminGuard(http.HandlerFunc(updateApplyHandler(sup))))
 	mux.Handle("/admin/update/rollback", adminGuard(http.HandlerFunc(updateRollbackHandler(sup))))
+	mux.Handle("/admin/update/channels", adminGuard(http.HandlerFunc(updateChannelsHandler)))
 	mux.Handle("/admin/update/status", adminGuard(http.HandlerFunc(updateStatusHandler)))
+	mux.Handle("/admin/update/history", adminGuard(http.HandlerFunc(updateHistoryHandler)))
+	mux.Handle("/admin/update/verify", adminGuard(http.HandlerFunc(updateVerifyHandler)))
+	mux.Handle("/admin/config", adminGuard(http.HandlerFunc(configHandler)))
+	mux.Handle("/admin/config/apply", adminGuard(http.HandlerFunc(configApplyHandler(sup))))
+	mux.Handle("/admin/heartbeat", adminGuard(http.HandlerFunc(heartbeatHandler)))
+	mux.Handle("/admin/commands", adminGuard(http.HandlerFunc(commandsHandler)))
 	mux.Handle("/admin/child/restart", adminGuard(http.HandlerFunc(restartHandler(sup))))
 	mux.Handle("/admin/child/status", adminGuard(http.HandlerFunc(statusHandler(sup))))
 	mux.Handle("/admin/logs", adminGuard(http.HandlerFunc(logsHandler(sup))))
+	mux.Handle("/admin/crashes", adminGuard(http.HandlerFunc(crashesHandler)))
+	mux.Handle("/admin/events", adminGuard(eventsHandler(lifecycle.Default)))
+	mux.Handle("/admin/loglevel", adminGuard(http.HandlerFunc(logLevelHandler)))
 	mux.Handle("/admin/secrets/openai", adminGuard(http.HandlerFunc(secretsHandler)))
 	mux.Handle("/admin/secrets/status", adminGuard(http.HandlerFunc(secretsStatusHandler)))
 
+	mux.Handle(uiPath, newNetworkGuardFromEnv()(uiHandler()))
+	mux.Handle("/admin/ui", http.RedirectHandler(uiPath, http.StatusMovedPermanently))
+
 	return mux
 }
 
@@ -60,24 +86,82 @@ func versionHandler(w http.ResponseWriter, _ *http.Request) {
 	RespondOK(w, http.StatusOK, version.Get())
 }
 
-func adminVersionHandler(w http.ResponseWriter, r *http.Request) {
+// adminVersionHandler reports agent and child versions, whether each child answers its health
+// probe, and whether a child is still serving a build other than the recorded release.
+func adminVersionHandler(sup Supervisor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chat, mcp, expected := childVersions(r.Context(), sup)
+		RespondOK(w, http.StatusOK, map[string]any{
+			"agent":            version.Get(),
+			"chat":             chat,
+			"mcp":              mcp,
+			"expected_version": expected,
+			"drift":            chat.Drift || mcp.Drift,
+		})
+	}
+}
+
+// childVersions probes both children for their version and readiness and checks them for drift
+// against the recorded release.
+func childVersions(ctx context.Context, sup Supervisor) (chat, mcp childVersionResult, expected string) {
 	client := &http.Client{Timeout: 2 * time.Second}
-	ctx := r.Context()
 
 	chatPort := envPort("PAYRAM_CHAT_PORT", 2358)
 	mcpPort := envPort("PAYRAM_MCP_PORT", 3333)
 
-	chatURL := fmt.Sprintf("http://127.0.0.1:%d/version", chatPort)
-	mcpURL := fmt.Sprintf("http://127.0.0.1:%d/version", mcpPort)
+	chat = fetchChildVersion(ctx, client, fmt.Sprintf("http://127.0.0.1:%d/version", chatPort))
+	mcp = fetchChildVersion(ctx, client, fmt.Sprintf("http://127.0.0.1:%d/version", mcpPort))
 
-	chat := fetchChildVersion(ctx, client, chatURL)
-	mcp := fetchChildVersion(ctx, client, mcpURL)
+	healthPath := childHealthPath()
+	chat.Ready = pingOnce(client, fmt.Sprintf("http://127.0.0.1:%d%s", chatPort, healthPath)) == nil
+	mcp.Ready = pingOnce(client, fmt.Sprintf("http://127.0.0.1:%d%s", mcpPort, healthPath)) == nil
 
-	RespondOK(w, http.StatusOK, map[string]any{
-		"agent": version.Get(),
-		"chat":  chat,
-		"mcp":   mcp,
-	})
+	expected, switchedAt := recordedRelease()
+	components := map[string]supervisor.ComponentStatus{}
+	for _, c := range sup.Status().Components {
+		components[c.Name] = c
+	}
+	checkDrift(&chat, expected, components["chat"], switchedAt)
+	checkDrift(&mcp, expected, components["mcp"], switchedAt)
+	return chat, mcp, expected
+}
+
+// recordedRelease returns the release version the status file (or current symlink) says is
+// installed, and when the current symlink was last switched.
+func recordedRelease() (string, time.Time) {
+	var switchedAt time.Time
+	if info, err := os.Lstat(update.CurrentSymlink()); err == nil {
+		switchedAt = info.ModTime()
+	}
+
+	if st, err := update.LoadStatus(); err == nil && st.CurrentVersion != "" {
+		return st.CurrentVersion, switchedAt
+	}
+	target, _ := os.Readlink(update.CurrentSymlink())
+	return update.VersionFromTarget(target), switchedAt
+}
+
+// checkDrift flags a child serving a different version than the recorded release, or one whose
+// process predates the last release switch (it never restarted onto the new build).
+func checkDrift(res *childVersionResult, expected string, comp supervisor.ComponentStatus, switchedAt time.Time) {
+	res.Expected = expected
+	if expected == "" {
+		return
+	}
+
+	if res.Info != nil && res.Info.Version != "" && res.Info.Version != "dev" {
+		if strings.TrimPrefix(res.Info.Version, "v") != strings.TrimPrefix(expected, "v") {
+			res.Drift = true
+			res.DriftReason = fmt.Sprintf("serving %s but release is %s", res.Info.Version, expected)
+			return
+		}
+	}
+
+	if comp.PID != 0 && !switchedAt.IsZero() && comp.StartTime.Before(switchedAt) {
+		res.Drift = true
+		res.DriftReason = fmt.Sprintf("process started %s, before release %s was installed at %s",
+			comp.StartTime.Format(time.RFC3339), expected, switchedAt.Format(time.RFC3339))
+	}
 }
 
 func updateAvailableHandler(w http.ResponseWriter, r *http.Request) {
@@ -103,19 +187,16 @@ func updateAvailableHandler(w http.ResponseWriter, r *http.Request) {
 
 	channel := r.URL.Query().Get("channel")
 	if channel == "" {
-		channel = "stable"
-	}
-
-	manifest, raw, sig, err := update.FetchManifest(r.Context(), baseURL, channel)
-	if err != nil {
-		RespondError(w, http.StatusInternalServerError, "UPDATE_FETCH_FAILED", err.Error())
-		return
+		channel = configuredChannel()
 	}
 
-	if err := update.VerifyManifest(raw, sig, pub); err != nil {
-		RespondError(w, http.StatusInternalServerError, "SIGNATURE_INVALID", err.Error())
+	check, err := update.CheckManifest(r.Context(), baseURL, channel, pub)
+	if code := checkErrorCode(err); code != "" {
+		RespondError(w, http.StatusInternalServerError, code, err.Error())
 		return
 	}
+	markUpdateCheck(check)
+	manifest := check.Manifest
 
 	status, err := update.LoadStatus()
 	if err != nil {
@@ -125,6 +206,14 @@ func updateAvailableHandler(w http.ResponseWriter, r *http.Request) {
 
 	available := manifest.Version != "" && manifest.Version != status.CurrentVersion && !manifest.Revoked
 
+	problems, componentWarnings := update.CheckComponents(manifest, version.Get().Version)
+	componentsResult := map[string]any{
+		"compatible": len(problems) == 0 || ignoreCompat,
+		"problems":   problems,
+		"warnings":   componentWarnings,
+		"ignored":    ignoreCompat,
+	}
+
 	compatRange := manifest.Compatibility.PayramCore
 	coreInfo := map[string]any{
 		"min": compatRange.Min,
@@ -154,6 +243,7 @@ func updateAvailableHandler(w http.ResponseWriter, r *http.Request) {
 				"revoked":         manifest.Revoked,
 				"payram_core":     coreInfo,
 				"compat":          compatResult,
+				"components":      componentsResult,
 			})
 			return
 		}
@@ -179,6 +269,7 @@ func updateAvailableHandler(w http.ResponseWriter, r *http.Request) {
 				"revoked":         manifest.Revoked,
 				"payram_core":     coreInfo,
 				"compat":          compatResult,
+				"components":      componentsResult,
 			})
 			return
 		}
@@ -210,6 +301,7 @@ func updateAvailableHandler(w http.ResponseWriter, r *http.Request) {
 			"revoked":         manifest.Revoked,
 			"payram_core":     coreInfo,
 			"compat":          compatResult,
+			"components":      componentsResult,
 		})
 		return
 	}
@@ -222,6 +314,7 @@ func updateAvailableHandler(w http.ResponseWriter, r *http.Request) {
 		"revoked":         manifest.Revoked,
 		"payram_core":     coreInfo,
 		"compat":          compatResult,
+		"components":      componentsResult,
 	})
 }
 
@@ -233,6 +326,17 @@ func updateApplyHandler(sup Supervisor) http.HandlerFunc {
 		}
 
 		ignoreCompat := ignoreCompatEnabled()
+		dryRun := queryFlag(r, "dry_run")
+		if !dryRun && refuseReadOnly(w, "applying an update") {
+			return
+		}
+		// A dry run goes through every check and download but leaves the persisted status untouched.
+		saveStatus := func(st update.UpdateStatus) error {
+			if dryRun {
+				return nil
+			}
+			return update.SaveStatus(st)
+		}
 
 		unlock, err := update.AcquireUpdateLock()
 		if err != nil {
@@ -251,51 +355,95 @@ func updateApplyHandler(sup Supervisor) http.HandlerFunc {
 			return
 		}
 		status.MarkAttempt()
-		if err := update.SaveStatus(status); err != nil {
+		if err := saveStatus(status); err != nil {
 			RespondError(w, http.StatusInternalServerError, "STATUS_SAVE_FAILED", err.Error())
 			return
 		}
 		defer func() {
 			status.InProgress = false
-			_ = update.SaveStatus(status)
+			_ = saveStatus(status)
+		}()
+
+		history := update.HistoryEntry{Action: "apply", FromVersion: status.CurrentVersion, RemoteAddr: r.RemoteAddr}
+		succeeded := false
+		started := time.Now()
+		timing := update.NewTiming("apply")
+		defer func() {
+			if dryRun {
+				return
+			}
+			updateDuration.Observe(time.Since(started).Seconds())
+			status.RecordTiming(timing.Finish(history.ToVersion, attemptResult(succeeded)))
+			recordHistory(history, status, succeeded)
 		}()
 
 		baseURL := os.Getenv("PAYRAM_AGENT_UPDATE_BASE_URL")
 		if baseURL == "" {
+			status.MarkFailure("UPDATE_BASE_URL_MISSING", "update base URL not configured")
+			_ = saveStatus(status)
 			RespondError(w, http.StatusInternalServerError, "UPDATE_BASE_URL_MISSING", "update base URL not configured")
 			return
 		}
 
 		pub := os.Getenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64")
 		if pub == "" {
+			status.MarkFailure("UPDATE_PUBKEY_MISSING", "update public key not configured")
+			_ = saveStatus(status)
 			RespondError(w, http.StatusInternalServerError, "UPDATE_PUBKEY_MISSING", "update public key not configured")
 			return
 		}
 
+		smoke, err := smokeFromEnv()
+		if err != nil {
+			status.MarkFailure("SMOKE_CONFIG_INVALID", err.Error())
+			_ = saveStatus(status)
+			RespondError(w, http.StatusInternalServerError, "SMOKE_CONFIG_INVALID", err.Error())
+			return
+		}
+
 		coreURL := os.Getenv("PAYRAM_CORE_URL")
 
-		channel := r.URL.Query().Get("channel")
-		if channel == "" {
-			channel = "stable"
+		channel, forced, err := resolveApplyChannel(r)
+		history.Channel = channel
+		history.ForcedChannel = forced
+		if err != nil {
+			status.MarkFailure("CHANNEL_LOCKED", err.Error())
+			_ = saveStatus(status)
+			RespondError(w, http.StatusForbidden, "CHANNEL_LOCKED", err.Error())
+			return
 		}
 
+		canary, err := canaryPolicyFor(r)
+		if err != nil {
+			status.MarkFailure("INVALID_CANARY", err.Error())
+			_ = saveStatus(status)
+			RespondError(w, http.StatusBadRequest, "INVALID_CANARY", err.Error())
+			return
+		}
+
+		timing.Stage(update.StageFetch)
 		manifest, raw, sig, err := update.FetchManifest(r.Context(), baseURL, channel)
 		if err != nil {
 			status.MarkFailure("UPDATE_FETCH_FAILED", err.Error())
-			_ = update.SaveStatus(status)
+			_ = saveStatus(status)
 			RespondError(w, http.StatusInternalServerError, "UPDATE_FETCH_FAILED", err.Error())
 			return
 		}
 
+		timing.Stage(update.StageVerify)
 		if err := update.VerifyManifest(raw, sig, pub); err != nil {
+			signatureFailures.Inc()
 			status.MarkFailure("SIGNATURE_INVALID", err.Error())
-			_ = update.SaveStatus(status)
+			_ = saveStatus(status)
 			RespondError(w, http.StatusInternalServerError, "SIGNATURE_INVALID", err.Error())
 			return
 		}
 
+		markUpdateCheck(update.ManifestCheck{})
 		status.LastAttemptVersion = manifest.Version
-		if err := update.SaveStatus(status); err != nil {
+		history.ToVersion = manifest.Version
+		if err := saveStatus(status); err != nil {
+			status.MarkFailure("STATUS_SAVE_FAILED", err.Error())
 			RespondError(w, http.StatusInternalServerError, "STATUS_SAVE_FAILED", err.Error())
 			return
 		}
@@ -303,19 +451,27 @@ func updateApplyHandler(sup Supervisor) http.HandlerFunc {
 		if manifest.Revoked {
 			msg := "release revoked"
 			status.MarkFailure("REVOKED_RELEASE", msg)
-			_ = update.SaveStatus(status)
+			_ = saveStatus(status)
 			RespondError(w, http.StatusBadRequest, "REVOKED_RELEASE", msg)
 			return
 		}
 
-		warnings := []string{}
+		warnings := channelWarnings(channel, manifest.Channel, status.CurrentChannel)
+		if forced {
+			warnings = append(warnings, fmt.Sprintf("channel %q forced over pinned %q", channel, configuredChannel()))
+		}
+		releaseChannel := manifest.Channel
+		if releaseChannel == "" {
+			releaseChannel = channel
+		}
+
 		coreVersion := ""
 		if coreURL == "" {
 			if ignoreCompat {
 				warnings = append(warnings, "compatibility ignored: PAYRAM_CORE_URL not set")
 			} else {
 				status.MarkFailure("CORE_URL_MISSING", "payram core URL not configured")
-				_ = update.SaveStatus(status)
+				_ = saveStatus(status)
 				RespondError(w, http.StatusInternalServerError, "CORE_URL_MISSING", "payram core URL not configured")
 				return
 			}
@@ -326,7 +482,7 @@ func updateApplyHandler(sup Supervisor) http.HandlerFunc {
 					warnings = append(warnings, fmt.Sprintf("compatibility ignored: core unreachable (%s)", err.Error()))
 				} else {
 					status.MarkFailure("CORE_UNREACHABLE", err.Error())
-					_ = update.SaveStatus(status)
+					_ = saveStatus(status)
 					RespondError(w, http.StatusInternalServerError, "CORE_UNREACHABLE", err.Error())
 					return
 				}
@@ -342,7 +498,7 @@ func updateApplyHandler(sup Supervisor) http.HandlerFunc {
 							reason = "incompatible payram-core version"
 						}
 						status.MarkFailure("INCOMPATIBLE_CORE", reason)
-						_ = update.SaveStatus(status)
+						_ = saveStatus(status)
 						RespondError(w, http.StatusBadRequest, "INCOMPATIBLE_CORE", reason)
 						return
 					}
@@ -350,110 +506,202 @@ func updateApplyHandler(sup Supervisor) http.HandlerFunc {
 			}
 		}
 
+		problems, componentWarnings := update.CheckComponents(manifest, version.Get().Version)
+		warnings = append(warnings, componentWarnings...)
+		if len(problems) > 0 {
+			reason := strings.Join(problems, "; ")
+			if ignoreCompat {
+				warnings = append(warnings, fmt.Sprintf("compatibility ignored: %s", reason))
+			} else {
+				status.MarkFailure("INCOMPATIBLE_COMPONENTS", reason)
+				_ = saveStatus(status)
+				RespondError(w, http.StatusBadRequest, "INCOMPATIBLE_COMPONENTS", reason)
+				return
+			}
+		}
+
 		releaseDir := update.ReleaseDir(manifest.Version)
 		stageDir := filepath.Join(update.ReleasesDir(), manifest.Version+".tmp-"+randHex(6))
 
+		timing.Stage(update.StageDownload)
 		_ = os.RemoveAll(stageDir)
 		if err := os.MkdirAll(stageDir, 0o755); err != nil {
 			status.MarkFailure("STAGE_CREATE_FAILED", err.Error())
-			_ = update.SaveStatus(status)
+			_ = saveStatus(status)
 			RespondError(w, http.StatusInternalServerError, "STAGE_CREATE_FAILED", err.Error())
 			return
 		}
 
 		download := func(url, path, sha string) error {
-			if err := update.DownloadToFile(r.Context(), url, path); err != nil {
-				return fmt.Errorf("download: %w", err)
+			cached, err := update.FetchArtifact(r.Context(), url, sha, path)
+			if err != nil {
+				return err
 			}
-			if err := update.VerifySHA256(path, sha); err != nil {
-				return fmt.Errorf("sha256: %w", err)
+			if cached {
+				artifactCacheHits.Inc()
+			} else if fi, err := os.Stat(path); err == nil {
+				updateDownloadBytes.Add(float64(fi.Size()))
 			}
 			return os.Chmod(path, 0o755)
 		}
 
-		chatPath := filepath.Join(stageDir, "payram-analytics-chat")
-		if err := download(manifest.Artifacts.Chat.URL, chatPath, manifest.Artifacts.Chat.SHA256); err != nil {
-			status.MarkFailure("UPDATE_DOWNLOAD_FAILED", err.Error())
-			_ = update.SaveStatus(status)
-			RespondError(w, http.StatusInternalServerError, "UPDATE_DOWNLOAD_FAILED", err.Error())
+		docker := supervisor.DockerMode()
+		imagesOnly := docker || kube.Enabled()
+		staged := map[string]string{}
+		if imagesOnly {
+			// Containerized children run the images the signed manifest names; there are no
+			// binaries to fetch. The docker driver pulls them now to keep the restart short;
+			// in a cluster the nodes pull them during the rollout.
+			for _, c := range []struct {
+				name string
+				art  update.Artifact
+			}{{"chat", manifest.Artifacts.Chat}, {"mcp", manifest.Artifacts.MCP}} {
+				if c.art.Image == "" {
+					msg := fmt.Sprintf("manifest has no %s image for the %s child driver", c.name, os.Getenv("PAYRAM_AGENT_CHILD_DRIVER"))
+					status.MarkFailure("IMAGE_MISSING", msg)
+					_ = saveStatus(status)
+					RespondError(w, http.StatusBadRequest, "IMAGE_MISSING", msg)
+					return
+				}
+				if !strings.Contains(c.art.Image, "@sha256:") {
+					warnings = append(warnings, fmt.Sprintf("%s image %s is not pinned by digest", c.name, c.art.Image))
+				}
+				if !docker {
+					continue
+				}
+				if err := supervisor.PullImage(r.Context(), c.art.Image); err != nil {
+					status.MarkFailure("UPDATE_DOWNLOAD_FAILED", err.Error())
+					_ = saveStatus(status)
+					RespondError(w, http.StatusInternalServerError, "UPDATE_DOWNLOAD_FAILED", err.Error())
+					return
+				}
+			}
+		} else {
+			staged["chat"] = filepath.Join(stageDir, update.ChatBinaryName)
+			if err := download(manifest.Artifacts.Chat.URL, staged["chat"], manifest.Artifacts.Chat.SHA256); err != nil {
+				status.MarkFailure("UPDATE_DOWNLOAD_FAILED", err.Error())
+				_ = saveStatus(status)
+				RespondError(w, http.StatusInternalServerError, "UPDATE_DOWNLOAD_FAILED", err.Error())
+				return
+			}
+
+			staged["mcp"] = filepath.Join(stageDir, update.MCPBinaryName)
+			if err := download(manifest.Artifacts.MCP.URL, staged["mcp"], manifest.Artifacts.MCP.SHA256); err != nil {
+				status.MarkFailure("UPDATE_DOWNLOAD_FAILED", err.Error())
+				_ = saveStatus(status)
+				RespondError(w, http.StatusInternalServerError, "UPDATE_DOWNLOAD_FAILED", err.Error())
+				return
+			}
+		}
+
+		if err := update.SaveReleaseManifest(stageDir, raw, sig); err != nil {
+			status.MarkFailure("MANIFEST_SAVE_FAILED", err.Error())
+			_ = saveStatus(status)
+			RespondError(w, http.StatusInternalServerError, "MANIFEST_SAVE_FAILED", err.Error())
 			return
 		}
 
-		mcpPath := filepath.Join(stageDir, "payram-analytics-mcp")
-		if err := download(manifest.Artifacts.MCP.URL, mcpPath, manifest.Artifacts.MCP.SHA256); err != nil {
-			status.MarkFailure("UPDATE_DOWNLOAD_FAILED", err.Error())
-			_ = update.SaveStatus(status)
-			RespondError(w, http.StatusInternalServerError, "UPDATE_DOWNLOAD_FAILED", err.Error())
+		if dryRun {
+			plan := dryRunPlan(manifest, channel, status.CurrentChannel, coreVersion, staged, warnings)
+			_ = os.RemoveAll(stageDir)
+			plan["timing"] = timing.Finish(manifest.Version, "dry_run")
+			RespondOK(w, http.StatusOK, plan)
 			return
 		}
 
+		timing.Stage(update.StageSwitch)
 		_ = os.RemoveAll(releaseDir)
 		if err := os.Rename(stageDir, releaseDir); err != nil {
 			status.MarkFailure("FINALIZE_FAILED", err.Error())
-			_ = update.SaveStatus(status)
+			_ = saveStatus(status)
 			RespondError(w, http.StatusInternalServerError, "FINALIZE_FAILED", err.Error())
 			return
 		}
 
-		if err := update.EnsureCompatSymlinks(releaseDir); err != nil {
-			status.MarkFailure("FINALIZE_FAILED", err.Error())
-			_ = update.SaveStatus(status)
-			RespondError(w, http.StatusInternalServerError, "FINALIZE_FAILED", err.Error())
-			return
+		if !imagesOnly {
+			if err := update.EnsureCompatSymlinks(releaseDir); err != nil {
+				status.MarkFailure("FINALIZE_FAILED", err.Error())
+				_ = saveStatus(status)
+				RespondError(w, http.StatusInternalServerError, "FINALIZE_FAILED", err.Error())
+				return
+			}
 		}
 
 		oldTarget, err := update.UpdateSymlinks(releaseDir)
 		if err != nil {
 			status.MarkFailure("SYMLINK_UPDATE_FAILED", err.Error())
-			_ = update.SaveStatus(status)
+			_ = saveStatus(status)
 			RespondError(w, http.StatusInternalServerError, "SYMLINK_UPDATE_FAILED", err.Error())
 			return
 		}
 
 		previousVersion := update.VersionFromTarget(oldTarget)
+		previousChannel := status.CurrentChannel
 		status.CurrentVersion = manifest.Version
 		status.PreviousVersion = previousVersion
-		if err := update.SaveStatus(status); err != nil {
+		status.CurrentChannel = releaseChannel
+		status.PreviousChannel = previousChannel
+		status.Canary = nil
+		if err := saveStatus(status); err != nil {
+			status.MarkFailure("STATUS_SAVE_FAILED", err.Error())
 			RespondError(w, http.StatusInternalServerError, "STATUS_SAVE_FAILED", err.Error())
 			return
 		}
 
-		if err := sup.RestartAll(); err != nil {
-			status.MarkFailure("RESTART_FAILED", err.Error())
-			_ = update.SaveStatus(status)
-			RespondError(w, http.StatusInternalServerError, "RESTART_FAILED", err.Error())
-			return
+		timing.End()
+		restarts := chaosHealth(sup.RestartAllAndWait(healthTimeout()))
+		addRestartTiming(timing, restarts)
+		failCode, failErr := "UPDATE_FAILED_ROLLED_BACK", supervisor.RestartError(restarts)
+		if failErr == nil && smoke != nil {
+			timing.Stage(update.StageSmoke)
+			if err := smoke.run(r.Context()); err != nil {
+				failCode, failErr = "SMOKE_FAILED_ROLLED_BACK", err
+			}
+			timing.End()
 		}
-
-		healthErr := waitForHealth(envPort("PAYRAM_CHAT_PORT", 2358), envPort("PAYRAM_MCP_PORT", 3333), healthTimeout())
-		if healthErr != nil {
+		if failErr != nil {
+			rollbackTotal.Inc()
+			timing.Stage(update.StageRollback)
 			_, _ = update.UpdateSymlinks(oldTarget)
-			_ = sup.RestartAll()
+			_ = sup.RestartAllAndWait(healthTimeout())
+			publishAutoRollback(manifest.Version, previousVersion, failErr.Error())
 			reloaded, err := update.LoadStatus()
 			if err != nil {
+				status.MarkFailure(failCode, failErr.Error())
 				RespondError(w, http.StatusInternalServerError, "STATUS_LOAD_FAILED", err.Error())
 				return
 			}
-			reloaded.MarkFailure("UPDATE_FAILED_ROLLED_BACK", healthErr.Error())
+			reloaded.MarkFailure(failCode, failErr.Error())
 			reloaded.CurrentVersion = previousVersion
 			reloaded.PreviousVersion = manifest.Version
-			if reloaded.LastAttemptVersion == "" {
-				reloaded.LastAttemptVersion = manifest.Version
-				reloaded.LastAttemptAt = time.Now()
-			}
+			reloaded.CurrentChannel = previousChannel
+			reloaded.PreviousChannel = releaseChannel
+			reloaded.EnsureAttempt(manifest.Version)
 			_ = update.SaveStatus(reloaded)
 			status = reloaded
-			RespondError(w, http.StatusInternalServerError, "UPDATE_FAILED_ROLLED_BACK", healthErr.Error())
+			RespondError(w, http.StatusInternalServerError, failCode, failErr.Error())
 			return
 		}
 
-		status.MarkSuccess(manifest.Version, previousVersion)
-		if err := update.SaveStatus(status); err != nil {
+		resp := map[string]any{"ok": true, "updated_to": manifest.Version, "restarts": restarts}
+		switch {
+		case canary.Soak > 0 && previousVersion != "":
+			// The release only counts as a success once it has soaked.
+			status.InProgress = false
+			resp["canary"] = startCanary(sup, &status, manifest.Version, previousVersion, canary)
+		case canary.Soak > 0:
+			warnings = append(warnings, "canary skipped: no previous release to roll back to")
+			fallthrough
+		default:
+			status.MarkSuccess(manifest.Version, previousVersion)
+		}
+		if err := saveStatus(status); err != nil {
+			status.MarkFailure("STATUS_SAVE_FAILED", err.Error())
 			RespondError(w, http.StatusInternalServerError, "STATUS_SAVE_FAILED", err.Error())
 			return
 		}
 
-		resp := map[string]any{"ok": true, "updated_to": manifest.Version}
+		succeeded = true
 		if len(warnings) > 0 {
 			resp["warnings"] = warnings
 		}
@@ -468,6 +716,9 @@ func updateRollbackHandler(sup Supervisor) http.HandlerFunc {
 			RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only POST allowed")
 			return
 		}
+		if refuseReadOnly(w, "rolling back") {
+			return
+		}
 
 		unlock, err := update.AcquireUpdateLock()
 		if err != nil {
@@ -488,6 +739,15 @@ func updateRollbackHandler(sup Supervisor) http.HandlerFunc {
 		status.MarkAttempt()
 		_ = update.SaveStatus(status)
 
+		history := update.HistoryEntry{Action: "rollback", FromVersion: status.CurrentVersion, RemoteAddr: r.RemoteAddr}
+		succeeded := false
+		timing := update.NewTiming("rollback")
+		defer func() {
+			status.RecordTiming(timing.Finish(history.ToVersion, attemptResult(succeeded)))
+			_ = update.SaveStatus(status)
+			recordHistory(history, status, succeeded)
+		}()
+
 		prevTarget, err := os.Readlink(update.PreviousSymlink())
 		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			status.MarkFailure("ROLLBACK_FAILED", err.Error())
@@ -503,6 +763,10 @@ func updateRollbackHandler(sup Supervisor) http.HandlerFunc {
 			return
 		}
 
+		history.ToVersion = update.VersionFromTarget(prevTarget)
+		history.Channel = status.PreviousChannel
+
+		timing.Stage(update.StageSwitch)
 		oldCurrent, err := update.UpdateSymlinks(prevTarget)
 		if err != nil {
 			status.MarkFailure("SYMLINK_UPDATE_FAILED", err.Error())
@@ -511,14 +775,10 @@ func updateRollbackHandler(sup Supervisor) http.HandlerFunc {
 			return
 		}
 
-		if err := sup.RestartAll(); err != nil {
-			status.MarkFailure("RESTART_FAILED", err.Error())
-			_ = update.SaveStatus(status)
-			RespondError(w, http.StatusInternalServerError, "RESTART_FAILED", err.Error())
-			return
-		}
-
-		if err := waitForHealth(envPort("PAYRAM_CHAT_PORT", 2358), envPort("PAYRAM_MCP_PORT", 3333), healthTimeout()); err != nil {
+		timing.End()
+		restarts := sup.RestartAllAndWait(healthTimeout())
+		addRestartTiming(timing, restarts)
+		if err := supervisor.RestartError(restarts); err != nil {
 			status.MarkFailure("ROLLBACK_HEALTH_FAILED", err.Error())
 			_ = update.SaveStatus(status)
 			RespondError(w, http.StatusInternalServerError, "ROLLBACK_HEALTH_FAILED", err.Error())
@@ -527,17 +787,19 @@ func updateRollbackHandler(sup Supervisor) http.HandlerFunc {
 
 		status.CurrentVersion = update.VersionFromTarget(prevTarget)
 		status.PreviousVersion = update.VersionFromTarget(oldCurrent)
+		status.CurrentChannel, status.PreviousChannel = status.PreviousChannel, status.CurrentChannel
 		status.InProgress = false
-		if status.LastAttemptVersion == "" {
-			status.LastAttemptVersion = status.CurrentVersion
-			status.LastAttemptAt = time.Now()
-		}
+		status.Canary = nil
+		status.EnsureAttempt(status.CurrentVersion)
 		if err := update.SaveStatus(status); err != nil {
+			status.MarkFailure("STATUS_SAVE_FAILED", err.Error())
 			RespondError(w, http.StatusInternalServerError, "STATUS_SAVE_FAILED", err.Error())
 			return
 		}
 
-		RespondOK(w, http.StatusOK, map[string]any{"ok": true, "rolled_back_to": update.VersionFromTarget(prevTarget)})
+		succeeded = true
+		rollbackTotal.Inc()
+		RespondOK(w, http.StatusOK, map[string]any{"ok": true, "rolled_back_to": update.VersionFromTarget(prevTarget), "restarts": restarts})
 	}
 }
 
@@ -556,24 +818,255 @@ func updateStatusHandler(w http.ResponseWriter, r *http.Request) {
 	RespondOK(w, http.StatusOK, status)
 }
 
+// updateChannelsHandler lists the channels the update base URL hosts with their latest
+// release, marking the one the agent is installed from and the one it checks by default.
+func updateChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET allowed")
+		return
+	}
+
+	baseURL := os.Getenv("PAYRAM_AGENT_UPDATE_BASE_URL")
+	if baseURL == "" {
+		RespondError(w, http.StatusInternalServerError, "UPDATE_BASE_URL_MISSING", "update base URL not configured")
+		return
+	}
+	pub := os.Getenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64")
+	if pub == "" {
+		RespondError(w, http.StatusInternalServerError, "UPDATE_PUBKEY_MISSING", "update public key not configured")
+		return
+	}
+
+	channels, err := update.ListChannels(r.Context(), baseURL, pub)
+	if err != nil {
+		RespondError(w, http.StatusBadGateway, "CHANNEL_INDEX_FAILED", err.Error())
+		return
+	}
+	status, err := update.LoadStatus()
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "STATUS_LOAD_FAILED", err.Error())
+		return
+	}
+
+	RespondOK(w, http.StatusOK, map[string]any{
+		"current_version":    status.CurrentVersion,
+		"current_channel":    status.CurrentChannel,
+		"configured_channel": configuredChannel(),
+		"channels":           channels,
+	})
+}
+
+// dryRunPlan describes what applying manifest would change, given the verified staged artifacts.
+func dryRunPlan(manifest update.Manifest, channel, currentChannel, coreVersion string, staged map[string]string, warnings []string) map[string]any {
+	currentTarget, _ := os.Readlink(update.CurrentSymlink())
+	releaseDir := update.ReleaseDir(manifest.Version)
+	_, statErr := os.Stat(releaseDir)
+
+	artifacts := map[string]any{}
+	for name, art := range map[string]update.Artifact{"chat": manifest.Artifacts.Chat, "mcp": manifest.Artifacts.MCP} {
+		info := map[string]any{
+			"url":      art.URL,
+			"sha256":   art.SHA256,
+			"verified": true,
+		}
+		if fi, err := os.Stat(staged[name]); err == nil {
+			info["size_bytes"] = fi.Size()
+		}
+		if art.Image != "" {
+			info["image"] = art.Image
+		}
+		artifacts[name] = info
+	}
+
+	return map[string]any{
+		"dry_run":          true,
+		"channel":          channel,
+		"current_channel":  currentChannel,
+		"current_version":  update.VersionFromTarget(currentTarget),
+		"target_version":   manifest.Version,
+		"up_to_date":       update.VersionFromTarget(currentTarget) == manifest.Version,
+		"release_dir":      releaseDir,
+		"replaces_release": statErr == nil,
+		"symlinks": map[string]string{
+			"current":  releaseDir,
+			"previous": currentTarget,
+		},
+		"artifacts":        artifacts,
+		"payram_core":      coreVersion,
+		"restart_required": true,
+		"warnings":         warnings,
+	}
+}
+
+// channelWarnings flags a manifest whose signed channel differs from the channel it was fetched
+// from, and an apply that moves the install to a different channel than it is currently on.
+func channelWarnings(requested, manifestChannel, currentChannel string) []string {
+	warnings := []string{}
+	if manifestChannel != "" && manifestChannel != requested {
+		warnings = append(warnings, fmt.Sprintf("manifest channel %q differs from requested channel %q", manifestChannel, requested))
+	}
+	effective := manifestChannel
+	if effective == "" {
+		effective = requested
+	}
+	if currentChannel != "" && currentChannel != effective {
+		warnings = append(warnings, fmt.Sprintf("switching channel from %q to %q", currentChannel, effective))
+	}
+	return warnings
+}
+
+// queryFlag reports whether a boolean query parameter is set to 1 or true.
+func queryFlag(r *http.Request, key string) bool {
+	v := strings.ToLower(r.URL.Query().Get(key))
+	return v == "1" || v == "true"
+}
+
+// updateHistoryHandler returns the most recent update audit entries, newest first.
+func updateHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET allowed")
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			RespondError(w, http.StatusBadRequest, "INVALID_LIMIT", "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	entries, err := update.LoadHistory(limit)
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "HISTORY_LOAD_FAILED", err.Error())
+		return
+	}
+	RespondOK(w, http.StatusOK, map[string]any{"entries": entries})
+}
+
+// addRestartTiming splits the children's restarts into the restart stage, until each had a
+// new process, and the health stage, until each passed its probe.
+func addRestartTiming(t *update.Timing, results []supervisor.RestartResult) {
+	var start, health int64
+	for _, r := range results {
+		start += r.StartMS
+		health += r.ElapsedMS - r.StartMS
+	}
+	t.Add(update.StageRestart, time.Duration(start)*time.Millisecond)
+	t.Add(update.StageHealth, time.Duration(health)*time.Millisecond)
+}
+
+func attemptResult(succeeded bool) string {
+	if succeeded {
+		return "success"
+	}
+	return "failed"
+}
+
+// recordHistory appends entry to the update history and publishes it as the lifecycle event
+// for its action. Failures take their code and message from the status the handler marked.
+func recordHistory(entry update.HistoryEntry, status update.UpdateStatus, succeeded bool) {
+	entry.Result = attemptResult(succeeded)
+	if !succeeded {
+		entry.ErrorCode = status.LastErrorCode
+		entry.Message = status.LastErrorMessage
+	}
+	_ = update.AppendHistory(entry)
+	lifecycle.Publish("update."+entry.Action, "", map[string]any{
+		"result": entry.Result, "from_version": entry.FromVersion, "to_version": entry.ToVersion,
+		"channel": entry.Channel, "error_code": entry.ErrorCode, "message": entry.Message,
+	})
+}
+
+// configuredChannel returns PAYRAM_AGENT_UPDATE_CHANNEL, defaulting to stable.
+func configuredChannel() string {
+	if v := strings.TrimSpace(os.Getenv("PAYRAM_AGENT_UPDATE_CHANNEL")); v != "" {
+		return v
+	}
+	return "stable"
+}
+
+// resolveApplyChannel picks the channel for an apply. When PAYRAM_AGENT_UPDATE_CHANNEL is set
+// the agent is pinned to it and a different ?channel requires force_channel=1; forced reports
+// whether that override was used.
+func resolveApplyChannel(r *http.Request) (channel string, forced bool, err error) {
+	pinned := strings.TrimSpace(os.Getenv("PAYRAM_AGENT_UPDATE_CHANNEL"))
+	channel = r.URL.Query().Get("channel")
+	if channel == "" {
+		return configuredChannel(), false, nil
+	}
+	if pinned == "" || channel == pinned {
+		return channel, false, nil
+	}
+	if !queryFlag(r, "force_channel") {
+		return channel, false, fmt.Errorf("agent is pinned to channel %q; pass force_channel=1 to apply from %q", pinned, channel)
+	}
+	return channel, true, nil
+}
+
+// updateVerifyHandler re-checks the current release against the manifest it was installed from.
+func updateVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET allowed")
+		return
+	}
+
+	RespondOK(w, http.StatusOK, update.VerifyInstall(os.Getenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64")))
+}
+
 func ignoreCompatEnabled() bool {
 	v := strings.ToLower(os.Getenv("PAYRAM_AGENT_IGNORE_COMPAT"))
 	return v == "1" || v == "true"
 }
 
+// chaosHealth marks every child unhealthy when update.ChaosHealthFailure injects a failure.
+func chaosHealth(restarts []supervisor.RestartResult) []supervisor.RestartResult {
+	err := update.ChaosHealthFailure()
+	if err == nil {
+		return restarts
+	}
+	for i := range restarts {
+		restarts[i].Ready, restarts[i].Error = false, err.Error()
+	}
+	return restarts
+}
+
+// readOnlyEnabled reports whether READ_ONLY is set, which the whole stack shares: the agent
+// then refuses to change what runs, and the MCP server hides mutating tools.
+func readOnlyEnabled() bool {
+	v := strings.ToLower(os.Getenv("READ_ONLY"))
+	return v == "1" || v == "true"
+}
+
+// refuseReadOnly answers 403 READ_ONLY and returns true when read-only mode is on; action
+// names what was refused.
+func refuseReadOnly(w http.ResponseWriter, action string) bool {
+	if !readOnlyEnabled() {
+		return false
+	}
+	RespondError(w, http.StatusForbidden, "READ_ONLY", action+" is disabled: the agent runs in read-only mode (READ_ONLY=true)")
+	return true
+}
+
 func restartHandler(sup Supervisor) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only POST allowed")
 			return
 		}
+		if refuseReadOnly(w, "restarting the children") {
+			return
+		}
 
-		if err := sup.RestartAll(); err != nil {
+		restarts := sup.RestartAllAndWait(healthTimeout())
+		if err := supervisor.RestartError(restarts); err != nil {
 			RespondError(w, http.StatusInternalServerError, "RESTART_FAILED", err.Error())
 			return
 		}
 
-		RespondOK(w, http.StatusOK, map[string]any{"status": "restarted"})
+		RespondOK(w, http.StatusOK, map[string]any{"status": "restarted", "restarts": restarts})
 	}
 }
 
@@ -611,7 +1104,144 @@ func logsHandler(sup Supervisor) func(http.ResponseWriter, *http.Request) {
 	}
 }
 
+// crashesHandler lists the saved crash reports, newest first (?component=, ?limit=, default
+// 20), or returns one with ?id=.
+func crashesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET allowed")
+		return
+	}
+	q := r.URL.Query()
+	if id := q.Get("id"); id != "" {
+		report, err := supervisor.LoadCrash(update.CrashDir(), id)
+		switch {
+		case errors.Is(err, supervisor.ErrCrashNotFound):
+			RespondError(w, http.StatusNotFound, "CRASH_NOT_FOUND", err.Error())
+		case err != nil:
+			RespondError(w, http.StatusInternalServerError, "CRASH_LOAD_FAILED", err.Error())
+		default:
+			RespondOK(w, http.StatusOK, report)
+		}
+		return
+	}
+
+	component := q.Get("component")
+	if component != "" && component != "chat" && component != "mcp" {
+		RespondError(w, http.StatusBadRequest, "INVALID_COMPONENT", "component must be chat or mcp")
+		return
+	}
+	limit := 20
+	if raw := q.Get("limit"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	reports, err := supervisor.ListCrashes(update.CrashDir(), component, limit)
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "CRASH_LOAD_FAILED", err.Error())
+		return
+	}
+	RespondOK(w, http.StatusOK, map[string]any{"crashes": reports})
+}
+
+// logLevelHandler reads (GET) or changes (PUT {"component": "agent|chat-api|mcp|all", "level": "debug"})
+// log levels at runtime. Agent levels change in-process; children are reached on their control endpoint.
+func logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	client := &http.Client{Timeout: 2 * time.Second}
+	children := map[string]int{
+		"chat-api": envPort("PAYRAM_CHAT_PORT", 2358),
+		"mcp":      envPort("PAYRAM_MCP_PORT", 3333),
+	}
+
+	var (
+		targets = []string{"agent", "chat-api", "mcp"}
+		level   string
+	)
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if refuseReadOnly(w, "changing log levels") {
+			return
+		}
+		var req struct {
+			Component string `json:"component"`
+			Level     string `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			RespondError(w, http.StatusBadRequest, "INVALID_JSON", err.Error())
+			return
+		}
+		lvl, err := logrus.ParseLevel(req.Level)
+		if err != nil {
+			RespondError(w, http.StatusBadRequest, "INVALID_ARGUMENT", err.Error())
+			return
+		}
+		level = lvl.String()
+
+		switch req.Component {
+		case "", "all":
+		case "agent", "chat-api", "mcp":
+			targets = []string{req.Component}
+		default:
+			RespondError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "component must be agent, chat-api, mcp, or all")
+			return
+		}
+	default:
+		RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "only GET and PUT allowed")
+		return
+	}
+
+	out := map[string]any{}
+	for _, name := range targets {
+		if name == "agent" {
+			if level != "" {
+				lvl, _ := logrus.ParseLevel(level)
+				logging.SetLevel("", lvl)
+			}
+			out[name] = map[string]any{"levels": logging.Levels()}
+			continue
+		}
+		out[name] = childLogLevel(r.Context(), client, children[name], level)
+	}
+	RespondOK(w, http.StatusOK, out)
+}
+
+// childLogLevel reads a child's levels, or sets them first when level is non-empty.
+func childLogLevel(ctx context.Context, client *http.Client, port int, level string) map[string]any {
+	fail := func(msg string) map[string]any {
+		return map[string]any{"error": respError{Code: "CHILD_UNREACHABLE", Message: msg}}
+	}
+
+	method, body := http.MethodGet, io.Reader(nil)
+	if level != "" {
+		method = http.MethodPut
+		raw, _ := json.Marshal(map[string]string{"level": level})
+		body = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("http://127.0.0.1:%d%s", port, logging.LevelPath), body)
+	if err != nil {
+		return fail(err.Error())
+	}
+	req.Header.Set(logging.ControlTokenHeader, os.Getenv(logging.ControlTokenEnv))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(err.Error())
+	}
+	defer resp.Body.Close()
+
+	var payload map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil || resp.StatusCode != http.StatusOK {
+		return fail(fmt.Sprintf("status %d", resp.StatusCode))
+	}
+	return payload
+}
+
 func secretsHandler(w http.ResponseWriter, r *http.Request) {
+	if (r.Method == http.MethodPut || r.Method == http.MethodDelete) && refuseReadOnly(w, "changing secrets") {
+		return
+	}
 	switch r.Method {
 	case http.MethodPut:
 		var req struct {
@@ -661,8 +1291,12 @@ func secretsStatusHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 type childVersionResult struct {
-	Info  *version.Info `json:"info,omitempty"`
-	Error *respError    `json:"error,omitempty"`
+	Info        *version.Info `json:"info,omitempty"`
+	Error       *respError    `json:"error,omitempty"`
+	Ready       bool          `json:"ready"`
+	Expected    string        `json:"expected_version,omitempty"`
+	Drift       bool          `json:"drift"`
+	DriftReason string        `json:"drift_reason,omitempty"`
 }
 
 func fetchChildVersion(ctx context.Context, client *http.Client, url string) childVersionResult {
@@ -726,37 +1360,6 @@ func randHex(n int) string {
 	return hex.EncodeToString(b)
 }
 
-func waitForHealth(chatPort, mcpPort int, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-	var lastErr error
-	for {
-		if err := checkHealth(chatPort, mcpPort, childHealthPath()); err == nil {
-			return nil
-		} else {
-			lastErr = err
-		}
-
-		if time.Now().After(deadline) {
-			return lastErr
-		}
-		time.Sleep(500 * time.Millisecond)
-	}
-}
-
-func checkHealth(chatPort, mcpPort int, path string) error {
-	client := &http.Client{Timeout: 2 * time.Second}
-	chatURL := fmt.Sprintf("http://127.0.0.1:%d%s", chatPort, path)
-	mcpURL := fmt.Sprintf("http://127.0.0.1:%d%s", mcpPort, path)
-
-	if err := pingOnce(client, chatURL); err != nil {
-		return fmt.Errorf("chat health: %w", err)
-	}
-	if err := pingOnce(client, mcpURL); err != nil {
-		return fmt.Errorf("mcp health: %w", err)
-	}
-	return nil
-}
-
 func pingOnce(client *http.Client, url string) error {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+)
+
+// smokeProbe is the functional check an apply runs once the children pass their health
+// probe: the MCP child must list the expected tools and answer one tool call.
+type smokeProbe struct {
+	tools    []string
+	call     string
+	callArgs map[string]any
+	timeout  time.Duration
+}
+
+// smokeFromEnv reads the post-update smoke check from PAYRAM_AGENT_SMOKE_TOOLS,
+// PAYRAM_AGENT_SMOKE_CALL and PAYRAM_AGENT_SMOKE_CALL_ARGS. It returns nil when neither tools
+// nor a call are configured.
+func smokeFromEnv() (*smokeProbe, error) {
+	p := &smokeProbe{
+		call:    strings.TrimSpace(os.Getenv("PAYRAM_AGENT_SMOKE_CALL")),
+		timeout: envMillis("PAYRAM_AGENT_SMOKE_TIMEOUT_MS", 30*time.Second),
+	}
+	for _, name := range strings.Split(os.Getenv("PAYRAM_AGENT_SMOKE_TOOLS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			p.tools = append(p.tools, name)
+		}
+	}
+	if len(p.tools) == 0 && p.call == "" {
+		return nil, nil
+	}
+	if raw := strings.TrimSpace(os.Getenv("PAYRAM_AGENT_SMOKE_CALL_ARGS")); raw != "" {
+		if p.call == "" {
+			return nil, fmt.Errorf("PAYRAM_AGENT_SMOKE_CALL_ARGS is set without PAYRAM_AGENT_SMOKE_CALL")
+		}
+		if err := json.Unmarshal([]byte(raw), &p.callArgs); err != nil {
+			return nil, fmt.Errorf("PAYRAM_AGENT_SMOKE_CALL_ARGS: %w", err)
+		}
+	}
+	if p.call != "" && !slices.Contains(p.tools, p.call) {
+		p.tools = append(p.tools, p.call)
+	}
+	return p, nil
+}
+
+// run checks the MCP child on PAYRAM_MCP_PORT.
+func (p *smokeProbe) run(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	client := chatserver.NewMCPClient(fmt.Sprintf("http://127.0.0.1:%d/", envPort("PAYRAM_MCP_PORT", 3333)))
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return fmt.Errorf("smoke: tools/list: %w", err)
+	}
+	listed := map[string]bool{}
+	for _, t := range tools {
+		listed[t.Name] = true
+	}
+	var missing []string
+	for _, name := range p.tools {
+		if !listed[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("smoke: tools/list is missing %s", strings.Join(missing, ", "))
+	}
+
+	if p.call == "" {
+		return nil
+	}
+	args := p.callArgs
+	if args == nil {
+		args = map[string]any{}
+	}
+	res, err := client.CallTool(ctx, p.call, args)
+	if err != nil {
+		return fmt.Errorf("smoke: tools/call %s: %w", p.call, err)
+	}
+	if len(res.Content) == 0 {
+		return fmt.Errorf("smoke: tools/call %s returned no content", p.call)
+	}
+	return nil
+}
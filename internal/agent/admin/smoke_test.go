@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// fakeMCPChild serves health and a JSON-RPC endpoint listing tools, where calling failing
+// returns an error, on PAYRAM_MCP_PORT.
+func fakeMCPChild(t *testing.T, tools []string, failing string) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var req protocol.Request
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		resp := protocol.Response{JSONRPC: "2.0", ID: req.ID}
+		switch req.Method {
+		case "tools/list":
+			var list protocol.ListResult
+			for _, name := range tools {
+				list.Tools = append(list.Tools, protocol.ToolDescriptor{Name: name})
+			}
+			resp.Result = list
+		case "tools/call":
+			var params protocol.CallParams
+			_ = json.Unmarshal(req.Params, &params)
+			if params.Name == failing {
+				resp.Error = &protocol.ResponseError{Code: -32000, Message: "upstream unavailable"}
+				break
+			}
+			resp.Result = protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: "ok"}}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("PAYRAM_MCP_PORT", portFromURL(srv.URL))
+}
+
+func TestUpdateApplySmokeCheckPasses(t *testing.T) {
+	sup := chaosInstall(t)
+	fakeMCPChild(t, []string{"payram_numbers_summary", "payram_daily_stats"}, "")
+	t.Setenv("PAYRAM_AGENT_SMOKE_TOOLS", "payram_daily_stats")
+	t.Setenv("PAYRAM_AGENT_SMOKE_CALL", "payram_numbers_summary")
+
+	expectApplied(t, sup)
+	timings := loadStatus(t).RecentTimings
+	if len(timings) == 0 {
+		t.Fatalf("expected a recorded timing")
+	}
+	found := false
+	for _, s := range timings[len(timings)-1].Stages {
+		found = found || s.Stage == update.StageSmoke
+	}
+	if !found {
+		t.Fatalf("expected a smoke stage in %+v", timings[len(timings)-1].Stages)
+	}
+}
+
+func TestUpdateApplySmokeFailureRollsBack(t *testing.T) {
+	for name, env := range map[string]map[string]string{
+		"missing tool": {"PAYRAM_AGENT_SMOKE_TOOLS": "payram_daily_stats,payram_new_tool"},
+		"failed call":  {"PAYRAM_AGENT_SMOKE_CALL": "payram_numbers_summary"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sup := chaosInstall(t)
+			fakeMCPChild(t, []string{"payram_numbers_summary", "payram_daily_stats"}, "payram_numbers_summary")
+			for k, v := range env {
+				t.Setenv(k, v)
+			}
+
+			rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply")
+			if rr.Code != http.StatusInternalServerError || errorCode(t, decodeBody(t, rr)) != "SMOKE_FAILED_ROLLED_BACK" {
+				t.Fatalf("expected SMOKE_FAILED_ROLLED_BACK, got %d %s", rr.Code, rr.Body.String())
+			}
+			st := loadStatus(t)
+			if st.LastErrorCode != "SMOKE_FAILED_ROLLED_BACK" || st.CurrentVersion != "1.0.0" || st.LastSuccessVersion != "1.0.0" {
+				t.Fatalf("unexpected status after rollback: %+v", st)
+			}
+			if got := currentRelease(t); got != "1.0.0" {
+				t.Fatalf("current not rolled back: %s", got)
+			}
+			if sup.restarts != 2 {
+				t.Fatalf("expected a restart for the update and one for the rollback, got %d", sup.restarts)
+			}
+		})
+	}
+}
+
+func TestUpdateApplyRejectsInvalidSmokeConfig(t *testing.T) {
+	sup := chaosInstall(t)
+	t.Setenv("PAYRAM_AGENT_SMOKE_CALL", "payram_numbers_summary")
+	t.Setenv("PAYRAM_AGENT_SMOKE_CALL_ARGS", "{not json")
+
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply")
+	if rr.Code != http.StatusInternalServerError || errorCode(t, decodeBody(t, rr)) != "SMOKE_CONFIG_INVALID" {
+		t.Fatalf("expected SMOKE_CONFIG_INVALID, got %d %s", rr.Code, rr.Body.String())
+	}
+	if sup.restarts != 0 {
+		t.Fatalf("expected no restart, got %d", sup.restarts)
+	}
+}
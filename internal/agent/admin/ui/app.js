@@ -0,0 +1,193 @@
+"use strict";
+
+// Minimal agent dashboard. The admin token is kept in sessionStorage and sent as
+// X-MCP-Key on every API call; the page itself carries no data.
+(function () {
+  const tokenKey = "payram-agent-token";
+  const refreshMs = 10000;
+  const $ = (id) => document.getElementById(id);
+  let timer = null;
+  let busy = false;
+
+  function token() { return sessionStorage.getItem(tokenKey) || ""; }
+
+  async function api(method, path) {
+    const resp = await fetch(path, { method, headers: { "X-MCP-Key": token() } });
+    let body = null;
+    try { body = await resp.json(); } catch (_) { /* non-JSON */ }
+    if (resp.status === 401) {
+      signOut();
+      throw new Error("admin token rejected");
+    }
+    if (!body || !body.ok) {
+      const err = body && body.error ? body.error.code + ": " + body.error.message : "HTTP " + resp.status;
+      throw new Error(err);
+    }
+    return body.data;
+  }
+
+  function el(tag, text, cls) {
+    const e = document.createElement(tag);
+    if (text !== undefined && text !== null) e.textContent = String(text);
+    if (cls) e.className = cls;
+    return e;
+  }
+
+  function fillList(dl, rows) {
+    dl.replaceChildren();
+    for (const [k, v, cls] of rows) {
+      dl.append(el("dt", k), el("dd", v === "" || v === undefined || v === null ? "—" : v, cls));
+    }
+  }
+
+  function fmtTime(t) {
+    if (!t || t.startsWith("0001-")) return "";
+    return new Date(t).toLocaleString();
+  }
+
+  function notice(msg, isError) {
+    const n = $("notice");
+    n.textContent = msg || "";
+    n.classList.toggle("error", !!isError);
+  }
+
+  function render(o) {
+    $("agent-version").textContent = "agent " + ((o.agent && o.agent.version) || "");
+
+    fillList($("current"), [
+      ["Version", o.current.version],
+      ["Channel", o.current.channel],
+      ["Installed", fmtTime(o.current.installed_at)],
+      ["Previous", o.previous.version ? o.previous.version + (o.previous.channel ? " (" + o.previous.channel + ")" : "") : ""],
+      ["Last error", o.update.last_error_code ? o.update.last_error_code + ": " + o.update.last_error_message : "", "bad"],
+    ]);
+    $("rollback").disabled = !o.previous.version;
+
+    const a = o.available;
+    const availRows = [["Channel", a.channel]];
+    if (a.error) {
+      availRows.push(["Check", a.error.code + ": " + a.error.message, "bad"]);
+    } else {
+      availRows.push(
+        ["Latest", a.target_version],
+        ["Released", fmtTime(a.released_at)],
+        ["Status", a.revoked ? "revoked" : a.available ? "update available" : "up to date", a.available ? "ok" : ""],
+      );
+      if (a.problems && a.problems.length) availRows.push(["Compatibility", a.problems.join("; "), "bad"]);
+    }
+    fillList($("available"), availRows);
+    $("changelog").textContent = a.changelog || "";
+    $("apply").disabled = !a.available;
+    $("dry-run").disabled = !a.available;
+
+    const tbody = $("children");
+    tbody.replaceChildren();
+    for (const name of ["chat", "mcp"]) {
+      const c = o.children[name] || {};
+      const tr = el("tr");
+      const ver = c.info ? c.info.version : c.error ? c.error.message : "";
+      tr.append(
+        el("td", name),
+        el("td", ver + (c.drift ? " (drift: " + c.drift_reason + ")" : ""), c.drift ? "bad" : ""),
+        el("td", c.pid || "—"),
+        el("td", c.restarts || 0),
+        el("td", c.ready ? "yes" : "no", c.ready ? "ok" : "bad"),
+      );
+      tbody.append(tr);
+    }
+
+    const hist = $("history");
+    hist.replaceChildren();
+    for (const h of o.history || []) {
+      const tr = el("tr");
+      tr.append(
+        el("td", fmtTime(h.time)),
+        el("td", h.action),
+        el("td", h.result, h.result === "success" ? "ok" : "bad"),
+        el("td", h.from_version),
+        el("td", h.to_version),
+        el("td", (h.channel || "") + (h.forced_channel ? " (forced)" : "")),
+        el("td", h.message || h.error_code || ""),
+      );
+      hist.append(tr);
+    }
+  }
+
+  async function refresh() {
+    if (busy) return;
+    try {
+      render(await api("GET", "/admin/overview"));
+    } catch (e) {
+      notice("Refresh failed: " + e.message, true);
+    }
+  }
+
+  async function refreshLogs() {
+    const comp = $("log-component").value;
+    const tail = $("log-tail").value;
+    try {
+      const d = await api("GET", "/admin/logs?component=" + encodeURIComponent(comp) + "&tail=" + encodeURIComponent(tail));
+      const pre = $("logs");
+      pre.textContent = (d.lines || []).join("\n");
+      pre.scrollTop = pre.scrollHeight;
+    } catch (e) {
+      $("logs").textContent = "Failed to load logs: " + e.message;
+    }
+  }
+
+  async function action(label, method, path, confirmText) {
+    if (confirmText && !window.confirm(confirmText)) return;
+    busy = true;
+    document.querySelectorAll("main button").forEach((b) => { b.disabled = true; });
+    notice(label + "…");
+    try {
+      const d = await api(method, path);
+      const warnings = d && d.warnings && d.warnings.length ? "\nWarnings:\n- " + d.warnings.join("\n- ") : "";
+      notice(label + " done." + warnings);
+      if (d && d.dry_run) notice(label + ":\n" + JSON.stringify(d, null, 2));
+    } catch (e) {
+      notice(label + " failed: " + e.message, true);
+    } finally {
+      busy = false;
+      document.querySelectorAll("main button").forEach((b) => { b.disabled = false; });
+      await refresh();
+      await refreshLogs();
+    }
+  }
+
+  function showDashboard() {
+    $("login").hidden = true;
+    $("dashboard").hidden = false;
+    $("logout").hidden = false;
+    refresh();
+    refreshLogs();
+    clearInterval(timer);
+    timer = setInterval(refresh, refreshMs);
+  }
+
+  function signOut() {
+    sessionStorage.removeItem(tokenKey);
+    clearInterval(timer);
+    $("dashboard").hidden = true;
+    $("logout").hidden = true;
+    $("login").hidden = false;
+    $("token").focus();
+  }
+
+  $("login-form").addEventListener("submit", (ev) => {
+    ev.preventDefault();
+    sessionStorage.setItem(tokenKey, $("token").value.trim());
+    $("token").value = "";
+    notice("");
+    showDashboard();
+  });
+  $("logout").addEventListener("click", signOut);
+  $("restart").addEventListener("click", () => action("Restart", "POST", "/admin/child/restart", "Restart chat and MCP now?"));
+  $("dry-run").addEventListener("click", () => action("Update preview", "POST", "/admin/update/apply?dry_run=1"));
+  $("apply").addEventListener("click", () => action("Update", "POST", "/admin/update/apply", "Install the available update and restart children?"));
+  $("rollback").addEventListener("click", () => action("Rollback", "POST", "/admin/update/rollback", "Switch back to the previous release?"));
+  $("log-refresh").addEventListener("click", refreshLogs);
+  $("log-component").addEventListener("change", refreshLogs);
+
+  if (token()) showDashboard(); else signOut();
+})();
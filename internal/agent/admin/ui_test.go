@@ -0,0 +1,33 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDashboardServedWithoutKeyButIPGated(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "")
+	mux := NewMux(&fakeSupervisor{})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/ui/", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "PayRam Agent") {
+		t.Fatalf("expected dashboard page, got %d %s", rr.Code, rr.Body.String())
+	}
+	if csp := rr.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "default-src 'self'") {
+		t.Fatalf("expected CSP header, got %q", csp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/ui/app.js", nil)
+	req.RemoteAddr = "10.1.2.3:1234"
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 outside allowlist, got %d", rr.Code)
+	}
+}
@@ -7,20 +7,40 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/supervisor"
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+	"github.com/payram/payram-analytics-mcp-server/internal/version"
 )
 
 type fakeSupervisor struct{ restarts int }
 
-func (f *fakeSupervisor) RestartAll() error         { f.restarts++; return nil }
+// RestartAllAndWait reports each child ready when its health port (PAYRAM_CHAT_PORT,
+// PAYRAM_MCP_PORT) answers, as the real supervisor's health probe would.
+func (f *fakeSupervisor) RestartAllAndWait(time.Duration) []supervisor.RestartResult {
+	f.restarts++
+	client := &http.Client{Timeout: time.Second}
+	var results []supervisor.RestartResult
+	for name, port := range map[string]int{"mcp": envPort("PAYRAM_MCP_PORT", 3333), "chat": envPort("PAYRAM_CHAT_PORT", 2358)} {
+		res := supervisor.RestartResult{Name: name, PID: 1, Ready: true}
+		if err := pingOnce(client, fmt.Sprintf("http://127.0.0.1:%d%s", port, childHealthPath())); err != nil {
+			res.Ready, res.Error = false, "health: "+err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
 func (f *fakeSupervisor) Status() supervisor.Status { return supervisor.Status{} }
 func (f *fakeSupervisor) Logs(string, int) []string { return nil }
 
@@ -109,6 +129,20 @@ func TestUpdateApplySuccess(t *testing.T) {
 	if sup.restarts != 1 {
 		t.Fatalf("expected 1 restart got %d", sup.restarts)
 	}
+	if len(st.RecentTimings) != 1 {
+		t.Fatalf("expected 1 recorded timing got %d", len(st.RecentTimings))
+	}
+	timing := st.RecentTimings[0]
+	if timing.Action != "apply" || timing.Result != "success" || timing.Version != manifest.Version || timing.Bottleneck == "" {
+		t.Fatalf("unexpected timing: %+v", timing)
+	}
+	var stages []string
+	for _, s := range timing.Stages {
+		stages = append(stages, s.Stage)
+	}
+	if want := "fetch verify download switch restart health"; strings.Join(stages, " ") != want {
+		t.Fatalf("stages = %v, want %s", stages, want)
+	}
 
 	target, err := os.Readlink(update.CurrentSymlink())
 	if err != nil {
@@ -127,6 +161,10 @@ func TestUpdateApplySuccess(t *testing.T) {
 	if target, err := os.Readlink(filepath.Join(update.ReleaseDir(manifest.Version), "mcp")); err != nil || filepath.Base(target) != "payram-analytics-mcp" {
 		t.Fatalf("mcp compat link missing/invalid: err=%v target=%s", err, target)
 	}
+
+	if rep := update.VerifyInstall(base64.StdEncoding.EncodeToString(pub)); !rep.Intact {
+		t.Fatalf("expected installed release to verify: %+v", rep)
+	}
 }
 
 func TestUpdateApplyIgnoreCompatNoCoreURL(t *testing.T) {
@@ -248,8 +286,9 @@ func TestUpdateApplyHealthRollback(t *testing.T) {
 		raw, _ := json.Marshal(manifest)
 		w.Write(ed25519.Sign(priv, raw))
 	})
-	mux.HandleFunc("/chat", func(w http.ResponseWriter, _ *http.Request) { w.Write(chatData) })
-	mux.HandleFunc("/mcp", func(w http.ResponseWriter, _ *http.Request) { w.Write(mcpData) })
+	var downloads atomic.Int32
+	mux.HandleFunc("/chat", func(w http.ResponseWriter, _ *http.Request) { downloads.Add(1); w.Write(chatData) })
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, _ *http.Request) { downloads.Add(1); w.Write(mcpData) })
 
 	srv := httptest.NewServer(mux)
 	defer srv.Close()
@@ -317,6 +356,16 @@ func TestUpdateApplyHealthRollback(t *testing.T) {
 	if sup.restarts < 2 {
 		t.Fatalf("expected at least 2 restarts got %d", sup.restarts)
 	}
+
+	// A retry takes the verified artifacts from the cache instead of downloading them again.
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("retry: expected 500 got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if n := downloads.Load(); n != 2 {
+		t.Fatalf("expected 2 artifact downloads across both attempts, got %d", n)
+	}
 }
 
 func TestRollbackEndpoint(t *testing.T) {
@@ -382,3 +431,247 @@ func portFromURL(raw string) string {
 	u, _ := url.Parse(raw)
 	return u.Port()
 }
+
+func TestUpdateApplyDryRunLeavesInstallUntouched(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("PAYRAM_AGENT_HOME", home)
+	t.Setenv("PAYRAM_AGENT_IGNORE_COMPAT", "1")
+	t.Setenv("PAYRAM_CORE_URL", "")
+
+	newReleaseFixture(t, update.Manifest{Version: "2.0.0"})
+
+	oldRelease := update.ReleaseDir("1.0.0")
+	if err := os.MkdirAll(oldRelease, 0o755); err != nil {
+		t.Fatalf("mkdir old: %v", err)
+	}
+	if _, err := update.UpdateSymlinks(oldRelease); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	sup := &fakeSupervisor{}
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply?dry_run=1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	data := decodeBody(t, rr)["data"].(map[string]any)
+	if data["dry_run"] != true || data["current_version"] != "1.0.0" || data["target_version"] != "2.0.0" {
+		t.Fatalf("unexpected plan: %v", data)
+	}
+	if links := data["symlinks"].(map[string]any); links["previous"] != oldRelease {
+		t.Fatalf("expected previous to become old release, got %v", links)
+	}
+
+	if sup.restarts != 0 {
+		t.Fatalf("dry run must not restart children")
+	}
+	if target, _ := os.Readlink(update.CurrentSymlink()); target != oldRelease {
+		t.Fatalf("dry run moved current to %s", target)
+	}
+	entries, _ := os.ReadDir(update.ReleasesDir())
+	if len(entries) != 1 {
+		t.Fatalf("expected only the old release to remain, got %d entries", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(update.StateDir(), "update_status.json")); !os.IsNotExist(err) {
+		t.Fatalf("dry run must not write update status: %v", err)
+	}
+}
+
+func TestUpdateApplyDockerDriverPullsImages(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_IGNORE_COMPAT", "1")
+	t.Setenv("PAYRAM_CORE_URL", "")
+	healthyChildren(t)
+
+	dir := t.TempDir()
+	bin, log := filepath.Join(dir, "docker"), filepath.Join(dir, "calls.log")
+	script := "#!/bin/sh\necho \"$*\" >> " + log + "\n[ \"$1\" = image ] && exit 1\nexit 0\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PAYRAM_AGENT_CHILD_DRIVER", "docker")
+	t.Setenv("PAYRAM_AGENT_DOCKER_BIN", bin)
+
+	fixture := newReleaseFixture(t, update.Manifest{Version: "1.0.0"})
+	m := fixture.manifests["stable"]
+	m.Artifacts.Chat.Image = "registry/chat@sha256:abc"
+	m.Artifacts.MCP.Image = "registry/mcp:1.0.0"
+	fixture.manifests["stable"] = m
+
+	rr := adminRequest(t, &fakeSupervisor{}, http.MethodPost, "/admin/update/apply")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("apply: %d %s", rr.Code, rr.Body.String())
+	}
+	warnings, _ := decodeBody(t, rr)["data"].(map[string]any)["warnings"].([]any)
+	if len(warnings) == 0 || warnings[len(warnings)-1] != "mcp image registry/mcp:1.0.0 is not pinned by digest" {
+		t.Fatalf("expected digest warning, got %v", warnings)
+	}
+	calls, _ := os.ReadFile(log)
+	if !strings.Contains(string(calls), "pull registry/chat@sha256:abc\n") || !strings.Contains(string(calls), "pull registry/mcp:1.0.0\n") {
+		t.Fatalf("expected both images pulled, got:\n%s", calls)
+	}
+	if _, err := os.Stat(filepath.Join(update.ReleaseDir("1.0.0"), update.ChatBinaryName)); !os.IsNotExist(err) {
+		t.Fatalf("docker releases hold no binaries: %v", err)
+	}
+	if rep := update.VerifyInstall(fixture.pubB64); !rep.Intact {
+		t.Fatalf("expected intact image release, got %+v", rep)
+	}
+
+	fixture.publish("stable", update.Manifest{Version: "1.1.0"})
+	rr = adminRequest(t, &fakeSupervisor{}, http.MethodPost, "/admin/update/apply")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without images, got %d", rr.Code)
+	}
+	if code := errorCode(t, decodeBody(t, rr)); code != "IMAGE_MISSING" {
+		t.Fatalf("expected IMAGE_MISSING, got %s", code)
+	}
+}
+
+func TestUpdateApplyRecordsChannelAndWarnsOnSwitch(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_IGNORE_COMPAT", "1")
+	t.Setenv("PAYRAM_CORE_URL", "")
+	healthyChildren(t)
+
+	fixture := newReleaseFixture(t, update.Manifest{Version: "1.0.0", Channel: "stable"})
+	fixture.publish("beta", update.Manifest{Version: "1.1.0-beta", Channel: "beta"})
+
+	sup := &fakeSupervisor{}
+	if rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply"); rr.Code != http.StatusOK {
+		t.Fatalf("stable apply: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply?channel=beta")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("beta apply: %d %s", rr.Code, rr.Body.String())
+	}
+	warnings, _ := decodeBody(t, rr)["data"].(map[string]any)["warnings"].([]any)
+	if len(warnings) == 0 || warnings[0] != `switching channel from "stable" to "beta"` {
+		t.Fatalf("expected channel switch warning, got %v", warnings)
+	}
+
+	rr = adminRequest(t, sup, http.MethodGet, "/admin/update/status")
+	data := decodeBody(t, rr)["data"].(map[string]any)
+	if data["current_channel"] != "beta" || data["previous_channel"] != "stable" {
+		t.Fatalf("unexpected channels in status: %v", data)
+	}
+
+	if rr := adminRequest(t, sup, http.MethodPost, "/admin/update/rollback"); rr.Code != http.StatusOK {
+		t.Fatalf("rollback: %d %s", rr.Code, rr.Body.String())
+	}
+	st, _ := update.LoadStatus()
+	if st.CurrentChannel != "stable" || st.PreviousChannel != "beta" {
+		t.Fatalf("expected rollback to swap channels, got current=%q previous=%q", st.CurrentChannel, st.PreviousChannel)
+	}
+}
+
+func TestChannelWarningsFlagsMismatchedManifest(t *testing.T) {
+	got := channelWarnings("stable", "beta", "")
+	if len(got) != 1 || got[0] != `manifest channel "beta" differs from requested channel "stable"` {
+		t.Fatalf("unexpected warnings: %v", got)
+	}
+	if got := channelWarnings("stable", "stable", "stable"); len(got) != 0 {
+		t.Fatalf("expected no warnings, got %v", got)
+	}
+}
+
+func TestUpdateApplyPinnedChannelRequiresForce(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_IGNORE_COMPAT", "1")
+	t.Setenv("PAYRAM_CORE_URL", "")
+	t.Setenv("PAYRAM_AGENT_UPDATE_CHANNEL", "stable")
+	healthyChildren(t)
+
+	fixture := newReleaseFixture(t, update.Manifest{Version: "1.0.0", Channel: "stable"})
+	fixture.publish("beta", update.Manifest{Version: "1.1.0-beta", Channel: "beta"})
+
+	sup := &fakeSupervisor{}
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply?channel=beta")
+	if rr.Code != http.StatusForbidden || errorCode(t, decodeBody(t, rr)) != "CHANNEL_LOCKED" {
+		t.Fatalf("expected CHANNEL_LOCKED, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = adminRequest(t, sup, http.MethodPost, "/admin/update/apply?channel=beta&force_channel=1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("forced apply: %d %s", rr.Code, rr.Body.String())
+	}
+
+	entries, err := update.LoadHistory(0)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 history entries, got %+v", entries)
+	}
+	forced, rejected := entries[0], entries[1]
+	if !forced.ForcedChannel || forced.Channel != "beta" || forced.Result != "success" || forced.ToVersion != "1.1.0-beta" {
+		t.Fatalf("unexpected forced entry: %+v", forced)
+	}
+	if rejected.Result != "failed" || rejected.ErrorCode != "CHANNEL_LOCKED" {
+		t.Fatalf("unexpected rejected entry: %+v", rejected)
+	}
+
+	rr = adminRequest(t, sup, http.MethodGet, "/admin/update/history?limit=1")
+	if got := decodeBody(t, rr)["data"].(map[string]any)["entries"].([]any); len(got) != 1 {
+		t.Fatalf("expected limit to apply, got %d entries", len(got))
+	}
+}
+
+func TestUpdateApplyRefusesComponentNeedingNewerAgent(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_IGNORE_COMPAT", "false")
+	healthyChildren(t)
+
+	core := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"version":"1.12.3"}`))
+	}))
+	defer core.Close()
+	t.Setenv("PAYRAM_CORE_URL", core.URL)
+
+	prev := version.Version
+	version.Version = "1.0.0"
+	defer func() { version.Version = prev }()
+
+	fixture := newReleaseFixture(t, update.Manifest{Version: "2.0.0"})
+	m := fixture.manifests["stable"]
+	m.Artifacts.MCP.Requires = map[string]update.Range{"agent": {Min: "1.1.0"}}
+	fixture.manifests["stable"] = m
+
+	sup := &fakeSupervisor{}
+	rr := adminRequest(t, sup, http.MethodPost, "/admin/update/apply")
+	if rr.Code != http.StatusBadRequest || errorCode(t, decodeBody(t, rr)) != "INCOMPATIBLE_COMPONENTS" {
+		t.Fatalf("expected INCOMPATIBLE_COMPONENTS, got %d %s", rr.Code, rr.Body.String())
+	}
+	if sup.restarts != 0 {
+		t.Fatalf("expected no restart")
+	}
+	if _, err := os.Lstat(update.CurrentSymlink()); !os.IsNotExist(err) {
+		t.Fatalf("expected no release switch: %v", err)
+	}
+}
+
+func TestUpdateApplyEarlyFailuresRecordErrorCode(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+
+	cases := []struct {
+		baseURL, pubKey, code string
+	}{
+		{"", "", "UPDATE_BASE_URL_MISSING"},
+		{"http://127.0.0.1:1", "", "UPDATE_PUBKEY_MISSING"},
+	}
+	for i, c := range cases {
+		t.Setenv("PAYRAM_AGENT_UPDATE_BASE_URL", c.baseURL)
+		t.Setenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64", c.pubKey)
+		rr := adminRequest(t, &fakeSupervisor{}, http.MethodPost, "/admin/update/apply")
+		if got := errorCode(t, decodeBody(t, rr)); got != c.code {
+			t.Fatalf("code %q, want %q", got, c.code)
+		}
+		entries, err := update.LoadHistory(0)
+		if err != nil || len(entries) != i+1 {
+			t.Fatalf("history: %+v, %v", entries, err)
+		}
+		if e := entries[0]; e.Result != "failed" || e.ErrorCode != c.code || e.Message == "" {
+			t.Fatalf("history entry %+v, want failed with %s", e, c.code)
+		}
+	}
+}
@@ -393,3 +393,57 @@ func TestUpdateAvailableIgnoreCompatNoCoreURL(t *testing.T) {
 		t.Fatalf("expected compatible true when ignored")
 	}
 }
+
+func TestUpdateChannelsListsIndexedChannels(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/channels.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"channels":["stable","nightly"]}`))
+	})
+	for channel, version := range map[string]string{"stable": "1.7.4", "nightly": "1.8.0-rc.1"} {
+		raw, _ := json.Marshal(update.Manifest{Channel: channel, Version: version, Notes: channel + " notes"})
+		mux.HandleFunc("/"+channel+"/manifest.json", func(w http.ResponseWriter, _ *http.Request) { w.Write(raw) })
+		mux.HandleFunc("/"+channel+"/manifest.json.sig", func(w http.ResponseWriter, _ *http.Request) { w.Write(ed25519.Sign(priv, raw)) })
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_ADMIN_TOKEN", "tok")
+	t.Setenv("PAYRAM_AGENT_ADMIN_ALLOWLIST", "")
+	t.Setenv("PAYRAM_AGENT_UPDATE_BASE_URL", srv.URL)
+	t.Setenv("PAYRAM_AGENT_UPDATE_PUBKEY_B64", base64.StdEncoding.EncodeToString(pub))
+	t.Setenv("PAYRAM_AGENT_UPDATE_CHANNEL", "")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/update/channels", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set(adminKeyHeader, "tok")
+	rr := httptest.NewRecorder()
+	NewMux(&supervisor.Supervisor{}).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+
+	var body struct {
+		Data struct {
+			ConfiguredChannel string               `json:"configured_channel"`
+			Channels          []update.ChannelInfo `json:"channels"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	got := body.Data.Channels
+	if body.Data.ConfiguredChannel != "stable" || len(got) != 3 {
+		t.Fatalf("unexpected response %+v", body.Data)
+	}
+	if got[0].Name != "stable" || got[0].Version != "1.7.4" || got[0].Notes != "stable notes" {
+		t.Fatalf("stable = %+v", got[0])
+	}
+	if got[1].Name != "beta" || got[1].Version != "" || got[1].Error == "" {
+		t.Fatalf("beta is not hosted and should report an error: %+v", got[1])
+	}
+	if got[2].Name != "nightly" || got[2].Version != "1.8.0-rc.1" || got[2].Error != "" {
+		t.Fatalf("nightly = %+v", got[2])
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/supervisor"
 	"github.com/payram/payram-analytics-mcp-server/internal/version"
 )
 
@@ -60,3 +61,36 @@ func TestFetchChildVersionInvalidJSON(t *testing.T) {
 		t.Fatalf("expected no info on error")
 	}
 }
+
+func TestCheckDriftFlagsMismatchedVersion(t *testing.T) {
+	res := childVersionResult{Info: &version.Info{Version: "v1.0.0"}}
+	checkDrift(&res, "1.1.0", supervisor.ComponentStatus{}, time.Time{})
+	if !res.Drift || res.Expected != "1.1.0" {
+		t.Fatalf("expected drift for old build, got %+v", res)
+	}
+
+	res = childVersionResult{Info: &version.Info{Version: "v1.1.0"}}
+	checkDrift(&res, "1.1.0", supervisor.ComponentStatus{}, time.Time{})
+	if res.Drift {
+		t.Fatalf("expected matching version to be in sync, got %+v", res)
+	}
+}
+
+func TestCheckDriftFlagsProcessOlderThanRelease(t *testing.T) {
+	switchedAt := time.Now()
+	stale := supervisor.ComponentStatus{Name: "mcp", PID: 42, StartTime: switchedAt.Add(-time.Minute)}
+
+	res := childVersionResult{Info: &version.Info{Version: "dev"}}
+	checkDrift(&res, "1.1.0", stale, switchedAt)
+	if !res.Drift || res.DriftReason == "" {
+		t.Fatalf("expected drift for process started before the switch, got %+v", res)
+	}
+
+	fresh := stale
+	fresh.StartTime = switchedAt.Add(time.Second)
+	res = childVersionResult{Info: &version.Info{Version: "dev"}}
+	checkDrift(&res, "1.1.0", fresh, switchedAt)
+	if res.Drift {
+		t.Fatalf("expected restarted process to be in sync, got %+v", res)
+	}
+}
@@ -0,0 +1,165 @@
+// Package kube lets the agent manage chat and MCP running in a Kubernetes Deployment:
+// updates patch the Deployment's container images instead of restarting local processes.
+// It talks to the API server directly with the pod's service account.
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DriverKubernetes is the PAYRAM_AGENT_CHILD_DRIVER value that selects this package.
+const DriverKubernetes = "kubernetes"
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Enabled reports whether PAYRAM_AGENT_CHILD_DRIVER selects Kubernetes mode.
+func Enabled() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("PAYRAM_AGENT_CHILD_DRIVER")), DriverKubernetes)
+}
+
+// APIError is a non-2xx answer from the API server.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("kubernetes API: %d %s", e.Status, e.Message)
+}
+
+// Client is a minimal Kubernetes API client. TokenFile is re-read for every request because
+// projected service account tokens rotate.
+type Client struct {
+	BaseURL   string
+	Token     string
+	TokenFile string
+	HTTP      *http.Client
+}
+
+// ClientFromEnv uses PAYRAM_AGENT_KUBE_API_URL, PAYRAM_AGENT_KUBE_TOKEN and
+// PAYRAM_AGENT_KUBE_CA_FILE when set (for running the agent outside the cluster), else the
+// in-cluster service account.
+func ClientFromEnv() (*Client, error) {
+	c := &Client{
+		BaseURL: strings.TrimRight(os.Getenv("PAYRAM_AGENT_KUBE_API_URL"), "/"),
+		Token:   os.Getenv("PAYRAM_AGENT_KUBE_TOKEN"),
+	}
+	caFile := os.Getenv("PAYRAM_AGENT_KUBE_CA_FILE")
+	if c.BaseURL == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("not running in a cluster: set PAYRAM_AGENT_KUBE_API_URL")
+		}
+		c.BaseURL = "https://" + net.JoinHostPort(host, port)
+		if caFile == "" {
+			caFile = serviceAccountDir + "/ca.crt"
+		}
+	}
+	if c.Token == "" {
+		c.TokenFile = serviceAccountDir + "/token"
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("kubernetes CA: no certificates in %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	c.HTTP = &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	return c, nil
+}
+
+// DefaultNamespace is PAYRAM_AGENT_KUBE_NAMESPACE, else the service account's namespace,
+// else "default".
+func DefaultNamespace() string {
+	if ns := os.Getenv("PAYRAM_AGENT_KUBE_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if raw, err := os.ReadFile(serviceAccountDir + "/namespace"); err == nil {
+		if ns := strings.TrimSpace(string(raw)); ns != "" {
+			return ns
+		}
+	}
+	return "default"
+}
+
+// do sends a request and decodes a JSON answer into out, or returns the raw body when out
+// is a *[]byte.
+func (c *Client) do(ctx context.Context, method, path, contentType string, body any, out any) error {
+	var rd io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, rd)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	token := c.Token
+	if c.TokenFile != "" {
+		raw, err := os.ReadFile(c.TokenFile)
+		if err != nil {
+			return fmt.Errorf("service account token: %w", err)
+		}
+		token = strings.TrimSpace(string(raw))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var st struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(raw, &st) != nil || st.Message == "" {
+			st.Message = strings.TrimSpace(string(raw))
+		}
+		return &APIError{Status: resp.StatusCode, Message: st.Message}
+	}
+	switch o := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*o = raw
+		return nil
+	default:
+		return json.Unmarshal(raw, out)
+	}
+}
@@ -0,0 +1,217 @@
+package kube
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// fakeCluster serves one Deployment. After a patch the rollout completes once the
+// Deployment has been read rolloutReads more times, unless stuck is set.
+type fakeCluster struct {
+	mu           sync.Mutex
+	images       map[string]string
+	annotations  map[string]string
+	generation   int64
+	observed     int64
+	reads        int
+	rolloutReads int
+	stuck        bool
+	patches      []string
+}
+
+func (f *fakeCluster) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/apps/v1/namespaces/payram/deployments/web", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"kind":"Status","message":"Unauthorized"}`))
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch r.Method {
+		case http.MethodPatch:
+			if ct := r.Header.Get("Content-Type"); ct != "application/strategic-merge-patch+json" {
+				t.Errorf("patch content type = %q", ct)
+			}
+			raw, _ := io.ReadAll(r.Body)
+			f.patches = append(f.patches, string(raw))
+			var patch struct {
+				Metadata struct {
+					Annotations map[string]string `json:"annotations"`
+				} `json:"metadata"`
+				Spec struct {
+					Template struct {
+						Spec struct {
+							Containers []struct{ Name, Image string } `json:"containers"`
+						} `json:"spec"`
+					} `json:"template"`
+				} `json:"spec"`
+			}
+			if err := json.Unmarshal(raw, &patch); err != nil {
+				t.Errorf("patch: %v", err)
+			}
+			for _, c := range patch.Spec.Template.Spec.Containers {
+				f.images[c.Name] = c.Image
+			}
+			for k, v := range patch.Metadata.Annotations {
+				f.annotations[k] = v
+			}
+			f.generation++
+			f.reads = 0
+		case http.MethodGet:
+			f.reads++
+			if !f.stuck && f.reads >= f.rolloutReads {
+				f.observed = f.generation
+			}
+		}
+		w.Write(f.deploymentJSON())
+	})
+	mux.HandleFunc("/api/v1/namespaces/payram/pods", func(w http.ResponseWriter, r *http.Request) {
+		if sel := r.URL.Query().Get("labelSelector"); sel != "app=web" {
+			t.Errorf("labelSelector = %q", sel)
+		}
+		w.Write([]byte(`{"items":[
+			{"metadata":{"name":"web-old","creationTimestamp":"2026-01-01T00:00:00Z"},"status":{"containerStatuses":[{"name":"chat","restartCount":1}]}},
+			{"metadata":{"name":"web-new","creationTimestamp":"2026-01-02T00:00:00Z"},"status":{"containerStatuses":[
+				{"name":"chat","restartCount":2,"state":{"running":{"startedAt":"2026-01-02T00:00:05Z"}},"lastState":{"terminated":{"exitCode":137,"reason":"OOMKilled","finishedAt":"2026-01-02T00:00:04Z"}}},
+				{"name":"mcp","restartCount":0}]}}]}`))
+	})
+	mux.HandleFunc("/api/v1/namespaces/payram/pods/web-new/log", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("container") != "mcp" || r.URL.Query().Get("tailLines") != "2" {
+			t.Errorf("log query = %s", r.URL.RawQuery)
+		}
+		w.Write([]byte("one\ntwo\n"))
+	})
+	return mux
+}
+
+func (f *fakeCluster) deploymentJSON() []byte {
+	var containers []map[string]string
+	for _, name := range []string{"chat", "mcp"} {
+		containers = append(containers, map[string]string{"name": name, "image": f.images[name]})
+	}
+	updated := int32(0)
+	if f.observed == f.generation {
+		updated = 2
+	}
+	raw, _ := json.Marshal(map[string]any{
+		"metadata": map[string]any{"name": "web", "generation": f.generation, "annotations": f.annotations},
+		"spec": map[string]any{
+			"replicas": 2,
+			"selector": map[string]any{"matchLabels": map[string]string{"app": "web"}},
+			"template": map[string]any{"spec": map[string]any{"containers": containers}},
+		},
+		"status": map[string]any{"observedGeneration": f.observed, "replicas": 2, "updatedReplicas": updated, "availableReplicas": 2},
+	})
+	return raw
+}
+
+func installImages(t *testing.T, version, chat, mcp string) {
+	t.Helper()
+	dir := update.ReleaseDir(version)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(update.Manifest{Version: version, Artifacts: update.Artifacts{
+		Chat: update.Artifact{Image: chat}, MCP: update.Artifact{Image: mcp},
+	}})
+	if err := update.SaveReleaseManifest(dir, raw, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := update.UpdateSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestSupervisor(t *testing.T, f *fakeCluster) *Supervisor {
+	t.Helper()
+	srv := httptest.NewServer(f.handler(t))
+	t.Cleanup(srv.Close)
+	s := New(&Client{BaseURL: srv.URL, Token: "tok"}, "payram", Target{"web", "chat"}, Target{"web", "mcp"})
+	s.pollInterval = time.Millisecond
+	return s
+}
+
+func TestRestartAllAndWaitRollsOutReleaseImages(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	f := &fakeCluster{images: map[string]string{"chat": "reg/chat:1", "mcp": "reg/mcp:1"}, annotations: map[string]string{}, generation: 1, observed: 1, rolloutReads: 3}
+	s := newTestSupervisor(t, f)
+
+	installImages(t, "2.0.0", "reg/chat@sha256:c2", "reg/mcp:1")
+	results := s.RestartAllAndWait(time.Second)
+	if len(results) != 2 || results[0].Name != "mcp" || results[1].Name != "chat" {
+		t.Fatalf("expected mcp then chat, got %+v", results)
+	}
+	for _, r := range results {
+		if !r.Ready {
+			t.Fatalf("%s not ready: %s", r.Name, r.Error)
+		}
+	}
+	// One patch for the shared Deployment, changing only the chat image.
+	if len(f.patches) != 1 || !strings.Contains(f.patches[0], `"containers":[{"image":"reg/chat@sha256:c2","name":"chat"}]`) {
+		t.Fatalf("unexpected patches: %v", f.patches)
+	}
+	if f.images["chat"] != "reg/chat@sha256:c2" || f.annotations[VersionAnnotation] != "2.0.0" {
+		t.Fatalf("deployment not updated: %v %v", f.images, f.annotations)
+	}
+
+	// Unchanged images restart the pods instead.
+	s.RestartAllAndWait(time.Second)
+	if len(f.patches) != 2 || !strings.Contains(f.patches[1], restartedAtAnnotation) || strings.Contains(f.patches[1], "containers") {
+		t.Fatalf("expected a restart patch, got %v", f.patches)
+	}
+
+	if st := s.Status(); st.Components[0].Restarts != 3 || st.Components[0].LastExit == nil || st.Components[0].LastExit.ExitCode != 137 {
+		t.Fatalf("unexpected chat status: %+v", st.Components[0])
+	}
+	if logs := s.Logs("mcp", 2); len(logs) != 2 || logs[1] != "[mcp][web-new] two" {
+		t.Fatalf("unexpected logs: %v", logs)
+	}
+}
+
+func TestRestartAllAndWaitReportsStuckRollout(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	f := &fakeCluster{images: map[string]string{"chat": "reg/chat:1", "mcp": "reg/mcp:1"}, annotations: map[string]string{}, generation: 1, observed: 1, stuck: true}
+	s := newTestSupervisor(t, f)
+	s.rolloutTimeout = 50 * time.Millisecond
+
+	installImages(t, "2.0.0", "reg/chat:2", "reg/mcp:2")
+	for _, r := range s.RestartAllAndWait(0) {
+		if r.Ready || !strings.Contains(r.Error, "not rolled out after 50ms") {
+			t.Fatalf("expected a stuck rollout, got %+v", r)
+		}
+	}
+
+	installImages(t, "3.0.0", "reg/chat:3", "")
+	if r := s.RestartAllAndWait(0); r[0].Ready || !strings.Contains(r[0].Error, "names no mcp image") {
+		t.Fatalf("expected missing image error, got %+v", r)
+	}
+}
+
+func TestClientReportsAPIErrors(t *testing.T) {
+	f := &fakeCluster{images: map[string]string{}, annotations: map[string]string{}}
+	srv := httptest.NewServer(f.handler(t))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, Token: "wrong"}
+	err := c.do(t.Context(), http.MethodGet, "/apis/apps/v1/namespaces/payram/deployments/web", "", nil, nil)
+	if apiErr, ok := err.(*APIError); !ok || apiErr.Status != http.StatusUnauthorized || apiErr.Message != "Unauthorized" {
+		t.Fatalf("expected 401 APIError, got %v", err)
+	}
+	if _, err := ParseTarget("/chat", "chat"); err == nil {
+		t.Fatal("expected invalid target error")
+	}
+	if tgt, _ := ParseTarget("web", "mcp"); tgt != (Target{"web", "mcp"}) {
+		t.Fatalf("unexpected default container: %+v", tgt)
+	}
+}
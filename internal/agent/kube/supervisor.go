@@ -0,0 +1,332 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/lifecycle"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/supervisor"
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// VersionAnnotation records on each Deployment the release whose images it runs.
+const VersionAnnotation = "payram.io/release-version"
+
+// restartedAtAnnotation is the pod template annotation kubectl rollout restart sets.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// Target is the Deployment container that runs one component.
+type Target struct {
+	Deployment string
+	Container  string
+}
+
+// ParseTarget reads "deployment[/container]"; the container defaults to container.
+func ParseTarget(raw, container string) (Target, error) {
+	deploy, name, found := strings.Cut(strings.TrimSpace(raw), "/")
+	if !found {
+		name = container
+	}
+	if deploy == "" || name == "" {
+		return Target{}, fmt.Errorf("kubernetes target %q: want deployment[/container]", raw)
+	}
+	return Target{Deployment: deploy, Container: name}, nil
+}
+
+// Supervisor stands in for the process supervisor when chat and MCP run in a cluster: it
+// serves the same admin operations against Deployments. A restart rolls each Deployment out
+// with the images of the current release (see RestartAllAndWait), so the admin update,
+// rollback and canary flows work unchanged; the current/previous links only record which
+// release the cluster should run.
+type Supervisor struct {
+	client    *Client
+	namespace string
+	chat      Target
+	mcp       Target
+
+	rolloutTimeout time.Duration
+	pollInterval   time.Duration
+}
+
+// New builds a Supervisor for the chat and mcp targets in namespace.
+func New(client *Client, namespace string, chat, mcp Target) *Supervisor {
+	return &Supervisor{
+		client:         client,
+		namespace:      namespace,
+		chat:           chat,
+		mcp:            mcp,
+		rolloutTimeout: 5 * time.Minute,
+		pollInterval:   2 * time.Second,
+	}
+}
+
+// NewFromEnv reads PAYRAM_AGENT_KUBE_CHAT and PAYRAM_AGENT_KUBE_MCP (deployment[/container],
+// default payram-analytics/chat and payram-analytics/mcp), PAYRAM_AGENT_KUBE_NAMESPACE and
+// PAYRAM_AGENT_KUBE_ROLLOUT_TIMEOUT_MS (default 5m).
+func NewFromEnv() (*Supervisor, error) {
+	client, err := ClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	chat, err := ParseTarget(getenvDefault("PAYRAM_AGENT_KUBE_CHAT", "payram-analytics/chat"), "chat")
+	if err != nil {
+		return nil, err
+	}
+	mcp, err := ParseTarget(getenvDefault("PAYRAM_AGENT_KUBE_MCP", "payram-analytics/mcp"), "mcp")
+	if err != nil {
+		return nil, err
+	}
+	s := New(client, DefaultNamespace(), chat, mcp)
+	if v := os.Getenv("PAYRAM_AGENT_KUBE_ROLLOUT_TIMEOUT_MS"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms <= 0 {
+			return nil, fmt.Errorf("PAYRAM_AGENT_KUBE_ROLLOUT_TIMEOUT_MS: want a positive number of milliseconds, got %q", v)
+		}
+		s.rolloutTimeout = time.Duration(ms) * time.Millisecond
+	}
+	return s, nil
+}
+
+// Wait returns at once: the cluster, not the agent, owns the children.
+func (s *Supervisor) Wait() {}
+
+func (s *Supervisor) target(name string) Target {
+	if name == "mcp" {
+		return s.mcp
+	}
+	return s.chat
+}
+
+// RestartAllAndWait points each Deployment at the images the current release names and
+// waits for the rollout to finish, MCP's Deployment first. A Deployment that already runs
+// them is restarted the way kubectl rollout restart does. Each rollout is bounded by the
+// larger of timeout and the configured rollout timeout; a component counts as ready once
+// every replica runs the new pod template and is available.
+func (s *Supervisor) RestartAllAndWait(timeout time.Duration) []supervisor.RestartResult {
+	if timeout < s.rolloutTimeout {
+		timeout = s.rolloutTimeout
+	}
+	version, images, imageErr := currentImages()
+
+	var results []supervisor.RestartResult
+	done := map[string]bool{}
+	for _, name := range []string{"mcp", "chat"} {
+		deploy := s.target(name).Deployment
+		if done[deploy] {
+			continue
+		}
+		done[deploy] = true
+		var names []string
+		containers := map[string]string{}
+		for _, n := range []string{"mcp", "chat"} {
+			if t := s.target(n); t.Deployment == deploy {
+				names = append(names, n)
+				containers[t.Container] = images[n]
+			}
+		}
+
+		requested := time.Now()
+		err := imageErr
+		if err == nil {
+			err = s.rollout(deploy, version, containers, timeout)
+		}
+		for _, n := range names {
+			res := supervisor.RestartResult{Name: n, Ready: err == nil, ElapsedMS: time.Since(requested).Milliseconds()}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			lifecycle.Publish(lifecycle.ChildRestarted, n, map[string]any{
+				"deployment": deploy, "ready": res.Ready, "elapsed_ms": res.ElapsedMS, "error": res.Error,
+			})
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// currentImages returns the version and per-component images of the current release.
+func currentImages() (string, map[string]string, error) {
+	m, _, _, err := update.LoadReleaseManifest(update.CurrentSymlink())
+	if err != nil {
+		return "", nil, fmt.Errorf("current release manifest: %w", err)
+	}
+	images := map[string]string{"chat": m.Artifacts.Chat.Image, "mcp": m.Artifacts.MCP.Image}
+	for name, image := range images {
+		if image == "" {
+			return "", nil, fmt.Errorf("release %s names no %s image", m.Version, name)
+		}
+	}
+	return m.Version, images, nil
+}
+
+func (s *Supervisor) deploymentPath(name string) string {
+	return "/apis/apps/v1/namespaces/" + url.PathEscape(s.namespace) + "/deployments/" + url.PathEscape(name)
+}
+
+// rollout patches the Deployment's container images (container name to image) and waits
+// until the rollout completes.
+func (s *Supervisor) rollout(name, version string, images map[string]string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var dep deployment
+	if err := s.client.do(ctx, "GET", s.deploymentPath(name), "", nil, &dep); err != nil {
+		return err
+	}
+	running := map[string]string{}
+	for _, c := range dep.Spec.Template.Spec.Containers {
+		running[c.Name] = c.Image
+	}
+	template := map[string]any{}
+	var changed []map[string]string
+	for container, image := range images {
+		current, ok := running[container]
+		if !ok {
+			return fmt.Errorf("deployment %s has no container %q", name, container)
+		}
+		if current != image {
+			changed = append(changed, map[string]string{"name": container, "image": image})
+		}
+	}
+	if len(changed) > 0 {
+		sort.Slice(changed, func(i, j int) bool { return changed[i]["name"] < changed[j]["name"] })
+		template["spec"] = map[string]any{"containers": changed}
+	} else {
+		template["metadata"] = map[string]any{"annotations": map[string]string{restartedAtAnnotation: time.Now().UTC().Format(time.RFC3339)}}
+	}
+	patch := map[string]any{
+		"metadata": map[string]any{"annotations": map[string]string{VersionAnnotation: version}},
+		"spec":     map[string]any{"template": template},
+	}
+	if err := s.client.do(ctx, "PATCH", s.deploymentPath(name), "application/strategic-merge-patch+json", patch, &dep); err != nil {
+		return err
+	}
+
+	generation := dep.Metadata.Generation
+	for {
+		complete, detail := dep.rolledOut(generation)
+		if complete {
+			return nil
+		}
+		if dep.progressDeadlineExceeded() {
+			return fmt.Errorf("deployment %s exceeded its progress deadline: %s", name, detail)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("deployment %s not rolled out after %s: %s", name, timeout, detail)
+		case <-time.After(s.pollInterval):
+		}
+		if err := s.client.do(ctx, "GET", s.deploymentPath(name), "", nil, &dep); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("deployment %s not rolled out after %s: %s", name, timeout, detail)
+			}
+			return err
+		}
+	}
+}
+
+// Status reports each component's pods: Restarts sums the container's restarts across
+// them, StartTime and LastExit come from the most recently started one. PID is always 0.
+func (s *Supervisor) Status() supervisor.Status {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var st supervisor.Status
+	for _, name := range []string{"chat", "mcp"} {
+		comp := supervisor.ComponentStatus{Name: name}
+		t := s.target(name)
+		pods, err := s.pods(ctx, t.Deployment)
+		if err != nil {
+			comp.LastExit = &supervisor.ExitInfo{Time: time.Now(), Error: err.Error()}
+		}
+		for _, p := range pods {
+			for _, cs := range p.Status.ContainerStatuses {
+				if cs.Name != t.Container {
+					continue
+				}
+				comp.Restarts += cs.RestartCount
+				if r := cs.State.Running; r != nil && r.StartedAt.After(comp.StartTime) {
+					comp.StartTime = r.StartedAt
+				}
+				if term := cs.LastState.Terminated; term != nil && (comp.LastExit == nil || term.FinishedAt.After(comp.LastExit.Time)) {
+					comp.LastExit = &supervisor.ExitInfo{Time: term.FinishedAt, ExitCode: term.ExitCode, Error: term.Reason}
+				}
+			}
+		}
+		st.Components = append(st.Components, comp)
+	}
+	return st
+}
+
+// Logs returns the last tail lines of the component's container in its newest pod.
+func (s *Supervisor) Logs(component string, tail int) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t := s.target(component)
+	pods, err := s.pods(ctx, t.Deployment)
+	if err != nil {
+		return []string{fmt.Sprintf("[%s] logs unavailable: %v", component, err)}
+	}
+	if len(pods) == 0 {
+		return []string{fmt.Sprintf("[%s] no pods for deployment %s", component, t.Deployment)}
+	}
+	newest := pods[0]
+	for _, p := range pods[1:] {
+		if p.Metadata.CreationTimestamp.After(newest.Metadata.CreationTimestamp) {
+			newest = p
+		}
+	}
+
+	q := url.Values{"container": {t.Container}}
+	if tail > 0 {
+		q.Set("tailLines", strconv.Itoa(tail))
+	}
+	var raw []byte
+	path := "/api/v1/namespaces/" + url.PathEscape(s.namespace) + "/pods/" + url.PathEscape(newest.Metadata.Name) + "/log?" + q.Encode()
+	if err := s.client.do(ctx, "GET", path, "", nil, &raw); err != nil {
+		return []string{fmt.Sprintf("[%s] logs unavailable: %v", component, err)}
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(raw), "\n"), "\n") {
+		if line != "" {
+			lines = append(lines, fmt.Sprintf("[%s][%s] %s", component, newest.Metadata.Name, line))
+		}
+	}
+	return lines
+}
+
+// pods lists the pods selected by the Deployment.
+func (s *Supervisor) pods(ctx context.Context, name string) ([]pod, error) {
+	var dep deployment
+	if err := s.client.do(ctx, "GET", s.deploymentPath(name), "", nil, &dep); err != nil {
+		return nil, err
+	}
+	var selector []string
+	for k, v := range dep.Spec.Selector.MatchLabels {
+		selector = append(selector, k+"="+v)
+	}
+	sort.Strings(selector)
+	var list struct {
+		Items []pod `json:"items"`
+	}
+	path := "/api/v1/namespaces/" + url.PathEscape(s.namespace) + "/pods?labelSelector=" + url.QueryEscape(strings.Join(selector, ","))
+	if err := s.client.do(ctx, "GET", path, "", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func getenvDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,89 @@
+package kube
+
+import (
+	"fmt"
+	"time"
+)
+
+// deployment holds the apps/v1 Deployment fields the agent reads.
+type deployment struct {
+	Metadata struct {
+		Name       string `json:"name"`
+		Generation int64  `json:"generation"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int32 `json:"replicas"`
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+		Template struct {
+			Spec struct {
+				Containers []struct {
+					Name  string `json:"name"`
+					Image string `json:"image"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		ObservedGeneration  int64 `json:"observedGeneration"`
+		Replicas            int32 `json:"replicas"`
+		UpdatedReplicas     int32 `json:"updatedReplicas"`
+		AvailableReplicas   int32 `json:"availableReplicas"`
+		UnavailableReplicas int32 `json:"unavailableReplicas"`
+		Conditions          []struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// rolledOut reports whether the controller has rolled out generation to every replica, as
+// kubectl rollout status does, with a summary of the progress.
+func (d deployment) rolledOut(generation int64) (bool, string) {
+	want := int32(1)
+	if d.Spec.Replicas != nil {
+		want = *d.Spec.Replicas
+	}
+	st := d.Status
+	detail := fmt.Sprintf("%d/%d updated, %d available, %d old", st.UpdatedReplicas, want, st.AvailableReplicas, st.Replicas-st.UpdatedReplicas)
+	if st.ObservedGeneration < generation {
+		return false, "waiting for the controller to observe the update"
+	}
+	return st.UpdatedReplicas == want && st.Replicas == want && st.AvailableReplicas == want, detail
+}
+
+func (d deployment) progressDeadlineExceeded() bool {
+	for _, c := range d.Status.Conditions {
+		if c.Type == "Progressing" && c.Reason == "ProgressDeadlineExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+// pod holds the v1 Pod fields the agent reads.
+type pod struct {
+	Metadata struct {
+		Name              string    `json:"name"`
+		CreationTimestamp time.Time `json:"creationTimestamp"`
+	} `json:"metadata"`
+	Status struct {
+		ContainerStatuses []struct {
+			Name         string `json:"name"`
+			RestartCount int    `json:"restartCount"`
+			State        struct {
+				Running *struct {
+					StartedAt time.Time `json:"startedAt"`
+				} `json:"running"`
+			} `json:"state"`
+			LastState struct {
+				Terminated *struct {
+					ExitCode   int       `json:"exitCode"`
+					Reason     string    `json:"reason"`
+					FinishedAt time.Time `json:"finishedAt"`
+				} `json:"terminated"`
+			} `json:"lastState"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
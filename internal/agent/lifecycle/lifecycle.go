@@ -0,0 +1,123 @@
+// Package lifecycle is the agent's event bus: child processes starting, exiting and being
+// restarted, update stages, and update, rollback and canary outcomes. The admin API streams it
+// over SSE and an optional webhook forwards it, so automation need not scrape logs.
+package lifecycle
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	ChildStarted   = "child.started"   // a child process started; data: pid
+	ChildExited    = "child.exited"    // a child process exited; data: pid, exit_code, error, crash
+	ChildRestarted = "child.restarted" // a requested restart finished; data: pid, ready, elapsed_ms, error
+	UpdateStage    = "update.stage"    // an apply or rollback entered a stage; data: action, stage
+	UpdateApply    = "update.apply"    // an apply finished; data: result, from_version, to_version, ...
+	UpdateRollback = "update.rollback" // a rollback finished; automatic ones carry automatic and reason
+	UpdateCanary   = "update.canary"   // a canary soak ended in a promotion or rollback
+)
+
+// DefaultKeep is how many events Default keeps for replay.
+const DefaultKeep = 256
+
+// subscriberBuffer is how many events a subscriber may fall behind before it is dropped.
+const subscriberBuffer = 64
+
+// Event is one lifecycle event. Seq increases by one per event, so a subscriber can tell what
+// it missed.
+type Event struct {
+	Seq       int64          `json:"seq"`
+	Time      time.Time      `json:"time"`
+	Type      string         `json:"type"`
+	Component string         `json:"component,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// Bus fans events out to subscribers and keeps the most recent ones for replay.
+type Bus struct {
+	mu     sync.Mutex
+	keep   int
+	seq    int64
+	recent []Event
+	subs   map[chan Event]struct{}
+}
+
+// NewBus returns a bus keeping the last keep events.
+func NewBus(keep int) *Bus {
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	return &Bus{keep: keep, subs: map[chan Event]struct{}{}}
+}
+
+// Default is the bus the supervisor and the admin API publish to.
+var Default = NewBus(DefaultKeep)
+
+// Publish sends an event of type typ to Default.
+func Publish(typ, component string, data map[string]any) {
+	Default.Publish(Event{Type: typ, Component: component, Data: data})
+}
+
+// Publish stamps e with the next sequence number and the current time, keeps it, and sends it
+// to every subscriber. A subscriber whose buffer is full is dropped (its channel is closed)
+// rather than allowed to hold up the publisher; it can resubscribe from the last Seq it saw.
+func (b *Bus) Publish(e Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.Seq = b.seq
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.recent = append(b.recent, e)
+	if len(b.recent) > b.keep {
+		b.recent = b.recent[len(b.recent)-b.keep:]
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return e
+}
+
+// Subscribe returns the kept events after seq, then a channel of events published from now
+// on. cancel ends the subscription; the channel is also closed if the subscriber falls behind.
+func (b *Bus) Subscribe(after int64) (backlog []Event, events <-chan Event, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, e := range b.recent {
+		if e.Seq > after {
+			backlog = append(backlog, e)
+		}
+	}
+	ch := make(chan Event, subscriberBuffer)
+	b.subs[ch] = struct{}{}
+	return backlog, ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Recent returns up to limit kept events, oldest first; limit <= 0 returns all of them.
+func (b *Bus) Recent(limit int) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := b.recent
+	if limit > 0 && len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	return append([]Event(nil), out...)
+}
@@ -0,0 +1,104 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBusReplaysAndFansOut(t *testing.T) {
+	b := NewBus(3)
+	for _, typ := range []string{"a", "b", "c", "d"} {
+		b.Publish(Event{Type: typ})
+	}
+	if got := b.Recent(0); len(got) != 3 || got[0].Type != "b" || got[2].Seq != 4 {
+		t.Fatalf("Recent kept %+v, want b..d", got)
+	}
+
+	backlog, events, cancel := b.Subscribe(3)
+	defer cancel()
+	if len(backlog) != 1 || backlog[0].Type != "d" {
+		t.Fatalf("backlog after 3 = %+v, want [d]", backlog)
+	}
+	b.Publish(Event{Type: "e", Component: "mcp"})
+	select {
+	case e := <-events:
+		if e.Type != "e" || e.Seq != 5 || e.Component != "mcp" || e.Time.IsZero() {
+			t.Fatalf("event = %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event delivered")
+	}
+}
+
+func TestBusDropsSlowSubscriber(t *testing.T) {
+	b := NewBus(0)
+	_, events, cancel := b.Subscribe(0)
+	defer cancel()
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(Event{Type: "x"})
+	}
+	n := 0
+	for range events {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Fatalf("received %d events before the drop, want %d", n, subscriberBuffer)
+	}
+}
+
+func TestWebhookSignsAndRetries(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		got      []Event
+	)
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != Sign("s3cret", body) {
+			t.Errorf("bad signature %q", r.Header.Get(SignatureHeader))
+		}
+		var e Event
+		_ = json.Unmarshal(body, &e)
+		if r.Header.Get(EventHeader) != e.Type {
+			t.Errorf("event header %q for %q", r.Header.Get(EventHeader), e.Type)
+		}
+		got = append(got, e)
+		if len(got) == 2 {
+			close(done)
+		}
+	}))
+	defer srv.Close()
+
+	b := NewBus(0)
+	b.Publish(Event{Type: ChildStarted, Component: "chat"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wh := &Webhook{URL: srv.URL, Secret: "s3cret", Retries: 2, Backoff: time.Millisecond}
+	go wh.Run(ctx, b)
+	b.Publish(Event{Type: UpdateApply})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook did not deliver both events")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got[0].Type != ChildStarted || got[1].Type != UpdateApply || attempts != 3 {
+		t.Fatalf("delivered %+v in %d attempts", got, attempts)
+	}
+}
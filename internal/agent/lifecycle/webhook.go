@@ -0,0 +1,132 @@
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Webhook headers. The signature is "sha256=" and the hex HMAC-SHA256 of the body under the
+// webhook secret.
+const (
+	EventHeader     = "X-Payram-Agent-Event"
+	SignatureHeader = "X-Payram-Agent-Signature"
+)
+
+// Webhook posts each event of a bus as JSON to URL, retrying failed deliveries.
+type Webhook struct {
+	URL    string
+	Secret string
+	// Retries is how often a failed delivery is retried, with doubling delays from Backoff.
+	Retries int
+	Backoff time.Duration
+	Client  *http.Client
+}
+
+// WebhookFromEnv configures a webhook from PAYRAM_AGENT_EVENTS_WEBHOOK_URL and
+// PAYRAM_AGENT_EVENTS_WEBHOOK_SECRET; ok is false when no URL is set.
+func WebhookFromEnv() (w *Webhook, ok bool) {
+	url := os.Getenv("PAYRAM_AGENT_EVENTS_WEBHOOK_URL")
+	if url == "" {
+		return nil, false
+	}
+	return &Webhook{
+		URL:     url,
+		Secret:  os.Getenv("PAYRAM_AGENT_EVENTS_WEBHOOK_SECRET"),
+		Retries: 3,
+		Backoff: time.Second,
+		Client:  &http.Client{Timeout: 5 * time.Second},
+	}, true
+}
+
+// Run delivers b's kept events and then each new one, in order, until ctx is done. An event
+// that still fails after the retries is logged and skipped.
+func (w *Webhook) Run(ctx context.Context, b *Bus) {
+	var last int64
+	for ctx.Err() == nil {
+		backlog, events, cancel := b.Subscribe(last)
+		deliver := func(e Event) {
+			if err := w.deliver(ctx, e); err != nil && ctx.Err() == nil {
+				log.Printf("lifecycle webhook: dropped event %d (%s): %v", e.Seq, e.Type, err)
+			}
+			last = e.Seq
+		}
+		for _, e := range backlog {
+			deliver(e)
+		}
+	stream:
+		for {
+			select {
+			case <-ctx.Done():
+				break stream
+			case e, open := <-events:
+				if !open {
+					// Fell behind; resubscribe and catch up from the kept events.
+					break stream
+				}
+				deliver(e)
+			}
+		}
+		cancel()
+	}
+}
+
+func (w *Webhook) deliver(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	delay := w.Backoff
+	for attempt := 0; ; attempt++ {
+		err = w.post(ctx, e.Type, body)
+		if err == nil || attempt >= w.Retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (w *Webhook) post(ctx context.Context, typ string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, typ)
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the SignatureHeader value for body under secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
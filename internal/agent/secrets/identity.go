@@ -0,0 +1,74 @@
+package secrets
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// IdentityKey returns the agent's ed25519 identity key, creating it on first use. It is kept
+// as a base64 seed in state/identity.key with 0600 permissions and signs what the agent
+// reports about itself, such as fleet heartbeats.
+func IdentityKey(home string) (ed25519.PrivateKey, error) {
+	if home == "" {
+		home = update.HomeDir()
+	}
+	path := identityPath(home)
+	raw, err := os.ReadFile(path)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("identity key %s is corrupt", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	// O_EXCL: when two processes race, the loser reads the winner's key.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return IdentityKey(home)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(seed) + "\n"); err != nil {
+		f.Close()
+		_ = os.Remove(path)
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// AgentID is a short stable name for the agent holding pub: the first 16 hex digits of
+// its SHA-256.
+func AgentID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+func identityPath(home string) string {
+	return filepath.Join(home, "state", "identity.key")
+}
@@ -1,6 +1,7 @@
 package secrets
 
 import (
+	"crypto/ed25519"
 	"os"
 	"path/filepath"
 	"testing"
@@ -76,3 +77,34 @@ func TestEnvBeatsState(t *testing.T) {
 		t.Fatalf("env key not returned")
 	}
 }
+
+func TestIdentityKeyIsCreatedOnceAndKept(t *testing.T) {
+	home := t.TempDir()
+
+	key, err := IdentityKey(home)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(home, "state", "identity.key"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected a 0600 key file: %v, %v", info, err)
+	}
+
+	again, err := IdentityKey(home)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !key.Equal(again) {
+		t.Fatal("expected the stored key to be reused")
+	}
+	if id := AgentID(key.Public().(ed25519.PublicKey)); len(id) != 16 {
+		t.Fatalf("unexpected agent id %q", id)
+	}
+
+	if err := os.WriteFile(filepath.Join(home, "state", "identity.key"), []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := IdentityKey(home); err == nil {
+		t.Fatal("expected a corrupt key to be reported, not replaced")
+	}
+}
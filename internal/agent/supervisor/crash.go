@@ -0,0 +1,177 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCrashLines = 100
+	defaultCrashKeep  = 50
+)
+
+// CrashReport is what the supervisor keeps of a child that exited unexpectedly: a snapshot
+// of its last stderr lines, so a crash can be looked into after the log buffer rolled over.
+type CrashReport struct {
+	ID        string    `json:"id"`
+	Component string    `json:"component"`
+	Time      time.Time `json:"time"`
+	PID       int       `json:"pid"`
+	StartTime time.Time `json:"startTime"`
+	ExitCode  int       `json:"exitCode,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Restarts  int       `json:"restarts"`
+	Stderr    []string  `json:"stderr"`
+}
+
+// ErrCrashNotFound is returned by LoadCrash for an unknown report ID.
+var ErrCrashNotFound = errors.New("crash report not found")
+
+// crashSuffix ends every report file name; reports are named <id>.json.
+const crashSuffix = ".json"
+
+// writeCrash stores report in dir and removes the oldest reports beyond keep.
+func writeCrash(dir string, report CrashReport, keep int) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, report.ID+crashSuffix)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return pruneCrashes(dir, keep)
+}
+
+// pruneCrashes keeps the newest keep reports in dir.
+func pruneCrashes(dir string, keep int) error {
+	ids, err := crashIDs(dir)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids[min(keep, len(ids)):] {
+		if err := os.Remove(filepath.Join(dir, id+crashSuffix)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// crashIDs lists the report IDs in dir, newest first. IDs start with a sortable timestamp.
+func crashIDs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if name := e.Name(); e.Type().IsRegular() && strings.HasSuffix(name, crashSuffix) {
+			ids = append(ids, strings.TrimSuffix(name, crashSuffix))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+// ListCrashes returns up to limit reports from dir, newest first, optionally only those of
+// one component. Unreadable reports are skipped.
+func ListCrashes(dir, component string, limit int) ([]CrashReport, error) {
+	ids, err := crashIDs(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := []CrashReport{}
+	for _, id := range ids {
+		if len(out) >= limit {
+			break
+		}
+		report, err := LoadCrash(dir, id)
+		if err != nil || (component != "" && report.Component != component) {
+			continue
+		}
+		out = append(out, report)
+	}
+	return out, nil
+}
+
+// LoadCrash reads one report from dir.
+func LoadCrash(dir, id string) (CrashReport, error) {
+	if id == "" || filepath.Base(id) != id || strings.HasPrefix(id, ".") {
+		return CrashReport{}, ErrCrashNotFound
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, id+crashSuffix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return CrashReport{}, ErrCrashNotFound
+		}
+		return CrashReport{}, err
+	}
+	var report CrashReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return CrashReport{}, fmt.Errorf("crash report %s: %w", id, err)
+	}
+	return report, nil
+}
+
+// captureCrash stores a report for a process that exited on its own with an error. The
+// output readers get a moment to drain so the last lines the process wrote are included.
+func (c *child) captureCrash(proc *process, exitErr error, pid int, startedAt time.Time) {
+	if c.crashDir == "" || exitErr == nil {
+		return
+	}
+	waitTimeout(&proc.output, 500*time.Millisecond)
+
+	st := c.status()
+	now := time.Now().UTC()
+	report := CrashReport{
+		ID:        fmt.Sprintf("%s-%s-%d", now.Format("20060102T150405.000000000Z"), c.name, pid),
+		Component: c.name,
+		Time:      now,
+		PID:       pid,
+		StartTime: startedAt,
+		Restarts:  st.Restarts,
+		Stderr:    proc.stderr.Tail(c.crashLines),
+	}
+	if st.LastExit != nil {
+		report.ExitCode, report.Error = st.LastExit.ExitCode, st.LastExit.Error
+	}
+	if report.Stderr == nil {
+		report.Stderr = []string{}
+	}
+	if err := writeCrash(c.crashDir, report, c.crashKeep); err != nil {
+		c.logBuf.Add(fmt.Sprintf("[%s] crash report failed: %v", c.name, err))
+		return
+	}
+	c.logBuf.Add(fmt.Sprintf("[%s] crash report %s saved", c.name, report.ID))
+}
+
+// waitTimeout waits for wg, giving up after d.
+func waitTimeout(wg *sync.WaitGroup, d time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,103 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCrashReportCapturesStderr(t *testing.T) {
+	dir := t.TempDir()
+	cfg := lifecycleConfig("sleep 5")
+	cfg.ChatArgs = []string{"-c", "echo out; for i in 1 2 3 4; do echo err$i >&2; done; exit 3"}
+	cfg.CrashDir = dir
+	cfg.CrashLines = 3
+	cfg.CrashKeep = 2
+	sup := New(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	// The chat crashes every backoff; wait for retention to kick in.
+	deadline := time.Now().Add(3 * time.Second)
+	for sup.chat.status().Restarts < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("chat did not crash repeatedly: %+v", sup.chat.status())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err := sup.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	reports, err := ListCrashes(dir, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports kept, got %d", len(reports))
+	}
+	r := reports[0]
+	if r.Component != "chat" || r.ExitCode != 3 || r.PID == 0 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if !slices.Equal(r.Stderr, []string{"err2", "err3", "err4"}) {
+		t.Fatalf("stderr = %v", r.Stderr)
+	}
+	if !reports[0].Time.After(reports[1].Time) {
+		t.Fatal("expected newest report first")
+	}
+
+	got, err := LoadCrash(dir, r.ID)
+	if err != nil || got.ID != r.ID {
+		t.Fatalf("load %s: %+v, %v", r.ID, got, err)
+	}
+	if mcp, _ := ListCrashes(dir, "mcp", 10); len(mcp) != 0 {
+		t.Fatalf("mcp never crashed, got %d reports", len(mcp))
+	}
+}
+
+func TestStopDoesNotCaptureCrash(t *testing.T) {
+	dir := t.TempDir()
+	cfg := lifecycleConfig("sleep 5")
+	cfg.CrashDir = dir
+	sup := New(cfg)
+
+	if err := sup.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := sup.chat.waitReady(time.Time{}, 2*time.Second); err != nil {
+		t.Fatalf("chat not started: %v", err)
+	}
+	if err := sup.RestartAll(); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if err := sup.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected no crash reports, got %d", len(entries))
+	}
+}
+
+func TestLoadCrashRejectsPaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"", "../secret", "missing", ".hidden"} {
+		if _, err := LoadCrash(dir, id); !errors.Is(err, ErrCrashNotFound) {
+			t.Errorf("%q: expected ErrCrashNotFound, got %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// DriverDocker is the PAYRAM_AGENT_CHILD_DRIVER value that runs children as containers.
+const DriverDocker = "docker"
+
+// dockerPullTimeout bounds one image pull.
+const dockerPullTimeout = 10 * time.Minute
+
+// dockerHostVars are agent variables that describe the host and would break a container.
+var dockerHostVars = map[string]bool{
+	"PATH": true, "HOME": true, "HOSTNAME": true, "PWD": true, "OLDPWD": true, "SHELL": true,
+	"SHLVL": true, "USER": true, "LOGNAME": true, "TERM": true, "TMPDIR": true, "_": true,
+}
+
+// DockerConfig makes the supervisor run each child as a container of the image recorded in
+// the current release's manifest (ChatImage/MCPImage are used when it records none, e.g.
+// before the first update). Bin is the docker CLI; Network is passed to docker run (default
+// host, so the health probes and clients reach the children on their usual ports; on any
+// other network the port is published). RunArgs are extra docker run arguments.
+type DockerConfig struct {
+	Bin       string
+	Network   string
+	RunArgs   []string
+	ChatImage string
+	MCPImage  string
+}
+
+// DockerMode reports whether PAYRAM_AGENT_CHILD_DRIVER selects the docker driver.
+func DockerMode() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("PAYRAM_AGENT_CHILD_DRIVER")), DriverDocker)
+}
+
+func dockerFromEnv() *DockerConfig {
+	return &DockerConfig{
+		Bin:       getenvDefault("PAYRAM_AGENT_DOCKER_BIN", "docker"),
+		Network:   getenvDefault("PAYRAM_AGENT_DOCKER_NETWORK", "host"),
+		RunArgs:   strings.Fields(os.Getenv("PAYRAM_AGENT_DOCKER_RUN_ARGS")),
+		ChatImage: os.Getenv("PAYRAM_AGENT_CHAT_IMAGE"),
+		MCPImage:  os.Getenv("PAYRAM_AGENT_MCP_IMAGE"),
+	}
+}
+
+// PullImage fetches ref with the configured docker CLI unless it is already present.
+func PullImage(ctx context.Context, ref string) error {
+	return dockerFromEnv().pull(ctx, ref)
+}
+
+func (d *DockerConfig) pull(ctx context.Context, ref string) error {
+	if exec.CommandContext(ctx, d.Bin, "image", "inspect", ref).Run() == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, dockerPullTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, d.Bin, "pull", ref).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("docker pull %s: %v: %s", ref, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// image returns the image for the child from the current release, else from the config.
+func (c *child) image() (string, error) {
+	if m, _, _, err := update.LoadReleaseManifest(update.CurrentSymlink()); err == nil {
+		art := m.Artifacts.Chat
+		if c.name == "mcp" {
+			art = m.Artifacts.MCP
+		}
+		if art.Image != "" {
+			return art.Image, nil
+		}
+	}
+	ref := c.docker.ChatImage
+	if c.name == "mcp" {
+		ref = c.docker.MCPImage
+	}
+	if ref == "" {
+		return "", fmt.Errorf("%s: no image in the current release and PAYRAM_AGENT_%s_IMAGE unset", c.name, strings.ToUpper(c.name))
+	}
+	return ref, nil
+}
+
+// dockerCommand prepares a container for the child and returns the docker run command for
+// it, with the environment to run it in. The child's variables are passed by name (-e NAME),
+// so their values stay out of the process list; the docker CLI additionally gets the agent's
+// own PATH, HOME and DOCKER_* variables. docker run stays attached, so its output is the
+// child's output and SIGTERM is forwarded to the container.
+func (c *child) dockerCommand(env []string) (string, []string, []string, error) {
+	ref, err := c.image()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if err := c.docker.pull(context.Background(), ref); err != nil {
+		return "", nil, nil, err
+	}
+
+	// A container left behind by a killed docker run would hold the name.
+	name := "payram-" + c.name
+	_ = exec.Command(c.docker.Bin, "rm", "-f", name).Run()
+
+	args := []string{"run", "--rm", "--name", name, "--network", c.docker.Network}
+	if c.docker.Network != "host" {
+		port := envValue(env, "PAYRAM_CHAT_PORT", "2358")
+		if c.name == "mcp" {
+			port = envValue(env, "PAYRAM_MCP_PORT", "3333")
+		}
+		args = append(args, "-p", port+":"+port)
+	}
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		if key != "" && !dockerHostVars[key] {
+			args = append(args, "-e", key)
+		}
+	}
+	args = append(args, c.docker.RunArgs...)
+	args = append(args, ref)
+	args = append(args, c.args...)
+
+	cliEnv := append([]string(nil), env...)
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if (key == "PATH" || key == "HOME" || strings.HasPrefix(key, "DOCKER_")) && !hasEnv(cliEnv, key) {
+			cliEnv = append(cliEnv, kv)
+		}
+	}
+
+	c.logBuf.Add(fmt.Sprintf("[%s] running image %s", c.name, ref))
+	return c.docker.Bin, args, cliEnv, nil
+}
@@ -0,0 +1,110 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+// fakeDocker writes a docker CLI stand-in that records its arguments in the returned log,
+// has no local images, and for run prints the container's port and stays up.
+func fakeDocker(t *testing.T) (bin, log string) {
+	t.Helper()
+	dir := t.TempDir()
+	bin, log = filepath.Join(dir, "docker"), filepath.Join(dir, "calls.log")
+	script := "#!/bin/sh\necho \"$*\" >> " + log + "\n" +
+		"case \"$1\" in\nimage) exit 1 ;;\nrun) echo \"up chat=$PAYRAM_CHAT_PORT mcp=$PAYRAM_MCP_PORT\"; exec sleep 5 ;;\nesac\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return bin, log
+}
+
+func TestDockerDriverRunsReleaseImages(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_CHAT_PORT", "2400")
+	t.Setenv("CHILD_SECRET", "s3cret")
+	bin, log := fakeDocker(t)
+
+	// The current release names an mcp image; chat falls back to the configured one.
+	dir := update.ReleaseDir("2.0.0")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(update.Manifest{Version: "2.0.0", Artifacts: update.Artifacts{MCP: update.Artifact{Image: "img/mcp@sha256:ff"}}})
+	if err := update.SaveReleaseManifest(dir, raw, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := update.UpdateSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	sup := New(Config{
+		ChatArgs:         []string{"--verbose"},
+		BufferLines:      50,
+		TerminateTimeout: time.Second,
+		Docker: &DockerConfig{
+			Bin:       bin,
+			Network:   "bridge",
+			RunArgs:   []string{"--memory", "256m"},
+			ChatImage: "img/chat:1",
+			MCPImage:  "img/mcp:1",
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, name := range []string{"chat", "mcp"} {
+		for !strings.Contains(strings.Join(sup.Logs(name, 50), "\n"), "up chat=2400") {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s container did not start: %v", name, sup.Logs(name, 50))
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := string(data)
+	for _, want := range []string{"pull img/chat:1", "pull img/mcp@sha256:ff", "rm -f payram-chat", "rm -f payram-mcp"} {
+		if !strings.Contains(calls, want+"\n") {
+			t.Fatalf("missing %q in docker calls:\n%s", want, calls)
+		}
+	}
+	var chatRun string
+	for _, line := range strings.Split(calls, "\n") {
+		if strings.HasPrefix(line, "run ") && strings.Contains(line, "--name payram-chat") {
+			chatRun = line
+		}
+	}
+	if !strings.HasPrefix(chatRun, "run --rm --name payram-chat --network bridge -p 2400:2400 ") ||
+		!strings.HasSuffix(chatRun, " --memory 256m img/chat:1 --verbose") {
+		t.Fatalf("unexpected chat run: %q", chatRun)
+	}
+	if !strings.Contains(chatRun, " -e CHILD_SECRET ") || strings.Contains(chatRun, "s3cret") || strings.Contains(chatRun, " -e PATH ") {
+		t.Fatalf("variables should be passed by name, without host ones: %q", chatRun)
+	}
+}
+
+func TestDockerDriverNeedsAnImage(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	c := newChild("chat", "", nil, Config{BufferLines: 10, Docker: &DockerConfig{Bin: "docker"}})
+	if _, _, _, err := c.dockerCommand(nil); err == nil || !strings.Contains(err.Error(), "PAYRAM_AGENT_CHAT_IMAGE") {
+		t.Fatalf("expected missing image error, got %v", err)
+	}
+}
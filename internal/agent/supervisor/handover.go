@@ -0,0 +1,182 @@
+package supervisor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/handover"
+)
+
+// process is one running instance of a child binary.
+type process struct {
+	cmd  *exec.Cmd
+	done chan error
+	// ready is closed once the process reports it is accepting connections (handover mode only).
+	ready chan struct{}
+	// stderr keeps the last stderr lines for a crash report; output is done once both
+	// streams are drained.
+	stderr *ringBuffer
+	output sync.WaitGroup
+}
+
+// listen binds the child's listening socket once so it can be passed to every process
+// started for the child. The socket stays open across restarts.
+func (c *child) listen() error {
+	if c.listenAddr == "" {
+		return fmt.Errorf("%s: handover requires a listen address", c.name)
+	}
+	ln, err := net.Listen("tcp", c.listenAddr)
+	if err != nil {
+		return fmt.Errorf("%s: listen %s: %w", c.name, c.listenAddr, err)
+	}
+	tcp, ok := ln.(*net.TCPListener)
+	if !ok {
+		_ = ln.Close()
+		return fmt.Errorf("%s: unexpected listener type %T", c.name, ln)
+	}
+	f, err := tcp.File()
+	addr := ln.Addr().String()
+	_ = ln.Close() // File duplicated the descriptor; the socket stays open through f.
+	if err != nil {
+		return fmt.Errorf("%s: listener file: %w", c.name, err)
+	}
+
+	c.listener = f
+	c.listenAddr = addr
+	c.logBuf.Add(fmt.Sprintf("[%s] holding listener %s for handover", c.name, addr))
+	return nil
+}
+
+// startProcess launches the child binary, or its container with the docker driver. In
+// handover mode the shared listener is passed as fd 3 and a readiness pipe as fd 4 (see
+// internal/handover).
+func (c *child) startProcess() (*process, error) {
+	env := c.childEnv()
+	path, args := c.path, c.args
+	if c.docker != nil {
+		var err error
+		if path, args, env, err = c.dockerCommand(env); err != nil {
+			return nil, err
+		}
+	}
+	cmd := exec.CommandContext(context.Background(), path, args...)
+	cmd.Env = env
+	// The output pipes are made here rather than with StdoutPipe/StderrPipe: Wait closes those
+	// as soon as the child exits, which could drop the last lines it wrote before they were read.
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	stderr, stderrW, err := os.Pipe()
+	if err != nil {
+		_ = stdout.Close()
+		_ = stdoutW.Close()
+		return nil, fmt.Errorf("stderr pipe: %w", err)
+	}
+	cmd.Stdout, cmd.Stderr = stdoutW, stderrW
+
+	p := &process{
+		cmd:    cmd,
+		done:   make(chan error, 1),
+		ready:  make(chan struct{}),
+		stderr: newRingBuffer(c.crashLines, c.logBuf.lineBytes, 0),
+	}
+
+	var readyW *os.File
+	if c.listener != nil {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, fmt.Errorf("ready pipe: %w", err)
+		}
+		readyW = w
+		cmd.ExtraFiles = []*os.File{c.listener, w}
+		cmd.Env = append(cmd.Env, handover.ListenFDEnv+"=3", handover.ReadyFDEnv+"=4")
+		go watchReady(r, p.ready)
+	}
+
+	err = cmd.Start()
+	// Drop our copies of the write ends so the readers see EOF once the child exits.
+	_ = stdoutW.Close()
+	_ = stderrW.Close()
+	if readyW != nil {
+		// Drop our copy of the write end so the reader sees EOF if the child exits.
+		_ = readyW.Close()
+	}
+	if err != nil {
+		_ = stdout.Close()
+		_ = stderr.Close()
+		return nil, err
+	}
+
+	go func() { p.done <- cmd.Wait() }()
+	p.output.Add(2)
+	go func() {
+		defer p.output.Done()
+		defer stdout.Close()
+		c.pipeOutput(stdout, "stdout", nil)
+	}()
+	go func() {
+		defer p.output.Done()
+		defer stderr.Close()
+		c.pipeOutput(stderr, "stderr", p.stderr)
+	}()
+	return p, nil
+}
+
+func watchReady(r *os.File, ready chan struct{}) {
+	defer r.Close()
+	line, _ := bufio.NewReader(r).ReadString('\n')
+	if strings.TrimSpace(line) == "READY" {
+		close(ready)
+	}
+}
+
+// handoverTo starts a replacement process on the shared listener and, once it reports
+// ready, stops the old process. Both serve the same socket in between, so clients never
+// see a gap. If the replacement does not become ready it is killed, the old process keeps
+// serving, and false is returned so the caller can fall back to a plain restart.
+func (c *child) handoverTo(ctx context.Context, old *process) (*process, bool) {
+	next, err := c.startProcess()
+	if err != nil {
+		c.logBuf.Add(fmt.Sprintf("[%s] handover start failed: %v", c.name, err))
+		return nil, false
+	}
+	c.logBuf.Add(fmt.Sprintf("[%s] handover started pid=%d", c.name, next.cmd.Process.Pid))
+
+	timer := time.NewTimer(c.readyTimeout)
+	defer timer.Stop()
+
+	var failure error
+	exited := false
+	select {
+	case <-next.ready:
+	case err := <-next.done:
+		exited = true
+		failure = fmt.Errorf("exited before ready: %v", err)
+	case <-timer.C:
+		failure = fmt.Errorf("not ready after %s", c.readyTimeout)
+	case <-ctx.Done():
+		failure = errors.New("agent shutting down")
+	}
+	if failure != nil {
+		if !exited {
+			_ = next.cmd.Process.Kill()
+			<-next.done
+		}
+		c.logBuf.Add(fmt.Sprintf("[%s] handover aborted: %v", c.name, failure))
+		return nil, false
+	}
+
+	startedAt := time.Now()
+	c.recordExit(c.signalAndWait(old.cmd, old.done), true, false)
+	c.recordStart(next.cmd.Process.Pid, startedAt)
+	return next, true
+}
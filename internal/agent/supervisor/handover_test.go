@@ -0,0 +1,134 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/handover"
+)
+
+// TestHandoverHelperProcess is not a real test; it is the child binary used by
+// TestRestartAllHandsOverListener.
+func TestHandoverHelperProcess(t *testing.T) {
+	if os.Getenv("PAYRAM_TEST_HANDOVER_HELPER") != "1" {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, os.Getpid())
+	})}
+	if err := handover.ListenAndServe(ctx, srv, time.Second); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestRestartAllHandsOverListener(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_TEST_HANDOVER_HELPER", "1")
+
+	helperArgs := []string{"-test.run=^TestHandoverHelperProcess$"}
+	sup := New(Config{
+		ChatPath:         os.Args[0],
+		ChatArgs:         helperArgs,
+		MCPPath:          os.Args[0],
+		MCPArgs:          helperArgs,
+		BufferLines:      50,
+		InitialBackoff:   20 * time.Millisecond,
+		MaxBackoff:       50 * time.Millisecond,
+		TerminateTimeout: 2 * time.Second,
+		HealthTimeout:    5 * time.Second,
+		Handover:         true,
+		ChatListenAddr:   "127.0.0.1:0",
+		MCPListenAddr:    "127.0.0.1:0",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	url := "http://" + sup.chat.listenAddr + "/"
+	client := &http.Client{Timeout: 2 * time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+	first := waitForPID(t, client, url)
+
+	var failures atomic.Int32
+	stopLoad := make(chan struct{})
+	loadDone := make(chan struct{})
+	go func() {
+		defer close(loadDone)
+		for {
+			select {
+			case <-stopLoad:
+				return
+			default:
+			}
+			if _, err := fetchPID(client, url); err != nil {
+				failures.Add(1)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	if err := sup.RestartAll(); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	close(stopLoad)
+	<-loadDone
+
+	if n := failures.Load(); n != 0 {
+		t.Fatalf("expected no failed requests during handover, got %d\nlogs:\n%s", n, strings.Join(sup.chat.logs(50), "\n"))
+	}
+
+	second, err := fetchPID(client, url)
+	if err != nil {
+		t.Fatalf("fetch after restart: %v", err)
+	}
+	if second == first {
+		t.Fatalf("expected a new process after handover, still pid %s", first)
+	}
+	if st := sup.chat.status(); fmt.Sprint(st.PID) != second || st.Restarts != 1 {
+		t.Fatalf("unexpected status after handover: %+v (serving pid %s)", st, second)
+	}
+}
+
+func waitForPID(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pid, err := fetchPID(client, url)
+		if err == nil {
+			return pid
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("child never served: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func fetchPID(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package supervisor
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// handoverSupported reports whether listeners can be passed to children (PAYRAM_AGENT_HANDOVER).
+const handoverSupported = true
+
+// terminate asks the process to shut down gracefully.
+func terminate(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
+
+// shellCommand wraps a hook command line for execution via /bin/sh.
+func shellCommand(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	return []string{"/bin/sh", "-c", raw}
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package supervisor
+
+import (
+	"os"
+	"strings"
+)
+
+// handoverSupported is false: Windows cannot pass a listening socket as an inherited file.
+const handoverSupported = false
+
+// terminate stops the process. Windows has no SIGTERM to deliver to a console-less child, so
+// this kills it right away; a pre-stop hook is the way to drain it first.
+func terminate(p *os.Process) error {
+	return p.Kill()
+}
+
+// shellCommand wraps a hook command line for execution via cmd.exe.
+func shellCommand(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	return []string{"cmd", "/C", raw}
+}
@@ -1,32 +1,79 @@
 package supervisor
 
-import "sync"
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
 
+const (
+	defaultBufferLines     = 200
+	defaultBufferLineBytes = 8 << 10
+	defaultBufferBytes     = 512 << 10
+)
+
+// ringBuffer keeps the most recent log lines, bounded by line count and by bytes: each line
+// is cut at lineBytes, and the oldest lines are dropped to keep the total under maxBytes.
 type ringBuffer struct {
-	mu    sync.Mutex
-	lines []string
-	next  int
-	count int
+	mu        sync.Mutex
+	lines     []string
+	next      int
+	count     int
+	bytes     int
+	lineBytes int
+	maxBytes  int
+	// dropped counts lines evicted early to stay under maxBytes.
+	dropped int
 }
 
-func newRingBuffer(size int) *ringBuffer {
+func newRingBuffer(size, lineBytes, maxBytes int) *ringBuffer {
 	if size <= 0 {
-		size = 200
+		size = defaultBufferLines
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultBufferBytes
 	}
-	return &ringBuffer{lines: make([]string, size)}
+	if lineBytes <= 0 {
+		lineBytes = defaultBufferLineBytes
+	}
+	lineBytes = min(lineBytes, maxBytes)
+	return &ringBuffer{lines: make([]string, size), lineBytes: lineBytes, maxBytes: maxBytes}
 }
 
 func (r *ringBuffer) Add(line string) {
+	r.addCut(line, 0)
+}
+
+// addCut adds a line whose last cut bytes were already discarded by the reader.
+func (r *ringBuffer) addCut(line string, cut int) {
+	line = truncateLine(line, r.lineBytes, cut)
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if r.count == len(r.lines) {
+		r.evict()
+	}
+	for r.count > 0 && r.bytes+len(line) > r.maxBytes {
+		r.evict()
+		r.dropped++
+	}
 	r.lines[r.next] = line
+	r.bytes += len(line)
 	r.next = (r.next + 1) % len(r.lines)
-	if r.count < len(r.lines) {
-		r.count++
-	}
+	r.count++
+}
+
+// evict drops the oldest line.
+func (r *ringBuffer) evict() {
+	oldest := (r.next - r.count + len(r.lines)) % len(r.lines)
+	r.bytes -= len(r.lines[oldest])
+	r.lines[oldest] = ""
+	r.count--
 }
 
+// Tail returns the last n lines, oldest first. When fewer are kept because of the byte
+// limit, a marker line saying so comes first.
 func (r *ringBuffer) Tail(n int) []string {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -35,15 +82,31 @@ func (r *ringBuffer) Tail(n int) []string {
 		return nil
 	}
 
+	var out []string
 	if n > r.count {
 		n = r.count
+		if r.dropped > 0 {
+			out = append(out, fmt.Sprintf("[... %d older lines dropped to keep the log buffer under %d bytes ...]", r.dropped, r.maxBytes))
+		}
 	}
 
 	start := (r.next - n + len(r.lines)) % len(r.lines)
-	out := make([]string, n)
 	for i := 0; i < n; i++ {
-		out[i] = r.lines[(start+i)%len(r.lines)]
+		out = append(out, r.lines[(start+i)%len(r.lines)])
 	}
 
 	return out
 }
+
+// truncateLine cuts line to at most max bytes, on a rune boundary, with a marker saying how
+// many bytes were cut. extra counts bytes of the line already discarded before it got here.
+func truncateLine(line string, max, extra int) string {
+	if len(line) <= max && extra == 0 {
+		return line
+	}
+	keep := min(len(line), max)
+	for keep > 0 && keep < len(line) && !utf8.RuneStart(line[keep]) {
+		keep--
+	}
+	return fmt.Sprintf("%s [... truncated %d bytes]", line[:keep], len(line)-keep+extra)
+}
@@ -1,9 +1,13 @@
 package supervisor
 
-import "testing"
+import (
+	"io"
+	"strings"
+	"testing"
+)
 
 func TestRingBufferTail(t *testing.T) {
-	buf := newRingBuffer(3)
+	buf := newRingBuffer(3, 0, 0)
 
 	buf.Add("a")
 	buf.Add("b")
@@ -29,3 +33,54 @@ func TestRingBufferTail(t *testing.T) {
 		t.Fatalf("expected nil for zero tail, got %v", empty)
 	}
 }
+
+func TestRingBufferTruncatesLongLines(t *testing.T) {
+	buf := newRingBuffer(10, 8, 1024)
+	buf.Add("short")
+	buf.Add("0123456789abcdef")
+	buf.Add("ééééé") // 10 bytes; the cut must not split a rune
+
+	got := buf.Tail(3)
+	want := []string{"short", "01234567 [... truncated 8 bytes]", "éééé [... truncated 2 bytes]"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("line %d: want %q got %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestRingBufferByteLimitDropsOldest(t *testing.T) {
+	buf := newRingBuffer(100, 10, 25)
+	for _, l := range []string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc"} {
+		buf.Add(l)
+	}
+	if buf.bytes > 25 {
+		t.Fatalf("buffer holds %d bytes, limit 25", buf.bytes)
+	}
+	got := buf.Tail(10)
+	if len(got) != 3 || !strings.Contains(got[0], "1 older lines dropped") || got[1] != "bbbbbbbbbb" || got[2] != "cccccccccc" {
+		t.Fatalf("unexpected tail: %q", got)
+	}
+	if got := buf.Tail(1); len(got) != 1 || got[0] != "cccccccccc" {
+		t.Fatalf("a tail within what is kept should have no marker: %q", got)
+	}
+}
+
+func TestPipeOutputBoundsHugeLines(t *testing.T) {
+	c := &child{name: "chat", logBuf: newRingBuffer(10, 64, 1024)}
+	huge := strings.Repeat("x", 100000)
+	r, w := io.Pipe()
+	go func() {
+		io.WriteString(w, "before\r\n"+huge+"\nafter")
+		w.Close()
+	}()
+	c.pipeOutput(r, "stdout", nil)
+
+	got := c.logBuf.Tail(10)
+	if len(got) != 3 || got[0] != "[chat][stdout] before" || got[2] != "[chat][stdout] after" {
+		t.Fatalf("unexpected lines: %q", got)
+	}
+	if !strings.HasSuffix(got[1], "[... truncated 99951 bytes]") || len(got[1]) > 100 {
+		t.Fatalf("huge line not cut: %q", got[1])
+	}
+}
@@ -2,34 +2,104 @@ package supervisor
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
-	"syscall"
+	"text/template"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/lifecycle"
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/secrets"
 	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+	"github.com/payram/payram-analytics-mcp-server/internal/logging"
 )
 
 // Config controls supervisor behavior.
-// BufferLines defines how many log lines to keep per child.
+// BufferLines defines how many log lines to keep per child, and BufferBytes how many bytes
+// they may take in all (default 512 KiB); lines longer than BufferLineBytes (default 8 KiB)
+// are cut with a truncation marker.
 // InitialBackoff defines the first delay after a crash; MaxBackoff caps it.
-// TerminateTimeout defines how long to wait after SIGTERM before SIGKILL.
+// TerminateTimeout defines how long to wait after SIGTERM before SIGKILL (on Windows, where
+// children are killed outright, it bounds the wait for the exit).
+// RestartOrder lists the components RestartAll restarts one after another (default mcp, chat).
+// ChatHealthURL/MCPHealthURL are probed after a child restarts, bounded by HealthTimeout,
+// before the next component in RestartOrder is restarted.
+// ChatDependsOn/MCPDependsOn name the components a child waits for before each start: until
+// every one has a process that passes its health probe (or, without a health URL, is
+// running), the child is held and reported as waiting.
+// ChatPreStop/MCPPreStop are optional commands run before a child is signalled to stop.
+// ChatEnv/MCPEnv restrict and extend the environment each child receives.
+// Handover makes the supervisor own the listening sockets (ChatListenAddr/MCPListenAddr)
+// and pass them to each child, so a restart starts the replacement first and only stops
+// the old process once the new one reports ready (bounded by HealthTimeout).
+// Forward, when set, also receives every stdout/stderr line (e.g. for syslog/journald).
+// CrashDir, when set, receives a CrashReport with the last CrashLines stderr lines (default
+// 100) whenever a child exits with an error the supervisor did not cause; only the newest
+// CrashKeep reports (default 50) are kept.
+// Docker, when set, runs the children as containers instead of ChatPath/MCPPath; the
+// ChatArgs/MCPArgs are then passed to the image.
 type Config struct {
 	ChatPath         string
 	ChatArgs         []string
 	MCPPath          string
 	MCPArgs          []string
 	BufferLines      int
+	BufferLineBytes  int
+	BufferBytes      int
 	InitialBackoff   time.Duration
 	MaxBackoff       time.Duration
 	TerminateTimeout time.Duration
+	RestartOrder     []string
+	ChatHealthURL    string
+	MCPHealthURL     string
+	HealthTimeout    time.Duration
+	ChatDependsOn    []string
+	MCPDependsOn     []string
+	ChatPreStop      []string
+	MCPPreStop       []string
+	ChatEnv          ChildEnv
+	MCPEnv           ChildEnv
+	Handover         bool
+	ChatListenAddr   string
+	MCPListenAddr    string
+	Forward          LineSink
+	CrashDir         string
+	CrashLines       int
+	CrashKeep        int
+	Docker           *DockerConfig
+}
+
+// ChildEnv controls the environment passed to one child.
+// Allow lists variable names inherited from the agent; a trailing "*" matches a prefix.
+// An empty Allow inherits the whole agent environment.
+// Set adds or overrides variables; values are text/template strings rendered with
+// EnvTemplateData, e.g. "http://127.0.0.1:{{.MCPPort}}/".
+type ChildEnv struct {
+	Allow []string
+	Set   map[string]string
+}
+
+// EnvTemplateData is the data available to ChildEnv.Set templates.
+type EnvTemplateData struct {
+	Name       string
+	Port       string
+	ChatPort   string
+	MCPPort    string
+	Home       string
+	BinPath    string
+	ReleaseDir string
+	Version    string
 }
 
 // ExitInfo describes the last exit of a child process.
@@ -46,6 +116,8 @@ type ComponentStatus struct {
 	StartTime time.Time `json:"startTime"`
 	Restarts  int       `json:"restarts"`
 	LastExit  *ExitInfo `json:"lastExit,omitempty"`
+	// WaitingFor lists the dependencies a child is held for before it may start.
+	WaitingFor []string `json:"waitingFor,omitempty"`
 }
 
 // Status aggregates child statuses.
@@ -58,32 +130,81 @@ type Supervisor struct {
 	chat *child
 	mcp  *child
 
+	restartOrder  []*child
+	healthTimeout time.Duration
+	handover      bool
+
+	// mu guards the fields above against Reconfigure, and the current run: runCtx is
+	// cancelled by Stop, and done is closed once that run's children have exited.
+	mu     sync.Mutex
+	runCtx context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
 	wg sync.WaitGroup
 }
 
+// ErrRunning is returned by Reconfigure while the children are supervised.
+var ErrRunning = errors.New("supervisor is running")
+
 // NewFromEnv builds a Supervisor using environment overrides for binaries.
 func NewFromEnv() (*Supervisor, error) {
 	chatPath := getenvDefault("PAYRAM_AGENT_CHAT_BIN", update.DefaultChatBin())
 	mcpPath := getenvDefault("PAYRAM_AGENT_MCP_BIN", update.DefaultMCPBin())
 
-	if chatPath == update.DefaultChatBin() {
+	var docker *DockerConfig
+	if DockerMode() {
+		docker = dockerFromEnv()
+	} else if chatPath == update.DefaultChatBin() {
 		if _, err := os.Stat(chatPath); err != nil {
 			return nil, fmt.Errorf("chat binary not found at %s", chatPath)
 		}
 	}
-	if mcpPath == update.DefaultMCPBin() {
+	if docker == nil && mcpPath == update.DefaultMCPBin() {
 		if _, err := os.Stat(mcpPath); err != nil {
 			return nil, fmt.Errorf("mcp binary not found at %s", mcpPath)
 		}
 	}
 
+	healthPath := childHealthPath()
+	chatPort := getenvDefault("PAYRAM_CHAT_PORT", "2358")
+	mcpPort := getenvDefault("PAYRAM_MCP_PORT", "3333")
+	forward, err := syslogFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	handover := envBool("PAYRAM_AGENT_HANDOVER")
+	if handover && !handoverSupported {
+		return nil, fmt.Errorf("PAYRAM_AGENT_HANDOVER is not supported on this platform")
+	}
+	if handover && docker != nil {
+		return nil, fmt.Errorf("PAYRAM_AGENT_HANDOVER is not supported with the docker child driver")
+	}
 	cfg := Config{
 		ChatPath:         chatPath,
 		MCPPath:          mcpPath,
-		BufferLines:      200,
+		BufferLines:      defaultBufferLines,
 		InitialBackoff:   time.Second,
 		MaxBackoff:       30 * time.Second,
 		TerminateTimeout: 5 * time.Second,
+		RestartOrder:     splitList(os.Getenv("PAYRAM_AGENT_RESTART_ORDER")),
+		ChatHealthURL:    fmt.Sprintf("http://127.0.0.1:%s%s", chatPort, healthPath),
+		MCPHealthURL:     fmt.Sprintf("http://127.0.0.1:%s%s", mcpPort, healthPath),
+		HealthTimeout:    envDurationMS("PAYRAM_AGENT_HEALTH_TIMEOUT_MS", 20*time.Second),
+		ChatDependsOn:    dependsOnFromEnv("PAYRAM_AGENT_CHAT_DEPENDS_ON", []string{"mcp"}),
+		MCPDependsOn:     dependsOnFromEnv("PAYRAM_AGENT_MCP_DEPENDS_ON", nil),
+		ChatPreStop:      shellCommand(os.Getenv("PAYRAM_AGENT_CHAT_PRESTOP")),
+		MCPPreStop:       shellCommand(os.Getenv("PAYRAM_AGENT_MCP_PRESTOP")),
+		ChatEnv:          childEnvFromEnv("CHAT"),
+		MCPEnv:           childEnvFromEnv("MCP"),
+		Handover:         handover,
+		ChatListenAddr:   ":" + chatPort,
+		MCPListenAddr:    ":" + mcpPort,
+		Forward:          forward,
+		CrashDir:         update.CrashDir(),
+		CrashLines:       envInt("PAYRAM_AGENT_CRASH_LINES", defaultCrashLines),
+		CrashKeep:        envInt("PAYRAM_AGENT_CRASH_KEEP", defaultCrashKeep),
+		Docker:           docker,
 	}
 	return New(cfg), nil
 }
@@ -97,7 +218,7 @@ func New(cfg Config) *Supervisor {
 		cfg.MCPPath = update.DefaultMCPBin()
 	}
 	if cfg.BufferLines <= 0 {
-		cfg.BufferLines = 200
+		cfg.BufferLines = defaultBufferLines
 	}
 	if cfg.InitialBackoff <= 0 {
 		cfg.InitialBackoff = time.Second
@@ -108,50 +229,318 @@ func New(cfg Config) *Supervisor {
 	if cfg.TerminateTimeout <= 0 {
 		cfg.TerminateTimeout = 5 * time.Second
 	}
+	if cfg.HealthTimeout <= 0 {
+		cfg.HealthTimeout = 20 * time.Second
+	}
+	if cfg.CrashLines <= 0 {
+		cfg.CrashLines = defaultCrashLines
+	}
+	if cfg.CrashKeep <= 0 {
+		cfg.CrashKeep = defaultCrashKeep
+	}
+
+	chat := newChild("chat", cfg.ChatPath, cfg.ChatArgs, cfg)
+	chat.preStop = cfg.ChatPreStop
+	chat.healthURL = cfg.ChatHealthURL
+	chat.envCfg = cfg.ChatEnv
+	chat.listenAddr = cfg.ChatListenAddr
+
+	mcp := newChild("mcp", cfg.MCPPath, cfg.MCPArgs, cfg)
+	mcp.preStop = cfg.MCPPreStop
+	mcp.healthURL = cfg.MCPHealthURL
+	mcp.envCfg = cfg.MCPEnv
+	mcp.listenAddr = cfg.MCPListenAddr
+
+	s := &Supervisor{
+		chat:          chat,
+		mcp:           mcp,
+		healthTimeout: cfg.HealthTimeout,
+		handover:      cfg.Handover,
+	}
+	s.restartOrder = s.resolveOrder(cfg.RestartOrder)
+	chat.dependsOn = s.resolveDeps(chat, cfg.ChatDependsOn)
+	mcp.dependsOn = s.resolveDeps(mcp, cfg.MCPDependsOn)
+	return s
+}
+
+// resolveDeps maps dependency names to children, ignoring unknown names and c itself.
+func (s *Supervisor) resolveDeps(c *child, names []string) []*child {
+	var out []*child
+	for _, name := range names {
+		dep := s.child(strings.TrimSpace(name))
+		if dep == nil || dep == c || slices.Contains(out, dep) {
+			continue
+		}
+		out = append(out, dep)
+	}
+	return out
+}
+
+// dependencyCycle returns an error naming a child that transitively depends on itself.
+func dependencyCycle(children []*child) error {
+	var visit func(c *child, path []string) error
+	visit = func(c *child, path []string) error {
+		if slices.Contains(path, c.name) {
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, c.name), " -> "))
+		}
+		for _, dep := range c.dependsOn {
+			if err := visit(dep, append(path, c.name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, c := range children {
+		if err := visit(c, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveOrder maps component names to children, defaulting to mcp before chat
+// because chat depends on the MCP server. Unknown names are ignored and missing
+// components are appended so every child is always restarted.
+func (s *Supervisor) resolveOrder(names []string) []*child {
+	if len(names) == 0 {
+		names = []string{"mcp", "chat"}
+	}
 
-	return &Supervisor{
-		chat: newChild("chat", cfg.ChatPath, cfg.ChatArgs, cfg),
-		mcp:  newChild("mcp", cfg.MCPPath, cfg.MCPArgs, cfg),
+	out := make([]*child, 0, 2)
+	seen := map[string]bool{}
+	for _, name := range append(slices.Clone(names), "mcp", "chat") {
+		c := s.child(strings.TrimSpace(name))
+		if c == nil || seen[c.name] {
+			continue
+		}
+		seen[c.name] = true
+		out = append(out, c)
+	}
+	return out
+}
+
+func (s *Supervisor) child(name string) *child {
+	switch name {
+	case "chat":
+		return s.chat
+	case "mcp":
+		return s.mcp
+	default:
+		return nil
 	}
 }
 
-// Start launches child supervision loops. Safe to call once.
+// Start launches child supervision loops, which run until ctx is cancelled or Stop is
+// called. Calling Start while they run is a no-op; after they stop, Start launches them
+// again.
 func (s *Supervisor) Start(ctx context.Context) error {
 	if ctx == nil {
 		return errors.New("context is nil")
 	}
 
-	s.wg.Add(2)
-	go s.chat.run(ctx, &s.wg)
-	go s.mcp.run(ctx, &s.wg)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.done != nil {
+		if s.runCtx.Err() == nil {
+			return nil
+		}
+		// Still stopping: let the previous run release its listeners first.
+		done := s.done
+		s.mu.Unlock()
+		<-done
+		s.mu.Lock()
+	}
+
+	children := []*child{s.chat, s.mcp}
+	if err := dependencyCycle(children); err != nil {
+		return err
+	}
+	if s.handover {
+		for i, c := range children {
+			if err := c.listen(); err != nil {
+				closeListeners(children[:i])
+				return err
+			}
+		}
+	}
+	for _, c := range children {
+		// Drop a restart requested while stopped.
+		select {
+		case <-c.restartCh:
+		default:
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	s.runCtx, s.cancel, s.done = runCtx, cancel, done
+
+	var run sync.WaitGroup
+	run.Add(len(children))
+	for _, c := range children {
+		go c.run(runCtx, &run)
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		run.Wait()
+		cancel()
+		closeListeners(children)
+
+		s.mu.Lock()
+		s.runCtx, s.cancel, s.done = nil, nil, nil
+		s.mu.Unlock()
+		close(done)
+	}()
 	return nil
 }
 
-// RestartAll forces both children to restart.
-func (s *Supervisor) RestartAll() error {
-	s.chat.triggerRestart()
-	s.mcp.triggerRestart()
+// Stop ends supervision, stopping each child as on shutdown, and waits for the children to
+// exit or ctx to be done. It is a no-op when the children are not supervised.
+func (s *Supervisor) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Running reports whether the children are supervised.
+func (s *Supervisor) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done != nil && s.runCtx.Err() == nil
+}
+
+// Reconfigure replaces the configuration, as New(cfg) would build it, for the next Start.
+// The children must be stopped first. Log buffers and restart counts start afresh.
+func (s *Supervisor) Reconfigure(cfg Config) error {
+	next := New(cfg)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		return ErrRunning
+	}
+	s.chat, s.mcp = next.chat, next.mcp
+	s.restartOrder = next.restartOrder
+	s.healthTimeout = next.healthTimeout
+	s.handover = next.handover
 	return nil
 }
 
+func closeListeners(children []*child) {
+	for _, c := range children {
+		if c.listener != nil {
+			_ = c.listener.Close()
+			c.listener = nil
+		}
+	}
+}
+
+// RestartResult reports how one child came back from RestartAllAndWait. Ready means it
+// started a new process (PID) and, when a health URL is configured, answered one health
+// probe within the timeout; otherwise Error says which step it did not get past. StartMS is
+// how long the new process took to start, and ElapsedMS includes the health probes.
+type RestartResult struct {
+	Name      string `json:"name"`
+	PID       int    `json:"pid"`
+	Ready     bool   `json:"ready"`
+	StartMS   int64  `json:"startMs"`
+	ElapsedMS int64  `json:"elapsedMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RestartAll restarts every child as RestartAllAndWait does, bounded per child by
+// HealthTimeout, and returns RestartError of the results.
+func (s *Supervisor) RestartAll() error {
+	return RestartError(s.RestartAllAndWait(0))
+}
+
+// RestartAllAndWait restarts children one at a time in the configured order. It waits for
+// each to register a new PID and pass its health probe, up to timeout per child, before
+// moving to the next. A child that is not ready in time is noted in its log buffer and the
+// remaining children are still restarted, so the results always cover every child. While
+// the supervisor is stopped, no child is restarted and each result says so.
+func (s *Supervisor) RestartAllAndWait(timeout time.Duration) []RestartResult {
+	s.mu.Lock()
+	order, running := s.restartOrder, s.done != nil && s.runCtx.Err() == nil
+	if timeout <= 0 {
+		timeout = s.healthTimeout
+	}
+	s.mu.Unlock()
+
+	results := make([]RestartResult, 0, len(order))
+	for _, c := range order {
+		if !running {
+			results = append(results, RestartResult{Name: c.name, Error: "supervisor is stopped"})
+			continue
+		}
+		requested := time.Now()
+		c.triggerRestart()
+		err := c.waitReady(requested, timeout)
+		st := c.status()
+		res := RestartResult{
+			Name:      c.name,
+			PID:       st.PID,
+			Ready:     err == nil,
+			ElapsedMS: time.Since(requested).Milliseconds(),
+		}
+		if st.PID != 0 && st.StartTime.After(requested) {
+			res.StartMS = st.StartTime.Sub(requested).Milliseconds()
+		}
+		if err != nil {
+			res.Error = err.Error()
+			c.logBuf.Add(fmt.Sprintf("[%s] not ready after restart: %v", c.name, err))
+		}
+		lifecycle.Publish(lifecycle.ChildRestarted, c.name, map[string]any{
+			"pid": res.PID, "ready": res.Ready, "elapsed_ms": res.ElapsedMS, "error": res.Error,
+		})
+		results = append(results, res)
+	}
+	return results
+}
+
+// RestartError joins one error per child in results that did not become ready, each
+// naming the child, or returns nil if all did.
+func RestartError(results []RestartResult) error {
+	var failed []error
+	for _, r := range results {
+		if !r.Ready {
+			failed = append(failed, fmt.Errorf("%s not ready after restart: %s", r.Name, r.Error))
+		}
+	}
+	return errors.Join(failed...)
+}
+
 // Status returns aggregate child status.
 func (s *Supervisor) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return Status{Components: []ComponentStatus{s.chat.status(), s.mcp.status()}}
 }
 
 // Logs returns recent log lines for a component, or nil if component is unknown.
 func (s *Supervisor) Logs(component string, tail int) []string {
-	switch component {
-	case "chat":
-		return s.chat.logs(tail)
-	case "mcp":
-		return s.mcp.logs(tail)
-	default:
+	s.mu.Lock()
+	c := s.child(component)
+	s.mu.Unlock()
+	if c == nil {
 		return nil
 	}
+	return c.logs(tail)
 }
 
-// Wait blocks until supervision goroutines exit.
+// Wait blocks until supervision goroutines exit, including after a Stop.
 func (s *Supervisor) Wait() {
 	s.wg.Wait()
 }
@@ -161,9 +550,25 @@ type child struct {
 	name string
 	path string
 	args []string
-	env  []string
 
-	logBuf *ringBuffer
+	preStop   []string
+	healthURL string
+	envCfg    ChildEnv
+	dependsOn []*child
+
+	// listenAddr/listener are only used in handover mode; listener is shared with every
+	// process started for this child.
+	listenAddr   string
+	listener     *os.File
+	readyTimeout time.Duration
+
+	logBuf  *ringBuffer
+	forward LineSink
+	docker  *DockerConfig
+
+	crashDir   string
+	crashLines int
+	crashKeep  int
 
 	mu               sync.Mutex
 	pid              int
@@ -173,6 +578,11 @@ type child struct {
 	initialBackoff   time.Duration
 	maxBackoff       time.Duration
 	terminateTimeout time.Duration
+	waitingFor       []string
+	// startErr is the last failure to start a process, at startErrAt; a later start
+	// clears it.
+	startErr   error
+	startErrAt time.Time
 
 	restartCh chan struct{}
 }
@@ -182,10 +592,16 @@ func newChild(name, path string, args []string, cfg Config) *child {
 		name:             name,
 		path:             path,
 		args:             args,
-		logBuf:           newRingBuffer(cfg.BufferLines),
+		logBuf:           newRingBuffer(cfg.BufferLines, cfg.BufferLineBytes, cfg.BufferBytes),
+		forward:          cfg.Forward,
+		docker:           cfg.Docker,
 		initialBackoff:   cfg.InitialBackoff,
 		maxBackoff:       cfg.MaxBackoff,
 		terminateTimeout: cfg.TerminateTimeout,
+		readyTimeout:     cfg.HealthTimeout,
+		crashDir:         cfg.CrashDir,
+		crashLines:       cfg.CrashLines,
+		crashKeep:        cfg.CrashKeep,
 		restartCh:        make(chan struct{}, 1),
 	}
 }
@@ -208,13 +624,13 @@ func (c *child) run(ctx context.Context, wg *sync.WaitGroup) {
 		default:
 		}
 
-		cmd := exec.CommandContext(context.Background(), c.path, c.args...)
-		cmd.Env = c.childEnv()
-		stdout, _ := cmd.StdoutPipe()
-		stderr, _ := cmd.StderrPipe()
-
-		if err := cmd.Start(); err != nil {
-			c.recordExit(err, false)
+		if !c.waitDependencies(ctx) {
+			return
+		}
+		proc, err := c.startProcess()
+		if err != nil {
+			c.recordStartError(err)
+			c.recordExit(err, false, true)
 			if !c.sleep(ctx, backoff) {
 				return
 			}
@@ -223,29 +639,39 @@ func (c *child) run(ctx context.Context, wg *sync.WaitGroup) {
 		}
 
 		startedAt := time.Now()
-		c.recordStart(cmd.Process.Pid, startedAt)
-
-		done := make(chan error, 1)
-		go func() { done <- cmd.Wait() }()
-
-		go c.pipeOutput(stdout, "stdout")
-		go c.pipeOutput(stderr, "stderr")
+		c.recordStart(proc.cmd.Process.Pid, startedAt)
 
 		forcedRestart := false
 		var exitErr error
-		select {
-		case err := <-done:
-			exitErr = err
-		case <-c.restartCh:
-			forcedRestart = true
-			exitErr = c.signalAndWait(cmd, done)
-		case <-ctx.Done():
-			exitErr = c.signalAndWait(cmd, done)
-			c.recordExit(exitErr, false)
-			return
+	wait:
+		for {
+			select {
+			case err := <-proc.done:
+				exitErr = err
+				break wait
+			case <-c.restartCh:
+				if c.listener != nil {
+					if next, ok := c.handoverTo(ctx, proc); ok {
+						proc, startedAt = next, time.Now()
+						backoff = c.initialBackoff
+						continue
+					}
+				}
+				forcedRestart = true
+				exitErr = c.signalAndWait(proc.cmd, proc.done)
+				break wait
+			case <-ctx.Done():
+				exitErr = c.signalAndWait(proc.cmd, proc.done)
+				c.recordExit(exitErr, false, false)
+				return
+			}
 		}
 
-		c.recordExit(exitErr, true)
+		pid := proc.cmd.Process.Pid
+		c.recordExit(exitErr, true, !forcedRestart)
+		if !forcedRestart {
+			c.captureCrash(proc, exitErr, pid, startedAt)
+		}
 
 		runtime := time.Since(startedAt)
 		if runtime > c.maxBackoff {
@@ -264,9 +690,60 @@ func (c *child) run(ctx context.Context, wg *sync.WaitGroup) {
 	}
 }
 
+// dependencyPoll is how often a held child probes its dependencies again.
+const dependencyPoll = 200 * time.Millisecond
+
+// waitDependencies holds the child until every dependency is ready, probing every
+// dependencyPoll. It returns false if ctx ends first.
+func (c *child) waitDependencies(ctx context.Context) bool {
+	if len(c.dependsOn) == 0 {
+		return true
+	}
+	client := &http.Client{Timeout: 2 * time.Second}
+	since := time.Now()
+	for {
+		var waiting []string
+		for _, dep := range c.dependsOn {
+			if !dep.ready(client) {
+				waiting = append(waiting, dep.name)
+			}
+		}
+
+		c.mu.Lock()
+		was := c.waitingFor
+		c.waitingFor = waiting
+		c.mu.Unlock()
+		switch {
+		case len(waiting) == 0 && was != nil:
+			c.logBuf.Add(fmt.Sprintf("[%s] dependencies ready after %s", c.name, time.Since(since).Round(time.Millisecond)))
+			return true
+		case len(waiting) == 0:
+			return true
+		case was == nil:
+			c.logBuf.Add(fmt.Sprintf("[%s] waiting for %s before starting", c.name, strings.Join(waiting, ", ")))
+		}
+
+		if !c.sleep(ctx, dependencyPoll) {
+			c.mu.Lock()
+			c.waitingFor = nil
+			c.mu.Unlock()
+			return false
+		}
+	}
+}
+
+// ready reports whether the child has a running process that passes its health probe.
+func (c *child) ready(client *http.Client) bool {
+	if c.status().PID == 0 {
+		return false
+	}
+	return c.healthURL == "" || probeHealth(client, c.healthURL) == nil
+}
+
 func (c *child) signalAndWait(cmd *exec.Cmd, done <-chan error) error {
 	if cmd.Process != nil {
-		_ = cmd.Process.Signal(syscall.SIGTERM)
+		c.runPreStop(cmd.Process.Pid)
+		_ = terminate(cmd.Process)
 	}
 
 	select {
@@ -280,11 +757,85 @@ func (c *child) signalAndWait(cmd *exec.Cmd, done <-chan error) error {
 	}
 }
 
+// runPreStop executes the configured pre-stop hook, bounded by the terminate timeout.
+// The hook sees the child environment plus PAYRAM_AGENT_CHILD and PAYRAM_AGENT_CHILD_PID.
+func (c *child) runPreStop(pid int) {
+	if len(c.preStop) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.terminateTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.preStop[0], c.preStop[1:]...)
+	cmd.Env = append(c.childEnv(),
+		"PAYRAM_AGENT_CHILD="+c.name,
+		fmt.Sprintf("PAYRAM_AGENT_CHILD_PID=%d", pid),
+	)
+	out, err := cmd.CombinedOutput()
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			c.logBuf.Add(fmt.Sprintf("[%s][prestop] %s", c.name, line))
+		}
+	}
+	if err != nil {
+		c.logBuf.Add(fmt.Sprintf("[%s] pre-stop hook failed: %v", c.name, err))
+	}
+}
+
+// waitReady blocks until the child has started after the given time and, when a health
+// URL is configured, answered one health probe successfully.
+func (c *child) waitReady(after time.Time, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		st := c.status()
+		if st.PID != 0 && !st.StartTime.Before(after) {
+			break
+		}
+		if err := c.startFailure(after); err != nil {
+			return fmt.Errorf("start: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return errors.New("timed out waiting for process start")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if c.healthURL == "" {
+		return nil
+	}
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	var lastErr error
+	for {
+		if lastErr = probeHealth(client, c.healthURL); lastErr == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("health: %w", lastErr)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func probeHealth(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 func (c *child) stopRunningProcess(current *exec.Cmd) {
 	if current == nil || current.Process == nil {
 		return
 	}
-	_ = current.Process.Signal(syscall.SIGTERM)
+	_ = terminate(current.Process)
 	timer := time.NewTimer(c.terminateTimeout)
 	select {
 	case <-timer.C:
@@ -297,12 +848,33 @@ func (c *child) recordStart(pid int, start time.Time) {
 	c.mu.Lock()
 	c.pid = pid
 	c.startTime = start
+	c.startErr = nil
 	c.mu.Unlock()
 
 	c.logBuf.Add(fmt.Sprintf("[%s] started pid=%d", c.name, pid))
+	lifecycle.Publish(lifecycle.ChildStarted, c.name, map[string]any{"pid": pid})
+}
+
+// recordStartError notes that a process could not be started.
+func (c *child) recordStartError(err error) {
+	c.mu.Lock()
+	c.startErr, c.startErrAt = err, time.Now()
+	c.mu.Unlock()
+}
+
+// startFailure returns the error of a failed start at or after after, if any.
+func (c *child) startFailure(after time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.startErr == nil || c.startErrAt.Before(after) {
+		return nil
+	}
+	return c.startErr
 }
 
-func (c *child) recordExit(err error, countRestart bool) {
+// recordExit notes that the child's process ended; crash is false for exits the supervisor
+// asked for.
+func (c *child) recordExit(err error, countRestart, crash bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -310,13 +882,14 @@ func (c *child) recordExit(err error, countRestart bool) {
 	if err != nil {
 		exitInfo.Error = err.Error()
 		if ee, ok := err.(*exec.ExitError); ok {
-			if ws, ok := ee.Sys().(syscall.WaitStatus); ok {
-				exitInfo.ExitCode = ws.ExitStatus()
-			}
+			exitInfo.ExitCode = ee.ExitCode()
 		}
 	}
 
 	c.lastExit = exitInfo
+	lifecycle.Publish(lifecycle.ChildExited, c.name, map[string]any{
+		"pid": c.pid, "exit_code": exitInfo.ExitCode, "error": exitInfo.Error, "crash": crash,
+	})
 	c.pid = 0
 	if countRestart {
 		c.restarts++
@@ -338,23 +911,196 @@ func exitSummary(info *ExitInfo) string {
 	return "ok"
 }
 
-func (c *child) pipeOutput(r io.ReadCloser, stream string) {
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		c.logBuf.Add(fmt.Sprintf("[%s][%s] %s", c.name, stream, scanner.Text()))
+// pipeOutput copies r into the log buffer, and tail when set, line by line. It holds at most the per-line byte
+// limit of a line and discards the rest, so a huge line (say, dumped JSON) neither grows the
+// agent's memory nor stops the child's output from being drained.
+func (c *child) pipeOutput(r io.ReadCloser, stream string, tail *ringBuffer) {
+	max := c.logBuf.lineBytes
+	br := bufio.NewReader(r)
+	var line []byte
+	cut := 0
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if err == nil {
+			chunk = bytes.TrimSuffix(bytes.TrimSuffix(chunk, []byte("\n")), []byte("\r"))
+		}
+		take := min(max-len(line), len(chunk))
+		line = append(line, chunk[:take]...)
+		cut += len(chunk) - take
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == nil || len(line) > 0 || cut > 0 {
+			text := string(line)
+			c.logBuf.addCut(fmt.Sprintf("[%s][%s] %s", c.name, stream, text), cut)
+			if tail != nil {
+				tail.addCut(text, cut)
+			}
+			if c.forward != nil {
+				c.forward.WriteLine(c.name, stream, truncateLine(text, max, cut))
+			}
+		}
+		if err != nil {
+			return
+		}
+		line, cut = line[:0], 0
 	}
 }
 
 func (c *child) childEnv() []string {
-	base := os.Environ()
+	base := filterEnv(os.Environ(), c.envCfg.Allow)
+	base = applyBundleSettings(base, update.BundleSettings(c.name))
 	switch c.name {
 	case "chat":
 		base = ensureEnv(base, "PAYRAM_CHAT_PORT", "2358")
+		// Only chat talks to OpenAI; keep the stored key out of the MCP process.
+		base = ensureOpenAIKey(base)
 	case "mcp":
 		base = ensureEnv(base, "PAYRAM_MCP_PORT", "3333")
+		// Keep the group metadata cache across restarts and updates.
+		base = ensureEnv(base, "PAYRAM_ANALYTICS_GROUPS_FILE", filepath.Join(update.StateDir(), "mcp_groups_cache.json"))
+	}
+	// The control token lets the agent reach the child's log level endpoint; pass it even
+	// when an allowlist filters the rest of the agent environment.
+	if tok := os.Getenv(logging.ControlTokenEnv); tok != "" {
+		base = setEnv(base, logging.ControlTokenEnv, tok)
+	}
+	return c.applyEnvTemplates(base)
+}
+
+// applyBundleSettings sets the variables of the installed config bundle. They pass any
+// allowlist, and PAYRAM_AGENT_<CHILD>_SETENV_ templates (ChildEnv.Set) still override them.
+func applyBundleSettings(env []string, settings map[string]string) []string {
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = setEnv(env, k, settings[k])
+	}
+	return env
+}
+
+// filterEnv keeps only variables matching the allowlist; an empty allowlist keeps everything.
+func filterEnv(env, allow []string) []string {
+	if len(allow) == 0 {
+		return env
+	}
+
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		if envAllowed(key, allow) {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
+
+func envAllowed(key string, allow []string) bool {
+	for _, pattern := range allow {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+			continue
+		}
+		if key == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEnvTemplates renders ChildEnv.Set values and overrides them in env.
+// Variables whose template fails to render are skipped and noted in the log buffer.
+func (c *child) applyEnvTemplates(env []string) []string {
+	if len(c.envCfg.Set) == 0 {
+		return env
+	}
+
+	data := c.templateData(env)
+	keys := make([]string, 0, len(c.envCfg.Set))
+	for k := range c.envCfg.Set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(c.envCfg.Set[key])
+		if err != nil {
+			c.logBuf.Add(fmt.Sprintf("[%s] env template %s: %v", c.name, key, err))
+			continue
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			c.logBuf.Add(fmt.Sprintf("[%s] env template %s: %v", c.name, key, err))
+			continue
+		}
+		env = setEnv(env, key, sb.String())
+	}
+	return env
+}
+
+func (c *child) templateData(env []string) EnvTemplateData {
+	data := EnvTemplateData{
+		Name:     c.name,
+		ChatPort: envValue(env, "PAYRAM_CHAT_PORT", getenvDefault("PAYRAM_CHAT_PORT", "2358")),
+		MCPPort:  envValue(env, "PAYRAM_MCP_PORT", getenvDefault("PAYRAM_MCP_PORT", "3333")),
+		Home:     update.HomeDir(),
+		BinPath:  c.path,
+	}
+	if c.name == "chat" {
+		data.Port = data.ChatPort
+	} else {
+		data.Port = data.MCPPort
+	}
+	if target, err := os.Readlink(update.CurrentSymlink()); err == nil {
+		data.ReleaseDir = target
+		data.Version = update.VersionFromTarget(target)
+	}
+	return data
+}
+
+func envValue(env []string, key, fallback string) string {
+	prefix := key + "="
+	for _, kv := range env {
+		if strings.HasPrefix(kv, prefix) && len(kv) > len(prefix) {
+			return kv[len(prefix):]
+		}
+	}
+	return fallback
+}
+
+func setEnv(env []string, key, value string) []string {
+	prefix := key + "="
+	for i, kv := range env {
+		if strings.HasPrefix(kv, prefix) {
+			env[i] = prefix + value
+			return env
+		}
 	}
-	base = ensureOpenAIKey(base)
-	return base
+	return append(env, prefix+value)
+}
+
+// childEnvFromEnv reads PAYRAM_AGENT_<COMPONENT>_ENV_ALLOW (comma-separated) and
+// PAYRAM_AGENT_<COMPONENT>_SETENV_<NAME>=<template> variables.
+func childEnvFromEnv(component string) ChildEnv {
+	cfg := ChildEnv{Allow: splitList(os.Getenv("PAYRAM_AGENT_" + component + "_ENV_ALLOW"))}
+	prefix := "PAYRAM_AGENT_" + component + "_SETENV_"
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(key, prefix)
+		if !ok || name == "" {
+			continue
+		}
+		if cfg.Set == nil {
+			cfg.Set = map[string]string{}
+		}
+		cfg.Set[name] = value
+	}
+	return cfg
 }
 
 func ensureEnv(env []string, key, def string) []string {
@@ -439,11 +1185,12 @@ func (c *child) status() ComponentStatus {
 	defer c.mu.Unlock()
 
 	return ComponentStatus{
-		Name:      c.name,
-		PID:       c.pid,
-		StartTime: c.startTime,
-		Restarts:  c.restarts,
-		LastExit:  c.lastExit,
+		Name:       c.name,
+		PID:        c.pid,
+		StartTime:  c.startTime,
+		Restarts:   c.restarts,
+		LastExit:   c.lastExit,
+		WaitingFor: slices.Clone(c.waitingFor),
 	}
 }
 
@@ -451,9 +1198,66 @@ func (c *child) logs(tail int) []string {
 	return c.logBuf.Tail(tail)
 }
 
+// dependsOnFromEnv reads a comma-separated dependency list; "none" clears the default.
+func dependsOnFromEnv(key string, fallback []string) []string {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	if strings.EqualFold(strings.TrimSpace(v), "none") {
+		return nil
+	}
+	return splitList(v)
+}
+
+func envBool(key string) bool {
+	v := strings.ToLower(os.Getenv(key))
+	return v == "1" || v == "true"
+}
+
 func getenvDefault(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return fallback
 }
+
+func envInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+func envDurationMS(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return fallback
+}
+
+func childHealthPath() string {
+	path := os.Getenv("PAYRAM_AGENT_CHILD_HEALTH_PATH")
+	if path == "" {
+		return "/health"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// splitList parses a comma-separated list, skipping empty entries.
+func splitList(raw string) []string {
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package supervisor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChatWaitsForMCPHealth(t *testing.T) {
+	var healthy atomic.Bool
+	mcpHealth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer mcpHealth.Close()
+
+	cfg := lifecycleConfig("sleep 5")
+	cfg.MCPHealthURL = mcpHealth.URL
+	cfg.ChatDependsOn = []string{"mcp"}
+	sup := New(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := sup.mcp.waitReady(time.Time{}, 2*time.Second); err == nil {
+		t.Fatal("expected mcp to be unhealthy")
+	}
+
+	chat := sup.chat.status()
+	if chat.PID != 0 {
+		t.Fatalf("chat started before mcp was healthy: pid %d", chat.PID)
+	}
+	if !slices.Equal(chat.WaitingFor, []string{"mcp"}) {
+		t.Fatalf("waitingFor = %v, want [mcp]", chat.WaitingFor)
+	}
+
+	healthy.Store(true)
+	if err := sup.chat.waitReady(time.Time{}, 2*time.Second); err != nil {
+		t.Fatalf("chat not started once mcp was healthy: %v", err)
+	}
+	if w := sup.chat.status().WaitingFor; len(w) != 0 {
+		t.Fatalf("waitingFor = %v after start", w)
+	}
+	logs := strings.Join(sup.Logs("chat", 20), "\n")
+	if !strings.Contains(logs, "waiting for mcp") || !strings.Contains(logs, "dependencies ready") {
+		t.Fatalf("expected wait to be logged, got:\n%s", logs)
+	}
+}
+
+func TestDependencyCycleFailsStart(t *testing.T) {
+	cfg := lifecycleConfig("sleep 5")
+	cfg.ChatDependsOn = []string{"mcp"}
+	cfg.MCPDependsOn = []string{"chat", "mcp", "bogus"}
+	sup := New(cfg)
+
+	err := sup.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Fatalf("expected a dependency cycle error, got %v", err)
+	}
+	if sup.Running() {
+		t.Fatal("expected nothing started")
+	}
+}
+
+func TestDependsOnFromEnv(t *testing.T) {
+	if got := dependsOnFromEnv("PAYRAM_AGENT_TEST_DEPENDS_ON", []string{"mcp"}); !slices.Equal(got, []string{"mcp"}) {
+		t.Fatalf("unset: got %v", got)
+	}
+	t.Setenv("PAYRAM_AGENT_TEST_DEPENDS_ON", "none")
+	if got := dependsOnFromEnv("PAYRAM_AGENT_TEST_DEPENDS_ON", []string{"mcp"}); len(got) != 0 {
+		t.Fatalf("none: got %v", got)
+	}
+	t.Setenv("PAYRAM_AGENT_TEST_DEPENDS_ON", "chat, mcp")
+	if got := dependsOnFromEnv("PAYRAM_AGENT_TEST_DEPENDS_ON", nil); !slices.Equal(got, []string{"chat", "mcp"}) {
+		t.Fatalf("list: got %v", got)
+	}
+}
@@ -0,0 +1,120 @@
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/update"
+)
+
+func TestChildEnvAllowlistFiltersInheritedVars(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_ANALYTICS_TOKEN", "mcp-only")
+	t.Setenv("CHAT_API_KEY", "chat-key")
+
+	c := newChild("chat", "echo", nil, Config{BufferLines: 10})
+	c.envCfg = ChildEnv{Allow: []string{"PATH", "CHAT_*"}}
+
+	env := c.childEnv()
+	if hasEnv(env, "PAYRAM_ANALYTICS_TOKEN") {
+		t.Fatalf("expected PAYRAM_ANALYTICS_TOKEN filtered out, got %v", env)
+	}
+	if !hasEnv(env, "CHAT_API_KEY") {
+		t.Fatalf("expected CHAT_API_KEY allowed by prefix")
+	}
+	if !hasEnv(env, "PAYRAM_CHAT_PORT") {
+		t.Fatalf("expected default port still injected")
+	}
+}
+
+func TestChildEnvRendersTemplates(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("PAYRAM_AGENT_HOME", home)
+	t.Setenv("PAYRAM_MCP_PORT", "4444")
+
+	releaseDir := filepath.Join(home, "releases", "1.2.3")
+	if err := os.MkdirAll(releaseDir, 0o755); err != nil {
+		t.Fatalf("mkdir release: %v", err)
+	}
+	if _, err := update.UpdateSymlinks(releaseDir); err != nil {
+		t.Fatalf("symlinks: %v", err)
+	}
+
+	c := newChild("chat", "echo", nil, Config{BufferLines: 10})
+	c.envCfg = ChildEnv{Set: map[string]string{
+		"MCP_SERVER_URL": "http://127.0.0.1:{{.MCPPort}}/",
+		"RELEASE":        "{{.Name}}@{{.Version}}",
+		"BROKEN":         "{{.Nope}}",
+	}}
+
+	env := c.childEnv()
+	if got := envValue(env, "MCP_SERVER_URL", ""); got != "http://127.0.0.1:4444/" {
+		t.Fatalf("unexpected MCP_SERVER_URL: %q", got)
+	}
+	if got := envValue(env, "RELEASE", ""); got != "chat@1.2.3" {
+		t.Fatalf("unexpected RELEASE: %q", got)
+	}
+	if hasEnv(env, "BROKEN") {
+		t.Fatalf("expected failed template to be skipped")
+	}
+	if logs := strings.Join(c.logs(10), "\n"); !strings.Contains(logs, "env template BROKEN") {
+		t.Fatalf("expected template failure logged, got %q", logs)
+	}
+}
+
+func TestChildEnvKeepsStoredOpenAIKeyOutOfMCP(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("PAYRAM_AGENT_HOME", home)
+	t.Setenv("OPENAI_API_KEY", "")
+	if err := os.MkdirAll(filepath.Join(home, "state"), 0o755); err != nil {
+		t.Fatalf("mkdir state: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "state", "secrets.json"), []byte(`{"openai_api_key":"sk-secret"}`), 0o600); err != nil {
+		t.Fatalf("write secrets: %v", err)
+	}
+
+	c := newChild("mcp", "echo", nil, Config{BufferLines: 10})
+	if hasEnvWithValue(c.childEnv(), "OPENAI_API_KEY") {
+		t.Fatalf("mcp child should not receive the stored OpenAI key")
+	}
+}
+
+func TestChildEnvFromEnvParsesAllowAndSet(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_MCP_ENV_ALLOW", "PATH, PAYRAM_ANALYTICS_*")
+	t.Setenv("PAYRAM_AGENT_MCP_SETENV_MCP_HTTP_ADDR", ":{{.Port}}")
+
+	cfg := childEnvFromEnv("MCP")
+	if len(cfg.Allow) != 2 || cfg.Allow[1] != "PAYRAM_ANALYTICS_*" {
+		t.Fatalf("unexpected allow: %v", cfg.Allow)
+	}
+	if cfg.Set["MCP_HTTP_ADDR"] != ":{{.Port}}" {
+		t.Fatalf("unexpected set: %v", cfg.Set)
+	}
+}
+
+func TestChildEnvAppliesConfigBundle(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_ANALYTICS_MAX_PAGES", "20")
+	bundle := update.ConfigBundle{Version: "7", Settings: map[string]map[string]string{
+		"mcp":  {"PAYRAM_ANALYTICS_MAX_PAGES": "50", "PAYRAM_ANALYTICS_TIMEOUT_MS": "9000"},
+		"chat": {"CHAT_ONLY": "1"},
+	}}
+	if _, err := update.InstallConfigBundle("", bundle, nil, nil); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	c := newChild("mcp", "echo", nil, Config{BufferLines: 10})
+	c.envCfg = ChildEnv{Allow: []string{"PATH"}, Set: map[string]string{"PAYRAM_ANALYTICS_TIMEOUT_MS": "1000"}}
+	env := c.childEnv()
+	if got := envValue(env, "PAYRAM_ANALYTICS_MAX_PAGES", ""); got != "50" {
+		t.Fatalf("expected the bundle to override the inherited value and pass the allowlist, got %q", got)
+	}
+	if got := envValue(env, "PAYRAM_ANALYTICS_TIMEOUT_MS", ""); got != "1000" {
+		t.Fatalf("expected ChildEnv.Set to win over the bundle, got %q", got)
+	}
+	if hasEnv(env, "CHAT_ONLY") {
+		t.Fatal("chat settings leaked into mcp")
+	}
+}
@@ -0,0 +1,147 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func lifecycleConfig(script string) Config {
+	return Config{
+		ChatPath:         "/bin/sh",
+		ChatArgs:         []string{"-c", script},
+		MCPPath:          "/bin/sh",
+		MCPArgs:          []string{"-c", script},
+		BufferLines:      20,
+		InitialBackoff:   20 * time.Millisecond,
+		MaxBackoff:       50 * time.Millisecond,
+		TerminateTimeout: 500 * time.Millisecond,
+		HealthTimeout:    2 * time.Second,
+	}
+}
+
+func TestStartStopStartAgain(t *testing.T) {
+	sup := New(lifecycleConfig("sleep 5"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := sup.chat.waitReady(time.Time{}, 2*time.Second); err != nil {
+		t.Fatalf("chat not started: %v", err)
+	}
+	first := sup.chat.status().PID
+
+	// A second Start while running must not launch a second pair of loops.
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("second start: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if pid := sup.chat.status().PID; pid != first {
+		t.Fatalf("second start replaced the chat process: %d -> %d", first, pid)
+	}
+
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer stopCancel()
+	if err := sup.Stop(stopCtx); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if sup.Running() {
+		t.Fatal("expected stopped after Stop")
+	}
+	for _, st := range sup.Status().Components {
+		if st.PID != 0 {
+			t.Fatalf("%s still has pid %d after Stop", st.Name, st.PID)
+		}
+	}
+	if err := sup.Stop(stopCtx); err != nil {
+		t.Fatalf("second stop: %v", err)
+	}
+	if err := RestartError(sup.RestartAllAndWait(100 * time.Millisecond)); err == nil {
+		t.Fatal("expected restart to fail while stopped")
+	}
+
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if !sup.Running() {
+		t.Fatal("expected running after Start")
+	}
+	if err := sup.mcp.waitReady(time.Time{}, 2*time.Second); err != nil {
+		t.Fatalf("mcp not started again: %v", err)
+	}
+	if pid := sup.chat.status().PID; pid == 0 || pid == first {
+		t.Fatalf("expected a new chat process, got pid %d (first %d)", pid, first)
+	}
+}
+
+func TestReconfigureRequiresStop(t *testing.T) {
+	sup := New(lifecycleConfig("sleep 5"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	next := lifecycleConfig("echo reconfigured; sleep 5")
+	if err := sup.Reconfigure(next); !errors.Is(err, ErrRunning) {
+		t.Fatalf("expected ErrRunning, got %v", err)
+	}
+
+	if err := sup.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := sup.Reconfigure(next); err != nil {
+		t.Fatalf("reconfigure: %v", err)
+	}
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start after reconfigure: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		logs := sup.Logs("chat", 20)
+		for _, line := range logs {
+			if line == "[chat][stdout] reconfigured" {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected output from the new config, got %v", logs)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestCancelledContextEndsRun(t *testing.T) {
+	sup := New(lifecycleConfig("sleep 5"))
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	cancel()
+	sup.Wait()
+	if sup.Running() {
+		t.Fatal("expected stopped once the context is cancelled")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start again: %v", err)
+	}
+	if err := sup.chat.waitReady(time.Time{}, 2*time.Second); err != nil {
+		t.Fatalf("chat not started again: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package supervisor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestartAllOrdersChildrenAndRunsPreStop(t *testing.T) {
+	hookLog := filepath.Join(t.TempDir(), "prestop.log")
+	hook := []string{"/bin/sh", "-c", "echo $PAYRAM_AGENT_CHILD >> " + hookLog}
+
+	cfg := Config{
+		ChatPath:         "/bin/sh",
+		ChatArgs:         []string{"-c", "sleep 5"},
+		MCPPath:          "/bin/sh",
+		MCPArgs:          []string{"-c", "sleep 5"},
+		BufferLines:      20,
+		InitialBackoff:   20 * time.Millisecond,
+		MaxBackoff:       50 * time.Millisecond,
+		TerminateTimeout: 500 * time.Millisecond,
+		HealthTimeout:    2 * time.Second,
+		ChatPreStop:      hook,
+		MCPPreStop:       hook,
+	}
+	sup := New(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := sup.chat.waitReady(time.Time{}, 2*time.Second); err != nil {
+		t.Fatalf("chat not started: %v", err)
+	}
+	if err := sup.mcp.waitReady(time.Time{}, 2*time.Second); err != nil {
+		t.Fatalf("mcp not started: %v", err)
+	}
+
+	if err := sup.RestartAll(); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+
+	raw, err := os.ReadFile(hookLog)
+	if err != nil {
+		t.Fatalf("read hook log: %v", err)
+	}
+	if got := strings.Fields(string(raw)); len(got) != 2 || got[0] != "mcp" || got[1] != "chat" {
+		t.Fatalf("unexpected pre-stop order: %v", got)
+	}
+
+	chat := sup.chat.status()
+	mcp := sup.mcp.status()
+	if !mcp.StartTime.Before(chat.StartTime) {
+		t.Fatalf("expected mcp restarted before chat: mcp=%s chat=%s", mcp.StartTime, chat.StartTime)
+	}
+}
+
+func TestResolveOrderHonorsConfigAndAppendsMissing(t *testing.T) {
+	sup := New(Config{RestartOrder: []string{"chat", "bogus"}})
+
+	if len(sup.restartOrder) != 2 {
+		t.Fatalf("expected 2 children, got %d", len(sup.restartOrder))
+	}
+	if sup.restartOrder[0].name != "chat" || sup.restartOrder[1].name != "mcp" {
+		t.Fatalf("unexpected order: %s, %s", sup.restartOrder[0].name, sup.restartOrder[1].name)
+	}
+}
+
+func TestResolveOrderLeavesNamesAlone(t *testing.T) {
+	names := make([]string, 1, 3)
+	names[0] = "chat"
+	sup := New(Config{})
+	sup.resolveOrder(names)
+	if got := names[:3]; got[1] != "" || got[2] != "" {
+		t.Fatalf("resolveOrder wrote into the caller's array: %q", got)
+	}
+}
+
+func TestRestartAllReportsStartFailure(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "child.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsleep 5\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sup := New(Config{
+		ChatPath:         "/bin/sh",
+		ChatArgs:         []string{"-c", "sleep 5"},
+		MCPPath:          script,
+		InitialBackoff:   20 * time.Millisecond,
+		MaxBackoff:       50 * time.Millisecond,
+		TerminateTimeout: 500 * time.Millisecond,
+		HealthTimeout:    5 * time.Second,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := sup.mcp.waitReady(time.Time{}, 2*time.Second); err != nil {
+		t.Fatalf("mcp not started: %v", err)
+	}
+	if err := os.Remove(script); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	err := sup.RestartAll()
+	if err == nil || !strings.Contains(err.Error(), "mcp not ready after restart: start:") {
+		t.Fatalf("RestartAll = %v, want the mcp start failure", err)
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Fatalf("RestartAll took %s; a failed start should not wait out the health timeout", elapsed)
+	}
+}
+
+func TestRestartAllAndWaitReportsEachChild(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	sup := New(Config{
+		ChatPath:         "/bin/sh",
+		ChatArgs:         []string{"-c", "sleep 5"},
+		MCPPath:          "/bin/sh",
+		MCPArgs:          []string{"-c", "sleep 5"},
+		InitialBackoff:   20 * time.Millisecond,
+		MaxBackoff:       50 * time.Millisecond,
+		TerminateTimeout: 500 * time.Millisecond,
+		ChatHealthURL:    unhealthy.URL,
+		MCPHealthURL:     healthy.URL,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := sup.mcp.waitReady(time.Time{}, 2*time.Second); err != nil {
+		t.Fatalf("mcp not started: %v", err)
+	}
+	oldPID := sup.mcp.status().PID
+
+	results := sup.RestartAllAndWait(500 * time.Millisecond)
+	if len(results) != 2 || results[0].Name != "mcp" || results[1].Name != "chat" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if mcp := results[0]; !mcp.Ready || mcp.PID == 0 || mcp.PID == oldPID || mcp.Error != "" {
+		t.Fatalf("mcp result = %+v, want ready with a new pid (old %d)", mcp, oldPID)
+	}
+	if chat := results[1]; chat.Ready || !strings.Contains(chat.Error, "status 503") || chat.ElapsedMS < 500 {
+		t.Fatalf("chat result = %+v, want not ready after the timeout", chat)
+	}
+	if err := RestartError(results); err == nil || !strings.Contains(err.Error(), "chat not ready after restart: health") || strings.Contains(err.Error(), "mcp") {
+		t.Fatalf("RestartError = %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package supervisor
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// LineSink receives every line a child writes to stdout or stderr, besides the child's log
+// buffer. WriteLine must not block for long; it runs on the goroutine draining the pipe.
+type LineSink interface {
+	WriteLine(child, stream, line string)
+}
+
+// Severity of a forwarded line, most severe first, as in syslog.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+	SeverityDebug
+)
+
+// levelPattern finds the level in logrus text (level=warning) and JSON ("level":"warning")
+// output, which both children produce.
+var levelPattern = regexp.MustCompile(`(?:^|[\s{,])"?level"?[=:]"?(panic|fatal|error|warn|warning|info|debug|trace)\b`)
+
+// LineSeverity returns the level a logrus line carries, else info.
+func LineSeverity(line string) Severity {
+	m := levelPattern.FindStringSubmatch(line)
+	if m == nil {
+		return SeverityInfo
+	}
+	switch m[1] {
+	case "panic", "fatal", "error":
+		return SeverityError
+	case "warn", "warning":
+		return SeverityWarning
+	case "debug", "trace":
+		return SeverityDebug
+	}
+	return SeverityInfo
+}
+
+// syslogConfig is where forwarded lines go: network and addr are empty for the local daemon
+// (/dev/log, which journald also reads).
+type syslogConfig struct {
+	network  string
+	addr     string
+	facility string
+	tag      string
+}
+
+// syslogFromEnv reads PAYRAM_AGENT_SYSLOG ("local", or udp://host:port or tcp://host:port),
+// PAYRAM_AGENT_SYSLOG_FACILITY (default daemon) and PAYRAM_AGENT_SYSLOG_TAG (default payram;
+// each child logs as <tag>-<child>). It returns a nil sink when forwarding is off.
+func syslogFromEnv() (LineSink, error) {
+	target := strings.TrimSpace(os.Getenv("PAYRAM_AGENT_SYSLOG"))
+	if target == "" {
+		return nil, nil
+	}
+	cfg := syslogConfig{
+		facility: strings.ToLower(getenvDefault("PAYRAM_AGENT_SYSLOG_FACILITY", "daemon")),
+		tag:      getenvDefault("PAYRAM_AGENT_SYSLOG_TAG", "payram"),
+	}
+	if target != "local" {
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" {
+			return nil, fmt.Errorf("PAYRAM_AGENT_SYSLOG: want local, udp://host:port or tcp://host:port, got %q", target)
+		}
+		cfg.network, cfg.addr = u.Scheme, u.Host
+	}
+	return newSyslogSink(cfg)
+}
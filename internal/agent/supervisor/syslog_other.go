@@ -0,0 +1,9 @@
+//go:build windows || plan9
+
+package supervisor
+
+import "errors"
+
+func newSyslogSink(syslogConfig) (LineSink, error) {
+	return nil, errors.New("PAYRAM_AGENT_SYSLOG: syslog is not available on this platform")
+}
@@ -0,0 +1,105 @@
+//go:build !windows && !plan9
+
+package supervisor
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChildOutputForwardedToSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	t.Setenv("PAYRAM_AGENT_SYSLOG", "udp://"+conn.LocalAddr().String())
+	t.Setenv("PAYRAM_AGENT_SYSLOG_FACILITY", "local3")
+	t.Setenv("PAYRAM_AGENT_SYSLOG_TAG", "")
+	forward, err := syslogFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sup := New(Config{
+		ChatPath:         "/bin/sh",
+		ChatArgs:         []string{"-c", `echo 'time="now" level=error msg="boom"'; sleep 5`},
+		MCPPath:          "/bin/sh",
+		MCPArgs:          []string{"-c", `echo '{"level":"warning","msg":"slow"}' >&2; sleep 5`},
+		BufferLines:      20,
+		TerminateTimeout: 200 * time.Millisecond,
+		Forward:          forward,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sup.Wait()
+	}()
+	if err := sup.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// local3 is facility 19: err is <155>, warning <156>.
+	want := map[string]string{
+		"payram-chat": `<155>`,
+		"payram-mcp":  `<156>`,
+	}
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(want) > 0 {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("waiting for %v: %v", want, err)
+		}
+		msg := string(buf[:n])
+		for tag, prio := range want {
+			if strings.Contains(msg, " "+tag+"[") {
+				if !strings.HasPrefix(msg, prio) {
+					t.Fatalf("%s logged %q, want priority %s", tag, msg, prio)
+				}
+				delete(want, tag)
+			}
+		}
+	}
+
+	if tail := sup.Logs("chat", 10); !strings.Contains(strings.Join(tail, "\n"), "boom") {
+		t.Fatalf("chat log buffer = %v", tail)
+	}
+}
+
+func TestSyslogFromEnvValidates(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_SYSLOG", "")
+	if sink, err := syslogFromEnv(); sink != nil || err != nil {
+		t.Fatalf("unset: sink %v, err %v", sink, err)
+	}
+	for _, target := range []string{"journald", "http://host:514", "udp://"} {
+		t.Setenv("PAYRAM_AGENT_SYSLOG", target)
+		if _, err := syslogFromEnv(); err == nil {
+			t.Fatalf("%q: expected error", target)
+		}
+	}
+	t.Setenv("PAYRAM_AGENT_SYSLOG", "local")
+	t.Setenv("PAYRAM_AGENT_SYSLOG_FACILITY", "mail2")
+	if _, err := syslogFromEnv(); err == nil {
+		t.Fatal("expected unknown facility error")
+	}
+}
+
+func TestLineSeverity(t *testing.T) {
+	cases := map[string]Severity{
+		`time="x" level=warning msg="a"`: SeverityWarning,
+		`{"level":"fatal","msg":"a"}`:    SeverityError,
+		`level=debug`:                    SeverityDebug,
+		`plain output mentioning level`:  SeverityInfo,
+		`loglevel=error`:                 SeverityInfo,
+	}
+	for line, want := range cases {
+		if got := LineSeverity(line); got != want {
+			t.Errorf("%q: got %d, want %d", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+//go:build !windows && !plan9
+
+package supervisor
+
+import (
+	"fmt"
+	"log/syslog"
+	"sync"
+	"time"
+)
+
+// syslogRetry is how long forwarding pauses after the syslog daemon could not be reached.
+const syslogRetry = 30 * time.Second
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern": syslog.LOG_KERN, "user": syslog.LOG_USER, "daemon": syslog.LOG_DAEMON,
+	"local0": syslog.LOG_LOCAL0, "local1": syslog.LOG_LOCAL1, "local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3, "local4": syslog.LOG_LOCAL4, "local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6, "local7": syslog.LOG_LOCAL7,
+}
+
+// syslogSink writes each child's lines under its own tag. Connections are opened on first
+// use, so the agent starts even while the syslog daemon is down; lines written while it
+// cannot be reached are only kept in the log buffer.
+type syslogSink struct {
+	cfg      syslogConfig
+	facility syslog.Priority
+
+	mu       sync.Mutex
+	writers  map[string]*syslog.Writer
+	failedAt map[string]time.Time
+}
+
+func newSyslogSink(cfg syslogConfig) (LineSink, error) {
+	facility, ok := syslogFacilities[cfg.facility]
+	if !ok {
+		return nil, fmt.Errorf("PAYRAM_AGENT_SYSLOG_FACILITY: unknown facility %q", cfg.facility)
+	}
+	return &syslogSink{
+		cfg:      cfg,
+		facility: facility,
+		writers:  map[string]*syslog.Writer{},
+		failedAt: map[string]time.Time{},
+	}, nil
+}
+
+func (s *syslogSink) WriteLine(child, _, line string) {
+	w := s.writer(child)
+	if w == nil {
+		return
+	}
+	switch LineSeverity(line) {
+	case SeverityError:
+		_ = w.Err(line)
+	case SeverityWarning:
+		_ = w.Warning(line)
+	case SeverityDebug:
+		_ = w.Debug(line)
+	default:
+		_ = w.Info(line)
+	}
+}
+
+func (s *syslogSink) writer(child string) *syslog.Writer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w, ok := s.writers[child]; ok {
+		return w
+	}
+	if time.Since(s.failedAt[child]) < syslogRetry {
+		return nil
+	}
+	w, err := syslog.Dial(s.cfg.network, s.cfg.addr, s.facility|syslog.LOG_INFO, s.cfg.tag+"-"+child)
+	if err != nil {
+		s.failedAt[child] = time.Now()
+		return nil
+	}
+	s.writers[child] = w
+	return w
+}
@@ -0,0 +1,190 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfigBundle is a signed set of settings for the children, published next to the releases
+// so a fleet can be configured centrally. Settings maps a component ("chat" or "mcp") to the
+// environment variables it starts with, e.g. {"mcp": {"PAYRAM_ANALYTICS_MAX_PAGES": "50"}}.
+type ConfigBundle struct {
+	Version  string                       `json:"version"`
+	Settings map[string]map[string]string `json:"settings"`
+}
+
+// defaultBundleName is fetched when no bundle name is configured.
+const defaultBundleName = "default"
+
+// ErrInvalidConfigBundle wraps every Validate error.
+var ErrInvalidConfigBundle = errors.New("invalid config bundle")
+
+var envName = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// reservedSettings are variables a bundle may not set: the agent's own configuration, the
+// ports its health checks use, and secrets that are managed through /admin/secrets.
+var reservedSettings = []string{"PAYRAM_AGENT_*", "PAYRAM_CHAT_PORT", "PAYRAM_MCP_PORT", "OPENAI_API_KEY", "PAYRAM_CHILD_CONTROL_TOKEN"}
+
+// Validate reports the first problem that keeps the bundle from being applied.
+func (b ConfigBundle) Validate() error {
+	if strings.TrimSpace(b.Version) == "" {
+		return fmt.Errorf("%w: no version", ErrInvalidConfigBundle)
+	}
+	components := make([]string, 0, len(b.Settings))
+	for c := range b.Settings {
+		components = append(components, c)
+	}
+	sort.Strings(components)
+	for _, c := range components {
+		if c != "chat" && c != "mcp" {
+			return fmt.Errorf("%w: unknown component %q", ErrInvalidConfigBundle, c)
+		}
+		for key, value := range b.Settings[c] {
+			if !envName.MatchString(key) {
+				return fmt.Errorf("%w: %s: invalid variable name %q", ErrInvalidConfigBundle, c, key)
+			}
+			if settingReserved(key) {
+				return fmt.Errorf("%w: %s: %s cannot be set by a bundle", ErrInvalidConfigBundle, c, key)
+			}
+			if strings.ContainsRune(value, 0) {
+				return fmt.Errorf("%w: %s: %s contains a NUL byte", ErrInvalidConfigBundle, c, key)
+			}
+		}
+	}
+	return nil
+}
+
+func settingReserved(key string) bool {
+	for _, r := range reservedSettings {
+		if prefix, ok := strings.CutSuffix(r, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == r {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchConfigBundle downloads <base>/config/<name>.json and its .sig, then verifies and
+// validates the bundle. name defaults to "default".
+func FetchConfigBundle(ctx context.Context, baseURL, name, pubKeyB64 string) (ConfigBundle, []byte, []byte, error) {
+	var bundle ConfigBundle
+	if name == "" {
+		name = defaultBundleName
+	}
+	if strings.ContainsAny(name, "/?#") || strings.HasPrefix(name, ".") {
+		return bundle, nil, nil, fmt.Errorf("invalid config bundle name %q", name)
+	}
+	bundleURL := fmt.Sprintf("%s/config/%s.json", strings.TrimRight(baseURL, "/"), name)
+
+	raw, err := fetchBytes(ctx, bundleURL)
+	if err != nil {
+		return bundle, nil, nil, err
+	}
+	sig, err := fetchBytes(ctx, bundleURL+".sig")
+	if err != nil {
+		return bundle, raw, nil, err
+	}
+	if err := VerifyManifest(raw, sig, pubKeyB64); err != nil {
+		return bundle, raw, sig, &SignatureError{Err: err}
+	}
+	if err := json.Unmarshal(raw, &bundle); err != nil {
+		return bundle, raw, sig, fmt.Errorf("%w: %v", ErrInvalidConfigBundle, err)
+	}
+	return bundle, raw, sig, bundle.Validate()
+}
+
+// AppliedBundle is a bundle as installed on this host, with the signed bytes it came from.
+type AppliedBundle struct {
+	ConfigBundle
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+	Raw       []byte    `json:"raw"`
+	Sig       []byte    `json:"sig"`
+}
+
+// BundleState is the installed bundle and the one it replaced. Both live in one file so an
+// install or a restore is a single atomic rename.
+type BundleState struct {
+	Current  *AppliedBundle `json:"current,omitempty"`
+	Previous *AppliedBundle `json:"previous,omitempty"`
+}
+
+func bundleStatePath() string {
+	return filepath.Join(StateDir(), "config_bundle.json")
+}
+
+// LoadBundleState reads the installed bundles; a host without one has an empty state.
+func LoadBundleState() (BundleState, error) {
+	var st BundleState
+	raw, err := os.ReadFile(bundleStatePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return st, nil
+		}
+		return st, err
+	}
+	if err := json.Unmarshal(raw, &st); err != nil {
+		return BundleState{}, fmt.Errorf("config bundle state: %w", err)
+	}
+	return st, nil
+}
+
+func saveBundleState(st BundleState) error {
+	if err := EnsureBaseDirs(); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := bundleStatePath()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// InstallConfigBundle makes bundle the current one, keeping the one it replaces as previous,
+// and returns the state before the install for RestoreBundleState.
+func InstallConfigBundle(name string, bundle ConfigBundle, raw, sig []byte) (BundleState, error) {
+	before, err := LoadBundleState()
+	if err != nil {
+		return before, err
+	}
+	if name == "" {
+		name = defaultBundleName
+	}
+	applied := &AppliedBundle{ConfigBundle: bundle, Name: name, AppliedAt: clk.Now(), Raw: raw, Sig: sig}
+	return before, saveBundleState(BundleState{Current: applied, Previous: before.Current})
+}
+
+// RestoreBundleState puts back a state returned by InstallConfigBundle.
+func RestoreBundleState(st BundleState) error {
+	return saveBundleState(st)
+}
+
+// BundleSettings returns the current bundle's variables for a component, or nil when no
+// bundle is installed or the state cannot be read.
+func BundleSettings(component string) map[string]string {
+	st, err := LoadBundleState()
+	if err != nil || st.Current == nil {
+		return nil
+	}
+	return st.Current.Settings[component]
+}
@@ -0,0 +1,84 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfigBundleValidate(t *testing.T) {
+	cases := map[string]ConfigBundle{
+		"no version":     {Settings: map[string]map[string]string{"mcp": {"A": "1"}}},
+		"component":      {Version: "1", Settings: map[string]map[string]string{"agent": {"A": "1"}}},
+		"name":           {Version: "1", Settings: map[string]map[string]string{"mcp": {"lower-case": "1"}}},
+		"agent setting":  {Version: "1", Settings: map[string]map[string]string{"mcp": {"PAYRAM_AGENT_HOME": "/tmp"}}},
+		"port":           {Version: "1", Settings: map[string]map[string]string{"chat": {"PAYRAM_CHAT_PORT": "1"}}},
+		"secret":         {Version: "1", Settings: map[string]map[string]string{"chat": {"OPENAI_API_KEY": "sk"}}},
+		"nul in a value": {Version: "1", Settings: map[string]map[string]string{"mcp": {"A": "x\x00y"}}},
+	}
+	for name, b := range cases {
+		if err := b.Validate(); !errors.Is(err, ErrInvalidConfigBundle) {
+			t.Errorf("%s: expected ErrInvalidConfigBundle, got %v", name, err)
+		}
+	}
+	ok := ConfigBundle{Version: "1", Settings: map[string]map[string]string{"mcp": {"PAYRAM_ANALYTICS_MAX_PAGES": "50"}}}
+	if err := ok.Validate(); err != nil {
+		t.Fatalf("valid bundle: %v", err)
+	}
+}
+
+func TestFetchConfigBundleRejectsBadSignature(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+	raw := []byte(`{"version":"1","settings":{}}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config/default.json":
+			_, _ = w.Write(raw)
+		case "/config/default.json.sig":
+			_, _ = w.Write(ed25519.Sign(otherPriv, raw))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	_, _, _, err := FetchConfigBundle(context.Background(), srv.URL, "", base64.StdEncoding.EncodeToString(pub))
+	var sigErr *SignatureError
+	if !errors.As(err, &sigErr) {
+		t.Fatalf("expected a signature error, got %v", err)
+	}
+	if _, _, _, err := FetchConfigBundle(context.Background(), srv.URL, "../secrets", ""); err == nil {
+		t.Fatal("expected a path-like bundle name to be rejected")
+	}
+}
+
+func TestInstallAndRestoreConfigBundle(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	first := ConfigBundle{Version: "1", Settings: map[string]map[string]string{"mcp": {"A": "1"}}}
+	second := ConfigBundle{Version: "2", Settings: map[string]map[string]string{"mcp": {"A": "2"}}}
+
+	if _, err := InstallConfigBundle("fleet", first, []byte("one"), nil); err != nil {
+		t.Fatal(err)
+	}
+	before, err := InstallConfigBundle("fleet", second, []byte("two"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, _ := LoadBundleState()
+	if st.Current.Version != "2" || st.Previous.Version != "1" || BundleSettings("mcp")["A"] != "2" {
+		t.Fatalf("after install: %+v", st)
+	}
+
+	if err := RestoreBundleState(before); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := LoadBundleState(); st.Current.Version != "1" || st.Previous != nil || BundleSettings("mcp")["A"] != "1" {
+		t.Fatalf("after restore: %+v", st)
+	}
+}
@@ -0,0 +1,86 @@
+package update
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFetchArtifactReusesAndPrunesCache(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	t.Setenv("PAYRAM_AGENT_ARTIFACT_CACHE_MB", "")
+
+	blobs := map[string][]byte{"/a": []byte("artifact-a"), "/b": []byte("artifact-b")}
+	hits := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		w.Write(blobs[r.URL.Path])
+	}))
+	defer srv.Close()
+	hash := func(p string) string {
+		sum := sha256.Sum256(blobs[p])
+		return hex.EncodeToString(sum[:])
+	}
+
+	dst := filepath.Join(t.TempDir(), "bin")
+	for i, want := range []bool{false, true} {
+		cached, err := FetchArtifact(context.Background(), srv.URL+"/a", hash("/a"), dst)
+		if err != nil || cached != want {
+			t.Fatalf("fetch %d: cached=%v err=%v, want cached=%v", i, cached, err, want)
+		}
+	}
+	if hits["/a"] != 1 {
+		t.Fatalf("expected one download of /a, got %d", hits["/a"])
+	}
+
+	// A corrupted entry is dropped and downloaded again.
+	entry := filepath.Join(CacheDir(), hash("/a"))
+	if err := os.WriteFile(entry, []byte("tampered"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := FetchArtifact(context.Background(), srv.URL+"/a", hash("/a"), dst); err != nil || cached {
+		t.Fatalf("fetch after tamper: cached=%v err=%v", cached, err)
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "artifact-a" {
+		t.Fatalf("dst = %q", got)
+	}
+
+	// A wrong hash never reaches the cache.
+	bogus := hex.EncodeToString(make([]byte, sha256.Size))
+	if _, err := FetchArtifact(context.Background(), srv.URL+"/b", bogus, dst); err == nil {
+		t.Fatal("expected a sha256 mismatch")
+	}
+	if _, err := os.Stat(filepath.Join(CacheDir(), bogus)); !os.IsNotExist(err) {
+		t.Fatalf("mismatched artifact cached, stat err=%v", err)
+	}
+
+	// Pruning keeps the most recently used entries within the limit.
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(entry, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FetchArtifact(context.Background(), srv.URL+"/b", hash("/b"), dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := PruneCache(int64(len(blobs["/b"]))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(entry); !os.IsNotExist(err) {
+		t.Fatalf("expected the older entry pruned, stat err=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(CacheDir(), hash("/b"))); err != nil {
+		t.Fatalf("newest entry pruned: %v", err)
+	}
+
+	// A zero limit turns the cache off.
+	t.Setenv("PAYRAM_AGENT_ARTIFACT_CACHE_MB", "0")
+	if cached, err := FetchArtifact(context.Background(), srv.URL+"/b", hash("/b"), dst); err != nil || cached {
+		t.Fatalf("fetch with cache off: cached=%v err=%v", cached, err)
+	}
+}
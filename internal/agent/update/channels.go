@@ -0,0 +1,111 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KnownChannels are listed by ListChannels whether or not the channel index names them.
+var KnownChannels = []string{"stable", "beta"}
+
+// ChannelIndex is the optional <base>/channels.json naming the channels a base URL hosts. It
+// is not signed: it only says where to look, and every manifest found is verified.
+type ChannelIndex struct {
+	Channels []string `json:"channels"`
+}
+
+// ChannelInfo is the latest release of one channel. Error is set, and the release fields
+// empty, when the channel's manifest could not be fetched or did not verify.
+type ChannelInfo struct {
+	Name       string    `json:"name"`
+	Version    string    `json:"version,omitempty"`
+	ReleasedAt time.Time `json:"released_at,omitzero"`
+	Notes      string    `json:"notes,omitempty"`
+	Revoked    bool      `json:"revoked,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// ListChannels probes KnownChannels and the channels of the base URL's index, fetching and
+// verifying each manifest. Channels are returned in that order. A missing index is not an
+// error; an index that cannot be read is.
+func ListChannels(ctx context.Context, baseURL, pubKeyB64 string) ([]ChannelInfo, error) {
+	index, err := FetchChannelIndex(ctx, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	names := append([]string{}, KnownChannels...)
+	for _, name := range index.Channels {
+		name = strings.TrimSpace(name)
+		if name != "" && !strings.ContainsAny(name, "/?#") && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	out := make([]ChannelInfo, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out[i] = probeChannel(ctx, baseURL, name, pubKeyB64)
+		}()
+	}
+	wg.Wait()
+	return out, nil
+}
+
+func probeChannel(ctx context.Context, baseURL, channel, pubKeyB64 string) ChannelInfo {
+	info := ChannelInfo{Name: channel}
+	manifest, raw, sig, err := FetchManifest(ctx, baseURL, channel)
+	if err == nil {
+		err = VerifyManifest(raw, sig, pubKeyB64)
+	}
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Version, info.ReleasedAt, info.Notes, info.Revoked = manifest.Version, manifest.ReleasedAt, manifest.Notes, manifest.Revoked
+	return info
+}
+
+// FetchChannelIndex reads <base>/channels.json. A base URL without one has an empty index.
+func FetchChannelIndex(ctx context.Context, baseURL string) (ChannelIndex, error) {
+	var index ChannelIndex
+	url := strings.TrimRight(baseURL, "/") + "/channels.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return index, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return index, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden:
+		// Object stores answer 403 for missing keys when listing is not allowed.
+		return index, nil
+	default:
+		return index, fmt.Errorf("channel index: unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return index, err
+	}
+	if len(body) > maxManifestBytes {
+		return index, fmt.Errorf("%s exceeds %d bytes", url, maxManifestBytes)
+	}
+	if err := json.Unmarshal(body, &index); err != nil {
+		return index, fmt.Errorf("channel index: %w", err)
+	}
+	return index, nil
+}
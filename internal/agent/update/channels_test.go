@@ -0,0 +1,41 @@
+package update
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchChannelIndex(t *testing.T) {
+	status, body := http.StatusNotFound, ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/channels.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	index, err := FetchChannelIndex(context.Background(), srv.URL+"/")
+	if err != nil || len(index.Channels) != 0 {
+		t.Fatalf("missing index: %+v, %v", index, err)
+	}
+
+	status, body = http.StatusOK, `{"channels":["stable","edge"]}`
+	index, err = FetchChannelIndex(context.Background(), srv.URL)
+	if err != nil || len(index.Channels) != 2 || index.Channels[1] != "edge" {
+		t.Fatalf("index: %+v, %v", index, err)
+	}
+
+	body = `not json`
+	if _, err := FetchChannelIndex(context.Background(), srv.URL); err == nil {
+		t.Fatal("expected an error for a malformed index")
+	}
+	status = http.StatusInternalServerError
+	if _, err := ListChannels(context.Background(), srv.URL, ""); err == nil {
+		t.Fatal("expected ListChannels to fail when the index cannot be read")
+	}
+}
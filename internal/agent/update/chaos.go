@@ -0,0 +1,94 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Fault injection points for exercising the updater end to end. Each is off unless its
+// variable is set, and none of them belong in production.
+const (
+	// ChaosFailDownloadEnv aborts every artifact download after this percentage (0-100) of
+	// its bytes.
+	ChaosFailDownloadEnv = "PAYRAM_AGENT_CHAOS_FAIL_DOWNLOAD_PCT"
+	// ChaosCorruptHashEnv flips a byte of every downloaded artifact before it is verified.
+	ChaosCorruptHashEnv = "PAYRAM_AGENT_CHAOS_CORRUPT_HASH"
+	// ChaosFailHealthOnceEnv fails the first post-update health check for each home.
+	ChaosFailHealthOnceEnv = "PAYRAM_AGENT_CHAOS_FAIL_HEALTH_ONCE"
+)
+
+// ErrChaos marks a failure injected by one of the chaos variables.
+var ErrChaos = errors.New("chaos")
+
+// chaosDownloadPercent returns the percentage a download is cut off at, and false when
+// PAYRAM_AGENT_CHAOS_FAIL_DOWNLOAD_PCT is unset or not a number between 0 and 100.
+func chaosDownloadPercent() (int, bool) {
+	v := strings.TrimSpace(os.Getenv(ChaosFailDownloadEnv))
+	if v == "" {
+		return 0, false
+	}
+	pct, err := strconv.Atoi(v)
+	if err != nil || pct < 0 || pct > 100 {
+		return 0, false
+	}
+	return pct, true
+}
+
+// chaosCopy copies src to dst like io.Copy, but stops with ErrChaos once pct percent of size
+// bytes are written. An unknown size fails before the first byte.
+func chaosCopy(dst io.Writer, src io.Reader, size int64, pct int) error {
+	limit := int64(0)
+	if size > 0 {
+		limit = size * int64(pct) / 100
+	}
+	if _, err := io.CopyN(dst, src, limit); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return fmt.Errorf("%w: download failed at %d%%", ErrChaos, pct)
+}
+
+func chaosFlag(key string) bool {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv(key)))
+	return v == "1" || v == "true"
+}
+
+// chaosCorrupt flips the first byte of path when PAYRAM_AGENT_CHAOS_CORRUPT_HASH is set, so
+// the checksum that follows fails.
+func chaosCorrupt(path string) error {
+	if !chaosFlag(ChaosCorruptHashEnv) {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		data = []byte{0}
+	} else {
+		data[0] ^= 0xff
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// ChaosHealthFailure returns an ErrChaos error the first time it is called with
+// PAYRAM_AGENT_CHAOS_FAIL_HEALTH_ONCE set, and nil after that. The failure is recorded in the
+// state directory, so a retried apply, or one after an agent restart, passes.
+func ChaosHealthFailure() error {
+	if !chaosFlag(ChaosFailHealthOnceEnv) {
+		return nil
+	}
+	if err := os.MkdirAll(StateDir(), 0o755); err != nil {
+		return nil
+	}
+	f, err := os.OpenFile(filepath.Join(StateDir(), "chaos_health_failed"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil
+	}
+	_ = f.Close()
+	return fmt.Errorf("%w: injected health check failure", ErrChaos)
+}
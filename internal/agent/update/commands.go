@@ -0,0 +1,101 @@
+package update
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CommandRecord is the local audit entry of one command from the fleet command queue:
+// what was asked, and what came of it. Result is "success", "failed", or "rejected" for a
+// command that was not run (bad signature, expired, unknown type).
+type CommandRecord struct {
+	Time      time.Time         `json:"time"`
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Args      map[string]string `json:"args,omitempty"`
+	IssuedAt  time.Time         `json:"issued_at,omitzero"`
+	Result    string            `json:"result"`
+	Status    int               `json:"status,omitempty"`
+	ErrorCode string            `json:"error_code,omitempty"`
+	Message   string            `json:"message,omitempty"`
+}
+
+// AppendCommandRecord adds r to the command audit log, stamping Time when unset.
+func AppendCommandRecord(r CommandRecord) error {
+	if err := EnsureBaseDirs(); err != nil {
+		return err
+	}
+	if r.Time.IsZero() {
+		r.Time = clk.Now()
+	}
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(commandsPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(raw, '\n'))
+	return err
+}
+
+// LoadCommandRecords returns up to limit most recent audit entries, newest first. limit <= 0
+// returns all. Lines that fail to parse are skipped.
+func LoadCommandRecords(limit int) ([]CommandRecord, error) {
+	f, err := os.Open(commandsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []CommandRecord{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var all []CommandRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r CommandRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		all = append(all, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]CommandRecord, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		out = append(out, all[i])
+	}
+	return out, nil
+}
+
+// CommandSeen reports whether the audit log already has an entry for command id, so a
+// command the queue hands out again is not run twice. Entries rejected for their signature do
+// not count: anyone can send a command with a given id, only a signed one claims it.
+func CommandSeen(id string) (bool, error) {
+	records, err := LoadCommandRecords(0)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range records {
+		if r.ID == id && r.ErrorCode != "SIGNATURE_INVALID" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func commandsPath() string {
+	return filepath.Join(StateDir(), "command_audit.jsonl")
+}
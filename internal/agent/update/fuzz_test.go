@@ -0,0 +1,90 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// FuzzFetchManifest serves arbitrary manifest and signature bodies and checks FetchManifest
+// hands back exactly what it fetched, decoding the manifest only when it is valid JSON.
+func FuzzFetchManifest(f *testing.F) {
+	f.Add([]byte(`{"version":"1.2.3","channel":"stable","artifacts":{"chat":{"url":"http://x/chat","sha256":"ab"}}}`), []byte("sig"))
+	f.Add([]byte(`{"version":1}`), []byte{})
+	f.Add([]byte(`{"compatibility":{"payram_core":{"min":"1.0.0"},"agent":{"max":"2.x"}}}`), []byte{0xff})
+	f.Add([]byte(`[`), []byte("\x00"))
+
+	var (
+		mu       sync.Mutex
+		manifest []byte
+		sig      []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/stable/manifest.json":
+			_, _ = w.Write(manifest)
+		case "/stable/manifest.json.sig":
+			_, _ = w.Write(sig)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f.Fuzz(func(t *testing.T, rawIn, sigIn []byte) {
+		mu.Lock()
+		manifest, sig = rawIn, sigIn
+		mu.Unlock()
+
+		m, raw, gotSig, err := FetchManifest(context.Background(), srv.URL, "")
+		if len(rawIn) > maxManifestBytes || len(sigIn) > maxManifestBytes {
+			if err == nil {
+				t.Fatalf("oversized manifest or signature accepted")
+			}
+			return
+		}
+		if string(raw) != string(rawIn) || string(gotSig) != string(sigIn) {
+			t.Fatalf("fetched bytes differ from served bytes")
+		}
+		if valid := json.Unmarshal(rawIn, new(Manifest)) == nil; valid != (err == nil) {
+			t.Fatalf("err = %v for manifest that decodes: %v", err, valid)
+		}
+		if err == nil {
+			_ = VerifyManifest(raw, gotSig, base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize)))
+			_, _ = CheckComponents(m, "1.0.0")
+		}
+	})
+}
+
+// FuzzVerifyManifest feeds arbitrary keys, manifests and signatures to VerifyManifest, and
+// checks that a signature only verifies the exact bytes it was made over.
+func FuzzVerifyManifest(f *testing.F) {
+	seed := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	pub := base64.StdEncoding.EncodeToString(seed.Public().(ed25519.PublicKey))
+	f.Add([]byte(`{"version":"1.0.0"}`), []byte("sig"), pub)
+	f.Add([]byte{}, []byte{}, "")
+	f.Add([]byte("x"), make([]byte, ed25519.SignatureSize), "not base64!")
+	f.Add([]byte("x"), []byte("y"), base64.StdEncoding.EncodeToString([]byte("short")))
+
+	f.Fuzz(func(t *testing.T, raw, sig []byte, pubKey string) {
+		if VerifyManifest(raw, sig, pubKey) == nil && len(sig) != ed25519.SignatureSize {
+			t.Fatalf("accepted a %d-byte signature", len(sig))
+		}
+
+		good := ed25519.Sign(seed, raw)
+		if err := VerifyManifest(raw, good, pub); err != nil {
+			t.Fatalf("valid signature rejected: %v", err)
+		}
+		tampered := append(append([]byte{}, raw...), '\n')
+		if VerifyManifest(tampered, good, pub) == nil {
+			t.Fatalf("signature verified bytes it was not made over")
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package update
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistoryEntry is one line of the update audit log.
+type HistoryEntry struct {
+	Time          time.Time `json:"time"`
+	Action        string    `json:"action"`
+	Result        string    `json:"result"`
+	FromVersion   string    `json:"from_version,omitempty"`
+	ToVersion     string    `json:"to_version,omitempty"`
+	Channel       string    `json:"channel,omitempty"`
+	ForcedChannel bool      `json:"forced_channel,omitempty"`
+	ErrorCode     string    `json:"error_code,omitempty"`
+	Message       string    `json:"message,omitempty"`
+	RemoteAddr    string    `json:"remote_addr,omitempty"`
+}
+
+// AppendHistory appends an entry to the update history log, stamping Time when unset.
+func AppendHistory(e HistoryEntry) error {
+	if err := EnsureBaseDirs(); err != nil {
+		return err
+	}
+	if e.Time.IsZero() {
+		e.Time = clk.Now()
+	}
+
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(historyPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(raw, '\n'))
+	return err
+}
+
+// LoadHistory returns up to limit most recent entries, newest first. limit <= 0 returns all.
+// Lines that fail to parse are skipped.
+func LoadHistory(limit int) ([]HistoryEntry, error) {
+	f, err := os.Open(historyPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []HistoryEntry{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var all []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		all = append(all, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]HistoryEntry, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		out = append(out, all[i])
+	}
+	return out, nil
+}
+
+func historyPath() string {
+	return filepath.Join(StateDir(), "update_history.jsonl")
+}
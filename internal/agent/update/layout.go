@@ -7,22 +7,25 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
-const defaultHomeDir = "/var/lib/payram-mcp"
-
+// Binary file names inside a release (with .exe on Windows).
 const (
-	chatBinaryName = "payram-analytics-chat"
-	mcpBinaryName  = "payram-analytics-mcp"
+	ChatBinaryName = "payram-analytics-chat" + exeSuffix
+	MCPBinaryName  = "payram-analytics-mcp" + exeSuffix
 )
 
-// HomeDir resolves the agent home directory from PAYRAM_AGENT_HOME or default.
+// HomeDir resolves the agent home directory from PAYRAM_AGENT_HOME or the platform default
+// (/var/lib/payram-mcp; ~/Library/Application Support/payram-mcp on macOS;
+// %ProgramData%\payram-mcp on Windows).
 func HomeDir() string {
 	if v := os.Getenv("PAYRAM_AGENT_HOME"); v != "" {
 		return v
 	}
-	return defaultHomeDir
+	return defaultHomeDir()
 }
 
 // ReleasesDir returns the releases directory.
@@ -34,6 +37,9 @@ func ReleaseDir(version string) string { return filepath.Join(ReleasesDir(), ver
 // StateDir returns the state directory.
 func StateDir() string { return filepath.Join(HomeDir(), "state") }
 
+// CrashDir returns the directory holding child crash reports.
+func CrashDir() string { return filepath.Join(StateDir(), "crashes") }
+
 // LockDir returns the lock directory.
 func LockDir() string { return filepath.Join(HomeDir(), "lock") }
 
@@ -58,7 +64,9 @@ func EnsureBaseDirs() error {
 
 var ErrUpdateInProgress = errors.New("update already in progress")
 
-// AcquireUpdateLock obtains an exclusive update lock, writing pid/timestamp into the file.
+// AcquireUpdateLock obtains an exclusive update lock, writing pid/timestamp into the file. A
+// lock left behind by an agent process that no longer exists, e.g. one killed mid-update, is
+// recovered.
 func AcquireUpdateLock() (func() error, error) {
 	if err := EnsureBaseDirs(); err != nil {
 		return nil, err
@@ -66,6 +74,10 @@ func AcquireUpdateLock() (func() error, error) {
 
 	path := LockFilePath()
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil && os.IsExist(err) && staleLock(path) {
+		_ = os.Remove(path)
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	}
 	if err != nil {
 		if os.IsExist(err) {
 			return nil, ErrUpdateInProgress
@@ -80,7 +92,24 @@ func AcquireUpdateLock() (func() error, error) {
 	return unlock, nil
 }
 
-// UpdateSymlinks atomically sets current to newTarget and previous to the old current target.
+// staleLock reports whether the lock file at path names a process other than this one that
+// is no longer running. A lock without a readable pid is never stale.
+func staleLock(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "pid="); ok {
+			pid, err := strconv.Atoi(strings.TrimSpace(v))
+			return err == nil && pid > 0 && pid != os.Getpid() && !processAlive(pid)
+		}
+	}
+	return false
+}
+
+// UpdateSymlinks sets current to newTarget and previous to the old current target. Each link
+// is replaced atomically, except on Windows (see replaceLink).
 func UpdateSymlinks(newTarget string) (string, error) {
 	if err := EnsureBaseDirs(); err != nil {
 		return "", err
@@ -92,24 +121,11 @@ func UpdateSymlinks(newTarget string) (string, error) {
 	oldTarget, _ := os.Readlink(current)
 
 	if oldTarget != "" {
-		tmpPrev := previous + ".tmp"
-		_ = os.Remove(tmpPrev)
-		if err := os.Symlink(oldTarget, tmpPrev); err != nil {
-			return oldTarget, err
-		}
-		if err := os.Rename(tmpPrev, previous); err != nil {
-			_ = os.Remove(tmpPrev)
+		if err := replaceLink(oldTarget, previous); err != nil {
 			return oldTarget, err
 		}
 	}
-
-	tmpCur := current + ".tmp"
-	_ = os.Remove(tmpCur)
-	if err := os.Symlink(newTarget, tmpCur); err != nil {
-		return oldTarget, err
-	}
-	if err := os.Rename(tmpCur, current); err != nil {
-		_ = os.Remove(tmpCur)
+	if err := replaceLink(newTarget, current); err != nil {
 		return oldTarget, err
 	}
 
@@ -126,22 +142,23 @@ func VersionFromTarget(target string) string {
 
 // DefaultChatBin returns the default chat binary path inside the current release.
 func DefaultChatBin() string {
-	return filepath.Join(CurrentSymlink(), chatBinaryName)
+	return filepath.Join(CurrentSymlink(), ChatBinaryName)
 }
 
 // DefaultMCPBin returns the default MCP binary path inside the current release.
 func DefaultMCPBin() string {
-	return filepath.Join(CurrentSymlink(), mcpBinaryName)
+	return filepath.Join(CurrentSymlink(), MCPBinaryName)
 }
 
 // EnsureCompatSymlinks creates compatibility symlinks (chat, mcp) pointing to the canonical binaries.
+// On Windows they fall back to hard links when symlinks are not permitted.
 func EnsureCompatSymlinks(releaseDir string) error {
 	links := []struct {
 		name   string
 		target string
 	}{
-		{name: "chat", target: chatBinaryName},
-		{name: "mcp", target: mcpBinaryName},
+		{name: "chat" + exeSuffix, target: ChatBinaryName},
+		{name: "mcp" + exeSuffix, target: MCPBinaryName},
 	}
 
 	for _, l := range links {
@@ -151,7 +168,7 @@ func EnsureCompatSymlinks(releaseDir string) error {
 		}
 		linkPath := filepath.Join(releaseDir, l.name)
 		_ = os.Remove(linkPath)
-		if err := os.Symlink(targetPath, linkPath); err != nil {
+		if err := linkFile(targetPath, linkPath); err != nil {
 			return fmt.Errorf("create compat symlink %s: %w", linkPath, err)
 		}
 	}
@@ -166,12 +183,8 @@ func EnsureSeedRelease(ctx context.Context, home string) (bool, string, error) {
 	}
 
 	current := filepath.Join(home, "current")
-	if info, err := os.Lstat(current); err == nil {
-		if info.Mode()&os.ModeSymlink != 0 {
-			if _, err := os.Readlink(current); err == nil {
-				return false, "", nil
-			}
-		}
+	if _, err := os.Readlink(current); err == nil {
+		return false, "", nil
 	}
 
 	releaseDir := filepath.Join(home, "releases", "0.0.0")
@@ -188,8 +201,8 @@ func EnsureSeedRelease(ctx context.Context, home string) (bool, string, error) {
 		mcpSrc = "/app/mcp"
 	}
 
-	chatDst := filepath.Join(releaseDir, chatBinaryName)
-	mcpDst := filepath.Join(releaseDir, mcpBinaryName)
+	chatDst := filepath.Join(releaseDir, ChatBinaryName)
+	mcpDst := filepath.Join(releaseDir, MCPBinaryName)
 
 	if err := copyFileWithMode(chatSrc, chatDst, 0o755); err != nil {
 		return false, "", fmt.Errorf("seed chat copy: %w", err)
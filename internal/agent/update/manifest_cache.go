@@ -0,0 +1,215 @@
+package update
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChecksKept is how many manifest checks UpdateStatus.RecentChecks keeps.
+const ChecksKept = 20
+
+// Check results recorded in UpdateStatus.RecentChecks.
+const (
+	CheckResultUpdated     = "updated"
+	CheckResultNotModified = "not_modified"
+	CheckResultFailed      = "failed"
+)
+
+// CheckRecord is one manifest check. Result is CheckResultUpdated when a manifest was downloaded
+// and verified, CheckResultNotModified when the server said the cached one is unchanged, and
+// CheckResultFailed otherwise.
+type CheckRecord struct {
+	Time    time.Time `json:"time"`
+	Channel string    `json:"channel"`
+	Result  string    `json:"result"`
+	Version string    `json:"version,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// RecordCheck keeps c as the newest of the last ChecksKept checks.
+func (s *UpdateStatus) RecordCheck(c CheckRecord) {
+	s.RecentChecks = append([]CheckRecord{c}, s.RecentChecks...)
+	if len(s.RecentChecks) > ChecksKept {
+		s.RecentChecks = s.RecentChecks[:ChecksKept]
+	}
+}
+
+// SignatureError is returned by CheckManifest when the manifest signature does not verify.
+type SignatureError struct{ Err error }
+
+func (e *SignatureError) Error() string { return e.Err.Error() }
+func (e *SignatureError) Unwrap() error { return e.Err }
+
+// ManifestCheck is a verified manifest returned by CheckManifest. NotModified means it came
+// from the cache after the server answered 304 Not Modified.
+type ManifestCheck struct {
+	Manifest    Manifest
+	Raw         []byte
+	Sig         []byte
+	NotModified bool
+}
+
+// cachedManifest is the last manifest fetched for a channel, with the validators to ask the
+// server whether it changed. VerifiedKey is the SHA-256 of the public key it last verified
+// against.
+type cachedManifest struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Raw          []byte `json:"raw"`
+	Sig          []byte `json:"sig"`
+	VerifiedKey  string `json:"verified_key,omitempty"`
+}
+
+// CheckManifest fetches and verifies a channel's manifest, as FetchManifest and
+// VerifyManifest do, but sends the ETag and Last-Modified of the previous fetch. When the
+// server answers 304 the cached manifest is used; it is only verified again if it was
+// verified with another key. Every check is recorded in UpdateStatus.RecentChecks.
+func CheckManifest(ctx context.Context, baseURL, channel, pubKeyB64 string) (ManifestCheck, error) {
+	if channel == "" {
+		channel = "stable"
+	}
+	res, err := checkManifest(ctx, baseURL, channel, pubKeyB64)
+
+	rec := CheckRecord{Time: clk.Now(), Channel: channel, Result: CheckResultUpdated, Version: res.Manifest.Version}
+	switch {
+	case err != nil:
+		rec.Result, rec.Version, rec.Error = CheckResultFailed, "", err.Error()
+	case res.NotModified:
+		rec.Result = CheckResultNotModified
+	}
+	if st, loadErr := LoadStatus(); loadErr == nil {
+		st.RecordCheck(rec)
+		_ = SaveStatus(st)
+	}
+	return res, err
+}
+
+func checkManifest(ctx context.Context, baseURL, channel, pubKeyB64 string) (ManifestCheck, error) {
+	manifestURL := fmt.Sprintf("%s/%s/manifest.json", strings.TrimRight(baseURL, "/"), channel)
+	cache := loadManifestCache()
+	entry, ok := cache[channel]
+	if !ok || entry.URL != manifestURL {
+		entry = cachedManifest{URL: manifestURL}
+	}
+
+	raw, etag, lastModified, notModified, err := fetchConditional(ctx, manifestURL, entry)
+	if err != nil {
+		return ManifestCheck{}, err
+	}
+	res := ManifestCheck{NotModified: notModified}
+	if notModified {
+		res.Raw, res.Sig = entry.Raw, entry.Sig
+	} else {
+		sig, err := fetchBytes(ctx, manifestURL+".sig")
+		if err != nil {
+			return ManifestCheck{}, err
+		}
+		res.Raw, res.Sig = raw, sig
+		entry = cachedManifest{URL: manifestURL, ETag: etag, LastModified: lastModified, Raw: raw, Sig: sig}
+	}
+
+	key := keyID(pubKeyB64)
+	if !notModified || entry.VerifiedKey != key {
+		if err := VerifyManifest(res.Raw, res.Sig, pubKeyB64); err != nil {
+			return ManifestCheck{}, &SignatureError{Err: err}
+		}
+	}
+	if err := json.Unmarshal(res.Raw, &res.Manifest); err != nil {
+		return ManifestCheck{}, err
+	}
+
+	if entry.ETag != "" || entry.LastModified != "" {
+		entry.VerifiedKey = key
+		cache[channel] = entry
+		_ = saveManifestCache(cache)
+	}
+	return res, nil
+}
+
+// fetchConditional GETs url with the validators of entry, if it holds a manifest. It reports
+// notModified on a 304, and otherwise returns the body and the response's validators.
+func fetchConditional(ctx context.Context, url string, entry cachedManifest) (body []byte, etag, lastModified string, notModified bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", "", false, err
+	}
+	if len(entry.Raw) > 0 && len(entry.Sig) > 0 {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && len(entry.Raw) > 0 {
+		return nil, "", "", true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	body, err = io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return nil, "", "", false, err
+	}
+	if len(body) > maxManifestBytes {
+		return nil, "", "", false, fmt.Errorf("%s exceeds %d bytes", url, maxManifestBytes)
+	}
+	return body, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"), false, nil
+}
+
+func keyID(pubKeyB64 string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(pubKeyB64)))
+	return hex.EncodeToString(sum[:])
+}
+
+func manifestCachePath() string {
+	return filepath.Join(StateDir(), "manifest_cache.json")
+}
+
+// loadManifestCache returns the cached manifests by channel; a missing or unreadable cache
+// is empty.
+func loadManifestCache() map[string]cachedManifest {
+	cache := map[string]cachedManifest{}
+	raw, err := os.ReadFile(manifestCachePath())
+	if err == nil && json.Unmarshal(raw, &cache) != nil {
+		cache = map[string]cachedManifest{}
+	}
+	return cache
+}
+
+func saveManifestCache(cache map[string]cachedManifest) error {
+	if err := EnsureBaseDirs(); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	path := manifestCachePath()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
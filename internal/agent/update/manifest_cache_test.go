@@ -0,0 +1,86 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckManifestNotModified(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	pubB64 := base64.StdEncoding.EncodeToString(pub)
+
+	raw, _ := json.Marshal(Manifest{Version: "1.2.0"})
+	sig := ed25519.Sign(priv, raw)
+	var manifestGets, sigGets atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stable/manifest.json":
+			manifestGets.Add(1)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write(raw)
+		case "/stable/manifest.json.sig":
+			sigGets.Add(1)
+			_, _ = w.Write(sig)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	first, err := CheckManifest(context.Background(), srv.URL, "stable", pubB64)
+	if err != nil || first.NotModified || first.Manifest.Version != "1.2.0" {
+		t.Fatalf("first check: %+v, %v", first, err)
+	}
+	second, err := CheckManifest(context.Background(), srv.URL, "stable", pubB64)
+	if err != nil || !second.NotModified || second.Manifest.Version != "1.2.0" {
+		t.Fatalf("second check: %+v, %v", second, err)
+	}
+	if manifestGets.Load() != 2 || sigGets.Load() != 1 {
+		t.Fatalf("manifest fetched %d times, signature %d times", manifestGets.Load(), sigGets.Load())
+	}
+
+	// The cached manifest is verified again against a different key.
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	_, err = CheckManifest(context.Background(), srv.URL, "stable", base64.StdEncoding.EncodeToString(otherPub))
+	var sigErr *SignatureError
+	if !errors.As(err, &sigErr) {
+		t.Fatalf("expected a signature error for another key, got %v", err)
+	}
+
+	st, err := LoadStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []string
+	for _, c := range st.RecentChecks {
+		results = append(results, c.Result)
+	}
+	want := []string{CheckResultFailed, CheckResultNotModified, CheckResultUpdated}
+	if !slices.Equal(results, want) {
+		t.Fatalf("recent checks = %v, want %v", results, want)
+	}
+}
+
+func TestRecordCheckKeepsNewest(t *testing.T) {
+	var st UpdateStatus
+	for i := 0; i < ChecksKept+5; i++ {
+		st.RecordCheck(CheckRecord{Channel: "stable", Version: string(rune('a' + i))})
+	}
+	if len(st.RecentChecks) != ChecksKept || st.RecentChecks[0].Version != string(rune('a'+ChecksKept+4)) {
+		t.Fatalf("kept %d checks, newest %q", len(st.RecentChecks), st.RecentChecks[0].Version)
+	}
+}
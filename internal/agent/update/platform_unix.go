@@ -0,0 +1,54 @@
+//go:build !windows
+
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+// exeSuffix is appended to binary names in a release.
+const exeSuffix = ""
+
+// defaultHomeDir is /var/lib/payram-mcp, except on macOS, where developer machines have no
+// writable /var/lib and the agent lives under the user's Application Support instead.
+func defaultHomeDir() string {
+	if runtime.GOOS == "darwin" {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, "Library", "Application Support", "payram-mcp")
+		}
+	}
+	return "/var/lib/payram-mcp"
+}
+
+// replaceLink points link at the target directory atomically: a new symlink is created next
+// to it and renamed over the old one.
+func replaceLink(target, link string) error {
+	tmp := link + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// linkFile makes link an alias of the target file.
+func linkFile(target, link string) error {
+	return os.Symlink(target, link)
+}
+
+func isExecutable(info os.FileInfo) bool {
+	return info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+// processAlive reports whether a process with the given pid exists.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,79 @@
+//go:build windows
+
+package update
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const exeSuffix = ".exe"
+
+// defaultHomeDir is %ProgramData%\payram-mcp.
+func defaultHomeDir() string {
+	base := os.Getenv("ProgramData")
+	if base == "" {
+		base = `C:\ProgramData`
+	}
+	return filepath.Join(base, "payram-mcp")
+}
+
+// replaceLink points link at the target directory. Symlinks need Developer Mode or an
+// elevated agent on Windows, so a directory junction is used when one cannot be created.
+// Windows cannot rename a link over an existing one, so the old link is removed first and
+// there is a short window in which link does not exist.
+func replaceLink(target, link string) error {
+	tmp := link + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		if err := junction(target, tmp); err != nil {
+			return err
+		}
+	}
+	// os.Remove on a symlink or junction removes the link, never the release it points to.
+	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func junction(target, link string) error {
+	out, err := exec.Command("cmd", "/C", "mklink", "/J", link, target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("mklink /J %s: %v: %s", link, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// linkFile makes link an alias of the target file, as a hard link when symlinks are not
+// permitted.
+func linkFile(target, link string) error {
+	if err := os.Symlink(target, link); err == nil {
+		return nil
+	}
+	return os.Link(target, link)
+}
+
+// isExecutable reports whether info is a program; Windows has no execute permission bit.
+func isExecutable(info os.FileInfo) bool {
+	return info.Mode().IsRegular() && strings.EqualFold(filepath.Ext(info.Name()), exeSuffix)
+}
+
+// processAlive reports whether a process with the given pid exists; FindProcess fails for
+// one that does not on Windows.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
@@ -38,8 +38,8 @@ func TestEnsureSeedReleaseCreatesBaseline(t *testing.T) {
 	}
 
 	releaseDir := filepath.Join(home, "releases", "0.0.0")
-	chatBin := filepath.Join(releaseDir, chatBinaryName)
-	mcpBin := filepath.Join(releaseDir, mcpBinaryName)
+	chatBin := filepath.Join(releaseDir, ChatBinaryName)
+	mcpBin := filepath.Join(releaseDir, MCPBinaryName)
 	if _, err := os.Stat(chatBin); err != nil {
 		t.Fatalf("chat not copied: %v", err)
 	}
@@ -47,10 +47,10 @@ func TestEnsureSeedReleaseCreatesBaseline(t *testing.T) {
 		t.Fatalf("mcp not copied: %v", err)
 	}
 
-	if target, err := os.Readlink(filepath.Join(releaseDir, "chat")); err != nil || filepath.Base(target) != chatBinaryName {
+	if target, err := os.Readlink(filepath.Join(releaseDir, "chat")); err != nil || filepath.Base(target) != ChatBinaryName {
 		t.Fatalf("chat compat link invalid: %v target=%s", err, target)
 	}
-	if target, err := os.Readlink(filepath.Join(releaseDir, "mcp")); err != nil || filepath.Base(target) != mcpBinaryName {
+	if target, err := os.Readlink(filepath.Join(releaseDir, "mcp")); err != nil || filepath.Base(target) != MCPBinaryName {
 		t.Fatalf("mcp compat link invalid: %v target=%s", err, target)
 	}
 
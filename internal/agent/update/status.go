@@ -5,21 +5,51 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
 )
 
+// clk stamps status and history entries.
+var clk = clock.System
+
+// SetClock replaces the clock used for status and history timestamps and returns a function
+// restoring the previous one.
+func SetClock(c clock.Clock) (restore func()) {
+	prev := clk
+	clk = c
+	return func() { clk = prev }
+}
+
 // UpdateStatus captures persisted update state.
 type UpdateStatus struct {
-	CurrentVersion      string    `json:"current_version"`
-	PreviousVersion     string    `json:"previous_version"`
-	LastSuccessVersion  string    `json:"last_success_version"`
-	LastSuccessAt       time.Time `json:"last_success_at"`
-	LastAttemptVersion  string    `json:"last_attempt_version"`
-	LastAttemptAt       time.Time `json:"last_attempt_at"`
-	LastErrorCode       string    `json:"last_error_code"`
-	LastErrorMessage    string    `json:"last_error_message"`
-	LastErrorAt         time.Time `json:"last_error_at"`
-	InProgress          bool      `json:"in_progress"`
-	InProgressStartedAt time.Time `json:"in_progress_started_at"`
+	CurrentVersion      string        `json:"current_version"`
+	PreviousVersion     string        `json:"previous_version"`
+	CurrentChannel      string        `json:"current_channel"`
+	PreviousChannel     string        `json:"previous_channel"`
+	LastSuccessVersion  string        `json:"last_success_version"`
+	LastSuccessAt       time.Time     `json:"last_success_at"`
+	LastAttemptVersion  string        `json:"last_attempt_version"`
+	LastAttemptAt       time.Time     `json:"last_attempt_at"`
+	LastErrorCode       string        `json:"last_error_code"`
+	LastErrorMessage    string        `json:"last_error_message"`
+	LastErrorAt         time.Time     `json:"last_error_at"`
+	InProgress          bool          `json:"in_progress"`
+	InProgressStartedAt time.Time     `json:"in_progress_started_at"`
+	RecentTimings       []Timing      `json:"recent_timings"`
+	RecentChecks        []CheckRecord `json:"recent_checks,omitempty"`
+	Canary              *Canary       `json:"canary,omitempty"`
+}
+
+// Canary is an applied release still in its soak period. It becomes the last success once
+// the soak passes, and is rolled back when the children fail more health probes or crash more
+// often than the limits allow.
+type Canary struct {
+	Version           string    `json:"version"`
+	PreviousVersion   string    `json:"previous_version"`
+	StartedAt         time.Time `json:"started_at"`
+	Until             time.Time `json:"until"`
+	MaxHealthFailures int       `json:"max_health_failures"`
+	MaxCrashes        int       `json:"max_crashes"`
 }
 
 // LoadStatus loads persisted status, returning a zero value when missing.
@@ -75,10 +105,11 @@ func SaveStatus(st UpdateStatus) error {
 
 // MarkAttempt sets in-progress markers.
 func (s *UpdateStatus) MarkAttempt() {
+	now := clk.Now()
 	s.InProgress = true
-	s.InProgressStartedAt = time.Now()
+	s.InProgressStartedAt = now
 	s.LastAttemptVersion = ""
-	s.LastAttemptAt = time.Now()
+	s.LastAttemptAt = now
 	s.LastErrorCode = ""
 	s.LastErrorMessage = ""
 	s.LastErrorAt = time.Time{}
@@ -89,7 +120,7 @@ func (s *UpdateStatus) MarkSuccess(current, previous string) {
 	s.CurrentVersion = current
 	s.PreviousVersion = previous
 	s.LastSuccessVersion = current
-	s.LastSuccessAt = time.Now()
+	s.LastSuccessAt = clk.Now()
 	s.InProgress = false
 }
 
@@ -97,10 +128,18 @@ func (s *UpdateStatus) MarkSuccess(current, previous string) {
 func (s *UpdateStatus) MarkFailure(code, msg string) {
 	s.LastErrorCode = code
 	s.LastErrorMessage = msg
-	s.LastErrorAt = time.Now()
+	s.LastErrorAt = clk.Now()
 	s.InProgress = false
 }
 
+// EnsureAttempt records version as the last attempted one when no attempt is recorded yet.
+func (s *UpdateStatus) EnsureAttempt(version string) {
+	if s.LastAttemptVersion == "" {
+		s.LastAttemptVersion = version
+		s.LastAttemptAt = clk.Now()
+	}
+}
+
 func statusPath() string {
 	return filepath.Join(StateDir(), "update_status.json")
 }
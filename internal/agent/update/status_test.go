@@ -1,6 +1,11 @@
 package update
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+)
 
 func TestStatusPersistence(t *testing.T) {
 	home := t.TempDir()
@@ -36,3 +41,100 @@ func TestStatusPersistence(t *testing.T) {
 		t.Fatalf("expected in progress true")
 	}
 }
+
+func TestHistoryAppendAndLoadNewestFirst(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+
+	for _, v := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		if err := AppendHistory(HistoryEntry{Action: "apply", Result: "success", ToVersion: v}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	entries, err := LoadHistory(2)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ToVersion != "1.2.0" || entries[1].ToVersion != "1.1.0" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if entries[0].Time.IsZero() {
+		t.Fatalf("expected time stamped")
+	}
+}
+
+func TestStatusTimestampsUseClock(t *testing.T) {
+	at := time.Date(2025, 3, 31, 23, 59, 59, 0, time.UTC)
+	defer SetClock(clock.Fixed(at))()
+
+	var st UpdateStatus
+	st.MarkAttempt()
+	if !st.InProgressStartedAt.Equal(at) || !st.LastAttemptAt.Equal(at) {
+		t.Fatalf("attempt timestamps = %v/%v, want %v", st.InProgressStartedAt, st.LastAttemptAt, at)
+	}
+	st.MarkFailure("ERR", "boom")
+	if !st.LastErrorAt.Equal(at) {
+		t.Fatalf("error timestamp = %v, want %v", st.LastErrorAt, at)
+	}
+	st.MarkSuccess("1.0.0", "0.9.0")
+	if !st.LastSuccessAt.Equal(at) {
+		t.Fatalf("success timestamp = %v, want %v", st.LastSuccessAt, at)
+	}
+
+	st.LastAttemptVersion = ""
+	st.EnsureAttempt("1.0.0")
+	if st.LastAttemptVersion != "1.0.0" || !st.LastAttemptAt.Equal(at) {
+		t.Fatalf("ensure attempt = %q at %v", st.LastAttemptVersion, st.LastAttemptAt)
+	}
+	st.EnsureAttempt("2.0.0")
+	if st.LastAttemptVersion != "1.0.0" {
+		t.Fatalf("ensure attempt overwrote recorded version: %q", st.LastAttemptVersion)
+	}
+}
+
+// stepClock advances by step on every reading.
+type stepClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *stepClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func TestTimingStagesAndBottleneck(t *testing.T) {
+	defer SetClock(&stepClock{now: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), step: time.Second})()
+
+	timing := NewTiming("apply")
+	timing.Stage(StageFetch)    // fetch runs for one tick
+	timing.Stage(StageDownload) // download for one tick, plus the Add below
+	timing.Add(StageDownload, 4*time.Second)
+	timing.End()
+	timing.Add(StageRestart, 2*time.Second)
+	got := timing.Finish("1.2.0", "success")
+
+	if got.Bottleneck != StageDownload || got.Version != "1.2.0" || got.Result != "success" {
+		t.Fatalf("unexpected timing: %+v", got)
+	}
+	want := []StageTiming{{StageFetch, 1000}, {StageDownload, 5000}, {StageRestart, 2000}}
+	if len(got.Stages) != len(want) {
+		t.Fatalf("stages = %+v, want %+v", got.Stages, want)
+	}
+	for i := range want {
+		if got.Stages[i] != want[i] {
+			t.Fatalf("stages = %+v, want %+v", got.Stages, want)
+		}
+	}
+	if got.TotalMS != 5000 {
+		t.Fatalf("total = %dms, want 5000", got.TotalMS)
+	}
+
+	var st UpdateStatus
+	for i := 0; i < TimingsKept+2; i++ {
+		st.RecordTiming(Timing{Version: string(rune('a' + i))})
+	}
+	if len(st.RecentTimings) != TimingsKept || st.RecentTimings[0].Version != string(rune('a'+TimingsKept+1)) {
+		t.Fatalf("recent timings not newest first and capped: %d, first %q", len(st.RecentTimings), st.RecentTimings[0].Version)
+	}
+}
@@ -0,0 +1,98 @@
+package update
+
+import (
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/agent/lifecycle"
+)
+
+// Stages of an update or rollback, in the order an apply runs them.
+const (
+	StageFetch    = "fetch"    // manifest and signature download
+	StageVerify   = "verify"   // signature, revocation and compatibility checks
+	StageDownload = "download" // artifact downloads and checksum verification
+	StageSwitch   = "switch"   // release directory and symlink switch
+	StageRestart  = "restart"  // until every child has a new process
+	StageHealth   = "health"   // until every child passes its health probe
+	StageSmoke    = "smoke"    // post-update functional checks, when configured
+	StageRollback = "rollback" // automatic rollback after a failed health check
+)
+
+// TimingsKept is how many attempts UpdateStatus.RecentTimings keeps.
+const TimingsKept = 10
+
+// StageTiming is the time one stage of an attempt took.
+type StageTiming struct {
+	Stage      string `json:"stage"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Timing breaks one update or rollback attempt down by stage. Bottleneck names the slowest
+// stage, so a slow artifact CDN shows up as "download".
+type Timing struct {
+	Action     string        `json:"action"`
+	Version    string        `json:"version,omitempty"`
+	Result     string        `json:"result"`
+	StartedAt  time.Time     `json:"started_at"`
+	TotalMS    int64         `json:"total_ms"`
+	Bottleneck string        `json:"bottleneck,omitempty"`
+	Stages     []StageTiming `json:"stages"`
+
+	open      string
+	openSince time.Time
+}
+
+// NewTiming starts timing an attempt of action ("apply" or "rollback").
+func NewTiming(action string) *Timing {
+	return &Timing{Action: action, StartedAt: clk.Now(), Stages: []StageTiming{}}
+}
+
+// Stage ends the running stage, if any, and starts timing stage, announcing it on the
+// lifecycle bus.
+func (t *Timing) Stage(stage string) {
+	t.End()
+	t.open, t.openSince = stage, clk.Now()
+	lifecycle.Publish(lifecycle.UpdateStage, "", map[string]any{"action": t.Action, "stage": stage})
+}
+
+// End ends the running stage, if any.
+func (t *Timing) End() {
+	if t.open != "" {
+		t.Add(t.open, clk.Now().Sub(t.openSince))
+		t.open = ""
+	}
+}
+
+// Add records d against stage, for stages measured elsewhere; a repeated stage accumulates.
+func (t *Timing) Add(stage string, d time.Duration) {
+	for i := range t.Stages {
+		if t.Stages[i].Stage == stage {
+			t.Stages[i].DurationMS += d.Milliseconds()
+			return
+		}
+	}
+	t.Stages = append(t.Stages, StageTiming{Stage: stage, DurationMS: d.Milliseconds()})
+}
+
+// Finish ends the running stage and returns the attempt with its total and bottleneck.
+func (t *Timing) Finish(version, result string) Timing {
+	t.End()
+	t.Version, t.Result = version, result
+	t.TotalMS = clk.Now().Sub(t.StartedAt).Milliseconds()
+	t.Bottleneck = ""
+	var slowest int64 = -1
+	for _, s := range t.Stages {
+		if s.DurationMS > slowest {
+			t.Bottleneck, slowest = s.Stage, s.DurationMS
+		}
+	}
+	return *t
+}
+
+// RecordTiming keeps t as the newest of the last TimingsKept attempts.
+func (s *UpdateStatus) RecordTiming(t Timing) {
+	s.RecentTimings = append([]Timing{t}, s.RecentTimings...)
+	if len(s.RecentTimings) > TimingsKept {
+		s.RecentTimings = s.RecentTimings[:TimingsKept]
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -37,14 +38,23 @@ type Artifacts struct {
 }
 
 // Artifact describes a downloadable binary.
+// Version defaults to the manifest version. Requires constrains the versions of the
+// other pieces this component runs with, keyed by "agent", "chat" or "mcp".
+// Image is the container image the docker child driver runs instead of the binary; pin it
+// by digest (name@sha256:...) so the signed manifest fixes what runs.
 type Artifact struct {
-	URL    string `json:"url"`
-	SHA256 string `json:"sha256"`
+	URL      string           `json:"url"`
+	SHA256   string           `json:"sha256"`
+	Version  string           `json:"version,omitempty"`
+	Requires map[string]Range `json:"requires,omitempty"`
+	Image    string           `json:"image,omitempty"`
 }
 
 // Compatibility captures version ranges for dependencies.
+// Agent is the range of agent versions able to install and supervise the release.
 type Compatibility struct {
 	PayramCore Range `json:"payram_core"`
+	Agent      Range `json:"agent,omitzero"`
 }
 
 // Range defines min/max versions.
@@ -163,32 +173,88 @@ func MatchesMax(version, max string) (bool, error) {
 
 // IsCompatible checks coreVersion against min/max, returning a reason when incompatible.
 func IsCompatible(coreVersion, min, max string) (bool, string) {
+	return inRange("payram-core", coreVersion, min, max)
+}
+
+func inRange(name, v, min, max string) (bool, string) {
 	if min != "" {
-		cmp, err := CompareVersions(coreVersion, min)
+		cmp, err := CompareVersions(v, min)
 		if err != nil {
-			return false, "invalid core or min version"
+			return false, fmt.Sprintf("invalid %s or min version", name)
 		}
 		if cmp < 0 {
-			return false, fmt.Sprintf("Requires payram-core >= %s", min)
+			return false, fmt.Sprintf("Requires %s >= %s", name, min)
 		}
 	}
 
 	if max != "" {
-		ok, err := MatchesMax(coreVersion, max)
+		ok, err := MatchesMax(v, max)
 		if err != nil {
 			return false, "invalid max version"
 		}
 		if !ok {
 			if strings.HasSuffix(max, ".x") {
-				return false, fmt.Sprintf("Requires payram-core %s", max)
+				return false, fmt.Sprintf("Requires %s %s", name, max)
 			}
-			return false, fmt.Sprintf("Requires payram-core <= %s", max)
+			return false, fmt.Sprintf("Requires %s <= %s", name, max)
 		}
 	}
 
 	return true, ""
 }
 
+// CheckComponents enforces the agent and cross-component constraints of a manifest against the
+// running agent version and the component versions the release ships. It returns the violated
+// constraints, plus warnings for constraints that could not be evaluated (e.g. a dev agent build).
+func CheckComponents(m Manifest, agentVersion string) (problems, warnings []string) {
+	versions := map[string]string{
+		"agent": strings.TrimPrefix(agentVersion, "v"),
+		"chat":  defaultVersion(m.Artifacts.Chat.Version, m.Version),
+		"mcp":   defaultVersion(m.Artifacts.MCP.Version, m.Version),
+	}
+
+	check := func(owner, name string, r Range) {
+		if r.Min == "" && r.Max == "" {
+			return
+		}
+		v, known := versions[name]
+		if !known {
+			warnings = append(warnings, fmt.Sprintf("%s: unknown component %q in requirements", owner, name))
+			return
+		}
+		if _, _, _, ok := ParseVersion(v); !ok {
+			warnings = append(warnings, fmt.Sprintf("%s: cannot check %s version %q", owner, name, v))
+			return
+		}
+		if ok, reason := inRange(name, v, r.Min, r.Max); !ok {
+			problems = append(problems, fmt.Sprintf("%s: %s (have %s)", owner, reason, v))
+		}
+	}
+
+	check("release", "agent", m.Compatibility.Agent)
+	for _, comp := range []struct {
+		name string
+		art  Artifact
+	}{{"chat", m.Artifacts.Chat}, {"mcp", m.Artifacts.MCP}} {
+		names := make([]string, 0, len(comp.art.Requires))
+		for name := range comp.art.Requires {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			check(comp.name, name, comp.art.Requires[name])
+		}
+	}
+	return problems, warnings
+}
+
+func defaultVersion(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
+
 // FetchManifest downloads manifest and signature for a channel.
 func FetchManifest(ctx context.Context, baseURL, channel string) (Manifest, []byte, []byte, error) {
 	var manifest Manifest
@@ -258,7 +324,12 @@ func DownloadToFile(ctx context.Context, url, dstPath string) error {
 		return err
 	}
 
-	_, copyErr := io.Copy(f, resp.Body)
+	var copyErr error
+	if pct, ok := chaosDownloadPercent(); ok {
+		copyErr = chaosCopy(f, resp.Body, resp.ContentLength, pct)
+	} else {
+		_, copyErr = io.Copy(f, resp.Body)
+	}
 	closeErr := f.Close()
 	if copyErr != nil {
 		_ = os.Remove(tmp)
@@ -297,6 +368,9 @@ func VerifySHA256(filePath, expectedHex string) error {
 	return nil
 }
 
+// maxManifestBytes caps a fetched manifest or signature; real ones are a few kilobytes.
+const maxManifestBytes = 1 << 20
+
 func fetchBytes(ctx context.Context, url string) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -313,5 +387,12 @@ func fetchBytes(ctx context.Context, url string) ([]byte, error) {
 		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
 	}
 
-	return io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxManifestBytes {
+		return nil, fmt.Errorf("%s exceeds %d bytes", url, maxManifestBytes)
+	}
+	return body, nil
 }
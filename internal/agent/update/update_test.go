@@ -85,3 +85,14 @@ func TestFetchManifest(t *testing.T) {
 		t.Fatalf("verification failed: %v", err)
 	}
 }
+
+func TestFetchManifestRejectsOversizedBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, maxManifestBytes+1))
+	}))
+	defer srv.Close()
+
+	if _, _, _, err := FetchManifest(context.Background(), srv.URL, "stable"); err == nil {
+		t.Fatalf("expected oversized manifest to be rejected")
+	}
+}
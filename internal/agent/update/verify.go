@@ -0,0 +1,188 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	releaseManifestName = "manifest.json"
+	releaseSigName      = "manifest.json.sig"
+)
+
+// Install check states.
+const (
+	CheckOK      = "ok"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+)
+
+// InstallCheck is the outcome of one installation consistency check.
+type InstallCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// InstallReport summarises VerifyInstall. Intact is false when any check failed.
+type InstallReport struct {
+	Intact     bool           `json:"intact"`
+	Version    string         `json:"version"`
+	ReleaseDir string         `json:"release_dir"`
+	Checks     []InstallCheck `json:"checks"`
+}
+
+// SaveReleaseManifest records the signed manifest a release was installed from.
+func SaveReleaseManifest(releaseDir string, raw, sig []byte) error {
+	if err := os.WriteFile(filepath.Join(releaseDir, releaseManifestName), raw, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(releaseDir, releaseSigName), sig, 0o644)
+}
+
+// LoadReleaseManifest reads the manifest recorded in a release directory.
+func LoadReleaseManifest(releaseDir string) (Manifest, []byte, []byte, error) {
+	raw, err := os.ReadFile(filepath.Join(releaseDir, releaseManifestName))
+	if err != nil {
+		return Manifest{}, nil, nil, err
+	}
+	sig, err := os.ReadFile(filepath.Join(releaseDir, releaseSigName))
+	if err != nil {
+		return Manifest{}, nil, nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return Manifest{}, nil, nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	return m, raw, sig, nil
+}
+
+// VerifyInstall checks the current release against its recorded manifest: symlink consistency,
+// binaries and compat links present, manifest signature (when pubKeyB64 is set), and binary hashes.
+func VerifyInstall(pubKeyB64 string) InstallReport {
+	rep := InstallReport{}
+	add := func(name, status, detail string) {
+		rep.Checks = append(rep.Checks, InstallCheck{Name: name, Status: status, Detail: detail})
+	}
+
+	target, err := os.Readlink(CurrentSymlink())
+	if err != nil {
+		add("current_symlink", CheckFailed, err.Error())
+		return rep.finish()
+	}
+	rep.ReleaseDir = target
+	rep.Version = VersionFromTarget(target)
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		add("current_symlink", CheckFailed, fmt.Sprintf("target %s is not a release directory", target))
+		return rep.finish()
+	}
+	if filepath.Dir(filepath.Clean(target)) != filepath.Clean(ReleasesDir()) {
+		add("current_symlink", CheckFailed, fmt.Sprintf("target %s is outside %s", target, ReleasesDir()))
+	} else {
+		add("current_symlink", CheckOK, target)
+	}
+
+	if st, err := LoadStatus(); err == nil && st.CurrentVersion != "" && st.CurrentVersion != rep.Version {
+		add("status", CheckFailed, fmt.Sprintf("status records %s but current points to %s", st.CurrentVersion, rep.Version))
+	}
+
+	if prev, err := os.Readlink(PreviousSymlink()); err != nil {
+		add("previous_symlink", CheckSkipped, "no previous release")
+	} else if info, err := os.Stat(prev); err != nil || !info.IsDir() {
+		add("previous_symlink", CheckFailed, fmt.Sprintf("target %s is missing", prev))
+	} else {
+		add("previous_symlink", CheckOK, prev)
+	}
+
+	manifest, raw, sig, manifestErr := LoadReleaseManifest(target)
+
+	// Releases installed for the docker child driver record images and hold no binaries.
+	imageOnly := map[string]string{}
+	binaries := []struct {
+		name, file, link string
+		art              Artifact
+	}{
+		{name: "chat", file: ChatBinaryName, link: "chat" + exeSuffix, art: manifest.Artifacts.Chat},
+		{name: "mcp", file: MCPBinaryName, link: "mcp" + exeSuffix, art: manifest.Artifacts.MCP},
+	}
+	for _, b := range binaries {
+		path := filepath.Join(target, b.file)
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err) && b.art.Image != "":
+			imageOnly[b.name] = b.art.Image
+			add("binary:"+b.name, CheckSkipped, "runs image "+b.art.Image)
+			continue
+		case err != nil:
+			add("binary:"+b.name, CheckFailed, err.Error())
+		case !isExecutable(info):
+			add("binary:"+b.name, CheckFailed, fmt.Sprintf("%s is not an executable file", path))
+		default:
+			add("binary:"+b.name, CheckOK, path)
+		}
+
+		if !linksTo(filepath.Join(target, b.link), path) {
+			add("compat_link:"+b.name, CheckFailed, fmt.Sprintf("%s should link to %s", b.link, b.file))
+		} else {
+			add("compat_link:"+b.name, CheckOK, "")
+		}
+	}
+
+	if manifestErr != nil {
+		add("manifest", CheckSkipped, "no recorded manifest; hashes cannot be verified")
+		return rep.finish()
+	}
+	if manifest.Version != rep.Version {
+		add("manifest", CheckFailed, fmt.Sprintf("manifest version %s does not match release %s", manifest.Version, rep.Version))
+	} else {
+		add("manifest", CheckOK, manifest.Version)
+	}
+
+	if pubKeyB64 == "" {
+		add("signature", CheckSkipped, "update public key not configured")
+	} else if err := VerifyManifest(raw, sig, pubKeyB64); err != nil {
+		add("signature", CheckFailed, err.Error())
+	} else {
+		add("signature", CheckOK, "")
+	}
+
+	for _, b := range binaries {
+		if image, ok := imageOnly[b.name]; ok {
+			add("sha256:"+b.name, CheckSkipped, "runs image "+image)
+			continue
+		}
+		if err := VerifySHA256(filepath.Join(target, b.file), b.art.SHA256); err != nil {
+			add("sha256:"+b.name, CheckFailed, err.Error())
+		} else {
+			add("sha256:"+b.name, CheckOK, b.art.SHA256)
+		}
+	}
+
+	return rep.finish()
+}
+
+// linksTo reports whether link is a symlink to file, or a hard link of it (see linkFile).
+func linksTo(link, file string) bool {
+	if target, err := os.Readlink(link); err == nil {
+		return filepath.Base(target) == filepath.Base(file)
+	}
+	li, err := os.Lstat(link)
+	if err != nil {
+		return false
+	}
+	fi, err := os.Stat(file)
+	return err == nil && os.SameFile(li, fi)
+}
+
+func (r InstallReport) finish() InstallReport {
+	r.Intact = true
+	for _, c := range r.Checks {
+		if c.Status == CheckFailed {
+			r.Intact = false
+		}
+	}
+	return r
+}
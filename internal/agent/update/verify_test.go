@@ -0,0 +1,135 @@
+package update
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func installRelease(t *testing.T, version string, priv ed25519.PrivateKey) string {
+	t.Helper()
+
+	dir := ReleaseDir(version)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	m := Manifest{Version: version}
+	for name, art := range map[string]*Artifact{ChatBinaryName: &m.Artifacts.Chat, MCPBinaryName: &m.Artifacts.MCP} {
+		data := []byte(name + "-" + version)
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o755); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		sum := sha256.Sum256(data)
+		art.SHA256 = hex.EncodeToString(sum[:])
+	}
+	if err := EnsureCompatSymlinks(dir); err != nil {
+		t.Fatalf("compat: %v", err)
+	}
+
+	raw, _ := json.Marshal(m)
+	if err := SaveReleaseManifest(dir, raw, ed25519.Sign(priv, raw)); err != nil {
+		t.Fatalf("save manifest: %v", err)
+	}
+	if _, err := UpdateSymlinks(dir); err != nil {
+		t.Fatalf("symlinks: %v", err)
+	}
+	return dir
+}
+
+func checkStatus(rep InstallReport, name string) string {
+	for _, c := range rep.Checks {
+		if c.Name == name {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+func TestVerifyInstallIntact(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+
+	installRelease(t, "1.0.0", priv)
+	installRelease(t, "1.1.0", priv)
+
+	rep := VerifyInstall(base64.StdEncoding.EncodeToString(pub))
+	if !rep.Intact || rep.Version != "1.1.0" {
+		t.Fatalf("expected intact 1.1.0, got %+v", rep)
+	}
+	for _, name := range []string{"current_symlink", "previous_symlink", "signature", "sha256:chat", "sha256:mcp"} {
+		if got := checkStatus(rep, name); got != CheckOK {
+			t.Fatalf("check %s: expected ok, got %q", name, got)
+		}
+	}
+}
+
+func TestVerifyInstallAcceptsHardLinkedCompat(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	dir := installRelease(t, "1.0.0", priv)
+
+	// Windows falls back to hard links when symlinks are not permitted.
+	link := filepath.Join(dir, "chat"+exeSuffix)
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(dir, ChatBinaryName), link); err != nil {
+		t.Skipf("hard links unavailable: %v", err)
+	}
+	if got := checkStatus(VerifyInstall(""), "compat_link:chat"); got != CheckOK {
+		t.Fatalf("hard-linked compat: expected ok, got %q", got)
+	}
+
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(link, []byte("stale"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := checkStatus(VerifyInstall(""), "compat_link:chat"); got != CheckFailed {
+		t.Fatalf("unlinked compat copy: expected failed, got %q", got)
+	}
+}
+
+func TestVerifyInstallDetectsTampering(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+
+	dir := installRelease(t, "1.0.0", priv)
+	if err := os.WriteFile(filepath.Join(dir, MCPBinaryName), []byte("evil"), 0o755); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "chat")); err != nil {
+		t.Fatalf("remove link: %v", err)
+	}
+
+	rep := VerifyInstall(base64.StdEncoding.EncodeToString(pub))
+	if rep.Intact {
+		t.Fatalf("expected tampering detected: %+v", rep)
+	}
+	if got := checkStatus(rep, "sha256:mcp"); got != CheckFailed {
+		t.Fatalf("expected mcp hash failure, got %q", got)
+	}
+	if got := checkStatus(rep, "compat_link:chat"); got != CheckFailed {
+		t.Fatalf("expected chat compat link failure, got %q", got)
+	}
+	if got := checkStatus(rep, "sha256:chat"); got != CheckOK {
+		t.Fatalf("expected chat hash ok, got %q", got)
+	}
+}
+
+func TestVerifyInstallWithoutCurrent(t *testing.T) {
+	t.Setenv("PAYRAM_AGENT_HOME", t.TempDir())
+
+	rep := VerifyInstall("")
+	if rep.Intact || checkStatus(rep, "current_symlink") != CheckFailed {
+		t.Fatalf("expected missing current to fail: %+v", rep)
+	}
+}
@@ -85,3 +85,35 @@ func TestIsCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckComponents(t *testing.T) {
+	m := Manifest{
+		Version:       "2.0.0",
+		Compatibility: Compatibility{Agent: Range{Min: "1.0.0"}},
+		Artifacts: Artifacts{
+			Chat: Artifact{Requires: map[string]Range{"mcp": {Min: "2.1.0"}}},
+			MCP:  Artifact{Version: "2.1.0", Requires: map[string]Range{"agent": {Min: "1.5.0"}}},
+		},
+	}
+
+	problems, warnings := CheckComponents(m, "v1.2.0")
+	if len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	if len(problems) != 1 || problems[0] != "mcp: Requires agent >= 1.5.0 (have 1.2.0)" {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+
+	if problems, _ := CheckComponents(m, "1.5.0"); len(problems) != 0 {
+		t.Fatalf("expected newer agent to satisfy constraints, got %v", problems)
+	}
+
+	m.Artifacts.MCP.Version = ""
+	if problems, _ := CheckComponents(m, "1.5.0"); len(problems) != 1 || problems[0] != "chat: Requires mcp >= 2.1.0 (have 2.0.0)" {
+		t.Fatalf("expected cross-component violation, got %v", problems)
+	}
+
+	if problems, warnings := CheckComponents(m, "dev"); len(warnings) != 2 || len(problems) != 1 {
+		t.Fatalf("expected dev agent to be unverifiable, got problems=%v warnings=%v", problems, warnings)
+	}
+}
@@ -1,13 +1,46 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/compress"
+	"github.com/payram/payram-analytics-mcp-server/internal/events"
+	"github.com/payram/payram-analytics-mcp-server/internal/httplimits"
+	"github.com/payram/payram-analytics-mcp-server/internal/jobs"
+	"github.com/payram/payram-analytics-mcp-server/internal/latency"
 	"github.com/payram/payram-analytics-mcp-server/internal/mcp"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
 	"github.com/payram/payram-analytics-mcp-server/internal/tools"
 )
 
-// NewToolbox builds the shared PayRam MCP toolbox.
+// NewToolbox builds the shared PayRam MCP toolbox. PAYRAM_MCP_DEBUG_REQUESTS=true adds
+// payram_debug_request, limited to the comma-separated path globs in PAYRAM_MCP_DEBUG_PATHS
+// (the group list and graph data when unset).
 func NewToolbox() *mcp.Toolbox {
-	return mcp.NewToolbox(
+	tb := mcp.NewToolbox(Tools()...)
+	if envBool("PAYRAM_MCP_DEBUG_REQUESTS") {
+		paths := tools.DefaultDebugPaths
+		if v := strings.TrimSpace(os.Getenv("PAYRAM_MCP_DEBUG_PATHS")); v != "" {
+			paths = nil
+			for _, p := range strings.Split(v, ",") {
+				if p = strings.TrimSpace(p); p != "" {
+					paths = append(paths, p)
+				}
+			}
+		}
+		tb.Register(tools.PayramDebugRequest(paths))
+	}
+	return tb
+}
+
+// Tools returns a fresh instance of every PayRam tool.
+func Tools() []mcp.Tool {
+	return []mcp.Tool{
 		// Core info tools
 		tools.PayramIntro(),
 		tools.PayramDocs(),
@@ -27,6 +60,7 @@ func NewToolbox() *mcp.Toolbox {
 		tools.PayramCurrencyBreakdown(),
 		tools.PayramPayingUsers(),
 		tools.PayramUserGrowth(),
+		tools.PayramRetention(),
 
 		// Transaction tools
 		tools.PayramRecentTransactions(),
@@ -36,7 +70,11 @@ func NewToolbox() *mcp.Toolbox {
 
 		// Comparison and analysis tools
 		tools.PayramComparePeriods(),
-	)
+		tools.PayramChanges(),
+
+		// Exports, run as background jobs on the HTTP server
+		tools.PayramExport(),
+	}
 }
 
 // NewMCPServer constructs an MCP server with the shared toolbox.
@@ -44,7 +82,129 @@ func NewMCPServer() *mcp.Server {
 	return mcp.NewServer(NewToolbox())
 }
 
+// RunMCPStdio serves an MCP session over stdin and stdout until stdin closes or ctx is done.
+func RunMCPStdio(ctx context.Context) error {
+	server := NewMCPServer()
+	server.SetMaskPII(envBool("PAYRAM_MCP_MASK_PII"))
+	server.SetReadOnly(envBool("READ_ONLY"))
+	return server.ServeStdio(ctx, os.Stdin, os.Stdout)
+}
+
 // RunMCPHTTP starts the MCP HTTP server on the provided address.
 func RunMCPHTTP(addr string) error {
-	return mcp.RunHTTP(NewMCPServer(), addr)
+	return RunMCPHTTPContext(context.Background(), addr)
+}
+
+// RunMCPHTTPContext starts the MCP HTTP server and shuts it down gracefully when ctx is done.
+func RunMCPHTTPContext(ctx context.Context, addr string) error {
+	server, err := newHTTPMCPServer()
+	if err != nil {
+		return err
+	}
+	return mcp.RunHTTPContext(ctx, server, addr)
+}
+
+// newHTTPMCPServer is NewMCPServer plus the tenant registry named by PAYRAM_MCP_TENANTS, if any,
+// with quota usage kept in PAYRAM_MCP_USAGE_FILE (memory only when unset), and the connection
+// limits set by the PAYRAM_MCP_*_TIMEOUT and PAYRAM_MCP_MAX_HEADER_BYTES variables, and the
+// response compression set by PAYRAM_MCP_COMPRESSION*.
+// PAYRAM_MCP_MASK_PII=true masks customer identifiers in tool output, and READ_ONLY=true
+// hides tools that change state. Background jobs are
+// kept in PAYRAM_MCP_JOBS_FILE (memory only when unset) and run by PAYRAM_MCP_JOB_WORKERS
+// workers for up to PAYRAM_MCP_JOB_TIMEOUT each. PayRam payment webhooks are accepted when
+// PAYRAM_MCP_WEBHOOK_KEY is set, or PAYRAM_MCP_WEBHOOKS=true with per-tenant webhook keys; the
+// newest PAYRAM_MCP_EVENTS_MAX are kept in PAYRAM_MCP_EVENTS_FILE (memory only when unset).
+// PAYRAM_MCP_GRAFANA=true serves a Grafana JSON datasource under /grafana, and
+// PAYRAM_MCP_STATS_WINDOW sets how far back /admin/tools/stats reaches.
+// PAYRAM_MCP_WARMUP=true prefetches PAYRAM_MCP_WARMUP_CALLS (mcp.DefaultWarmupCalls when
+// unset) on startup.
+func newHTTPMCPServer() (*mcp.Server, error) {
+	server := NewMCPServer()
+	limits, err := httplimits.FromEnv("PAYRAM_MCP", httplimits.Default)
+	if err != nil {
+		return nil, err
+	}
+	server.SetLimits(limits)
+	compression, err := compress.FromEnv("PAYRAM_MCP", compress.Default)
+	if err != nil {
+		return nil, err
+	}
+	server.SetCompression(compression)
+	server.SetMaskPII(envBool("PAYRAM_MCP_MASK_PII"))
+	server.SetReadOnly(envBool("READ_ONLY"))
+	if file := strings.TrimSpace(os.Getenv("PAYRAM_MCP_TENANTS")); file != "" {
+		reg, err := tenant.Load(file)
+		if err != nil {
+			return nil, fmt.Errorf("tenants: %w", err)
+		}
+		usage, err := tenant.OpenUsage(os.Getenv("PAYRAM_MCP_USAGE_FILE"))
+		if err != nil {
+			return nil, err
+		}
+		server.SetTenants(reg, usage)
+	}
+	opts := jobs.Options{}
+	if v := os.Getenv("PAYRAM_MCP_JOB_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid PAYRAM_MCP_JOB_WORKERS %q", v)
+		}
+		opts.Workers = n
+	}
+	if v := os.Getenv("PAYRAM_MCP_JOB_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid PAYRAM_MCP_JOB_TIMEOUT %q", v)
+		}
+		opts.Timeout = d
+	}
+	if v := os.Getenv("PAYRAM_MCP_STATS_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid PAYRAM_MCP_STATS_WINDOW %q", v)
+		}
+		latency.SetWindow(d)
+	}
+	queue, err := jobs.Open(os.Getenv("PAYRAM_MCP_JOBS_FILE"), opts)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: %w", err)
+	}
+	server.SetJobs(queue)
+
+	webhookKey := strings.TrimSpace(os.Getenv("PAYRAM_MCP_WEBHOOK_KEY"))
+	if webhookKey != "" || envBool("PAYRAM_MCP_WEBHOOKS") {
+		max := 0
+		if v := os.Getenv("PAYRAM_MCP_EVENTS_MAX"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid PAYRAM_MCP_EVENTS_MAX %q", v)
+			}
+			max = n
+		}
+		store, err := events.Open(os.Getenv("PAYRAM_MCP_EVENTS_FILE"), max)
+		if err != nil {
+			return nil, fmt.Errorf("events: %w", err)
+		}
+		server.SetWebhooks(store, webhookKey)
+	}
+	if envBool("PAYRAM_MCP_GRAFANA") {
+		server.SetGrafana(tools.GrafanaSource())
+	}
+	if envBool("PAYRAM_MCP_WARMUP") {
+		calls := mcp.DefaultWarmupCalls
+		if v := strings.TrimSpace(os.Getenv("PAYRAM_MCP_WARMUP_CALLS")); v != "" {
+			if calls, err = mcp.ParseWarmupCalls(v); err != nil {
+				return nil, fmt.Errorf("PAYRAM_MCP_WARMUP_CALLS: %w", err)
+			}
+		}
+		if err := server.SetWarmup(calls); err != nil {
+			return nil, fmt.Errorf("PAYRAM_MCP_WARMUP_CALLS: %w", err)
+		}
+	}
+	return server, nil
+}
+
+func envBool(key string) bool {
+	v := strings.ToLower(os.Getenv(key))
+	return v == "1" || v == "true"
 }
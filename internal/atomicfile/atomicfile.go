@@ -0,0 +1,40 @@
+// Package atomicfile replaces state files so that a crash leaves either the old or the new
+// contents on disk, never a partly written file.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile replaces file with data: it writes and syncs file+".tmp", renames it over file
+// and syncs the directory so the rename itself survives a crash. The file is created with
+// mode 0600.
+func WriteFile(file string, data []byte) error {
+	tmp := file + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		return err
+	}
+	// Directories cannot be synced on every platform (Windows refuses), and the data itself
+	// is already on disk, so a failure here is not reported.
+	if dir, err := os.Open(filepath.Dir(file)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
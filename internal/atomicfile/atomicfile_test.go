@@ -0,0 +1,34 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+	// A write that crashed before its rename leaves a partial temporary file behind.
+	if err := os.WriteFile(file+".tmp", []byte(`{"partial`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{`{"v":1}`, `{"v":2}`} {
+		if err := WriteFile(file, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(file)
+		if err != nil || string(got) != data {
+			t.Fatalf("read %q, %v; want %q", got, err, data)
+		}
+	}
+	if _, err := os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind: %v", err)
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode %v, %v", info.Mode(), err)
+	}
+
+	if err := WriteFile(filepath.Join(t.TempDir(), "missing", "state.json"), []byte("x")); err == nil {
+		t.Fatal("wrote into a missing directory")
+	}
+}
@@ -0,0 +1,302 @@
+package chatapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+	"github.com/payram/payram-analytics-mcp-server/internal/tools"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	authPath = "/auth/"
+
+	// csrfHeader must accompany cookie-authenticated requests. Browsers only send custom
+	// headers cross-origin after a CORS preflight, which this server never approves.
+	csrfHeader = "X-Requested-With"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// Username is set for web UI sessions and SSO tokens; API key callers have none.
+	Username       string
+	AnalyticsToken string
+	// Language is the signed-in user's configured answer language.
+	Language string
+	// Tools lists the tool patterns an SSO caller's scopes grant; it only applies when restricted.
+	Tools []string
+	// Tenant is set when the caller sent a valid X-Tenant-Key and tenants are configured.
+	Tenant     *tenant.Tenant
+	restricted bool
+	viaOIDC    bool
+}
+
+// isOperator reports whether the caller used the API key (or no auth is configured), as
+// opposed to a web session, SSO token or tenant key.
+func (id Identity) isOperator() bool {
+	return id.Username == "" && !id.viaOIDC && id.Tenant == nil
+}
+
+// allows reports whether the caller may invoke tool.
+func (id Identity) allows(tool string) bool {
+	// The raw analytics request tool is for operators, and SSO callers whose scopes grant it
+	// by name.
+	if tool == tools.DebugRequestTool {
+		return id.isOperator() || (id.restricted && slices.Contains(id.Tools, tool))
+	}
+	if !id.restricted {
+		return true
+	}
+	for _, p := range id.Tools {
+		if ok, _ := path.Match(p, tool); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+func withIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFrom returns the caller stored on ctx by the handler's auth check.
+func IdentityFrom(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// SetOIDC enables JWT bearer authentication. Authorization then carries the SSO token, and
+// callers pass their analytics token in X-Analytics-Token instead.
+func (h *Handler) SetOIDC(v *OIDCVerifier) {
+	h.oidc = v
+}
+
+// SetUsers enables web UI sign-in for the given users. With users configured, every API
+// request needs either the API key or a session cookie.
+func (h *Handler) SetUsers(users *UserStore) {
+	h.users = users
+}
+
+// authenticate checks the API key or session cookie and returns r carrying the caller's
+// Identity and any tenant headers to forward to MCP. On failure it writes the 401 (403 for a
+// session cookie sent without the csrfHeader) and returns false.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	id, ok := h.identify(r)
+	if !ok && h.missingCSRFHeader(r) {
+		h.logger.Warn("session cookie without " + csrfHeader)
+		writeError(w, http.StatusForbidden, errTypeInvalidRequest, "missing_header", csrfHeader+" header is required")
+		return r, false
+	}
+	if !ok {
+		h.logger.Warn("unauthorized request")
+		writeError(w, http.StatusUnauthorized, errTypeAuthentication, "invalid_api_key", "invalid or missing API key")
+		return r, false
+	}
+	if h.tenants != nil && r.Header.Get(tenant.KeyHeader) != "" {
+		t, err := h.tenants.Resolve(r)
+		if err != nil {
+			h.logger.Warnf("tenant rejected: %v", err)
+			writeError(w, http.StatusUnauthorized, errTypeAuthentication, "invalid_tenant_key", err.Error())
+			return r, false
+		}
+		id.Tenant = t
+	}
+	ctx := withIdentity(r.Context(), id)
+	if tid, key := r.Header.Get(tenant.IDHeader), r.Header.Get(tenant.KeyHeader); tid != "" || key != "" {
+		ctx = chatserver.WithTenant(ctx, tid, key)
+	}
+	if lang := h.language(r, id); lang != "" {
+		ctx = i18n.WithLanguage(ctx, lang)
+	}
+	return r.WithContext(ctx), true
+}
+
+// language is the caller's answer language: X-Language, else the user's, the tenant's, or
+// the handler default. An invalid header is logged and ignored.
+func (h *Handler) language(r *http.Request, id Identity) string {
+	if v := r.Header.Get(i18n.Header); v != "" {
+		if lang, ok := i18n.Parse(v); ok {
+			return lang
+		}
+		h.logger.Warnf("ignoring invalid %s %q", i18n.Header, v)
+	}
+	switch {
+	case id.Language != "":
+		return id.Language
+	case id.Tenant != nil && id.Tenant.Language != "":
+		return id.Tenant.Language
+	}
+	return h.defaultLanguage
+}
+
+func (h *Handler) identify(r *http.Request) (Identity, bool) {
+	if v := strings.TrimSpace(r.Header.Get("X-MCP-Key")); v != "" && h.apiKey != "" {
+		return Identity{}, v == h.apiKey
+	}
+	if h.oidc != nil {
+		if token := bearerToken(r.Header.Get("Authorization")); looksLikeJWT(token) {
+			id, err := h.oidc.Verify(r.Context(), token)
+			if err != nil {
+				h.logger.Warnf("sso token rejected: %v", err)
+				return Identity{}, false
+			}
+			return id, true
+		}
+	}
+	if h.users != nil {
+		if c, err := r.Cookie(sessionCookie); err == nil && r.Header.Get(csrfHeader) != "" {
+			if u, ok := h.users.session(c.Value); ok {
+				return Identity{Username: u.Username, AnalyticsToken: u.AnalyticsToken, Language: u.Language}, true
+			}
+		}
+	}
+	return Identity{}, h.apiKey == "" && h.users == nil && h.oidc == nil
+}
+
+// missingCSRFHeader reports whether r carries a session cookie but not the csrfHeader, as a
+// cross-site form or script would.
+func (h *Handler) missingCSRFHeader(r *http.Request) bool {
+	if h.users == nil || r.Header.Get(csrfHeader) != "" {
+		return false
+	}
+	_, err := r.Cookie(sessionCookie)
+	return err == nil
+}
+
+// log returns the handler logger tagged with the caller's username and tenant, if any.
+func (h *Handler) log(ctx context.Context) *logrus.Entry {
+	id, _ := IdentityFrom(ctx)
+	fields := logrus.Fields{}
+	if id.Username != "" {
+		fields["user"] = id.Username
+	}
+	if id.Tenant != nil {
+		fields["tenant"] = id.Tenant.ID
+	}
+	if len(fields) == 0 {
+		return h.logger
+	}
+	return h.logger.WithFields(fields)
+}
+
+// analyticsToken is the token injected into tool calls: the caller's bearer token (or
+// X-Analytics-Token for SSO callers), else the signed-in user's configured token. Empty leaves
+// the MCP server default in place.
+func analyticsToken(r *http.Request) string {
+	id, _ := IdentityFrom(r.Context())
+	if id.viaOIDC {
+		return strings.TrimSpace(r.Header.Get(analyticsTokenHeader))
+	}
+	if t := bearerToken(r.Header.Get("Authorization")); t != "" {
+		return t
+	}
+	return id.AnalyticsToken
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type authStatus struct {
+	AuthRequired bool      `json:"auth_required"`
+	SignIn       bool      `json:"sign_in"`
+	Username     string    `json:"username,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
+}
+
+// handleAuth serves the web UI session endpoints:
+//
+//	GET  /auth/me      whether auth is required, whether sign-in is enabled, and who is signed in
+//	POST /auth/login   {username, password}; sets the session cookie
+//	POST /auth/logout  ends the session
+func (h *Handler) handleAuth(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, authPath) {
+	case "me":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		status := authStatus{AuthRequired: h.users != nil || h.apiKey != "" || h.oidc != nil, SignIn: h.users != nil}
+		if h.users != nil {
+			if c, err := r.Cookie(sessionCookie); err == nil {
+				if u, ok := h.users.session(c.Value); ok {
+					status.Username = u.Username
+				}
+			}
+		}
+		writeJSON(w, status, http.StatusOK)
+	case "login":
+		h.handleLogin(w, r)
+	case "logout":
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		if c, err := r.Cookie(sessionCookie); err == nil && h.users != nil {
+			if u, ok := h.users.session(c.Value); ok {
+				h.logger.WithField("user", u.Username).Info("signed out")
+			}
+			h.users.endSession(c.Value)
+		}
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "not_found", "not found")
+	}
+}
+
+func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	if h.users == nil {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "not_found", "sign-in is not enabled")
+		return
+	}
+	if r.Header.Get(csrfHeader) == "" {
+		writeError(w, http.StatusForbidden, errTypeInvalidRequest, "missing_header", csrfHeader+" header is required")
+		return
+	}
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_json", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	username := strings.TrimSpace(req.Username)
+	u, ok := h.users.verify(username, req.Password)
+	if !ok {
+		h.logger.WithField("user", username).Warn("sign-in failed")
+		writeError(w, http.StatusUnauthorized, errTypeAuthentication, "invalid_credentials", "invalid username or password")
+		return
+	}
+	id, expires, err := h.users.newSession(u.Username)
+	if err != nil {
+		h.logger.Errorf("create session: %v", err)
+		writeError(w, http.StatusInternalServerError, errTypeAPI, "session_failed", "could not create session")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	h.logger.WithField("user", u.Username).Info("signed in")
+	writeJSON(w, authStatus{AuthRequired: true, SignIn: true, Username: u.Username, ExpiresAt: expires}, http.StatusOK)
+}
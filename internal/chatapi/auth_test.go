@@ -0,0 +1,139 @@
+package chatapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newUsersHandler returns a handler where alice (password "pw") signs in, and its user store.
+func newUsersHandler(t *testing.T) (*UserStore, *http.ServeMux) {
+	t.Helper()
+	users, err := LoadUsers(writeUsers(t, User{Username: "alice", PasswordHash: quickHash(t, "pw")}), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, mux := newTestHandler(t, newFakeLLM(t, nil), newFakeMCP(t))
+	h.SetUsers(users)
+	return users, mux
+}
+
+// login signs alice in with password and returns the response.
+func login(mux http.Handler, password string, header ...string) *httptest.ResponseRecorder {
+	return serve(mux, http.MethodPost, "/auth/login", loginRequest{Username: "alice", Password: password}, header...)
+}
+
+func sessionOf(t *testing.T, rec *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookie {
+			return c
+		}
+	}
+	t.Fatalf("no session cookie: %d %s", rec.Code, rec.Body)
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	_, mux := newUsersHandler(t)
+
+	if rec := login(mux, "pw"); rec.Code != http.StatusForbidden || errorCode(decodeError(t, rec)) != "missing_header" {
+		t.Fatalf("login without %s: %d %s", csrfHeader, rec.Code, rec.Body)
+	}
+	rec := login(mux, "wrong", csrfHeader, "fetch")
+	if rec.Code != http.StatusUnauthorized || errorCode(decodeError(t, rec)) != "invalid_credentials" || len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("wrong password: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = login(mux, "pw", csrfHeader, "fetch")
+	c := sessionOf(t, rec)
+	if !c.HttpOnly || c.SameSite != http.SameSiteStrictMode || c.Path != "/" {
+		t.Fatalf("cookie %+v", c)
+	}
+	var status authStatus
+	decodeJSON(t, rec, &status)
+	if status.Username != "alice" || !status.AuthRequired || !status.SignIn {
+		t.Fatalf("login answered %+v", status)
+	}
+	decodeJSON(t, serve(mux, http.MethodGet, "/auth/me", nil, "Cookie", c.String()), &status)
+	if status.Username != "alice" {
+		t.Fatalf("/auth/me = %+v", status)
+	}
+}
+
+func TestSessionAuthentication(t *testing.T) {
+	users, mux := newUsersHandler(t)
+	cookie := sessionOf(t, login(mux, "pw", csrfHeader, "fetch")).String()
+
+	cases := []struct {
+		name   string
+		header []string
+		status int
+		code   string
+	}{
+		{"session with header", []string{"Cookie", cookie, csrfHeader, "fetch"}, http.StatusOK, ""},
+		{"session without header", []string{"Cookie", cookie}, http.StatusForbidden, "missing_header"},
+		{"unknown session", []string{"Cookie", sessionCookie + "=forged", csrfHeader, "fetch"}, http.StatusUnauthorized, "invalid_api_key"},
+		{"no credentials", nil, http.StatusUnauthorized, "invalid_api_key"},
+	}
+	for _, c := range cases {
+		rec := chat(mux, "hi", c.header...)
+		if rec.Code != c.status {
+			t.Errorf("%s: status %d, want %d: %s", c.name, rec.Code, c.status, rec.Body)
+			continue
+		}
+		if c.code != "" {
+			if got := errorCode(decodeError(t, rec)); got != c.code {
+				t.Errorf("%s: code %q, want %q", c.name, got, c.code)
+			}
+		}
+	}
+
+	for id, s := range users.sessions {
+		users.sessions[id] = session{username: s.username, expires: time.Now().Add(-time.Minute)}
+	}
+	if rec := chat(mux, "hi", "Cookie", cookie, csrfHeader, "fetch"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expired session: status %d", rec.Code)
+	}
+}
+
+func TestLogoutRevokesTheSession(t *testing.T) {
+	_, mux := newUsersHandler(t)
+	cookie := sessionOf(t, login(mux, "pw", csrfHeader, "fetch")).String()
+
+	rec := serve(mux, http.MethodPost, "/auth/logout", nil, "Cookie", cookie)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("logout: %d", rec.Code)
+	}
+	if cleared := sessionOf(t, rec); cleared.Value != "" || cleared.MaxAge >= 0 {
+		t.Fatalf("logout left cookie %+v", cleared)
+	}
+	// A client that kept the old cookie is signed out too.
+	if rec := chat(mux, "hi", "Cookie", cookie, csrfHeader, "fetch"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("after logout: status %d", rec.Code)
+	}
+	var status authStatus
+	decodeJSON(t, serve(mux, http.MethodGet, "/auth/me", nil, "Cookie", cookie), &status)
+	if status.Username != "" {
+		t.Fatalf("/auth/me after logout = %+v", status)
+	}
+}
+
+func TestAPIKeyDoesNotNeedTheCSRFHeader(t *testing.T) {
+	users, err := LoadUsers(writeUsers(t, User{Username: "alice", PasswordHash: quickHash(t, "pw")}), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(quietLogger(), "secret", "sk-test", "gpt-4o-mini", newFakeLLM(t, nil).URL, newFakeMCP(t).URL)
+	h.SetUsers(users)
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	if rec := chat(mux, "hi", "X-MCP-Key", "secret", "Cookie", sessionCookie+"=stale"); rec.Code != http.StatusOK {
+		t.Fatalf("api key with a stale cookie: %d %s", rec.Code, rec.Body)
+	}
+	if rec := chat(mux, "hi", "X-MCP-Key", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong api key: %d", rec.Code)
+	}
+}
@@ -0,0 +1,145 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	brandingPath = "/v1/branding"
+	// maxBrandingText caps each free-text branding field, in characters, since all of them
+	// end up in every system prompt.
+	maxBrandingText = 500
+)
+
+// Branding presents the assistant under a deployment's own name and house style. It is
+// added to the system prompt and served to the web UI.
+type Branding struct {
+	// AssistantName is what the assistant calls itself, and the UI title.
+	AssistantName string `json:"assistant_name"`
+	// Company is whose assistant it is, in place of PayRam.
+	Company string `json:"company,omitempty"`
+	// Greeting is the UI's opening message.
+	Greeting string `json:"greeting,omitempty"`
+	// CurrencySymbols maps currency codes to the symbols amounts in them are written with,
+	// e.g. "USD" to "$".
+	CurrencySymbols map[string]string `json:"currency_symbols,omitempty"`
+	// DateFormat is how dates are written, e.g. "DD/MM/YYYY".
+	DateFormat string `json:"date_format,omitempty"`
+	// Tone describes the voice of answers, e.g. "friendly and informal".
+	Tone string `json:"tone,omitempty"`
+}
+
+// LoadBranding reads a branding file. An empty path keeps the PayRam branding and returns
+// nil.
+func LoadBranding(file string) (*Branding, error) {
+	if strings.TrimSpace(file) == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read branding: %w", err)
+	}
+	var b Branding
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("decode branding: %w", err)
+	}
+	if err := b.validate(); err != nil {
+		return nil, fmt.Errorf("branding %s: %w", file, err)
+	}
+	return &b, nil
+}
+
+func (b *Branding) validate() error {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"assistant_name", &b.AssistantName},
+		{"company", &b.Company},
+		{"greeting", &b.Greeting},
+		{"date_format", &b.DateFormat},
+		{"tone", &b.Tone},
+	}
+	for _, f := range fields {
+		*f.value = strings.TrimSpace(*f.value)
+		if utf8.RuneCountInString(*f.value) > maxBrandingText {
+			return fmt.Errorf("%s must be at most %d characters", f.name, maxBrandingText)
+		}
+	}
+	if b.AssistantName == "" {
+		return fmt.Errorf("assistant_name is required")
+	}
+	symbols := map[string]string{}
+	for code, sym := range b.CurrencySymbols {
+		code, sym = strings.ToUpper(strings.TrimSpace(code)), strings.TrimSpace(sym)
+		if code == "" || sym == "" || utf8.RuneCountInString(sym) > 8 {
+			return fmt.Errorf("currency_symbols: %q: %q is not a currency code and a symbol of up to 8 characters", code, sym)
+		}
+		symbols[code] = sym
+	}
+	b.CurrencySymbols = symbols
+	return nil
+}
+
+// SetBranding presents the assistant as b in the system prompt and the UI; nil keeps the
+// PayRam branding.
+func (h *Handler) SetBranding(b *Branding) {
+	h.branding = b
+}
+
+// persona returns the system prompt instructions for b, or "" without branding.
+func (b *Branding) persona() string {
+	if b == nil {
+		return ""
+	}
+	var s strings.Builder
+	s.WriteString("\n\nBRANDING:\n")
+	if b.Company != "" {
+		fmt.Fprintf(&s, "- You are %s, the analytics assistant of %s. Never call yourself PayRam's assistant; PayRam is only the payment platform behind the data.\n", b.AssistantName, b.Company)
+	} else {
+		fmt.Fprintf(&s, "- Your name is %s. Introduce yourself by that name.\n", b.AssistantName)
+	}
+	if b.Tone != "" {
+		fmt.Fprintf(&s, "- Tone: %s.\n", b.Tone)
+	}
+	if b.DateFormat != "" {
+		fmt.Fprintf(&s, "- Write dates as %s.\n", b.DateFormat)
+	}
+	codes := make([]string, 0, len(b.CurrencySymbols))
+	for code := range b.CurrencySymbols {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		fmt.Fprintf(&s, "- Write %s amounts with the %s symbol instead of the code.\n", code, b.CurrencySymbols[code])
+	}
+	return strings.TrimRight(s.String(), "\n")
+}
+
+// BrandingInfo is the body of GET /v1/branding.
+type BrandingInfo struct {
+	Object        string `json:"object"`
+	AssistantName string `json:"assistant_name"`
+	Company       string `json:"company,omitempty"`
+	Greeting      string `json:"greeting,omitempty"`
+}
+
+// handleBranding tells the UI what to call the assistant: GET /v1/branding. It needs no
+// credentials, since the sign-in page is branded too.
+func (h *Handler) handleBranding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	info := BrandingInfo{Object: "branding", AssistantName: "PayRam Analytics"}
+	if b := h.branding; b != nil {
+		info.AssistantName, info.Company, info.Greeting = b.AssistantName, b.Company, b.Greeting
+	}
+	writeJSON(w, info, http.StatusOK)
+}
@@ -0,0 +1,103 @@
+package chatapi
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadBranding(t *testing.T) {
+	if b, err := LoadBranding(""); err != nil || b != nil {
+		t.Fatalf("no file: %+v, %v", b, err)
+	}
+	dir := t.TempDir()
+	write := func(body string) string {
+		file := filepath.Join(dir, "branding.json")
+		if err := os.WriteFile(file, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	b, err := LoadBranding(write(`{"assistant_name":"  Ledger  ","company":"Acme","greeting":"Hi!","tone":" friendly ",
+		"date_format":"DD/MM/YYYY","currency_symbols":{" usd ":" $ ","eur":"€"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Branding{AssistantName: "Ledger", Company: "Acme", Greeting: "Hi!", Tone: "friendly", DateFormat: "DD/MM/YYYY",
+		CurrencySymbols: map[string]string{"USD": "$", "EUR": "€"}}
+	if !reflect.DeepEqual(b, want) {
+		t.Fatalf("loaded %+v, want %+v", b, want)
+	}
+
+	for body, want := range map[string]string{
+		`{"company":"Acme"}`:       "assistant_name is required",
+		`{"assistant_name":"   "}`: "assistant_name is required",
+		`{"assistant_name":"x","tone":"` + strings.Repeat("é", maxBrandingText+1) + `"}`: "tone must be at most 500 characters",
+		`{"assistant_name":"x","currency_symbols":{"USD":""}}`:                           "currency_symbols",
+		`{"assistant_name":"x","currency_symbols":{"":"$"}}`:                             "currency_symbols",
+		`{"assistant_name":"x","currency_symbols":{"USD":"dollars!!"}}`:                  "currency_symbols",
+		`[]`: "decode branding",
+	} {
+		if _, err := LoadBranding(write(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%.60s: err = %v, want %q", body, err, want)
+		}
+	}
+	if _, err := LoadBranding(filepath.Join(dir, "missing.json")); err == nil || !strings.Contains(err.Error(), "read branding") {
+		t.Errorf("missing file: %v", err)
+	}
+}
+
+func TestBrandingPersona(t *testing.T) {
+	var none *Branding
+	if got := none.persona(); got != "" {
+		t.Fatalf("persona without branding: %q", got)
+	}
+	b := &Branding{AssistantName: "Ledger", Company: "Acme", Tone: "friendly", DateFormat: "DD/MM/YYYY",
+		CurrencySymbols: map[string]string{"USD": "$", "EUR": "€"}}
+	want := "\n\nBRANDING:\n" +
+		"- You are Ledger, the analytics assistant of Acme. Never call yourself PayRam's assistant; PayRam is only the payment platform behind the data.\n" +
+		"- Tone: friendly.\n" +
+		"- Write dates as DD/MM/YYYY.\n" +
+		"- Write EUR amounts with the € symbol instead of the code.\n" +
+		"- Write USD amounts with the $ symbol instead of the code."
+	if got := b.persona(); got != want {
+		t.Fatalf("persona:\n%s\nwant:\n%s", got, want)
+	}
+	if got := (&Branding{AssistantName: "Ledger"}).persona(); got != "\n\nBRANDING:\n- Your name is Ledger. Introduce yourself by that name." {
+		t.Fatalf("name-only persona %q", got)
+	}
+}
+
+func TestBrandingIsServedAndPrompted(t *testing.T) {
+	llm := newFakeLLM(t, nil)
+	h := NewHandler(quietLogger(), "api-key", "sk-test", "gpt-4o-mini", llm.URL, newFakeMCP(t).URL)
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	// The sign-in page is branded too, so no credentials are needed.
+	var info BrandingInfo
+	decodeJSON(t, serve(mux, http.MethodGet, brandingPath, nil), &info)
+	if info != (BrandingInfo{Object: "branding", AssistantName: "PayRam Analytics"}) {
+		t.Fatalf("default branding %+v", info)
+	}
+
+	b := &Branding{AssistantName: "Ledger", Company: "Acme", Greeting: "Ask me about Acme's payments.", Tone: "formal"}
+	h.SetBranding(b)
+	decodeJSON(t, serve(mux, http.MethodGet, brandingPath, nil), &info)
+	if info != (BrandingInfo{Object: "branding", AssistantName: "Ledger", Company: "Acme", Greeting: "Ask me about Acme's payments."}) {
+		t.Fatalf("branding %+v", info)
+	}
+	if rec := serve(mux, http.MethodPost, brandingPath, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST: %d", rec.Code)
+	}
+
+	decodeAnswer(t, chat(mux, "who are you?", "X-MCP-Key", "api-key"))
+	system := llm.calls()[0].Messages[0].Content
+	if !strings.HasPrefix(system, basePrompt) || !strings.HasSuffix(system, b.persona()) {
+		t.Fatalf("system prompt does not end with the persona: %.80q", system[len(basePrompt):])
+	}
+}
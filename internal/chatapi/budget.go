@@ -0,0 +1,185 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// defaultOutputReserve is kept free for the completion when the caller sets no token limit.
+	defaultOutputReserve = 4096
+	// minToolTokens is the smallest size a tool output is truncated to.
+	minToolTokens = 256
+	// Per-message and per-reply overheads of the OpenAI chat format.
+	messageOverhead = 3
+	replyOverhead   = 3
+	truncatedNote   = "\n[truncated: tool output exceeded the model context budget]"
+)
+
+// estimateTokens approximates the tiktoken (cl100k/o200k) count of s without the vocabulary:
+// letter runs count about one token per four characters, digits group in threes, and other
+// symbols and non-Latin characters count one each. Whitespace folds into the following word.
+func estimateTokens(s string) int {
+	n := 0
+	letters, digits := 0, 0
+	flush := func() {
+		n += (letters + 3) / 4
+		n += (digits + 2) / 3
+		letters, digits = 0, 0
+	}
+	for _, r := range s {
+		switch {
+		case r < utf8.RuneSelf && unicode.IsLetter(r):
+			if digits > 0 {
+				flush()
+			}
+			letters++
+		case unicode.IsDigit(r):
+			if letters > 0 {
+				flush()
+			}
+			digits++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			n++
+		}
+	}
+	flush()
+	return n
+}
+
+func estimateMessage(m OAChatMessage) int {
+	n := messageOverhead + estimateTokens(m.Role) + estimateTokens(m.Content)
+	if m.Name != "" {
+		n += 1 + estimateTokens(m.Name)
+	}
+	for _, tc := range m.ToolCalls {
+		n += messageOverhead + estimateTokens(tc.Function.Name) + estimateTokens(tc.Function.Arguments)
+	}
+	return n
+}
+
+func estimateTools(tools []OATool) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	raw, err := json.Marshal(tools)
+	if err != nil {
+		return 0
+	}
+	return estimateTokens(string(raw))
+}
+
+func estimateMessages(messages []OAChatMessage) int {
+	n := replyOverhead
+	for _, m := range messages {
+		n += estimateMessage(m)
+	}
+	return n
+}
+
+// contextTrim describes what fitContext removed.
+type contextTrim struct {
+	Budget    int
+	Before    int
+	After     int
+	Dropped   int
+	Truncated int
+}
+
+func (t contextTrim) changed() bool { return t.Dropped > 0 || t.Truncated > 0 }
+
+// fitContext trims messages so the request fits the model's context window: oversized tool
+// outputs are truncated first, then the oldest turns are dropped. Leading system messages and
+// everything from the last user message on are always kept.
+func (p ModelPolicy) fitContext(model string, messages []OAChatMessage, tools []OATool, params SamplingParams) ([]OAChatMessage, contextTrim) {
+	reserve := defaultOutputReserve
+	if params.MaxCompletionTokens != nil {
+		reserve = *params.MaxCompletionTokens
+	} else if params.MaxTokens != nil {
+		reserve = *params.MaxTokens
+	}
+	trim := contextTrim{Budget: p.contextWindow(model) - reserve - estimateTools(tools)}
+	trim.Before = estimateMessages(messages)
+	trim.After = trim.Before
+	if trim.Before <= trim.Budget {
+		return messages, trim
+	}
+
+	out := make([]OAChatMessage, len(messages))
+	copy(out, messages)
+
+	// Cap each tool output at a quarter of the budget.
+	toolCap := max(trim.Budget/4, minToolTokens)
+	for i := range out {
+		if capToolMessage(&out[i], toolCap) {
+			trim.Truncated++
+		}
+	}
+
+	// Drop oldest turns, never splitting an assistant tool call from its results.
+	start := 0
+	for start < len(out) && out[start].Role == "system" {
+		start++
+	}
+	lastUser := len(out)
+	for i := len(out) - 1; i >= start; i-- {
+		if out[i].Role == "user" {
+			lastUser = i
+			break
+		}
+	}
+	total := estimateMessages(out)
+	cut := start
+	for total > trim.Budget && cut < lastUser {
+		total -= estimateMessage(out[cut])
+		cut++
+	}
+	for cut < lastUser && out[cut].Role == "tool" {
+		total -= estimateMessage(out[cut])
+		cut++
+	}
+	if cut > start {
+		trim.Dropped = cut - start
+		out = append(out[:start], out[cut:]...)
+	}
+
+	// Still over: share what is left evenly between the remaining tool outputs.
+	if total > trim.Budget {
+		toolTokens, tools := 0, 0
+		for _, m := range out {
+			if m.Role == "tool" {
+				toolTokens += estimateMessage(m)
+				tools++
+			}
+		}
+		if tools > 0 {
+			share := max((trim.Budget-(total-toolTokens))/tools, minToolTokens)
+			for i := range out {
+				if capToolMessage(&out[i], share) {
+					trim.Truncated++
+				}
+			}
+		}
+	}
+	trim.After = estimateMessages(out)
+	return out, trim
+}
+
+// capToolMessage truncates a tool message's content to roughly limit tokens.
+func capToolMessage(m *OAChatMessage, limit int) bool {
+	if m.Role != "tool" {
+		return false
+	}
+	tokens := estimateTokens(m.Content)
+	if tokens <= limit {
+		return false
+	}
+	keep := len(m.Content) * limit / tokens
+	m.Content, _ = truncate(m.Content, keep)
+	m.Content += truncatedNote
+	return true
+}
@@ -0,0 +1,161 @@
+package chatapi
+
+import (
+	"math"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	cases := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"hello", 2},
+		{"hello world", 4},
+		{"12345", 2},
+		{"USDC: 1,204.50", 7},
+		{"日本", 2},
+	}
+	for _, c := range cases {
+		if got := estimateTokens(c.s); got != c.want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", c.s, got, c.want)
+		}
+	}
+}
+
+// smallWindow is a policy whose only model has a context of window tokens.
+func smallWindow(window int) ModelPolicy {
+	return ModelPolicy{Models: []ModelRule{{Match: "*", ContextWindow: window, Price: &ModelPrice{Input: 0.15, Output: 0.60}}}}
+}
+
+func TestFitContextLeavesShortConversations(t *testing.T) {
+	messages := []OAChatMessage{{Role: "system", Content: "be brief"}, {Role: "user", Content: "hi"}}
+	out, trim := smallWindow(8000).fitContext("m", messages, nil, SamplingParams{MaxTokens: ptr(100)})
+	if trim.changed() || len(out) != 2 || trim.Before > trim.Budget {
+		t.Fatalf("trimmed %+v to %+v", trim, out)
+	}
+}
+
+func TestFitContextDropsOldestTurnsAndCapsToolOutput(t *testing.T) {
+	long := strings.Repeat("payments ", 3000)
+	messages := []OAChatMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "first question " + long},
+		{Role: "assistant", ToolCalls: []OAToolCall{{ID: "a", Function: OAToolCallFunc{Name: "payram_numbers_summary", Arguments: "{}"}}}},
+		{Role: "tool", ToolCallID: "a", Content: long},
+		{Role: "assistant", Content: "first answer"},
+		{Role: "user", Content: "latest question"},
+		{Role: "assistant", ToolCalls: []OAToolCall{{ID: "b", Function: OAToolCallFunc{Name: "payram_numbers_summary", Arguments: "{}"}}}},
+		{Role: "tool", ToolCallID: "b", Content: long},
+	}
+	p := smallWindow(3000)
+	params := SamplingParams{MaxTokens: ptr(500)}
+	out, trim := p.fitContext("m", messages, nil, params)
+
+	if trim.Dropped != 1 || trim.Truncated != 2 || trim.After > trim.Budget || trim.Before <= trim.Budget {
+		t.Fatalf("trim %+v", trim)
+	}
+	if len(out) != 7 || out[0].Role != "system" || out[1].Role != "assistant" || out[4].Content != "latest question" {
+		t.Fatalf("kept %+v", out)
+	}
+	for _, i := range []int{2, 6} {
+		if !strings.HasSuffix(out[i].Content, truncatedNote) {
+			t.Fatalf("tool output %d not capped: %d bytes", i, len(out[i].Content))
+		}
+	}
+	if messages[7].Content != long {
+		t.Fatal("fitContext changed its input")
+	}
+}
+
+func TestFitContextNeverSplitsToolResultsFromTheirCall(t *testing.T) {
+	messages := []OAChatMessage{
+		{Role: "user", Content: strings.Repeat("old ", 2000)},
+		{Role: "assistant", ToolCalls: []OAToolCall{{ID: "a"}}},
+		{Role: "tool", ToolCallID: "a", Content: "x"},
+		{Role: "tool", ToolCallID: "a", Content: "y"},
+		{Role: "user", Content: "now"},
+	}
+	out, _ := smallWindow(1500).fitContext("m", messages, nil, SamplingParams{MaxTokens: ptr(100)})
+	if out[0].Role == "tool" {
+		t.Fatalf("kept a tool result without its call: %+v", out)
+	}
+}
+
+func TestChatTrimsContextToTheModelWindow(t *testing.T) {
+	llm := newFakeLLM(t, nil)
+	h, mux := newTestHandler(t, llm, newFakeMCP(t))
+	h.SetModelPolicy(smallWindow(2500))
+
+	history := []OAChatMessage{
+		{Role: "user", Content: strings.Repeat("old turn ", 2000)},
+		{Role: "assistant", Content: "old answer"},
+		{Role: "user", Content: "latest question"},
+	}
+	decodeAnswer(t, serve(mux, http.MethodPost, "/v1/chat/completions", map[string]any{"messages": history, "max_tokens": 200}))
+	calls := llm.calls()
+	if len(calls) != 1 {
+		t.Fatalf("%d llm calls", len(calls))
+	}
+	sent := calls[0].Messages
+	if len(sent) != 3 || sent[0].Role != "system" || sent[1].Content != "old answer" || sent[2].Content != "latest question" {
+		t.Fatalf("sent %d messages: %+v", len(sent), sent)
+	}
+}
+
+func TestConversationBudget(t *testing.T) {
+	llm := newFakeLLM(t, nil)
+	h, mux := newTestHandler(t, llm, newFakeMCP(t))
+	h.SetModelPolicy(smallWindow(128000))
+	// Each fake reply reports 100 prompt and 20 completion tokens: 0.000027 USD at this price.
+	costs, err := OpenCostTracker(filepath.Join(t.TempDir(), "spend.json"), 0.00004)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetConversationBudget(costs)
+
+	ask := func() (*BudgetStatus, ChatCompletionResponse) {
+		t.Helper()
+		rec := chat(mux, "hi", conversationHeader, "conv-1")
+		if got := rec.Header().Get(conversationHeader); got != "conv-1" {
+			t.Fatalf("%s = %q", conversationHeader, got)
+		}
+		resp := decodeAnswer(t, rec)
+		if resp.Budget == nil {
+			t.Fatalf("no budget in %+v", resp)
+		}
+		return resp.Budget, resp
+	}
+
+	st, _ := ask()
+	if st.Conversation != "conv-1" || st.Exceeded || math.Abs(st.SpentUSD-0.000027) > 1e-9 || st.LimitUSD != 0.00004 {
+		t.Fatalf("after one call: %+v", st)
+	}
+	st, _ = ask()
+	if !st.Exceeded || math.Abs(st.SpentUSD-0.000054) > 1e-9 {
+		t.Fatalf("after crossing the limit: %+v", st)
+	}
+	st, resp := ask()
+	if !st.Exceeded || resp.Choices[0].FinishReason != finishBudget || content(resp) != budgetNotice {
+		t.Fatalf("over the limit: %+v", resp)
+	}
+	if n := len(llm.calls()); n != 2 {
+		t.Fatalf("%d llm calls, want none once over the limit", n)
+	}
+
+	// Another conversation has its own budget, and spend survives a restart.
+	if st := decodeAnswer(t, chat(mux, "hi", conversationHeader, "conv-2")).Budget; st == nil || st.Exceeded {
+		t.Fatalf("conv-2: %+v", st)
+	}
+	reopened, err := OpenCostTracker(costs.path, 0.00004)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened.entries) != 2 {
+		t.Fatalf("reopened %d conversations", len(reopened.entries))
+	}
+}
@@ -0,0 +1,186 @@
+package chatapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// conversationHeader names the conversation a request belongs to. Without it the
+	// conversation is identified by the caller and the opening turns.
+	conversationHeader = "X-Conversation-ID"
+	// maxConversations bounds the CostTracker; the least recently used are forgotten first.
+	maxConversations = 10000
+	budgetNotice     = "This conversation has reached its spending limit, so no further analytics were run. Start a new conversation to continue."
+	finishBudget     = "budget_exceeded"
+)
+
+// BudgetStatus is a conversation's estimated LLM spend, returned with every answer when
+// budgets are enabled.
+type BudgetStatus struct {
+	Conversation string  `json:"conversation"`
+	SpentUSD     float64 `json:"spent_usd"`
+	LimitUSD     float64 `json:"limit_usd"`
+	Exceeded     bool    `json:"exceeded"`
+}
+
+// CostTracker adds up the estimated cost of each conversation's LLM calls and caps it at a
+// per-conversation limit.
+type CostTracker struct {
+	mu      sync.Mutex
+	path    string
+	limit   float64
+	entries map[string]costEntry
+}
+
+type costEntry struct {
+	SpentUSD float64   `json:"spent_usd"`
+	Used     time.Time `json:"used"`
+}
+
+// OpenCostTracker limits every conversation to limitUSD, keeping spend in file, or in
+// memory only when file is empty.
+func OpenCostTracker(file string, limitUSD float64) (*CostTracker, error) {
+	if limitUSD <= 0 {
+		return nil, fmt.Errorf("conversation budget must be positive, got %v", limitUSD)
+	}
+	t := &CostTracker{path: strings.TrimSpace(file), limit: limitUSD, entries: map[string]costEntry{}}
+	if t.path == "" {
+		return t, nil
+	}
+	if err := readJSONFile(t.path, &t.entries); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// status reports the conversation's spend so far.
+func (t *CostTracker) status(key string) BudgetStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spent := t.entries[key].SpentUSD
+	return BudgetStatus{SpentUSD: spent, LimitUSD: t.limit, Exceeded: spent >= t.limit}
+}
+
+// add charges usd to the conversation.
+func (t *CostTracker) add(key string, usd float64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := t.entries[key]
+	e.SpentUSD += usd
+	e.Used = time.Now().UTC()
+	t.entries[key] = e
+	for len(t.entries) > maxConversations {
+		oldest := ""
+		for k, v := range t.entries {
+			if oldest == "" || v.Used.Before(t.entries[oldest].Used) {
+				oldest = k
+			}
+		}
+		delete(t.entries, oldest)
+	}
+	if t.path == "" {
+		return nil
+	}
+	return writeJSONFile(t.path, t.entries)
+}
+
+// SetConversationBudget caps the estimated LLM spend of each conversation (see
+// CostTracker). Once a conversation is over its limit, requests in it get a notice instead
+// of more tool rounds.
+func (h *Handler) SetConversationBudget(t *CostTracker) {
+	h.costs = t
+}
+
+// budgetStatus is the conversation's spend, or nil when budgets are off.
+func (h *Handler) budgetStatus(c conversation) *BudgetStatus {
+	if h.costs == nil {
+		return nil
+	}
+	st := h.costs.status(c.key)
+	st.Conversation = c.id
+	return &st
+}
+
+// conversation is the budget key of a conversation and the id its caller knows it by.
+type conversation struct {
+	key, id string
+	// variant names the prompt variant the conversation is answered with, if any.
+	variant string
+}
+
+// conversationOf identifies the conversation r belongs to: the X-Conversation-ID header,
+// else a hash of the turns up to the first user message, which clients resend with every
+// request. Either way it is scoped to the caller; with budgets on, the id is echoed in the
+// response header.
+func (h *Handler) conversationOf(ctx context.Context, w http.ResponseWriter, r *http.Request, messages []OAChatMessage) conversation {
+	subject, _ := h.quotaSubject(ctx)
+	id := strings.TrimSpace(r.Header.Get(conversationHeader))
+	if id == "" || len(id) > 128 {
+		sum := sha256.New()
+		for _, m := range messages {
+			fmt.Fprintf(sum, "%s\x00%s\x00", m.Role, m.Content)
+			if m.Role == "user" {
+				break
+			}
+		}
+		id = hex.EncodeToString(sum.Sum(nil))[:16]
+	}
+	if h.costs != nil {
+		w.Header().Set(conversationHeader, id)
+	}
+	c := conversation{key: subject + "\x00" + id, id: id}
+	if v := h.experiment.variant(c.key); v != nil {
+		c.variant = v.Name
+	}
+	return c
+}
+
+// charge adds the cost of one LLM call to the conversation, using the token counts OpenAI
+// reported, or estimates when it reported none.
+func (h *Handler) charge(ctx context.Context, c conversation, req ChatCompletionRequest, resp ChatCompletionResponse) {
+	if h.costs == nil && c.variant == "" {
+		return
+	}
+	in, out := usageTokens(resp.Usage, "prompt_tokens"), usageTokens(resp.Usage, "completion_tokens")
+	if in == 0 && out == 0 {
+		in = estimateMessages(req.Messages) + estimateTools(req.Tools)
+		for _, c := range resp.Choices {
+			out += estimateMessage(c.Message)
+		}
+	}
+	h.experiment.countCall(c.variant, in, out)
+	if h.costs == nil {
+		return
+	}
+	price := h.policy.price(req.Model)
+	cost := (float64(in)*price.Input + float64(out)*price.Output) / 1e6
+	if err := h.costs.add(c.key, cost); err != nil {
+		h.log(ctx).Warnf("record conversation spend: %v", err)
+	}
+}
+
+func usageTokens(usage map[string]interface{}, key string) int {
+	n, _ := usage[key].(float64)
+	return int(n)
+}
+
+// budgetExceeded answers with the budget notice in place of the model's reply.
+func budgetExceeded(model string, status BudgetStatus) ChatCompletionResponse {
+	return ChatCompletionResponse{
+		ID:     fmt.Sprintf("budget-%d", time.Now().UnixNano()),
+		Object: "chat.completion",
+		Model:  model,
+		Choices: []ChatChoice{{
+			Message:      OAChatMessage{Role: "assistant", Content: budgetNotice},
+			FinishReason: finishBudget,
+		}},
+		Budget: &status,
+	}
+}
@@ -0,0 +1,42 @@
+package chatapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/deadline"
+)
+
+// answerShare is the part of the time left when tools start that is kept for the model's
+// answer: tool calls still running past the rest are abandoned.
+const answerShare = 3
+
+// deadlineSkipped is the tool result given to the model in place of a tool call the request
+// deadline cut short.
+const deadlineSkipped = "Not available: the request deadline was reached before this tool returned. Answer from the other results and say that this data is missing."
+
+// withDeadline bounds the request by the caller's deadline.Header budget, rejecting an
+// invalid header. LLM calls, MCP calls and, through the forwarded header, the MCP server's
+// analytics calls all stop once it runs out.
+func withDeadline(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel, err := deadline.Apply(r.Context(), r)
+		defer cancel()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_deadline", err.Error())
+			return
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// toolContext bounds tool calls under a request deadline so there is time left to answer
+// from the results that did come back.
+func toolContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, d.Add(-time.Until(d)/answerShare))
+}
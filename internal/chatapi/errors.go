@@ -0,0 +1,111 @@
+package chatapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+// OpenAI error types used in error payloads.
+const (
+	errTypeInvalidRequest = "invalid_request_error"
+	errTypeAuthentication = "authentication_error"
+	errTypeRateLimit      = "rate_limit_error"
+	errTypeAPI            = "api_error"
+)
+
+// ErrorResponse is the OpenAI-compatible error envelope.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes a single error.
+type ErrorBody struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
+
+// upstreamError is returned by callOpenAI when OpenAI answers with a non-2xx status.
+type upstreamError struct {
+	status int
+	body   ErrorBody
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("openai status %d: %s", e.status, e.body.Message)
+}
+
+// parseUpstreamError builds an upstreamError from an OpenAI error response body.
+func parseUpstreamError(status int, raw []byte) *upstreamError {
+	var payload ErrorResponse
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Error.Message == "" {
+		msg := strings.TrimSpace(string(raw))
+		if len(msg) > 400 {
+			msg = msg[:400] + "..."
+		}
+		payload.Error = ErrorBody{Message: msg, Type: errTypeAPI}
+	}
+	return &upstreamError{status: status, body: payload.Error}
+}
+
+// writeError writes an OpenAI-compatible error payload.
+func writeError(w http.ResponseWriter, status int, errType, code, message string) {
+	body := ErrorBody{Message: message, Type: errType}
+	if code != "" {
+		body.Code = &code
+	}
+	writeJSON(w, ErrorResponse{Error: body}, status)
+}
+
+// writeDeadlineExceeded reports a request whose X-Request-Deadline-Ms budget ran out before
+// anything could be answered.
+func writeDeadlineExceeded(w http.ResponseWriter) {
+	writeError(w, http.StatusGatewayTimeout, errTypeAPI, "deadline_exceeded", "the request deadline passed before an answer was ready")
+}
+
+// writeParamError reports a request parameter rejected by the model policy.
+func writeParamError(w http.ResponseWriter, err *ParamError) {
+	param, code := err.Param, err.Code
+	writeJSON(w, ErrorResponse{Error: ErrorBody{Message: err.Message, Type: errTypeInvalidRequest, Param: &param, Code: &code}}, http.StatusBadRequest)
+}
+
+// writeUpstreamError maps an OpenAI call failure to the error returned to the client.
+// Client-side problems (bad request, rate limits) keep their status so SDK retry logic works;
+// upstream auth failures and server errors become 502 since they are not the caller's fault.
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeDeadlineExceeded(w)
+		return
+	}
+	var qe *tenant.QuotaError
+	if errors.As(err, &qe) {
+		writeError(w, http.StatusTooManyRequests, errTypeRateLimit, "quota_exceeded", qe.Error())
+		return
+	}
+	var ue *upstreamError
+	if !errors.As(err, &ue) {
+		writeError(w, http.StatusBadGateway, errTypeAPI, "upstream_unavailable", fmt.Sprintf("openai error: %v", err))
+		return
+	}
+	status := http.StatusBadGateway
+	switch ue.status {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		status = ue.status
+	case http.StatusTooManyRequests:
+		status = ue.status
+		if ue.body.Type == "" {
+			ue.body.Type = errTypeRateLimit
+		}
+	}
+	if ue.body.Type == "" {
+		ue.body.Type = errTypeAPI
+	}
+	writeJSON(w, ErrorResponse{Error: ue.body}, status)
+}
@@ -0,0 +1,106 @@
+package chatapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+// decodeError returns the OpenAI error body rec holds, failing t when it holds none.
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) ErrorBody {
+	t.Helper()
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Error.Message == "" {
+		t.Fatalf("not an error body: %d %s", rec.Code, rec.Body)
+	}
+	return resp.Error
+}
+
+func errorCode(b ErrorBody) string {
+	if b.Code == nil {
+		return ""
+	}
+	return *b.Code
+}
+
+func TestWriteUpstreamError(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		status   int
+		errType  string
+		code     string
+		contains string
+	}{
+		{"bad request keeps its status", parseUpstreamError(400, []byte(`{"error":{"message":"bad model","type":"invalid_request_error","code":"model_not_found"}}`)), 400, errTypeInvalidRequest, "model_not_found", "bad model"},
+		{"rate limit keeps its status", parseUpstreamError(429, []byte(`{"error":{"message":"slow down"}}`)), 429, errTypeRateLimit, "", "slow down"},
+		{"upstream auth is not the caller's fault", parseUpstreamError(401, []byte(`{"error":{"message":"bad key","type":"invalid_request_error"}}`)), 502, errTypeInvalidRequest, "", "bad key"},
+		{"server error becomes 502", parseUpstreamError(503, []byte(`<html>overloaded</html>`)), 502, errTypeAPI, "", "overloaded"},
+		{"transport failure", errors.New("dial tcp: connection refused"), 502, errTypeAPI, "upstream_unavailable", "connection refused"},
+		{"deadline", fmt.Errorf("post: %w", context.DeadlineExceeded), 504, errTypeAPI, "deadline_exceeded", "deadline"},
+		{"llm quota", &tenant.QuotaError{Subject: "acme", Kind: "llm_calls", Counter: tenant.Counter{Used: 5, Limit: 5}}, 429, errTypeRateLimit, "quota_exceeded", "acme used 5 of 5 llm calls"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeUpstreamError(rec, c.err)
+			body := decodeError(t, rec)
+			if rec.Code != c.status || body.Type != c.errType || errorCode(body) != c.code || !strings.Contains(body.Message, c.contains) {
+				t.Fatalf("got %d %+v (code %q), want %d %s %q containing %q", rec.Code, body, errorCode(body), c.status, c.errType, c.code, c.contains)
+			}
+		})
+	}
+}
+
+func TestParseUpstreamErrorTruncatesRawBodies(t *testing.T) {
+	ue := parseUpstreamError(500, []byte(strings.Repeat("x", 1000)))
+	if len(ue.body.Message) != 403 || !strings.HasSuffix(ue.body.Message, "...") {
+		t.Fatalf("message of %d bytes: %q", len(ue.body.Message), ue.body.Message)
+	}
+	if got := ue.Error(); !strings.HasPrefix(got, "openai status 500: ") {
+		t.Fatalf("Error() = %q", got)
+	}
+}
+
+func TestWriteToolError(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"quota", &chatserver.RPCError{Code: protocol.CodeQuotaExceeded, Message: "daily tool calls used up"}, http.StatusTooManyRequests, "quota_exceeded"},
+		{"deadline", fmt.Errorf("call: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "deadline_exceeded"},
+		{"rpc error", &chatserver.RPCError{Code: protocol.CodeUpstreamUnavailable, Message: "core returned 500"}, http.StatusBadGateway, "tool_call_failed"},
+		{"transport", errors.New("call mcp server: EOF"), http.StatusBadGateway, "tool_call_failed"},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		writeToolError(rec, c.err)
+		body := decodeError(t, rec)
+		if rec.Code != c.status || errorCode(body) != c.code {
+			t.Errorf("%s: got %d %q, want %d %q", c.name, rec.Code, errorCode(body), c.status, c.code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: content type %q", c.name, ct)
+		}
+	}
+}
+
+func TestWriteParamError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeParamError(rec, &ParamError{Param: "temperature", Code: "unsupported_parameter", Message: "not supported"})
+	body := decodeError(t, rec)
+	if rec.Code != http.StatusBadRequest || body.Param == nil || *body.Param != "temperature" || errorCode(body) != "unsupported_parameter" || body.Type != errTypeInvalidRequest {
+		t.Fatalf("got %d %+v", rec.Code, body)
+	}
+}
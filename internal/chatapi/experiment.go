@@ -0,0 +1,226 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	experimentsPath = "/admin/experiments"
+	// promptVariantHeader names the system prompt variant that answered a request.
+	promptVariantHeader = "X-Prompt-Variant"
+)
+
+var variantName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// PromptExperiment splits chat traffic between system prompt variants.
+type PromptExperiment struct {
+	Variants []PromptVariant `json:"variants"`
+
+	mu    sync.Mutex
+	total int
+	stats map[string]*VariantUsage
+}
+
+// PromptVariant is one system prompt and its share of conversations. A variant without Prompt
+// or PromptFile uses the built-in prompt, so it can serve as the control.
+type PromptVariant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	Prompt string `json:"prompt,omitempty"`
+	// PromptFile reads the prompt from a file, relative to the experiment file.
+	PromptFile string `json:"prompt_file,omitempty"`
+}
+
+// VariantUsage counts the answers a variant gave and the LLM tokens they took.
+type VariantUsage struct {
+	Answers          int `json:"answers"`
+	LLMCalls         int `json:"llm_calls"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// LoadPromptExperiment reads a prompt experiment file. An empty path disables experiments and
+// returns nil.
+func LoadPromptExperiment(file string) (*PromptExperiment, error) {
+	if strings.TrimSpace(file) == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read prompt experiment: %w", err)
+	}
+	var e PromptExperiment
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("decode prompt experiment: %w", err)
+	}
+	if err := e.compile(filepath.Dir(file)); err != nil {
+		return nil, fmt.Errorf("prompt experiment %s: %w", file, err)
+	}
+	return &e, nil
+}
+
+func (e *PromptExperiment) compile(dir string) error {
+	if len(e.Variants) == 0 {
+		return fmt.Errorf("no variants configured")
+	}
+	e.stats = map[string]*VariantUsage{}
+	for i := range e.Variants {
+		v := &e.Variants[i]
+		switch {
+		case !variantName.MatchString(v.Name):
+			return fmt.Errorf("variants[%d]: name must be 1-64 letters, digits, '.', '_' or '-'", i)
+		case e.stats[v.Name] != nil:
+			return fmt.Errorf("variants[%d]: duplicate name %q", i, v.Name)
+		case v.Weight < 0:
+			return fmt.Errorf("variants[%d]: weight must not be negative", i)
+		case v.Prompt != "" && v.PromptFile != "":
+			return fmt.Errorf("variants[%d]: set prompt or prompt_file, not both", i)
+		}
+		if v.PromptFile != "" {
+			path := v.PromptFile
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("variants[%d]: %w", i, err)
+			}
+			v.Prompt = string(data)
+		}
+		v.Prompt = strings.TrimSpace(v.Prompt)
+		e.total += v.Weight
+		e.stats[v.Name] = &VariantUsage{}
+	}
+	if e.total == 0 {
+		return fmt.Errorf("variant weights add up to zero")
+	}
+	return nil
+}
+
+// SetPromptExperiment splits conversations between the experiment's prompt variants; nil
+// uses the built-in prompt for all of them.
+func (h *Handler) SetPromptExperiment(e *PromptExperiment) {
+	h.experiment = e
+}
+
+// variant picks the variant for conversation key. The pick is a hash of the key, so every
+// turn of a conversation gets the same prompt.
+func (e *PromptExperiment) variant(key string) *PromptVariant {
+	if e == nil {
+		return nil
+	}
+	sum := fnv.New32a()
+	sum.Write([]byte(key))
+	n := int(sum.Sum32() % uint32(e.total))
+	for i := range e.Variants {
+		if n < e.Variants[i].Weight {
+			return &e.Variants[i]
+		}
+		n -= e.Variants[i].Weight
+	}
+	return &e.Variants[len(e.Variants)-1]
+}
+
+// prompt returns the variant's system prompt in place of the built-in one.
+func (v *PromptVariant) prompt() string {
+	if v == nil || v.Prompt == "" {
+		return basePrompt
+	}
+	return v.Prompt
+}
+
+func (e *PromptExperiment) countCall(name string, in, out int) {
+	if e == nil || name == "" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if u := e.stats[name]; u != nil {
+		u.LLMCalls++
+		u.PromptTokens += in
+		u.CompletionTokens += out
+	}
+}
+
+// tagVariant marks resp as answered by the conversation's prompt variant and counts it.
+func (h *Handler) tagVariant(w http.ResponseWriter, c conversation, resp *ChatCompletionResponse) {
+	if c.variant == "" {
+		return
+	}
+	resp.PromptVariant = c.variant
+	w.Header().Set(promptVariantHeader, c.variant)
+	h.experiment.mu.Lock()
+	defer h.experiment.mu.Unlock()
+	if u := h.experiment.stats[c.variant]; u != nil {
+		u.Answers++
+	}
+}
+
+// ExperimentReport is the body of GET /admin/experiments.
+type ExperimentReport struct {
+	Object   string          `json:"object"`
+	Variants []VariantReport `json:"variants"`
+}
+
+// VariantReport is one variant's usage since startup and the feedback on its answers.
+type VariantReport struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	// Builtin is set when the variant uses the built-in prompt.
+	Builtin bool `json:"builtin"`
+	VariantUsage
+	Up   int `json:"up"`
+	Down int `json:"down"`
+}
+
+func (h *Handler) experimentReport() ExperimentReport {
+	e := h.experiment
+	out := ExperimentReport{Object: "experiment.report", Variants: []VariantReport{}}
+	var votes map[string]FeedbackCount
+	if h.feedback != nil {
+		votes = h.feedback.byVariant()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, v := range e.Variants {
+		out.Variants = append(out.Variants, VariantReport{
+			Name:         v.Name,
+			Weight:       v.Weight,
+			Builtin:      v.Prompt == "",
+			VariantUsage: *e.stats[v.Name],
+			Up:           votes[v.Name].Up,
+			Down:         votes[v.Name].Down,
+		})
+	}
+	return out
+}
+
+// handleExperiments reports per-variant usage and feedback to API key callers:
+// GET /admin/experiments.
+func (h *Handler) handleExperiments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if id, _ := IdentityFrom(r.Context()); !id.isOperator() {
+		writeError(w, http.StatusForbidden, errTypeInvalidRequest, "forbidden", "experiment reports require the API key")
+		return
+	}
+	if h.experiment == nil {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "not_found", "no prompt experiment is configured")
+		return
+	}
+	writeJSON(w, h.experimentReport(), http.StatusOK)
+}
@@ -0,0 +1,163 @@
+package chatapi
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testExperiment is a compiled experiment with the given variants.
+func testExperiment(t *testing.T, variants ...PromptVariant) *PromptExperiment {
+	t.Helper()
+	e := &PromptExperiment{Variants: variants}
+	if err := e.compile(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestPromptVariantIsStablePerConversation(t *testing.T) {
+	e := testExperiment(t, PromptVariant{Name: "control", Weight: 1}, PromptVariant{Name: "terse", Weight: 1, Prompt: "Be terse."})
+	for i := range 100 {
+		key := fmt.Sprintf("user:alice\x00conv-%d", i)
+		first := e.variant(key)
+		for range 3 {
+			if got := e.variant(key); got != first {
+				t.Fatalf("%q got %s, then %s", key, first.Name, got.Name)
+			}
+		}
+	}
+	var nilExperiment *PromptExperiment
+	if v := nilExperiment.variant("k"); v != nil || v.prompt() != basePrompt {
+		t.Fatalf("without an experiment: %+v", v)
+	}
+}
+
+func TestPromptVariantFollowsTheSplit(t *testing.T) {
+	e := testExperiment(t,
+		PromptVariant{Name: "control", Weight: 70},
+		PromptVariant{Name: "off", Weight: 0, Prompt: "never"},
+		PromptVariant{Name: "terse", Weight: 20, Prompt: "Be terse."},
+		PromptVariant{Name: "warm", Weight: 10, Prompt: "Be warm."})
+	const keys = 20000
+	counts := map[string]int{}
+	for i := range keys {
+		counts[e.variant(fmt.Sprintf("user:u%d\x00conv", i)).Name]++
+	}
+	if counts["off"] != 0 {
+		t.Fatalf("a zero-weight variant got %d conversations", counts["off"])
+	}
+	for name, weight := range map[string]int{"control": 70, "terse": 20, "warm": 10} {
+		share := float64(counts[name]) / keys * 100
+		if math.Abs(share-float64(weight)) > 2 {
+			t.Errorf("%s got %.1f%% of conversations, want about %d%%", name, share, weight)
+		}
+	}
+}
+
+func TestLoadPromptExperiment(t *testing.T) {
+	if e, err := LoadPromptExperiment(""); err != nil || e != nil {
+		t.Fatalf("no file: %+v, %v", e, err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "terse.txt"), []byte("  Be terse.\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	write := func(body string) string {
+		file := filepath.Join(dir, "experiment.json")
+		if err := os.WriteFile(file, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	e, err := LoadPromptExperiment(write(`{"variants":[{"name":"control","weight":3},{"name":"terse","weight":1,"prompt_file":"terse.txt"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.total != 4 || e.Variants[0].prompt() != basePrompt || e.Variants[1].prompt() != "Be terse." {
+		t.Fatalf("loaded %+v", e.Variants)
+	}
+
+	for body, want := range map[string]string{
+		`{"variants":[]}`:                                                           "no variants configured",
+		`{"variants":[{"name":"a b","weight":1}]}`:                                  "name must be",
+		`{"variants":[{"name":"a","weight":1},{"name":"a","weight":1}]}`:            `duplicate name "a"`,
+		`{"variants":[{"name":"a","weight":-1}]}`:                                   "weight must not be negative",
+		`{"variants":[{"name":"a","weight":1,"prompt":"x","prompt_file":"t.txt"}]}`: "not both",
+		`{"variants":[{"name":"a","weight":1,"prompt_file":"missing.txt"}]}`:        "missing.txt",
+		`{"variants":[{"name":"a","weight":0}]}`:                                    "add up to zero",
+		`{"variants":{}}`:                                                           "decode prompt experiment",
+	} {
+		if _, err := LoadPromptExperiment(write(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", body, err, want)
+		}
+	}
+}
+
+func TestChatUsesTheConversationsPromptVariant(t *testing.T) {
+	llm := newFakeLLM(t, nil)
+	h, mux := newTestHandler(t, llm, newFakeMCP(t))
+	e := testExperiment(t, PromptVariant{Name: "control", Weight: 1}, PromptVariant{Name: "terse", Weight: 1, Prompt: "Be terse."})
+	h.SetPromptExperiment(e)
+	store, _ := OpenFeedbackStore("")
+	h.SetFeedback(store)
+
+	answers := map[string]int{}
+	for i := range 10 {
+		conv := fmt.Sprintf("conv-%d", i)
+		want := e.variant("default\x00" + conv).Name
+		for range 2 {
+			rec := chat(mux, "hi", conversationHeader, conv)
+			resp := decodeAnswer(t, rec)
+			if got := rec.Header().Get(promptVariantHeader); got != want || resp.PromptVariant != want {
+				t.Fatalf("%s answered by %q (body %q), want %q", conv, got, resp.PromptVariant, want)
+			}
+			calls := llm.calls()
+			system := calls[len(calls)-1].Messages[0].Content
+			if (want == "terse") != strings.HasPrefix(system, "Be terse.") || (want == "control") != strings.HasPrefix(system, basePrompt) {
+				t.Fatalf("%s (%s) sent system prompt %.40q", conv, want, system)
+			}
+			answers[want]++
+		}
+	}
+	if answers["control"] == 0 || answers["terse"] == 0 {
+		t.Fatalf("only one variant used: %v", answers)
+	}
+
+	rec := chat(mux, "rate me", conversationHeader, "conv-0")
+	rated := decodeAnswer(t, rec)
+	if rec := rate(mux, rated.ID, "down", ""); rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+		t.Fatalf("rating: %d %s", rec.Code, rec.Body)
+	}
+	answers[rated.PromptVariant]++
+
+	var report ExperimentReport
+	decodeJSON(t, serve(mux, http.MethodGet, experimentsPath, nil), &report)
+	if report.Object != "experiment.report" || len(report.Variants) != 2 {
+		t.Fatalf("report %+v", report)
+	}
+	for _, v := range report.Variants {
+		down := 0
+		if v.Name == rated.PromptVariant {
+			down = 1
+		}
+		if v.Answers != answers[v.Name] || v.LLMCalls != answers[v.Name] || v.PromptTokens != 100*answers[v.Name] || v.Down != down || v.Builtin != (v.Name == "control") {
+			t.Errorf("%s: %+v, want %d answers and %d down-votes", v.Name, v, answers[v.Name], down)
+		}
+	}
+}
+
+func TestExperimentReportNeedsAnExperiment(t *testing.T) {
+	_, mux := newTestHandler(t, newFakeLLM(t, nil), newFakeMCP(t))
+	if rec := serve(mux, http.MethodGet, experimentsPath, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("without an experiment: %d", rec.Code)
+	}
+	if rec := serve(mux, http.MethodPost, experimentsPath, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST: %d", rec.Code)
+	}
+}
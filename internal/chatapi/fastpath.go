@@ -0,0 +1,156 @@
+package chatapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/glossary"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// fastCurrencies are the currency codes the analytics tools filter by.
+var fastCurrencies = []string{"BTC", "ETH", "TRX", "BASE", "USDT", "USDC", "CBBTC"}
+
+var (
+	// fastFiller is the phrasing around a question that carries no meaning for the tools.
+	fastFiller = regexp.MustCompile(`^(?:(?:what|how) (?:is|was|were|are) (?:the |my |our )?|how much (?:in )?|show (?:me )?(?:the |my |our )?|get (?:me )?(?:the |my )?|give me (?:the |my )?)`)
+	fastDaily  = regexp.MustCompile(`^(?:daily payments|payments (?:per|each|by) day)$`)
+	fastTotal  = regexp.MustCompile(`^(?:total )?(?:payments?|payment volume|payment amount|volume|revenue)(?: total)?$`)
+	fastByCur  = regexp.MustCompile(`^([a-z]+) (?:payments?|payment volume|volume|amount|revenue)$`)
+	fastPeriod = regexp.MustCompile(`^(.*?) (?:in |for |over |during )?(?:the )?(today|yesterday|this month|last month|last week|(?:last|past) (\d{1,3}) days)$`)
+)
+
+// fastQuery is a question answered by one tool call without the LLM.
+type fastQuery struct {
+	tool   string
+	args   map[string]any
+	metric string
+	period string
+}
+
+// SetFastPath answers clearly parseable questions such as "total payments last 7 days" or
+// "USDC volume yesterday" with a direct tool call and a template, skipping OpenAI. Anything
+// else goes to the LLM as usual.
+func (h *Handler) SetFastPath(enabled bool) {
+	h.fastPath = enabled
+}
+
+// parseFastQuery matches question against the fast-path patterns. The whole question must
+// parse, or ok is false and the LLM answers it.
+func parseFastQuery(question string) (q fastQuery, ok bool) {
+	s := strings.ToLower(strings.Join(strings.Fields(question), " "))
+	s = strings.TrimRight(s, "?.! ")
+	s = fastFiller.ReplaceAllString(s, "")
+	m := fastPeriod.FindStringSubmatch(s)
+	if m == nil {
+		return q, false
+	}
+	subject, period := strings.TrimSpace(m[1]), m[2]
+	days := 0
+	switch {
+	case m[3] != "":
+		days, _ = strconv.Atoi(m[3])
+		if days < 1 || days > 365 {
+			return q, false
+		}
+		period = fmt.Sprintf("the last %d days", days)
+	case period == "last week":
+		days, period = 7, "the last 7 days"
+	}
+	filters := map[string]string{"today": "today", "yesterday": "yesterday", "this month": "this_month", "last month": "last_month"}
+	args := map[string]any{}
+	if days > 0 {
+		args["days"] = days
+	} else {
+		args["date_filter"] = filters[period]
+	}
+
+	switch {
+	case fastDaily.MatchString(subject):
+		q = fastQuery{tool: "payram_daily_stats", metric: "Daily payments"}
+	case fastTotal.MatchString(subject):
+		q = fastQuery{tool: "payram_payments_summary", metric: "Total payments"}
+	default:
+		cm := fastByCur.FindStringSubmatch(subject)
+		if cm == nil {
+			return q, false
+		}
+		code := glossary.Default().Currency(cm[1])
+		if !slices.Contains(fastCurrencies, code) {
+			return q, false
+		}
+		q = fastQuery{tool: "payram_currency_breakdown", metric: code + " payments"}
+		args["currency_code"] = code
+	}
+	q.args, q.period = args, period
+	return q, true
+}
+
+// fastAnswer answers req without the LLM when the fast path is on and the conversation is a
+// single English question it can parse, calling an offered tool. It reports whether it
+// wrote a response.
+func (h *Handler) fastAnswer(ctx context.Context, w http.ResponseWriter, r *http.Request, req ChatCompletionRequest, tools []protocol.ToolDescriptor, c conversation) bool {
+	if !h.fastPath || !i18n.IsDefault(i18n.FromContext(ctx)) {
+		return false
+	}
+	var question []string
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "user":
+			question = append(question, m.Content)
+		case "system":
+		default:
+			return false
+		}
+	}
+	if len(question) != 1 {
+		return false
+	}
+	q, ok := parseFastQuery(question[0])
+	if !ok || !slices.ContainsFunc(tools, func(t protocol.ToolDescriptor) bool { return t.Name == q.tool }) {
+		return false
+	}
+	log := h.log(ctx)
+	log.Infof("fast path: answering with %s %v", q.tool, q.args)
+
+	args := q.args
+	injectAuthToken(q.tool, analyticsToken(r), args)
+	start := time.Now()
+	result, err := h.mcp.CallTool(ctx, q.tool, args)
+	recordTool(ctx, q.tool, args, result, err, time.Since(start))
+	h.noteToolResult(ctx, q.tool, err)
+	if err != nil {
+		log.Errorf("tool error for %s: %v", q.tool, err)
+		writeToolError(w, err)
+		return true
+	}
+	rendered := renderContent(result)
+	resp := ChatCompletionResponse{
+		ID:     fmt.Sprintf("fastpath-%d", time.Now().UnixNano()),
+		Object: "chat.completion",
+		Model:  req.Model,
+		Choices: []ChatChoice{{
+			Message:      OAChatMessage{Role: "assistant", Content: fmt.Sprintf("%s for %s:\n\n%s", q.metric, q.period, strings.TrimSpace(rendered))},
+			FinishReason: "stop",
+		}},
+		Usage:      map[string]interface{}{"prompt_tokens": 0, "completion_tokens": 0, "total_tokens": 0},
+		Charts:     resultCharts(result),
+		Provenance: []ToolProvenance{toolProvenance(q.tool, args, result)},
+	}
+	if req.IncludeToolTrace {
+		resp.ToolTrace = []ToolTrace{newToolTrace(q.tool, args, time.Since(start), rendered, nil)}
+	}
+	h.moderate(ctx, &resp)
+	resp.Budget = h.budgetStatus(c)
+	h.provenance.remember(c.key, resp.Provenance)
+	h.rememberAnswer(ctx, req.Messages, resp, []string{q.tool})
+	writeCompletion(w, resp, req.Stream)
+	return true
+}
@@ -0,0 +1,92 @@
+package chatapi
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+func TestParseFastQuery(t *testing.T) {
+	cases := []struct {
+		question string
+		tool     string
+		args     map[string]any
+		period   string
+	}{
+		{"total payments last 7 days", "payram_payments_summary", map[string]any{"days": 7}, "the last 7 days"},
+		{"What were the total payments in the past 30 days?", "payram_payments_summary", map[string]any{"days": 30}, "the last 30 days"},
+		{"payment volume last week", "payram_payments_summary", map[string]any{"days": 7}, "the last 7 days"},
+		{"  Revenue   TODAY ", "payram_payments_summary", map[string]any{"date_filter": "today"}, "today"},
+		{"show me payments for last month", "payram_payments_summary", map[string]any{"date_filter": "last_month"}, "last month"},
+		{"usdc volume yesterday", "payram_currency_breakdown", map[string]any{"date_filter": "yesterday", "currency_code": "USDC"}, "yesterday"},
+		{"how much BTC payments last 365 days", "payram_currency_breakdown", map[string]any{"days": 365, "currency_code": "BTC"}, "the last 365 days"},
+		{"daily payments this month", "payram_daily_stats", map[string]any{"date_filter": "this_month"}, "this month"},
+		{"payments per day over the last 1 days", "payram_daily_stats", map[string]any{"days": 1}, "the last 1 days"},
+
+		// Rejected: the LLM answers these.
+		{"total payments last 0 days", "", nil, ""},
+		{"total payments last 400 days", "", nil, ""},
+		{"eur volume yesterday", "", nil, ""},
+		{"dogecoin payments today", "", nil, ""},
+		{"total payments last 7 days by currency", "", nil, ""},
+		{"total refunded payments yesterday", "", nil, ""},
+		{"why were payments low yesterday", "", nil, ""},
+		{"total payments", "", nil, ""},
+		{"", "", nil, ""},
+	}
+	for _, c := range cases {
+		q, ok := parseFastQuery(c.question)
+		if c.tool == "" {
+			if ok {
+				t.Errorf("%q parsed as %+v, want it left to the LLM", c.question, q)
+			}
+			continue
+		}
+		if !ok || q.tool != c.tool || !reflect.DeepEqual(q.args, c.args) || q.period != c.period {
+			t.Errorf("%q = %+v, %t; want %s %v for %q", c.question, q, ok, c.tool, c.args, c.period)
+		}
+	}
+}
+
+func TestFastPathAnswersWithoutTheLLM(t *testing.T) {
+	llm := newFakeLLM(t, nil)
+	mcp := newFakeMCP(t, tool("payram_payments_summary", "date_filter"), tool("payram_currency_breakdown", "currency_code", "date_filter"))
+	mcp.call = func(name string, args map[string]any) (protocol.CallResult, *protocol.ResponseError) {
+		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: "USDC: 1,204.50\n"}}}, nil
+	}
+	h, mux := newTestHandler(t, llm, mcp)
+	h.SetFastPath(true)
+
+	resp := decodeAnswer(t, chat(mux, "USDC volume yesterday?"))
+	if got := content(resp); got != "USDC payments for yesterday:\n\nUSDC: 1,204.50" || !strings.HasPrefix(resp.ID, "fastpath-") {
+		t.Fatalf("answer %q (%s)", got, resp.ID)
+	}
+	calls := mcp.toolCalls()
+	if len(calls) != 1 || calls[0].Name != "payram_currency_breakdown" || calls[0].Args["currency_code"] != "USDC" || calls[0].Args["date_filter"] != "yesterday" {
+		t.Fatalf("tool calls %+v", calls)
+	}
+	if n := len(llm.calls()); n != 0 {
+		t.Fatalf("%d llm calls on the fast path", n)
+	}
+
+	// A question it cannot parse, one for a tool that is not offered, a follow-up and
+	// another language all go to the LLM.
+	chat(mux, "why were payments low yesterday?")
+	chat(mux, "daily payments this month")
+	serve(mux, http.MethodPost, "/v1/chat/completions", map[string]any{"messages": []OAChatMessage{
+		{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}, {Role: "user", Content: "total payments today"},
+	}})
+	chat(mux, "total payments today", "X-Language", "es")
+	if n := len(llm.calls()); n != 4 {
+		t.Fatalf("%d llm calls, want 4", n)
+	}
+
+	h.SetFastPath(false)
+	chat(mux, "total payments today")
+	if n := len(llm.calls()); n != 5 {
+		t.Fatal("fast path answered while off")
+	}
+}
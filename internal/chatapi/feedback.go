@@ -0,0 +1,386 @@
+package chatapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	feedbackPath      = "/v1/feedback"
+	feedbackAdminPath = "/admin/feedback"
+	// maxFeedbackComment caps a comment, in characters.
+	maxFeedbackComment = 2000
+	// maxRatedAnswers caps how many recent answers can be rated; older ones are forgotten
+	// first, and feedback on them is refused as unknown.
+	maxRatedAnswers = 5000
+	// maxFeedbackExamples caps the questions listed per pattern and the recent down-votes in
+	// the summary.
+	maxFeedbackExamples = 5
+	maxRecentDownvotes  = 20
+)
+
+// Feedback is a caller's thumbs up or down on one chat answer.
+type Feedback struct {
+	ResponseID string   `json:"response_id"`
+	Rating     string   `json:"rating"`
+	Comment    string   `json:"comment,omitempty"`
+	Question   string   `json:"question"`
+	Tools      []string `json:"tools"`
+	Model      string   `json:"model,omitempty"`
+	User       string   `json:"user,omitempty"`
+	Tenant     string   `json:"tenant,omitempty"`
+	// PromptVariant names the prompt variant that gave the answer, if any.
+	PromptVariant string    `json:"prompt_variant,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ratedAnswer is what is kept of an answer until it is rated.
+type ratedAnswer struct {
+	question string
+	tools    []string
+	model    string
+	user     string
+	tenant   string
+	variant  string
+}
+
+// FeedbackStore holds feedback, optionally persisted to a JSON file, and the recent answers
+// it can be given on.
+type FeedbackStore struct {
+	mu       sync.Mutex
+	path     string
+	entries  []Feedback
+	answers  map[string]ratedAnswer
+	answered []string
+}
+
+// OpenFeedbackStore loads feedback from file, or keeps it in memory only when file is empty.
+func OpenFeedbackStore(file string) (*FeedbackStore, error) {
+	s := &FeedbackStore{path: strings.TrimSpace(file), answers: map[string]ratedAnswer{}}
+	if s.path == "" {
+		return s, nil
+	}
+	if err := readJSONFile(s.path, &s.entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetFeedback enables /v1/feedback and its summary under /admin/feedback, backed by store.
+func (h *Handler) SetFeedback(store *FeedbackStore) {
+	h.feedback = store
+}
+
+func (s *FeedbackStore) answer(id string, a ratedAnswer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.answers[id]; !ok {
+		s.answered = append(s.answered, id)
+		if len(s.answered) > maxRatedAnswers {
+			delete(s.answers, s.answered[0])
+			s.answered = s.answered[1:]
+		}
+	}
+	s.answers[id] = a
+}
+
+func (s *FeedbackStore) rated(id string) (ratedAnswer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.answers[id]
+	return a, ok
+}
+
+// put adds f, replacing an earlier rating of the same answer, and reports whether it did.
+func (s *FeedbackStore) put(f Feedback) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := slices.Clone(s.entries)
+	i := slices.IndexFunc(s.entries, func(e Feedback) bool { return e.ResponseID == f.ResponseID })
+	if i >= 0 {
+		s.entries = slices.Delete(s.entries, i, i+1)
+	}
+	s.entries = append(s.entries, f)
+	if s.path != "" {
+		if err := writeJSONFile(s.path, s.entries); err != nil {
+			s.entries = prev
+			return i >= 0, err
+		}
+	}
+	return i >= 0, nil
+}
+
+// rememberAnswer lets resp, the answer to messages, be rated later. tools names the tools
+// the answer used.
+func (h *Handler) rememberAnswer(ctx context.Context, messages []OAChatMessage, resp ChatCompletionResponse, tools []string) {
+	if h.feedback == nil || resp.ID == "" {
+		return
+	}
+	a := ratedAnswer{tools: tools, model: resp.Model, variant: resp.PromptVariant}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			a.question = strings.TrimSpace(messages[i].Content)
+			break
+		}
+	}
+	if id, ok := IdentityFrom(ctx); ok {
+		a.user = id.Username
+		if id.Tenant != nil {
+			a.tenant = id.Tenant.ID
+		}
+	}
+	h.feedback.answer(resp.ID, a)
+}
+
+// handleFeedback records a rating of an answer: POST /v1/feedback with
+// {"response_id": "...", "rating": "up" | "down", "comment": "..."}. A second rating of the
+// same answer replaces the first.
+func (h *Handler) handleFeedback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if h.feedback == nil {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "not_found", "feedback is not enabled")
+		return
+	}
+	var body struct {
+		ResponseID string `json:"response_id"`
+		Rating     string `json:"rating"`
+		Comment    string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_json", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	rating := strings.ToLower(strings.TrimSpace(body.Rating))
+	if rating != "up" && rating != "down" {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_rating", `rating must be "up" or "down"`)
+		return
+	}
+	comment := strings.TrimSpace(body.Comment)
+	if utf8.RuneCountInString(comment) > maxFeedbackComment {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "comment_too_long", fmt.Sprintf("comment must be at most %d characters", maxFeedbackComment))
+		return
+	}
+
+	id := strings.TrimSpace(body.ResponseID)
+	a, found := h.feedback.rated(id)
+	caller, _ := IdentityFrom(r.Context())
+	// Only the caller who got the answer may rate it, so IDs cannot be probed for questions.
+	if found && !caller.isOperator() {
+		tenantID := ""
+		if caller.Tenant != nil {
+			tenantID = caller.Tenant.ID
+		}
+		found = a.user == caller.Username && a.tenant == tenantID
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "response_not_found", fmt.Sprintf("no recent answer %q to rate", id))
+		return
+	}
+
+	f := Feedback{
+		ResponseID:    id,
+		Rating:        rating,
+		Comment:       comment,
+		Question:      a.question,
+		Tools:         a.tools,
+		Model:         a.model,
+		User:          a.user,
+		Tenant:        a.tenant,
+		PromptVariant: a.variant,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if f.Tools == nil {
+		f.Tools = []string{}
+	}
+	replaced, err := h.feedback.put(f)
+	if err != nil {
+		h.log(r.Context()).Errorf("save feedback: %v", err)
+		writeError(w, http.StatusInternalServerError, errTypeAPI, "storage_error", "failed to save feedback")
+		return
+	}
+	status := http.StatusCreated
+	if replaced {
+		status = http.StatusOK
+	}
+	writeJSON(w, f, status)
+}
+
+// FeedbackSummary is the body of GET /admin/feedback.
+type FeedbackSummary struct {
+	Object string `json:"object"`
+	Total  int    `json:"total"`
+	Up     int    `json:"up"`
+	Down   int    `json:"down"`
+	// Tools counts the ratings of the answers each tool took part in, most down-voted first.
+	// Answers without tools are counted under "(none)".
+	Tools []FeedbackCount `json:"tools"`
+	// Patterns groups down-voted questions that differ only in numbers, currencies and
+	// punctuation, most down-voted first.
+	Patterns   []FeedbackPattern `json:"patterns"`
+	RecentDown []Feedback        `json:"recent_down"`
+}
+
+// FeedbackCount is the ratings of one tool's answers.
+type FeedbackCount struct {
+	Tool string `json:"tool"`
+	Up   int    `json:"up"`
+	Down int    `json:"down"`
+}
+
+// FeedbackPattern is a family of questions and their ratings.
+type FeedbackPattern struct {
+	Pattern  string   `json:"pattern"`
+	Up       int      `json:"up"`
+	Down     int      `json:"down"`
+	Examples []string `json:"examples"`
+}
+
+var (
+	patternNumber      = regexp.MustCompile(`\d+(?:[.,]\d+)*`)
+	patternPunctuation = regexp.MustCompile(`[^\pL\pN<>#]+`)
+)
+
+// questionPattern reduces a question to its shape: "USDC volume last 7 days?" and "BTC
+// volume last 30 days" are both "<currency> volume last # days".
+func questionPattern(q string) string {
+	s := patternNumber.ReplaceAllString(strings.ToLower(q), "#")
+	words := strings.Fields(patternPunctuation.ReplaceAllString(s, " "))
+	for i, w := range words {
+		if slices.Contains(fastCurrencies, strings.ToUpper(w)) {
+			words[i] = "<currency>"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func (s *FeedbackStore) summary() FeedbackSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := FeedbackSummary{Object: "feedback.summary", Tools: []FeedbackCount{}, Patterns: []FeedbackPattern{}, RecentDown: []Feedback{}}
+	tools := map[string]*FeedbackCount{}
+	patterns := map[string]*FeedbackPattern{}
+	for _, f := range s.entries {
+		down := f.Rating == "down"
+		out.Total++
+		if down {
+			out.Down++
+		} else {
+			out.Up++
+		}
+		names := f.Tools
+		if len(names) == 0 {
+			names = []string{"(none)"}
+		}
+		for _, name := range names {
+			c := tools[name]
+			if c == nil {
+				c = &FeedbackCount{Tool: name}
+				tools[name] = c
+			}
+			if down {
+				c.Down++
+			} else {
+				c.Up++
+			}
+		}
+		key := questionPattern(f.Question)
+		p := patterns[key]
+		if p == nil {
+			p = &FeedbackPattern{Pattern: key, Examples: []string{}}
+			patterns[key] = p
+		}
+		if down {
+			p.Down++
+			if len(p.Examples) < maxFeedbackExamples && !slices.Contains(p.Examples, f.Question) {
+				p.Examples = append(p.Examples, f.Question)
+			}
+		} else {
+			p.Up++
+		}
+	}
+	for _, c := range tools {
+		out.Tools = append(out.Tools, *c)
+	}
+	sort.Slice(out.Tools, func(i, j int) bool {
+		a, b := out.Tools[i], out.Tools[j]
+		if a.Down != b.Down {
+			return a.Down > b.Down
+		}
+		return a.Tool < b.Tool
+	})
+	for _, p := range patterns {
+		if p.Down > 0 {
+			out.Patterns = append(out.Patterns, *p)
+		}
+	}
+	sort.Slice(out.Patterns, func(i, j int) bool {
+		a, b := out.Patterns[i], out.Patterns[j]
+		if a.Down != b.Down {
+			return a.Down > b.Down
+		}
+		return a.Pattern < b.Pattern
+	})
+	for _, f := range slices.Backward(s.entries) {
+		if f.Rating == "down" && len(out.RecentDown) < maxRecentDownvotes {
+			out.RecentDown = append(out.RecentDown, f)
+		}
+	}
+	return out
+}
+
+// byVariant counts the ratings of each prompt variant's answers.
+func (s *FeedbackStore) byVariant() map[string]FeedbackCount {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := map[string]FeedbackCount{}
+	for _, f := range s.entries {
+		if f.PromptVariant == "" {
+			continue
+		}
+		c := out[f.PromptVariant]
+		if f.Rating == "down" {
+			c.Down++
+		} else {
+			c.Up++
+		}
+		out[f.PromptVariant] = c
+	}
+	return out
+}
+
+// handleFeedbackSummary summarizes all feedback for API key callers: GET /admin/feedback.
+func (h *Handler) handleFeedbackSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if id, _ := IdentityFrom(r.Context()); !id.isOperator() {
+		writeError(w, http.StatusForbidden, errTypeInvalidRequest, "forbidden", "the feedback summary requires the API key")
+		return
+	}
+	if h.feedback == nil {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "not_found", "feedback is not enabled")
+		return
+	}
+	writeJSON(w, h.feedback.summary(), http.StatusOK)
+}
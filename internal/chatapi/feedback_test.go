@@ -0,0 +1,203 @@
+package chatapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+// numberedAnswers answers every call with a completion of its own ID, chatcmpl-<n>.
+func numberedAnswers(n int, _ ChatCompletionRequest) (int, any) {
+	resp := answer("ok")
+	resp.ID = fmt.Sprintf("chatcmpl-%d", n)
+	return http.StatusOK, resp
+}
+
+func rate(mux http.Handler, id, rating, comment string, header ...string) *httptest.ResponseRecorder {
+	return serve(mux, http.MethodPost, feedbackPath, map[string]any{"response_id": id, "rating": rating, "comment": comment}, header...)
+}
+
+func TestFeedbackIsStoredAndReplaced(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "feedback.json")
+	store, err := OpenFeedbackStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm := newFakeLLM(t, toolThenAnswer("payram_daily_stats", `{"days":"7"}`, "USDC volume was 1,204."))
+	h, mux := newTestHandler(t, llm, newFakeMCP(t, tool("payram_daily_stats", "days")))
+	h.SetFeedback(store)
+
+	id := decodeAnswer(t, chat(mux, "  USDC volume last 7 days?  ")).ID
+	rec := rate(mux, id, "UP", "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("first rating: %d %s", rec.Code, rec.Body)
+	}
+	rec = rate(mux, id, "down", "  wrong currency  ")
+	var f Feedback
+	decodeJSON(t, rec, &f)
+	if f.ResponseID != id || f.Rating != "down" || f.Comment != "wrong currency" || f.Question != "USDC volume last 7 days?" ||
+		!reflect.DeepEqual(f.Tools, []string{"payram_daily_stats"}) || f.Model != "gpt-4o-mini" || f.CreatedAt.IsZero() {
+		t.Fatalf("second rating %+v", f)
+	}
+
+	reopened, err := OpenFeedbackStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened.entries) != 1 || reopened.entries[0].Rating != "down" || reopened.entries[0].Comment != "wrong currency" {
+		t.Fatalf("reopened %+v", reopened.entries)
+	}
+}
+
+func TestFeedbackValidation(t *testing.T) {
+	h, mux := newTestHandler(t, newFakeLLM(t, nil), newFakeMCP(t))
+	if rec := rate(mux, "chatcmpl-test", "up", ""); rec.Code != http.StatusNotFound || errorCode(decodeError(t, rec)) != "not_found" {
+		t.Fatalf("feedback disabled: %d %s", rec.Code, rec.Body)
+	}
+	store, _ := OpenFeedbackStore("")
+	h.SetFeedback(store)
+	id := decodeAnswer(t, chat(mux, "hi")).ID
+
+	cases := []struct {
+		name   string
+		body   any
+		status int
+		code   string
+	}{
+		{"bad json", "not an object", http.StatusBadRequest, "invalid_json"},
+		{"no rating", map[string]any{"response_id": id}, http.StatusBadRequest, "invalid_rating"},
+		{"bad rating", map[string]any{"response_id": id, "rating": "meh"}, http.StatusBadRequest, "invalid_rating"},
+		{"long comment", map[string]any{"response_id": id, "rating": "down", "comment": strings.Repeat("é", maxFeedbackComment+1)}, http.StatusBadRequest, "comment_too_long"},
+		{"unknown answer", map[string]any{"response_id": "chatcmpl-nope", "rating": "up"}, http.StatusNotFound, "response_not_found"},
+	}
+	for _, c := range cases {
+		rec := serve(mux, http.MethodPost, feedbackPath, c.body)
+		if rec.Code != c.status || errorCode(decodeError(t, rec)) != c.code {
+			t.Errorf("%s: %d %s, want %d %s", c.name, rec.Code, rec.Body, c.status, c.code)
+		}
+	}
+	if rec := serve(mux, http.MethodGet, feedbackPath, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: %d", rec.Code)
+	}
+	// A comment of exactly the limit is fine, and nothing invalid was stored.
+	if rec := rate(mux, id, "down", strings.Repeat("é", maxFeedbackComment)); rec.Code != http.StatusCreated {
+		t.Fatalf("comment at the limit: %d %s", rec.Code, rec.Body)
+	}
+	if len(store.entries) != 1 {
+		t.Fatalf("%d entries stored", len(store.entries))
+	}
+}
+
+func TestFeedbackIsScopedToTheCaller(t *testing.T) {
+	reg, err := tenant.New([]tenant.Tenant{
+		{ID: "acme", BaseURL: "http://acme.invalid", Token: "t1", APIKeys: []string{"acme-key"}},
+		{ID: "globex", BaseURL: "http://globex.invalid", Token: "t2", APIKeys: []string{"globex-key"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, mux := newTestHandler(t, newFakeLLM(t, numberedAnswers), newFakeMCP(t))
+	h.SetUsage(nil, reg, 0)
+	store, _ := OpenFeedbackStore("")
+	h.SetFeedback(store)
+	acme := []string{tenant.KeyHeader, "acme-key"}
+	globex := []string{tenant.KeyHeader, "globex-key"}
+
+	id := decodeAnswer(t, chat(mux, "hi", acme...)).ID
+	if rec := rate(mux, id, "up", "", globex...); rec.Code != http.StatusNotFound {
+		t.Fatalf("another tenant rated the answer: %d %s", rec.Code, rec.Body)
+	}
+	if rec := rate(mux, id, "up", "", acme...); rec.Code != http.StatusCreated {
+		t.Fatalf("own answer: %d %s", rec.Code, rec.Body)
+	}
+	if store.entries[0].Tenant != "acme" {
+		t.Fatalf("stored %+v", store.entries[0])
+	}
+	// The summary is for operators only.
+	if rec := serve(mux, http.MethodGet, feedbackAdminPath, nil, acme...); rec.Code != http.StatusForbidden {
+		t.Fatalf("tenant summary: %d", rec.Code)
+	}
+}
+
+func TestFeedbackStoreForgetsOldAnswers(t *testing.T) {
+	store, _ := OpenFeedbackStore("")
+	for i := range maxRatedAnswers + 1 {
+		store.answer(fmt.Sprint(i), ratedAnswer{question: "q"})
+	}
+	// Seeing an answer again does not count it twice.
+	store.answer("1", ratedAnswer{question: "again"})
+	if _, ok := store.rated("0"); ok {
+		t.Fatal("oldest answer still rateable")
+	}
+	if a, ok := store.rated("1"); !ok || a.question != "again" {
+		t.Fatalf("answer 1: %+v, %v", a, ok)
+	}
+	if len(store.answers) != maxRatedAnswers || len(store.answered) != maxRatedAnswers {
+		t.Fatalf("%d answers, %d in order", len(store.answers), len(store.answered))
+	}
+}
+
+func TestQuestionPattern(t *testing.T) {
+	cases := map[string]string{
+		"USDC volume last 7 days?":   "<currency> volume last # days",
+		"btc volume, last 30 days":   "<currency> volume last # days",
+		"Payments over 1,204.50 $?":  "payments over #",
+		"  What is   my top chain? ": "what is my top chain",
+	}
+	for q, want := range cases {
+		if got := questionPattern(q); got != want {
+			t.Errorf("questionPattern(%q) = %q, want %q", q, got, want)
+		}
+	}
+}
+
+func TestFeedbackSummary(t *testing.T) {
+	store, _ := OpenFeedbackStore("")
+	for i, f := range []Feedback{
+		{ResponseID: "1", Rating: "down", Question: "USDC volume last 7 days?", Tools: []string{"payram_daily_stats"}},
+		{ResponseID: "2", Rating: "down", Question: "BTC volume last 30 days", Tools: []string{"payram_daily_stats", "payram_numbers_summary"}},
+		{ResponseID: "3", Rating: "up", Question: "USDC volume last 1 days", Tools: []string{"payram_daily_stats"}},
+		{ResponseID: "4", Rating: "up", Question: "hello"},
+		{ResponseID: "5", Rating: "down", Question: "who are you?"},
+	} {
+		if _, err := store.put(f); err != nil {
+			t.Fatalf("put %d: %v", i, err)
+		}
+	}
+	h, mux := newTestHandler(t, newFakeLLM(t, nil), newFakeMCP(t))
+	h.SetFeedback(store)
+	var s FeedbackSummary
+	decodeJSON(t, serve(mux, http.MethodGet, feedbackAdminPath, nil), &s)
+
+	if s.Object != "feedback.summary" || s.Total != 5 || s.Up != 2 || s.Down != 3 {
+		t.Fatalf("totals %+v", s)
+	}
+	wantTools := []FeedbackCount{
+		{Tool: "payram_daily_stats", Up: 1, Down: 2},
+		{Tool: "(none)", Up: 1, Down: 1},
+		{Tool: "payram_numbers_summary", Down: 1},
+	}
+	if !reflect.DeepEqual(s.Tools, wantTools) {
+		t.Fatalf("tools %+v", s.Tools)
+	}
+	wantPatterns := []FeedbackPattern{
+		{Pattern: "<currency> volume last # days", Up: 1, Down: 2, Examples: []string{"USDC volume last 7 days?", "BTC volume last 30 days"}},
+		{Pattern: "who are you", Down: 1, Examples: []string{"who are you?"}},
+	}
+	if !reflect.DeepEqual(s.Patterns, wantPatterns) {
+		t.Fatalf("patterns %+v", s.Patterns)
+	}
+	var recent []string
+	for _, f := range s.RecentDown {
+		recent = append(recent, f.ResponseID)
+	}
+	if !reflect.DeepEqual(recent, []string{"5", "2", "1"}) {
+		t.Fatalf("recent down-votes %v, want newest first", recent)
+	}
+}
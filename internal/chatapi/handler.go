@@ -1,17 +1,20 @@
 package chatapi
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/openapi"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
 	"github.com/sirupsen/logrus"
 )
 
@@ -23,25 +26,106 @@ type Handler struct {
 	mcp         *chatserver.MCPClient
 	apiKey      string
 	httpClient  *http.Client
+	policy      ModelPolicy
 	logger      *logrus.Entry
+
+	summaryModel string
+	summaries    *SummaryStore
+	saved        *SavedQueryStore
+	users        *UserStore
+	oidc         *OIDCVerifier
+	usage        *tenant.UsageTracker
+	tenants      *tenant.Registry
+	llmQuota     int
+	recorder     *Recorder
+	costs        *CostTracker
+	router       *ToolRouter
+	fastPath     bool
+	health       toolHealth
+	llm          llmProviders
+	provenance   provenanceLog
+	feedback     *FeedbackStore
+	experiment   *PromptExperiment
+	branding     *Branding
+	outputLimits OutputLimits
+
+	defaultLanguage string
+	moderation      *ModerationConfig
+	liveInterval    time.Duration
+	liveTickets     liveTickets
 }
 
 // NewHandler constructs a chat API handler.
 func NewHandler(logger *logrus.Entry, apiKey, openaiKey, openaiModel, openaiBase, mcpURL string) *Handler {
 	oc := &http.Client{Timeout: 30 * time.Second}
-	return &Handler{
-		openaiKey:   openaiKey,
-		openaiModel: openaiModel,
-		openaiBase:  strings.TrimRight(openaiBase, "/"),
-		mcp:         chatserver.NewMCPClient(mcpURL),
-		apiKey:      apiKey,
-		httpClient:  oc,
-		logger:      logger,
+	h := &Handler{
+		openaiKey:    openaiKey,
+		openaiModel:  openaiModel,
+		openaiBase:   strings.TrimRight(openaiBase, "/"),
+		mcp:          chatserver.NewMCPClient(mcpURL),
+		apiKey:       apiKey,
+		httpClient:   oc,
+		policy:       DefaultModelPolicy(),
+		outputLimits: DefaultOutputLimits(),
+		logger:       logger,
+	}
+	h.llm.set([]LLMProvider{{Name: "primary", BaseURL: h.openaiBase, key: openaiKey}})
+	return h
+}
+
+// SetSummarizer enables rolling summaries of long conversations using model.
+func (h *Handler) SetSummarizer(model string, store *SummaryStore) {
+	h.summaryModel = strings.TrimSpace(model)
+	h.summaries = store
+}
+
+// SetMCPTenantKey sets the tenant key sent to a multi-tenant MCP server when the caller
+// sends no X-Tenant-Key of its own.
+func (h *Handler) SetMCPTenantKey(key string) {
+	h.mcp.SetTenantKey(key)
+}
+
+// SetLanguage sets the answer language for callers that request none, e.g. "es". Empty
+// answers in English.
+func (h *Handler) SetLanguage(tag string) error {
+	if tag == "" {
+		h.defaultLanguage = ""
+		return nil
+	}
+	lang, ok := i18n.Parse(tag)
+	if !ok {
+		return fmt.Errorf("invalid language %q", tag)
 	}
+	h.defaultLanguage = lang
+	return nil
+}
+
+// SetModelPolicy replaces the built-in per-model parameter policy.
+func (h *Handler) SetModelPolicy(p ModelPolicy) {
+	h.policy = p
 }
 
 func (h *Handler) Register(mux *http.ServeMux) {
-	mux.HandleFunc("/v1/chat/completions", h.handleChat)
+	mux.HandleFunc("/v1/chat/completions", withDeadline(h.handleChat))
+	mux.HandleFunc("/v1/chat/structured", withDeadline(h.handleStructured))
+	mux.HandleFunc("/v1/query", withDeadline(h.handleQuery))
+	mux.HandleFunc(toolsPath, h.handleTools)
+	mux.HandleFunc(brandingPath, h.handleBranding)
+	mux.HandleFunc(savedQueriesPath, withDeadline(h.handleSavedQueries))
+	mux.HandleFunc(savedQueriesPath+"/", withDeadline(h.handleSavedQueries))
+	mux.HandleFunc(usagePath, h.handleUsage)
+	mux.HandleFunc(feedbackPath, h.handleFeedback)
+	mux.HandleFunc(feedbackAdminPath, h.handleFeedbackSummary)
+	mux.HandleFunc(experimentsPath, h.handleExperiments)
+	mux.HandleFunc(authPath, h.handleAuth)
+	mux.HandleFunc(recordingsPath, h.handleRecordings)
+	mux.HandleFunc(recordingsPath+"/", h.handleRecordings)
+	mux.HandleFunc(livePath, h.handleLive)
+	mux.HandleFunc(liveTicketPath, h.handleLiveTicket)
+	mux.Handle(openapi.Path, openapi.Handler(openAPIDoc))
+	mux.Handle(webPath, webHandler())
+	mux.Handle("/{$}", http.RedirectHandler(webPath, http.StatusFound))
+	mux.HandleFunc(readyPath, h.handleReady)
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
@@ -49,86 +133,203 @@ func (h *Handler) Register(mux *http.ServeMux) {
 }
 
 func (h *Handler) handleChat(w http.ResponseWriter, r *http.Request) {
+	h.serveChat(w, r, false)
+}
+
+// serveChat answers a conversation, constraining the answer to the structured answer
+// schema when structured is set (see handleStructured).
+func (h *Handler) serveChat(w http.ResponseWriter, r *http.Request, structured bool) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodPost)
 		return
 	}
-	if !h.authorize(r) {
-		h.logger.Warn("unauthorized request")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	r, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
+	log := h.log(r.Context())
 	var req ChatCompletionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warnf("bad request: %v", err)
-		http.Error(w, "bad request", http.StatusBadRequest)
+		log.Warnf("bad request: %v", err)
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_json", fmt.Sprintf("invalid request body: %v", err))
 		return
 	}
+	ctx, w, finish := h.startRecording(w, r, req)
+	defer finish()
 	if req.Model == "" {
 		req.Model = h.openaiModel
 	}
 	if len(req.Messages) == 0 {
-		http.Error(w, "messages required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "missing_messages", "messages is required")
+		return
+	}
+	params, notes, err := h.policy.Resolve(req.Model, req.SamplingParams)
+	if err != nil {
+		var pe *ParamError
+		if errors.As(err, &pe) {
+			writeParamError(w, pe)
+			return
+		}
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_parameter_value", err.Error())
+		return
+	}
+	for _, n := range notes {
+		log.Infof("model policy: %s", n)
+	}
+	if structured && req.Stream {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_parameter_value", "stream is not supported for structured answers")
+		return
+	}
+	var responseFormat any
+	if structured {
+		responseFormat = structuredResponseFormat
+	}
+	conversation := h.conversationOf(ctx, w, r, req.Messages)
+	if conversation.variant != "" {
+		log = log.WithField("prompt_variant", conversation.variant)
+	}
+	if isProvenanceCommand(req.Messages) {
+		h.provenanceAnswer(w, req, conversation, structured)
+		return
+	}
+	if st := h.budgetStatus(conversation); st != nil && st.Exceeded {
+		log.Warnf("conversation %s is over its budget (%.4f of %.4f USD)", st.Conversation, st.SpentUSD, st.LimitUSD)
+		h.writeAnswer(w, budgetExceeded(req.Model, *st), req.Stream, structured)
 		return
 	}
-
-	ctx := r.Context()
 
 	// Build system prompt and tools from MCP.
 	tools, err := h.mcp.ListTools(ctx)
 	if err != nil {
-		h.logger.Errorf("list tools error: %v", err)
-		http.Error(w, fmt.Sprintf("list tools error: %v", err), http.StatusBadGateway)
+		log.Errorf("list tools error: %v", err)
+		writeError(w, http.StatusBadGateway, errTypeAPI, "tools_unavailable", fmt.Sprintf("list tools error: %v", err))
+		return
+	}
+	caller, _ := IdentityFrom(ctx)
+	tools = slices.DeleteFunc(tools, func(t protocol.ToolDescriptor) bool { return !caller.allows(t.Name) })
+	if !structured && h.fastAnswer(ctx, w, r, req, tools, conversation) {
 		return
 	}
 	oaTools := convertTools(tools)
+	var toolChoice any = "auto"
+	if len(oaTools) == 0 {
+		toolChoice = nil
+	}
+
+	offered := h.routeTools(ctx, req.Messages, oaTools)
+	h.annotateToolHealth(ctx, offered)
 
-	system := OAChatMessage{Role: "system", Content: systemPrompt()}
+	variant := h.experiment.variant(conversation.key)
+	system := OAChatMessage{Role: "system", Content: systemPrompt(variant.prompt()+h.branding.persona(), i18n.FromContext(ctx))}
 	messages := append([]OAChatMessage{system}, req.Messages...)
+	messages = h.summarize(ctx, req.Model, messages, offered, params)
+	messages = h.fitContext(req.Model, messages, offered, params)
 
 	firstReq := ChatCompletionRequest{
-		Model:       req.Model,
-		Messages:    messages,
-		Tools:       oaTools,
-		ToolChoice:  "auto",
-		Temperature: sanitizeTemperature(req.Model, req.Temperature),
+		Model:          req.Model,
+		Messages:       messages,
+		Tools:          offered,
+		ToolChoice:     toolChoice,
+		ResponseFormat: responseFormat,
+		SamplingParams: params,
 	}
 
 	firstResp, err := h.callOpenAI(ctx, firstReq)
 	if err != nil {
-		h.logger.Errorf("openai first call error: %v", err)
-		http.Error(w, fmt.Sprintf("openai error: %v", err), http.StatusBadGateway)
+		log.Errorf("openai first call error: %v", err)
+		writeUpstreamError(w, err)
 		return
 	}
+	h.charge(ctx, conversation, firstReq, firstResp)
+	if len(firstResp.Choices) > 0 && callsDiscovery(firstResp.Choices[0].Message.ToolCalls) {
+		log.Infof("tool router: model asked for more tools; offering all %d", len(oaTools))
+		h.annotateToolHealth(ctx, oaTools)
+		messages = h.fitContext(req.Model, messages, oaTools, params)
+		firstReq.Messages, firstReq.Tools = messages, oaTools
+		if firstResp, err = h.callOpenAI(ctx, firstReq); err != nil {
+			log.Errorf("openai first call error: %v", err)
+			writeUpstreamError(w, err)
+			return
+		}
+		h.charge(ctx, conversation, firstReq, firstResp)
+	}
 	if len(firstResp.Choices) == 0 {
-		http.Error(w, "no choices", http.StatusBadGateway)
+		writeError(w, http.StatusBadGateway, errTypeAPI, "empty_completion", "openai returned no choices")
 		return
 	}
 
 	choice := firstResp.Choices[0]
 	if len(choice.Message.ToolCalls) == 0 {
-		writeJSON(w, firstResp, http.StatusOK)
+		if req.IncludeToolTrace {
+			firstResp.ToolTrace = []ToolTrace{}
+		}
+		h.provenance.remember(conversation.key, nil)
+		h.moderate(ctx, &firstResp)
+		firstResp.Budget = h.budgetStatus(conversation)
+		h.tagVariant(w, conversation, &firstResp)
+		h.rememberAnswer(ctx, req.Messages, firstResp, nil)
+		h.writeAnswer(w, firstResp, req.Stream, structured)
+		return
+	}
+	if st := h.budgetStatus(conversation); st != nil && st.Exceeded {
+		log.Warnf("conversation %s reached its budget; declining %d tool calls", st.Conversation, len(choice.Message.ToolCalls))
+		h.writeAnswer(w, budgetExceeded(req.Model, *st), req.Stream, structured)
 		return
 	}
 
 	// Execute tool calls via MCP, then ask LLM again with tool results.
-	authToken := bearerToken(r.Header.Get("Authorization"))
+	authToken := analyticsToken(r)
 	toolMessages := make([]OAChatMessage, 0, len(choice.Message.ToolCalls))
+	var trace []ToolTrace
+	var charts []protocol.ChartData
+	var sources []ToolProvenance
+	toolCtx, cancelTools := toolContext(ctx)
+	defer cancelTools()
 	for _, tc := range choice.Message.ToolCalls {
 		args := tc.Function.Arguments
 		if strings.TrimSpace(args) == "" {
 			args = "{}"
 		}
+		if !caller.allows(tc.Function.Name) {
+			log.Warnf("model requested tool %s outside caller's scopes", tc.Function.Name)
+			writeError(w, http.StatusForbidden, errTypeInvalidRequest, "tool_not_permitted", fmt.Sprintf("tool %q is not permitted for this caller", tc.Function.Name))
+			return
+		}
 		var raw json.RawMessage = json.RawMessage(args)
 		callArgs := mapFromRaw(raw)
 		injectAuthToken(tc.Function.Name, authToken, callArgs)
-		result, err := h.mcp.CallTool(ctx, tc.Function.Name, callArgs)
-		if err != nil {
-			h.logger.Errorf("tool error for %s: %v", tc.Function.Name, err)
-			http.Error(w, fmt.Sprintf("tool error: %v", err), http.StatusBadGateway)
+		start := time.Now()
+		var result protocol.CallResult
+		err := toolCtx.Err()
+		if err == nil {
+			result, err = h.mcp.CallTool(toolCtx, tc.Function.Name, callArgs)
+			recordTool(ctx, tc.Function.Name, callArgs, result, err, time.Since(start))
+		}
+		// A tool cut short by the deadline is left out of the answer rather than failing it.
+		cutShort := err != nil && toolCtx.Err() != nil && ctx.Err() == nil
+		if !cutShort {
+			h.noteToolResult(ctx, tc.Function.Name, err)
+		}
+		rendered := ""
+		if cutShort {
+			log.Warnf("tool %s cut short by the request deadline; answering without it", tc.Function.Name)
+			rendered = deadlineSkipped
+		} else if err == nil {
+			var cut int
+			if rendered, cut = h.outputLimits.capOutput(tc.Function.Name, renderContent(result)); cut > 0 {
+				log.Infof("tool output of %s truncated by %d chars", tc.Function.Name, cut)
+			}
+			charts = append(charts, resultCharts(result)...)
+			sources = append(sources, toolProvenance(tc.Function.Name, callArgs, result))
+		}
+		if req.IncludeToolTrace {
+			trace = append(trace, newToolTrace(tc.Function.Name, callArgs, time.Since(start), rendered, err))
+		}
+		if err != nil && !cutShort {
+			log.Errorf("tool error for %s: %v", tc.Function.Name, err)
+			writeToolError(w, err)
 			return
 		}
-		rendered := renderContent(result)
 		toolMessages = append(toolMessages, OAChatMessage{
 			Role:       "tool",
 			ToolCallID: tc.ID,
@@ -139,65 +340,95 @@ func (h *Handler) handleChat(w http.ResponseWriter, r *http.Request) {
 
 	followMessages := append(messages, OAChatMessage{Role: "assistant", ToolCalls: choice.Message.ToolCalls})
 	followMessages = append(followMessages, toolMessages...)
+	followMessages = h.fitContext(req.Model, followMessages, nil, params)
 
 	secondReq := ChatCompletionRequest{
-		Model:       req.Model,
-		Messages:    followMessages,
-		Temperature: sanitizeTemperature(req.Model, req.Temperature),
+		Model:          req.Model,
+		Messages:       followMessages,
+		ResponseFormat: responseFormat,
+		SamplingParams: params,
 	}
 
 	secondResp, err := h.callOpenAI(ctx, secondReq)
 	if err != nil {
-		h.logger.Errorf("openai second call error: %v", err)
-		http.Error(w, fmt.Sprintf("openai error: %v", err), http.StatusBadGateway)
+		log.Errorf("openai second call error: %v", err)
+		writeUpstreamError(w, err)
 		return
 	}
-	writeJSON(w, secondResp, http.StatusOK)
-}
-
-func (h *Handler) callOpenAI(ctx context.Context, req ChatCompletionRequest) (ChatCompletionResponse, error) {
-	var resp ChatCompletionResponse
-	body, err := json.Marshal(req)
-	if err != nil {
-		return resp, fmt.Errorf("encode openai request: %w", err)
-	}
-	url := h.openaiBase + "/chat/completions"
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return resp, fmt.Errorf("build openai request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+h.openaiKey)
-
-	httpResp, err := h.httpClient.Do(httpReq)
-	if err != nil {
-		return resp, fmt.Errorf("call openai: %w", err)
-	}
-	defer httpResp.Body.Close()
-
-	respBody, _ := io.ReadAll(httpResp.Body)
-	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-		msg := strings.TrimSpace(string(respBody))
-		if len(msg) > 400 {
-			msg = msg[:400] + "..."
+	h.charge(ctx, conversation, secondReq, secondResp)
+	secondResp.ToolTrace = trace
+	secondResp.Charts = charts
+	secondResp.Provenance = sources
+	h.provenance.remember(conversation.key, sources)
+	h.moderate(ctx, &secondResp)
+	secondResp.Budget = h.budgetStatus(conversation)
+	used := make([]string, 0, len(choice.Message.ToolCalls))
+	for _, tc := range choice.Message.ToolCalls {
+		if !slices.Contains(used, tc.Function.Name) {
+			used = append(used, tc.Function.Name)
 		}
-		return resp, fmt.Errorf("openai status %d: %s", httpResp.StatusCode, msg)
 	}
+	h.tagVariant(w, conversation, &secondResp)
+	h.rememberAnswer(ctx, req.Messages, secondResp, used)
+	h.writeAnswer(w, secondResp, req.Stream, structured)
+}
 
-	if err := json.NewDecoder(bytes.NewReader(respBody)).Decode(&resp); err != nil {
-		return resp, fmt.Errorf("decode openai response: %w", err)
+// fitContext trims messages to the model's context window and logs what was cut.
+func (h *Handler) fitContext(model string, messages []OAChatMessage, tools []OATool, params SamplingParams) []OAChatMessage {
+	out, trim := h.policy.fitContext(model, messages, tools, params)
+	if trim.changed() {
+		h.logger.WithFields(logrus.Fields{
+			"model":     model,
+			"dropped":   trim.Dropped,
+			"truncated": trim.Truncated,
+			"tokens":    trim.Before,
+			"trimmed":   trim.After,
+			"budget":    trim.Budget,
+		}).Warn("context trimmed to fit model window")
 	}
-	return resp, nil
+	return out
 }
 
-func (h *Handler) authorize(r *http.Request) bool {
-	if h.apiKey == "" {
-		return true
+func (h *Handler) callOpenAI(ctx context.Context, req ChatCompletionRequest) (ChatCompletionResponse, error) {
+	if err := h.takeLLMCall(ctx); err != nil {
+		return ChatCompletionResponse{}, err
 	}
-	if v := r.Header.Get("X-MCP-Key"); v != "" {
-		return strings.TrimSpace(v) == h.apiKey
+	start := time.Now()
+	resp, raw, err := h.doOpenAI(ctx, req)
+	recordLLM(ctx, req, raw, err, time.Since(start))
+	return resp, err
+}
+
+// doOpenAI posts req to the first healthy provider, failing over to the next while they are
+// unavailable, and returns the decoded response along with its raw body.
+func (h *Handler) doOpenAI(ctx context.Context, req ChatCompletionRequest) (ChatCompletionResponse, []byte, error) {
+	var (
+		resp ChatCompletionResponse
+		raw  []byte
+		err  error
+	)
+	for n, i := range h.llm.order() {
+		p := h.llm.provider(i)
+		preq := req
+		if p.Model != "" {
+			preq.Model = p.Model
+		}
+		body, encErr := json.Marshal(preq)
+		if encErr != nil {
+			return resp, nil, fmt.Errorf("encode openai request: %w", encErr)
+		}
+		if n > 0 {
+			h.logger.WithFields(logrus.Fields{"provider": p.Name, "error": err}).Warn("failing over to llm provider")
+		}
+		resp, raw, err = h.postChat(ctx, p, body)
+		if err == nil || !providerFailed(ctx, err, raw) {
+			return resp, raw, err
+		}
+		if h.llm.note(i, err, 0, false) {
+			h.logProviderChange(p, err)
+		}
 	}
-	return false
+	return resp, raw, err
 }
 
 func writeJSON(w http.ResponseWriter, v any, status int) {
@@ -206,8 +437,16 @@ func writeJSON(w http.ResponseWriter, v any, status int) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-func systemPrompt() string {
-	return `You are PayRam's analytics assistant. ALWAYS call MCP tools to answer questions—never guess or say data is unavailable without trying.
+// systemPrompt returns the assistant instructions base, asking for answers in lang unless it
+// is the default language.
+func systemPrompt(base, lang string) string {
+	if i18n.IsDefault(lang) {
+		return base
+	}
+	return base + fmt.Sprintf("\n\nAnswer in %s (%s). Keep tool names, currency codes, IDs and numbers exactly as the tools return them.", i18n.Name(lang), lang)
+}
+
+const basePrompt = `You are PayRam's analytics assistant. ALWAYS call MCP tools to answer questions—never guess or say data is unavailable without trying.
 
 TOOL SELECTION GUIDE:
 - For per-day/daily breakdown (e.g., "payments each day", "daily counts"): Use payram_daily_stats with days=N
@@ -217,16 +456,20 @@ TOOL SELECTION GUIDE:
 - For SPECIFIC CURRENCY queries (e.g., "USDC amount", "BTC transactions"): Use payram_currency_breakdown with currency_code parameter (e.g., currency_code="USDC")
 - For currency distribution breakdown: Use payram_deposit_distribution
 - For user growth (new vs recurring): Use payram_user_growth or payram_paying_users
+- For cohort retention or churn ("do users who paid in January keep paying?"): Use payram_retention, and say the matrix is an estimate
 - For recent transactions table: Use payram_recent_transactions
+- For growth vs the previous period (e.g., "payments vs the week before"): Use payram_payments_summary with compare_previous=true
 - For period comparison: Use payram_compare_periods
+- For "what changed since yesterday" or a morning overview of moves: Use payram_changes
 - For any graph by ID: Use payram_fetch_graph_data (discover with payram_discover_analytics first)
+- For CSV exports, a full year, or reports across several groups: Use payram_export; it returns a job ID, then call payram_job_status with it to get the CSV
+- For "did invoice/payment X just get paid?": Use payram_live_events with reference_id (when available); it sees payments before analytics do
 
 IMPORTANT: 
 - When user asks for "last N days", set the days parameter to N
 - When user mentions a SPECIFIC CURRENCY (USDC, BTC, ETH, etc.), use payram_currency_breakdown with currency_code set to that currency
 
 Reply concisely with the actual data. No preambles. If a tool fails, state the error briefly.`
-}
 
 // convert MCP tool descriptors to OpenAI tools schema.
 func convertTools(tools []protocol.ToolDescriptor) []OATool {
@@ -284,11 +527,11 @@ func toParameterMap(s protocol.JSONSchema) map[string]interface{} {
 	return m
 }
 
-// renderContent joins call result content parts into a string.
+// renderContent joins the text parts of a call result into a string.
 func renderContent(result protocol.CallResult) string {
 	var sb strings.Builder
-	for i, c := range result.Content {
-		if i > 0 {
+	for _, c := range result.Content {
+		if sb.Len() > 0 {
 			sb.WriteString("\n")
 		}
 		sb.WriteString(c.Text)
@@ -296,17 +539,12 @@ func renderContent(result protocol.CallResult) string {
 	return sb.String()
 }
 
-// sanitizeTemperature omits temperature when the target model does not support custom values.
-// Mini models (gpt-4.1-mini, gpt-4o-mini, gpt-5-mini, etc.) reject non-default temps; drop to avoid 400s.
-func sanitizeTemperature(model string, t *float64) *float64 {
-	if t == nil {
-		return nil
-	}
-	lowerModel := strings.ToLower(strings.TrimSpace(model))
-	if (strings.Contains(lowerModel, "mini") || strings.Contains(lowerModel, "gpt-4.1") || strings.Contains(lowerModel, "gpt-4o")) && *t != 1.0 {
+// resultCharts returns the charts attached to a call result.
+func resultCharts(result protocol.CallResult) []protocol.ChartData {
+	if result.Meta == nil {
 		return nil
 	}
-	return t
+	return result.Meta.Charts
 }
 
 // bearerToken extracts the token portion from an Authorization header value.
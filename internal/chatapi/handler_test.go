@@ -0,0 +1,307 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeLLM is an OpenAI-compatible endpoint answering chat completions with reply, which
+// sees the number of the call (from 0) and its request. Without reply it answers "ok".
+type fakeLLM struct {
+	*httptest.Server
+	reply func(n int, req ChatCompletionRequest) (int, any)
+
+	mu       sync.Mutex
+	requests []ChatCompletionRequest
+}
+
+func newFakeLLM(t *testing.T, reply func(n int, req ChatCompletionRequest) (int, any)) *fakeLLM {
+	f := &fakeLLM{reply: reply}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			_, _ = io.WriteString(w, `{"data":[]}`)
+			return
+		}
+		var req ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		n := len(f.requests)
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+		status, body := http.StatusOK, any(answer("ok"))
+		if f.reply != nil {
+			status, body = f.reply(n, req)
+		}
+		writeJSON(w, body, status)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// calls returns the requests received so far.
+func (f *fakeLLM) calls() []ChatCompletionRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ChatCompletionRequest(nil), f.requests...)
+}
+
+// answer is a completion answering text.
+func answer(text string) ChatCompletionResponse {
+	return ChatCompletionResponse{
+		ID: "chatcmpl-test", Object: "chat.completion", Model: "gpt-4o-mini",
+		Choices: []ChatChoice{{Message: OAChatMessage{Role: "assistant", Content: text}, FinishReason: "stop"}},
+		Usage:   map[string]any{"prompt_tokens": 100, "completion_tokens": 20, "total_tokens": 120},
+	}
+}
+
+// toolCall is a completion asking for tool with the JSON arguments args.
+func toolCall(tool, args string) ChatCompletionResponse {
+	resp := answer("")
+	resp.Choices[0].FinishReason = "tool_calls"
+	resp.Choices[0].Message.ToolCalls = []OAToolCall{{ID: "call_1", Type: "function", Function: OAToolCallFunc{Name: tool, Arguments: args}}}
+	return resp
+}
+
+// toolThenAnswer asks for tool on the first call and answers text on the next ones.
+func toolThenAnswer(tool, args, text string) func(int, ChatCompletionRequest) (int, any) {
+	return func(n int, _ ChatCompletionRequest) (int, any) {
+		if n == 0 {
+			return http.StatusOK, toolCall(tool, args)
+		}
+		return http.StatusOK, answer(text)
+	}
+}
+
+// fakeMCP is an MCP server over HTTP listing tools and answering tools/call with call.
+// Without call every tool answers "result of <name>".
+type fakeMCP struct {
+	*httptest.Server
+	tools []protocol.ToolDescriptor
+	call  func(name string, args map[string]any) (protocol.CallResult, *protocol.ResponseError)
+
+	mu    sync.Mutex
+	calls []fakeToolCall
+}
+
+type fakeToolCall struct {
+	Name string
+	Args map[string]any
+}
+
+func newFakeMCP(t *testing.T, tools ...protocol.ToolDescriptor) *fakeMCP {
+	f := &fakeMCP{tools: tools}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req protocol.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := protocol.Response{JSONRPC: "2.0", ID: req.ID}
+		switch req.Method {
+		case "tools/list":
+			resp.Result = protocol.ListResult{Tools: f.tools}
+		case "tools/call":
+			var p protocol.CallParams
+			_ = json.Unmarshal(req.Params, &p)
+			args := map[string]any{}
+			_ = json.Unmarshal(p.Args, &args)
+			f.mu.Lock()
+			f.calls = append(f.calls, fakeToolCall{Name: p.Name, Args: args})
+			f.mu.Unlock()
+			if f.call == nil {
+				resp.Result = protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: "result of " + p.Name}}}
+				break
+			}
+			result, rerr := f.call(p.Name, args)
+			if rerr != nil {
+				resp.Error = rerr
+			} else {
+				resp.Result = result
+			}
+		default:
+			resp.Error = &protocol.ResponseError{Code: -32601, Message: "method not found: " + req.Method}
+		}
+		writeJSON(w, resp, http.StatusOK)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// toolCalls returns the tool calls received so far.
+func (f *fakeMCP) toolCalls() []fakeToolCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeToolCall(nil), f.calls...)
+}
+
+// tool describes a tool taking the given string properties.
+func tool(name string, props ...string) protocol.ToolDescriptor {
+	schema := &protocol.JSONSchema{Type: "object", Properties: map[string]protocol.JSONSchema{}}
+	for _, p := range props {
+		schema.Properties[p] = protocol.JSONSchema{Type: "string"}
+	}
+	return protocol.ToolDescriptor{Name: name, Description: "The " + name + " tool.", InputSchema: schema}
+}
+
+func quietLogger() *logrus.Entry {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return logrus.NewEntry(l)
+}
+
+// newTestHandler returns a handler without auth talking to llm and mcp, and its mux.
+func newTestHandler(t *testing.T, llm *fakeLLM, mcp *fakeMCP) (*Handler, *http.ServeMux) {
+	t.Helper()
+	h := NewHandler(quietLogger(), "", "sk-test", "gpt-4o-mini", llm.URL, mcp.URL)
+	mux := http.NewServeMux()
+	h.Register(mux)
+	return h, mux
+}
+
+// serve sends a request with a JSON body (none when body is nil) to mux.
+func serve(mux http.Handler, method, target string, body any, header ...string) *httptest.ResponseRecorder {
+	var r io.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		r = strings.NewReader(string(raw))
+	}
+	req := httptest.NewRequest(method, target, r)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// chat posts a one-message conversation to /v1/chat/completions.
+func chat(mux http.Handler, question string, header ...string) *httptest.ResponseRecorder {
+	return serve(mux, http.MethodPost, "/v1/chat/completions", map[string]any{
+		"messages": []OAChatMessage{{Role: "user", Content: question}},
+	}, header...)
+}
+
+// decodeAnswer decodes a successful completion from rec.
+func decodeAnswer(t *testing.T, rec *httptest.ResponseRecorder) ChatCompletionResponse {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp ChatCompletionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return resp
+}
+
+// decodeJSON decodes a 200 response from rec into v.
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+}
+
+func content(resp ChatCompletionResponse) string {
+	if len(resp.Choices) == 0 {
+		return ""
+	}
+	return resp.Choices[0].Message.Content
+}
+
+func TestChatCallsToolsAndAnswers(t *testing.T) {
+	llm := newFakeLLM(t, toolThenAnswer("payram_numbers_summary", `{"date_filter":"last_7_days"}`, "You took 12 payments."))
+	mcp := newFakeMCP(t, tool("payram_numbers_summary", "date_filter"))
+	_, mux := newTestHandler(t, llm, mcp)
+
+	resp := decodeAnswer(t, chat(mux, "How many payments this week?"))
+	if got := content(resp); got != "You took 12 payments." {
+		t.Fatalf("answer %q", got)
+	}
+	calls := mcp.toolCalls()
+	if len(calls) != 1 || calls[0].Name != "payram_numbers_summary" || calls[0].Args["date_filter"] != "last_7_days" {
+		t.Fatalf("tool calls %+v", calls)
+	}
+	reqs := llm.calls()
+	if len(reqs) != 2 {
+		t.Fatalf("%d llm calls, want 2", len(reqs))
+	}
+	if len(reqs[0].Tools) != 1 || reqs[0].Tools[0].Function.Name != "payram_numbers_summary" {
+		t.Fatalf("first call offered %+v", reqs[0].Tools)
+	}
+	last := reqs[1].Messages[len(reqs[1].Messages)-1]
+	if last.Role != "tool" || last.Content != "result of payram_numbers_summary" || last.ToolCallID != "call_1" {
+		t.Fatalf("follow-up ends with %+v", last)
+	}
+}
+
+func TestChatRejectsBadRequests(t *testing.T) {
+	_, mux := newTestHandler(t, newFakeLLM(t, nil), newFakeMCP(t))
+	cases := []struct {
+		name   string
+		method string
+		body   any
+		status int
+		code   string
+	}{
+		{"get", http.MethodGet, nil, http.StatusMethodNotAllowed, ""},
+		{"no messages", http.MethodPost, map[string]any{"model": "gpt-4o-mini"}, http.StatusBadRequest, "missing_messages"},
+	}
+	for _, c := range cases {
+		rec := serve(mux, c.method, "/v1/chat/completions", c.body)
+		if rec.Code != c.status {
+			t.Errorf("%s: status %d, want %d", c.name, rec.Code, c.status)
+			continue
+		}
+		if c.code != "" {
+			if got := errorCode(decodeError(t, rec)); got != c.code {
+				t.Errorf("%s: code %q, want %q", c.name, got, c.code)
+			}
+		}
+	}
+}
+
+func TestChatReportsToolErrors(t *testing.T) {
+	llm := newFakeLLM(t, toolThenAnswer("payram_numbers_summary", `{}`, "unused"))
+	mcp := newFakeMCP(t, tool("payram_numbers_summary"))
+	mcp.call = func(string, map[string]any) (protocol.CallResult, *protocol.ResponseError) {
+		return protocol.CallResult{}, &protocol.ResponseError{Code: protocol.CodeUpstreamUnavailable, Message: "core returned 503"}
+	}
+	_, mux := newTestHandler(t, llm, mcp)
+
+	rec := chat(mux, "numbers?")
+	body := decodeError(t, rec)
+	if rec.Code != http.StatusBadGateway || errorCode(body) != "tool_call_failed" || !strings.Contains(body.Message, "core returned 503") {
+		t.Fatalf("got %d %+v", rec.Code, body)
+	}
+	if n := len(llm.calls()); n != 1 {
+		t.Fatalf("%d llm calls after a failed tool, want 1", n)
+	}
+}
+
+func TestChatMapsUpstreamErrors(t *testing.T) {
+	llm := newFakeLLM(t, func(int, ChatCompletionRequest) (int, any) {
+		return http.StatusBadRequest, map[string]any{"error": map[string]any{"message": "context too long", "type": "invalid_request_error", "code": "context_length_exceeded"}}
+	})
+	_, mux := newTestHandler(t, llm, newFakeMCP(t))
+
+	rec := chat(mux, "hi")
+	body := decodeError(t, rec)
+	if rec.Code != http.StatusBadRequest || errorCode(body) != "context_length_exceeded" {
+		t.Fatalf("got %d %+v", rec.Code, body)
+	}
+}
@@ -0,0 +1,297 @@
+package chatapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+	"github.com/payram/payram-analytics-mcp-server/internal/events"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+const (
+	livePath       = "/api/live"
+	liveTicketPath = livePath + "/ticket"
+	// liveTicketTTL is how long a ticket from liveTicketPath may wait to open the socket.
+	liveTicketTTL = 30 * time.Second
+
+	defaultLiveInterval = 15 * time.Second
+	// liveWindowMinutes is the period the live metrics cover.
+	liveWindowMinutes = 60
+	// liveLatest is how many of the newest events each update lists.
+	liveLatest = 5
+)
+
+// paidStates are the webhook payment states that count as a payment received.
+var paidStates = map[string]bool{"FILLED": true, "OVER_FILLED": true, "PARTIALLY_FILLED": true}
+
+// LiveMetrics is one update pushed on /api/live.
+type LiveMetrics struct {
+	Type          string            `json:"type"`
+	WindowMinutes int               `json:"window_minutes"`
+	Payments      int               `json:"payments"`
+	Totals        map[string]string `json:"totals"`
+	Latest        []LiveEvent       `json:"latest"`
+}
+
+// LiveEvent is a payment webhook as listed in LiveMetrics.
+type LiveEvent struct {
+	ReferenceID string    `json:"reference_id,omitempty"`
+	State       string    `json:"payment_state,omitempty"`
+	Amount      string    `json:"amount,omitempty"`
+	Currency    string    `json:"currency,omitempty"`
+	Received    time.Time `json:"received_at"`
+}
+
+// LiveTicket lets a browser open /api/live without putting its API key in the URL, where
+// proxies and access logs would keep it.
+type LiveTicket struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// liveTickets are the tickets issued and not yet used, each good for one socket.
+type liveTickets struct {
+	mu      sync.Mutex
+	tickets map[string]liveTicket
+}
+
+// liveTicket is the caller a ticket was issued to, as authenticate found it.
+type liveTicket struct {
+	id                  Identity
+	tenantID, tenantKey string
+	language            string
+	expires             time.Time
+}
+
+func (t *liveTickets) issue(ticket liveTicket) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for k, v := range t.tickets {
+		if now.After(v.expires) {
+			delete(t.tickets, k)
+		}
+	}
+	if t.tickets == nil {
+		t.tickets = map[string]liveTicket{}
+	}
+	t.tickets[token] = ticket
+	return token, nil
+}
+
+// redeem returns the caller of token and forgets it, so a ticket seen in a log is useless.
+func (t *liveTickets) redeem(token string) (liveTicket, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ticket, ok := t.tickets[token]
+	delete(t.tickets, token)
+	if !ok || time.Now().After(ticket.expires) {
+		return liveTicket{}, false
+	}
+	return ticket, true
+}
+
+type liveNotice struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// SetLiveInterval sets how often /api/live polls the MCP server for new payment events.
+func (h *Handler) SetLiveInterval(d time.Duration) {
+	h.liveInterval = d
+}
+
+// handleLive upgrades to a WebSocket and pushes LiveMetrics for the last hour whenever they
+// change, built from the payment webhooks the MCP server has received. When the MCP server
+// takes no webhooks it sends one "unavailable" notice and closes.
+//
+// Browsers cannot set headers on a WebSocket. Web sessions send their cookie, and Origin
+// stands in for the CSRF header, refusing cross-site pages. API key callers first POST to
+// liveTicketPath and pass the ticket as the "ticket" query parameter. The language may come
+// as the "language" query parameter.
+func (h *Handler) handleLive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, errTypeInvalidRequest, "cross_origin", "cross-origin WebSocket requests are not allowed")
+		return
+	}
+	q := r.URL.Query()
+	if v := q.Get("language"); v != "" && r.Header.Get("X-Language") == "" {
+		r.Header.Set("X-Language", v)
+	}
+	if token := q.Get("ticket"); token != "" {
+		ticket, ok := h.liveTickets.redeem(token)
+		if !ok {
+			h.logger.Warn("live: unknown or expired ticket")
+			writeError(w, http.StatusUnauthorized, errTypeAuthentication, "invalid_ticket", "invalid or expired live ticket")
+			return
+		}
+		ctx := withIdentity(r.Context(), ticket.id)
+		if ticket.tenantID != "" || ticket.tenantKey != "" {
+			ctx = chatserver.WithTenant(ctx, ticket.tenantID, ticket.tenantKey)
+		}
+		if ticket.language != "" {
+			ctx = i18n.WithLanguage(ctx, ticket.language)
+		}
+		r = r.WithContext(ctx)
+	} else {
+		if r.Header.Get("Origin") != "" && r.Header.Get(csrfHeader) == "" {
+			r.Header.Set(csrfHeader, "websocket")
+		}
+		var ok bool
+		if r, ok = h.authenticate(w, r); !ok {
+			return
+		}
+	}
+	log := h.log(r.Context())
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		log.Warnf("live: %v", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+	go func() {
+		_ = ws.readLoop()
+		cancel()
+	}()
+
+	interval := h.liveInterval
+	if interval <= 0 {
+		interval = defaultLiveInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last []byte
+	for {
+		msg, err := h.liveUpdate(ctx)
+		if errors.Is(err, chatserver.ErrEventsDisabled) {
+			_ = sendLive(ws, liveNotice{Type: "unavailable", Message: err.Error()})
+			_ = ws.Close(wsCloseNormal)
+			return
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warnf("live: %v", err)
+			msg = liveNotice{Type: "error", Message: "live metrics are unavailable right now"}
+		}
+		data, _ := json.Marshal(msg)
+		if !bytes.Equal(data, last) {
+			if err := ws.WriteText(data); err != nil {
+				_ = ws.conn.Close()
+				return
+			}
+			last = data
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleLiveTicket issues a ticket for /api/live to an authenticated caller. The socket it
+// opens runs as that caller, with the tenant and language of this request.
+func (h *Handler) handleLiveTicket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, _ := IdentityFrom(r.Context())
+	token, err := h.liveTickets.issue(liveTicket{
+		id:        id,
+		tenantID:  r.Header.Get(tenant.IDHeader),
+		tenantKey: r.Header.Get(tenant.KeyHeader),
+		language:  i18n.FromContext(r.Context()),
+		expires:   time.Now().Add(liveTicketTTL),
+	})
+	if err != nil {
+		h.log(r.Context()).Errorf("live ticket: %v", err)
+		writeError(w, http.StatusInternalServerError, errTypeAPI, "ticket_failed", "could not issue a live ticket")
+		return
+	}
+	writeJSON(w, LiveTicket{Ticket: token, ExpiresIn: int(liveTicketTTL / time.Second)}, http.StatusOK)
+}
+
+func sendLive(ws *wsConn, msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return ws.WriteText(data)
+}
+
+// liveUpdate builds the current metrics from the MCP server's recent events.
+func (h *Handler) liveUpdate(ctx context.Context) (any, error) {
+	list, err := h.mcp.RecentEvents(ctx, liveWindowMinutes)
+	if err != nil {
+		return nil, err
+	}
+	return liveMetrics(list), nil
+}
+
+// liveMetrics summarizes events, newest first. A payment's newest event decides its state,
+// so one paid after several webhooks counts once.
+func liveMetrics(list []events.Event) LiveMetrics {
+	m := LiveMetrics{Type: "metrics", WindowMinutes: liveWindowMinutes, Totals: map[string]string{}, Latest: []LiveEvent{}}
+	sums := map[string]float64{}
+	seen := map[string]bool{}
+	for i, e := range list {
+		if i < liveLatest {
+			m.Latest = append(m.Latest, LiveEvent{ReferenceID: e.ReferenceID, State: e.State, Amount: e.Amount, Currency: e.Currency, Received: e.Received})
+		}
+		if e.ReferenceID != "" {
+			if seen[e.ReferenceID] {
+				continue
+			}
+			seen[e.ReferenceID] = true
+		}
+		if !paidStates[e.State] {
+			continue
+		}
+		m.Payments++
+		if amount, err := strconv.ParseFloat(e.Amount, 64); err == nil && e.Currency != "" {
+			sums[e.Currency] += amount
+		}
+	}
+	for cur, sum := range sums {
+		m.Totals[cur] = strconv.FormatFloat(sum, 'f', -1, 64)
+	}
+	return m
+}
+
+// sameOrigin reports whether r has no Origin header or one naming the host it was sent to.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
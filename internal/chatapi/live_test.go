@@ -0,0 +1,125 @@
+package chatapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/events"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+// liveMCP serves one paid payment on /events and remembers the tenant each poll came with.
+type liveMCP struct {
+	*httptest.Server
+	mu      sync.Mutex
+	tenants []string
+}
+
+func newLiveMCP(t *testing.T) *liveMCP {
+	m := &liveMCP{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.tenants = append(m.tenants, r.Header.Get(tenant.IDHeader))
+		m.mu.Unlock()
+		writeJSON(w, map[string]any{"events": []events.Event{{ReferenceID: "ref-1", State: "FILLED", Amount: "30", Currency: "USDT", Received: time.Now()}}}, http.StatusOK)
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *liveMCP) polledTenants() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.tenants...)
+}
+
+// openLive performs a WebSocket handshake for target on srv and returns the response and,
+// after a 101, a reader for the frames.
+func openLive(t *testing.T, srv *httptest.Server, target string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, _ = io.WriteString(conn, "GET "+target+" HTTP/1.1\r\nHost: "+srv.Listener.Addr().String()+"\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, br
+}
+
+func TestLiveTicket(t *testing.T) {
+	mcp := newLiveMCP(t)
+	h := NewHandler(quietLogger(), "secret", "sk-test", "gpt-4o-mini", "http://127.0.0.1:1", mcp.URL)
+	mux := http.NewServeMux()
+	h.Register(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// The API key in the URL is not accepted, and tickets need the usual credentials.
+	if resp, _ := openLive(t, srv, livePath+"?key=secret"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("?key= opened the socket: %d", resp.StatusCode)
+	}
+	if rec := serve(mux, http.MethodPost, liveTicketPath, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("ticket without a key: %d", rec.Code)
+	}
+	if rec := serve(mux, http.MethodGet, liveTicketPath, nil, "X-MCP-Key", "secret"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET ticket: %d", rec.Code)
+	}
+
+	var ticket LiveTicket
+	decodeJSON(t, serve(mux, http.MethodPost, liveTicketPath, nil, "X-MCP-Key", "secret", tenant.IDHeader, "acme", "X-Language", "es"), &ticket)
+	if ticket.Ticket == "" || ticket.ExpiresIn != 30 {
+		t.Fatalf("ticket %+v", ticket)
+	}
+	target := livePath + "?ticket=" + url.QueryEscape(ticket.Ticket)
+	resp, br := openLive(t, srv, target)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("ticket refused: %d", resp.StatusCode)
+	}
+	op, payload := readServerFrame(t, br)
+	var m LiveMetrics
+	if err := json.Unmarshal(payload, &m); op != wsOpText || err != nil || m.Type != "metrics" || m.Payments != 1 {
+		t.Fatalf("first update op %d: %s", op, payload)
+	}
+	if polled := mcp.polledTenants(); len(polled) == 0 || polled[0] != "acme" {
+		t.Fatalf("events polled for tenants %q, want the ticket's", polled)
+	}
+
+	// Each ticket opens one socket.
+	if resp, _ := openLive(t, srv, target); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("reused ticket: %d", resp.StatusCode)
+	}
+
+	// Expired tickets are refused.
+	decodeJSON(t, serve(mux, http.MethodPost, liveTicketPath, nil, "X-MCP-Key", "secret"), &ticket)
+	h.liveTickets.mu.Lock()
+	issued := h.liveTickets.tickets[ticket.Ticket]
+	issued.expires = time.Now().Add(-time.Second)
+	h.liveTickets.tickets[ticket.Ticket] = issued
+	h.liveTickets.mu.Unlock()
+	if resp, _ := openLive(t, srv, livePath+"?ticket="+url.QueryEscape(ticket.Ticket)); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expired ticket: %d", resp.StatusCode)
+	}
+}
+
+func TestLiveRefusesCrossOrigin(t *testing.T) {
+	_, mux := newTestHandler(t, newFakeLLM(t, nil), newFakeMCP(t))
+	rec := serve(mux, http.MethodGet, livePath, nil, "Origin", "https://evil.example")
+	if rec.Code != http.StatusForbidden || errorCode(decodeError(t, rec)) != "cross_origin" {
+		t.Fatalf("got %d %s", rec.Code, rec.Body)
+	}
+}
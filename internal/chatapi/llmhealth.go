@@ -0,0 +1,324 @@
+package chatapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	readyPath = "/readyz"
+	// maxProbeTimeout caps one probe, so a hung endpoint is marked down before the next
+	// probe is due.
+	maxProbeTimeout = 10 * time.Second
+	maxProbeError   = 200
+)
+
+// LLMProvider is an OpenAI-compatible endpoint chat completions can be sent to. The primary
+// provider comes from OPENAI_BASE_URL and OPENAI_API_KEY; fallbacks are tried in order when
+// it is down.
+type LLMProvider struct {
+	Name    string `json:"name"`
+	BaseURL string `json:"base_url"`
+	// APIKeyEnv names the environment variable holding the provider's key, so keys stay out
+	// of the file.
+	APIKeyEnv string `json:"api_key_env"`
+	// Model replaces the requested model, for providers that name it differently. Empty
+	// keeps the requested one.
+	Model string `json:"model,omitempty"`
+
+	key string
+}
+
+// LoadLLMFallbacks reads fallback providers from a JSON file of the form
+// {"providers": [{"name": ..., "base_url": ..., "api_key_env": ..., "model": ...}]}. An empty
+// path returns none.
+func LoadLLMFallbacks(file string) ([]LLMProvider, error) {
+	if strings.TrimSpace(file) == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read llm fallbacks: %w", err)
+	}
+	var cfg struct {
+		Providers []LLMProvider `json:"providers"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("decode llm fallbacks: %w", err)
+	}
+	for i := range cfg.Providers {
+		p := &cfg.Providers[i]
+		p.BaseURL = strings.TrimRight(strings.TrimSpace(p.BaseURL), "/")
+		if p.BaseURL == "" {
+			return nil, fmt.Errorf("llm fallbacks %s: providers[%d]: base_url is required", file, i)
+		}
+		if p.APIKeyEnv != "" {
+			if p.key = os.Getenv(p.APIKeyEnv); p.key == "" {
+				return nil, fmt.Errorf("llm fallbacks %s: providers[%d]: %s is not set", file, i, p.APIKeyEnv)
+			}
+		}
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("fallback-%d", i+1)
+		}
+	}
+	return cfg.Providers, nil
+}
+
+// llmProviders holds the configured providers and what was last learned about each, from
+// probes and from failed chat calls.
+type llmProviders struct {
+	mu        sync.Mutex
+	providers []LLMProvider
+	status    []providerStatus
+	// probing is set while ProbeLLM runs. Without it, nothing would bring a provider marked
+	// down by a failed call back, so calls do not mark providers down.
+	probing bool
+}
+
+type providerStatus struct {
+	// down is set by a failed probe or call and cleared by the next successful probe.
+	down    bool
+	checked time.Time
+	latency time.Duration
+	err     string
+}
+
+// ProviderStatus is one provider's entry in the /readyz response.
+type ProviderStatus struct {
+	Name      string     `json:"name"`
+	BaseURL   string     `json:"base_url"`
+	Healthy   bool       `json:"healthy"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	LatencyMS int64      `json:"latency_ms,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// ReadyResponse is the body of /readyz.
+type ReadyResponse struct {
+	Ready     bool             `json:"ready"`
+	Providers []ProviderStatus `json:"providers"`
+}
+
+func (l *llmProviders) set(providers []LLMProvider) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.providers = providers
+	l.status = make([]providerStatus, len(providers))
+}
+
+// order returns the indexes of the providers to try: the healthy ones in configured order,
+// then the ones marked down, which are still better than failing outright.
+func (l *llmProviders) order() []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	up := make([]int, 0, len(l.providers))
+	var down []int
+	for i, s := range l.status {
+		if s.down {
+			down = append(down, i)
+		} else {
+			up = append(up, i)
+		}
+	}
+	return append(up, down...)
+}
+
+func (l *llmProviders) provider(i int) LLMProvider {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.providers[i]
+}
+
+// note records the outcome of a probe or call of provider i and reports whether its health
+// changed. Calls only ever mark a provider down; coming back up is left to the probe.
+func (l *llmProviders) note(i int, err error, latency time.Duration, probe bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !probe && !l.probing {
+		return false
+	}
+	s := &l.status[i]
+	was := s.down
+	switch {
+	case err != nil:
+		s.down = true
+		s.err = err.Error()
+		if len(s.err) > maxProbeError {
+			s.err = s.err[:maxProbeError] + "..."
+		}
+	case probe:
+		s.down = false
+		s.err = ""
+	default:
+		return false
+	}
+	if probe {
+		s.checked = time.Now()
+		s.latency = latency
+	}
+	return s.down != was
+}
+
+func (l *llmProviders) setProbing(on bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.probing = on
+}
+
+func (l *llmProviders) ready() ReadyResponse {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	resp := ReadyResponse{Providers: make([]ProviderStatus, len(l.providers))}
+	for i, p := range l.providers {
+		s := l.status[i]
+		ps := ProviderStatus{Name: p.Name, BaseURL: p.BaseURL, Healthy: !s.down, Error: s.err}
+		if !s.checked.IsZero() {
+			checked := s.checked
+			ps.CheckedAt = &checked
+			ps.LatencyMS = s.latency.Milliseconds()
+		}
+		resp.Ready = resp.Ready || ps.Healthy
+		resp.Providers[i] = ps
+	}
+	return resp
+}
+
+// SetLLMFallbacks adds providers to fail over to, in order, when the primary is down.
+func (h *Handler) SetLLMFallbacks(fallbacks []LLMProvider) {
+	primary := h.llm.provider(0)
+	h.llm.set(append([]LLMProvider{primary}, fallbacks...))
+}
+
+// ProbeLLM checks every provider every interval until ctx is done, so calls go to a working
+// provider without first waiting on one that is down. /readyz reports the results.
+func (h *Handler) ProbeLLM(ctx context.Context, interval time.Duration) {
+	h.llm.setProbing(true)
+	defer h.llm.setProbing(false)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.probeLLM(ctx, min(interval, maxProbeTimeout))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) probeLLM(ctx context.Context, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, i := range h.llm.order() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := h.llm.provider(i)
+			pctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := h.probeProvider(pctx, p)
+			if ctx.Err() != nil {
+				return
+			}
+			if h.llm.note(i, err, time.Since(start), true) {
+				h.logProviderChange(p, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// probeProvider lists the provider's models, which every OpenAI-compatible endpoint serves
+// cheaply and which checks the key as well as the connection.
+func (h *Handler) probeProvider(ctx context.Context, p LLMProvider) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.key)
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return parseUpstreamError(resp.StatusCode, body)
+	}
+	return nil
+}
+
+func (h *Handler) logProviderChange(p LLMProvider, err error) {
+	entry := h.logger.WithFields(logrus.Fields{"provider": p.Name, "base_url": p.BaseURL})
+	if err != nil {
+		entry.WithError(err).Warn("llm provider down")
+		return
+	}
+	entry.Info("llm provider back up")
+}
+
+// providerFailed reports whether a chat call that failed with err, after receiving raw,
+// says the provider is unavailable rather than that the request itself was bad, so that the
+// next provider is worth trying.
+func providerFailed(ctx context.Context, err error, raw []byte) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var up *upstreamError
+	if errors.As(err, &up) {
+		return up.status >= 500 || up.status == http.StatusTooManyRequests || up.status == http.StatusUnauthorized
+	}
+	// No response at all: the provider could not be reached.
+	return raw == nil
+}
+
+// postChat posts body to provider p's chat completions endpoint.
+func (h *Handler) postChat(ctx context.Context, p LLMProvider, body []byte) (ChatCompletionResponse, []byte, error) {
+	var resp ChatCompletionResponse
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return resp, nil, fmt.Errorf("build openai request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.key)
+
+	httpResp, err := h.httpClient.Do(httpReq)
+	if err != nil {
+		return resp, nil, fmt.Errorf("call openai: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, _ := io.ReadAll(httpResp.Body)
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return resp, respBody, parseUpstreamError(httpResp.StatusCode, respBody)
+	}
+
+	if err := json.NewDecoder(bytes.NewReader(respBody)).Decode(&resp); err != nil {
+		return resp, respBody, fmt.Errorf("decode openai response: %w", err)
+	}
+	return resp, respBody, nil
+}
+
+func (h *Handler) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	resp := h.llm.ready()
+	status := http.StatusOK
+	if !resp.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, resp, status)
+}
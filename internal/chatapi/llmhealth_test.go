@@ -0,0 +1,252 @@
+package chatapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Provider behaviours for flakyLLM.
+const (
+	providerUp int32 = iota
+	providerFailing
+	providerHanging
+)
+
+// flakyLLM is an OpenAI-compatible provider that answers "from <name>", fails with a 503,
+// or hangs until the client gives up, on both /models and /chat/completions.
+type flakyLLM struct {
+	*httptest.Server
+	mode atomic.Int32
+
+	mu     sync.Mutex
+	models []string
+}
+
+func newFlakyLLM(t *testing.T, name string, mode int32) *flakyLLM {
+	f := &flakyLLM{}
+	f.mode.Store(mode)
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			var req ChatCompletionRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			f.mu.Lock()
+			f.models = append(f.models, req.Model)
+			f.mu.Unlock()
+		}
+		switch f.mode.Load() {
+		case providerFailing:
+			writeJSON(w, map[string]any{"error": map[string]any{"message": name + " is overloaded", "type": "server_error"}}, http.StatusServiceUnavailable)
+		case providerHanging:
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			if r.URL.Path == "/models" {
+				writeJSON(w, map[string]any{"data": []any{}}, http.StatusOK)
+				return
+			}
+			writeJSON(w, answer("from "+name), http.StatusOK)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// chatModels returns the model of every chat completion received so far.
+func (f *flakyLLM) chatModels() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.models...)
+}
+
+// newFailoverHandler returns a handler whose primary provider is primary, failing over to
+// fallback, which renames the model, with calls timing out after 200ms.
+func newFailoverHandler(t *testing.T, primary, fallback *flakyLLM) (*Handler, *http.ServeMux) {
+	t.Helper()
+	h := NewHandler(quietLogger(), "", "sk-test", "gpt-4o-mini", primary.URL, newFakeMCP(t).URL)
+	h.httpClient = &http.Client{Timeout: 200 * time.Millisecond}
+	h.SetLLMFallbacks([]LLMProvider{{Name: "backup", BaseURL: fallback.URL, Model: "backup-model"}})
+	mux := http.NewServeMux()
+	h.Register(mux)
+	return h, mux
+}
+
+func ready(t *testing.T, mux http.Handler) (int, ReadyResponse) {
+	t.Helper()
+	rec := serve(mux, http.MethodGet, readyPath, nil)
+	var resp ReadyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return rec.Code, resp
+}
+
+func TestChatFailsOverToTheNextProvider(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		mode int32
+	}{
+		{"5xx", providerFailing},
+		{"timeout", providerHanging},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			primary, fallback := newFlakyLLM(t, "primary", c.mode), newFlakyLLM(t, "backup", providerUp)
+			_, mux := newFailoverHandler(t, primary, fallback)
+
+			if got := content(decodeAnswer(t, chat(mux, "hi"))); got != "from backup" {
+				t.Fatalf("answer %q", got)
+			}
+			if got := primary.chatModels(); len(got) != 1 || got[0] != "gpt-4o-mini" {
+				t.Fatalf("primary got %v", got)
+			}
+			if got := fallback.chatModels(); len(got) != 1 || got[0] != "backup-model" {
+				t.Fatalf("fallback got %v, want the provider's model", got)
+			}
+		})
+	}
+}
+
+func TestChatDoesNotFailOverOnBadRequests(t *testing.T) {
+	primary := newFakeLLM(t, func(int, ChatCompletionRequest) (int, any) {
+		return http.StatusBadRequest, map[string]any{"error": map[string]any{"message": "context too long", "code": "context_length_exceeded"}}
+	})
+	fallback := newFlakyLLM(t, "backup", providerUp)
+	h := NewHandler(quietLogger(), "", "sk-test", "gpt-4o-mini", primary.URL, newFakeMCP(t).URL)
+	h.SetLLMFallbacks([]LLMProvider{{Name: "backup", BaseURL: fallback.URL}})
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	if rec := chat(mux, "hi"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if n := len(fallback.chatModels()); n != 0 {
+		t.Fatalf("%d calls failed over for a bad request", n)
+	}
+}
+
+func TestReadyzReportsProviderHealth(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		mode  int32
+		error string
+	}{
+		{"5xx", providerFailing, "primary is overloaded"},
+		{"timeout", providerHanging, "deadline exceeded"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			primary, fallback := newFlakyLLM(t, "primary", c.mode), newFlakyLLM(t, "backup", providerUp)
+			h, mux := newFailoverHandler(t, primary, fallback)
+
+			// Nothing has been probed yet, so both count as healthy.
+			if code, resp := ready(t, mux); code != http.StatusOK || !resp.Ready || len(resp.Providers) != 2 || resp.Providers[0].CheckedAt != nil {
+				t.Fatalf("before probing: %d %+v", code, resp)
+			}
+
+			h.probeLLM(context.Background(), 100*time.Millisecond)
+			code, resp := ready(t, mux)
+			if code != http.StatusOK || !resp.Ready {
+				t.Fatalf("with a healthy fallback: %d %+v", code, resp)
+			}
+			p, b := resp.Providers[0], resp.Providers[1]
+			if p.Name != "primary" || p.Healthy || !strings.Contains(p.Error, c.error) || p.CheckedAt == nil {
+				t.Fatalf("primary %+v", p)
+			}
+			if b.Name != "backup" || !b.Healthy || b.Error != "" || b.CheckedAt == nil {
+				t.Fatalf("fallback %+v", b)
+			}
+
+			// A provider marked down is tried last, so chats go straight to the fallback.
+			if got := content(decodeAnswer(t, chat(mux, "hi"))); got != "from backup" {
+				t.Fatalf("answer %q", got)
+			}
+			if n := len(primary.chatModels()); n != 0 {
+				t.Fatalf("%d calls to the provider marked down", n)
+			}
+
+			fallback.mode.Store(c.mode)
+			h.probeLLM(context.Background(), 100*time.Millisecond)
+			if code, resp := ready(t, mux); code != http.StatusServiceUnavailable || resp.Ready || resp.Providers[1].Healthy {
+				t.Fatalf("with every provider down: %d %+v", code, resp)
+			}
+
+			primary.mode.Store(providerUp)
+			h.probeLLM(context.Background(), 100*time.Millisecond)
+			if code, resp := ready(t, mux); code != http.StatusOK || !resp.Providers[0].Healthy || resp.Providers[0].Error != "" {
+				t.Fatalf("after the primary recovered: %d %+v", code, resp)
+			}
+		})
+	}
+}
+
+func TestFailedCallsMarkProvidersDownOnlyWhileProbing(t *testing.T) {
+	primary, fallback := newFlakyLLM(t, "primary", providerFailing), newFlakyLLM(t, "backup", providerUp)
+	h, mux := newFailoverHandler(t, primary, fallback)
+
+	// Without probes nothing would bring the primary back, so a failed call leaves it up.
+	decodeAnswer(t, chat(mux, "hi"))
+	if _, resp := ready(t, mux); !resp.Providers[0].Healthy {
+		t.Fatalf("marked down without probing: %+v", resp.Providers[0])
+	}
+
+	h.llm.setProbing(true)
+	defer h.llm.setProbing(false)
+	decodeAnswer(t, chat(mux, "hi"))
+	decodeAnswer(t, chat(mux, "hi"))
+	if _, resp := ready(t, mux); resp.Providers[0].Healthy || !strings.Contains(resp.Providers[0].Error, "overloaded") {
+		t.Fatalf("not marked down while probing: %+v", resp.Providers[0])
+	}
+	if n := len(primary.chatModels()); n != 2 {
+		t.Fatalf("%d calls to the primary, want it skipped once marked down", n)
+	}
+}
+
+func TestLoadLLMFallbacks(t *testing.T) {
+	if providers, err := LoadLLMFallbacks(""); err != nil || providers != nil {
+		t.Fatalf("no file: %+v, %v", providers, err)
+	}
+	t.Setenv("TEST_FALLBACK_KEY", "sk-fallback")
+	dir := t.TempDir()
+	write := func(body string) string {
+		file := filepath.Join(dir, "fallbacks.json")
+		if err := os.WriteFile(file, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	providers, err := LoadLLMFallbacks(write(`{"providers":[
+		{"name":"azure","base_url":" https://azure.example/v1/ ","api_key_env":"TEST_FALLBACK_KEY","model":"gpt-4o"},
+		{"base_url":"http://localhost:11434/v1"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 {
+		t.Fatalf("%d providers", len(providers))
+	}
+	if p := providers[0]; p.Name != "azure" || p.BaseURL != "https://azure.example/v1" || p.key != "sk-fallback" || p.Model != "gpt-4o" {
+		t.Fatalf("first %+v", p)
+	}
+	if p := providers[1]; p.Name != "fallback-2" || p.key != "" {
+		t.Fatalf("second %+v", p)
+	}
+
+	for body, want := range map[string]string{
+		`{"providers":[{"name":"x"}]}`: "base_url is required",
+		`{"providers":[{"base_url":"http://x","api_key_env":"TEST_UNSET_FALLBACK_KEY"}]}`: "TEST_UNSET_FALLBACK_KEY is not set",
+		`{"providers":{}}`: "decode llm fallbacks",
+	} {
+		if _, err := LoadLLMFallbacks(write(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", body, err, want)
+		}
+	}
+}
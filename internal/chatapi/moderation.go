@@ -0,0 +1,207 @@
+package chatapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/pii"
+)
+
+const (
+	defaultModerationModel = "omni-moderation-latest"
+	withheldReply          = "This answer was withheld by the content filter."
+)
+
+// ModerationConfig is the content filter run on final assistant replies before they are
+// returned to the caller.
+type ModerationConfig struct {
+	// Rules redact matching text, in order.
+	Rules []ModerationRule `json:"rules"`
+	// OpenAI, when set, sends each reply to the OpenAI moderation endpoint and withholds
+	// flagged ones.
+	OpenAI *OpenAIModeration `json:"openai,omitempty"`
+}
+
+// ModerationRule replaces every match of Pattern, or of the named Builtin pattern (see
+// pii.Pattern), with Replacement ("[redacted <name>]" when empty).
+type ModerationRule struct {
+	Name        string `json:"name"`
+	Pattern     string `json:"pattern,omitempty"`
+	Builtin     string `json:"builtin,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// OpenAIModeration configures the OpenAI moderation check. A failed check lets the reply
+// through unless FailClosed is set.
+type OpenAIModeration struct {
+	Model      string `json:"model,omitempty"`
+	FailClosed bool   `json:"fail_closed,omitempty"`
+}
+
+// LoadModeration reads a moderation config file. An empty path disables moderation and
+// returns nil.
+func LoadModeration(file string) (*ModerationConfig, error) {
+	if strings.TrimSpace(file) == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read moderation config: %w", err)
+	}
+	var c ModerationConfig
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("decode moderation config: %w", err)
+	}
+	if err := c.compile(); err != nil {
+		return nil, fmt.Errorf("moderation config %s: %w", file, err)
+	}
+	return &c, nil
+}
+
+func (c *ModerationConfig) compile() error {
+	if len(c.Rules) == 0 && c.OpenAI == nil {
+		return fmt.Errorf("no rules and no openai check configured")
+	}
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		pattern := r.Pattern
+		switch {
+		case r.Builtin != "" && r.Pattern != "":
+			return fmt.Errorf("rules[%d]: set pattern or builtin, not both", i)
+		case r.Builtin != "":
+			var ok bool
+			if pattern, ok = pii.Pattern(r.Builtin); !ok {
+				return fmt.Errorf("rules[%d]: unknown builtin %q", i, r.Builtin)
+			}
+		case r.Pattern == "":
+			return fmt.Errorf("rules[%d]: pattern or builtin is required", i)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		r.re = re
+		if r.Name == "" {
+			r.Name = r.Builtin
+		}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i)
+		}
+		if r.Replacement == "" {
+			r.Replacement = "[redacted " + r.Name + "]"
+		}
+	}
+	if c.OpenAI != nil && c.OpenAI.Model == "" {
+		c.OpenAI.Model = defaultModerationModel
+	}
+	return nil
+}
+
+// SetModeration enables the content filter on final replies; nil disables it.
+func (h *Handler) SetModeration(c *ModerationConfig) {
+	h.moderation = c
+}
+
+// moderate filters the reply of every choice in resp in place: rules redact their matches,
+// then the OpenAI check, if configured, withholds flagged replies.
+func (h *Handler) moderate(ctx context.Context, resp *ChatCompletionResponse) {
+	c := h.moderation
+	if c == nil {
+		return
+	}
+	log := h.log(ctx)
+	for i := range resp.Choices {
+		msg := &resp.Choices[i].Message
+		if msg.Content == "" {
+			continue
+		}
+		var redacted []string
+		for _, r := range c.Rules {
+			if r.re.MatchString(msg.Content) {
+				msg.Content = r.re.ReplaceAllLiteralString(msg.Content, r.Replacement)
+				redacted = append(redacted, r.Name)
+			}
+		}
+		if len(redacted) > 0 {
+			log.Warnf("moderation: redacted %s from reply", strings.Join(redacted, ", "))
+		}
+		if c.OpenAI == nil {
+			continue
+		}
+		flagged, err := h.checkModeration(ctx, c.OpenAI.Model, msg.Content)
+		if err != nil {
+			log.Errorf("moderation check failed: %v", err)
+			if !c.OpenAI.FailClosed {
+				continue
+			}
+			flagged = []string{"check failed"}
+		}
+		if len(flagged) > 0 {
+			log.Warnf("moderation: withheld reply (%s)", strings.Join(flagged, ", "))
+			msg.Content = withheldReply
+			resp.Choices[i].FinishReason = "content_filter"
+		}
+	}
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// checkModeration asks the OpenAI moderation endpoint about text and returns the categories
+// it was flagged for, if any.
+func (h *Handler) checkModeration(ctx context.Context, model, text string) ([]string, error) {
+	body, err := json.Marshal(map[string]string{"model": model, "input": text})
+	if err != nil {
+		return nil, fmt.Errorf("encode moderation request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.openaiBase+"/moderations", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build moderation request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+h.openaiKey)
+
+	httpResp, err := h.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("call moderation: %w", err)
+	}
+	defer httpResp.Body.Close()
+	respBody, _ := io.ReadAll(httpResp.Body)
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, parseUpstreamError(httpResp.StatusCode, respBody)
+	}
+	var mr moderationResponse
+	if err := json.Unmarshal(respBody, &mr); err != nil {
+		return nil, fmt.Errorf("decode moderation response: %w", err)
+	}
+	var flagged []string
+	for _, r := range mr.Results {
+		if !r.Flagged {
+			continue
+		}
+		for name, hit := range r.Categories {
+			if hit && !slices.Contains(flagged, name) {
+				flagged = append(flagged, name)
+			}
+		}
+		if len(flagged) == 0 {
+			flagged = append(flagged, "flagged")
+		}
+	}
+	slices.Sort(flagged)
+	return flagged, nil
+}
@@ -0,0 +1,136 @@
+package chatapi
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLoadModeration(t *testing.T) {
+	if c, err := LoadModeration(" "); c != nil || err != nil {
+		t.Fatalf("empty path: %+v, %v", c, err)
+	}
+	dir := t.TempDir()
+	write := func(body string) string {
+		file := filepath.Join(dir, "moderation.json")
+		if err := os.WriteFile(file, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	c, err := LoadModeration(write(`{"rules":[{"builtin":"email"},{"pattern":"sk-[a-z0-9]+"},{"name":"iban","pattern":"DE[0-9]{20}","replacement":"[iban]"}],"openai":{}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := [][2]string{}
+	for _, r := range c.Rules {
+		got = append(got, [2]string{r.Name, r.Replacement})
+	}
+	want := [][2]string{{"email", "[redacted email]"}, {"rule 1", "[redacted rule 1]"}, {"iban", "[iban]"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("rules %v, want %v", got, want)
+	}
+	if c.OpenAI.Model != defaultModerationModel {
+		t.Fatalf("model %q", c.OpenAI.Model)
+	}
+
+	for body, want := range map[string]string{
+		`{}`:                       "no rules and no openai check",
+		`{"rules":[{"name":"x"}]}`: "pattern or builtin is required",
+		`{"rules":[{"builtin":"email","pattern":"x"}]}`: "not both",
+		`{"rules":[{"builtin":"ssn"}]}`:                 `unknown builtin "ssn"`,
+		`{"rules":[{"pattern":"("}]}`:                   "rules[0]",
+		`not json`:                                      "decode moderation config",
+	} {
+		if _, err := LoadModeration(write(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", body, err, want)
+		}
+	}
+}
+
+func TestModerationRedactsReplies(t *testing.T) {
+	llm := newFakeLLM(t, func(int, ChatCompletionRequest) (int, any) {
+		return http.StatusOK, answer("Refund sent to ada@example.com, key sk-abc123, and bob@example.org.")
+	})
+	h, mux := newTestHandler(t, llm, newFakeMCP(t))
+	c := &ModerationConfig{Rules: []ModerationRule{{Builtin: "email"}, {Name: "key", Pattern: `sk-[a-z0-9]+`, Replacement: "[key]"}}}
+	if err := c.compile(); err != nil {
+		t.Fatal(err)
+	}
+	h.SetModeration(c)
+
+	resp := decodeAnswer(t, chat(mux, "who got the refund?"))
+	if got := content(resp); got != "Refund sent to [redacted email], key [key], and [redacted email]." {
+		t.Fatalf("answer %q", got)
+	}
+	if resp.Choices[0].FinishReason != "stop" {
+		t.Fatalf("finish reason %q", resp.Choices[0].FinishReason)
+	}
+}
+
+func TestModerationEndpointDecision(t *testing.T) {
+	flagged := map[string]any{"results": []map[string]any{{"flagged": true, "categories": map[string]bool{"violence": true, "hate": true, "sexual": false}}}}
+	clean := map[string]any{"results": []map[string]any{{"flagged": false, "categories": map[string]bool{"violence": false}}}}
+	cases := []struct {
+		name       string
+		status     int
+		body       any
+		failClosed bool
+		withheld   bool
+	}{
+		{"allowed", http.StatusOK, clean, false, false},
+		{"flagged", http.StatusOK, flagged, false, true},
+		{"flagged without categories", http.StatusOK, map[string]any{"results": []map[string]any{{"flagged": true}}}, false, true},
+		{"endpoint fails open", http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": "down"}}, false, false},
+		{"endpoint fails closed", http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": "down"}}, true, true},
+		{"undecodable response fails closed", http.StatusOK, "not a moderation result", true, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var checks atomic.Int32
+			llm := newFakeLLM(t, func(_ int, req ChatCompletionRequest) (int, any) {
+				if req.Model == defaultModerationModel {
+					checks.Add(1)
+					return c.status, c.body
+				}
+				return http.StatusOK, answer("You took 12 payments.")
+			})
+			h, mux := newTestHandler(t, llm, newFakeMCP(t))
+			mc := &ModerationConfig{OpenAI: &OpenAIModeration{FailClosed: c.failClosed}}
+			if err := mc.compile(); err != nil {
+				t.Fatal(err)
+			}
+			h.SetModeration(mc)
+
+			resp := decodeAnswer(t, chat(mux, "payments?"))
+			if n := checks.Load(); n != 1 {
+				t.Fatalf("%d moderation checks", n)
+			}
+			got, finish := content(resp), resp.Choices[0].FinishReason
+			if c.withheld && (got != withheldReply || finish != "content_filter") {
+				t.Fatalf("not withheld: %q (%s)", got, finish)
+			}
+			if !c.withheld && (got != "You took 12 payments." || finish != "stop") {
+				t.Fatalf("withheld: %q (%s)", got, finish)
+			}
+		})
+	}
+}
+
+func TestCheckModerationListsFlaggedCategories(t *testing.T) {
+	llm := newFakeLLM(t, func(int, ChatCompletionRequest) (int, any) {
+		return http.StatusOK, map[string]any{"results": []map[string]any{
+			{"flagged": true, "categories": map[string]bool{"violence": true, "hate": true}},
+			{"flagged": true, "categories": map[string]bool{"hate": true, "self-harm": false}},
+		}}
+	})
+	h, _ := newTestHandler(t, llm, newFakeMCP(t))
+	got, err := h.checkModeration(t.Context(), defaultModerationModel, "text")
+	if err != nil || strings.Join(got, ",") != "hate,violence" {
+		t.Fatalf("flagged %v, %v", got, err)
+	}
+}
@@ -0,0 +1,433 @@
+package chatapi
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	oidcKeysTTL        = time.Hour
+	oidcRefetchBackoff = time.Minute
+	oidcClockSkew      = time.Minute
+
+	// analyticsTokenHeader carries the analytics token when Authorization holds an SSO token.
+	analyticsTokenHeader = "X-Analytics-Token"
+)
+
+// OIDCConfig enables JWT bearer authentication against an OIDC issuer.
+type OIDCConfig struct {
+	Issuer   string
+	Audience string
+	// UsernameClaim names the claim logged as the user (default: email, then sub).
+	UsernameClaim string
+	// ScopesClaim holds the caller's scopes or groups: a space-separated string or a list (default "scope").
+	ScopesClaim string
+	// ToolScopes maps a scope to the tool name patterns (path.Match globs) it grants. Nil allows every tool.
+	ToolScopes map[string][]string
+}
+
+// LoadToolScopes reads a JSON object mapping scope names to tool patterns, e.g.
+// {"analytics:read": ["payram_*"], "finance": ["payram_payments_summary"]}.
+func LoadToolScopes(file string) (map[string][]string, error) {
+	scopes := map[string][]string{}
+	if err := readJSONFile(file, &scopes); err != nil {
+		return nil, err
+	}
+	for scope, patterns := range scopes {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("tool scope %q: invalid pattern %q", scope, p)
+			}
+		}
+	}
+	return scopes, nil
+}
+
+// OIDCVerifier validates RS*/ES* signed JWTs with keys from the issuer's JWKS.
+type OIDCVerifier struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	jwksURI   string
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// lastAttempt is when the last fetch finished, successful or not, and fetchErr is its
+	// error. Fetches are at most once per oidcRefetchBackoff, so a failing issuer is not
+	// asked again on every request.
+	lastAttempt time.Time
+	fetchErr    error
+	// fetching is closed when the JWKS fetch in flight, if any, finishes.
+	fetching chan struct{}
+}
+
+// NewOIDCVerifier returns a verifier for cfg. Discovery and JWKS are fetched on first use.
+func NewOIDCVerifier(cfg OIDCConfig) (*OIDCVerifier, error) {
+	cfg.Issuer = strings.TrimRight(strings.TrimSpace(cfg.Issuer), "/")
+	if cfg.Issuer == "" {
+		return nil, errors.New("oidc issuer is required")
+	}
+	if strings.TrimSpace(cfg.Audience) == "" {
+		return nil, errors.New("oidc audience is required")
+	}
+	if cfg.ScopesClaim == "" {
+		cfg.ScopesClaim = "scope"
+	}
+	return &OIDCVerifier{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// looksLikeJWT reports whether a bearer token has the three-segment JWS compact form.
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2 && strings.HasPrefix(token, "eyJ")
+}
+
+// Verify checks the token's signature, issuer, audience, and validity window, and
+// returns the caller's identity.
+func (v *OIDCVerifier) Verify(ctx context.Context, token string) (Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Identity{}, errors.New("malformed token")
+	}
+	var hdr jwtHeader
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return Identity{}, fmt.Errorf("token header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Identity{}, errors.New("token signature: invalid encoding")
+	}
+	key, err := v.key(ctx, hdr.Kid)
+	if err != nil {
+		return Identity{}, err
+	}
+	if err := verifySignature(hdr.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return Identity{}, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Identity{}, fmt.Errorf("token claims: %w", err)
+	}
+	if err := v.checkClaims(claims, time.Now()); err != nil {
+		return Identity{}, err
+	}
+	return v.identity(claims), nil
+}
+
+func (v *OIDCVerifier) checkClaims(claims map[string]any, now time.Time) error {
+	if iss, _ := claims["iss"].(string); strings.TrimRight(iss, "/") != v.cfg.Issuer {
+		return fmt.Errorf("token issuer %q not accepted", iss)
+	}
+	if !audienceContains(claims["aud"], v.cfg.Audience) {
+		return errors.New("token audience not accepted")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no exp")
+	}
+	if now.Add(-oidcClockSkew).After(time.Unix(int64(exp), 0)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(oidcClockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not yet valid")
+	}
+	return nil
+}
+
+func audienceContains(aud any, want string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == want
+	case []any:
+		for _, x := range a {
+			if s, _ := x.(string); s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v *OIDCVerifier) identity(claims map[string]any) Identity {
+	id := Identity{viaOIDC: true}
+	for _, c := range []string{v.cfg.UsernameClaim, "email", "sub"} {
+		if s, _ := claims[c].(string); c != "" && s != "" {
+			id.Username = s
+			break
+		}
+	}
+	if v.cfg.ToolScopes == nil {
+		return id
+	}
+	id.restricted = true
+	for _, scope := range claimStrings(claims[v.cfg.ScopesClaim]) {
+		id.Tools = append(id.Tools, v.cfg.ToolScopes[scope]...)
+	}
+	return id
+}
+
+func claimStrings(v any) []string {
+	switch x := v.(type) {
+	case string:
+		return strings.Fields(x)
+	case []any:
+		out := make([]string, 0, len(x))
+		for _, s := range x {
+			if str, ok := s.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return errors.New("invalid encoding")
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// esCurves is the curve each ECDSA algorithm signs with (RFC 7518 section 3.4).
+var esCurves = map[string]string{"ES256": "P-256", "ES384": "P-384", "ES512": "P-521"}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	var h crypto.Hash
+	switch alg[min(len(alg), 2):] {
+	case "256":
+		h = crypto.SHA256
+	case "384":
+		h = crypto.SHA384
+	case "512":
+		h = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	hasher := h.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("token algorithm %q does not match RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(k, h, digest, sig); err != nil {
+			return errors.New("token signature invalid")
+		}
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("token algorithm %q does not match EC key", alg)
+		}
+		if curve := k.Curve.Params().Name; curve != esCurves[alg] {
+			return fmt.Errorf("token algorithm %q does not match %s key", alg, curve)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("token signature invalid")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("token signature invalid")
+		}
+	default:
+		return errors.New("unsupported signing key")
+	}
+	return nil
+}
+
+// key returns the signing key for kid, refetching the JWKS when it is stale or the kid is
+// unknown (key rotation), at most once per oidcRefetchBackoff; in between, a failed fetch's
+// error is returned again. The fetch runs without v.mu held; concurrent callers wait for it
+// instead of fetching again.
+func (v *OIDCVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	waited := false
+	for v.fetching != nil {
+		done := v.fetching
+		v.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		waited = true
+		v.mu.Lock()
+	}
+	k, ok := v.keys[kid]
+	stale := time.Since(v.fetchedAt) > oidcKeysTTL
+	refetch := !waited && (!ok || stale) && time.Since(v.lastAttempt) > oidcRefetchBackoff
+	if (ok && !stale) || !refetch {
+		fetchErr := v.fetchErr
+		v.mu.Unlock()
+		if !ok && fetchErr != nil {
+			return nil, fmt.Errorf("fetch signing keys: %w", fetchErr)
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return k, nil
+	}
+	done := make(chan struct{})
+	v.fetching = done
+	jwksURI := v.jwksURI
+	v.mu.Unlock()
+
+	keys, jwksURI, err := v.fetchKeys(ctx, jwksURI)
+
+	v.mu.Lock()
+	if err == nil {
+		v.jwksURI, v.keys, v.fetchedAt = jwksURI, keys, time.Now()
+	}
+	v.lastAttempt, v.fetchErr = time.Now(), err
+	v.fetching = nil
+	close(done)
+	v.mu.Unlock()
+
+	if err != nil {
+		if ok {
+			return k, nil
+		}
+		return nil, fmt.Errorf("fetch signing keys: %w", err)
+	}
+	k, ok = keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return k, nil
+}
+
+// fetchKeys fetches the JWKS from jwksURI, discovering it first when empty, and returns the
+// keys with the URI they came from.
+func (v *OIDCVerifier) fetchKeys(ctx context.Context, jwksURI string) (map[string]crypto.PublicKey, string, error) {
+	if jwksURI == "" {
+		var disc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, v.cfg.Issuer+"/.well-known/openid-configuration", &disc); err != nil {
+			return nil, "", err
+		}
+		if strings.TrimRight(disc.Issuer, "/") != v.cfg.Issuer {
+			return nil, "", fmt.Errorf("discovery issuer %q does not match %q", disc.Issuer, v.cfg.Issuer)
+		}
+		if disc.JWKSURI == "" {
+			return nil, "", errors.New("discovery document has no jwks_uri")
+		}
+		jwksURI = disc.JWKSURI
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := v.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, "", err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	if len(keys) == 0 {
+		return nil, "", errors.New("jwks has no usable signing keys")
+	}
+	return keys, jwksURI, nil
+}
+
+func (v *OIDCVerifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	dec := base64.RawURLEncoding
+	switch k.Kty {
+	case "RSA":
+		n, err := dec.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := dec.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := dec.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := dec.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("EC point not on curve")
+		}
+		return pub, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
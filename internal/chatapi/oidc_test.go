@@ -0,0 +1,292 @@
+package chatapi
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/tools"
+)
+
+// fakeIssuer is an OIDC issuer serving discovery and a JWKS that tests can rotate. While
+// failing is set, the JWKS endpoint answers 500.
+type fakeIssuer struct {
+	*httptest.Server
+	fetches atomic.Int32
+	failing atomic.Bool
+
+	mu   sync.Mutex
+	keys []jwk
+}
+
+func newFakeIssuer(t *testing.T, keys ...jwk) *fakeIssuer {
+	f := &fakeIssuer{keys: keys}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			writeJSON(w, map[string]string{"issuer": f.URL, "jwks_uri": f.URL + "/jwks"}, http.StatusOK)
+		case "/jwks":
+			f.fetches.Add(1)
+			if f.failing.Load() {
+				http.Error(w, "unavailable", http.StatusInternalServerError)
+				return
+			}
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			writeJSON(w, map[string]any{"keys": f.keys}, http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeIssuer) rotate(keys ...jwk) {
+	f.mu.Lock()
+	f.keys = keys
+	f.mu.Unlock()
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func rsaJWK(kid string, k *rsa.PrivateKey) jwk {
+	return jwk{Kty: "RSA", Kid: kid, Use: "sig", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}
+}
+
+func ecJWK(kid string, k *ecdsa.PrivateKey) jwk {
+	size := (k.Curve.Params().BitSize + 7) / 8
+	return jwk{Kty: "EC", Kid: kid, Crv: k.Curve.Params().Name, X: b64(k.X.FillBytes(make([]byte, size))), Y: b64(k.Y.FillBytes(make([]byte, size)))}
+}
+
+// signJWT returns a compact JWT of claims, signed by key with alg, or with garbage for
+// algorithms this verifier does not implement.
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	hdr, _ := json.Marshal(jwtHeader{Alg: alg, Kid: kid})
+	body, _ := json.Marshal(claims)
+	signed := b64(hdr) + "." + b64(body)
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	var err error
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if alg == "RS256" {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		}
+	case *ecdsa.PrivateKey:
+		if alg == "ES256" {
+			var r, s *big.Int
+			r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+			size := (k.Curve.Params().BitSize + 7) / 8
+			sig = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig == nil {
+		sig = []byte("not a signature")
+	}
+	return signed + "." + b64(sig)
+}
+
+// tamper replaces the claims of token, keeping its header and signature.
+func tamper(token string, claims map[string]any) string {
+	parts := strings.Split(token, ".")
+	body, _ := json.Marshal(claims)
+	return parts[0] + "." + b64(body) + "." + parts[2]
+}
+
+type oidcKeys struct {
+	rsa        *rsa.PrivateKey
+	p256, p384 *ecdsa.PrivateKey
+}
+
+var testOIDCKeys = sync.OnceValue(func() oidcKeys {
+	r, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	return oidcKeys{rsa: r, p256: p256, p384: p384}
+})
+
+func TestOIDCVerify(t *testing.T) {
+	keys := testOIDCKeys()
+	issuer := newFakeIssuer(t, rsaJWK("rsa", keys.rsa), ecJWK("ec", keys.p256), ecJWK("p384", keys.p384))
+	v, err := NewOIDCVerifier(OIDCConfig{Issuer: issuer.URL + "/", Audience: "chat-api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	claims := func(edit func(map[string]any)) map[string]any {
+		c := map[string]any{"iss": issuer.URL, "aud": "chat-api", "sub": "u1", "email": "ada@example.com", "exp": now + 300}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+
+	cases := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{"RS256", signJWT(t, "RS256", "rsa", keys.rsa, claims(nil)), ""},
+		{"ES256", signJWT(t, "ES256", "ec", keys.p256, claims(nil)), ""},
+		{"audience list", signJWT(t, "RS256", "rsa", keys.rsa, claims(func(c map[string]any) { c["aud"] = []string{"other", "chat-api"} })), ""},
+		{"within clock skew", signJWT(t, "RS256", "rsa", keys.rsa, claims(func(c map[string]any) { c["exp"], c["nbf"] = now-30, now+30 })), ""},
+		{"alg none", signJWT(t, "none", "rsa", keys.rsa, claims(nil)), `unsupported token algorithm "none"`},
+		{"HS256 with the RSA key", signJWT(t, "HS256", "rsa", keys.rsa, claims(nil)), `"HS256" does not match RSA key`},
+		{"RS256 with an EC key", signJWT(t, "RS256", "ec", keys.rsa, claims(nil)), `"RS256" does not match EC key`},
+		{"ES256 with a P-384 key", signJWT(t, "ES256", "p384", keys.p384, claims(nil)), `"ES256" does not match P-384 key`},
+		{"ES384 with a P-256 key", signJWT(t, "ES384", "ec", keys.p256, claims(nil)), `"ES384" does not match P-256 key`},
+		{"tampered claims", tamper(signJWT(t, "RS256", "rsa", keys.rsa, claims(nil)), claims(func(c map[string]any) { c["sub"] = "admin" })), "token signature invalid"},
+		{"wrong kid", signJWT(t, "RS256", "missing", keys.rsa, claims(nil)), `unknown signing key "missing"`},
+		{"wrong iss", signJWT(t, "RS256", "rsa", keys.rsa, claims(func(c map[string]any) { c["iss"] = "https://evil.example" })), "issuer"},
+		{"wrong aud", signJWT(t, "RS256", "rsa", keys.rsa, claims(func(c map[string]any) { c["aud"] = "other" })), "audience"},
+		{"no exp", signJWT(t, "RS256", "rsa", keys.rsa, claims(func(c map[string]any) { delete(c, "exp") })), "no exp"},
+		{"expired", signJWT(t, "RS256", "rsa", keys.rsa, claims(func(c map[string]any) { c["exp"] = now - 120 })), "expired"},
+		{"before nbf", signJWT(t, "RS256", "rsa", keys.rsa, claims(func(c map[string]any) { c["nbf"] = now + 120 })), "not yet valid"},
+		{"malformed", "eyJ.a", "malformed"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			id, err := v.Verify(context.Background(), c.token)
+			if c.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if id.Username != "ada@example.com" || !id.viaOIDC || id.restricted {
+					t.Fatalf("identity %+v", id)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("err = %v, want %q", err, c.err)
+			}
+		})
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	keys := testOIDCKeys()
+	issuer := newFakeIssuer(t, rsaJWK("old", keys.rsa))
+	v, _ := NewOIDCVerifier(OIDCConfig{Issuer: issuer.URL, Audience: "chat-api"})
+	claims := map[string]any{"iss": issuer.URL, "aud": "chat-api", "sub": "u1", "exp": time.Now().Unix() + 300}
+	ctx := context.Background()
+
+	if _, err := v.Verify(ctx, signJWT(t, "RS256", "old", keys.rsa, claims)); err != nil {
+		t.Fatal(err)
+	}
+	issuer.rotate(ecJWK("new", keys.p256))
+	rotated := signJWT(t, "ES256", "new", keys.p256, claims)
+
+	// Within the backoff an unknown kid does not refetch.
+	if _, err := v.Verify(ctx, rotated); err == nil {
+		t.Fatal("new key accepted before the refetch")
+	}
+	if n := issuer.fetches.Load(); n != 1 {
+		t.Fatalf("%d JWKS fetches within the backoff", n)
+	}
+
+	v.mu.Lock()
+	v.lastAttempt = time.Now().Add(-2 * oidcRefetchBackoff)
+	v.mu.Unlock()
+	if _, err := v.Verify(ctx, rotated); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+	if _, err := v.Verify(ctx, signJWT(t, "RS256", "old", keys.rsa, claims)); err == nil {
+		t.Fatal("retired key still accepted")
+	}
+	if n := issuer.fetches.Load(); n != 2 {
+		t.Fatalf("%d JWKS fetches, want 2", n)
+	}
+}
+
+func TestOIDCBacksOffAfterAFailedFetch(t *testing.T) {
+	keys := testOIDCKeys()
+	issuer := newFakeIssuer(t, rsaJWK("rsa", keys.rsa))
+	issuer.failing.Store(true)
+	v, _ := NewOIDCVerifier(OIDCConfig{Issuer: issuer.URL, Audience: "chat-api"})
+	token := signJWT(t, "RS256", "rsa", keys.rsa, map[string]any{"iss": issuer.URL, "aud": "chat-api", "sub": "u1", "exp": time.Now().Unix() + 300})
+	ctx := context.Background()
+
+	for i := range 2 {
+		if _, err := v.Verify(ctx, token); err == nil || !strings.Contains(err.Error(), "fetch signing keys") {
+			t.Fatalf("request %d: %v, want the fetch error", i, err)
+		}
+	}
+	if n := issuer.fetches.Load(); n != 1 {
+		t.Fatalf("%d JWKS fetches within the backoff, want 1", n)
+	}
+
+	issuer.failing.Store(false)
+	v.mu.Lock()
+	v.lastAttempt = time.Now().Add(-2 * oidcRefetchBackoff)
+	v.mu.Unlock()
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Fatalf("after the backoff: %v", err)
+	}
+	if n := issuer.fetches.Load(); n != 2 {
+		t.Fatalf("%d JWKS fetches, want 2", n)
+	}
+}
+
+func TestOIDCFetchesKeysOnceForConcurrentCallers(t *testing.T) {
+	keys := testOIDCKeys()
+	issuer := newFakeIssuer(t, rsaJWK("rsa", keys.rsa))
+	v, _ := NewOIDCVerifier(OIDCConfig{Issuer: issuer.URL, Audience: "chat-api"})
+	token := signJWT(t, "RS256", "rsa", keys.rsa, map[string]any{"iss": issuer.URL, "aud": "chat-api", "sub": "u1", "exp": time.Now().Unix() + 300})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := v.Verify(context.Background(), token)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := issuer.fetches.Load(); n != 1 {
+		t.Fatalf("%d JWKS fetches for concurrent first use", n)
+	}
+}
+
+func TestOIDCToolScopes(t *testing.T) {
+	keys := testOIDCKeys()
+	issuer := newFakeIssuer(t, rsaJWK("rsa", keys.rsa))
+	v, _ := NewOIDCVerifier(OIDCConfig{Issuer: issuer.URL, Audience: "chat-api", UsernameClaim: "preferred_username", ScopesClaim: "groups",
+		ToolScopes: map[string][]string{"finance": {"payram_payments_*"}, "ops": {tools.DebugRequestTool}}})
+	token := signJWT(t, "RS256", "rsa", keys.rsa, map[string]any{
+		"iss": issuer.URL, "aud": "chat-api", "sub": "u1", "preferred_username": "ada", "groups": []string{"finance"}, "exp": time.Now().Unix() + 300,
+	})
+	id, err := v.Verify(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Username != "ada" || !id.allows("payram_payments_summary") || id.allows("payram_daily_stats") || id.allows(tools.DebugRequestTool) {
+		t.Fatalf("identity %+v", id)
+	}
+}
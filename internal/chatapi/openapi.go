@@ -0,0 +1,9 @@
+package chatapi
+
+import _ "embed"
+
+// openAPIDoc describes the routes Register serves. Keep it in step with them: it is what
+// clients are generated from.
+//
+//go:embed openapi.json
+var openAPIDoc []byte
@@ -0,0 +1,137 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultToolOutputChars caps tool results when no limits file says otherwise, about 10k tokens.
+const defaultToolOutputChars = 40000
+
+// OutputLimits caps each tool result before it is given to the model. Results over a cap
+// keep their beginning and end, with a note in the middle saying how much was cut.
+type OutputLimits struct {
+	// MaxChars and MaxTokens (estimated) apply to every tool; 0 leaves that limit off.
+	MaxChars  int `json:"max_chars,omitempty"`
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Tools override them for tools matching a glob such as "payram_export*". The first
+	// matching rule wins, and limits it leaves at 0 fall back to the global ones.
+	Tools []OutputRule `json:"tools,omitempty"`
+}
+
+// OutputRule sets the output limits of the tools matching Match.
+type OutputRule struct {
+	Match     string `json:"match"`
+	MaxChars  int    `json:"max_chars,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+}
+
+// DefaultOutputLimits caps every tool result at 40,000 characters.
+func DefaultOutputLimits() OutputLimits {
+	return OutputLimits{MaxChars: defaultToolOutputChars}
+}
+
+// LoadOutputLimits reads an output limits file. An empty path returns the defaults; a file
+// replaces them, so it should set its own global limits.
+func LoadOutputLimits(file string) (OutputLimits, error) {
+	if strings.TrimSpace(file) == "" {
+		return DefaultOutputLimits(), nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return OutputLimits{}, fmt.Errorf("read tool output limits: %w", err)
+	}
+	var l OutputLimits
+	if err := json.Unmarshal(data, &l); err != nil {
+		return OutputLimits{}, fmt.Errorf("decode tool output limits: %w", err)
+	}
+	if err := l.validate(); err != nil {
+		return OutputLimits{}, fmt.Errorf("tool output limits %s: %w", file, err)
+	}
+	return l, nil
+}
+
+func (l OutputLimits) validate() error {
+	if l.MaxChars < 0 || l.MaxTokens < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	for i, r := range l.Tools {
+		if r.Match == "" {
+			return fmt.Errorf("tools[%d]: match is required", i)
+		}
+		if _, err := path.Match(r.Match, ""); err != nil {
+			return fmt.Errorf("tools[%d]: bad pattern %q: %w", i, r.Match, err)
+		}
+		if r.MaxChars < 0 || r.MaxTokens < 0 {
+			return fmt.Errorf("tools[%d]: limits must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// SetOutputLimits replaces the default caps on tool results given to the model.
+func (h *Handler) SetOutputLimits(l OutputLimits) {
+	h.outputLimits = l
+}
+
+// limitsFor returns the character and token caps for tool.
+func (l OutputLimits) limitsFor(tool string) (chars, tokens int) {
+	chars, tokens = l.MaxChars, l.MaxTokens
+	for _, r := range l.Tools {
+		if ok, _ := path.Match(r.Match, tool); ok {
+			if r.MaxChars > 0 {
+				chars = r.MaxChars
+			}
+			if r.MaxTokens > 0 {
+				tokens = r.MaxTokens
+			}
+			break
+		}
+	}
+	return chars, tokens
+}
+
+// capOutput applies tool's limits to its rendered output, cutting from the middle. It
+// returns the output and how many characters were cut.
+func (l OutputLimits) capOutput(tool, output string) (string, int) {
+	chars, tokens := l.limitsFor(tool)
+	keep := len(output)
+	if chars > 0 && utf8.RuneCountInString(output) > chars {
+		keep = byteOffset(output, chars)
+	}
+	if tokens > 0 {
+		if n := estimateTokens(output); n > tokens {
+			keep = min(keep, len(output)*tokens/n)
+		}
+	}
+	if keep >= len(output) {
+		return output, 0
+	}
+	head := runeStart(output, keep*2/3)
+	tail := runeStart(output, len(output)-(keep-head))
+	cut := utf8.RuneCountInString(output[head:tail])
+	return fmt.Sprintf("%s\n[... truncated %d chars ...]\n%s", output[:head], cut, output[tail:]), cut
+}
+
+// byteOffset is the byte offset of the n-th rune of s.
+func byteOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
+
+// runeStart moves i back to the start of the UTF-8 sequence it falls in.
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
@@ -0,0 +1,128 @@
+package chatapi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCapOutput(t *testing.T) {
+	ascii := strings.Repeat("0123456789", 10)
+	cases := []struct {
+		name   string
+		limits OutputLimits
+		tool   string
+		output string
+		want   string
+		cut    int
+	}{
+		{"under the cap", OutputLimits{MaxChars: 100}, "t", ascii, ascii, 0},
+		{"no limits", OutputLimits{}, "t", ascii, ascii, 0},
+		{"keeps head and tail", OutputLimits{MaxChars: 30}, "t", ascii, ascii[:20] + "\n[... truncated 70 chars ...]\n" + ascii[90:], 70},
+		{"counts runes, not bytes", OutputLimits{MaxChars: 7}, "t", "日本語テキスト", "日本語テキスト", 0},
+		{"multi-byte", OutputLimits{MaxChars: 3}, "t", "日本語テキスト", "日本\n[... truncated 4 chars ...]\nト", 4},
+		{"per-tool rule", OutputLimits{MaxChars: 1000, Tools: []OutputRule{{Match: "payram_*", MaxChars: 30}}}, "payram_x", ascii, ascii[:20] + "\n[... truncated 70 chars ...]\n" + ascii[90:], 70},
+		{"other tools keep the global cap", OutputLimits{MaxChars: 1000, Tools: []OutputRule{{Match: "payram_*", MaxChars: 30}}}, "other", ascii, ascii, 0},
+	}
+	for _, c := range cases {
+		got, cut := c.limits.capOutput(c.tool, c.output)
+		if got != c.want || cut != c.cut {
+			t.Errorf("%s: got %q (cut %d), want %q (cut %d)", c.name, got, cut, c.want, c.cut)
+		}
+	}
+}
+
+func TestCapOutputByTokens(t *testing.T) {
+	output := strings.Repeat("word ", 200)
+	limits := OutputLimits{MaxTokens: 50}
+	got, cut := limits.capOutput("t", output)
+	head, tail, ok := strings.Cut(got, fmt.Sprintf("\n[... truncated %d chars ...]\n", cut))
+	if cut == 0 || !ok {
+		t.Fatalf("not cut: %q", got)
+	}
+	if n := estimateTokens(head + tail); n > 52 {
+		t.Fatalf("%d tokens kept, want about 50", n)
+	}
+}
+
+func TestCapOutputNeverSplitsARune(t *testing.T) {
+	// Mixed one- to four-byte runes, cut at every size, must stay valid UTF-8 and account
+	// for every rune.
+	output := strings.Repeat("a€日😀", 25)
+	total := utf8.RuneCountInString(output)
+	for chars := 1; chars < total; chars++ {
+		for _, limits := range []OutputLimits{{MaxChars: chars}, {MaxTokens: chars}} {
+			got, cut := limits.capOutput("t", output)
+			if !utf8.ValidString(got) {
+				t.Fatalf("%+v: invalid UTF-8 %q", limits, got)
+			}
+			if cut == 0 {
+				continue
+			}
+			note := fmt.Sprintf("\n[... truncated %d chars ...]\n", cut)
+			head, tail, ok := strings.Cut(got, note)
+			if !ok {
+				t.Fatalf("%+v: no note in %q", limits, got)
+			}
+			if kept := utf8.RuneCountInString(head) + utf8.RuneCountInString(tail); kept+cut != total {
+				t.Fatalf("%+v: kept %d and cut %d of %d runes", limits, kept, cut, total)
+			}
+			if !strings.HasPrefix(output, head) || !strings.HasSuffix(output, tail) {
+				t.Fatalf("%+v: head %q or tail %q not from the output", limits, head, tail)
+			}
+		}
+	}
+}
+
+func TestOutputLimitsFor(t *testing.T) {
+	l := OutputLimits{MaxChars: 1000, MaxTokens: 200, Tools: []OutputRule{
+		{Match: "payram_export*", MaxChars: 50000},
+		{Match: "payram_*", MaxTokens: 50},
+		{Match: "payram_docs", MaxChars: 10},
+	}}
+	cases := []struct {
+		tool          string
+		chars, tokens int
+	}{
+		{"payram_export_csv", 50000, 200},
+		{"payram_docs", 1000, 50}, // the first matching rule wins
+		{"other_tool", 1000, 200},
+	}
+	for _, c := range cases {
+		if chars, tokens := l.limitsFor(c.tool); chars != c.chars || tokens != c.tokens {
+			t.Errorf("limitsFor(%s) = %d, %d; want %d, %d", c.tool, chars, tokens, c.chars, c.tokens)
+		}
+	}
+}
+
+func TestLoadOutputLimits(t *testing.T) {
+	if l, err := LoadOutputLimits(""); err != nil || l.MaxChars != defaultToolOutputChars {
+		t.Fatalf("defaults: %+v, %v", l, err)
+	}
+	dir := t.TempDir()
+	write := func(body string) string {
+		file := filepath.Join(dir, "limits.json")
+		if err := os.WriteFile(file, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	l, err := LoadOutputLimits(write(`{"max_tokens":500,"tools":[{"match":"payram_export*","max_chars":80000}]}`))
+	if err != nil || l.MaxChars != 0 || l.MaxTokens != 500 || len(l.Tools) != 1 {
+		t.Fatalf("loaded %+v, %v", l, err)
+	}
+	for body, want := range map[string]string{
+		`{"max_chars":-1}`:                          "must not be negative",
+		`{"tools":[{"max_chars":10}]}`:              "match is required",
+		`{"tools":[{"match":"[","max_chars":10}]}`:  "bad pattern",
+		`{"tools":[{"match":"x","max_tokens":-5}]}`: "tools[0]: limits must not be negative",
+		`[]`: "decode tool output limits",
+	} {
+		if _, err := LoadOutputLimits(write(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", body, err, want)
+		}
+	}
+}
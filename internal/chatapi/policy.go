@@ -0,0 +1,329 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Sampling parameters covered by the model policy.
+const (
+	paramTemperature         = "temperature"
+	paramTopP                = "top_p"
+	paramMaxTokens           = "max_tokens"
+	paramMaxCompletionTokens = "max_completion_tokens"
+	paramReasoningEffort     = "reasoning_effort"
+)
+
+var policyParams = []string{paramTemperature, paramTopP, paramMaxTokens, paramMaxCompletionTokens, paramReasoningEffort}
+
+// What to do with a parameter the model does not accept.
+const (
+	onInvalidReject = "reject"
+	onInvalidDrop   = "drop"
+	onInvalidClamp  = "clamp"
+)
+
+// SamplingParams are the caller-tunable generation parameters forwarded to OpenAI.
+type SamplingParams struct {
+	Temperature         *float64 `json:"temperature,omitempty"`
+	TopP                *float64 `json:"top_p,omitempty"`
+	MaxTokens           *int     `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int     `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string   `json:"reasoning_effort,omitempty"`
+}
+
+// ModelPolicy declares which sampling parameters each model accepts.
+// For every parameter, the first rule whose pattern matches the model and that mentions
+// the parameter decides; a parameter no rule mentions is forwarded unchanged.
+type ModelPolicy struct {
+	Models []ModelRule `json:"models"`
+}
+
+// ModelRule applies parameter rules to models matching a glob pattern (e.g. "gpt-5*").
+type ModelRule struct {
+	Match string `json:"match"`
+	// ContextWindow is the model's context size in tokens; the first matching rule that sets it wins.
+	ContextWindow int `json:"context_window,omitempty"`
+	// Price is what the model costs, for conversation budgets; the first matching rule that
+	// sets it wins.
+	Price  *ModelPrice          `json:"price,omitempty"`
+	Params map[string]ParamRule `json:"params"`
+}
+
+// ModelPrice is a model's price in USD per million input and output tokens.
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// ParamRule constrains a single parameter.
+type ParamRule struct {
+	// Allowed defaults to true; false means the model rejects the parameter entirely.
+	Allowed *bool    `json:"allowed,omitempty"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	// Values lists accepted values for string parameters (reasoning_effort).
+	Values []string `json:"values,omitempty"`
+	// Default is sent when the caller omits the parameter.
+	Default any `json:"default,omitempty"`
+	// OnInvalid is reject (default, 400 to the caller), drop, or clamp (numeric ranges only).
+	OnInvalid string `json:"on_invalid,omitempty"`
+}
+
+// ParamError reports a parameter rejected by the policy.
+type ParamError struct {
+	Param   string
+	Code    string
+	Message string
+}
+
+func (e *ParamError) Error() string { return e.Message }
+
+func ptr[T any](v T) *T { return &v }
+
+// DefaultModelPolicy returns the built-in policy: reasoning models only take the default
+// temperature/top_p (other values are dropped) and accept reasoning_effort; other models
+// get the standard OpenAI ranges.
+func DefaultModelPolicy() ModelPolicy {
+	reasoning := map[string]ParamRule{
+		paramTemperature:     {Min: ptr(1.0), Max: ptr(1.0), OnInvalid: onInvalidDrop},
+		paramTopP:            {Min: ptr(1.0), Max: ptr(1.0), OnInvalid: onInvalidDrop},
+		paramMaxTokens:       {Allowed: ptr(false)},
+		paramReasoningEffort: {Values: []string{"minimal", "low", "medium", "high"}},
+	}
+	return ModelPolicy{Models: []ModelRule{
+		{Match: "gpt-5-nano*", Price: &ModelPrice{0.05, 0.40}},
+		{Match: "gpt-5-mini*", Price: &ModelPrice{0.25, 2}},
+		{Match: "gpt-4.1-nano*", Price: &ModelPrice{0.10, 0.40}},
+		{Match: "gpt-4.1-mini*", Price: &ModelPrice{0.40, 1.60}},
+		{Match: "gpt-4o-mini*", Price: &ModelPrice{0.15, 0.60}},
+		{Match: "o1*", Price: &ModelPrice{15, 60}},
+		{Match: "o[34]-mini*", Price: &ModelPrice{1.10, 4.40}},
+		{Match: "gpt-5*", ContextWindow: 400000, Price: &ModelPrice{1.25, 10}, Params: reasoning},
+		{Match: "o[1-9]*", ContextWindow: 200000, Price: &ModelPrice{2, 8}, Params: reasoning},
+		{Match: "gpt-4.1*", ContextWindow: 1047576, Price: &ModelPrice{2, 8}},
+		{Match: "gpt-4o*", ContextWindow: 128000, Price: &ModelPrice{2.50, 10}},
+		{Match: "gpt-4-turbo*", ContextWindow: 128000, Price: &ModelPrice{10, 30}},
+		{Match: "gpt-4*", ContextWindow: 8192, Price: &ModelPrice{30, 60}},
+		{Match: "gpt-3.5*", ContextWindow: 16385, Price: &ModelPrice{0.50, 1.50}},
+		{Match: "*", ContextWindow: 128000, Price: &ModelPrice{2.50, 10}, Params: map[string]ParamRule{
+			paramTemperature:         {Min: ptr(0.0), Max: ptr(2.0)},
+			paramTopP:                {Min: ptr(0.0), Max: ptr(1.0)},
+			paramMaxTokens:           {Min: ptr(1.0)},
+			paramMaxCompletionTokens: {Min: ptr(1.0)},
+			paramReasoningEffort:     {Allowed: ptr(false)},
+		}},
+	}}
+}
+
+// LoadModelPolicy reads a JSON policy file and layers it over the built-in policy.
+// An empty path returns the built-in policy.
+func LoadModelPolicy(file string) (ModelPolicy, error) {
+	policy := DefaultModelPolicy()
+	if strings.TrimSpace(file) == "" {
+		return policy, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return policy, fmt.Errorf("read model policy: %w", err)
+	}
+	var custom ModelPolicy
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return policy, fmt.Errorf("decode model policy: %w", err)
+	}
+	if err := custom.validate(); err != nil {
+		return policy, fmt.Errorf("model policy %s: %w", file, err)
+	}
+	policy.Models = append(custom.Models, policy.Models...)
+	return policy, nil
+}
+
+func (p ModelPolicy) validate() error {
+	for i, m := range p.Models {
+		if m.Match == "" {
+			return fmt.Errorf("models[%d]: match is required", i)
+		}
+		if _, err := path.Match(m.Match, ""); err != nil {
+			return fmt.Errorf("models[%d]: bad match %q: %w", i, m.Match, err)
+		}
+		if m.ContextWindow < 0 {
+			return fmt.Errorf("models[%d]: context_window must not be negative", i)
+		}
+		if m.Price != nil && (m.Price.Input < 0 || m.Price.Output < 0) {
+			return fmt.Errorf("models[%d]: price must not be negative", i)
+		}
+		for name, r := range m.Params {
+			if !slices.Contains(policyParams, name) {
+				return fmt.Errorf("models[%d]: unknown param %q", i, name)
+			}
+			switch r.OnInvalid {
+			case "", onInvalidReject, onInvalidDrop:
+			case onInvalidClamp:
+				if name == paramReasoningEffort {
+					return fmt.Errorf("models[%d].%s: clamp only applies to numeric params", i, name)
+				}
+			default:
+				return fmt.Errorf("models[%d].%s: unknown on_invalid %q", i, name, r.OnInvalid)
+			}
+			if r.Default == nil {
+				continue
+			}
+			if _, isString := r.Default.(string); isString != (name == paramReasoningEffort) {
+				return fmt.Errorf("models[%d].%s: default has the wrong type", i, name)
+			}
+		}
+	}
+	return nil
+}
+
+// rule returns the rule that governs param for model, if any.
+func (p ModelPolicy) rule(model, param string) (ParamRule, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	for _, m := range p.Models {
+		if ok, _ := path.Match(strings.ToLower(m.Match), model); !ok {
+			continue
+		}
+		if r, ok := m.Params[param]; ok {
+			return r, true
+		}
+	}
+	return ParamRule{}, false
+}
+
+// contextWindow returns the context size in tokens for model.
+func (p ModelPolicy) contextWindow(model string) int {
+	model = strings.ToLower(strings.TrimSpace(model))
+	for _, m := range p.Models {
+		if ok, _ := path.Match(strings.ToLower(m.Match), model); ok && m.ContextWindow > 0 {
+			return m.ContextWindow
+		}
+	}
+	return 128000
+}
+
+// price returns what model costs; models no rule prices are free.
+func (p ModelPolicy) price(model string) ModelPrice {
+	model = strings.ToLower(strings.TrimSpace(model))
+	for _, m := range p.Models {
+		if ok, _ := path.Match(strings.ToLower(m.Match), model); ok && m.Price != nil {
+			return *m.Price
+		}
+	}
+	return ModelPrice{}
+}
+
+// Resolve checks in against the policy for model and returns the parameters to forward.
+// Parameters dropped or clamped under the policy are described in notes for logging.
+func (p ModelPolicy) Resolve(model string, in SamplingParams) (out SamplingParams, notes []string, err error) {
+	out.Temperature, notes, err = p.resolveFloat(model, paramTemperature, in.Temperature, notes)
+	if err != nil {
+		return out, notes, err
+	}
+	out.TopP, notes, err = p.resolveFloat(model, paramTopP, in.TopP, notes)
+	if err != nil {
+		return out, notes, err
+	}
+	out.MaxTokens, notes, err = p.resolveInt(model, paramMaxTokens, in.MaxTokens, notes)
+	if err != nil {
+		return out, notes, err
+	}
+	out.MaxCompletionTokens, notes, err = p.resolveInt(model, paramMaxCompletionTokens, in.MaxCompletionTokens, notes)
+	if err != nil {
+		return out, notes, err
+	}
+	out.ReasoningEffort, notes, err = p.resolveString(model, paramReasoningEffort, in.ReasoningEffort, notes)
+	return out, notes, err
+}
+
+func (p ModelPolicy) resolveFloat(model, param string, v *float64, notes []string) (*float64, []string, error) {
+	r, ok := p.rule(model, param)
+	if !ok {
+		return v, notes, nil
+	}
+	if v == nil {
+		if d, ok := r.Default.(float64); ok {
+			return &d, notes, nil
+		}
+		return nil, notes, nil
+	}
+	if r.Allowed != nil && !*r.Allowed {
+		return r.invalid(model, param, v, notes, "unsupported_parameter", fmt.Sprintf("%s is not supported with model %s", param, model))
+	}
+	val := *v
+	if (r.Min != nil && val < *r.Min) || (r.Max != nil && val > *r.Max) {
+		if r.OnInvalid == onInvalidClamp {
+			clamped := val
+			if r.Min != nil {
+				clamped = math.Max(clamped, *r.Min)
+			}
+			if r.Max != nil {
+				clamped = math.Min(clamped, *r.Max)
+			}
+			return &clamped, append(notes, fmt.Sprintf("%s clamped from %g to %g for model %s", param, val, clamped, model)), nil
+		}
+		return r.invalid(model, param, v, notes, "invalid_parameter_value", fmt.Sprintf("%s must be within %s for model %s, got %g", param, r.rangeString(), model, val))
+	}
+	return v, notes, nil
+}
+
+func (p ModelPolicy) resolveInt(model, param string, v *int, notes []string) (*int, []string, error) {
+	var f *float64
+	if v != nil {
+		f = ptr(float64(*v))
+	}
+	f, notes, err := p.resolveFloat(model, param, f, notes)
+	if err != nil || f == nil {
+		return nil, notes, err
+	}
+	return ptr(int(math.Round(*f))), notes, nil
+}
+
+func (p ModelPolicy) resolveString(model, param, v string, notes []string) (string, []string, error) {
+	r, ok := p.rule(model, param)
+	if !ok {
+		return v, notes, nil
+	}
+	if v == "" {
+		d, _ := r.Default.(string)
+		return d, notes, nil
+	}
+	msg := ""
+	code := "invalid_parameter_value"
+	switch {
+	case r.Allowed != nil && !*r.Allowed:
+		code = "unsupported_parameter"
+		msg = fmt.Sprintf("%s is not supported with model %s", param, model)
+	case len(r.Values) > 0 && !slices.Contains(r.Values, v):
+		msg = fmt.Sprintf("%s must be one of %s for model %s, got %q", param, strings.Join(r.Values, ", "), model, v)
+	default:
+		return v, notes, nil
+	}
+	if r.OnInvalid == onInvalidDrop {
+		return "", append(notes, fmt.Sprintf("%s dropped: %s", param, msg)), nil
+	}
+	return "", notes, &ParamError{Param: param, Code: code, Message: msg}
+}
+
+// invalid drops or rejects a numeric parameter according to the rule.
+func (r ParamRule) invalid(model, param string, v *float64, notes []string, code, msg string) (*float64, []string, error) {
+	if r.OnInvalid == onInvalidDrop {
+		return nil, append(notes, fmt.Sprintf("%s=%g dropped for model %s", param, *v, model)), nil
+	}
+	return nil, notes, &ParamError{Param: param, Code: code, Message: msg}
+}
+
+func (r ParamRule) rangeString() string {
+	lo, hi := "-inf", "+inf"
+	if r.Min != nil {
+		lo = fmt.Sprintf("%g", *r.Min)
+	}
+	if r.Max != nil {
+		hi = fmt.Sprintf("%g", *r.Max)
+	}
+	return "[" + lo + ", " + hi + "]"
+}
@@ -0,0 +1,163 @@
+package chatapi
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModelPolicyResolve(t *testing.T) {
+	p := DefaultModelPolicy()
+	cases := []struct {
+		name  string
+		model string
+		in    SamplingParams
+		want  SamplingParams
+		code  string
+		notes int
+	}{
+		{"standard model keeps valid values", "gpt-4o-mini", SamplingParams{Temperature: ptr(0.2), TopP: ptr(0.9), MaxTokens: ptr(500)}, SamplingParams{Temperature: ptr(0.2), TopP: ptr(0.9), MaxTokens: ptr(500)}, "", 0},
+		{"temperature out of range", "gpt-4o", SamplingParams{Temperature: ptr(2.5)}, SamplingParams{}, "invalid_parameter_value", 0},
+		{"max_tokens below one", "gpt-4o", SamplingParams{MaxTokens: ptr(0)}, SamplingParams{}, "invalid_parameter_value", 0},
+		{"reasoning_effort on a standard model", "gpt-4o", SamplingParams{ReasoningEffort: "high"}, SamplingParams{}, "unsupported_parameter", 0},
+		{"reasoning model drops temperature", "gpt-5-mini", SamplingParams{Temperature: ptr(0.2)}, SamplingParams{}, "", 1},
+		{"reasoning model keeps the default temperature", "o3", SamplingParams{Temperature: ptr(1.0)}, SamplingParams{Temperature: ptr(1.0)}, "", 0},
+		{"reasoning model rejects max_tokens", "o3-mini", SamplingParams{MaxTokens: ptr(100)}, SamplingParams{}, "unsupported_parameter", 0},
+		{"reasoning effort value", "gpt-5", SamplingParams{ReasoningEffort: "low", MaxCompletionTokens: ptr(900)}, SamplingParams{ReasoningEffort: "low", MaxCompletionTokens: ptr(900)}, "", 0},
+		{"unknown reasoning effort", "gpt-5", SamplingParams{ReasoningEffort: "extreme"}, SamplingParams{}, "invalid_parameter_value", 0},
+		{"model names match case-insensitively", "GPT-5", SamplingParams{Temperature: ptr(0.5)}, SamplingParams{}, "", 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, notes, err := p.Resolve(c.model, c.in)
+			if c.code != "" {
+				pe, ok := err.(*ParamError)
+				if !ok || pe.Code != c.code {
+					t.Fatalf("err = %v, want code %s", err, c.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameParams(got, c.want) || len(notes) != c.notes {
+				t.Fatalf("got %s with notes %q, want %s", paramString(got), notes, paramString(c.want))
+			}
+		})
+	}
+}
+
+func TestModelPolicyClampAndDefault(t *testing.T) {
+	p := ModelPolicy{Models: []ModelRule{{Match: "local-*", Params: map[string]ParamRule{
+		paramTemperature:     {Min: ptr(0.0), Max: ptr(1.0), OnInvalid: onInvalidClamp, Default: 0.3},
+		paramMaxTokens:       {Max: ptr(1000.0), OnInvalid: onInvalidClamp},
+		paramReasoningEffort: {Values: []string{"low"}, OnInvalid: onInvalidDrop},
+	}}}}
+	got, notes, err := p.Resolve("local-llama", SamplingParams{MaxTokens: ptr(4000), ReasoningEffort: "high"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SamplingParams{Temperature: ptr(0.3), MaxTokens: ptr(1000)}
+	if !sameParams(got, want) || len(notes) != 2 {
+		t.Fatalf("got %s with notes %q, want %s", paramString(got), notes, paramString(want))
+	}
+	got, _, _ = p.Resolve("local-llama", SamplingParams{Temperature: ptr(1.7)})
+	if got.Temperature == nil || *got.Temperature != 1 {
+		t.Fatalf("temperature %s, want clamped to 1", paramString(got))
+	}
+	// Other models are not governed by the rule.
+	got, _, _ = p.Resolve("gpt-4o", SamplingParams{Temperature: ptr(1.7)})
+	if got.Temperature == nil || *got.Temperature != 1.7 {
+		t.Fatalf("temperature %s for an ungoverned model", paramString(got))
+	}
+}
+
+func TestLoadModelPolicy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(body string) string {
+		file := filepath.Join(dir, "policy.json")
+		if err := os.WriteFile(file, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	p, err := LoadModelPolicy(write(`{"models":[{"match":"gpt-4o*","context_window":64000,"params":{"temperature":{"max":0.5,"on_invalid":"clamp"}}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := p.Resolve("gpt-4o", SamplingParams{Temperature: ptr(0.9)}); got.Temperature == nil || *got.Temperature != 0.5 {
+		t.Fatalf("custom rule not applied first: %s", paramString(got))
+	}
+	if w := p.contextWindow("gpt-4o"); w != 64000 {
+		t.Fatalf("context window %d", w)
+	}
+	if pr := p.price("gpt-4o"); pr.Input != 2.50 {
+		t.Fatalf("built-in price lost: %+v", pr)
+	}
+
+	for body, want := range map[string]string{
+		`{"models":[{"params":{}}]}`:                      "match is required",
+		`{"models":[{"match":"["}]}`:                      "bad match",
+		`{"models":[{"match":"*","params":{"seed":{}}}]}`: `unknown param "seed"`,
+		`{"models":[{"match":"*","params":{"reasoning_effort":{"on_invalid":"clamp"}}}]}`: "clamp only applies",
+		`{"models":[{"match":"*","params":{"top_p":{"on_invalid":"ignore"}}}]}`:           "unknown on_invalid",
+		`{"models":[{"match":"*","params":{"temperature":{"default":"hot"}}}]}`:           "wrong type",
+		`{"models":[{"match":"*","price":{"input":-1}}]}`:                                 "price must not be negative",
+		`not json`: "decode model policy",
+	} {
+		if _, err := LoadModelPolicy(write(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", body, err, want)
+		}
+	}
+}
+
+func TestChatAppliesModelPolicy(t *testing.T) {
+	llm := newFakeLLM(t, nil)
+	_, mux := newTestHandler(t, llm, newFakeMCP(t))
+
+	rec := serve(mux, http.MethodPost, "/v1/chat/completions", map[string]any{
+		"model": "gpt-4o", "temperature": 3,
+		"messages": []OAChatMessage{{Role: "user", Content: "hi"}},
+	})
+	body := decodeError(t, rec)
+	if rec.Code != http.StatusBadRequest || body.Param == nil || *body.Param != paramTemperature || errorCode(body) != "invalid_parameter_value" {
+		t.Fatalf("got %d %+v", rec.Code, body)
+	}
+	if n := len(llm.calls()); n != 0 {
+		t.Fatalf("rejected request reached the model %d times", n)
+	}
+
+	decodeAnswer(t, serve(mux, http.MethodPost, "/v1/chat/completions", map[string]any{
+		"model": "gpt-5-mini", "temperature": 0.2, "reasoning_effort": "low",
+		"messages": []OAChatMessage{{Role: "user", Content: "hi"}},
+	}))
+	calls := llm.calls()
+	if len(calls) != 1 || calls[0].Temperature != nil || calls[0].ReasoningEffort != "low" || calls[0].Model != "gpt-5-mini" {
+		t.Fatalf("forwarded %+v", calls)
+	}
+}
+
+func sameParams(a, b SamplingParams) bool {
+	return paramString(a) == paramString(b)
+}
+
+func paramString(p SamplingParams) string {
+	f := func(v *float64) string {
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprint(*v)
+	}
+	i := func(v *int) string {
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprint(*v)
+	}
+	return fmt.Sprintf("temperature=%s top_p=%s max_tokens=%s max_completion_tokens=%s reasoning_effort=%q",
+		f(p.Temperature), f(p.TopP), i(p.MaxTokens), i(p.MaxCompletionTokens), p.ReasoningEffort)
+}
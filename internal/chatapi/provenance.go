@@ -0,0 +1,130 @@
+package chatapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/provenance"
+)
+
+const (
+	// provenanceCommand, sent as the whole user message, answers with the sources of the
+	// conversation's previous answer instead of asking the model.
+	provenanceCommand = "/provenance"
+	// maxProvenanceConversations caps how many conversations' last sources are kept; the
+	// oldest are forgotten first.
+	maxProvenanceConversations = 1000
+)
+
+// ToolProvenance says where the figures of one tool call in an answer came from.
+type ToolProvenance struct {
+	Tool      string                `json:"tool"`
+	Arguments map[string]any        `json:"arguments"`
+	Sources   []protocol.DataSource `json:"sources"`
+}
+
+// provenanceLog keeps the provenance of the last answer in each conversation.
+type provenanceLog struct {
+	mu      sync.Mutex
+	answers map[string][]ToolProvenance
+	order   []string
+}
+
+func (l *provenanceLog) remember(conversation string, p []ToolProvenance) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.answers == nil {
+		l.answers = map[string][]ToolProvenance{}
+	}
+	if _, ok := l.answers[conversation]; !ok {
+		l.order = append(l.order, conversation)
+		if len(l.order) > maxProvenanceConversations {
+			delete(l.answers, l.order[0])
+			l.order = l.order[1:]
+		}
+	}
+	l.answers[conversation] = p
+}
+
+func (l *provenanceLog) last(conversation string) ([]ToolProvenance, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, ok := l.answers[conversation]
+	return p, ok
+}
+
+// toolProvenance describes a tool call's result for the answer's provenance.
+func toolProvenance(tool string, args map[string]any, result protocol.CallResult) ToolProvenance {
+	p := ToolProvenance{Tool: tool, Arguments: withoutSensitiveArgs(args), Sources: []protocol.DataSource{}}
+	if result.Meta != nil && len(result.Meta.Sources) > 0 {
+		p.Sources = result.Meta.Sources
+	}
+	return p
+}
+
+// isProvenanceCommand reports whether the latest message asks for the previous answer's
+// sources.
+func isProvenanceCommand(messages []OAChatMessage) bool {
+	if len(messages) == 0 {
+		return false
+	}
+	last := messages[len(messages)-1]
+	return last.Role == "user" && strings.EqualFold(strings.TrimSpace(last.Content), provenanceCommand)
+}
+
+// provenanceAnswer replies to the provenance command for conversation c without the model.
+func (h *Handler) provenanceAnswer(w http.ResponseWriter, req ChatCompletionRequest, c conversation, structured bool) {
+	p, ok := h.provenance.last(c.key)
+	resp := ChatCompletionResponse{
+		ID:     fmt.Sprintf("provenance-%d", time.Now().UnixNano()),
+		Object: "chat.completion",
+		Model:  req.Model,
+		Choices: []ChatChoice{{
+			Message:      OAChatMessage{Role: "assistant", Content: describeProvenance(p, ok)},
+			FinishReason: "stop",
+		}},
+		Usage:      map[string]interface{}{"prompt_tokens": 0, "completion_tokens": 0, "total_tokens": 0},
+		Provenance: p,
+	}
+	h.writeAnswer(w, resp, req.Stream, structured)
+}
+
+// describeProvenance renders the sources of an answer for people.
+func describeProvenance(p []ToolProvenance, known bool) string {
+	switch {
+	case !known:
+		return "There is no earlier answer in this conversation to explain."
+	case len(p) == 0:
+		return "The previous answer did not use any tools, so it has no analytics sources."
+	}
+	var b strings.Builder
+	b.WriteString("Sources of the previous answer:\n")
+	for _, tp := range p {
+		fmt.Fprintf(&b, "\n%s%s\n", tp.Tool, describeArgs(tp.Arguments))
+		if len(tp.Sources) == 0 {
+			b.WriteString("- no analytics graphs were read\n")
+		}
+		for _, s := range tp.Sources {
+			fmt.Fprintf(&b, "- %s\n", provenance.Describe(s))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// describeArgs renders tool arguments as " (days=7, currency_code=USDC)", sorted by name.
+func describeArgs(args map[string]any) string {
+	if len(args) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(args))
+	for k, v := range args {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(parts)
+	return " (" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,217 @@
+package chatapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// QueryRequest is the body of POST /v1/query.
+type QueryRequest struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// QueryResponse is a tool result returned without any LLM involvement.
+type QueryResponse struct {
+	Object     string                 `json:"object"`
+	Tool       string                 `json:"tool"`
+	Arguments  map[string]any         `json:"arguments"`
+	DurationMs int64                  `json:"duration_ms"`
+	Content    []protocol.ContentPart `json:"content"`
+	Text       string                 `json:"text"`
+	// Data is set when the tool output is itself JSON.
+	Data json.RawMessage `json:"data,omitempty"`
+	// Sources are the analytics graphs the result was read from.
+	Sources []protocol.DataSource `json:"sources,omitempty"`
+	// Charts are the tool's plottable data, as in chat responses.
+	Charts []protocol.ChartData `json:"charts,omitempty"`
+}
+
+// handleQuery calls a single MCP tool directly: POST /v1/query {"tool": "...", "arguments": {...}}.
+func (h *Handler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req QueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_json", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	req.Tool = strings.TrimSpace(req.Tool)
+	if req.Tool == "" {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "missing_tool", "tool is required")
+		return
+	}
+	if req.Arguments == nil {
+		req.Arguments = map[string]any{}
+	}
+	h.callToolDirect(w, r, req.Tool, req.Arguments)
+}
+
+// callToolDirect validates args against the tool's input schema, injects the caller's token,
+// and writes the MCP result as a QueryResponse.
+func (h *Handler) callToolDirect(w http.ResponseWriter, r *http.Request, tool string, args map[string]any) {
+	ctx := r.Context()
+	tools, err := h.mcp.ListTools(ctx)
+	if err != nil {
+		h.log(ctx).Errorf("list tools error: %v", err)
+		writeError(w, http.StatusBadGateway, errTypeAPI, "tools_unavailable", fmt.Sprintf("list tools error: %v", err))
+		return
+	}
+	idx := slices.IndexFunc(tools, func(t protocol.ToolDescriptor) bool { return t.Name == tool })
+	if idx < 0 {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "unknown_tool", fmt.Sprintf("unknown tool %q", tool))
+		return
+	}
+	if caller, _ := IdentityFrom(ctx); !caller.allows(tool) {
+		writeError(w, http.StatusForbidden, errTypeInvalidRequest, "tool_not_permitted", fmt.Sprintf("tool %q is not permitted for this caller", tool))
+		return
+	}
+	if schema := tools[idx].InputSchema; schema != nil {
+		if err := validateArgs(*schema, args); err != nil {
+			writeParamError(w, err)
+			return
+		}
+	}
+	injectAuthToken(tool, analyticsToken(r), args)
+
+	start := time.Now()
+	result, err := h.mcp.CallTool(ctx, tool, args)
+	h.noteToolResult(ctx, tool, err)
+	if err != nil {
+		h.log(ctx).Errorf("tool error for %s: %v", tool, err)
+		var rpcErr *chatserver.RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == protocol.CodeInvalidArgs {
+			writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_arguments", rpcErr.Message)
+			return
+		}
+		writeToolError(w, err)
+		return
+	}
+	resp := QueryResponse{
+		Object:     "tool.result",
+		Tool:       tool,
+		Arguments:  redactArgs(args),
+		DurationMs: time.Since(start).Milliseconds(),
+		Content:    result.Content,
+		Text:       renderContent(result),
+	}
+	if text := strings.TrimSpace(resp.Text); text != "" && json.Valid([]byte(text)) {
+		resp.Data = json.RawMessage(text)
+	}
+	if result.Meta != nil {
+		resp.Sources = result.Meta.Sources
+		resp.Charts = result.Meta.Charts
+	}
+	writeJSON(w, resp, http.StatusOK)
+}
+
+// validateArgs checks args against a tool's input schema: required properties, types, enums,
+// and unknown properties when the schema sets additionalProperties to false.
+func validateArgs(schema protocol.JSONSchema, args map[string]any) *ParamError {
+	for _, name := range schema.Required {
+		if _, ok := args[name]; !ok {
+			return &ParamError{Param: name, Code: "missing_required_parameter", Message: fmt.Sprintf("missing required argument %q", name)}
+		}
+	}
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop, ok := schema.Properties[name]
+		if !ok {
+			if open, ok := schema.AdditionalProperties.(bool); ok && !open {
+				return &ParamError{Param: name, Code: "unknown_parameter", Message: fmt.Sprintf("unknown argument %q", name)}
+			}
+			continue
+		}
+		if msg := checkValue(prop, args[name]); msg != "" {
+			return &ParamError{Param: name, Code: "invalid_parameter_value", Message: fmt.Sprintf("argument %q %s", name, msg)}
+		}
+	}
+	return nil
+}
+
+// checkValue returns why v does not satisfy s, or "" when it does.
+func checkValue(s protocol.JSONSchema, v any) string {
+	if v == nil {
+		return ""
+	}
+	switch s.Type {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return "must be one of " + strings.Join(s.Enum, ", ")
+		}
+	case "integer":
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) {
+			return "must be an integer"
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return "must be a number"
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return "must be a boolean"
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return "must be an array"
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				if msg := checkValue(*s.Items, item); msg != "" {
+					return fmt.Sprintf("item %d %s", i, msg)
+				}
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return "must be an object"
+		}
+		if err := validateArgs(s, obj); err != nil {
+			return err.Message
+		}
+	}
+	return ""
+}
+
+// writeToolError maps an MCP tool call failure to the client error: an exhausted tenant quota
+// is 429, anything else is an upstream 502.
+func writeToolError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeDeadlineExceeded(w)
+		return
+	}
+	var rpcErr *chatserver.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == protocol.CodeQuotaExceeded {
+		writeError(w, http.StatusTooManyRequests, errTypeRateLimit, "quota_exceeded", rpcErr.Message)
+		return
+	}
+	writeError(w, http.StatusBadGateway, errTypeAPI, "tool_call_failed", fmt.Sprintf("tool error: %v", err))
+}
@@ -0,0 +1,94 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// statsSchema is a tool schema using every type validateArgs checks.
+var statsSchema = protocol.JSONSchema{
+	Type:     "object",
+	Required: []string{"days"},
+	Properties: map[string]protocol.JSONSchema{
+		"days":     {Type: "integer"},
+		"ratio":    {Type: "number"},
+		"compare":  {Type: "boolean"},
+		"currency": {Type: "string", Enum: []string{"USDC", "BTC"}},
+		"projects": {Type: "array", Items: &protocol.JSONSchema{Type: "integer"}},
+		"filter": {Type: "object", AdditionalProperties: false, Properties: map[string]protocol.JSONSchema{
+			"status": {Type: "string"},
+		}},
+	},
+}
+
+func TestValidateArgs(t *testing.T) {
+	closed := statsSchema
+	closed.AdditionalProperties = false
+	cases := []struct {
+		name   string
+		schema protocol.JSONSchema
+		args   string
+		param  string
+		code   string
+		msg    string
+	}{
+		{"valid", statsSchema, `{"days":7,"ratio":0.5,"compare":true,"currency":"USDC","projects":[1,2],"filter":{"status":"paid"}}`, "", "", ""},
+		{"null values pass", statsSchema, `{"days":7,"currency":null}`, "", "", ""},
+		{"missing required", statsSchema, `{"ratio":1}`, "days", "missing_required_parameter", `missing required argument "days"`},
+		{"integer as string", statsSchema, `{"days":"7"}`, "days", "invalid_parameter_value", `argument "days" must be an integer`},
+		{"fractional integer", statsSchema, `{"days":7.5}`, "days", "invalid_parameter_value", `argument "days" must be an integer`},
+		{"number as bool", statsSchema, `{"days":7,"ratio":true}`, "ratio", "invalid_parameter_value", `argument "ratio" must be a number`},
+		{"boolean as string", statsSchema, `{"days":7,"compare":"yes"}`, "compare", "invalid_parameter_value", `argument "compare" must be a boolean`},
+		{"string as number", statsSchema, `{"days":7,"currency":5}`, "currency", "invalid_parameter_value", `argument "currency" must be a string`},
+		{"value outside enum", statsSchema, `{"days":7,"currency":"DOGE"}`, "currency", "invalid_parameter_value", `argument "currency" must be one of USDC, BTC`},
+		{"array as scalar", statsSchema, `{"days":7,"projects":3}`, "projects", "invalid_parameter_value", `argument "projects" must be an array`},
+		{"bad array item", statsSchema, `{"days":7,"projects":[1,"two"]}`, "projects", "invalid_parameter_value", `argument "projects" item 1 must be an integer`},
+		{"object as string", statsSchema, `{"days":7,"filter":"paid"}`, "filter", "invalid_parameter_value", `argument "filter" must be an object`},
+		{"unknown nested field", statsSchema, `{"days":7,"filter":{"state":"paid"}}`, "filter", "invalid_parameter_value", `argument "filter" unknown argument "state"`},
+		{"unknown field allowed by default", statsSchema, `{"days":7,"extra":1}`, "", "", ""},
+		{"unknown field rejected", closed, `{"days":7,"extra":1}`, "extra", "unknown_parameter", `unknown argument "extra"`},
+		{"first bad field in name order", closed, `{"days":"x","compare":"y"}`, "compare", "invalid_parameter_value", `argument "compare" must be a boolean`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var args map[string]any
+			if err := json.Unmarshal([]byte(c.args), &args); err != nil {
+				t.Fatal(err)
+			}
+			err := validateArgs(c.schema, args)
+			if c.code == "" {
+				if err != nil {
+					t.Fatalf("rejected: %+v", err)
+				}
+				return
+			}
+			if err == nil || err.Param != c.param || err.Code != c.code || err.Message != c.msg {
+				t.Fatalf("got %+v, want %s %s %q", err, c.param, c.code, c.msg)
+			}
+		})
+	}
+}
+
+func TestQueryRejectsInvalidArgumentsBeforeCallingTheTool(t *testing.T) {
+	mcp := newFakeMCP(t, protocol.ToolDescriptor{Name: "payram_daily_stats", InputSchema: &statsSchema})
+	_, mux := newTestHandler(t, newFakeLLM(t, nil), mcp)
+
+	rec := serve(mux, http.MethodPost, "/v1/query", map[string]any{"tool": "payram_daily_stats", "arguments": map[string]any{"days": "seven"}})
+	body := decodeError(t, rec)
+	if rec.Code != http.StatusBadRequest || body.Param == nil || *body.Param != "days" || errorCode(body) != "invalid_parameter_value" {
+		t.Fatalf("got %d %+v", rec.Code, body)
+	}
+	if calls := mcp.toolCalls(); len(calls) != 0 {
+		t.Fatalf("invalid call reached the tool: %+v", calls)
+	}
+
+	rec = serve(mux, http.MethodPost, "/v1/query", map[string]any{"tool": "payram_daily_stats", "arguments": map[string]any{"days": 7}})
+	var resp QueryResponse
+	decodeJSON(t, rec, &resp)
+	if resp.Tool != "payram_daily_stats" || resp.Text != "result of payram_daily_stats" {
+		t.Fatalf("got %+v", resp)
+	}
+}
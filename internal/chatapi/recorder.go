@@ -0,0 +1,374 @@
+package chatapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/recording"
+)
+
+const (
+	recordingsPath    = "/admin/recordings"
+	recordingIDHeader = "X-Recording-ID"
+	// maxRecordedResponse caps the chat response body kept in a bundle.
+	maxRecordedResponse = 1 << 20
+)
+
+var recordingIDPattern = regexp.MustCompile(`^[0-9]{8}T[0-9]{9}-[0-9a-f]{8}$`)
+
+// Bundle is everything one chat request did: the request, each LLM exchange, each MCP tool
+// call with the analytics HTTP exchanges behind it, and the response. Credentials are redacted.
+type Bundle struct {
+	ID         string          `json:"id"`
+	Time       time.Time       `json:"time"`
+	DurationMs int64           `json:"duration_ms"`
+	User       string          `json:"user,omitempty"`
+	Tenant     string          `json:"tenant,omitempty"`
+	Request    json.RawMessage `json:"request"`
+	LLM        []LLMExchange   `json:"llm"`
+	Tools      []ToolExchange  `json:"tools"`
+	Status     int             `json:"status"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Truncated  bool            `json:"truncated,omitempty"`
+
+	mu sync.Mutex
+}
+
+// LLMExchange is one OpenAI call.
+type LLMExchange struct {
+	Request    json.RawMessage `json:"request"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// ToolExchange is one MCP tools/call and the analytics HTTP exchanges the tool made.
+type ToolExchange struct {
+	Name       string                  `json:"name"`
+	Arguments  json.RawMessage         `json:"arguments"`
+	Result     *protocol.CallResult    `json:"result,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+	DurationMs int64                   `json:"duration_ms"`
+	Exchanges  []protocol.HTTPExchange `json:"exchanges,omitempty"`
+}
+
+// recordingSummary is a bundle's entry in the recordings list.
+type recordingSummary struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	DurationMs int64     `json:"duration_ms"`
+	User       string    `json:"user,omitempty"`
+	Tenant     string    `json:"tenant,omitempty"`
+	Status     int       `json:"status"`
+	LLMCalls   int       `json:"llm_calls"`
+	ToolCalls  int       `json:"tool_calls"`
+}
+
+// Recorder writes bundles to a directory, keeping only the newest max of them.
+type Recorder struct {
+	mu  sync.Mutex
+	dir string
+	max int
+}
+
+// OpenRecorder records into dir, creating it if needed. max <= 0 keeps 200 bundles.
+func OpenRecorder(dir string, max int) (*Recorder, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, fmt.Errorf("recording directory is required")
+	}
+	if max <= 0 {
+		max = 200
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("mkdir %s: %w", dir, err)
+	}
+	return &Recorder{dir: dir, max: max}, nil
+}
+
+// SetRecorder enables the flight recorder: every chat request is saved as a bundle, listed
+// under /admin/recordings for API key callers.
+func (h *Handler) SetRecorder(r *Recorder) {
+	h.recorder = r
+}
+
+// save writes b and prunes the oldest bundles beyond the retention cap.
+func (r *Recorder) save(b *Bundle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := writeJSONFile(filepath.Join(r.dir, b.ID+".json"), b); err != nil {
+		return err
+	}
+	ids, err := r.ids()
+	if err != nil {
+		return err
+	}
+	for len(ids) > r.max {
+		if err := os.Remove(filepath.Join(r.dir, ids[0]+".json")); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("prune recording: %w", err)
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// ids lists bundle IDs oldest first; IDs start with a timestamp so they sort by time.
+func (r *Recorder) ids() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read recordings: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && recordingIDPattern.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r *Recorder) load(id string) (*Bundle, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("decode recording %s: %w", id, err)
+	}
+	return &b, nil
+}
+
+func (r *Recorder) list() ([]recordingSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids, err := r.ids()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]recordingSummary, 0, len(ids))
+	for _, id := range slices.Backward(ids) {
+		b, err := r.load(id)
+		if err != nil {
+			continue
+		}
+		out = append(out, recordingSummary{
+			ID: b.ID, Time: b.Time, DurationMs: b.DurationMs, User: b.User, Tenant: b.Tenant,
+			Status: b.Status, LLMCalls: len(b.LLM), ToolCalls: len(b.Tools),
+		})
+	}
+	return out, nil
+}
+
+func newRecordingID(now time.Time) string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	now = now.UTC()
+	return fmt.Sprintf("%s%03d-%s", now.Format("20060102T150405"), now.Nanosecond()/int(time.Millisecond), hex.EncodeToString(b[:]))
+}
+
+type bundleKey struct{}
+
+// startRecording begins a bundle for a chat request when the recorder is enabled. It returns
+// the context to serve the request under and a writer that captures the response; finish
+// must be called once the handler returns.
+func (h *Handler) startRecording(w http.ResponseWriter, r *http.Request, req ChatCompletionRequest) (context.Context, http.ResponseWriter, func()) {
+	ctx := r.Context()
+	if h.recorder == nil {
+		return ctx, w, func() {}
+	}
+	now := time.Now()
+	b := &Bundle{ID: newRecordingID(now), Time: now.UTC(), Request: redactedJSON(req), LLM: []LLMExchange{}, Tools: []ToolExchange{}}
+	if id, ok := IdentityFrom(ctx); ok {
+		b.User = id.Username
+		if id.Tenant != nil {
+			b.Tenant = id.Tenant.ID
+		}
+	}
+	w.Header().Set(recordingIDHeader, b.ID)
+	rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+	ctx = chatserver.WithRecording(context.WithValue(ctx, bundleKey{}, b))
+	return ctx, rw, func() {
+		b.mu.Lock()
+		b.DurationMs = time.Since(now).Milliseconds()
+		b.Status = rw.status
+		b.Truncated = rw.truncated
+		if body := bytes.TrimSpace(rw.body.Bytes()); json.Valid(body) {
+			b.Response = json.RawMessage(recording.RedactBody(string(body)))
+		}
+		b.mu.Unlock()
+		if err := h.recorder.save(b); err != nil {
+			h.log(ctx).Warnf("save recording %s: %v", b.ID, err)
+		}
+	}
+}
+
+func bundleFrom(ctx context.Context) *Bundle {
+	b, _ := ctx.Value(bundleKey{}).(*Bundle)
+	return b
+}
+
+// recordLLM adds an OpenAI exchange to the request's bundle, if it is being recorded.
+func recordLLM(ctx context.Context, req ChatCompletionRequest, raw []byte, err error, d time.Duration) {
+	b := bundleFrom(ctx)
+	if b == nil {
+		return
+	}
+	ex := LLMExchange{Request: redactedJSON(req), DurationMs: d.Milliseconds()}
+	if json.Valid(raw) {
+		ex.Response = json.RawMessage(recording.RedactBody(string(raw)))
+	}
+	if err != nil {
+		ex.Error = err.Error()
+	}
+	b.mu.Lock()
+	b.LLM = append(b.LLM, ex)
+	b.mu.Unlock()
+}
+
+// recordTool adds an MCP tool call to the request's bundle, if it is being recorded. The
+// analytics exchanges come back in the result's _meta, or in the error data when the call failed.
+func recordTool(ctx context.Context, name string, args map[string]any, result protocol.CallResult, err error, d time.Duration) {
+	b := bundleFrom(ctx)
+	if b == nil {
+		return
+	}
+	ex := ToolExchange{Name: name, Arguments: redactedJSON(args), DurationMs: d.Milliseconds()}
+	if err != nil {
+		ex.Error = err.Error()
+		var rpcErr *chatserver.RPCError
+		if errors.As(err, &rpcErr) {
+			ex.Exchanges = exchangesFromData(rpcErr.Data)
+		}
+	} else {
+		if result.Meta != nil {
+			ex.Exchanges = result.Meta.Exchanges
+		}
+		stripped := result
+		stripped.Meta = nil
+		if charts := resultCharts(result); len(charts) > 0 {
+			stripped.Meta = &protocol.CallMeta{Charts: charts}
+		}
+		ex.Result = &stripped
+	}
+	b.mu.Lock()
+	b.Tools = append(b.Tools, ex)
+	b.mu.Unlock()
+}
+
+// exchangesFromData reads the exchanges the MCP server attaches to error data.
+func exchangesFromData(data any) []protocol.HTTPExchange {
+	if data == nil {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var meta protocol.CallMeta
+	if json.Unmarshal(raw, &meta) != nil {
+		return nil
+	}
+	return meta.Exchanges
+}
+
+func redactedJSON(v any) json.RawMessage {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(recording.RedactBody(string(raw)))
+}
+
+// recordingWriter keeps a copy of the response for the bundle.
+type recordingWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if room := maxRecordedResponse - w.body.Len(); room < len(p) {
+		w.body.Write(p[:max(room, 0)])
+		w.truncated = true
+	} else {
+		w.body.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// handleRecordings serves the flight recorder to API key callers:
+//
+//	GET /admin/recordings       newest first
+//	GET /admin/recordings/{id}  one bundle
+func (h *Handler) handleRecordings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if id, _ := IdentityFrom(r.Context()); !id.isOperator() {
+		writeError(w, http.StatusForbidden, errTypeInvalidRequest, "forbidden", "recordings require the API key")
+		return
+	}
+	if h.recorder == nil {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "not_found", "recording is not enabled")
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, recordingsPath), "/")
+	if id == "" {
+		list, err := h.recorder.list()
+		if err != nil {
+			h.log(r.Context()).Errorf("list recordings: %v", err)
+			writeError(w, http.StatusInternalServerError, errTypeAPI, "storage_error", "failed to list recordings")
+			return
+		}
+		writeJSON(w, map[string]any{"object": "list", "data": list}, http.StatusOK)
+		return
+	}
+	if !recordingIDPattern.MatchString(id) {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "recording_not_found", fmt.Sprintf("no recording %q", id))
+		return
+	}
+	b, err := h.recorder.load(id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			writeError(w, http.StatusNotFound, errTypeInvalidRequest, "recording_not_found", fmt.Sprintf("no recording %q", id))
+			return
+		}
+		h.log(r.Context()).Errorf("load recording: %v", err)
+		writeError(w, http.StatusInternalServerError, errTypeAPI, "storage_error", "failed to load recording")
+		return
+	}
+	writeJSON(w, b, http.StatusOK)
+}
@@ -0,0 +1,219 @@
+package chatapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// discoveryTool is always offered alongside the routed tools. When the model calls it,
+	// the request is retried with every tool.
+	discoveryTool = "find_more_tools"
+	// maxRouterVectors bounds the cached tool embeddings; the cache is reset past it.
+	maxRouterVectors = 1000
+)
+
+// ToolRouter advertises only the tools most relevant to the latest user turns, ranked by
+// embedding similarity, so prompts do not carry every tool descriptor.
+type ToolRouter struct {
+	model string
+	topK  int
+
+	mu      sync.Mutex
+	vectors map[string][]float64
+}
+
+// NewToolRouter ranks tools with the OpenAI embedding model and offers the best topK.
+func NewToolRouter(model string, topK int) (*ToolRouter, error) {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return nil, fmt.Errorf("tool router needs an embedding model")
+	}
+	if topK <= 0 {
+		return nil, fmt.Errorf("tool router top-k must be positive, got %d", topK)
+	}
+	return &ToolRouter{model: model, topK: topK, vectors: map[string][]float64{}}, nil
+}
+
+// SetToolRouter enables embedding-based tool routing (see ToolRouter).
+func (h *Handler) SetToolRouter(r *ToolRouter) {
+	h.router = r
+}
+
+// routeTools picks the tools to advertise for messages, plus the discovery tool. All tools
+// are returned when routing is off, there are no more than top-k of them, or embedding fails.
+func (h *Handler) routeTools(ctx context.Context, messages []OAChatMessage, tools []OATool) []OATool {
+	r := h.router
+	if r == nil || len(tools) <= r.topK {
+		return tools
+	}
+	query := routingQuery(messages)
+	if query == "" {
+		return tools
+	}
+	texts := make([]string, len(tools))
+	for i, t := range tools {
+		texts[i] = t.Function.Name + ": " + t.Function.Description
+	}
+	vectors, err := h.toolVectors(ctx, append(texts, query))
+	if err != nil {
+		h.log(ctx).Warnf("tool router: %v; offering all tools", err)
+		return tools
+	}
+	q := vectors[len(texts)]
+	scores := make([]float64, len(tools))
+	order := make([]int, len(tools))
+	for i := range tools {
+		scores[i] = cosine(q, vectors[i])
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case scores[a] > scores[b]:
+			return -1
+		case scores[a] < scores[b]:
+			return 1
+		}
+		return 0
+	})
+	picked := order[:r.topK]
+	slices.Sort(picked)
+	out := make([]OATool, 0, len(picked)+1)
+	names := make([]string, 0, len(picked))
+	for _, i := range picked {
+		out = append(out, tools[i])
+		names = append(names, tools[i].Function.Name)
+	}
+	h.log(ctx).Debugf("tool router offered %s of %d tools", strings.Join(names, ", "), len(tools))
+	return append(out, discoveryDescriptor())
+}
+
+// callsDiscovery reports whether the model asked for the tools it was not offered.
+func callsDiscovery(calls []OAToolCall) bool {
+	return slices.ContainsFunc(calls, func(tc OAToolCall) bool { return tc.Function.Name == discoveryTool })
+}
+
+func discoveryDescriptor() OATool {
+	return OATool{
+		Type: "function",
+		Function: OAFunction{
+			Name:        discoveryTool,
+			Description: "Offer every analytics tool. Call this when none of the other tools fits the question.",
+			Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+		},
+	}
+}
+
+// routingQuery is the text tools are ranked against: the last two user turns, so a
+// follow-up such as "and yesterday?" keeps the topic of the question before it.
+func routingQuery(messages []OAChatMessage) string {
+	var turns []string
+	for i := len(messages) - 1; i >= 0 && len(turns) < 2; i-- {
+		if messages[i].Role == "user" && strings.TrimSpace(messages[i].Content) != "" {
+			turns = append(turns, messages[i].Content)
+		}
+	}
+	slices.Reverse(turns)
+	return strings.Join(turns, "\n")
+}
+
+// toolVectors embeds texts, reusing cached tool embeddings. The last text is the query and
+// is never cached.
+func (h *Handler) toolVectors(ctx context.Context, texts []string) ([][]float64, error) {
+	r := h.router
+	out := make([][]float64, len(texts))
+	var missing []string
+	var at []int
+	r.mu.Lock()
+	for i, t := range texts {
+		if v, ok := r.vectors[t]; ok && i < len(texts)-1 {
+			out[i] = v
+			continue
+		}
+		missing = append(missing, t)
+		at = append(at, i)
+	}
+	r.mu.Unlock()
+
+	vectors, err := h.embed(ctx, r.model, missing)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.vectors)+len(vectors) > maxRouterVectors {
+		r.vectors = map[string][]float64{}
+	}
+	for j, i := range at {
+		out[i] = vectors[j]
+		if i < len(texts)-1 {
+			r.vectors[texts[i]] = vectors[j]
+		}
+	}
+	return out, nil
+}
+
+// embed calls the OpenAI embeddings endpoint.
+func (h *Handler) embed(ctx context.Context, model string, input []string) ([][]float64, error) {
+	body, err := json.Marshal(map[string]any{"model": model, "input": input})
+	if err != nil {
+		return nil, fmt.Errorf("encode embeddings request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.openaiBase+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build embeddings request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+h.openaiKey)
+	httpResp, err := h.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("call embeddings: %w", err)
+	}
+	defer httpResp.Body.Close()
+	respBody, _ := io.ReadAll(httpResp.Body)
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, parseUpstreamError(httpResp.StatusCode, respBody)
+	}
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("decode embeddings response: %w", err)
+	}
+	out := make([][]float64, len(input))
+	for _, d := range resp.Data {
+		if d.Index >= 0 && d.Index < len(out) {
+			out[d.Index] = d.Embedding
+		}
+	}
+	for i, v := range out {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("embeddings response is missing input %d", i)
+		}
+	}
+	return out, nil
+}
+
+func cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range min(len(a), len(b)) {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,222 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// routerTopics are the dimensions fakeEmbedder projects texts onto.
+var routerTopics = []string{"refund", "currenc", "daily", "deposit"}
+
+// fakeEmbedder serves /embeddings, giving each text a weight on every topic it mentions, and
+// hands every other path to llm.
+type fakeEmbedder struct {
+	*httptest.Server
+	status int
+
+	mu     sync.Mutex
+	inputs [][]string
+}
+
+func newFakeEmbedder(t *testing.T, llm *fakeLLM) *fakeEmbedder {
+	f := &fakeEmbedder{status: http.StatusOK}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			llm.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		var req struct {
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.inputs = append(f.inputs, req.Input)
+		status := f.status
+		f.mu.Unlock()
+		if status != http.StatusOK {
+			writeJSON(w, map[string]any{"error": map[string]any{"message": "embeddings down"}}, status)
+			return
+		}
+		data := make([]map[string]any, len(req.Input))
+		for i, text := range req.Input {
+			v := []float64{0.01, 0.01, 0.01, 0.01}
+			for d, topic := range routerTopics {
+				if strings.Contains(strings.ToLower(text), topic) {
+					v[d] = 1
+				}
+			}
+			data[i] = map[string]any{"index": i, "embedding": v}
+		}
+		writeJSON(w, map[string]any{"data": data}, http.StatusOK)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeEmbedder) calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.inputs...)
+}
+
+func routerTools() []OATool {
+	var out []OATool
+	for _, name := range []string{"payram_daily_stats", "payram_refunds", "payram_deposit_distribution", "payram_currency_breakdown"} {
+		out = append(out, OATool{Type: "function", Function: OAFunction{Name: name, Description: "The " + name + " tool."}})
+	}
+	return out
+}
+
+func toolNamesOf(tools []OATool) string {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.Function.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func newRoutingHandler(t *testing.T, llm *fakeLLM, mcp *fakeMCP, topK int) (*Handler, *http.ServeMux, *fakeEmbedder) {
+	t.Helper()
+	emb := newFakeEmbedder(t, llm)
+	h := NewHandler(quietLogger(), "", "sk-test", "gpt-4o-mini", emb.URL, mcp.URL)
+	r, err := NewToolRouter("text-embedding-3-small", topK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetToolRouter(r)
+	mux := http.NewServeMux()
+	h.Register(mux)
+	return h, mux, emb
+}
+
+func TestNewToolRouter(t *testing.T) {
+	if _, err := NewToolRouter(" ", 3); err == nil {
+		t.Fatal("router without a model")
+	}
+	if _, err := NewToolRouter("m", 0); err == nil {
+		t.Fatal("router with top-k 0")
+	}
+}
+
+func TestCosine(t *testing.T) {
+	cases := []struct {
+		a, b []float64
+		want float64
+	}{
+		{[]float64{1, 0}, []float64{1, 0}, 1},
+		{[]float64{1, 0}, []float64{0, 1}, 0},
+		{[]float64{1, 1}, []float64{-1, -1}, -1},
+		{[]float64{3, 4}, []float64{6, 8}, 1},
+		{[]float64{0, 0}, []float64{1, 1}, 0},
+		// Only the dimensions both vectors have are compared.
+		{[]float64{1, 0, 5}, []float64{1, 0}, 1},
+	}
+	for _, c := range cases {
+		if got := cosine(c.a, c.b); math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("cosine(%v, %v) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestRoutingQuery(t *testing.T) {
+	messages := []OAChatMessage{
+		{Role: "user", Content: "first"},
+		{Role: "user", Content: "refunds last week"},
+		{Role: "assistant", Content: "You had 3 refunds."},
+		{Role: "user", Content: "  "},
+		{Role: "tool", Content: "tool output"},
+		{Role: "user", Content: "and yesterday?"},
+	}
+	if got := routingQuery(messages); got != "refunds last week\nand yesterday?" {
+		t.Fatalf("query %q", got)
+	}
+	if got := routingQuery([]OAChatMessage{{Role: "system", Content: "be brief"}}); got != "" {
+		t.Fatalf("query without user turns %q", got)
+	}
+}
+
+func TestRouteToolsOffersTheClosestTools(t *testing.T) {
+	h, _, emb := newRoutingHandler(t, newFakeLLM(t, nil), newFakeMCP(t), 2)
+	ask := []OAChatMessage{{Role: "user", Content: "refunds by currency"}}
+
+	got := h.routeTools(t.Context(), ask, routerTools())
+	// The picked tools keep their order, followed by the discovery tool.
+	if names := toolNamesOf(got); names != "payram_refunds,payram_currency_breakdown,"+discoveryTool {
+		t.Fatalf("offered %s", names)
+	}
+	if got := h.routeTools(t.Context(), []OAChatMessage{{Role: "user", Content: "daily deposits"}}, routerTools()); toolNamesOf(got) != "payram_daily_stats,payram_deposit_distribution,"+discoveryTool {
+		t.Fatalf("offered %s", toolNamesOf(got))
+	}
+
+	// Tool embeddings are cached; only the queries are embedded again.
+	calls := emb.calls()
+	if len(calls) != 2 || len(calls[0]) != 5 || len(calls[1]) != 1 || calls[1][0] != "daily deposits" {
+		t.Fatalf("embedding calls %q", calls)
+	}
+}
+
+func TestRouteToolsOffersEverythingWhenItCannotRoute(t *testing.T) {
+	all := toolNamesOf(routerTools())
+	ask := []OAChatMessage{{Role: "user", Content: "refunds"}}
+
+	h, _, emb := newRoutingHandler(t, newFakeLLM(t, nil), newFakeMCP(t), 4)
+	if got := h.routeTools(t.Context(), ask, routerTools()); toolNamesOf(got) != all {
+		t.Fatalf("no more tools than top-k: offered %s", toolNamesOf(got))
+	}
+	if n := len(emb.calls()); n != 0 {
+		t.Fatalf("%d embedding calls with nothing to route", n)
+	}
+
+	h, _, emb = newRoutingHandler(t, newFakeLLM(t, nil), newFakeMCP(t), 2)
+	if got := h.routeTools(t.Context(), []OAChatMessage{{Role: "system", Content: "hi"}}, routerTools()); toolNamesOf(got) != all {
+		t.Fatalf("no user turn: offered %s", toolNamesOf(got))
+	}
+	emb.mu.Lock()
+	emb.status = http.StatusServiceUnavailable
+	emb.mu.Unlock()
+	if got := h.routeTools(t.Context(), ask, routerTools()); toolNamesOf(got) != all {
+		t.Fatalf("embeddings down: offered %s", toolNamesOf(got))
+	}
+
+	h.SetToolRouter(nil)
+	if got := h.routeTools(t.Context(), ask, routerTools()); toolNamesOf(got) != all {
+		t.Fatalf("routing off: offered %s", toolNamesOf(got))
+	}
+}
+
+func TestChatRetriesWithEveryToolOnDiscovery(t *testing.T) {
+	llm := newFakeLLM(t, func(n int, _ ChatCompletionRequest) (int, any) {
+		switch n {
+		case 0:
+			return http.StatusOK, toolCall(discoveryTool, `{}`)
+		case 1:
+			return http.StatusOK, toolCall("payram_daily_stats", `{}`)
+		}
+		return http.StatusOK, answer("12 payments a day.")
+	})
+	mcp := newFakeMCP(t, tool("payram_daily_stats"), tool("payram_refunds"), tool("payram_deposit_distribution"), tool("payram_currency_breakdown"))
+	_, mux, _ := newRoutingHandler(t, llm, mcp, 1)
+
+	resp := decodeAnswer(t, chat(mux, "refunds"))
+	if content(resp) != "12 payments a day." {
+		t.Fatalf("answer %q", content(resp))
+	}
+	calls := llm.calls()
+	if len(calls) != 3 {
+		t.Fatalf("%d llm calls", len(calls))
+	}
+	if got := toolNamesOf(calls[0].Tools); got != "payram_refunds,"+discoveryTool {
+		t.Fatalf("first call offered %s", got)
+	}
+	if got := toolNamesOf(calls[1].Tools); strings.Contains(got, discoveryTool) || strings.Count(got, ",") != 3 {
+		t.Fatalf("retry offered %s, want every tool", got)
+	}
+	if tc := mcp.toolCalls(); len(tc) != 1 || tc[0].Name != "payram_daily_stats" {
+		t.Fatalf("tool calls %+v", tc)
+	}
+}
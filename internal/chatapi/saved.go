@@ -0,0 +1,282 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+const savedQueriesPath = "/v1/saved-queries"
+
+var savedNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _.-]{0,63}$`)
+
+// SavedQuery is a question with its resolved tool call, replayable without the LLM.
+type SavedQuery struct {
+	Name      string         `json:"name"`
+	Question  string         `json:"question,omitempty"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// SavedQueryStore holds saved queries, optionally persisted to a JSON file. Queries belong
+// to the caller that saved them (see quotaSubject): each tenant or user has its own names.
+type SavedQueryStore struct {
+	mu   sync.Mutex
+	path string
+	// queries is keyed by savedKey(owner, name).
+	queries map[string]SavedQuery
+}
+
+// savedKey is the store key of owner's query name. Names cannot contain '/'.
+func savedKey(owner, name string) string {
+	return owner + "/" + name
+}
+
+// OpenSavedQueryStore loads saved queries from file, or keeps them in memory only when file is empty.
+// Queries saved before they were scoped to an owner belong to "default", the caller without an identity.
+func OpenSavedQueryStore(file string) (*SavedQueryStore, error) {
+	s := &SavedQueryStore{path: strings.TrimSpace(file), queries: map[string]SavedQuery{}}
+	if s.path == "" {
+		return s, nil
+	}
+	if err := readJSONFile(s.path, &s.queries); err != nil {
+		return nil, err
+	}
+	for k, q := range s.queries {
+		if !strings.Contains(k, "/") {
+			delete(s.queries, k)
+			s.queries[savedKey("default", k)] = q
+		}
+	}
+	return s, nil
+}
+
+func (s *SavedQueryStore) list(owner string) []SavedQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []SavedQuery{}
+	for k, q := range s.queries {
+		if strings.HasPrefix(k, owner+"/") {
+			out = append(out, q)
+		}
+	}
+	slices.SortFunc(out, func(a, b SavedQuery) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+func (s *SavedQueryStore) get(owner, name string) (SavedQuery, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queries[savedKey(owner, name)]
+	return q, ok
+}
+
+func (s *SavedQueryStore) put(owner string, q SavedQuery) (SavedQuery, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := savedKey(owner, q.Name)
+	now := time.Now().UTC()
+	prev, existed := s.queries[key]
+	q.CreatedAt = now
+	if existed {
+		q.CreatedAt = prev.CreatedAt
+	}
+	q.UpdatedAt = now
+	s.queries[key] = q
+	if err := s.save(); err != nil {
+		if existed {
+			s.queries[key] = prev
+		} else {
+			delete(s.queries, key)
+		}
+		return q, existed, err
+	}
+	return q, existed, nil
+}
+
+func (s *SavedQueryStore) delete(owner, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := savedKey(owner, name)
+	prev, ok := s.queries[key]
+	if !ok {
+		return false, nil
+	}
+	delete(s.queries, key)
+	if err := s.save(); err != nil {
+		s.queries[key] = prev
+		return true, err
+	}
+	return true, nil
+}
+
+func (s *SavedQueryStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	return writeJSONFile(s.path, s.queries)
+}
+
+// SetSavedQueries enables the /v1/saved-queries API backed by store.
+func (h *Handler) SetSavedQueries(store *SavedQueryStore) {
+	h.saved = store
+}
+
+// handleSavedQueries serves:
+//
+//	GET    /v1/saved-queries             list
+//	POST   /v1/saved-queries             create or replace {name, question, tool, arguments}
+//	GET    /v1/saved-queries/{name}      fetch one
+//	DELETE /v1/saved-queries/{name}      delete
+//	POST   /v1/saved-queries/{name}/run  same as /v1/query with the saved call; body {"arguments": {...}} overrides saved values
+//
+// Callers only see and run the queries they saved themselves.
+func (h *Handler) handleSavedQueries(w http.ResponseWriter, r *http.Request) {
+	r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if h.saved == nil {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "not_found", "saved queries are not enabled")
+		return
+	}
+
+	owner, _ := h.quotaSubject(r.Context())
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, savedQueriesPath), "/")
+	name, action, _ := strings.Cut(rest, "/")
+
+	switch {
+	case name == "":
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, map[string]any{"object": "list", "data": h.saved.list(owner)}, http.StatusOK)
+		case http.MethodPost:
+			h.saveQuery(w, r, owner)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+	case action == "":
+		switch r.Method {
+		case http.MethodGet:
+			q, ok := h.saved.get(owner, name)
+			if !ok {
+				writeError(w, http.StatusNotFound, errTypeInvalidRequest, "saved_query_not_found", fmt.Sprintf("no saved query named %q", name))
+				return
+			}
+			writeJSON(w, q, http.StatusOK)
+		case http.MethodDelete:
+			found, err := h.saved.delete(owner, name)
+			if err != nil {
+				h.log(r.Context()).Errorf("delete saved query: %v", err)
+				writeError(w, http.StatusInternalServerError, errTypeAPI, "storage_error", "failed to delete saved query")
+				return
+			}
+			if !found {
+				writeError(w, http.StatusNotFound, errTypeInvalidRequest, "saved_query_not_found", fmt.Sprintf("no saved query named %q", name))
+				return
+			}
+			writeJSON(w, map[string]any{"name": name, "deleted": true}, http.StatusOK)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		}
+	case action == "run":
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		h.runSavedQuery(w, r, owner, name)
+	default:
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "not_found", "unknown saved query action")
+	}
+}
+
+func (h *Handler) saveQuery(w http.ResponseWriter, r *http.Request, owner string) {
+	var q SavedQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_json", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	q.Name = strings.TrimSpace(q.Name)
+	q.Tool = strings.TrimSpace(q.Tool)
+	if !savedNamePattern.MatchString(q.Name) {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_name", "name must be 1-64 letters, digits, spaces, '.', '_' or '-'")
+		return
+	}
+	if q.Tool == "" {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "missing_tool", "tool is required")
+		return
+	}
+	tools, err := h.mcp.ListTools(r.Context())
+	if err != nil {
+		h.log(r.Context()).Errorf("list tools error: %v", err)
+		writeError(w, http.StatusBadGateway, errTypeAPI, "tools_unavailable", fmt.Sprintf("list tools error: %v", err))
+		return
+	}
+	if !slices.ContainsFunc(tools, func(t protocol.ToolDescriptor) bool { return t.Name == q.Tool }) {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "unknown_tool", fmt.Sprintf("unknown tool %q", q.Tool))
+		return
+	}
+	// Credentials are injected from the caller at run time, never stored.
+	q.Arguments = withoutSensitiveArgs(q.Arguments)
+
+	saved, existed, err := h.saved.put(owner, q)
+	if err != nil {
+		h.log(r.Context()).Errorf("save query: %v", err)
+		writeError(w, http.StatusInternalServerError, errTypeAPI, "storage_error", "failed to save query")
+		return
+	}
+	status := http.StatusCreated
+	if existed {
+		status = http.StatusOK
+	}
+	writeJSON(w, saved, status)
+}
+
+func (h *Handler) runSavedQuery(w http.ResponseWriter, r *http.Request, owner, name string) {
+	q, ok := h.saved.get(owner, name)
+	if !ok {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "saved_query_not_found", fmt.Sprintf("no saved query named %q", name))
+		return
+	}
+	var body struct {
+		Arguments map[string]any `json:"arguments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, errTypeInvalidRequest, "invalid_json", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	args := maps.Clone(q.Arguments)
+	if args == nil {
+		args = map[string]any{}
+	}
+	maps.Copy(args, body.Arguments)
+	h.callToolDirect(w, r, q.Tool, args)
+}
+
+// withoutSensitiveArgs copies args, leaving out credential-like keys.
+func withoutSensitiveArgs(args map[string]any) map[string]any {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if !isSensitiveArg(k) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errTypeInvalidRequest, "method_not_allowed", "method not allowed")
+}
@@ -0,0 +1,105 @@
+package chatapi
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+// newTenantHandler returns a handler resolving the tenants "acme" (key "acme-key") and
+// "globex" (key "globex-key"), with saved queries in store.
+func newTenantHandler(t *testing.T, mcp *fakeMCP, store *SavedQueryStore) *http.ServeMux {
+	t.Helper()
+	reg, err := tenant.New([]tenant.Tenant{
+		{ID: "acme", BaseURL: "http://acme.invalid", Token: "t1", APIKeys: []string{"acme-key"}},
+		{ID: "globex", BaseURL: "http://globex.invalid", Token: "t2", APIKeys: []string{"globex-key"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, mux := newTestHandler(t, newFakeLLM(t, nil), mcp)
+	h.SetUsage(nil, reg, 0)
+	h.SetSavedQueries(store)
+	return mux
+}
+
+func TestSavedQueriesAreScopedToTheCaller(t *testing.T) {
+	mcp := newFakeMCP(t, tool("payram_daily_stats", "days"))
+	store, _ := OpenSavedQueryStore("")
+	mux := newTenantHandler(t, mcp, store)
+	acme := []string{tenant.KeyHeader, "acme-key"}
+	globex := []string{tenant.KeyHeader, "globex-key"}
+
+	save := func(days string, header []string) int {
+		return serve(mux, http.MethodPost, savedQueriesPath, map[string]any{"name": "weekly", "tool": "payram_daily_stats", "arguments": map[string]any{"days": days}}, header...).Code
+	}
+	if code := save("7", acme); code != http.StatusCreated {
+		t.Fatalf("acme save: %d", code)
+	}
+	// The same name is free for another tenant and does not replace acme's query.
+	if code := save("30", globex); code != http.StatusCreated {
+		t.Fatalf("globex save: %d", code)
+	}
+
+	var list struct{ Data []SavedQuery }
+	rec := serve(mux, http.MethodGet, savedQueriesPath, nil, acme...)
+	decodeJSON(t, rec, &list)
+	if len(list.Data) != 1 || list.Data[0].Arguments["days"] != "7" {
+		t.Fatalf("acme lists %+v", list.Data)
+	}
+	rec = serve(mux, http.MethodGet, savedQueriesPath, nil)
+	decodeJSON(t, rec, &list)
+	if len(list.Data) != 0 {
+		t.Fatalf("caller without a tenant lists %+v", list.Data)
+	}
+
+	if rec := serve(mux, http.MethodPost, savedQueriesPath+"/weekly/run", nil, globex...); rec.Code != http.StatusOK {
+		t.Fatalf("globex run: %d %s", rec.Code, rec.Body)
+	}
+	if calls := mcp.toolCalls(); len(calls) != 1 || calls[0].Args["days"] != "30" {
+		t.Fatalf("globex ran %+v", calls)
+	}
+
+	if rec := serve(mux, http.MethodDelete, savedQueriesPath+"/weekly", nil, globex...); rec.Code != http.StatusOK {
+		t.Fatalf("globex delete: %d", rec.Code)
+	}
+	for _, c := range []struct {
+		header []string
+		status int
+	}{{acme, http.StatusOK}, {globex, http.StatusNotFound}, {nil, http.StatusNotFound}} {
+		if rec := serve(mux, http.MethodGet, savedQueriesPath+"/weekly", nil, c.header...); rec.Code != c.status {
+			t.Errorf("get %v after globex deleted: %d, want %d", c.header, rec.Code, c.status)
+		}
+	}
+}
+
+func TestSavedQueryStoreAdoptsUnscopedQueries(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "saved.json")
+	if err := os.WriteFile(file, []byte(`{"weekly":{"name":"weekly","tool":"payram_daily_stats","arguments":{"days":7}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenSavedQueryStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.get("default", "weekly"); !ok {
+		t.Fatal("unscoped query not owned by default")
+	}
+	if _, ok := store.get("tenant:acme", "weekly"); ok {
+		t.Fatal("unscoped query visible to a tenant")
+	}
+
+	if _, _, err := store.put("tenant:acme", SavedQuery{Name: "weekly", Tool: "payram_daily_stats"}); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenSavedQueryStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(reopened.list("default")) + len(reopened.list("tenant:acme")); got != 2 {
+		t.Fatalf("reopened %d queries, want 2", got)
+	}
+}
@@ -0,0 +1,40 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/atomicfile"
+)
+
+// writeJSONFile atomically and durably replaces file with the JSON encoding of v.
+func writeJSONFile(file string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", filepath.Base(file), err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(file), err)
+	}
+	if err := atomicfile.WriteFile(file, data); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(file), err)
+	}
+	return nil
+}
+
+// readJSONFile decodes file into v; a missing file leaves v untouched.
+func readJSONFile(file string, v any) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read %s: %w", filepath.Base(file), err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode %s: %w", filepath.Base(file), err)
+	}
+	return nil
+}
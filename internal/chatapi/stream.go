@@ -0,0 +1,76 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// ChatCompletionChunk is one server-sent event of a streamed answer, as OpenAI streams them.
+// The last chunk carries the finish reason along with usage, tool trace, charts, provenance
+// and budget.
+type ChatCompletionChunk struct {
+	ID         string                 `json:"id"`
+	Object     string                 `json:"object"`
+	Model      string                 `json:"model"`
+	Choices    []ChunkChoice          `json:"choices"`
+	Usage      map[string]interface{} `json:"usage,omitempty"`
+	ToolTrace  []ToolTrace            `json:"tool_trace,omitempty"`
+	Charts     []protocol.ChartData   `json:"charts,omitempty"`
+	Provenance []ToolProvenance       `json:"provenance,omitempty"`
+	Budget     *BudgetStatus          `json:"budget,omitempty"`
+	// PromptVariant is set on the final chunk, like on ChatCompletionResponse.
+	PromptVariant string `json:"prompt_variant,omitempty"`
+}
+
+type ChunkChoice struct {
+	Index        int        `json:"index"`
+	Delta        ChunkDelta `json:"delta"`
+	FinishReason *string    `json:"finish_reason"`
+}
+
+type ChunkDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// writeCompletion writes resp as JSON, or when stream is set as text/event-stream chunks
+// ending in "data: [DONE]". The answer is complete before the first chunk is sent, tool
+// calls included, so errors still arrive as an ordinary JSON error response; streaming is
+// for clients built on OpenAI's streaming API. Each line of the answer is one chunk.
+func writeCompletion(w http.ResponseWriter, resp ChatCompletionResponse, stream bool) {
+	if !stream {
+		writeJSON(w, resp, http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+
+	send := func(chunk ChatCompletionChunk) {
+		chunk.ID, chunk.Object, chunk.Model = resp.ID, "chat.completion.chunk", resp.Model
+		raw, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", raw)
+		_ = rc.Flush()
+	}
+	for _, c := range resp.Choices {
+		send(ChatCompletionChunk{Choices: []ChunkChoice{{Index: c.Index, Delta: ChunkDelta{Role: c.Message.Role}}}})
+		for _, line := range strings.SplitAfter(c.Message.Content, "\n") {
+			if line != "" {
+				send(ChatCompletionChunk{Choices: []ChunkChoice{{Index: c.Index, Delta: ChunkDelta{Content: line}}}})
+			}
+		}
+	}
+	final := ChatCompletionChunk{Usage: resp.Usage, ToolTrace: resp.ToolTrace, Charts: resp.Charts, Provenance: resp.Provenance, Budget: resp.Budget, PromptVariant: resp.PromptVariant}
+	for _, c := range resp.Choices {
+		reason := c.FinishReason
+		final.Choices = append(final.Choices, ChunkChoice{Index: c.Index, FinishReason: &reason})
+	}
+	send(final)
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	_ = rc.Flush()
+}
@@ -0,0 +1,160 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// StructuredAnswer is the normalized analytics answer returned by /v1/chat/structured.
+type StructuredAnswer struct {
+	// Metric names what was measured, e.g. "total payments".
+	Metric string `json:"metric"`
+	// Value is the headline figure, or nil when the question has no single number.
+	Value *float64 `json:"value"`
+	// Unit is a currency code, "count", "percent" or similar; empty when not applicable.
+	Unit   string       `json:"unit"`
+	Period AnswerPeriod `json:"period"`
+	// CurrencyBreakdown splits Value by currency when the data has one.
+	CurrencyBreakdown []CurrencyAmount `json:"currency_breakdown"`
+	// Summary is the answer as one or two sentences.
+	Summary string `json:"summary"`
+}
+
+// AnswerPeriod is the time range an answer covers. Start and End are YYYY-MM-DD, and nil
+// when the answer is not tied to dates.
+type AnswerPeriod struct {
+	Label string  `json:"label"`
+	Start *string `json:"start"`
+	End   *string `json:"end"`
+}
+
+type CurrencyAmount struct {
+	Currency string  `json:"currency"`
+	Value    float64 `json:"value"`
+}
+
+// StructuredResponse is the body of a /v1/chat/structured answer. Answer is nil when the
+// reply was withheld by the content filter or the conversation budget, or the model
+// refused, in which case Refusal says why.
+type StructuredResponse struct {
+	ID           string                 `json:"id"`
+	Object       string                 `json:"object"`
+	Model        string                 `json:"model"`
+	Answer       *StructuredAnswer      `json:"answer"`
+	FinishReason string                 `json:"finish_reason"`
+	Refusal      string                 `json:"refusal,omitempty"`
+	Usage        map[string]interface{} `json:"usage,omitempty"`
+	ToolTrace    []ToolTrace            `json:"tool_trace,omitempty"`
+	Charts       []protocol.ChartData   `json:"charts,omitempty"`
+	Provenance   []ToolProvenance       `json:"provenance,omitempty"`
+	Budget       *BudgetStatus          `json:"budget,omitempty"`
+	// PromptVariant is as on ChatCompletionResponse.
+	PromptVariant string `json:"prompt_variant,omitempty"`
+}
+
+// structuredResponseFormat constrains a reply to StructuredAnswer with OpenAI structured
+// outputs. Strict schemas list every property as required; optional ones are nullable.
+var structuredResponseFormat = map[string]any{
+	"type": "json_schema",
+	"json_schema": map[string]any{
+		"name":   "analytics_answer",
+		"strict": true,
+		"schema": map[string]any{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             []string{"metric", "value", "unit", "period", "currency_breakdown", "summary"},
+			"properties": map[string]any{
+				"metric": map[string]any{"type": "string", "description": "What was measured, e.g. total payments."},
+				"value":  map[string]any{"type": []string{"number", "null"}, "description": "The headline figure; null when the question has no single number."},
+				"unit":   map[string]any{"type": "string", "description": "Currency code, count, percent, or empty."},
+				"period": map[string]any{
+					"type":                 "object",
+					"additionalProperties": false,
+					"required":             []string{"label", "start", "end"},
+					"properties": map[string]any{
+						"label": map[string]any{"type": "string", "description": "The period in words, e.g. last 7 days."},
+						"start": map[string]any{"type": []string{"string", "null"}, "description": "First day, YYYY-MM-DD."},
+						"end":   map[string]any{"type": []string{"string", "null"}, "description": "Last day, YYYY-MM-DD."},
+					},
+				},
+				"currency_breakdown": map[string]any{
+					"type":        "array",
+					"description": "The value split by currency, when the data has one; else empty.",
+					"items": map[string]any{
+						"type":                 "object",
+						"additionalProperties": false,
+						"required":             []string{"currency", "value"},
+						"properties": map[string]any{
+							"currency": map[string]any{"type": "string"},
+							"value":    map[string]any{"type": "number"},
+						},
+					},
+				},
+				"summary": map[string]any{"type": "string", "description": "The answer in one or two sentences."},
+			},
+		},
+	},
+}
+
+// handleStructured answers like /v1/chat/completions, but the answering call is constrained
+// to the StructuredAnswer schema and the reply is returned parsed: POST /v1/chat/structured.
+func (h *Handler) handleStructured(w http.ResponseWriter, r *http.Request) {
+	h.serveChat(w, r, true)
+}
+
+// writeAnswer writes the final reply of a conversation in the shape the endpoint promises.
+func (h *Handler) writeAnswer(w http.ResponseWriter, resp ChatCompletionResponse, stream, structured bool) {
+	if !structured {
+		writeCompletion(w, resp, stream)
+		return
+	}
+	if len(resp.Choices) == 0 {
+		writeError(w, http.StatusBadGateway, errTypeAPI, "empty_completion", "openai returned no choices")
+		return
+	}
+	out := StructuredResponse{
+		ID:            resp.ID,
+		Object:        "chat.structured",
+		Model:         resp.Model,
+		Usage:         resp.Usage,
+		ToolTrace:     resp.ToolTrace,
+		Charts:        resp.Charts,
+		Provenance:    resp.Provenance,
+		Budget:        resp.Budget,
+		PromptVariant: resp.PromptVariant,
+	}
+	choice := resp.Choices[0]
+	out.FinishReason = choice.FinishReason
+	switch {
+	case choice.Message.Refusal != "":
+		out.Refusal = choice.Message.Refusal
+	case choice.FinishReason == "content_filter", choice.FinishReason == finishBudget:
+		out.Refusal = choice.Message.Content
+	default:
+		answer, err := parseStructuredAnswer(choice.Message.Content)
+		if err != nil {
+			h.logger.Errorf("structured answer: %v", err)
+			writeError(w, http.StatusBadGateway, errTypeAPI, "invalid_structured_output", err.Error())
+			return
+		}
+		out.Answer = answer
+	}
+	writeJSON(w, out, http.StatusOK)
+}
+
+func parseStructuredAnswer(content string) (*StructuredAnswer, error) {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.DisallowUnknownFields()
+	var answer StructuredAnswer
+	if err := dec.Decode(&answer); err != nil {
+		return nil, fmt.Errorf("model reply does not match the answer schema: %v", err)
+	}
+	if answer.CurrencyBreakdown == nil {
+		answer.CurrencyBreakdown = []CurrencyAmount{}
+	}
+	return &answer, nil
+}
@@ -0,0 +1,219 @@
+package chatapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	summaryPrefix = "Summary of the earlier conversation:\n"
+	// maxSummaryInput caps each message rendered into a summarization request.
+	maxSummaryInput = 4000
+	// maxSummaries bounds the number of stored summaries; the least recently used are evicted.
+	maxSummaries = 1000
+)
+
+const summaryInstructions = `Summarize the conversation below between a user and PayRam's analytics assistant so it can replace the original turns.
+Keep every figure, date range, currency, project, filter, and tool result the user may refer back to, plus any open question.
+If a previous summary is included, merge it in. Write plain prose or short bullets, at most 300 words.`
+
+// summaryEntry is a stored summary of the first Covered non-system messages of a conversation.
+type summaryEntry struct {
+	Summary string    `json:"summary"`
+	Covered int       `json:"covered"`
+	Used    time.Time `json:"used"`
+}
+
+// SummaryStore keeps rolling conversation summaries keyed by a hash of the messages they replace,
+// so a client resending the same history gets the same summary without another model call.
+type SummaryStore struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]summaryEntry
+}
+
+// OpenSummaryStore loads summaries from file, or keeps them in memory only when file is empty.
+func OpenSummaryStore(file string) (*SummaryStore, error) {
+	s := &SummaryStore{path: strings.TrimSpace(file), entries: map[string]summaryEntry{}}
+	if s.path == "" {
+		return s, nil
+	}
+	if err := readJSONFile(s.path, &s.entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SummaryStore) get(key string) (summaryEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if ok {
+		e.Used = time.Now().UTC()
+		s.entries[key] = e
+	}
+	return e, ok
+}
+
+func (s *SummaryStore) put(key string, e summaryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.Used = time.Now().UTC()
+	s.entries[key] = e
+	for len(s.entries) > maxSummaries {
+		oldest := ""
+		for k, v := range s.entries {
+			if oldest == "" || v.Used.Before(s.entries[oldest].Used) {
+				oldest = k
+			}
+		}
+		delete(s.entries, oldest)
+	}
+	if s.path == "" {
+		return nil
+	}
+	return writeJSONFile(s.path, s.entries)
+}
+
+// prefixKeys returns keys[k] identifying msgs[:k+1].
+func prefixKeys(msgs []OAChatMessage) []string {
+	h := sha256.New()
+	keys := make([]string, len(msgs))
+	for i, m := range msgs {
+		raw, _ := json.Marshal(m)
+		fmt.Fprintf(h, "%d:", len(raw))
+		h.Write(raw)
+		keys[i] = hex.EncodeToString(h.Sum(nil))
+	}
+	return keys
+}
+
+// summarize replaces the oldest turns with a stored or freshly generated summary when messages
+// exceed the model's budget. When summarization is disabled or fails, messages are returned
+// unchanged and fitContext falls back to dropping turns.
+func (h *Handler) summarize(ctx context.Context, model string, messages []OAChatMessage, tools []OATool, params SamplingParams) []OAChatMessage {
+	if h.summaryModel == "" || h.summaries == nil {
+		return messages
+	}
+	_, trim := h.policy.fitContext(model, messages, tools, params)
+	if !trim.changed() {
+		return messages
+	}
+
+	start := 0
+	for start < len(messages) && messages[start].Role == "system" {
+		start++
+	}
+	lastUser := len(messages)
+	for i := len(messages) - 1; i >= start; i-- {
+		if messages[i].Role == "user" {
+			lastUser = i
+			break
+		}
+	}
+	history := messages[start:lastUser]
+	if len(history) == 0 {
+		return messages
+	}
+	keys := prefixKeys(history)
+
+	// Reuse the longest stored summary of this history.
+	prev := summaryEntry{}
+	for k := len(keys) - 1; k >= 0; k-- {
+		if e, ok := h.summaries.get(keys[k]); ok && e.Covered == k+1 {
+			prev = e
+			break
+		}
+	}
+	withSummary := func(e summaryEntry) []OAChatMessage {
+		out := make([]OAChatMessage, 0, len(messages)-e.Covered+1)
+		out = append(out, messages[:start]...)
+		out = append(out, OAChatMessage{Role: "system", Content: summaryPrefix + e.Summary})
+		return append(out, messages[start+e.Covered:]...)
+	}
+	if prev.Covered > 0 {
+		candidate := withSummary(prev)
+		if _, t := h.policy.fitContext(model, candidate, tools, params); !t.changed() {
+			return candidate
+		}
+	}
+
+	// Summarize enough old turns that the rest fits in half the budget, so the next few
+	// requests reuse this summary instead of triggering a new one.
+	keep := 0
+	cut := len(history)
+	for cut > prev.Covered {
+		n := estimateMessage(history[cut-1])
+		if keep+n > trim.Budget/2 {
+			break
+		}
+		keep += n
+		cut--
+	}
+	for cut < len(history) && history[cut].Role == "tool" {
+		cut++
+	}
+	if cut <= prev.Covered {
+		return messages
+	}
+
+	summary, err := h.generateSummary(ctx, prev.Summary, history[prev.Covered:cut])
+	if err != nil {
+		h.log(ctx).Warnf("conversation summary failed, falling back to trimming: %v", err)
+		if prev.Covered > 0 {
+			return withSummary(prev)
+		}
+		return messages
+	}
+	entry := summaryEntry{Summary: summary, Covered: cut}
+	if err := h.summaries.put(keys[cut-1], entry); err != nil {
+		h.log(ctx).Warnf("store conversation summary: %v", err)
+	}
+	h.log(ctx).WithField("model", h.summaryModel).Infof("summarized %d earlier messages", cut)
+	return withSummary(entry)
+}
+
+func (h *Handler) generateSummary(ctx context.Context, previous string, turns []OAChatMessage) (string, error) {
+	var sb strings.Builder
+	if previous != "" {
+		sb.WriteString("Previous summary:\n")
+		sb.WriteString(previous)
+		sb.WriteString("\n\nConversation:\n")
+	}
+	for _, m := range turns {
+		content := m.Content
+		if m.Role == "assistant" && len(m.ToolCalls) > 0 {
+			names := make([]string, 0, len(m.ToolCalls))
+			for _, tc := range m.ToolCalls {
+				names = append(names, tc.Function.Name+"("+tc.Function.Arguments+")")
+			}
+			content = "called " + strings.Join(names, ", ")
+		}
+		content, _ = truncate(content, maxSummaryInput)
+		role := m.Role
+		if m.Name != "" {
+			role += " " + m.Name
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", role, content)
+	}
+	resp, err := h.callOpenAI(ctx, ChatCompletionRequest{
+		Model: h.summaryModel,
+		Messages: []OAChatMessage{
+			{Role: "system", Content: summaryInstructions},
+			{Role: "user", Content: sb.String()},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
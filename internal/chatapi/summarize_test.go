@@ -0,0 +1,171 @@
+package chatapi
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSummaryModel = "summary-model"
+
+// summarizingLLM answers summarization requests with summary (or fails them with
+// summaryStatus) and everything else with "ok".
+func summarizingLLM(t *testing.T, summaryStatus int, summary string) *fakeLLM {
+	return newFakeLLM(t, func(_ int, req ChatCompletionRequest) (int, any) {
+		if req.Model != testSummaryModel {
+			return http.StatusOK, answer("ok")
+		}
+		if summaryStatus != http.StatusOK {
+			return summaryStatus, map[string]any{"error": map[string]any{"message": "overloaded"}}
+		}
+		return http.StatusOK, answer(summary)
+	})
+}
+
+// longHistory is turns earlier user/assistant exchanges of a few hundred tokens each,
+// followed by a final question.
+func longHistory(turns int) []OAChatMessage {
+	var msgs []OAChatMessage
+	for i := range turns {
+		msgs = append(msgs,
+			OAChatMessage{Role: "user", Content: fmt.Sprintf("question %d %s", i, strings.Repeat("volume ", 300))},
+			OAChatMessage{Role: "assistant", Content: fmt.Sprintf("answer %d", i)})
+	}
+	return append(msgs, OAChatMessage{Role: "user", Content: "latest question"})
+}
+
+func summaryCalls(llm *fakeLLM) (summaries, answers []ChatCompletionRequest) {
+	for _, c := range llm.calls() {
+		if c.Model == testSummaryModel {
+			summaries = append(summaries, c)
+		} else {
+			answers = append(answers, c)
+		}
+	}
+	return summaries, answers
+}
+
+func newSummarizingHandler(t *testing.T, llm *fakeLLM, store *SummaryStore) *http.ServeMux {
+	h, mux := newTestHandler(t, llm, newFakeMCP(t))
+	h.SetModelPolicy(smallWindow(4000))
+	h.SetSummarizer(testSummaryModel, store)
+	return mux
+}
+
+func askHistory(mux *http.ServeMux, messages []OAChatMessage) (int, string) {
+	rec := serve(mux, http.MethodPost, "/v1/chat/completions", map[string]any{"messages": messages, "max_tokens": 200})
+	return rec.Code, rec.Body.String()
+}
+
+func TestSummarizeSkipsConversationsThatFit(t *testing.T) {
+	llm := summarizingLLM(t, http.StatusOK, "unused")
+	store, _ := OpenSummaryStore("")
+	mux := newSummarizingHandler(t, llm, store)
+
+	if code, body := askHistory(mux, longHistory(1)); code != http.StatusOK {
+		t.Fatalf("status %d: %s", code, body)
+	}
+	if summaries, _ := summaryCalls(llm); len(summaries) != 0 {
+		t.Fatalf("summarized a conversation that fits: %+v", summaries)
+	}
+}
+
+func TestSummarizeReplacesOldTurnsAndReusesTheSummary(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "summaries.json")
+	store, err := OpenSummaryStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm := summarizingLLM(t, http.StatusOK, "  The user took 42 USDC payments.  ")
+	mux := newSummarizingHandler(t, llm, store)
+	history := longHistory(8)
+
+	if code, body := askHistory(mux, history); code != http.StatusOK {
+		t.Fatalf("status %d: %s", code, body)
+	}
+	summaries, answers := summaryCalls(llm)
+	if len(summaries) != 1 || len(answers) != 1 {
+		t.Fatalf("%d summary and %d answer calls", len(summaries), len(answers))
+	}
+	input := summaries[0].Messages[1].Content
+	if summaries[0].Messages[0].Content != summaryInstructions || !strings.Contains(input, "user: question 0") || strings.Contains(input, "latest question") {
+		t.Fatalf("summary request %+v", summaries[0].Messages)
+	}
+
+	sent := answers[0].Messages
+	if sent[1].Role != "system" || sent[1].Content != summaryPrefix+"The user took 42 USDC payments." {
+		t.Fatalf("no summary after the system prompt: %+v", sent[1])
+	}
+	if last := sent[len(sent)-1]; last.Content != "latest question" {
+		t.Fatalf("last message %+v", last)
+	}
+	for _, m := range sent {
+		if strings.HasPrefix(m.Content, "question 0 ") {
+			t.Fatal("summarized turn still sent")
+		}
+	}
+
+	// The same history again, and with one more exchange, reuses the stored summary.
+	askHistory(mux, history)
+	askHistory(mux, append(history, OAChatMessage{Role: "assistant", Content: "ok"}, OAChatMessage{Role: "user", Content: "and yesterday?"}))
+	if summaries, answers := summaryCalls(llm); len(summaries) != 1 || len(answers) != 3 {
+		t.Fatalf("%d summary and %d answer calls, want the summary reused", len(summaries), len(answers))
+	}
+
+	reopened, err := OpenSummaryStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened.entries) != 1 {
+		t.Fatalf("reopened %d summaries", len(reopened.entries))
+	}
+}
+
+func TestSummarizeFallsBackToTrimming(t *testing.T) {
+	llm := summarizingLLM(t, http.StatusServiceUnavailable, "")
+	store, _ := OpenSummaryStore("")
+	mux := newSummarizingHandler(t, llm, store)
+
+	if code, body := askHistory(mux, longHistory(8)); code != http.StatusOK {
+		t.Fatalf("status %d: %s", code, body)
+	}
+	_, answers := summaryCalls(llm)
+	if len(answers) != 1 {
+		t.Fatalf("%d answer calls", len(answers))
+	}
+	for _, m := range answers[0].Messages {
+		if strings.HasPrefix(m.Content, summaryPrefix) {
+			t.Fatalf("sent a summary after it failed: %+v", m)
+		}
+	}
+	if len(answers[0].Messages) >= len(longHistory(8))+1 {
+		t.Fatal("conversation was not trimmed")
+	}
+	if len(store.entries) != 0 {
+		t.Fatal("stored a failed summary")
+	}
+}
+
+func TestSummaryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store, _ := OpenSummaryStore("")
+	start := time.Now().Add(-time.Hour)
+	for i := range maxSummaries {
+		store.entries[fmt.Sprint(i)] = summaryEntry{Summary: "s", Covered: 1, Used: start.Add(time.Duration(i) * time.Second)}
+	}
+	store.get("0")
+	if err := store.put("new", summaryEntry{Summary: "s", Covered: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.entries) != maxSummaries {
+		t.Fatalf("%d summaries kept", len(store.entries))
+	}
+	if _, ok := store.entries["1"]; ok {
+		t.Fatal("least recently used summary not evicted")
+	}
+	if _, ok := store.entries["0"]; !ok {
+		t.Fatal("evicted a summary that was just read")
+	}
+}
@@ -0,0 +1,117 @@
+package chatapi
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+const toolsPath = "/v1/tools"
+
+// ToolInfo describes one tool the assistant can use, for a "what can you do?" panel.
+type ToolInfo struct {
+	Object string `json:"object"`
+	Name   string `json:"name"`
+	// Title is a readable form of Name, e.g. "Daily stats" for payram_daily_stats.
+	Title string `json:"title"`
+	// Summary is the first line of Description.
+	Summary     string      `json:"summary"`
+	Description string      `json:"description"`
+	Parameters  []ToolParam `json:"parameters"`
+}
+
+// ToolParam is one argument a tool takes. Credential and endpoint overrides are left out.
+type ToolParam struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Required    bool     `json:"required"`
+	Enum        []string `json:"enum,omitempty"`
+}
+
+// titleWords are spelled in capitals in tool titles.
+var titleWords = map[string]string{"api": "API", "csv": "CSV", "id": "ID", "mcp": "MCP", "usd": "USD"}
+
+// handleTools lists the tools the caller may use: GET /v1/tools.
+func (h *Handler) handleTools(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	tools, err := h.mcp.ListTools(r.Context())
+	if err != nil {
+		h.log(r.Context()).Errorf("list tools error: %v", err)
+		writeError(w, http.StatusBadGateway, errTypeAPI, "tools_unavailable", fmt.Sprintf("list tools error: %v", err))
+		return
+	}
+	caller, _ := IdentityFrom(r.Context())
+	data := make([]ToolInfo, 0, len(tools))
+	for _, t := range tools {
+		if caller.allows(t.Name) {
+			data = append(data, toolInfo(t))
+		}
+	}
+	writeJSON(w, map[string]any{"object": "list", "data": data}, http.StatusOK)
+}
+
+func toolInfo(t protocol.ToolDescriptor) ToolInfo {
+	desc := strings.TrimSpace(t.Description)
+	summary, _, _ := strings.Cut(desc, "\n")
+	info := ToolInfo{
+		Object:      "tool",
+		Name:        t.Name,
+		Title:       toolTitle(t.Name),
+		Summary:     strings.TrimSpace(summary),
+		Description: desc,
+		Parameters:  []ToolParam{},
+	}
+	if t.InputSchema == nil {
+		return info
+	}
+	for name, s := range t.InputSchema.Properties {
+		if isSensitiveArg(name) {
+			continue
+		}
+		info.Parameters = append(info.Parameters, ToolParam{
+			Name:        name,
+			Type:        s.Type,
+			Description: s.Description,
+			Required:    slices.Contains(t.InputSchema.Required, name),
+			Enum:        s.Enum,
+		})
+	}
+	// Required parameters first, then by name, so the order does not change between calls.
+	sort.Slice(info.Parameters, func(i, j int) bool {
+		a, b := info.Parameters[i], info.Parameters[j]
+		if a.Required != b.Required {
+			return a.Required
+		}
+		return a.Name < b.Name
+	})
+	return info
+}
+
+// toolTitle turns a tool name such as payram_fetch_graph_data into "Fetch graph data".
+func toolTitle(name string) string {
+	words := strings.FieldsFunc(strings.TrimPrefix(name, "payram_"), func(r rune) bool { return r == '_' || r == '-' })
+	if len(words) == 0 {
+		return name
+	}
+	for i, w := range words {
+		switch {
+		case titleWords[w] != "":
+			words[i] = titleWords[w]
+		case i == 0:
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
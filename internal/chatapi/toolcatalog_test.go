@@ -0,0 +1,103 @@
+package chatapi
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+	"github.com/payram/payram-analytics-mcp-server/internal/tools"
+)
+
+func TestToolTitle(t *testing.T) {
+	cases := map[string]string{
+		"payram_fetch_graph_data": "Fetch graph data",
+		"payram_export_csv":       "Export CSV",
+		"payram_usd_volume":       "USD volume",
+		"payram_payment-by-id":    "Payment by ID",
+		"other_tool":              "Other tool",
+		"payram_":                 "payram_",
+	}
+	for name, want := range cases {
+		if got := toolTitle(name); got != want {
+			t.Errorf("toolTitle(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestToolInfo(t *testing.T) {
+	info := toolInfo(protocol.ToolDescriptor{
+		Name:        "payram_daily_stats",
+		Description: "  Daily payment totals.\nUse it for trends.  ",
+		InputSchema: &protocol.JSONSchema{
+			Type: "object",
+			Properties: map[string]protocol.JSONSchema{
+				"days":        {Type: "integer", Description: "How many days back."},
+				"currency":    {Type: "string", Enum: []string{"USDC", "BTC"}},
+				"date_filter": {Type: "string"},
+				"token":       {Type: "string"},
+				"api_key":     {Type: "string"},
+				"base_url":    {Type: "string"},
+			},
+			Required: []string{"date_filter", "currency"},
+		},
+	})
+	if info.Object != "tool" || info.Title != "Daily stats" || info.Summary != "Daily payment totals." || info.Description != "Daily payment totals.\nUse it for trends." {
+		t.Fatalf("info %+v", info)
+	}
+	// Required parameters come first, each group sorted by name, and credentials are left out.
+	want := []ToolParam{
+		{Name: "currency", Type: "string", Required: true, Enum: []string{"USDC", "BTC"}},
+		{Name: "date_filter", Type: "string", Required: true},
+		{Name: "days", Type: "integer", Description: "How many days back."},
+	}
+	if !reflect.DeepEqual(info.Parameters, want) {
+		t.Fatalf("parameters %+v, want %+v", info.Parameters, want)
+	}
+
+	if bare := toolInfo(protocol.ToolDescriptor{Name: "payram_ping"}); bare.Parameters == nil || len(bare.Parameters) != 0 {
+		t.Fatalf("parameters without a schema: %#v", bare.Parameters)
+	}
+}
+
+func TestToolCatalog(t *testing.T) {
+	mcp := newFakeMCP(t, tool("payram_daily_stats", "days"), tool(tools.DebugRequestTool, "path"), tool("payram_numbers_summary"))
+	list := func(mux http.Handler, header ...string) []string {
+		t.Helper()
+		var resp struct {
+			Object string     `json:"object"`
+			Data   []ToolInfo `json:"data"`
+		}
+		decodeJSON(t, serve(mux, http.MethodGet, toolsPath, nil, header...), &resp)
+		if resp.Object != "list" {
+			t.Fatalf("object %q", resp.Object)
+		}
+		var names []string
+		for _, d := range resp.Data {
+			names = append(names, d.Name)
+		}
+		return names
+	}
+
+	// Operators see every tool, in the order the MCP server lists them.
+	_, mux := newTestHandler(t, newFakeLLM(t, nil), mcp)
+	if got, want := list(mux), []string{"payram_daily_stats", tools.DebugRequestTool, "payram_numbers_summary"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("operator sees %v, want %v", got, want)
+	}
+
+	// Tenants are not offered the raw request tool.
+	store, _ := OpenSavedQueryStore("")
+	tenantMux := newTenantHandler(t, mcp, store)
+	if got, want := list(tenantMux, tenant.KeyHeader, "acme-key"), []string{"payram_daily_stats", "payram_numbers_summary"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("tenant sees %v, want %v", got, want)
+	}
+
+	if rec := serve(mux, http.MethodPost, toolsPath, nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST: %d", rec.Code)
+	}
+	mcp.Close()
+	if rec := serve(mux, http.MethodGet, toolsPath, nil); rec.Code != http.StatusBadGateway || errorCode(decodeError(t, rec)) != "tools_unavailable" {
+		t.Fatalf("with the MCP server down: %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,119 @@
+package chatapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+const (
+	// healthWindow is how many recent calls of each tool are kept, and healthMaxAge how
+	// long they count; a tool fixed since stops being flagged once its failures age out.
+	healthWindow = 20
+	healthMaxAge = time.Hour
+	// healthMinCalls is how many recent calls a tool needs before it is flagged.
+	healthMinCalls = 3
+	maxHealthError = 120
+)
+
+// toolHealth keeps the recent outcomes of each tool per tenant, so tool descriptions can
+// warn the model off tools that keep failing in a deployment.
+type toolHealth struct {
+	mu    sync.Mutex
+	tools map[string][]toolOutcome
+}
+
+type toolOutcome struct {
+	at  time.Time
+	err string
+}
+
+// toolFailed reports whether err says something about the tool, rather than about the
+// caller's arguments, credentials or quota, or about the MCP server being unreachable.
+func toolFailed(err error) bool {
+	var rpcErr *chatserver.RPCError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	switch rpcErr.Code {
+	case protocol.CodeInvalidArgs, protocol.CodeAuth, protocol.CodeQuotaExceeded:
+		return false
+	}
+	return true
+}
+
+// noteToolResult records the outcome of a tool call for the caller's tenant.
+func (h *Handler) noteToolResult(ctx context.Context, tool string, err error) {
+	if err != nil && !toolFailed(err) {
+		return
+	}
+	o := toolOutcome{at: time.Now()}
+	if err != nil {
+		o.err = err.Error()
+		if len(o.err) > maxHealthError {
+			o.err = o.err[:maxHealthError] + "..."
+		}
+	}
+	key := healthScope(ctx) + "\x00" + tool
+	t := &h.health
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tools == nil {
+		t.tools = map[string][]toolOutcome{}
+	}
+	outcomes := append(t.tools[key], o)
+	if len(outcomes) > healthWindow {
+		outcomes = outcomes[len(outcomes)-healthWindow:]
+	}
+	t.tools[key] = outcomes
+}
+
+// annotateToolHealth appends a reliability hint to the description of each tool that has
+// mostly failed recently for the caller's tenant.
+func (h *Handler) annotateToolHealth(ctx context.Context, tools []OATool) {
+	scope := healthScope(ctx)
+	t := &h.health
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range tools {
+		if hint := healthHint(t.tools[scope+"\x00"+tools[i].Function.Name]); hint != "" {
+			tools[i].Function.Description += "\n\n" + hint
+		}
+	}
+}
+
+// healthHint describes outcomes when at least half of the recent calls failed.
+func healthHint(outcomes []toolOutcome) string {
+	cutoff := time.Now().Add(-healthMaxAge)
+	calls, failed, lastErr := 0, 0, ""
+	for _, o := range outcomes {
+		if o.at.Before(cutoff) {
+			continue
+		}
+		calls++
+		if o.err != "" {
+			failed++
+			lastErr = o.err
+		}
+	}
+	switch {
+	case calls < healthMinCalls || failed*2 < calls:
+		return ""
+	case failed == calls:
+		return fmt.Sprintf("Unavailable in this deployment: the last %d calls failed (last error: %s). Do not call this tool; tell the user the data is unavailable or use another tool.", calls, lastErr)
+	}
+	return fmt.Sprintf("Unreliable in this deployment: %d of the last %d calls failed (last error: %s). Prefer another tool when one fits.", failed, calls, lastErr)
+}
+
+// healthScope separates tenants, whose PayRam deployments offer different analytics.
+func healthScope(ctx context.Context) string {
+	if id, _ := IdentityFrom(ctx); id.Tenant != nil {
+		return id.Tenant.ID
+	}
+	return ""
+}
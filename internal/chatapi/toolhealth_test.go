@@ -0,0 +1,125 @@
+package chatapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+func TestToolFailed(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&chatserver.RPCError{Code: protocol.CodeUpstreamUnavailable, Message: "core returned 503"}, true},
+		{&chatserver.RPCError{Code: -32603, Message: "internal"}, true},
+		{fmt.Errorf("call: %w", &chatserver.RPCError{Code: protocol.CodeUpstreamUnavailable}), true},
+		{&chatserver.RPCError{Code: protocol.CodeInvalidArgs, Message: "bad date"}, false},
+		{&chatserver.RPCError{Code: protocol.CodeAuth, Message: "bad token"}, false},
+		{&chatserver.RPCError{Code: protocol.CodeQuotaExceeded, Message: "quota"}, false},
+		{errors.New("dial tcp: connection refused"), false},
+	}
+	for _, c := range cases {
+		if got := toolFailed(c.err); got != c.want {
+			t.Errorf("toolFailed(%v) = %t, want %t", c.err, got, c.want)
+		}
+	}
+}
+
+// outcomes is calls recent tool calls of which the first failed ones failed with "boom".
+func outcomes(calls, failed int, at time.Time) []toolOutcome {
+	out := make([]toolOutcome, calls)
+	for i := range out {
+		out[i].at = at
+		if i < failed {
+			out[i].err = "boom"
+		}
+	}
+	return out
+}
+
+func TestHealthHint(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name     string
+		outcomes []toolOutcome
+		want     string
+	}{
+		{"no calls", nil, ""},
+		{"too few calls", outcomes(2, 2, now), ""},
+		{"mostly fine", outcomes(5, 2, now), ""},
+		{"half failed", outcomes(4, 2, now), "Unreliable in this deployment: 2 of the last 4 calls failed (last error: boom)."},
+		{"all failed", outcomes(3, 3, now), "Unavailable in this deployment: the last 3 calls failed (last error: boom). Do not call this tool"},
+		{"failures aged out", append(outcomes(3, 3, now.Add(-2*healthMaxAge)), outcomes(3, 0, now)...), ""},
+	}
+	for _, c := range cases {
+		got := healthHint(c.outcomes)
+		if (c.want == "") != (got == "") || !strings.HasPrefix(got, c.want) {
+			t.Errorf("%s: hint %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNoteToolResultKeepsARecentWindowPerTenant(t *testing.T) {
+	h := NewHandler(quietLogger(), "", "", "m", "http://127.0.0.1:1", "http://127.0.0.1:1")
+	acme := withIdentity(context.Background(), Identity{Tenant: &tenant.Tenant{ID: "acme"}})
+	down := &chatserver.RPCError{Code: protocol.CodeUpstreamUnavailable, Message: strings.Repeat("x", 2*maxHealthError)}
+
+	for range healthWindow + 5 {
+		h.noteToolResult(acme, "payram_refunds", down)
+	}
+	h.noteToolResult(acme, "payram_refunds", &chatserver.RPCError{Code: protocol.CodeInvalidArgs, Message: "bad date"})
+	h.noteToolResult(context.Background(), "payram_refunds", nil)
+
+	got := h.health.tools["acme\x00payram_refunds"]
+	if len(got) != healthWindow || len(got[0].err) != maxHealthError+3 {
+		t.Fatalf("kept %d outcomes, first error %d bytes", len(got), len(got[0].err))
+	}
+	if other := h.health.tools["\x00payram_refunds"]; len(other) != 1 || other[0].err != "" {
+		t.Fatalf("outcomes without a tenant %+v", other)
+	}
+
+	tools := []OATool{{Function: OAFunction{Name: "payram_refunds", Description: "Refunds."}}, {Function: OAFunction{Name: "payram_daily_stats", Description: "Daily."}}}
+	h.annotateToolHealth(acme, tools)
+	if !strings.HasPrefix(tools[0].Function.Description, "Refunds.\n\nUnavailable in this deployment: the last 20 calls failed") || tools[1].Function.Description != "Daily." {
+		t.Fatalf("annotated %+v", tools)
+	}
+	// Another tenant's failures do not mark the tool.
+	tools[0].Function.Description = "Refunds."
+	h.annotateToolHealth(context.Background(), tools)
+	if tools[0].Function.Description != "Refunds." {
+		t.Fatalf("annotated for another tenant: %q", tools[0].Function.Description)
+	}
+}
+
+func TestChatWarnsTheModelOffFailingTools(t *testing.T) {
+	llm := newFakeLLM(t, func(int, ChatCompletionRequest) (int, any) {
+		return http.StatusOK, toolCall("payram_refunds", `{}`)
+	})
+	mcp := newFakeMCP(t, tool("payram_refunds"))
+	mcp.call = func(string, map[string]any) (protocol.CallResult, *protocol.ResponseError) {
+		return protocol.CallResult{}, &protocol.ResponseError{Code: protocol.CodeUpstreamUnavailable, Message: "core returned 503"}
+	}
+	_, mux := newTestHandler(t, llm, mcp)
+
+	// Each chat fails on the tool; the one after the third failure is told about them.
+	for range healthMinCalls + 1 {
+		chat(mux, "refunds?")
+	}
+	calls := llm.calls()
+	desc := calls[len(calls)-1].Tools[0].Function.Description
+	if !strings.Contains(desc, "Unavailable in this deployment: the last 3 calls failed (last error: core returned 503)") {
+		t.Fatalf("description after %d failures: %q", healthMinCalls, desc)
+	}
+	if strings.Contains(calls[0].Tools[0].Function.Description, "deployment") {
+		t.Fatalf("first call already warned: %q", calls[0].Tools[0].Function.Description)
+	}
+}
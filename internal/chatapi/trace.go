@@ -0,0 +1,74 @@
+package chatapi
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxTraceOutput caps the tool output echoed back in a tool trace.
+const maxTraceOutput = 2000
+
+// ToolTrace records a single tool invocation made while answering a chat request.
+type ToolTrace struct {
+	Name       string         `json:"name"`
+	Arguments  map[string]any `json:"arguments"`
+	DurationMs int64          `json:"duration_ms"`
+	Output     string         `json:"output,omitempty"`
+	Truncated  bool           `json:"truncated,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// sensitiveArgs lists argument names (or fragments) whose values are never echoed in a trace.
+// base_url is among them: it names the deployment a caller's token is good for.
+var sensitiveArgs = []string{"token", "key", "secret", "password", "authorization", "base_url"}
+
+// newToolTrace builds a trace entry with redacted arguments and truncated output.
+func newToolTrace(name string, args map[string]any, dur time.Duration, output string, err error) ToolTrace {
+	t := ToolTrace{
+		Name:       name,
+		Arguments:  redactArgs(args),
+		DurationMs: dur.Milliseconds(),
+	}
+	if err != nil {
+		t.Error = err.Error()
+		return t
+	}
+	t.Output, t.Truncated = truncate(output, maxTraceOutput)
+	return t
+}
+
+// redactArgs copies args, replacing the values of sensitive keys.
+func redactArgs(args map[string]any) map[string]any {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if isSensitiveArg(k) {
+			out[k] = "[redacted]"
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func isSensitiveArg(name string) bool {
+	lower := strings.ToLower(name)
+	for _, s := range sensitiveArgs {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) (string, bool) {
+	if len(s) <= n {
+		return s, false
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "...", true
+}
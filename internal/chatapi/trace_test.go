@@ -0,0 +1,79 @@
+package chatapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRedactArgs(t *testing.T) {
+	args := map[string]any{
+		"token": "t", "Authorization": "Bearer x", "api_key": "k", "client_secret": "s",
+		"password": "p", "base_url": "https://core.internal", "date_filter": "last_7_days", "days": 7.0,
+	}
+	got := redactArgs(args)
+	for k, v := range got {
+		want := args[k]
+		if k != "date_filter" && k != "days" {
+			want = "[redacted]"
+		}
+		if v != want {
+			t.Errorf("%s = %v, want %v", k, v, want)
+		}
+	}
+	if args["token"] != "t" {
+		t.Fatal("redactArgs changed its input")
+	}
+}
+
+func TestTruncateKeepsRunesWhole(t *testing.T) {
+	s := strings.Repeat("é", 10) // 2 bytes each
+	for n := 0; n <= len(s); n++ {
+		got, cut := truncate(s, n)
+		if cut != (n < len(s)) {
+			t.Fatalf("n=%d: cut %v", n, cut)
+		}
+		if !utf8.ValidString(got) || len(strings.TrimSuffix(got, "...")) > n {
+			t.Fatalf("n=%d: %q", n, got)
+		}
+	}
+}
+
+func TestToolTraceNeverShowsCredentials(t *testing.T) {
+	const (
+		bearer  = "bearer-secret-1a2b"
+		argTok  = "arg-secret-3c4d"
+		baseURL = "https://core.secret-host.example"
+	)
+	llm := newFakeLLM(t, toolThenAnswer("payram_numbers_summary",
+		`{"date_filter":"last_7_days","base_url":"`+baseURL+`","api_key":"`+argTok+`"}`, "done"))
+	mcp := newFakeMCP(t, tool("payram_numbers_summary", "date_filter", "base_url", "token", "api_key"))
+	_, mux := newTestHandler(t, llm, mcp)
+
+	rec := serve(mux, http.MethodPost, "/v1/chat/completions", map[string]any{
+		"messages":           []OAChatMessage{{Role: "user", Content: "numbers?"}},
+		"include_tool_trace": true,
+	}, "Authorization", "Bearer "+bearer)
+	resp := decodeAnswer(t, rec)
+
+	// The tool itself gets the caller's token and arguments.
+	if calls := mcp.toolCalls(); len(calls) != 1 || calls[0].Args["token"] != bearer || calls[0].Args["base_url"] != baseURL {
+		t.Fatalf("tool calls %+v", calls)
+	}
+	if len(resp.ToolTrace) != 1 || resp.ToolTrace[0].Arguments["date_filter"] != "last_7_days" {
+		t.Fatalf("tool trace %+v", resp.ToolTrace)
+	}
+	for _, k := range []string{"token", "base_url", "api_key"} {
+		if v := resp.ToolTrace[0].Arguments[k]; v != "[redacted]" {
+			t.Errorf("trace argument %s = %v", k, v)
+		}
+	}
+	raw, _ := json.Marshal(resp)
+	for _, secret := range []string{bearer, argTok, baseURL} {
+		if strings.Contains(string(raw), secret) {
+			t.Errorf("response contains %q: %s", secret, raw)
+		}
+	}
+}
@@ -1,18 +1,30 @@
 package chatapi
 
+import "github.com/payram/payram-analytics-mcp-server/internal/protocol"
+
 // OpenAI-compatible request/response shapes (subset).
 
 type ChatCompletionRequest struct {
-	Model       string          `json:"model"`
-	Messages    []OAChatMessage `json:"messages"`
-	Tools       []OATool        `json:"tools,omitempty"`
-	ToolChoice  interface{}     `json:"tool_choice,omitempty"`
-	Temperature *float64        `json:"temperature,omitempty"`
+	Model      string          `json:"model"`
+	Messages   []OAChatMessage `json:"messages"`
+	Tools      []OATool        `json:"tools,omitempty"`
+	ToolChoice interface{}     `json:"tool_choice,omitempty"`
+	// ResponseFormat is set by the chat API for structured answers; clients cannot set it.
+	ResponseFormat interface{} `json:"response_format,omitempty"`
+	SamplingParams
+
+	// IncludeToolTrace asks the chat API to attach the tools it invoked to the response.
+	// It is never forwarded to OpenAI.
+	IncludeToolTrace bool `json:"include_tool_trace,omitempty"`
+	// Stream asks for the answer as server-sent chat.completion.chunk events. It is never
+	// forwarded to OpenAI.
+	Stream bool `json:"stream,omitempty"`
 }
 
 type OAChatMessage struct {
 	Role       string       `json:"role"`
 	Content    string       `json:"content,omitempty"`
+	Refusal    string       `json:"refusal,omitempty"`
 	Name       string       `json:"name,omitempty"`
 	ToolCallID string       `json:"tool_call_id,omitempty"`
 	ToolCalls  []OAToolCall `json:"tool_calls,omitempty"`
@@ -46,6 +58,23 @@ type ChatCompletionResponse struct {
 	Model   string                 `json:"model"`
 	Choices []ChatChoice           `json:"choices"`
 	Usage   map[string]interface{} `json:"usage,omitempty"`
+
+	// ToolTrace is set when the request had include_tool_trace.
+	ToolTrace []ToolTrace `json:"tool_trace,omitempty"`
+
+	// Charts carries structured chart data returned by the tools that were called.
+	Charts []protocol.ChartData `json:"charts,omitempty"`
+
+	// Provenance says which analytics graphs, date ranges and filters the answer's figures
+	// came from, per tool call.
+	Provenance []ToolProvenance `json:"provenance,omitempty"`
+
+	// Budget is the conversation's spend when conversation budgets are enabled.
+	Budget *BudgetStatus `json:"budget,omitempty"`
+
+	// PromptVariant names the system prompt variant that answered, when a prompt
+	// experiment is configured.
+	PromptVariant string `json:"prompt_variant,omitempty"`
 }
 
 type ChatChoice struct {
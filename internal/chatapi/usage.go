@@ -0,0 +1,72 @@
+package chatapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+const usagePath = "/v1/usage"
+
+// SetUsage enables LLM call quotas. Callers sending X-Tenant-Key are resolved against tenants
+// (which may be nil) and limited by their tenant's llm_calls_per_day; everyone else gets
+// defaultLLMPerDay (0 = unlimited), counted per signed-in user or as one shared "default" subject.
+func (h *Handler) SetUsage(usage *tenant.UsageTracker, tenants *tenant.Registry, defaultLLMPerDay int) {
+	h.usage = usage
+	h.tenants = tenants
+	h.llmQuota = defaultLLMPerDay
+}
+
+// quotaSubject names who a request's usage is counted against, and their limits.
+func (h *Handler) quotaSubject(ctx context.Context) (string, tenant.Quotas) {
+	id, _ := IdentityFrom(ctx)
+	switch {
+	case id.Tenant != nil:
+		return "tenant:" + id.Tenant.ID, id.Tenant.Quotas
+	case id.Username != "":
+		return "user:" + id.Username, tenant.Quotas{LLMCallsPerDay: h.llmQuota}
+	}
+	return "default", tenant.Quotas{LLMCallsPerDay: h.llmQuota}
+}
+
+// takeLLMCall counts one OpenAI call for the request's caller, refusing it with a
+// *tenant.QuotaError once their daily quota is used up.
+func (h *Handler) takeLLMCall(ctx context.Context) error {
+	if h.usage == nil {
+		return nil
+	}
+	subject, quotas := h.quotaSubject(ctx)
+	_, err := h.usage.Take(subject, tenant.KindLLMCalls, quotas.LLMCallsPerDay)
+	var qe *tenant.QuotaError
+	if errors.As(err, &qe) {
+		return err
+	}
+	if err != nil {
+		h.log(ctx).Warnf("record usage: %v", err)
+	}
+	return nil
+}
+
+// handleUsage reports the caller's LLM usage and quota for today: GET /v1/usage.
+func (h *Handler) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	r, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if h.usage == nil {
+		writeError(w, http.StatusNotFound, errTypeInvalidRequest, "not_found", "usage tracking is not enabled")
+		return
+	}
+	subject, quotas := h.quotaSubject(r.Context())
+	writeJSON(w, map[string]any{
+		"object":  "usage",
+		"subject": subject,
+		"usage":   map[string]tenant.Counter{tenant.KindLLMCalls: h.usage.Get(subject, quotas)[tenant.KindLLMCalls]},
+	}, http.StatusOK)
+}
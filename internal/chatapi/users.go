@@ -0,0 +1,194 @@
+package chatapi
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+)
+
+const (
+	passwordScheme     = "pbkdf2-sha256"
+	passwordIterations = 600_000
+	passwordKeyLen     = 32
+
+	sessionCookie     = "payram_chat_session"
+	defaultSessionTTL = 12 * time.Hour
+)
+
+// User is one entry of the users file. PasswordHash comes from HashPassword (chat-api --hash-password).
+// AnalyticsToken, when set, is used for the user's tool calls if the request carries no bearer token.
+// Language, when set, is the user's answer language unless the request sends X-Language.
+type User struct {
+	Username       string `json:"username"`
+	PasswordHash   string `json:"password_hash"`
+	AnalyticsToken string `json:"analytics_token,omitempty"`
+	Language       string `json:"language,omitempty"`
+}
+
+type usersFile struct {
+	Users []User `json:"users"`
+}
+
+// UserStore holds the static users allowed to sign in to the web UI, and their sessions.
+type UserStore struct {
+	users map[string]User
+	ttl   time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]session
+}
+
+type session struct {
+	username string
+	expires  time.Time
+}
+
+// LoadUsers reads a users file ({"users": [{"username", "password_hash", "analytics_token", "language"}]}).
+// Sessions live in memory for ttl (12h when zero); a restart signs everyone out.
+func LoadUsers(file string, ttl time.Duration) (*UserStore, error) {
+	var f usersFile
+	if err := readJSONFile(file, &f); err != nil {
+		return nil, err
+	}
+	if len(f.Users) == 0 {
+		return nil, fmt.Errorf("users file %s has no users", file)
+	}
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	s := &UserStore{users: map[string]User{}, ttl: ttl, sessions: map[string]session{}}
+	for i, u := range f.Users {
+		u.Username = strings.TrimSpace(u.Username)
+		if u.Username == "" {
+			return nil, fmt.Errorf("users[%d]: username is required", i)
+		}
+		if _, _, _, err := parsePasswordHash(u.PasswordHash); err != nil {
+			return nil, fmt.Errorf("users[%d] (%s): %w", i, u.Username, err)
+		}
+		if u.Language != "" {
+			lang, ok := i18n.Parse(u.Language)
+			if !ok {
+				return nil, fmt.Errorf("users[%d] (%s): invalid language %q", i, u.Username, u.Language)
+			}
+			u.Language = lang
+		}
+		if _, dup := s.users[u.Username]; dup {
+			return nil, fmt.Errorf("users[%d]: duplicate username %q", i, u.Username)
+		}
+		s.users[u.Username] = u
+	}
+	return s, nil
+}
+
+// HashPassword returns a salted PBKDF2 hash in the users file format.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, passwordKeyLen)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%s$%d$%s$%s", passwordScheme, passwordIterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+func parsePasswordHash(encoded string) (iter int, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != passwordScheme {
+		return 0, nil, nil, fmt.Errorf("password_hash must be %s$<iterations>$<salt>$<hash>", passwordScheme)
+	}
+	iter, err = strconv.Atoi(parts[1])
+	if err != nil || iter < 1 {
+		return 0, nil, nil, fmt.Errorf("password_hash: invalid iteration count")
+	}
+	enc := base64.RawStdEncoding
+	if salt, err = enc.DecodeString(parts[2]); err != nil {
+		return 0, nil, nil, fmt.Errorf("password_hash: invalid salt")
+	}
+	if key, err = enc.DecodeString(parts[3]); err != nil || len(key) == 0 {
+		return 0, nil, nil, fmt.Errorf("password_hash: invalid hash")
+	}
+	return iter, salt, key, nil
+}
+
+// verify checks a username and password. Unknown users cost the same as a wrong password.
+func (s *UserStore) verify(username, password string) (User, bool) {
+	u, known := s.users[username]
+	hash := u.PasswordHash
+	if !known {
+		hash = dummyPasswordHash()
+	}
+	iter, salt, want, err := parsePasswordHash(hash)
+	if err != nil {
+		return User{}, false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iter, len(want))
+	if err != nil {
+		return User{}, false
+	}
+	if subtle.ConstantTimeCompare(got, want) != 1 || !known {
+		return User{}, false
+	}
+	return u, true
+}
+
+var dummyPasswordHash = sync.OnceValue(func() string {
+	h, _ := HashPassword("")
+	return h
+})
+
+func (s *UserStore) user(username string) (User, bool) {
+	u, ok := s.users[username]
+	return u, ok
+}
+
+func (s *UserStore) newSession(username string) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	id := base64.RawURLEncoding.EncodeToString(buf)
+	expires := time.Now().Add(s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, v := range s.sessions {
+		if now.After(v.expires) {
+			delete(s.sessions, k)
+		}
+	}
+	s.sessions[id] = session{username: username, expires: expires}
+	return id, expires, nil
+}
+
+func (s *UserStore) session(id string) (User, bool) {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	if ok && time.Now().After(sess.expires) {
+		delete(s.sessions, id)
+		ok = false
+	}
+	s.mu.Unlock()
+	if !ok {
+		return User{}, false
+	}
+	return s.user(sess.username)
+}
+
+func (s *UserStore) endSession(id string) {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+}
@@ -0,0 +1,118 @@
+package chatapi
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// quickHash hashes password like HashPassword but with few iterations, to keep tests fast.
+func quickHash(t *testing.T, password string) string {
+	t.Helper()
+	salt := []byte("0123456789abcdef")
+	key, err := pbkdf2.Key(sha256.New, password, salt, 1000, passwordKeyLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%s$1000$%s$%s", passwordScheme, enc.EncodeToString(salt), enc.EncodeToString(key))
+}
+
+// writeUsers writes a users file holding users and returns its path.
+func writeUsers(t *testing.T, users ...User) string {
+	t.Helper()
+	raw, _ := json.Marshal(usersFile{Users: users})
+	file := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(file, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestHashPasswordRoundTrip(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter, salt, key, err := parsePasswordHash(hash)
+	if err != nil || iter != passwordIterations || len(salt) != 16 || len(key) != passwordKeyLen {
+		t.Fatalf("parse %q: %d %d %d %v", hash, iter, len(salt), len(key), err)
+	}
+	if again, _ := HashPassword("correct horse"); again == hash {
+		t.Fatal("two hashes share a salt")
+	}
+
+	users, err := LoadUsers(writeUsers(t, User{Username: " alice ", PasswordHash: hash}), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := users.verify("alice", "correct horse"); !ok || u.Username != "alice" {
+		t.Fatalf("right password rejected: %+v", u)
+	}
+	for _, c := range []struct{ user, password string }{
+		{"alice", "correct horse "},
+		{"alice", ""},
+		{"bob", "correct horse"},
+	} {
+		if _, ok := users.verify(c.user, c.password); ok {
+			t.Errorf("verify(%q, %q) succeeded", c.user, c.password)
+		}
+	}
+}
+
+func TestLoadUsersRejectsBadEntries(t *testing.T) {
+	good := quickHash(t, "pw")
+	for want, users := range map[string][]User{
+		"has no users":          nil,
+		"username is required":  {{Username: " ", PasswordHash: good}},
+		"password_hash must be": {{Username: "a", PasswordHash: "plain"}},
+		"invalid iteration":     {{Username: "a", PasswordHash: passwordScheme + "$0$c2FsdA$a2V5"}},
+		"invalid salt":          {{Username: "a", PasswordHash: passwordScheme + "$10$!!$a2V5"}},
+		"invalid language":      {{Username: "a", PasswordHash: good, Language: "klingon"}},
+	} {
+		if _, err := LoadUsers(writeUsers(t, users...), 0); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestSessions(t *testing.T) {
+	users, err := LoadUsers(writeUsers(t, User{Username: "alice", PasswordHash: quickHash(t, "pw"), Language: "es"}), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, expires, err := users.newSession("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(expires); d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("session expires in %s", d)
+	}
+	if u, ok := users.session(id); !ok || u.Username != "alice" || u.Language != "es" {
+		t.Fatalf("session(%q) = %+v, %t", id, u, ok)
+	}
+	if _, ok := users.session("forged"); ok {
+		t.Fatal("unknown session accepted")
+	}
+
+	users.sessions[id] = session{username: "alice", expires: time.Now().Add(-time.Second)}
+	if _, ok := users.session(id); ok {
+		t.Fatal("expired session accepted")
+	}
+	if _, ok := users.sessions[id]; ok {
+		t.Fatal("expired session kept")
+	}
+
+	id, _, _ = users.newSession("alice")
+	users.endSession(id)
+	if _, ok := users.session(id); ok {
+		t.Fatal("ended session accepted")
+	}
+}
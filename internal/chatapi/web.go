@@ -0,0 +1,36 @@
+package chatapi
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+const webPath = "/ui/"
+
+// chartJSOrigin hosts the Chart.js bundle the UI loads; the UI falls back to tables without it.
+const chartJSOrigin = "https://cdn.jsdelivr.net"
+
+// webHandler serves the embedded chat UI. The page holds no data and sends the same
+// credentials as any other client, so /v1/chat/completions enforces auth as usual.
+func webHandler() http.Handler {
+	sub, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix(webPath, http.FileServerFS(sub))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			methodNotAllowed(w, http.MethodGet, http.MethodHead)
+			return
+		}
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self' "+chartJSOrigin+"; frame-ancestors 'none'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("Cache-Control", "no-cache")
+		files.ServeHTTP(w, r)
+	})
+}
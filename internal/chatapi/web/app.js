@@ -0,0 +1,285 @@
+"use strict";
+
+// Minimal chat client for /v1/chat/completions. The conversation lives in the page. With
+// sign-in enabled the session cookie authenticates; otherwise the chat API key is kept in
+// sessionStorage. The optional analytics token and answer language are always kept there.
+// A WebSocket on /api/live feeds the payments ticker in the header. /v1/branding names the
+// assistant and gives its greeting.
+(function () {
+  const keyName = "payram-chat-key";
+  const tokenName = "payram-analytics-token";
+  const languageName = "payram-chat-language";
+  const $ = (id) => document.getElementById(id);
+  const history = [];
+  let signIn = false;
+  let live = null;
+  let liveRetry = null;
+  let liveGeneration = 0; // bumped by stopLive, so a connect awaiting its ticket gives up
+  let greeting = "";
+
+  function el(tag, text, cls) {
+    const e = document.createElement(tag);
+    if (text !== undefined && text !== null) e.textContent = String(text);
+    if (cls) e.className = cls;
+    return e;
+  }
+
+  function append(node) {
+    $("log").append(node);
+    node.scrollIntoView({ block: "end" });
+  }
+
+  function headers() {
+    const h = { "Content-Type": "application/json", "X-Requested-With": "payram-chat" };
+    const key = sessionStorage.getItem(keyName);
+    const token = sessionStorage.getItem(tokenName);
+    const language = sessionStorage.getItem(languageName);
+    if (key && !signIn) h["X-MCP-Key"] = key;
+    if (token) h["Authorization"] = "Bearer " + token;
+    if (language) h["X-Language"] = language;
+    return h;
+  }
+
+  const chartTypes = { timeseries: "line", pie: "pie", bar: "bar" };
+
+  function renderChart(c) {
+    const box = el("figure", null, "chart");
+    if (c.title) box.append(el("h3", c.title));
+    if (typeof Chart === "undefined") {
+      box.append(renderTable(c));
+    } else {
+      const canvas = el("canvas");
+      box.append(canvas);
+      new Chart(canvas, {
+        type: chartTypes[c.kind] || "bar",
+        data: {
+          labels: c.labels,
+          datasets: c.series.map((s) => ({ label: s.name, data: s.values })),
+        },
+        options: { responsive: true, maintainAspectRatio: false, animation: false },
+      });
+    }
+    append(box);
+  }
+
+  function renderTable(c) {
+    const table = el("table");
+    const head = el("tr");
+    head.append(el("th", ""));
+    for (const s of c.series) head.append(el("th", s.name));
+    table.append(head);
+    c.labels.forEach((label, i) => {
+      const tr = el("tr");
+      tr.append(el("td", label));
+      for (const s of c.series) tr.append(el("td", s.values[i]));
+      table.append(tr);
+    });
+    return table;
+  }
+
+  async function ask(question) {
+    history.push({ role: "user", content: question });
+    append(el("div", question, "msg user"));
+    $("send").disabled = true;
+    try {
+      const resp = await fetch("/v1/chat/completions", {
+        method: "POST",
+        headers: headers(),
+        body: JSON.stringify({ messages: history }),
+      });
+      let body = null;
+      try { body = await resp.json(); } catch (_) { /* non-JSON */ }
+      if (resp.status === 401 && signIn) {
+        showLogin("Session expired, please sign in again.");
+      }
+      if (!resp.ok) {
+        const msg = body && body.error ? body.error.message : "HTTP " + resp.status;
+        throw new Error(msg);
+      }
+      const content = (body.choices && body.choices[0] && body.choices[0].message.content) || "";
+      history.push({ role: "assistant", content });
+      append(el("div", content, "msg assistant"));
+      for (const c of body.charts || []) renderChart(c);
+    } catch (e) {
+      history.pop();
+      append(el("div", "Request failed: " + e.message, "msg error"));
+    } finally {
+      $("send").disabled = false;
+    }
+  }
+
+  function renderLive(m) {
+    const totals = Object.keys(m.totals).sort().map((c) => m.totals[c] + " " + c);
+    let text = "Last hour: " + m.payments + (m.payments === 1 ? " payment" : " payments");
+    if (totals.length) text += " · " + totals.join(", ");
+    const ticker = $("live");
+    ticker.textContent = text;
+    ticker.title = m.latest.length
+      ? m.latest.map((e) => [e.received_at, e.reference_id, e.payment_state, e.amount, e.currency].filter(Boolean).join(" ")).join("\n")
+      : "No payment webhooks in the last hour";
+    ticker.hidden = false;
+    ticker.classList.add("fresh");
+    setTimeout(() => ticker.classList.remove("fresh"), 2000);
+  }
+
+  // connectLive opens the live ticker socket, reconnecting after drops unless the server
+  // reports live events as unavailable. A WebSocket cannot carry the key or token headers,
+  // so those callers trade them for a one-time ticket first; web sessions send their cookie.
+  async function connectLive() {
+    stopLive();
+    const generation = liveGeneration;
+    const params = new URLSearchParams();
+    const key = sessionStorage.getItem(keyName);
+    const language = sessionStorage.getItem(languageName);
+    if ((key && !signIn) || sessionStorage.getItem(tokenName)) {
+      try {
+        const resp = await fetch("/api/live/ticket", { method: "POST", headers: headers() });
+        const body = resp.ok ? await resp.json() : null;
+        if (generation !== liveGeneration || !body) return;
+        params.set("ticket", body.ticket);
+      } catch (_) {
+        if (generation === liveGeneration) liveRetry = setTimeout(connectLive, 30000);
+        return;
+      }
+    }
+    if (language) params.set("language", language);
+    const scheme = location.protocol === "https:" ? "wss://" : "ws://";
+    const ws = new WebSocket(scheme + location.host + "/api/live?" + params);
+    let unavailable = false;
+    ws.onmessage = (ev) => {
+      let m = null;
+      try { m = JSON.parse(ev.data); } catch (_) { return; }
+      if (m.type === "metrics") renderLive(m);
+      else if (m.type === "unavailable") { unavailable = true; $("live").hidden = true; }
+    };
+    ws.onclose = () => {
+      if (live !== ws) return;
+      live = null;
+      if (!unavailable) liveRetry = setTimeout(connectLive, 30000);
+    };
+    live = ws;
+  }
+
+  function stopLive() {
+    liveGeneration++;
+    clearTimeout(liveRetry);
+    if (live) {
+      const ws = live;
+      live = null;
+      ws.close();
+    }
+    $("live").hidden = true;
+  }
+
+  function greet() {
+    if (greeting && !history.length) append(el("div", greeting, "msg assistant"));
+  }
+
+  async function loadBranding() {
+    try {
+      const resp = await fetch("/v1/branding");
+      const b = await resp.json();
+      if (b.assistant_name) {
+        $("title").textContent = b.assistant_name;
+        document.title = b.assistant_name;
+      }
+      greeting = b.greeting || "";
+    } catch (_) { /* keep the defaults */ }
+  }
+
+  function showChat(username) {
+    $("login").hidden = true;
+    $("log").hidden = false;
+    $("ask").hidden = false;
+    $("settings-toggle").hidden = false;
+    $("clear").hidden = false;
+    $("logout").hidden = !signIn;
+    $("api-key-field").hidden = signIn;
+    $("whoami").textContent = username ? "signed in as " + username : "";
+    if (!$("log").children.length) greet();
+    $("question").focus();
+    connectLive();
+  }
+
+  function showLogin(msg) {
+    stopLive();
+    $("login").hidden = false;
+    $("log").hidden = true;
+    $("ask").hidden = true;
+    $("settings").hidden = true;
+    $("settings-toggle").hidden = true;
+    $("clear").hidden = true;
+    $("logout").hidden = true;
+    $("whoami").textContent = "";
+    $("login-error").textContent = msg || "";
+    $("username").focus();
+  }
+
+  async function start() {
+    await loadBranding();
+    try {
+      const resp = await fetch("/auth/me", { headers: headers() });
+      const me = await resp.json();
+      signIn = !!me.sign_in;
+      if (signIn && !me.username) showLogin(); else showChat(me.username);
+    } catch (e) {
+      showChat();
+    }
+  }
+
+  $("login").addEventListener("submit", async (ev) => {
+    ev.preventDefault();
+    try {
+      const resp = await fetch("/auth/login", {
+        method: "POST",
+        headers: headers(),
+        body: JSON.stringify({ username: $("username").value.trim(), password: $("password").value }),
+      });
+      let body = null;
+      try { body = await resp.json(); } catch (_) { /* non-JSON */ }
+      if (!resp.ok) throw new Error(body && body.error ? body.error.message : "HTTP " + resp.status);
+      $("password").value = "";
+      showChat(body.username);
+    } catch (e) {
+      $("login-error").textContent = e.message;
+    }
+  });
+  $("logout").addEventListener("click", async () => {
+    await fetch("/auth/logout", { method: "POST", headers: headers() });
+    history.length = 0;
+    $("log").replaceChildren();
+    showLogin();
+  });
+  $("ask").addEventListener("submit", (ev) => {
+    ev.preventDefault();
+    const q = $("question").value.trim();
+    if (!q) return;
+    $("question").value = "";
+    ask(q);
+  });
+  $("question").addEventListener("keydown", (ev) => {
+    if (ev.key === "Enter" && !ev.shiftKey) {
+      ev.preventDefault();
+      $("ask").requestSubmit();
+    }
+  });
+  $("settings-toggle").addEventListener("click", () => { $("settings").hidden = !$("settings").hidden; });
+  $("settings").addEventListener("submit", (ev) => {
+    ev.preventDefault();
+    sessionStorage.setItem(keyName, $("api-key").value.trim());
+    sessionStorage.setItem(tokenName, $("analytics-token").value.trim());
+    sessionStorage.setItem(languageName, $("language").value.trim());
+    $("settings").hidden = true;
+    connectLive();
+  });
+  $("clear").addEventListener("click", () => {
+    history.length = 0;
+    $("log").replaceChildren();
+    greet();
+  });
+
+  $("api-key").value = sessionStorage.getItem(keyName) || "";
+  $("analytics-token").value = sessionStorage.getItem(tokenName) || "";
+  $("language").value = sessionStorage.getItem(languageName) || "";
+  start();
+})();
@@ -0,0 +1,284 @@
+package chatapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pipeWS is a server wsConn on one end of an in-memory connection and the client's end.
+func pipeWS(t *testing.T) (*wsConn, net.Conn) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	return &wsConn{conn: server, br: bufio.NewReader(server)}, client
+}
+
+// Length encodings for clientFrame.
+const (
+	len7 = iota
+	len16
+	len64
+)
+
+// clientFrame encodes a final client frame. masked=false leaves out the mask, which servers
+// must refuse; first is the first header byte when not zero.
+func clientFrame(op byte, payload []byte, masked bool, lenForm int, first byte) []byte {
+	if first == 0 {
+		first = 0x80 | op
+	}
+	b := []byte{first, 0}
+	switch lenForm {
+	case len7:
+		b[1] = byte(len(payload))
+	case len16:
+		b[1] = 126
+		b = binary.BigEndian.AppendUint16(b, uint16(len(payload)))
+	case len64:
+		b[1] = 127
+		b = binary.BigEndian.AppendUint64(b, uint64(len(payload)))
+	}
+	if !masked {
+		return append(b, payload...)
+	}
+	b[1] |= 0x80
+	mask := [4]byte{0x37, 0xfa, 0x21, 0x3d}
+	b = append(b, mask[:]...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+// readServerFrame decodes one server frame from r, checking it is final and unmasked.
+func readServerFrame(t *testing.T, r io.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatalf("read frame header: %v", err)
+	}
+	if head[0]&0x80 == 0 || head[1]&0x80 != 0 {
+		t.Fatalf("frame header %08b %08b: want FIN set and no mask", head[0], head[1])
+	}
+	n := uint64(head[1])
+	switch n {
+	case 126:
+		var ext [2]byte
+		_, _ = io.ReadFull(r, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, _ = io.ReadFull(r, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("read %d byte payload: %v", n, err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+func TestReadFrame(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), 300)
+	cases := []struct {
+		name    string
+		frame   []byte
+		op      byte
+		payload []byte
+		err     string
+	}{
+		{"masked text", clientFrame(wsOpText, []byte("hello"), true, len7, 0), wsOpText, []byte("hello"), ""},
+		{"empty ping", clientFrame(wsOpPing, nil, true, len7, 0), wsOpPing, []byte{}, ""},
+		{"16-bit length", clientFrame(wsOpBinary, long, true, len16, 0), wsOpBinary, long, ""},
+		{"64-bit length", clientFrame(wsOpText, long, true, len64, 0), wsOpText, long, ""},
+		{"largest allowed", clientFrame(wsOpText, make([]byte, wsMaxRead), true, len16, 0), wsOpText, make([]byte, wsMaxRead), ""},
+		{"unmasked", clientFrame(wsOpText, []byte("hi"), false, len7, 0), 0, nil, "not masked"},
+		{"reserved bits", clientFrame(wsOpText, []byte("hi"), true, len7, 0x80|0x40|wsOpText), 0, nil, "reserved bits"},
+		{"unknown opcode", clientFrame(0x3, []byte("hi"), true, len7, 0), 0, nil, "unknown opcode 3"},
+		{"too big", clientFrame(wsOpText, make([]byte, wsMaxRead+1), true, len16, 0), 0, nil, errFrameTooBig.Error()},
+		{"truncated payload", clientFrame(wsOpText, []byte("hello"), true, len7, 0)[:8], 0, nil, "unexpected EOF"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ws, client := pipeWS(t)
+			go func() {
+				_, _ = client.Write(c.frame)
+				client.Close()
+			}()
+			op, payload, err := ws.readFrame()
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("err = %v, want %q", err, c.err)
+				}
+				return
+			}
+			if err != nil || op != c.op || !bytes.Equal(payload, c.payload) {
+				t.Fatalf("got op %d, %d bytes, %v", op, len(payload), err)
+			}
+		})
+	}
+}
+
+func TestWriteTextLengths(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 0xFFFF, 0x10000} {
+		ws, client := pipeWS(t)
+		data := bytes.Repeat([]byte("x"), n)
+		errc := make(chan error, 1)
+		go func() { errc <- ws.WriteText(data) }()
+
+		var head [2]byte
+		if _, err := io.ReadFull(client, head[:]); err != nil {
+			t.Fatal(err)
+		}
+		want := byte(n)
+		switch {
+		case n > 0xFFFF:
+			want = 127
+		case n > 125:
+			want = 126
+		}
+		if head[1] != want {
+			t.Errorf("%d bytes: length byte %d, want %d", n, head[1], want)
+		}
+		op, payload := readServerFrame(t, io.MultiReader(bytes.NewReader(head[:]), client))
+		if op != wsOpText || !bytes.Equal(payload, data) {
+			t.Errorf("%d bytes: got op %d with %d bytes", n, op, len(payload))
+		}
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadLoopControlFrames(t *testing.T) {
+	ws, client := pipeWS(t)
+	done := make(chan error, 1)
+	go func() { done <- ws.readLoop() }()
+
+	// Data frames are ignored, pings are answered with the same payload, and a close is
+	// echoed before the loop ends.
+	_, _ = client.Write(clientFrame(wsOpText, []byte("ignored"), true, len7, 0))
+	_, _ = client.Write(clientFrame(wsOpPing, []byte("are you there"), true, len7, 0))
+	if op, payload := readServerFrame(t, client); op != wsOpPong || string(payload) != "are you there" {
+		t.Fatalf("got op %d %q, want a pong", op, payload)
+	}
+	_, _ = client.Write(clientFrame(wsOpClose, []byte{0x03, 0xE8}, true, len7, 0))
+	if op, payload := readServerFrame(t, client); op != wsOpClose || binary.BigEndian.Uint16(payload) != wsCloseNormal {
+		t.Fatalf("got op %d %v, want close 1000", op, payload)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("readLoop: %v", err)
+	}
+}
+
+func TestReadLoopClosesOnBadFrames(t *testing.T) {
+	cases := []struct {
+		name  string
+		frame []byte
+		code  uint16
+	}{
+		{"oversize", clientFrame(wsOpText, make([]byte, wsMaxRead+1), true, len64, 0), wsCloseTooBig},
+		{"unmasked", clientFrame(wsOpText, []byte("hi"), false, len7, 0), wsCloseProtocol},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ws, client := pipeWS(t)
+			done := make(chan error, 1)
+			go func() { done <- ws.readLoop() }()
+			go func() { _, _ = client.Write(c.frame) }()
+			if op, payload := readServerFrame(t, client); op != wsOpClose || binary.BigEndian.Uint16(payload) != c.code {
+				t.Fatalf("got op %d %v, want close %d", op, payload, c.code)
+			}
+			if err := <-done; err == nil {
+				t.Fatal("readLoop returned no error")
+			}
+		})
+	}
+
+	// A client that just goes away ends the loop without a close frame.
+	ws, client := pipeWS(t)
+	client.Close()
+	if err := ws.readLoop(); !errors.Is(err, io.EOF) {
+		t.Fatalf("readLoop after hang-up: %v", err)
+	}
+}
+
+func TestUpgradeWebSocket(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		_ = ws.WriteText([]byte("hi"))
+		_ = ws.Close(wsCloseNormal)
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	// The sample handshake from RFC 6455, section 1.3.
+	_, _ = io.WriteString(conn, "GET /live HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake %d %v", resp.StatusCode, resp.Header)
+	}
+	if op, payload := readServerFrame(t, br); op != wsOpText || string(payload) != "hi" {
+		t.Fatalf("got op %d %q", op, payload)
+	}
+	if op, _ := readServerFrame(t, br); op != wsOpClose {
+		t.Fatalf("got op %d, want close", op)
+	}
+}
+
+func TestUpgradeWebSocketRejects(t *testing.T) {
+	upgrade := []string{"Connection", "Upgrade", "Upgrade", "websocket", "Sec-WebSocket-Version", "13", "Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ=="}
+	cases := []struct {
+		name   string
+		method string
+		header []string
+		status int
+		code   string
+	}{
+		{"plain request", http.MethodGet, nil, http.StatusBadRequest, "websocket_required"},
+		{"post", http.MethodPost, upgrade, http.StatusBadRequest, "websocket_required"},
+		{"old version", http.MethodGet, append(upgrade[:4:4], "Sec-WebSocket-Version", "8", "Sec-WebSocket-Key", "x"), http.StatusUpgradeRequired, "websocket_version"},
+		{"no key", http.MethodGet, upgrade[:6], http.StatusBadRequest, "websocket_key"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/live", nil)
+		for i := 0; i+1 < len(c.header); i += 2 {
+			req.Header.Set(c.header[i], c.header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		if _, err := upgradeWebSocket(rec, req); err == nil {
+			t.Fatalf("%s: upgraded", c.name)
+		}
+		if rec.Code != c.status || errorCode(decodeError(t, rec)) != c.code {
+			t.Errorf("%s: got %d %s", c.name, rec.Code, rec.Body)
+		}
+		if c.status == http.StatusUpgradeRequired && rec.Header().Get("Sec-WebSocket-Version") != "13" {
+			t.Errorf("%s: no supported version advertised", c.name)
+		}
+	}
+}
@@ -7,18 +7,56 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/deadline"
+	"github.com/payram/payram-analytics-mcp-server/internal/events"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/recording"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
 )
 
+// RPCError is a JSON-RPC error returned by the MCP server.
+type RPCError struct {
+	Code    int
+	Message string
+	Data    any
+}
+
+func (e *RPCError) Error() string { return e.Message }
+
 // MCPClient issues JSON-RPC calls to the existing MCP server over HTTP.
 type MCPClient struct {
 	baseURL    string
 	httpClient *http.Client
 	counter    uint64
+	tenantKey  string
+}
+
+type tenantCtxKey struct{}
+
+type tenantHeaders struct{ id, key string }
+
+// WithTenant returns ctx carrying the tenant ID and key to send with MCP calls made under it.
+func WithTenant(ctx context.Context, id, key string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenantHeaders{id: strings.TrimSpace(id), key: strings.TrimSpace(key)})
+}
+
+type recordCtxKey struct{}
+
+// WithRecording returns ctx under which MCP calls ask the server to return the upstream HTTP
+// exchanges each tool made (see recording.Header).
+func WithRecording(ctx context.Context) context.Context {
+	return context.WithValue(ctx, recordCtxKey{}, true)
+}
+
+// SetTenantKey sets the tenant key sent when the context carries none.
+func (c *MCPClient) SetTenantKey(key string) {
+	c.tenantKey = strings.TrimSpace(key)
 }
 
 // NewMCPClient builds a client with a sane timeout.
@@ -59,6 +97,7 @@ func (c *MCPClient) do(ctx context.Context, method string, params any) (protocol
 		return resp, fmt.Errorf("build http request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	c.setHeaders(ctx, httpReq)
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -67,6 +106,9 @@ func (c *MCPClient) do(ctx context.Context, method string, params any) (protocol
 	defer httpResp.Body.Close()
 
 	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		if json.NewDecoder(httpResp.Body).Decode(&resp) == nil && resp.Error != nil {
+			return resp, &RPCError{Code: resp.Error.Code, Message: resp.Error.Message, Data: resp.Error.Data}
+		}
 		return resp, fmt.Errorf("mcp server returned status %d", httpResp.StatusCode)
 	}
 
@@ -75,12 +117,69 @@ func (c *MCPClient) do(ctx context.Context, method string, params any) (protocol
 	}
 
 	if resp.Error != nil {
-		return resp, errors.New(resp.Error.Message)
+		return resp, &RPCError{Code: resp.Error.Code, Message: resp.Error.Message, Data: resp.Error.Data}
 	}
 
 	return resp, nil
 }
 
+// setHeaders adds the tenant, recording, language and deadline headers ctx asks for.
+func (c *MCPClient) setHeaders(ctx context.Context, httpReq *http.Request) {
+	th, _ := ctx.Value(tenantCtxKey{}).(tenantHeaders)
+	if th.key == "" {
+		th.key = c.tenantKey
+	}
+	if th.id != "" {
+		httpReq.Header.Set(tenant.IDHeader, th.id)
+	}
+	if th.key != "" {
+		httpReq.Header.Set(tenant.KeyHeader, th.key)
+	}
+	if ctx.Value(recordCtxKey{}) != nil {
+		httpReq.Header.Set(recording.Header, "1")
+	}
+	if lang := i18n.FromContext(ctx); lang != "" {
+		httpReq.Header.Set(i18n.Header, lang)
+	}
+	deadline.Set(ctx, httpReq)
+}
+
+// ErrEventsDisabled is returned by RecentEvents when the MCP server accepts no webhooks.
+var ErrEventsDisabled = errors.New("payment webhooks are not enabled on the MCP server")
+
+// RecentEvents fetches the payment webhooks the MCP server received in the last minutes,
+// newest first.
+func (c *MCPClient) RecentEvents(ctx context.Context, minutes int) ([]events.Event, error) {
+	u := c.baseURL + "events?minutes=" + strconv.Itoa(minutes)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build http request: %w", err)
+	}
+	c.setHeaders(ctx, httpReq)
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("call mcp server: %w", err)
+	}
+	defer httpResp.Body.Close()
+	var body struct {
+		Events []events.Event `json:"events"`
+		Error  string         `json:"error"`
+	}
+	decodeErr := json.NewDecoder(httpResp.Body).Decode(&body)
+	switch {
+	case httpResp.StatusCode == http.StatusNotFound:
+		return nil, ErrEventsDisabled
+	case httpResp.StatusCode < 200 || httpResp.StatusCode >= 300:
+		if body.Error != "" {
+			return nil, fmt.Errorf("mcp server returned status %d: %s", httpResp.StatusCode, body.Error)
+		}
+		return nil, fmt.Errorf("mcp server returned status %d", httpResp.StatusCode)
+	case decodeErr != nil:
+		return nil, fmt.Errorf("decode events: %w", decodeErr)
+	}
+	return body.Events, nil
+}
+
 // ListTools fetches the advertised tools from the MCP server.
 func (c *MCPClient) ListTools(ctx context.Context) ([]protocol.ToolDescriptor, error) {
 	resp, err := c.do(ctx, "tools/list", map[string]any{})
@@ -0,0 +1,132 @@
+package cliconfig
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoadRoundTripsPrivately(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payram", "cli.json")
+	if c, err := Load(path); err != nil || c != (Config{}) {
+		t.Fatalf("Load of missing file = %+v, %v; want empty config", c, err)
+	}
+
+	want := Config{
+		MCP:   MCP{URL: "http://mcp:3333/", Token: "tok", TenantID: "acme", TenantKey: "k"},
+		Agent: Agent{URL: "http://agent:9900", AdminToken: "adm", Channel: "beta"},
+	}
+	if err := Save(path, want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("config mode = %o, want 600", perm)
+	}
+	got, err := Load(path)
+	if err != nil || got != want {
+		t.Fatalf("Load = %+v, %v; want %+v", got, err, want)
+	}
+}
+
+func TestRunWizardChecksBothServers(t *testing.T) {
+	mcp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Tenant-ID") != "acme" {
+			t.Errorf("tenant header = %q", r.Header.Get("X-Tenant-ID"))
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"a"},{"name":"b"}]}}`))
+	}))
+	defer mcp.Close()
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/update/status" || r.Header.Get("X-MCP-Key") != "adm" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ok":false,"error":{"code":"UNAUTHORIZED","message":"bad key"}}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"data":{"current_version":"1.4.0"}}`))
+	}))
+	defer agent.Close()
+
+	// Blank answers keep the current value, "-" clears one, and a bad URL is asked again.
+	answers := strings.Join([]string{mcp.URL, "", "acme", "k", "not a url", agent.URL, "adm", "-"}, "\n") + "\n"
+	var out strings.Builder
+	got, err := RunWizard(context.Background(), strings.NewReader(answers), &out, Config{MCP: MCP{Token: "tok"}})
+	if err != nil {
+		t.Fatalf("RunWizard: %v\n%s", err, out.String())
+	}
+	want := Config{
+		MCP:   MCP{URL: mcp.URL, Token: "tok", TenantID: "acme", TenantKey: "k"},
+		Agent: Agent{URL: agent.URL, AdminToken: "adm"},
+	}
+	if got != want {
+		t.Fatalf("config = %+v, want %+v", got, want)
+	}
+	for _, s := range []string{"ok, 2 tools", "ok, release 1.4.0", "enter an absolute URL"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("output lacks %q:\n%s", s, out.String())
+		}
+	}
+}
+
+func TestRunWizardAbortsOnFailedCheck(t *testing.T) {
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"ok":false,"error":{"code":"UNAUTHORIZED","message":"bad key"}}`))
+	}))
+	defer agent.Close()
+	mcp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`))
+	}))
+	defer mcp.Close()
+
+	answers := strings.Join([]string{mcp.URL, "", "", agent.URL, "wrong", "", "n"}, "\n") + "\n"
+	var out strings.Builder
+	if _, err := RunWizard(context.Background(), strings.NewReader(answers), &out, Config{}); !errors.Is(err, ErrAborted) {
+		t.Fatalf("RunWizard err = %v, want ErrAborted\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "failed: UNAUTHORIZED: bad key") {
+		t.Fatalf("output lacks the agent error:\n%s", out.String())
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	fs := flag.NewFlagSet("x", flag.ContinueOnError)
+	fs.String("url", "", "")
+	fs.Bool("json", false, "")
+	commands := []Command{
+		{Name: "logs", Flags: []string{"--tail"}, Args: []string{"chat", "mcp"}},
+		{Name: "update apply", Flags: []string{"--dry-run"}},
+		{Name: "call", ArgsFrom: "x tools", FlagsFrom: `x describe "$arg"`},
+	}
+	script, err := Completion("bash", "x-ctl", fs, commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`"") opts="--json --url logs update call" ;;`,
+		`"update") opts="apply" ;;`,
+		`"logs"|"logs "*) opts="--tail chat mcp" ;;`,
+		`"call") opts="$({ x tools; } 2>/dev/null)" ;;`,
+		`--url) ((i++)) ;;`,
+		"complete -F _x_ctl x-ctl",
+	} {
+		if !strings.Contains(script, s) {
+			t.Errorf("script lacks %q:\n%s", s, script)
+		}
+	}
+	if zsh, err := Completion("zsh", "x-ctl", fs, commands); err != nil || !strings.Contains(zsh, "bashcompinit") {
+		t.Errorf("zsh script = %q, %v", zsh, err)
+	}
+	if _, err := Completion("fish", "x-ctl", fs, commands); err == nil {
+		t.Error("fish accepted")
+	}
+}
@@ -0,0 +1,101 @@
+package cliconfig
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Command describes one command of a CLI for shell completion.
+type Command struct {
+	// Name is the command as typed, one or two words such as "update apply".
+	Name string
+	// Flags are the command's own flags, with their leading dashes.
+	Flags []string
+	// Args are the values of the command's first positional argument.
+	Args []string
+	// ArgsFrom is a shell command printing the first positional values, one per line, for
+	// values only the server knows.
+	ArgsFrom string
+	// FlagsFrom is a shell command printing flags, one per line, once the first positional
+	// argument is typed; it can refer to that argument as $arg.
+	FlagsFrom string
+}
+
+// Completion returns a script for shell ("bash" or "zsh") that completes prog's global flags,
+// taken from globals, its commands, and each command's flags and arguments.
+func Completion(shell, prog string, globals *flag.FlagSet, commands []Command) (string, error) {
+	var b strings.Builder
+	switch shell {
+	case "bash":
+		fmt.Fprintf(&b, "# bash completion for %s. Load it with: source <(%s completion bash)\n", prog, prog)
+	case "zsh":
+		fmt.Fprintf(&b, "# zsh completion for %s. Load it with: source <(%s completion zsh)\n", prog, prog)
+		b.WriteString("autoload -U +X bashcompinit && bashcompinit\n")
+	default:
+		return "", fmt.Errorf("unsupported shell %q; use bash or zsh", shell)
+	}
+
+	var globalFlags, valueFlags []string
+	globals.VisitAll(func(f *flag.Flag) {
+		globalFlags = append(globalFlags, "--"+f.Name)
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !bf.IsBoolFlag() {
+			valueFlags = append(valueFlags, "--"+f.Name)
+		}
+	})
+	fn := "_" + strings.ReplaceAll(prog, "-", "_")
+
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" words=\"\" opts=\"\" arg i\n")
+	b.WriteString("\tCOMPREPLY=()\n")
+	if len(valueFlags) > 0 {
+		// Words after a global flag that takes a value are not part of the command.
+		fmt.Fprintf(&b, "\tcase \"${COMP_WORDS[COMP_CWORD-1]}\" in\n\t%s) return ;;\n\tesac\n", strings.Join(valueFlags, "|"))
+	}
+	b.WriteString("\tfor ((i = 1; i < COMP_CWORD; i++)); do\n\t\tcase \"${COMP_WORDS[i]}\" in\n")
+	if len(valueFlags) > 0 {
+		fmt.Fprintf(&b, "\t\t%s) ((i++)) ;;\n", strings.Join(valueFlags, "|"))
+	}
+	b.WriteString("\t\t-*) ;;\n\t\t*) words=\"${words:+$words }${COMP_WORDS[i]}\" ;;\n\t\tesac\n\tdone\n")
+
+	var first []string
+	groups := map[string][]string{}
+	for _, c := range commands {
+		word, sub, _ := strings.Cut(c.Name, " ")
+		if !slices.Contains(first, word) {
+			first = append(first, word)
+		}
+		if sub != "" {
+			groups[word] = append(groups[word], sub)
+		}
+	}
+	b.WriteString("\tcase \"$words\" in\n")
+	fmt.Fprintf(&b, "\t\"\") opts=%q ;;\n", strings.Join(append(globalFlags, first...), " "))
+	for _, word := range first {
+		if subs, ok := groups[word]; ok {
+			fmt.Fprintf(&b, "\t%q) opts=%q ;;\n", word, strings.Join(subs, " "))
+		}
+	}
+	for _, c := range commands {
+		flags := strings.Join(c.Flags, " ")
+		args := strings.Join(c.Args, " ")
+		if c.ArgsFrom != "" {
+			args = "$({ " + c.ArgsFrom + "; } 2>/dev/null)"
+		}
+		if c.FlagsFrom == "" {
+			if opts := strings.TrimSpace(flags + " " + args); opts != "" {
+				fmt.Fprintf(&b, "\t%q|%q*) opts=\"%s\" ;;\n", c.Name, c.Name+" ", opts)
+			}
+			continue
+		}
+		// The flags depend on the first argument, so they are offered only after it.
+		fmt.Fprintf(&b, "\t%q) opts=\"%s\" ;;\n", c.Name, args)
+		fmt.Fprintf(&b, "\t%q*)\n\t\targ=\"${words#%s }\"\n\t\targ=\"${arg%%%% *}\"\n\t\topts=\"%s\"\n\t\t;;\n",
+			c.Name+" ", c.Name, strings.TrimSpace(flags+" $({ "+c.FlagsFrom+"; } 2>/dev/null)"))
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tCOMPREPLY=($(compgen -W \"$opts\" -- \"$cur\"))\n}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, prog)
+	return b.String(), nil
+}
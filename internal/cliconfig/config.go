@@ -0,0 +1,84 @@
+// Package cliconfig is the settings file shared by payramctl and agentctl, the init wizard
+// that writes it, and the shell completion scripts for both commands.
+package cliconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PathEnv overrides where the config file lives.
+const PathEnv = "PAYRAM_CLI_CONFIG"
+
+// Config is what the CLIs remember between runs, so URLs and tokens need not be typed or kept
+// in the shell environment. Flags and environment variables take precedence over it.
+type Config struct {
+	MCP   MCP   `json:"mcp"`
+	Agent Agent `json:"agent"`
+}
+
+// MCP is how payramctl reaches the MCP server.
+type MCP struct {
+	URL       string `json:"url,omitempty"`
+	Token     string `json:"token,omitempty"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	TenantKey string `json:"tenant_key,omitempty"`
+}
+
+// Agent is how agentctl reaches the agent admin API.
+type Agent struct {
+	URL        string `json:"url,omitempty"`
+	AdminToken string `json:"admin_token,omitempty"`
+	Channel    string `json:"channel,omitempty"`
+}
+
+// Path is $PAYRAM_CLI_CONFIG, else payram/cli.json in the user's config directory.
+func Path() (string, error) {
+	if v := os.Getenv(PathEnv); v != "" {
+		return v, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "payram", "cli.json"), nil
+}
+
+// Load reads the config file; a missing file is an empty config.
+func Load(path string) (Config, error) {
+	var c Config
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("read config: %w", err)
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes c readable only by the user, replacing the file atomically.
+func Save(path string, c Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create config directory: %w", err)
+	}
+	raw, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(raw, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package cliconfig
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/chatserver"
+)
+
+// Defaults used when neither a flag, the environment nor the config file sets a value.
+const (
+	DefaultMCPURL   = "http://localhost:3333/"
+	DefaultAgentURL = "http://localhost:9900"
+	DefaultChannel  = "stable"
+)
+
+// ErrAborted is returned by RunWizard when the user declines to save a config that failed
+// its connectivity checks.
+var ErrAborted = errors.New("init aborted; nothing was saved")
+
+const checkTimeout = 10 * time.Second
+
+// RunWizard asks for each setting on out, reading answers from in, with cur's values as the
+// defaults. It then checks that the MCP server and the agent answer with those settings, and
+// returns the new config for the caller to save.
+func RunWizard(ctx context.Context, in io.Reader, out io.Writer, cur Config) (Config, error) {
+	p := prompter{in: bufio.NewReader(in), out: out}
+	fmt.Fprintln(out, "Press Enter to keep the value in brackets, or type - to clear it. Tokens are shown as you type.")
+	fmt.Fprintln(out)
+
+	c := cur
+	var err error
+	if c.MCP.URL, err = p.url("MCP server URL", firstSet(cur.MCP.URL, DefaultMCPURL)); err != nil {
+		return cur, err
+	}
+	if c.MCP.Token, err = p.secret("PayRam analytics token", cur.MCP.Token); err != nil {
+		return cur, err
+	}
+	if c.MCP.TenantID, err = p.text("Tenant ID (blank for a single-tenant server)", cur.MCP.TenantID); err != nil {
+		return cur, err
+	}
+	c.MCP.TenantKey = ""
+	if c.MCP.TenantID != "" {
+		if c.MCP.TenantKey, err = p.secret("Tenant key", cur.MCP.TenantKey); err != nil {
+			return cur, err
+		}
+	}
+	if c.Agent.URL, err = p.url("Agent URL", firstSet(cur.Agent.URL, DefaultAgentURL)); err != nil {
+		return cur, err
+	}
+	if c.Agent.AdminToken, err = p.secret("Agent admin token", cur.Agent.AdminToken); err != nil {
+		return cur, err
+	}
+	if c.Agent.Channel, err = p.text("Update channel", firstSet(cur.Agent.Channel, DefaultChannel)); err != nil {
+		return cur, err
+	}
+
+	fmt.Fprintln(out)
+	ok := report(out, "MCP server", c.MCP.URL, func() (string, error) {
+		n, err := CheckMCP(ctx, c.MCP)
+		return fmt.Sprintf("%d tools", n), err
+	})
+	if c.Agent.AdminToken == "" {
+		fmt.Fprintf(out, "Checking agent at %s... skipped, no admin token\n", c.Agent.URL)
+	} else {
+		ok = report(out, "agent", c.Agent.URL, func() (string, error) {
+			v, err := CheckAgent(ctx, c.Agent)
+			if v == "" {
+				return "no release installed", err
+			}
+			return "release " + v, err
+		}) && ok
+	}
+	if !ok {
+		save, err := p.text("Save anyway? [y/N]", "")
+		if err != nil {
+			return cur, err
+		}
+		if s := strings.ToLower(save); s != "y" && s != "yes" {
+			return cur, ErrAborted
+		}
+	}
+	return c, nil
+}
+
+func report(out io.Writer, what, target string, check func() (string, error)) bool {
+	fmt.Fprintf(out, "Checking %s at %s... ", what, target)
+	detail, err := check()
+	if err != nil {
+		fmt.Fprintf(out, "failed: %v\n", err)
+		return false
+	}
+	fmt.Fprintf(out, "ok, %s\n", detail)
+	return true
+}
+
+// CheckMCP lists the MCP server's tools with m's tenant headers and returns how many it has.
+func CheckMCP(ctx context.Context, m MCP) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	client := chatserver.NewMCPClient(m.URL)
+	tools, err := client.ListTools(chatserver.WithTenant(ctx, m.TenantID, m.TenantKey))
+	if err != nil {
+		return 0, err
+	}
+	return len(tools), nil
+}
+
+// CheckAgent reads the agent's update status with a's admin token, which proves both that the
+// agent answers and that the token is accepted, and returns the installed version, if any.
+func CheckAgent(ctx context.Context, a Agent) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(a.URL, "/")+"/admin/update/status", nil)
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("X-MCP-Key", a.AdminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("call agent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var env struct {
+		Ok   bool `json:"ok"`
+		Data struct {
+			CurrentVersion string `json:"current_version"`
+		} `json:"data"`
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return "", fmt.Errorf("agent returned HTTP %d without a JSON envelope", resp.StatusCode)
+	}
+	if env.Error != nil {
+		return "", fmt.Errorf("%s: %s", env.Error.Code, env.Error.Message)
+	}
+	if !env.Ok {
+		return "", fmt.Errorf("agent returned HTTP %d", resp.StatusCode)
+	}
+	return env.Data.CurrentVersion, nil
+}
+
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// text asks for a value, returning def on an empty answer and "" on "-".
+func (p prompter) text(label, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", label)
+	}
+	return p.answer(def)
+}
+
+// secret is text that never echoes the current value back.
+func (p prompter) secret(label, cur string) (string, error) {
+	hint := "not set"
+	if cur != "" {
+		hint = "set"
+	}
+	fmt.Fprintf(p.out, "%s [%s]: ", label, hint)
+	return p.answer(cur)
+}
+
+// url asks until the answer is an absolute http or https URL.
+func (p prompter) url(label, def string) (string, error) {
+	for {
+		v, err := p.text(label, def)
+		if err != nil {
+			return "", err
+		}
+		if u, err := url.Parse(v); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			return v, nil
+		}
+		fmt.Fprintln(p.out, "  enter an absolute URL such as", def)
+	}
+}
+
+func (p prompter) answer(def string) (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("read answer: %w", err)
+	}
+	switch line = strings.TrimSpace(line); line {
+	case "":
+		return def, nil
+	case "-":
+		return "", nil
+	}
+	return line, nil
+}
+
+func firstSet(v, def string) string {
+	if v != "" {
+		return v
+	}
+	return def
+}
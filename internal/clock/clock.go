@@ -0,0 +1,24 @@
+// Package clock abstracts the current time so date-dependent logic can be tested at fixed
+// instants, such as just before and after midnight UTC.
+package clock
+
+import "time"
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the real clock.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Fixed returns a clock that always reports t.
+func Fixed(t time.Time) Clock { return fixedClock(t) }
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
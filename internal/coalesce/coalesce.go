@@ -0,0 +1,200 @@
+// Package coalesce shares one upstream round trip between concurrent identical analytics
+// requests, so a dashboard and a chat user reading the same graph at once cost the backend
+// a single call.
+package coalesce
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/metrics"
+)
+
+var sharedTotal = metrics.Default.Counter("analytics_upstream_shared_total",
+	"Analytics API requests answered by an identical request already in flight.")
+
+// Transport wraps base so concurrent GET and POST requests with the same key share one
+// round trip. Each caller gets its own copy of the response. Every analytics API call is a
+// read, so sharing never merges two changes into one.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, calls: map[string]*call{}}
+}
+
+type transport struct {
+	base http.RoundTripper
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// call is one shared round trip. waiters counts the callers still waiting for it; when the
+// last one gives up, the request is canceled.
+type call struct {
+	key     string
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodPost {
+		return t.base.RoundTrip(req)
+	}
+	k, ok := Key(req)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	t.mu.Lock()
+	c, shared := t.calls[k]
+	if shared {
+		c.waiters++
+		t.mu.Unlock()
+		sharedTotal.Inc()
+	} else {
+		ctx, cancel := detach(req.Context())
+		c = &call{key: k, done: make(chan struct{}), cancel: cancel, waiters: 1}
+		t.calls[k] = c
+		t.mu.Unlock()
+		go t.do(c, shareable(ctx, req))
+	}
+
+	select {
+	case <-c.done:
+		t.leave(c)
+		if c.err != nil {
+			return nil, c.err
+		}
+		return c.response(req), nil
+	case <-req.Context().Done():
+		t.leave(c)
+		return nil, req.Context().Err()
+	}
+}
+
+// detach returns a context for a shared round trip. It keeps the first caller's values but
+// neither its cancellation nor its deadline: each caller waits only until its own deadline,
+// and the round trip is canceled when the last one leaves.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithCancel(context.WithoutCancel(ctx))
+}
+
+// shareable copies req under ctx with a fresh body, so the shared round trip does not read
+// the body the first caller owns. Key has already checked that GetBody works.
+func shareable(ctx context.Context, req *http.Request) *http.Request {
+	out := req.Clone(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		out.Body, _ = req.GetBody()
+		req.Body.Close()
+	}
+	return out
+}
+
+// do makes the round trip for every caller of c.
+func (t *transport) do(c *call, req *http.Request) {
+	defer c.cancel()
+	c.resp, c.err = t.base.RoundTrip(req)
+	if c.err == nil {
+		c.body, c.err = io.ReadAll(c.resp.Body)
+		c.resp.Body.Close()
+	}
+	t.forget(c)
+	close(c.done)
+}
+
+// leave drops one waiter from c. When none are left the round trip is canceled, and later
+// callers start a new one.
+func (t *transport) leave(c *call) {
+	t.mu.Lock()
+	c.waiters--
+	last := c.waiters == 0
+	t.mu.Unlock()
+	if last {
+		t.forget(c)
+		c.cancel()
+	}
+}
+
+func (t *transport) forget(c *call) {
+	t.mu.Lock()
+	if t.calls[c.key] == c {
+		delete(t.calls, c.key)
+	}
+	t.mu.Unlock()
+}
+
+// response returns a copy of c's response for req, with its own body.
+func (c *call) response(req *http.Request) *http.Response {
+	resp := *c.resp
+	resp.Header = c.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(c.body))
+	resp.ContentLength = int64(len(c.body))
+	resp.Request = req
+	return &resp
+}
+
+// Key identifies what req asks for: its method, URL, headers and body. JSON bodies are
+// compacted with their keys sorted, so two payloads that differ only in key order or spacing
+// share a key. It reports false when the body cannot be read again.
+func Key(req *http.Request) (string, bool) {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.String()+"\n")
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range req.Header[name] {
+			io.WriteString(h, name+": "+v+"\n")
+		}
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return "", false
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return "", false
+		}
+		raw, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return "", false
+		}
+		h.Write(normalize(raw))
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// normalize re-encodes a JSON body, which sorts object keys and drops insignificant
+// whitespace. Other bodies are returned as they are.
+func normalize(raw []byte) []byte {
+	var v any
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if d.Decode(&v) != nil || d.More() {
+		return raw
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return raw
+	}
+	return out
+}
@@ -0,0 +1,201 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowServer answers after release is closed and counts the requests it got.
+func slowServer(t *testing.T, release <-chan struct{}) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		<-release
+		_, _ = io.WriteString(w, "echo:"+string(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func post(ctx context.Context, client *http.Client, url, body string) (string, error) {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer t")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	return string(out), err
+}
+
+// waitShared waits until n requests wait on one round trip.
+func waitShared(t *testing.T, tr http.RoundTripper, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		tt := tr.(*transport)
+		tt.mu.Lock()
+		waiting := 0
+		for _, c := range tt.calls {
+			waiting += c.waiters
+		}
+		tt.mu.Unlock()
+		if waiting >= n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d waiting requests", n)
+}
+
+func TestConcurrentIdenticalRequestsShareOneRoundTrip(t *testing.T) {
+	release := make(chan struct{})
+	srv, hits := slowServer(t, release)
+	tr := Transport(nil)
+	client := &http.Client{Transport: tr}
+
+	bodies := []string{`{"date_filter":"last_7_days","currency":"USDT"}`, `{ "currency": "USDT", "date_filter": "last_7_days" }`, `{"date_filter":"last_7_days","currency":"USDT"}`}
+	var wg sync.WaitGroup
+	got := make([]string, len(bodies))
+	for i, body := range bodies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := post(context.Background(), client, srv.URL+"/graph/1/data", body)
+			if err != nil {
+				t.Errorf("request %d: %v", i, err)
+			}
+			got[i] = out
+		}()
+	}
+	waitShared(t, tr, len(bodies))
+	close(release)
+	wg.Wait()
+
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("upstream got %d requests, want 1", n)
+	}
+	for i, out := range got {
+		if out != got[0] || !strings.Contains(out, "last_7_days") {
+			t.Errorf("request %d got %q", i, out)
+		}
+	}
+
+	// Once answered, the same request goes upstream again.
+	if _, err := post(context.Background(), client, srv.URL+"/graph/1/data", bodies[0]); err != nil {
+		t.Fatal(err)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("upstream got %d requests, want 2", n)
+	}
+}
+
+func TestKeySeparatesDifferentRequests(t *testing.T) {
+	base := func() *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "http://core/graph/1/data", strings.NewReader(`{"date_filter":"last_7_days"}`))
+		req.Header.Set("Authorization", "Bearer a")
+		return req
+	}
+	want, _ := Key(base())
+
+	otherBody, _ := http.NewRequest(http.MethodPost, "http://core/graph/1/data", strings.NewReader(`{"date_filter":"last_30_days"}`))
+	otherBody.Header.Set("Authorization", "Bearer a")
+	otherToken := base()
+	otherToken.Header.Set("Authorization", "Bearer b")
+	otherGraph, _ := http.NewRequest(http.MethodPost, "http://core/graph/2/data", strings.NewReader(`{"date_filter":"last_7_days"}`))
+	otherGraph.Header.Set("Authorization", "Bearer a")
+
+	for name, req := range map[string]*http.Request{"body": otherBody, "token": otherToken, "graph": otherGraph} {
+		if got, _ := Key(req); got == want {
+			t.Errorf("a different %s shares the key", name)
+		}
+	}
+}
+
+func TestCanceledCallerDoesNotFailOthers(t *testing.T) {
+	release := make(chan struct{})
+	srv, hits := slowServer(t, release)
+	tr := Transport(nil)
+	client := &http.Client{Transport: tr}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := post(first, client, srv.URL, `{}`)
+		firstErr <- err
+	}()
+	waitShared(t, tr, 1)
+	second := make(chan string, 1)
+	go func() {
+		out, err := post(context.Background(), client, srv.URL, `{}`)
+		if err != nil {
+			t.Errorf("second caller: %v", err)
+		}
+		second <- out
+	}()
+	waitShared(t, tr, 2)
+
+	cancel()
+	if err := <-firstErr; err == nil {
+		t.Fatalf("expected the canceled caller to fail")
+	}
+	close(release)
+	if out := <-second; out != "echo:{}" {
+		t.Fatalf("second caller got %q", out)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("upstream got %d requests, want 1", n)
+	}
+}
+
+func TestShortDeadlineDoesNotFailLongerOnes(t *testing.T) {
+	release := make(chan struct{})
+	srv, hits := slowServer(t, release)
+	tr := Transport(nil)
+	client := &http.Client{Transport: tr}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelShort()
+	long, cancelLong := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelLong()
+
+	shortErr := make(chan error, 1)
+	go func() {
+		_, err := post(short, client, srv.URL, `{}`)
+		shortErr <- err
+	}()
+	waitShared(t, tr, 1)
+	second := make(chan string, 1)
+	go func() {
+		out, err := post(long, client, srv.URL, `{}`)
+		if err != nil {
+			t.Errorf("long-deadline caller: %v", err)
+		}
+		second <- out
+	}()
+	waitShared(t, tr, 2)
+
+	if err := <-shortErr; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("short-deadline caller: %v, want a deadline error", err)
+	}
+	close(release)
+	if out := <-second; out != "echo:{}" {
+		t.Fatalf("long-deadline caller got %q", out)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("upstream got %d requests, want 1", n)
+	}
+}
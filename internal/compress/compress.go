@@ -0,0 +1,260 @@
+// Package compress gzip- or deflate-encodes HTTP responses for clients that send a matching
+// Accept-Encoding, so the large JSON answers of the MCP server and chat API cross slow links
+// quickly. Responses below a minimum size, already encoded, or of types that do not compress
+// (images, event streams) are sent as they are.
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Options configure the compression of one server's responses.
+type Options struct {
+	// Disabled sends every response unencoded.
+	Disabled bool
+	// MinBytes is the smallest body that is compressed; smaller ones gain less than the
+	// encoding costs.
+	MinBytes int
+	// Level is a compress/flate level, from 1 (fastest) to 9 (smallest), or -1 for the default.
+	Level int
+}
+
+// Default compresses bodies of 1 KiB and more at the default level.
+var Default = Options{MinBytes: 1024, Level: gzip.DefaultCompression}
+
+// FromEnv starts from def and applies <prefix>_COMPRESSION (false disables compression),
+// <prefix>_COMPRESSION_MIN_BYTES and <prefix>_COMPRESSION_LEVEL where they are set.
+func FromEnv(prefix string, def Options) (Options, error) {
+	o := def
+	if raw := strings.TrimSpace(os.Getenv(prefix + "_COMPRESSION")); raw != "" {
+		on, err := strconv.ParseBool(raw)
+		if err != nil {
+			return def, fmt.Errorf("%s_COMPRESSION: invalid boolean %q", prefix, raw)
+		}
+		o.Disabled = !on
+	}
+	if raw := strings.TrimSpace(os.Getenv(prefix + "_COMPRESSION_MIN_BYTES")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			return def, fmt.Errorf("%s_COMPRESSION_MIN_BYTES: invalid byte count %q", prefix, raw)
+		}
+		o.MinBytes = v
+	}
+	if raw := strings.TrimSpace(os.Getenv(prefix + "_COMPRESSION_LEVEL")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < -1 || v == 0 || v > 9 {
+			return def, fmt.Errorf("%s_COMPRESSION_LEVEL: want 1-9 or -1, got %q", prefix, raw)
+		}
+		o.Level = v
+	}
+	return o, nil
+}
+
+// String summarizes the options for startup logs.
+func (o Options) String() string {
+	if o.Disabled {
+		return "compression=off"
+	}
+	return fmt.Sprintf("compression=gzip,deflate min_bytes=%d level=%d", o.MinBytes, o.Level)
+}
+
+// encoder is a pooled gzip or zlib writer.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// Handler compresses the responses of next as o says. WebSocket upgrades and HEAD requests
+// pass through untouched.
+func (o Options) Handler(next http.Handler) http.Handler {
+	if o.Disabled {
+		return next
+	}
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, o.Level)
+			return w
+		}},
+		"deflate": {New: func() any {
+			w, _ := zlib.NewWriterLevel(io.Discard, o.Level)
+			return w
+		}},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := Negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &writer{ResponseWriter: w, encoding: encoding, pool: pools[encoding], minBytes: o.MinBytes}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// Negotiate picks the encoding to answer an Accept-Encoding header with: "gzip" or "deflate",
+// whichever the client rates higher (gzip on a tie), or "" for none.
+func Negotiate(accept string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		switch name {
+		case "gzip", "x-gzip", "*":
+			name = "gzip"
+		case "deflate":
+		default:
+			continue
+		}
+		if q > bestQ || q == bestQ && q > 0 && name == "gzip" {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressible reports whether a response of contentType is worth compressing.
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mt == "text/event-stream":
+		return false
+	case strings.HasPrefix(mt, "text/"), strings.HasSuffix(mt, "+json"), strings.HasSuffix(mt, "+xml"):
+		return true
+	}
+	switch mt {
+	case "application/json", "application/x-ndjson", "application/javascript", "application/xml", "application/yaml":
+		return true
+	}
+	return false
+}
+
+// writer holds back the first minBytes of a body to decide whether to compress it. Once it
+// has decided, it either encodes everything after or passes it through.
+type writer struct {
+	http.ResponseWriter
+	encoding string
+	pool     *sync.Pool
+	minBytes int
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (w *writer) WriteHeader(code int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+	if code >= 100 && code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	if code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent {
+		w.decide(false)
+	}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.minBytes || len(w.buf) == 0 {
+			return len(p), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide sends the header and the held-back body, encoded when compress is set and the
+// response allows it.
+func (w *writer) decide(compress bool) error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// Sniff now, as net/http would, since the encoded body cannot be sniffed.
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if compress && h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" && compressible(h.Get("Content-Type")) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		w.enc = w.pool.Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// FlushError sends what has been written so far. A body flushed before it reaches minBytes
+// is sent unencoded: it is a stream of small pieces, which compress poorly.
+func (w *writer) FlushError() error {
+	if !w.decided {
+		if err := w.decide(false); err != nil {
+			return err
+		}
+	}
+	if w.enc != nil {
+		if err := w.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Flush is FlushError for callers using http.Flusher.
+func (w *writer) Flush() { _ = w.FlushError() }
+
+// Unwrap lets http.ResponseController reach the connection for deadlines.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *writer) close() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+		w.enc.Reset(io.Discard)
+		w.pool.Put(w.enc)
+		w.enc = nil
+	}
+}
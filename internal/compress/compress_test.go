@@ -0,0 +1,164 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"gzip":                      "gzip",
+		"deflate, gzip":             "gzip",
+		"deflate":                   "deflate",
+		"gzip;q=0.5, deflate":       "deflate",
+		"gzip;q=0, deflate;q=0":     "",
+		"br, identity":              "",
+		"*":                         "gzip",
+		"x-gzip":                    "gzip",
+		"GZIP ; q=1.0":              "gzip",
+		"gzip;q=bad, deflate;q=0.1": "deflate",
+	}
+	for accept, want := range cases {
+		if got := Negotiate(accept); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func serve(h http.Handler, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		req.Header.Set("Accept-Encoding", accept)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerCompressesLargeJSON(t *testing.T) {
+	body := `{"data":[` + strings.Repeat(`{"date":"2026-01-01","count":3},`, 200) + `{}]}`
+	h := Default.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "123")
+		w.WriteHeader(http.StatusCreated)
+		// Several writes, the first below the threshold.
+		_, _ = io.WriteString(w, body[:10])
+		_, _ = io.WriteString(w, body[10:])
+	}))
+
+	rec := serve(h, "gzip, deflate")
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Content-Length") != "" {
+		t.Fatalf("response %d, headers %v", rec.Code, rec.Header())
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" || rec.Body.Len() >= len(body)/4 {
+		t.Fatalf("vary %q, %d bytes for %d", rec.Header().Get("Vary"), rec.Body.Len(), len(body))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(zr); string(got) != body {
+		t.Fatalf("decoded body differs")
+	}
+
+	// The pooled writer serves the next response, in the other encoding too.
+	rec = serve(h, "deflate")
+	zl, err := zlib.NewReader(rec.Body)
+	if err != nil || rec.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("deflate: %v, %v", err, rec.Header())
+	}
+	if got, _ := io.ReadAll(zl); string(got) != body {
+		t.Fatalf("decoded deflate body differs")
+	}
+
+	rec = serve(h, "")
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != body || rec.Header().Get("Content-Length") != "123" {
+		t.Fatalf("uncompressed response changed: %v", rec.Header())
+	}
+}
+
+func TestHandlerLeavesSomeResponsesAlone(t *testing.T) {
+	large := strings.Repeat("x", 4096)
+	cases := map[string]http.HandlerFunc{
+		"small": func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"ok":true}`)
+		},
+		"image": func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = io.WriteString(w, large)
+		},
+		"encoded": func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = io.WriteString(w, large)
+		},
+		"event stream": func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, large)
+		},
+	}
+	for name, fn := range cases {
+		rec := serve(Default.Handler(fn), "gzip")
+		if enc := rec.Header().Get("Content-Encoding"); enc == "gzip" || rec.Body.Len() < 11 {
+			t.Errorf("%s: encoding %q, %d bytes", name, enc, rec.Body.Len())
+		}
+	}
+}
+
+func TestHandlerFlushesStreams(t *testing.T) {
+	h := Default.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, strings.Repeat("row\n", 1000))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("flush: %v", err)
+		}
+		_, _ = io.WriteString(w, "last\n")
+	}))
+	rec := serve(h, "gzip")
+	if !rec.Flushed || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("flushed %v, headers %v", rec.Flushed, rec.Header())
+	}
+	zr, _ := gzip.NewReader(rec.Body)
+	if got, _ := io.ReadAll(zr); string(got) != strings.Repeat("row\n", 1000)+"last\n" {
+		t.Fatalf("streamed body differs")
+	}
+
+	// Flushed before reaching the threshold, a stream goes out unencoded.
+	h = Default.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, "tick\n")
+		w.(http.Flusher).Flush()
+		_, _ = io.WriteString(w, strings.Repeat("tock\n", 1000))
+	}))
+	if rec := serve(h, "gzip"); rec.Header().Get("Content-Encoding") != "" || !strings.HasPrefix(rec.Body.String(), "tick\ntock\n") {
+		t.Fatalf("early flush: %v", rec.Header())
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("TEST_COMPRESSION_MIN_BYTES", "256")
+	t.Setenv("TEST_COMPRESSION_LEVEL", "1")
+	o, err := FromEnv("TEST", Default)
+	if err != nil || o != (Options{MinBytes: 256, Level: 1}) {
+		t.Fatalf("FromEnv = %+v, %v", o, err)
+	}
+	t.Setenv("TEST_COMPRESSION", "false")
+	if o, _ := FromEnv("TEST", Default); !o.Disabled {
+		t.Fatalf("compression not disabled")
+	}
+	for key, val := range map[string]string{"TEST_COMPRESSION": "maybe", "TEST_COMPRESSION_LEVEL": "0", "TEST_COMPRESSION_MIN_BYTES": "-1"} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, val)
+			if _, err := FromEnv("TEST", Default); err == nil {
+				t.Fatalf("%s=%s accepted", key, val)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Package deadline carries a caller's time budget from the chat API to the MCP server, so an
+// interactive UI can bound how long a request runs, down to the analytics calls.
+package deadline
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header is the number of milliseconds the caller will wait for the response, counted from
+// when the request is received. A duration rather than a timestamp, so clock skew between
+// hosts does not matter.
+const Header = "X-Request-Deadline-Ms"
+
+// Max is the longest budget a caller may ask for.
+const Max = 10 * time.Minute
+
+// Apply bounds ctx by the budget in r's Header, if any. The cancel func must be called; it is
+// a no-op when r carries no budget.
+func Apply(ctx context.Context, r *http.Request) (context.Context, context.CancelFunc, error) {
+	v := strings.TrimSpace(r.Header.Get(Header))
+	if v == "" {
+		return ctx, func() {}, nil
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms <= 0 || ms > Max.Milliseconds() {
+		return ctx, func() {}, fmt.Errorf("%s must be a number of milliseconds between 1 and %d, got %q", Header, Max.Milliseconds(), v)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+	return ctx, cancel, nil
+}
+
+// Set sends the time left before ctx's deadline on req, so the server it calls gives up when
+// the caller does. Requests under a context with no deadline are left alone.
+func Set(ctx context.Context, req *http.Request) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	req.Header.Set(Header, strconv.FormatInt(max(time.Until(d).Milliseconds(), 1), 10))
+}
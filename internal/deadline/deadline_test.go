@@ -0,0 +1,63 @@
+package deadline
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestApplyBoundsContext(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", nil)
+	ctx, cancel, err := Apply(context.Background(), r)
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("no header should leave the context without a deadline")
+	}
+
+	r.Header.Set(Header, "1500")
+	ctx, cancel, err = Apply(context.Background(), r)
+	defer cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok := ctx.Deadline()
+	if left := time.Until(d); !ok || left <= time.Second || left > 1500*time.Millisecond {
+		t.Fatalf("deadline in %v, want about 1.5s", left)
+	}
+
+	for _, bad := range []string{"soon", "0", "-5", strconv.FormatInt(Max.Milliseconds()+1, 10)} {
+		r.Header.Set(Header, bad)
+		if _, cancel, err := Apply(context.Background(), r); err == nil {
+			cancel()
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestSetForwardsTimeLeft(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	Set(context.Background(), req)
+	if v := req.Header.Get(Header); v != "" {
+		t.Fatalf("no deadline should send no header, got %q", v)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	Set(ctx, req)
+	ms, err := strconv.Atoi(req.Header.Get(Header))
+	if err != nil || ms <= 1000 || ms > 2000 {
+		t.Fatalf("header = %q, want about 2000", req.Header.Get(Header))
+	}
+
+	past, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	Set(past, req)
+	if v := req.Header.Get(Header); v != "1" {
+		t.Fatalf("a passed deadline should send 1, got %q", v)
+	}
+}
@@ -0,0 +1,224 @@
+// Package degraded answers analytics requests from the last good response when the PayRam
+// core is unreachable, so tools stay useful through short outages. Every such answer is
+// noted, so the result can say how old its figures are.
+package degraded
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+	"github.com/payram/payram-analytics-mcp-server/internal/coalesce"
+	"github.com/payram/payram-analytics-mcp-server/internal/metrics"
+)
+
+// StaleHeader carries, on a response served from the last good copy, when that copy was
+// fetched (RFC 3339).
+const StaleHeader = "X-Payram-Stale-At"
+
+const (
+	// maxEntries caps the responses kept; the oldest go first.
+	maxEntries = 1000
+	// maxBody is the largest response kept.
+	maxBody = 1 << 20
+)
+
+var staleTotal = metrics.Default.Counter("analytics_degraded_responses_total",
+	"Analytics API requests answered from the last good response because the core failed.")
+
+// Notice collects the stale answers served under one context.
+type Notice struct {
+	mu     sync.Mutex
+	oldest time.Time
+	count  int
+}
+
+type noticeKey struct{}
+
+// WithNotice returns ctx under which Transport notes stale answers into the returned Notice.
+func WithNotice(ctx context.Context) (context.Context, *Notice) {
+	n := &Notice{}
+	return context.WithValue(ctx, noticeKey{}, n), n
+}
+
+// Stale reports how many answers were served from the last good copy, and when the oldest
+// of them was fetched.
+func (n *Notice) Stale() (count int, oldest time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.count, n.oldest
+}
+
+func (n *Notice) add(at time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.count == 0 || at.Before(n.oldest) {
+		n.oldest = at
+	}
+	n.count++
+}
+
+type entry struct {
+	at     time.Time
+	header http.Header
+	body   []byte
+}
+
+// Store keeps the last good response per request, for up to maxAge.
+type Store struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	loaded  bool
+	maxAge  time.Duration
+	entries map[string]*entry
+}
+
+// NewStore returns a store serving copies up to maxAge old; zero turns degraded answers off.
+func NewStore(maxAge time.Duration) *Store {
+	return &Store{clock: clock.System, loaded: true, maxAge: maxAge, entries: map[string]*entry{}}
+}
+
+// SetClock replaces the clock copies are timed by, e.g. in tests.
+func (s *Store) SetClock(c clock.Clock) { s.clock = c }
+
+// Default is the store Transport uses when given none. Its maximum age is read from
+// PAYRAM_ANALYTICS_DEGRADED_MAX_AGE (a Go duration) on first use; unset, degraded answers are
+// off.
+var Default = &Store{clock: clock.System, entries: map[string]*entry{}}
+
+// age returns how old a copy may be to be served; called with s.mu held.
+func (s *Store) age() time.Duration {
+	if !s.loaded {
+		s.loaded = true
+		if v := strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_DEGRADED_MAX_AGE")); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				log.Printf("[degraded] invalid PAYRAM_ANALYTICS_DEGRADED_MAX_AGE %q; degraded answers are off", v)
+			} else {
+				s.maxAge = d
+			}
+		}
+	}
+	return s.maxAge
+}
+
+func (s *Store) put(key string, resp *http.Response, body []byte) {
+	if len(body) > maxBody {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[key]; !ok && len(s.entries) >= maxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, e := range s.entries {
+			if oldestKey == "" || e.at.Before(oldest) {
+				oldestKey, oldest = k, e.at
+			}
+		}
+		delete(s.entries, oldestKey)
+	}
+	s.entries[key] = &entry{at: s.clock.Now(), header: resp.Header.Clone(), body: body}
+}
+
+// get returns the copy under key when it is young enough to serve.
+func (s *Store) get(key string) (*entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || s.clock.Now().Sub(e.at) > s.maxAge {
+		return nil, false
+	}
+	return e, true
+}
+
+// failed reports whether an upstream answer means the core is unavailable rather than that
+// the request was wrong.
+func failed(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// Transport wraps base so successful GET and POST responses are kept in store (Default when
+// nil), and a request the core fails to answer gets the last good copy instead, with
+// StaleHeader set. A request canceled by its caller is never answered from the copy.
+func Transport(base http.RoundTripper, store *Store) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if store == nil {
+		store = Default
+	}
+	return &transport{base: base, store: store}
+}
+
+type transport struct {
+	base  http.RoundTripper
+	store *Store
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.store.mu.Lock()
+	maxAge := t.store.age()
+	t.store.mu.Unlock()
+	if maxAge == 0 || (req.Method != http.MethodGet && req.Method != http.MethodPost) {
+		return t.base.RoundTrip(req)
+	}
+	key, ok := coalesce.Key(req)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if !failed(resp, err) {
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return resp, nil
+		}
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if readErr != nil {
+			return nil, readErr
+		}
+		t.store.put(key, resp, body)
+		return resp, nil
+	}
+	if req.Context().Err() != nil {
+		return resp, err
+	}
+	e, ok := t.store.get(key)
+	if !ok {
+		return resp, err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	staleTotal.Inc()
+	if n, _ := req.Context().Value(noticeKey{}).(*Notice); n != nil {
+		n.add(e.at)
+	}
+	header := e.header.Clone()
+	header.Set(StaleHeader, e.at.UTC().Format(time.RFC3339))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package degraded
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+)
+
+func TestTransportServesLastGoodCopyWhenCoreFails(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+		_, _ = io.WriteString(w, `{"total":12}`)
+	}))
+	defer srv.Close()
+
+	fetched := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	store := NewStore(time.Hour)
+	store.SetClock(clock.Fixed(fetched))
+	client := &http.Client{Transport: Transport(nil, store)}
+	post := func(ctx context.Context, body string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/graph/1/data", strings.NewReader(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return resp, string(out)
+	}
+
+	if resp, _ := post(context.Background(), `{"analytics_date_filter":"last_7_days"}`); resp.Header.Get(StaleHeader) != "" {
+		t.Fatalf("a fresh answer was marked stale")
+	}
+
+	status.Store(http.StatusBadGateway)
+	store.SetClock(clock.Fixed(fetched.Add(30 * time.Minute)))
+	ctx, notice := WithNotice(context.Background())
+	resp, body := post(ctx, `{"analytics_date_filter":"last_7_days"}`)
+	if resp.StatusCode != http.StatusOK || body != `{"total":12}` || resp.Header.Get(StaleHeader) != "2026-03-01T09:00:00Z" {
+		t.Fatalf("expected the cached copy, got %d %s %v", resp.StatusCode, body, resp.Header)
+	}
+	if n, asOf := notice.Stale(); n != 1 || !asOf.Equal(fetched) {
+		t.Fatalf("notice = %d %s", n, asOf)
+	}
+
+	// Another request has no copy, and copies past the maximum age are not served.
+	if resp, _ := post(context.Background(), `{"analytics_date_filter":"last_30_days"}`); resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("uncached request: status %d", resp.StatusCode)
+	}
+	store.SetClock(clock.Fixed(fetched.Add(2 * time.Hour)))
+	if resp, _ := post(context.Background(), `{"analytics_date_filter":"last_7_days"}`); resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expired copy: status %d", resp.StatusCode)
+	}
+}
+
+func TestTransportPassesClientErrorsThrough(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: Transport(nil, NewStore(time.Hour))}
+
+	for _, code := range []int{http.StatusOK, http.StatusUnauthorized} {
+		status.Store(int32(code))
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Fatalf("status %d, want %d", resp.StatusCode, code)
+		}
+	}
+}
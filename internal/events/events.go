@@ -0,0 +1,228 @@
+// Package events keeps the most recent PayRam payment webhooks, so tools can answer "did it
+// just get paid?" without polling the analytics API.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+)
+
+// DefaultMax is how many events a store keeps when Open is given no limit.
+const DefaultMax = 1000
+
+// Event is one received webhook. The common fields are copied out of Payload, which holds the
+// webhook body as sent.
+type Event struct {
+	Seq         int64          `json:"seq"`
+	Tenant      string         `json:"tenant,omitempty"`
+	Received    time.Time      `json:"received_at"`
+	ReferenceID string         `json:"reference_id,omitempty"`
+	State       string         `json:"payment_state,omitempty"`
+	Amount      string         `json:"amount,omitempty"`
+	Currency    string         `json:"currency,omitempty"`
+	Payload     map[string]any `json:"payload"`
+}
+
+// Payload keys the common fields are read from, in order of preference.
+var (
+	referenceKeys = []string{"reference_id", "referenceId", "invoice_id", "invoiceId", "payment_id", "id"}
+	stateKeys     = []string{"payment_state", "paymentState", "status", "state"}
+	amountKeys    = []string{"amount", "amount_in_usd", "amountInUSD", "filled_amount"}
+	currencyKeys  = []string{"currency_code", "currency", "currencyCode"}
+)
+
+// Parse reads a webhook body into an event for tenant (empty without tenancy).
+func Parse(body []byte, tenant string) (Event, error) {
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Event{}, fmt.Errorf("webhook body is not a JSON object: %w", err)
+	}
+	return Event{
+		Tenant:      tenant,
+		ReferenceID: value(payload, referenceKeys),
+		State:       strings.ToUpper(value(payload, stateKeys)),
+		Amount:      value(payload, amountKeys),
+		Currency:    strings.ToUpper(value(payload, currencyKeys)),
+		Payload:     payload,
+	}, nil
+}
+
+// Details returns the payload fields not copied into the common fields, as sorted
+// key=value pairs.
+func (e Event) Details() []string {
+	used := map[string]bool{}
+	for _, keys := range [][]string{referenceKeys, stateKeys, amountKeys, currencyKeys} {
+		if k, _ := first(e.Payload, keys); k != "" {
+			used[k] = true
+		}
+	}
+	var out []string
+	for k, v := range e.Payload {
+		if used[k] || v == nil {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			data, _ := json.Marshal(v)
+			s = string(data)
+		}
+		out = append(out, k+"="+s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func value(payload map[string]any, keys []string) string {
+	_, v := first(payload, keys)
+	return v
+}
+
+// first returns the first of keys with a non-empty string or number value, and that value.
+func first(payload map[string]any, keys []string) (string, string) {
+	for _, k := range keys {
+		switch v := payload[k].(type) {
+		case string:
+			if s := strings.TrimSpace(v); s != "" {
+				return k, s
+			}
+		case float64:
+			return k, strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return "", ""
+}
+
+// Filter selects events for Recent. Zero fields match everything.
+type Filter struct {
+	Tenant      string
+	ReferenceID string
+	State       string
+	Since       time.Time
+	Limit       int
+}
+
+// Store holds the newest events, optionally persisted to a JSON file.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	max    int
+	clk    clock.Clock
+	seq    int64
+	events []Event // oldest first
+}
+
+// Open loads events from file, or keeps them in memory only when file is empty. The store
+// keeps the newest max events (DefaultMax when zero).
+func Open(file string, max int) (*Store, error) {
+	if max <= 0 {
+		max = DefaultMax
+	}
+	s := &Store{path: strings.TrimSpace(file), max: max, clk: clock.System}
+	if s.path == "" {
+		return s, nil
+	}
+	raw, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read events: %w", err)
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &s.events); err != nil {
+			return nil, fmt.Errorf("decode events: %w", err)
+		}
+	}
+	if n := len(s.events); n > 0 {
+		s.seq = s.events[n-1].Seq
+	}
+	s.trim()
+	return s, nil
+}
+
+// SetClock replaces the clock stamping received events.
+func (s *Store) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clk = c
+}
+
+// Add records e, stamped with its receive time and sequence number, and drops the oldest
+// events beyond the limit.
+func (s *Store) Add(e Event) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	e.Seq = s.seq
+	e.Received = s.clk.Now().UTC()
+	s.events = append(s.events, e)
+	s.trim()
+	return e, s.save()
+}
+
+// Now returns the store's current time, which Filter.Since is relative to.
+func (s *Store) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clk.Now()
+}
+
+// Recent returns the events matching f, newest first.
+func (s *Store) Recent(f Filter) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Event
+	for i := len(s.events) - 1; i >= 0; i-- {
+		e := s.events[i]
+		switch {
+		case e.Tenant != f.Tenant:
+			continue
+		case !f.Since.IsZero() && e.Received.Before(f.Since):
+			continue
+		case f.ReferenceID != "" && !strings.EqualFold(e.ReferenceID, f.ReferenceID):
+			continue
+		case f.State != "" && !strings.EqualFold(e.State, f.State):
+			continue
+		}
+		out = append(out, e)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// trim drops the oldest events beyond s.max. Callers hold s.mu.
+func (s *Store) trim() {
+	if n := len(s.events) - s.max; n > 0 {
+		s.events = append([]Event(nil), s.events[n:]...)
+	}
+}
+
+// save writes the events to s.path. Callers hold s.mu.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.events)
+	if err != nil {
+		return fmt.Errorf("encode events: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(s.path), err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write events: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("rename events: %w", err)
+	}
+	return nil
+}
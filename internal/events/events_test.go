@@ -0,0 +1,94 @@
+package events
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+)
+
+func TestParseCopiesCommonFields(t *testing.T) {
+	e, err := Parse([]byte(`{"reference_id":"INV-7","payment_state":"filled","amount":12.5,"currency_code":"usdt","deposit_address":"0xabc","meta":{"a":1}}`), "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Tenant != "acme" || e.ReferenceID != "INV-7" || e.State != "FILLED" || e.Amount != "12.5" || e.Currency != "USDT" {
+		t.Errorf("Parse = %+v", e)
+	}
+	if got, want := e.Details(), []string{"deposit_address=0xabc", `meta={"a":1}`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Details = %q, want %q", got, want)
+	}
+	if _, err := Parse([]byte(`[1]`), ""); err == nil {
+		t.Error("Parse accepted a non-object body")
+	}
+}
+
+func TestRecentFiltersNewestFirst(t *testing.T) {
+	s, err := Open("", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	for i, ref := range []string{"A", "B", "C", "D"} {
+		s.SetClock(clock.Fixed(start.Add(time.Duration(i) * time.Minute)))
+		state := "OPEN"
+		if ref == "C" {
+			state = "FILLED"
+		}
+		if _, err := s.Add(Event{ReferenceID: ref, State: state}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Add(Event{Tenant: "other", ReferenceID: "E"}); err != nil {
+		t.Fatal(err)
+	}
+
+	refs := func(list []Event) []string {
+		var out []string
+		for _, e := range list {
+			out = append(out, e.ReferenceID)
+		}
+		return out
+	}
+	// The limit of 3 dropped A and B: E is the newest and belongs to another tenant.
+	if got := refs(s.Recent(Filter{})); !reflect.DeepEqual(got, []string{"D", "C"}) {
+		t.Errorf("Recent() = %v, want [D C]", got)
+	}
+	if got := refs(s.Recent(Filter{State: "filled"})); !reflect.DeepEqual(got, []string{"C"}) {
+		t.Errorf("Recent(state) = %v, want [C]", got)
+	}
+	if got := refs(s.Recent(Filter{Since: start.Add(3 * time.Minute)})); !reflect.DeepEqual(got, []string{"D"}) {
+		t.Errorf("Recent(since) = %v, want [D]", got)
+	}
+	if got := refs(s.Recent(Filter{Tenant: "other", ReferenceID: "e"})); !reflect.DeepEqual(got, []string{"E"}) {
+		t.Errorf("Recent(tenant, reference) = %v, want [E]", got)
+	}
+}
+
+func TestEventsPersistAcrossReopen(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.json")
+	s, err := Open(file, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(Event{ReferenceID: "INV-1", Payload: map[string]any{"reference_id": "INV-1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(file, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Recent(Filter{}); len(got) != 1 || got[0].ReferenceID != "INV-1" {
+		t.Fatalf("after reopen Recent = %+v", got)
+	}
+	e, err := s.Add(Event{ReferenceID: "INV-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Seq != 2 {
+		t.Errorf("Seq after reopen = %d, want 2", e.Seq)
+	}
+}
@@ -0,0 +1,185 @@
+// Package glossary maps the words people ask with to the words PayRam uses: "withdrawal" to
+// "payout" when searching the docs, "bitcoin" to BTC in currency filters and "volume" to
+// amount in metric names. A JSON file named by PAYRAM_GLOSSARY_FILE adds to or overrides the
+// built-in entries.
+package glossary
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Glossary holds the terms to expand. Keys are matched case-insensitively, as whole words.
+type Glossary struct {
+	// Synonyms lists, for a term, the words the docs use for it instead.
+	Synonyms map[string][]string `json:"synonyms"`
+	// Currencies maps currency names to codes, e.g. "tether" to "USDT".
+	Currencies map[string]string `json:"currencies"`
+	// Metrics maps metric names to the ones tools take, e.g. "volume" to "amount".
+	Metrics map[string]string `json:"metrics"`
+}
+
+// builtin is the glossary without a file.
+func builtin() *Glossary {
+	return &Glossary{
+		Synonyms: map[string][]string{
+			"withdrawal":  {"payout"},
+			"withdrawals": {"payouts"},
+			"chain":       {"network"},
+			"chains":      {"networks"},
+			"blockchain":  {"network"},
+			"coin":        {"currency", "token"},
+			"coins":       {"currencies", "tokens"},
+			"invoice":     {"payment link"},
+			"invoices":    {"payment links"},
+			"role":        {"permissions"},
+			"roles":       {"permissions"},
+		},
+		Currencies: map[string]string{
+			"bitcoin":  "BTC",
+			"ether":    "ETH",
+			"ethereum": "ETH",
+			"tron":     "TRX",
+			"tether":   "USDT",
+			"usd coin": "USDC",
+		},
+		Metrics: map[string]string{
+			"volume":       "amount",
+			"revenue":      "amount",
+			"sales":        "amount",
+			"value":        "amount",
+			"transactions": "count",
+			"number":       "count",
+		},
+	}
+}
+
+// Load returns the built-in glossary with the entries of file added, replacing built-in
+// entries for the same term. An empty path returns the built-in glossary.
+func Load(file string) (*Glossary, error) {
+	g := builtin()
+	if strings.TrimSpace(file) == "" {
+		return g, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read glossary: %w", err)
+	}
+	var extra Glossary
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return nil, fmt.Errorf("decode glossary: %w", err)
+	}
+	for k, v := range extra.Synonyms {
+		g.Synonyms[normalize(k)] = v
+	}
+	for k, v := range extra.Currencies {
+		g.Currencies[normalize(k)] = strings.ToUpper(strings.TrimSpace(v))
+	}
+	for k, v := range extra.Metrics {
+		g.Metrics[normalize(k)] = strings.ToLower(strings.TrimSpace(v))
+	}
+	return g, nil
+}
+
+var (
+	defaultOnce sync.Once
+	defaultG    *Glossary
+)
+
+// Default returns the glossary loaded from PAYRAM_GLOSSARY_FILE on first use. A file that
+// cannot be read is logged and the built-in glossary used instead.
+func Default() *Glossary {
+	defaultOnce.Do(func() {
+		file := os.Getenv("PAYRAM_GLOSSARY_FILE")
+		g, err := Load(file)
+		if err != nil {
+			log.Printf("[glossary] %s: %v; using the built-in glossary", file, err)
+			g = builtin()
+		}
+		defaultG = g
+	})
+	return defaultG
+}
+
+// Expand returns query followed by the synonyms of the terms it contains that it does not
+// already contain, e.g. "withdrawal fees payout" for "withdrawal fees".
+func (g *Glossary) Expand(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var extra []string
+	for term, synonyms := range g.Synonyms {
+		if !containsPhrase(words, strings.Fields(term)) {
+			continue
+		}
+		for _, s := range synonyms {
+			for _, w := range strings.Fields(strings.ToLower(s)) {
+				if !slices.Contains(words, w) && !slices.Contains(extra, w) {
+					extra = append(extra, w)
+				}
+			}
+		}
+	}
+	if len(extra) == 0 {
+		return query
+	}
+	// Map order is random; sorted extras keep the expansion stable.
+	slices.Sort(extra)
+	return query + " " + strings.Join(extra, " ")
+}
+
+// Currency returns the currency code for name, such as "BTC" for "Bitcoin", or name in
+// upper case when it is not in the glossary.
+func (g *Glossary) Currency(name string) string {
+	if code, ok := g.Currencies[normalize(name)]; ok {
+		return code
+	}
+	return strings.ToUpper(strings.TrimSpace(name))
+}
+
+// CurrencyCodes applies Currency to each of names.
+func (g *Glossary) CurrencyCodes(names []string) []string {
+	if names == nil {
+		return nil
+	}
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = g.Currency(n)
+	}
+	return out
+}
+
+// Metric returns the metric name tools take for name, such as "amount" for "volume", or name
+// in lower case when it is not in the glossary.
+func (g *Glossary) Metric(name string) string {
+	if m, ok := g.Metrics[normalize(name)]; ok {
+		return m
+	}
+	return normalize(name)
+}
+
+// normalize lower-cases s and collapses its spaces, underscores and dashes to single spaces.
+func normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-' || r == '\t'
+	}), " ")
+}
+
+// containsPhrase reports whether phrase appears in words as consecutive words.
+func containsPhrase(words, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package glossary
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExpandAddsDocWording(t *testing.T) {
+	g := builtin()
+	cases := map[string]string{
+		"Withdrawal fees?":      "Withdrawal fees? payout",
+		"which chains":          "which chains networks",
+		"payout to a chain":     "payout to a chain network",
+		"invoice expiry":        "invoice expiry link payment",
+		"withdrawals, payouts":  "withdrawals, payouts",
+		"webhook retries":       "webhook retries",
+		"chainlink integration": "chainlink integration",
+	}
+	for in, want := range cases {
+		if got := g.Expand(in); got != want {
+			t.Errorf("Expand(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLoadOverridesBuiltins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "glossary.json")
+	data := `{"synonyms":{"Cash Out":["payout","settlement"]},"currencies":{"Bitcoin":"cbbtc","usd_coin":"usdc"},"metrics":{"Volume":"Count"}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := g.Expand("cash out limits"); got != "cash out limits payout settlement" {
+		t.Errorf("Expand = %q", got)
+	}
+	if got := g.CurrencyCodes([]string{"bitcoin", "USD coin", "tether", "sol"}); !slices.Equal(got, []string{"CBBTC", "USDC", "USDT", "SOL"}) {
+		t.Errorf("CurrencyCodes = %v", got)
+	}
+	if g.Metric(" volume ") != "count" || g.Metric("Revenue") != "amount" || g.Metric("Both") != "both" {
+		t.Errorf("Metric: %q %q %q", g.Metric("volume"), g.Metric("Revenue"), g.Metric("Both"))
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
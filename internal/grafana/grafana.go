@@ -0,0 +1,402 @@
+// Package grafana serves the Grafana JSON datasource contract (GET /, POST /search, /query
+// and /annotations) over PayRam analytics graphs, so dashboards can chart them directly.
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// maxRequestBytes caps a datasource request body.
+const maxRequestBytes = 1 << 20
+
+// Target is one queryable graph, as listed by /search.
+type Target struct {
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+// Table is a graph's data for a time range. Rows[i] has one cell per column.
+type Table struct {
+	Name    string
+	Columns []string
+	Rows    [][]string
+}
+
+// Annotation marks an event on dashboards, such as a payment webhook.
+type Annotation struct {
+	Time  time.Time
+	Title string
+	Text  string
+	Tags  []string
+}
+
+// Source supplies the graphs behind the datasource.
+type Source interface {
+	Targets(ctx context.Context) ([]Target, *protocol.ResponseError)
+	Table(ctx context.Context, target string, from, to time.Time) (Table, *protocol.ResponseError)
+}
+
+// Annotator supplies annotations matching query within [from, to].
+type Annotator interface {
+	Annotations(ctx context.Context, from, to time.Time, query string) ([]Annotation, *protocol.ResponseError)
+}
+
+type handler struct {
+	src Source
+	ann Annotator
+}
+
+// NewHandler serves src as a Grafana JSON datasource. ann, if not nil, answers /annotations;
+// without it the endpoint returns none.
+func NewHandler(src Source, ann Annotator) http.Handler {
+	h := &handler{src: src, ann: ann}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/search", h.post(h.search))
+	mux.HandleFunc("/query", h.post(h.query))
+	mux.HandleFunc("/annotations", h.post(h.annotations))
+	return mux
+}
+
+// post restricts fn to POST requests and answers it as JSON.
+func (h *handler) post(fn func(ctx context.Context, body []byte) (any, *protocol.ResponseError)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		var raw json.RawMessage
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&raw); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+		out, errResp := fn(r.Context(), raw)
+		if errResp != nil {
+			writeError(w, statusOf(errResp), errResp.Message)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
+
+type timeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+func (rng timeRange) check() *protocol.ResponseError {
+	if rng.From.IsZero() || rng.To.IsZero() || !rng.From.Before(rng.To) {
+		return protocol.InvalidArgs("range.from and range.to are required, with from before to")
+	}
+	return nil
+}
+
+func (h *handler) search(ctx context.Context, body []byte) (any, *protocol.ResponseError) {
+	var req struct {
+		Target string `json:"target"`
+	}
+	_ = json.Unmarshal(body, &req)
+	targets, errResp := h.src.Targets(ctx)
+	if errResp != nil {
+		return nil, errResp
+	}
+	q := strings.ToLower(strings.TrimSpace(req.Target))
+	out := []Target{}
+	for _, t := range targets {
+		if q == "" || strings.Contains(strings.ToLower(t.Text), q) || t.Value == q {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+type queryRequest struct {
+	Range   timeRange `json:"range"`
+	Targets []struct {
+		RefID  string `json:"refId"`
+		Target string `json:"target"`
+		Type   string `json:"type"`
+		Hide   bool   `json:"hide"`
+	} `json:"targets"`
+}
+
+type timeSeries struct {
+	Target     string       `json:"target"`
+	RefID      string       `json:"refId,omitempty"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type tableColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+type tableResponse struct {
+	Type    string        `json:"type"`
+	RefID   string        `json:"refId,omitempty"`
+	Columns []tableColumn `json:"columns"`
+	Rows    [][]any       `json:"rows"`
+}
+
+func (h *handler) query(ctx context.Context, body []byte) (any, *protocol.ResponseError) {
+	var req queryRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, protocol.InvalidArgs("invalid query request")
+	}
+	if errResp := req.Range.check(); errResp != nil {
+		return nil, errResp
+	}
+	out := []any{}
+	for _, t := range req.Targets {
+		if t.Hide || strings.TrimSpace(t.Target) == "" {
+			continue
+		}
+		table, errResp := h.src.Table(ctx, strings.TrimSpace(t.Target), req.Range.From, req.Range.To)
+		if errResp != nil {
+			return nil, errResp
+		}
+		if t.Type == "table" {
+			out = append(out, tableOf(table, t.RefID))
+			continue
+		}
+		for _, s := range seriesOf(table, req.Range.To) {
+			s.RefID = t.RefID
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+type annotationRequest struct {
+	Range      timeRange       `json:"range"`
+	Annotation json.RawMessage `json:"annotation"`
+}
+
+type annotationResponse struct {
+	Annotation json.RawMessage `json:"annotation"`
+	Time       int64           `json:"time"`
+	Title      string          `json:"title"`
+	Text       string          `json:"text"`
+	Tags       []string        `json:"tags"`
+}
+
+func (h *handler) annotations(ctx context.Context, body []byte) (any, *protocol.ResponseError) {
+	var req annotationRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, protocol.InvalidArgs("invalid annotation request")
+	}
+	if errResp := req.Range.check(); errResp != nil {
+		return nil, errResp
+	}
+	var spec struct {
+		Query string `json:"query"`
+	}
+	_ = json.Unmarshal(req.Annotation, &spec)
+	out := []annotationResponse{}
+	if h.ann == nil {
+		return out, nil
+	}
+	list, errResp := h.ann.Annotations(ctx, req.Range.From, req.Range.To, strings.TrimSpace(spec.Query))
+	if errResp != nil {
+		return nil, errResp
+	}
+	for _, a := range list {
+		tags := a.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		out = append(out, annotationResponse{Annotation: req.Annotation, Time: a.Time.UnixMilli(), Title: a.Title, Text: a.Text, Tags: tags})
+	}
+	return out, nil
+}
+
+// seriesOf turns a table into time series. When every row starts with a date, each numeric
+// column becomes a series over those dates; otherwise each numeric cell is a single point at
+// at, named by its row label and column.
+func seriesOf(t Table, at time.Time) []timeSeries {
+	if len(t.Columns) == 0 {
+		return []timeSeries{}
+	}
+	times, timed := rowTimes(t.Rows)
+	var out []timeSeries
+	if timed {
+		for c := 1; c < len(t.Columns); c++ {
+			s := timeSeries{Target: seriesName(t.Name, t.Columns[c], len(t.Columns) > 2)}
+			for i, row := range t.Rows {
+				if v, ok := number(cell(row, c)); ok {
+					s.Datapoints = append(s.Datapoints, [2]float64{v, float64(times[i].UnixMilli())})
+				}
+			}
+			if len(s.Datapoints) > 0 {
+				sort.Slice(s.Datapoints, func(a, b int) bool { return s.Datapoints[a][1] < s.Datapoints[b][1] })
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	ms := float64(at.UnixMilli())
+	for _, row := range t.Rows {
+		first := 0
+		label := ""
+		if _, ok := number(cell(row, 0)); !ok && len(t.Columns) > 1 {
+			first, label = 1, cell(row, 0)
+		}
+		numeric := len(t.Columns) - first
+		for c := first; c < len(t.Columns); c++ {
+			v, ok := number(cell(row, c))
+			if !ok {
+				continue
+			}
+			name := t.Name
+			if label != "" {
+				name += " " + label
+			}
+			// Add the column when the row label alone does not say which number this is.
+			if numeric > 1 || (label == "" && len(t.Columns) > 1) {
+				name += " " + t.Columns[c]
+			}
+			out = append(out, timeSeries{Target: name, Datapoints: [][2]float64{{v, ms}}})
+		}
+	}
+	if out == nil {
+		out = []timeSeries{}
+	}
+	return out
+}
+
+func seriesName(table, column string, several bool) string {
+	if several {
+		return table + " " + column
+	}
+	return table
+}
+
+// tableOf converts a table to Grafana's table format, typing columns whose cells are all
+// numbers or all dates.
+func tableOf(t Table, refID string) tableResponse {
+	resp := tableResponse{Type: "table", RefID: refID, Columns: make([]tableColumn, len(t.Columns)), Rows: make([][]any, len(t.Rows))}
+	kinds := make([]string, len(t.Columns))
+	for c, name := range t.Columns {
+		kinds[c] = columnKind(t.Rows, c)
+		resp.Columns[c] = tableColumn{Text: name, Type: kinds[c]}
+	}
+	for i, row := range t.Rows {
+		cells := make([]any, len(t.Columns))
+		for c := range t.Columns {
+			v := cell(row, c)
+			switch kinds[c] {
+			case "number":
+				if n, ok := number(v); ok {
+					cells[c] = n
+				}
+			case "time":
+				ts, _ := parseTime(v)
+				cells[c] = ts.UnixMilli()
+			default:
+				cells[c] = v
+			}
+		}
+		resp.Rows[i] = cells
+	}
+	return resp
+}
+
+// columnKind is "number" or "time" when every non-empty cell of column c is one, else
+// "string".
+func columnKind(rows [][]string, c int) string {
+	numbers, times, values := 0, 0, 0
+	for _, row := range rows {
+		v := cell(row, c)
+		if v == "" {
+			continue
+		}
+		values++
+		if _, ok := number(v); ok {
+			numbers++
+		} else if _, ok := parseTime(v); ok {
+			times++
+		}
+	}
+	switch {
+	case values > 0 && numbers == values:
+		return "number"
+	case values > 0 && times == values:
+		return "time"
+	}
+	return "string"
+}
+
+// rowTimes parses the first cell of every row as a date, reporting false unless all parse.
+func rowTimes(rows [][]string) ([]time.Time, bool) {
+	if len(rows) == 0 {
+		return nil, false
+	}
+	times := make([]time.Time, len(rows))
+	for i, row := range rows {
+		t, ok := parseTime(cell(row, 0))
+		if !ok {
+			return nil, false
+		}
+		times[i] = t
+	}
+	return times, true
+}
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func number(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+func cell(row []string, c int) string {
+	if c < len(row) {
+		return row[c]
+	}
+	return ""
+}
+
+// statusOf maps a source error to the HTTP status Grafana is answered with.
+func statusOf(e *protocol.ResponseError) int {
+	d, _ := protocol.DataOf(e)
+	switch {
+	case d.Category == protocol.CategoryInvalidArgs:
+		return http.StatusBadRequest
+	case d.Category == protocol.CategoryNotFound:
+		return http.StatusNotFound
+	case d.Category == protocol.CategoryQuotaExceeded:
+		return http.StatusTooManyRequests
+	case d.Retryable:
+		return http.StatusServiceUnavailable
+	case d.Category == protocol.CategoryInternal:
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadGateway
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,99 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) Targets(context.Context) ([]Target, *protocol.ResponseError) {
+	return []Target{{Text: "Summary / Daily payments", Value: "2/21"}, {Text: "Numbers / Total", Value: "1/11"}}, nil
+}
+
+func (fakeSource) Table(_ context.Context, target string, _, _ time.Time) (Table, *protocol.ResponseError) {
+	switch target {
+	case "2/21":
+		return Table{Name: "Daily payments", Columns: []string{"date", "BTC", "USDT"}, Rows: [][]string{
+			{"2026-01-02", "0", "980"},
+			{"2026-01-01", "300", "1200.5"},
+		}}, nil
+	case "1/11":
+		return Table{Name: "Total", Columns: []string{"field", "value"}, Rows: [][]string{{"currency", "USD"}, {"value", "125430.55"}}}, nil
+	}
+	return Table{}, protocol.NotFound("no such graph")
+}
+
+type fakeAnnotator struct{}
+
+func (fakeAnnotator) Annotations(_ context.Context, from, _ time.Time, query string) ([]Annotation, *protocol.ResponseError) {
+	return []Annotation{{Time: from.Add(time.Hour), Title: query + " 25 USDT", Text: "INV-7"}}, nil
+}
+
+func post(t *testing.T, h http.Handler, path, body string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return rec.Code, rec.Body.String()
+}
+
+const testRange = `"range":{"from":"2026-01-01T00:00:00Z","to":"2026-01-03T00:00:00Z"}`
+
+func TestSearchFiltersTargets(t *testing.T) {
+	h := NewHandler(fakeSource{}, nil)
+	code, body := post(t, h, "/search", `{"target":"daily"}`)
+	if code != http.StatusOK || strings.TrimSpace(body) != `[{"text":"Summary / Daily payments","value":"2/21"}]` {
+		t.Errorf("search = %d %s", code, body)
+	}
+}
+
+func TestQueryReturnsSeriesAndTables(t *testing.T) {
+	h := NewHandler(fakeSource{}, nil)
+	code, body := post(t, h, "/query", `{`+testRange+`,"targets":[{"refId":"A","target":"2/21"},{"refId":"B","target":"1/11"},{"refId":"C","target":"2/21","type":"table"}]}`)
+	if code != http.StatusOK {
+		t.Fatalf("query = %d %s", code, body)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("query returned %d results, want 4: %s", len(got), body)
+	}
+	// Series are sorted by time: 2026-01-01 comes first.
+	if got[1]["target"] != "Daily payments USDT" || got[1]["datapoints"].([]any)[0].([]any)[0] != 1200.5 {
+		t.Errorf("USDT series = %v", got[1])
+	}
+	if got[2]["target"] != "Total value" || got[2]["datapoints"].([]any)[0].([]any)[1] != float64(time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC).UnixMilli()) {
+		t.Errorf("number series = %v", got[2])
+	}
+	cols := got[3]["columns"].([]any)
+	if got[3]["type"] != "table" || cols[0].(map[string]any)["type"] != "time" || cols[2].(map[string]any)["type"] != "number" {
+		t.Errorf("table = %v", got[3])
+	}
+
+	if code, _ := post(t, h, "/query", `{`+testRange+`,"targets":[{"target":"9/9"}]}`); code != http.StatusNotFound {
+		t.Errorf("unknown target: status %d, want 404", code)
+	}
+	if code, _ := post(t, h, "/query", `{"targets":[{"target":"2/21"}]}`); code != http.StatusBadRequest {
+		t.Errorf("missing range: status %d, want 400", code)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	code, body := post(t, NewHandler(fakeSource{}, nil), "/annotations", `{`+testRange+`,"annotation":{"name":"payments"}}`)
+	if code != http.StatusOK || strings.TrimSpace(body) != "[]" {
+		t.Errorf("annotations without annotator = %d %s", code, body)
+	}
+	code, body = post(t, NewHandler(fakeSource{}, fakeAnnotator{}), "/annotations", `{`+testRange+`,"annotation":{"name":"payments","query":"FILLED"}}`)
+	if code != http.StatusOK || !strings.Contains(body, `"title":"FILLED 25 USDT"`) || !strings.Contains(body, `"annotation":{"name":"payments","query":"FILLED"}`) {
+		t.Errorf("annotations = %d %s", code, body)
+	}
+}
@@ -0,0 +1,109 @@
+// Package handover lets supervised servers inherit their listening socket from the agent.
+// The agent keeps the socket open across restarts, so during an update the new binary
+// starts accepting connections on the same socket before the old process is stopped.
+package handover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// ListenFDEnv names the inherited listener file descriptor.
+	ListenFDEnv = "PAYRAM_LISTEN_FD"
+	// ReadyFDEnv names the pipe used to report readiness back to the agent.
+	ReadyFDEnv = "PAYRAM_READY_FD"
+)
+
+// acceptGrace is how long connections accepted just before shutdown get to send their
+// request; net/http drops requests it reads after Shutdown has begun.
+const acceptGrace = 200 * time.Millisecond
+
+var readyOnce sync.Once
+
+// Listen returns the inherited listener when PAYRAM_LISTEN_FD is set, otherwise listens on addr.
+func Listen(addr string) (net.Listener, error) {
+	fd, ok := envFD(ListenFDEnv)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+
+	f := os.NewFile(uintptr(fd), "payram-listener")
+	if f == nil {
+		return nil, fmt.Errorf("inherited listener fd %d is invalid", fd)
+	}
+	defer f.Close() // FileListener duplicates the descriptor.
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("inherited listener: %w", err)
+	}
+	return ln, nil
+}
+
+// Ready tells the supervising agent that this process is accepting connections.
+// It is a no-op when the process was not started with a readiness pipe.
+func Ready() {
+	readyOnce.Do(func() {
+		fd, ok := envFD(ReadyFDEnv)
+		if !ok {
+			return
+		}
+		f := os.NewFile(uintptr(fd), "payram-ready")
+		if f == nil {
+			return
+		}
+		_, _ = f.Write([]byte("READY\n"))
+		_ = f.Close()
+	})
+}
+
+// ListenAndServe serves srv on the inherited or newly bound listener, reports readiness,
+// and shuts down gracefully (waiting up to drain for in-flight requests) when ctx is done.
+func ListenAndServe(ctx context.Context, srv *http.Server, drain time.Duration) error {
+	ln, err := Listen(srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	Ready()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		// Stop accepting before Shutdown so connections already accepted (or still queued on
+		// a socket shared with a replacement process) are served rather than dropped.
+		_ = ln.Close()
+		<-errCh
+		time.Sleep(min(acceptGrace, drain))
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), drain)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+func envFD(key string) (int, bool) {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0, false
+	}
+	fd, err := strconv.Atoi(v)
+	if err != nil || fd < 3 {
+		return 0, false
+	}
+	return fd, true
+}
@@ -0,0 +1,84 @@
+// Package httplimits holds the connection timeouts and header size cap shared by every HTTP
+// server in the project, so a slow or idle client cannot hold a connection open indefinitely.
+package httplimits
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits are the http.Server fields that bound a single connection. A zero duration disables
+// that timeout, as it does on http.Server.
+type Limits struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+}
+
+// Default suits request/response APIs. WriteTimeout leaves room for a chat request that goes
+// through several model rounds and tool calls before it answers.
+var Default = Limits{
+	ReadHeaderTimeout: 5 * time.Second,
+	ReadTimeout:       30 * time.Second,
+	WriteTimeout:      5 * time.Minute,
+	IdleTimeout:       2 * time.Minute,
+	MaxHeaderBytes:    64 << 10,
+}
+
+// FromEnv starts from def and applies <prefix>_READ_HEADER_TIMEOUT, <prefix>_READ_TIMEOUT,
+// <prefix>_WRITE_TIMEOUT, <prefix>_IDLE_TIMEOUT (Go durations) and <prefix>_MAX_HEADER_BYTES
+// where they are set.
+func FromEnv(prefix string, def Limits) (Limits, error) {
+	l := def
+	durations := []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"READ_HEADER_TIMEOUT", &l.ReadHeaderTimeout},
+		{"READ_TIMEOUT", &l.ReadTimeout},
+		{"WRITE_TIMEOUT", &l.WriteTimeout},
+		{"IDLE_TIMEOUT", &l.IdleTimeout},
+	}
+	for _, d := range durations {
+		key := prefix + "_" + d.name
+		raw := strings.TrimSpace(os.Getenv(key))
+		if raw == "" {
+			continue
+		}
+		v, err := time.ParseDuration(raw)
+		if err != nil || v < 0 {
+			return def, fmt.Errorf("%s: invalid duration %q", key, raw)
+		}
+		*d.dst = v
+	}
+	key := prefix + "_MAX_HEADER_BYTES"
+	if raw := strings.TrimSpace(os.Getenv(key)); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			return def, fmt.Errorf("%s: invalid byte count %q", key, raw)
+		}
+		l.MaxHeaderBytes = v
+	}
+	return l, nil
+}
+
+// Apply sets the limits on srv.
+func (l Limits) Apply(srv *http.Server) {
+	srv.ReadHeaderTimeout = l.ReadHeaderTimeout
+	srv.ReadTimeout = l.ReadTimeout
+	srv.WriteTimeout = l.WriteTimeout
+	srv.IdleTimeout = l.IdleTimeout
+	srv.MaxHeaderBytes = l.MaxHeaderBytes
+}
+
+// String summarizes the limits for startup logs.
+func (l Limits) String() string {
+	return fmt.Sprintf("read_header=%s read=%s write=%s idle=%s max_header_bytes=%d",
+		l.ReadHeaderTimeout, l.ReadTimeout, l.WriteTimeout, l.IdleTimeout, l.MaxHeaderBytes)
+}
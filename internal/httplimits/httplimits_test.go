@@ -0,0 +1,46 @@
+package httplimits
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFromEnvOverridesOnlySetValues(t *testing.T) {
+	t.Setenv("TEST_READ_TIMEOUT", "10s")
+	t.Setenv("TEST_IDLE_TIMEOUT", "0")
+	t.Setenv("TEST_MAX_HEADER_BYTES", "8192")
+
+	l, err := FromEnv("TEST", Default)
+	if err != nil {
+		t.Fatalf("FromEnv: %v", err)
+	}
+	want := Default
+	want.ReadTimeout = 10 * time.Second
+	want.IdleTimeout = 0
+	want.MaxHeaderBytes = 8192
+	if l != want {
+		t.Fatalf("limits = %+v, want %+v", l, want)
+	}
+
+	var srv http.Server
+	l.Apply(&srv)
+	if srv.ReadTimeout != 10*time.Second || srv.WriteTimeout != Default.WriteTimeout || srv.MaxHeaderBytes != 8192 {
+		t.Fatalf("Apply set read=%s write=%s max_header_bytes=%d", srv.ReadTimeout, srv.WriteTimeout, srv.MaxHeaderBytes)
+	}
+}
+
+func TestFromEnvRejectsInvalidValues(t *testing.T) {
+	for key, val := range map[string]string{
+		"TEST_WRITE_TIMEOUT":    "soon",
+		"TEST_READ_TIMEOUT":     "-1s",
+		"TEST_MAX_HEADER_BYTES": "0",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, val)
+			if _, err := FromEnv("TEST", Default); err == nil {
+				t.Fatalf("%s=%s accepted", key, val)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package i18n
+
+// catalog maps a language to translations of the fixed strings tools print, keyed by the
+// English format without trailing newlines. Translations keep the English verbs in order.
+var catalog = map[string]map[string]string{
+	"es": {
+		"# Live Payment Events (last %d minutes)": "# Eventos de pago en vivo (últimos %d minutos)",
+		"No payment webhook for reference_id %s in the last %d minutes. PayRam sends one whenever a payment changes state, so it has not been paid yet, or its webhook has not arrived.": "No hay ningún webhook de pago para reference_id %s en los últimos %d minutos. PayRam envía uno cada vez que un pago cambia de estado, así que aún no se ha pagado o su webhook no ha llegado.",
+		"No payment webhooks received in the last %d minutes.": "No se recibieron webhooks de pago en los últimos %d minutos.",
+		"# %s (group %d, graph %d, %s)":                        "# %s (grupo %d, gráfico %d, %s)",
+		"# error: %s":                                          "# error: %s",
+		"Started %s as background job %s. Check on it with payram_job_status (job_id %s); large exports take a few minutes.": "Se inició %s como tarea en segundo plano %s. Consulta su estado con payram_job_status (job_id %s); las exportaciones grandes tardan unos minutos.",
+		"Job %s (%s) is %s. Check again in a minute.": "La tarea %s (%s) está %s. Vuelve a consultar en un minuto.",
+		"Description: %s":                                        "Descripción: %s",
+		"# Available PayRam Analytics":                           "# Analíticas de PayRam disponibles",
+		"## Group: %s (ID: %d)":                                  "## Grupo: %s (ID: %d)",
+		"### Filters:":                                           "### Filtros:",
+		"### Graphs:":                                            "### Gráficos:",
+		"# Daily Statistics (Last %d Days)":                      "# Estadísticas diarias (últimos %d días)",
+		"# Daily Statistics (%s)":                                "# Estadísticas diarias (%s)",
+		"# Period Comparison: %s vs %s":                          "# Comparación de periodos: %s frente a %s",
+		"# %s Payment Data (%s)":                                 "# Datos de pagos en %s (%s)",
+		"# Currency Breakdown (grouped by %s, %s)":               "# Desglose por moneda (agrupado por %s, %s)",
+		"# User Growth Analysis (%s)":                            "# Análisis del crecimiento de usuarios (%s)",
+		"## Payments in USD":                                     "## Pagos en USD",
+		"## Number of Transactions":                              "## Número de transacciones",
+		"## %s\nError: %s":                                       "## %s\nError: %s",
+		"- %s: error fetching data":                              "- %s: error al obtener los datos",
+		"- %s: error fetching data (%s)":                         "- %s: error al obtener los datos (%s)",
+		"No data available for this period.":                     "No hay datos para este periodo.",
+		"No results.":                                            "Sin resultados.",
+		"Deposit Distribution (group %d):":                       "Distribución de depósitos (grupo %d):",
+		"Numbers Summary (group %d):":                            "Resumen de cifras (grupo %d):",
+		"Paying User Summary (group %d):":                        "Resumen de usuarios de pago (grupo %d):",
+		"Projects Summary (group %d):":                           "Resumen de proyectos (grupo %d):",
+		"Recent Transactions (group %d):":                        "Transacciones recientes (grupo %d):",
+		"Graph: %s (group: %s)\n":                                "Gráfico: %s (grupo: %s)\n",
+		"Graph Data (group_id=%d, graph_id=%d, date_filter=%s):": "Datos del gráfico (group_id=%d, graph_id=%d, date_filter=%s):",
+		"Transaction Counts - Per Day Breakdown (group %d, date_filter: %s):":                                                                       "Número de transacciones por día (grupo %d, date_filter: %s):",
+		"No %s transactions found in the selected period. The data might be grouped differently - try without currency_code to see all currencies.": "No se encontraron transacciones en %s en el periodo seleccionado. Puede que los datos estén agrupados de otra forma: prueba sin currency_code para ver todas las monedas.",
+		"Projects Summary analytics group not found. This group may not be available in the current environment.":                                   "No se encontró el grupo de analíticas Projects Summary. Puede que no esté disponible en este entorno.",
+		"## Previous period: %s to %s":  "## Periodo anterior: %s a %s",
+		"### Change in %s":              "### Variación de %s",
+		"No numeric values to compare.": "No hay valores numéricos para comparar.",
+		"Warning: %s no longer has the structure this tool expects (%s), so it is shown unparsed.":       "Aviso: %s ya no tiene la estructura que espera esta herramienta (%s), así que se muestra sin procesar.",
+		"Warning: %s no longer has the structure this tool expects (%s), so its change is not computed.": "Aviso: %s ya no tiene la estructura que espera esta herramienta (%s), así que no se calcula su variación.",
+		"## Compared with the previous period":                                                           "## Comparación con el periodo anterior",
+		"- %s: all-time figure, no previous period":                                                      "- %s: cifra histórica, sin periodo anterior",
+		"- %s: the graph ignores date ranges, so there is no previous period":                            "- %s: el gráfico ignora los rangos de fechas, así que no hay periodo anterior",
+		"# Weekly Cohort Retention (%d cohorts)":                                                         "# Retención semanal por cohortes (%d cohortes)",
+		"# Monthly Cohort Retention (%d cohorts)":                                                        "# Retención mensual por cohortes (%d cohortes)",
+		"Estimated: PayRam reports paying users as totals per period, so the returning users of each period are attributed to the earlier cohorts in proportion to their size. Returning users who first paid before the first cohort are attributed too, so early cohorts may read high.": "Estimación: PayRam informa de los usuarios de pago como totales por periodo, así que los usuarios recurrentes de cada periodo se atribuyen a las cohortes anteriores en proporción a su tamaño. También se atribuyen los recurrentes que pagaron por primera vez antes de la primera cohorte, así que las cohortes iniciales pueden salir altas.",
+		"## Paying users per period": "## Usuarios de pago por periodo",
+		"- %s: %s new, %s returning": "- %s: %s nuevos, %s recurrentes",
+		"## Retention matrix":        "## Matriz de retención",
+		"| Cohort | New users |":     "| Cohorte | Usuarios nuevos |",
+		"Truncated: %d of %d rows fetched. Narrow the date range or filters to see the rest.":           "Truncado: se obtuvieron %d de %d filas. Acota el rango de fechas o los filtros para ver el resto.",
+		"Truncated: %d rows fetched, and more exist. Narrow the date range or filters to see the rest.": "Truncado: se obtuvieron %d filas y hay más. Acota el rango de fechas o los filtros para ver el resto.",
+		"Complete: all %d rows fetched.":    "Completo: se obtuvieron las %d filas.",
+		"Stopped at the limit of %d pages.": "Detenido en el límite de %d páginas.",
+		"# What Changed (%s)":               "# Qué ha cambiado (%s)",
+		"No earlier snapshot to compare with. This one, taken %s, is the baseline for the next run.": "No hay ninguna instantánea anterior con la que comparar. Esta, tomada %s, será la referencia para la próxima ejecución.",
+		"Compared with the snapshot taken %s; moves of at least %s%% are listed.":                    "Comparado con la instantánea tomada %s; se muestran los cambios de al menos %s%%.",
+		"## Moved":                                  "## Cambios",
+		"Nothing moved by %s%% or more.":            "Nada cambió un %s%% o más.",
+		"%d other metrics moved by less than %s%%.": "%d métricas más cambiaron menos de un %s%%.",
+		"## New currencies":                         "## Monedas nuevas",
+		"- %s: %s in volume":                        "- %s: %s de volumen",
+		"## Currencies no longer seen":              "## Monedas que ya no aparecen",
+		"Payment volume (USD)":                      "Volumen de pagos (USD)",
+		"Transactions":                              "Transacciones",
+		"%s volume":                                 "Volumen en %s",
+		"%s transactions":                           "Transacciones en %s",
+	},
+	"fr": {
+		"# Live Payment Events (last %d minutes)": "# Événements de paiement en direct (%d dernières minutes)",
+		"No payment webhook for reference_id %s in the last %d minutes. PayRam sends one whenever a payment changes state, so it has not been paid yet, or its webhook has not arrived.": "Aucun webhook de paiement pour reference_id %s dans les %d dernières minutes. PayRam en envoie un à chaque changement d'état d'un paiement : il n'est donc pas encore payé, ou son webhook n'est pas arrivé.",
+		"No payment webhooks received in the last %d minutes.": "Aucun webhook de paiement reçu dans les %d dernières minutes.",
+		"# %s (group %d, graph %d, %s)":                        "# %s (groupe %d, graphique %d, %s)",
+		"# error: %s":                                          "# erreur : %s",
+		"Started %s as background job %s. Check on it with payram_job_status (job_id %s); large exports take a few minutes.": "%s a été lancé en tâche de fond %s. Suivez-la avec payram_job_status (job_id %s) ; les gros exports prennent quelques minutes.",
+		"Job %s (%s) is %s. Check again in a minute.": "La tâche %s (%s) est %s. Réessayez dans une minute.",
+		"Description: %s":                                        "Description : %s",
+		"# Available PayRam Analytics":                           "# Analyses PayRam disponibles",
+		"## Group: %s (ID: %d)":                                  "## Groupe : %s (ID : %d)",
+		"### Filters:":                                           "### Filtres :",
+		"### Graphs:":                                            "### Graphiques :",
+		"# Daily Statistics (Last %d Days)":                      "# Statistiques quotidiennes (%d derniers jours)",
+		"# Daily Statistics (%s)":                                "# Statistiques quotidiennes (%s)",
+		"# Period Comparison: %s vs %s":                          "# Comparaison de périodes : %s et %s",
+		"# %s Payment Data (%s)":                                 "# Données de paiement %s (%s)",
+		"# Currency Breakdown (grouped by %s, %s)":               "# Répartition par devise (groupée par %s, %s)",
+		"# User Growth Analysis (%s)":                            "# Analyse de la croissance des utilisateurs (%s)",
+		"## Payments in USD":                                     "## Paiements en USD",
+		"## Number of Transactions":                              "## Nombre de transactions",
+		"## %s\nError: %s":                                       "## %s\nErreur : %s",
+		"- %s: error fetching data":                              "- %s : erreur lors de la récupération des données",
+		"- %s: error fetching data (%s)":                         "- %s : erreur lors de la récupération des données (%s)",
+		"No data available for this period.":                     "Aucune donnée pour cette période.",
+		"No results.":                                            "Aucun résultat.",
+		"Deposit Distribution (group %d):":                       "Répartition des dépôts (groupe %d) :",
+		"Numbers Summary (group %d):":                            "Résumé des chiffres (groupe %d) :",
+		"Paying User Summary (group %d):":                        "Résumé des utilisateurs payants (groupe %d) :",
+		"Projects Summary (group %d):":                           "Résumé des projets (groupe %d) :",
+		"Recent Transactions (group %d):":                        "Transactions récentes (groupe %d) :",
+		"Graph: %s (group: %s)\n":                                "Graphique : %s (groupe : %s)\n",
+		"Graph Data (group_id=%d, graph_id=%d, date_filter=%s):": "Données du graphique (group_id=%d, graph_id=%d, date_filter=%s) :",
+		"Transaction Counts - Per Day Breakdown (group %d, date_filter: %s):":                                                                       "Nombre de transactions par jour (groupe %d, date_filter : %s) :",
+		"No %s transactions found in the selected period. The data might be grouped differently - try without currency_code to see all currencies.": "Aucune transaction %s sur la période sélectionnée. Les données sont peut-être regroupées autrement : réessayez sans currency_code pour voir toutes les devises.",
+		"Projects Summary analytics group not found. This group may not be available in the current environment.":                                   "Groupe d'analyses Projects Summary introuvable. Il n'est peut-être pas disponible dans cet environnement.",
+		"## Previous period: %s to %s":  "## Période précédente : %s au %s",
+		"### Change in %s":              "### Évolution de %s",
+		"No numeric values to compare.": "Aucune valeur numérique à comparer.",
+		"Warning: %s no longer has the structure this tool expects (%s), so it is shown unparsed.":       "Attention : %s n'a plus la structure attendue par cet outil (%s), il est donc affiché brut.",
+		"Warning: %s no longer has the structure this tool expects (%s), so its change is not computed.": "Attention : %s n'a plus la structure attendue par cet outil (%s), sa variation n'est donc pas calculée.",
+		"## Compared with the previous period":                                                           "## Comparaison avec la période précédente",
+		"- %s: all-time figure, no previous period":                                                      "- %s : chiffre cumulé, pas de période précédente",
+		"- %s: the graph ignores date ranges, so there is no previous period":                            "- %s : le graphique ignore les plages de dates, il n'y a donc pas de période précédente",
+		"# Weekly Cohort Retention (%d cohorts)":                                                         "# Rétention hebdomadaire par cohorte (%d cohortes)",
+		"# Monthly Cohort Retention (%d cohorts)":                                                        "# Rétention mensuelle par cohorte (%d cohortes)",
+		"Estimated: PayRam reports paying users as totals per period, so the returning users of each period are attributed to the earlier cohorts in proportion to their size. Returning users who first paid before the first cohort are attributed too, so early cohorts may read high.": "Estimation : PayRam indique les utilisateurs payants en totaux par période, les utilisateurs récurrents de chaque période sont donc attribués aux cohortes précédentes au prorata de leur taille. Les récurrents ayant payé pour la première fois avant la première cohorte sont aussi attribués, les premières cohortes peuvent donc paraître élevées.",
+		"## Paying users per period": "## Utilisateurs payants par période",
+		"- %s: %s new, %s returning": "- %s : %s nouveaux, %s récurrents",
+		"## Retention matrix":        "## Matrice de rétention",
+		"| Cohort | New users |":     "| Cohorte | Nouveaux utilisateurs |",
+		"Truncated: %d of %d rows fetched. Narrow the date range or filters to see the rest.":           "Tronqué : %d lignes sur %d récupérées. Réduisez la période ou les filtres pour voir le reste.",
+		"Truncated: %d rows fetched, and more exist. Narrow the date range or filters to see the rest.": "Tronqué : %d lignes récupérées, et il en reste d'autres. Réduisez la période ou les filtres pour voir le reste.",
+		"Complete: all %d rows fetched.":    "Complet : les %d lignes ont été récupérées.",
+		"Stopped at the limit of %d pages.": "Arrêté à la limite de %d pages.",
+		"# What Changed (%s)":               "# Ce qui a changé (%s)",
+		"No earlier snapshot to compare with. This one, taken %s, is the baseline for the next run.": "Aucun instantané antérieur à comparer. Celui-ci, pris %s, sert de référence pour la prochaine exécution.",
+		"Compared with the snapshot taken %s; moves of at least %s%% are listed.":                    "Comparé à l'instantané pris %s ; les variations d'au moins %s%% sont listées.",
+		"## Moved":                                  "## Variations",
+		"Nothing moved by %s%% or more.":            "Rien n'a varié de %s%% ou plus.",
+		"%d other metrics moved by less than %s%%.": "%d autres métriques ont varié de moins de %s%%.",
+		"## New currencies":                         "## Nouvelles devises",
+		"- %s: %s in volume":                        "- %s : %s de volume",
+		"## Currencies no longer seen":              "## Devises disparues",
+		"Payment volume (USD)":                      "Volume des paiements (USD)",
+		"Transactions":                              "Transactions",
+		"%s volume":                                 "Volume en %s",
+		"%s transactions":                           "Transactions en %s",
+	},
+	"de": {
+		"# Live Payment Events (last %d minutes)": "# Live-Zahlungsereignisse (letzte %d Minuten)",
+		"No payment webhook for reference_id %s in the last %d minutes. PayRam sends one whenever a payment changes state, so it has not been paid yet, or its webhook has not arrived.": "Kein Zahlungs-Webhook für reference_id %s in den letzten %d Minuten. PayRam sendet einen bei jeder Statusänderung einer Zahlung, sie ist also noch nicht bezahlt oder ihr Webhook ist noch nicht eingegangen.",
+		"No payment webhooks received in the last %d minutes.": "In den letzten %d Minuten sind keine Zahlungs-Webhooks eingegangen.",
+		"# %s (group %d, graph %d, %s)":                        "# %s (Gruppe %d, Diagramm %d, %s)",
+		"# error: %s":                                          "# Fehler: %s",
+		"Started %s as background job %s. Check on it with payram_job_status (job_id %s); large exports take a few minutes.": "%s wurde als Hintergrundauftrag %s gestartet. Den Stand zeigt payram_job_status (job_id %s); große Exporte dauern ein paar Minuten.",
+		"Job %s (%s) is %s. Check again in a minute.": "Auftrag %s (%s) ist %s. Bitte in einer Minute erneut prüfen.",
+		"Description: %s":                                        "Beschreibung: %s",
+		"# Available PayRam Analytics":                           "# Verfügbare PayRam-Analysen",
+		"## Group: %s (ID: %d)":                                  "## Gruppe: %s (ID: %d)",
+		"### Filters:":                                           "### Filter:",
+		"### Graphs:":                                            "### Diagramme:",
+		"# Daily Statistics (Last %d Days)":                      "# Tägliche Statistik (letzte %d Tage)",
+		"# Daily Statistics (%s)":                                "# Tägliche Statistik (%s)",
+		"# Period Comparison: %s vs %s":                          "# Zeitraumvergleich: %s und %s",
+		"# %s Payment Data (%s)":                                 "# %s-Zahlungsdaten (%s)",
+		"# Currency Breakdown (grouped by %s, %s)":               "# Aufschlüsselung nach Währung (gruppiert nach %s, %s)",
+		"# User Growth Analysis (%s)":                            "# Analyse des Nutzerwachstums (%s)",
+		"## Payments in USD":                                     "## Zahlungen in USD",
+		"## Number of Transactions":                              "## Anzahl der Transaktionen",
+		"## %s\nError: %s":                                       "## %s\nFehler: %s",
+		"- %s: error fetching data":                              "- %s: Fehler beim Abrufen der Daten",
+		"- %s: error fetching data (%s)":                         "- %s: Fehler beim Abrufen der Daten (%s)",
+		"No data available for this period.":                     "Für diesen Zeitraum liegen keine Daten vor.",
+		"No results.":                                            "Keine Ergebnisse.",
+		"Deposit Distribution (group %d):":                       "Verteilung der Einzahlungen (Gruppe %d):",
+		"Numbers Summary (group %d):":                            "Kennzahlenübersicht (Gruppe %d):",
+		"Paying User Summary (group %d):":                        "Übersicht zahlender Nutzer (Gruppe %d):",
+		"Projects Summary (group %d):":                           "Projektübersicht (Gruppe %d):",
+		"Recent Transactions (group %d):":                        "Letzte Transaktionen (Gruppe %d):",
+		"Graph: %s (group: %s)\n":                                "Diagramm: %s (Gruppe: %s)\n",
+		"Graph Data (group_id=%d, graph_id=%d, date_filter=%s):": "Diagrammdaten (group_id=%d, graph_id=%d, date_filter=%s):",
+		"Transaction Counts - Per Day Breakdown (group %d, date_filter: %s):":                                                                       "Transaktionen pro Tag (Gruppe %d, date_filter: %s):",
+		"No %s transactions found in the selected period. The data might be grouped differently - try without currency_code to see all currencies.": "Im gewählten Zeitraum wurden keine %s-Transaktionen gefunden. Die Daten sind möglicherweise anders gruppiert – versuchen Sie es ohne currency_code, um alle Währungen zu sehen.",
+		"Projects Summary analytics group not found. This group may not be available in the current environment.":                                   "Analysegruppe Projects Summary nicht gefunden. Sie ist in dieser Umgebung möglicherweise nicht verfügbar.",
+		"## Previous period: %s to %s":  "## Vorheriger Zeitraum: %s bis %s",
+		"### Change in %s":              "### Veränderung bei %s",
+		"No numeric values to compare.": "Keine Zahlenwerte zum Vergleichen.",
+		"Warning: %s no longer has the structure this tool expects (%s), so it is shown unparsed.":       "Warnung: %s hat nicht mehr die Struktur, die dieses Tool erwartet (%s), und wird daher unverarbeitet angezeigt.",
+		"Warning: %s no longer has the structure this tool expects (%s), so its change is not computed.": "Warnung: %s hat nicht mehr die Struktur, die dieses Tool erwartet (%s), daher wird die Veränderung nicht berechnet.",
+		"## Compared with the previous period":                                                           "## Vergleich mit dem vorherigen Zeitraum",
+		"- %s: all-time figure, no previous period":                                                      "- %s: Gesamtwert, kein vorheriger Zeitraum",
+		"- %s: the graph ignores date ranges, so there is no previous period":                            "- %s: Das Diagramm ignoriert Datumsbereiche, daher gibt es keinen vorherigen Zeitraum",
+		"# Weekly Cohort Retention (%d cohorts)":                                                         "# Wöchentliche Kohortenbindung (%d Kohorten)",
+		"# Monthly Cohort Retention (%d cohorts)":                                                        "# Monatliche Kohortenbindung (%d Kohorten)",
+		"Estimated: PayRam reports paying users as totals per period, so the returning users of each period are attributed to the earlier cohorts in proportion to their size. Returning users who first paid before the first cohort are attributed too, so early cohorts may read high.": "Geschätzt: PayRam meldet zahlende Nutzer als Summen pro Zeitraum, daher werden die wiederkehrenden Nutzer jedes Zeitraums den früheren Kohorten im Verhältnis zu ihrer Größe zugeordnet. Wiederkehrende Nutzer, die vor der ersten Kohorte erstmals bezahlt haben, werden ebenfalls zugeordnet, frühe Kohorten können daher zu hoch ausfallen.",
+		"## Paying users per period": "## Zahlende Nutzer pro Zeitraum",
+		"- %s: %s new, %s returning": "- %s: %s neu, %s wiederkehrend",
+		"## Retention matrix":        "## Bindungsmatrix",
+		"| Cohort | New users |":     "| Kohorte | Neue Nutzer |",
+		"Truncated: %d of %d rows fetched. Narrow the date range or filters to see the rest.":           "Gekürzt: %d von %d Zeilen abgerufen. Grenzen Sie Zeitraum oder Filter ein, um den Rest zu sehen.",
+		"Truncated: %d rows fetched, and more exist. Narrow the date range or filters to see the rest.": "Gekürzt: %d Zeilen abgerufen, es gibt weitere. Grenzen Sie Zeitraum oder Filter ein, um den Rest zu sehen.",
+		"Complete: all %d rows fetched.":    "Vollständig: alle %d Zeilen abgerufen.",
+		"Stopped at the limit of %d pages.": "Beim Limit von %d Seiten angehalten.",
+		"# What Changed (%s)":               "# Was sich geändert hat (%s)",
+		"No earlier snapshot to compare with. This one, taken %s, is the baseline for the next run.": "Kein früherer Schnappschuss zum Vergleich. Dieser, aufgenommen %s, ist die Grundlage für den nächsten Lauf.",
+		"Compared with the snapshot taken %s; moves of at least %s%% are listed.":                    "Verglichen mit dem Schnappschuss vom %s; Änderungen ab %s%% werden aufgeführt.",
+		"## Moved":                                  "## Geändert",
+		"Nothing moved by %s%% or more.":            "Nichts hat sich um %s%% oder mehr geändert.",
+		"%d other metrics moved by less than %s%%.": "%d weitere Kennzahlen haben sich um weniger als %s%% geändert.",
+		"## New currencies":                         "## Neue Währungen",
+		"- %s: %s in volume":                        "- %s: %s Volumen",
+		"## Currencies no longer seen":              "## Nicht mehr gesehene Währungen",
+		"Payment volume (USD)":                      "Zahlungsvolumen (USD)",
+		"Transactions":                              "Transaktionen",
+		"%s volume":                                 "Volumen in %s",
+		"%s transactions":                           "Transaktionen in %s",
+	},
+	"pt": {
+		"# Live Payment Events (last %d minutes)": "# Eventos de pagamento ao vivo (últimos %d minutos)",
+		"No payment webhook for reference_id %s in the last %d minutes. PayRam sends one whenever a payment changes state, so it has not been paid yet, or its webhook has not arrived.": "Nenhum webhook de pagamento para reference_id %s nos últimos %d minutos. O PayRam envia um sempre que um pagamento muda de estado, então ele ainda não foi pago ou o webhook não chegou.",
+		"No payment webhooks received in the last %d minutes.": "Nenhum webhook de pagamento recebido nos últimos %d minutos.",
+		"# %s (group %d, graph %d, %s)":                        "# %s (grupo %d, gráfico %d, %s)",
+		"# error: %s":                                          "# erro: %s",
+		"Started %s as background job %s. Check on it with payram_job_status (job_id %s); large exports take a few minutes.": "%s foi iniciado como tarefa em segundo plano %s. Acompanhe com payram_job_status (job_id %s); exportações grandes levam alguns minutos.",
+		"Job %s (%s) is %s. Check again in a minute.": "A tarefa %s (%s) está %s. Verifique de novo em um minuto.",
+		"Description: %s":                                        "Descrição: %s",
+		"# Available PayRam Analytics":                           "# Análises do PayRam disponíveis",
+		"## Group: %s (ID: %d)":                                  "## Grupo: %s (ID: %d)",
+		"### Filters:":                                           "### Filtros:",
+		"### Graphs:":                                            "### Gráficos:",
+		"# Daily Statistics (Last %d Days)":                      "# Estatísticas diárias (últimos %d dias)",
+		"# Daily Statistics (%s)":                                "# Estatísticas diárias (%s)",
+		"# Period Comparison: %s vs %s":                          "# Comparação de períodos: %s vs %s",
+		"# %s Payment Data (%s)":                                 "# Dados de pagamentos em %s (%s)",
+		"# Currency Breakdown (grouped by %s, %s)":               "# Detalhamento por moeda (agrupado por %s, %s)",
+		"# User Growth Analysis (%s)":                            "# Análise do crescimento de usuários (%s)",
+		"## Payments in USD":                                     "## Pagamentos em USD",
+		"## Number of Transactions":                              "## Número de transações",
+		"## %s\nError: %s":                                       "## %s\nErro: %s",
+		"- %s: error fetching data":                              "- %s: erro ao buscar os dados",
+		"- %s: error fetching data (%s)":                         "- %s: erro ao buscar os dados (%s)",
+		"No data available for this period.":                     "Não há dados para este período.",
+		"No results.":                                            "Nenhum resultado.",
+		"Deposit Distribution (group %d):":                       "Distribuição de depósitos (grupo %d):",
+		"Numbers Summary (group %d):":                            "Resumo dos números (grupo %d):",
+		"Paying User Summary (group %d):":                        "Resumo de usuários pagantes (grupo %d):",
+		"Projects Summary (group %d):":                           "Resumo de projetos (grupo %d):",
+		"Recent Transactions (group %d):":                        "Transações recentes (grupo %d):",
+		"Graph: %s (group: %s)\n":                                "Gráfico: %s (grupo: %s)\n",
+		"Graph Data (group_id=%d, graph_id=%d, date_filter=%s):": "Dados do gráfico (group_id=%d, graph_id=%d, date_filter=%s):",
+		"Transaction Counts - Per Day Breakdown (group %d, date_filter: %s):":                                                                       "Número de transações por dia (grupo %d, date_filter: %s):",
+		"No %s transactions found in the selected period. The data might be grouped differently - try without currency_code to see all currencies.": "Nenhuma transação em %s encontrada no período selecionado. Os dados podem estar agrupados de outra forma; tente sem currency_code para ver todas as moedas.",
+		"Projects Summary analytics group not found. This group may not be available in the current environment.":                                   "Grupo de análises Projects Summary não encontrado. Ele pode não estar disponível neste ambiente.",
+		"## Previous period: %s to %s":  "## Período anterior: %s a %s",
+		"### Change in %s":              "### Variação de %s",
+		"No numeric values to compare.": "Não há valores numéricos para comparar.",
+		"Warning: %s no longer has the structure this tool expects (%s), so it is shown unparsed.":       "Aviso: %s não tem mais a estrutura que esta ferramenta espera (%s), por isso é mostrado sem processamento.",
+		"Warning: %s no longer has the structure this tool expects (%s), so its change is not computed.": "Aviso: %s não tem mais a estrutura que esta ferramenta espera (%s), por isso sua variação não é calculada.",
+		"## Compared with the previous period":                                                           "## Comparação com o período anterior",
+		"- %s: all-time figure, no previous period":                                                      "- %s: valor acumulado, sem período anterior",
+		"- %s: the graph ignores date ranges, so there is no previous period":                            "- %s: o gráfico ignora intervalos de datas, então não há período anterior",
+		"# Weekly Cohort Retention (%d cohorts)":                                                         "# Retenção semanal por coorte (%d coortes)",
+		"# Monthly Cohort Retention (%d cohorts)":                                                        "# Retenção mensal por coorte (%d coortes)",
+		"Estimated: PayRam reports paying users as totals per period, so the returning users of each period are attributed to the earlier cohorts in proportion to their size. Returning users who first paid before the first cohort are attributed too, so early cohorts may read high.": "Estimativa: o PayRam informa os usuários pagantes como totais por período, então os usuários recorrentes de cada período são atribuídos às coortes anteriores na proporção do seu tamanho. Recorrentes que pagaram pela primeira vez antes da primeira coorte também são atribuídos, então as coortes iniciais podem parecer altas.",
+		"## Paying users per period": "## Usuários pagantes por período",
+		"- %s: %s new, %s returning": "- %s: %s novos, %s recorrentes",
+		"## Retention matrix":        "## Matriz de retenção",
+		"| Cohort | New users |":     "| Coorte | Novos usuários |",
+		"Truncated: %d of %d rows fetched. Narrow the date range or filters to see the rest.":           "Truncado: %d de %d linhas obtidas. Restrinja o período ou os filtros para ver o restante.",
+		"Truncated: %d rows fetched, and more exist. Narrow the date range or filters to see the rest.": "Truncado: %d linhas obtidas, e há mais. Restrinja o período ou os filtros para ver o restante.",
+		"Complete: all %d rows fetched.":    "Completo: todas as %d linhas obtidas.",
+		"Stopped at the limit of %d pages.": "Interrompido no limite de %d páginas.",
+		"# What Changed (%s)":               "# O que mudou (%s)",
+		"No earlier snapshot to compare with. This one, taken %s, is the baseline for the next run.": "Não há instantâneo anterior para comparar. Este, tirado %s, é a referência para a próxima execução.",
+		"Compared with the snapshot taken %s; moves of at least %s%% are listed.":                    "Comparado com o instantâneo tirado %s; variações de pelo menos %s%% são listadas.",
+		"## Moved":                                  "## Variações",
+		"Nothing moved by %s%% or more.":            "Nada variou %s%% ou mais.",
+		"%d other metrics moved by less than %s%%.": "%d outras métricas variaram menos de %s%%.",
+		"## New currencies":                         "## Novas moedas",
+		"- %s: %s in volume":                        "- %s: %s em volume",
+		"## Currencies no longer seen":              "## Moedas que não aparecem mais",
+		"Payment volume (USD)":                      "Volume de pagamentos (USD)",
+		"Transactions":                              "Transações",
+		"%s volume":                                 "Volume em %s",
+		"%s transactions":                           "Transações em %s",
+	},
+}
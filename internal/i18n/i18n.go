@@ -0,0 +1,88 @@
+// Package i18n carries the language a caller wants answers in, from the chat API through the
+// MCP server to the tools, and translates the fixed strings tools print.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Header carries the response language on chat API and MCP requests, as a BCP 47 tag such
+// as "es" or "pt-BR".
+const Header = "X-Language"
+
+// Default is the language tools write in when none is requested.
+const Default = "en"
+
+var tagPattern = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$`)
+
+// Parse normalizes a language tag to lower case with "-" separators, e.g. "pt_BR" to "pt-br".
+// It reports false for anything that is not a tag.
+func Parse(tag string) (string, bool) {
+	tag = strings.TrimSpace(tag)
+	if !tagPattern.MatchString(tag) {
+		return "", false
+	}
+	return strings.ToLower(strings.ReplaceAll(tag, "_", "-")), true
+}
+
+// base returns the primary subtag of a parsed tag: "pt" for "pt-br".
+func base(tag string) string {
+	b, _, _ := strings.Cut(tag, "-")
+	return b
+}
+
+var names = map[string]string{
+	"ar": "Arabic", "de": "German", "en": "English", "es": "Spanish", "fr": "French",
+	"hi": "Hindi", "id": "Indonesian", "it": "Italian", "ja": "Japanese", "ko": "Korean",
+	"nl": "Dutch", "pl": "Polish", "pt": "Portuguese", "ru": "Russian", "th": "Thai",
+	"tr": "Turkish", "uk": "Ukrainian", "vi": "Vietnamese", "zh": "Chinese",
+}
+
+// Name returns the English name of a parsed tag's language for use in prompts, or the tag
+// itself when the language is not one we know by name.
+func Name(tag string) string {
+	if n, ok := names[base(tag)]; ok {
+		return n
+	}
+	return tag
+}
+
+// IsDefault reports whether tag asks for the default language.
+func IsDefault(tag string) bool {
+	return tag == "" || base(tag) == Default
+}
+
+type ctxKey struct{}
+
+// WithLanguage returns ctx carrying the parsed tag.
+func WithLanguage(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tag)
+}
+
+// FromContext returns the tag set with WithLanguage, or "" when none was.
+func FromContext(ctx context.Context) string {
+	tag, _ := ctx.Value(ctxKey{}).(string)
+	return tag
+}
+
+// Sprintf formats the translation of format into ctx's language. Formats without a
+// translation, and all formats in the default language, are used as they are.
+func Sprintf(ctx context.Context, format string, args ...any) string {
+	return fmt.Sprintf(Translate(FromContext(ctx), format), args...)
+}
+
+// Translate returns the catalog translation of format into tag's language. Trailing newlines
+// are not part of catalog keys, so layout stays with the caller.
+func Translate(tag, format string) string {
+	if IsDefault(tag) {
+		return format
+	}
+	text := strings.TrimRight(format, "\n")
+	if t, ok := catalog[base(tag)][text]; ok {
+		return t + format[len(text):]
+	}
+	return format
+}
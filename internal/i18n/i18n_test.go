@@ -0,0 +1,51 @@
+package i18n
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]string{"es": "es", " pt_BR ": "pt-br", "zh-Hant-TW": "zh-hant-tw"}
+	for in, want := range cases {
+		if got, ok := Parse(in); !ok || got != want {
+			t.Errorf("Parse(%q) = %q, %t; want %q", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "e", "spanish please", "es;q=0.9", "-es"} {
+		if got, ok := Parse(in); ok {
+			t.Errorf("Parse(%q) = %q, want rejection", in, got)
+		}
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	ctx := WithLanguage(context.Background(), "es-mx")
+	if got := Sprintf(ctx, "# Daily Statistics (Last %d Days)\n\n", 7); got != "# Estadísticas diarias (últimos 7 días)\n\n" {
+		t.Errorf("regional tag: %q", got)
+	}
+	if got := Sprintf(ctx, "untranslated %d", 1); got != "untranslated 1" {
+		t.Errorf("missing translation: %q", got)
+	}
+	if got := Sprintf(WithLanguage(context.Background(), "ja"), "No results."); got != "No results." {
+		t.Errorf("language without a catalog: %q", got)
+	}
+	if got := Sprintf(context.Background(), "No results."); got != "No results." {
+		t.Errorf("no language: %q", got)
+	}
+}
+
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+// TestCatalogKeepsVerbs guards against translations that would misformat their arguments.
+func TestCatalogKeepsVerbs(t *testing.T) {
+	for lang, entries := range catalog {
+		for key, text := range entries {
+			if !slices.Equal(verbPattern.FindAllString(key, -1), verbPattern.FindAllString(text, -1)) {
+				t.Errorf("%s: %q translates %q with different verbs", lang, text, key)
+			}
+		}
+	}
+}
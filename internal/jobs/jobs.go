@@ -0,0 +1,281 @@
+// Package jobs runs slow tool calls in the background, so callers get a job handle instead
+// of waiting past HTTP timeouts. Job state can be kept in a JSON file across restarts.
+//
+// The file is plain JSON rather than SQLite: the module has no SQLite driver, and the
+// cgo-free one would add a large dependency tree to every binary for a store that holds at
+// most a day of jobs. Each save writes a temporary file, syncs it and renames it over the
+// old one, so a crash leaves either the previous state or the new one.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/atomicfile"
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// Job states.
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// ErrFull is returned by Submit when the backlog of queued jobs is full.
+var ErrFull = errors.New("job queue is full")
+
+// Job is one background tool call and, once finished, its result or error.
+type Job struct {
+	ID       string                  `json:"id"`
+	Tool     string                  `json:"tool"`
+	Tenant   string                  `json:"tenant,omitempty"`
+	Status   string                  `json:"status"`
+	Created  time.Time               `json:"created_at"`
+	Started  time.Time               `json:"started_at,omitzero"`
+	Finished time.Time               `json:"finished_at,omitzero"`
+	Result   *protocol.CallResult    `json:"result,omitempty"`
+	Error    *protocol.ResponseError `json:"error,omitempty"`
+}
+
+// Ended reports whether the job is done or failed.
+func (j Job) Ended() bool {
+	return j.Status == StatusDone || j.Status == StatusFailed
+}
+
+// Func is the work of a job.
+type Func func(ctx context.Context) (protocol.CallResult, *protocol.ResponseError)
+
+// Options configures a Queue; zero fields take the defaults below.
+type Options struct {
+	Workers int           // jobs run at once (2)
+	Backlog int           // jobs waiting to run before Submit fails (100)
+	Timeout time.Duration // time one job may run (10m)
+	Retain  time.Duration // how long finished jobs are kept (24h)
+}
+
+const (
+	defaultWorkers = 2
+	defaultBacklog = 100
+	defaultTimeout = 10 * time.Minute
+	defaultRetain  = 24 * time.Hour
+)
+
+type task struct {
+	id  string
+	ctx context.Context
+	fn  Func
+}
+
+// Queue runs submitted jobs on a fixed pool of workers.
+type Queue struct {
+	mu   sync.Mutex
+	path string
+	jobs map[string]*Job
+	clk  clock.Clock
+	opts Options
+
+	work   chan task
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Open loads jobs from file, or keeps them in memory only when file is empty, and starts the
+// workers. Jobs that were queued or running when the process stopped are marked failed:
+// their work was lost with it.
+func Open(file string, opts Options) (*Queue, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.Backlog <= 0 {
+		opts.Backlog = defaultBacklog
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Retain <= 0 {
+		opts.Retain = defaultRetain
+	}
+	q := &Queue{
+		path: strings.TrimSpace(file),
+		jobs: map[string]*Job{},
+		clk:  clock.System,
+		opts: opts,
+		work: make(chan task, opts.Backlog),
+	}
+	if q.path != "" {
+		raw, err := os.ReadFile(q.path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read jobs: %w", err)
+		}
+		if len(raw) > 0 {
+			var saved []*Job
+			if err := json.Unmarshal(raw, &saved); err != nil {
+				return nil, fmt.Errorf("decode jobs: %w", err)
+			}
+			now := q.clk.Now().UTC()
+			for _, j := range saved {
+				if !j.Ended() {
+					j.Status = StatusFailed
+					j.Finished = now
+					j.Error = protocol.NewError(protocol.CategoryUpstreamUnavailable, "The job was interrupted by a server restart. Submit it again.")
+				}
+				q.jobs[j.ID] = j
+			}
+		}
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	for range opts.Workers {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	return q, nil
+}
+
+// Close stops the workers, cancelling running jobs, and waits for them to exit.
+func (q *Queue) Close() {
+	q.cancel()
+	q.wg.Wait()
+}
+
+// SetClock replaces the clock job timestamps come from.
+func (q *Queue) SetClock(c clock.Clock) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.clk = c
+}
+
+// Submit queues fn as a job for tool, owned by tenantID (empty without tenancy). The job keeps
+// ctx's values, such as the tenant and language, but not its cancellation, so it outlives the
+// request that submitted it.
+func (q *Queue) Submit(ctx context.Context, tool, tenantID string, fn Func) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// Only Submit sends on q.work, under q.mu, so a free slot stays free until the send.
+	if len(q.work) == cap(q.work) {
+		return Job{}, ErrFull
+	}
+	j := &Job{ID: id, Tool: tool, Tenant: tenantID, Status: StatusQueued, Created: q.clk.Now().UTC()}
+	q.jobs[id] = j
+	if err := q.save(); err != nil {
+		delete(q.jobs, id)
+		return Job{}, err
+	}
+	q.work <- task{id: id, ctx: context.WithoutCancel(ctx), fn: fn}
+	return *j, nil
+}
+
+// Get returns the job with id.
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *j, true
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case t := <-q.work:
+			q.run(t)
+		}
+	}
+}
+
+func (q *Queue) run(t task) {
+	if !q.update(t.id, func(j *Job, now time.Time) {
+		j.Status = StatusRunning
+		j.Started = now
+	}) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(t.ctx, q.opts.Timeout)
+	stop := context.AfterFunc(q.ctx, cancel)
+	result, errResp := t.fn(ctx)
+	stop()
+	cancel()
+	q.update(t.id, func(j *Job, now time.Time) {
+		j.Finished = now
+		if errResp != nil {
+			j.Status = StatusFailed
+			j.Error = errResp
+			return
+		}
+		j.Status = StatusDone
+		j.Result = &result
+	})
+}
+
+// update applies fn to the job with id, prunes expired jobs and saves. It reports false when
+// the job no longer exists.
+func (q *Queue) update(id string, fn func(*Job, time.Time)) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return false
+	}
+	now := q.clk.Now().UTC()
+	fn(j, now)
+	for id, old := range q.jobs {
+		if old.Ended() && now.Sub(old.Finished) > q.opts.Retain {
+			delete(q.jobs, id)
+		}
+	}
+	_ = q.save()
+	return true
+}
+
+// save writes all jobs to q.path, oldest first. Callers hold q.mu.
+func (q *Queue) save() error {
+	if q.path == "" {
+		return nil
+	}
+	list := make([]*Job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		list = append(list, j)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Created.Before(list[b].Created) })
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("encode jobs: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(q.path), err)
+	}
+	if err := atomicfile.WriteFile(q.path, data); err != nil {
+		return fmt.Errorf("write jobs: %w", err)
+	}
+	return nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("job id: %w", err)
+	}
+	return "job_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+type ctxKey struct{}
+
+func waitEnded(t *testing.T, q *Queue, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if j, ok := q.Get(id); ok && j.Ended() {
+			return j
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestJobRunsAfterRequestEnds(t *testing.T) {
+	q, err := Open("", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "acme"))
+	job, err := q.Submit(ctx, "export", "acme", func(ctx context.Context) (protocol.CallResult, *protocol.ResponseError) {
+		if err := ctx.Err(); err != nil {
+			return protocol.CallResult{}, protocol.Errorf(protocol.CategoryInternal, "cancelled: %v", err)
+		}
+		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: ctx.Value(ctxKey{}).(string)}}}, nil
+	})
+	cancel()
+	if err != nil || job.Status != StatusQueued || job.Tenant != "acme" {
+		t.Fatalf("submit: %+v, %v", job, err)
+	}
+	done := waitEnded(t, q, job.ID)
+	if done.Status != StatusDone || done.Result.Content[0].Text != "acme" || done.Started.IsZero() {
+		t.Fatalf("finished job: %+v (error %+v)", done, done.Error)
+	}
+}
+
+func TestSubmitFailsWhenBacklogIsFull(t *testing.T) {
+	q, err := Open("", Options{Workers: 1, Backlog: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	defer q.Close()
+	defer close(release)
+	block := func(ctx context.Context) (protocol.CallResult, *protocol.ResponseError) {
+		<-release
+		return protocol.CallResult{}, nil
+	}
+
+	first, err := q.Submit(context.Background(), "export", "", block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j, _ := q.Get(first.ID); j.Status != StatusRunning; j, _ = q.Get(first.ID) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := q.Submit(context.Background(), "export", "", block); err != nil {
+		t.Fatalf("second job should wait in the backlog: %v", err)
+	}
+	if _, err := q.Submit(context.Background(), "export", "", block); err != ErrFull {
+		t.Fatalf("third job: err = %v, want ErrFull", err)
+	}
+}
+
+func TestReopenKeepsResultsAndFailsUnfinishedJobs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jobs.json")
+	q, err := Open(file, Options{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, _ := q.Submit(context.Background(), "export", "", func(context.Context) (protocol.CallResult, *protocol.ResponseError) {
+		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: "date,count"}}}, nil
+	})
+	waitEnded(t, q, done.ID)
+	started := make(chan struct{})
+	stuck, _ := q.Submit(context.Background(), "export", "", func(ctx context.Context) (protocol.CallResult, *protocol.ResponseError) {
+		close(started)
+		<-ctx.Done()
+		return protocol.CallResult{}, protocol.Errorf(protocol.CategoryInternal, "stopped")
+	})
+	<-started
+	q.mu.Lock()
+	q.path = "" // the process dies: nothing after this point is saved
+	q.mu.Unlock()
+	q.Close()
+
+	reopened, err := Open(file, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if j, ok := reopened.Get(done.ID); !ok || j.Status != StatusDone || j.Result.Content[0].Text != "date,count" {
+		t.Fatalf("finished job after reopen: %+v, %t", j, ok)
+	}
+	j, ok := reopened.Get(stuck.ID)
+	if !ok || j.Status != StatusFailed || j.Error == nil || j.Finished.IsZero() {
+		t.Fatalf("interrupted job after reopen: %+v, %t", j, ok)
+	}
+	if d, ok := protocol.DataOf(j.Error); !ok || !d.Retryable {
+		t.Fatalf("interrupted job error should be retryable: %+v", j.Error)
+	}
+}
+
+func TestSaveReplacesTheFileAtomically(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jobs.json")
+	// A save that crashed before its rename leaves a partial temporary file behind.
+	if err := os.WriteFile(file+".tmp", []byte(`[{"id":"job_`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	q, err := Open(file, Options{Workers: 1})
+	if err != nil {
+		t.Fatalf("open beside a partial save: %v", err)
+	}
+	defer q.Close()
+	job, _ := q.Submit(context.Background(), "export", "", func(context.Context) (protocol.CallResult, *protocol.ResponseError) {
+		return protocol.CallResult{}, nil
+	})
+	waitEnded(t, q, job.ID)
+
+	if _, err := os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind: %v", err)
+	}
+	var saved []Job
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, &saved); err != nil || len(saved) != 1 || saved[0].ID != job.ID {
+		t.Fatalf("saved %s: %v", raw, err)
+	}
+}
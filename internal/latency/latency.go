@@ -0,0 +1,224 @@
+// Package latency keeps the duration and outcome of recent calls per name over a sliding
+// window, so p50/p95 latency and error rates can be read per tool and per analytics endpoint.
+package latency
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+	"github.com/payram/payram-analytics-mcp-server/internal/metrics"
+)
+
+const (
+	// DefaultWindow is how far back stats reach unless SetWindow changes it.
+	DefaultWindow = 15 * time.Minute
+	// maxSamples caps the samples kept per name; the oldest go first.
+	maxSamples = 10000
+	// maxNames caps the names a tracker keeps, so odd upstream paths cannot grow it forever.
+	maxNames = 500
+)
+
+// Stats summarizes one name's calls within the window.
+type Stats struct {
+	Name      string  `json:"name"`
+	Calls     int     `json:"calls"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	P50MS     float64 `json:"p50_ms"`
+	P95MS     float64 `json:"p95_ms"`
+	MaxMS     float64 `json:"max_ms"`
+}
+
+type sample struct {
+	at     time.Time
+	d      time.Duration
+	failed bool
+}
+
+// Tracker records calls by name. The zero value is not usable; use NewTracker.
+type Tracker struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	window  time.Duration
+	samples map[string][]sample
+}
+
+// NewTracker returns a tracker over the given window.
+func NewTracker(window time.Duration) *Tracker {
+	return &Tracker{clock: clock.System, window: window, samples: map[string][]sample{}}
+}
+
+// Process-wide trackers: tool calls by tool name and analytics API requests by endpoint.
+// Both are exported on metrics.Default.
+var (
+	Tools    = NewTracker(DefaultWindow)
+	Upstream = NewTracker(DefaultWindow)
+)
+
+func init() {
+	Tools.Export(metrics.Default, "mcp_tool", "tool")
+	Upstream.Export(metrics.Default, "analytics_upstream", "endpoint")
+}
+
+// SetWindow changes the window of Tools and Upstream.
+func SetWindow(d time.Duration) {
+	Tools.SetWindow(d)
+	Upstream.SetWindow(d)
+}
+
+// SetWindow changes how far back t's stats reach.
+func (t *Tracker) SetWindow(d time.Duration) {
+	t.mu.Lock()
+	t.window = d
+	t.mu.Unlock()
+}
+
+// Window returns how far back t's stats reach.
+func (t *Tracker) Window() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.window
+}
+
+// SetClock replaces the clock samples are timed by, e.g. in tests.
+func (t *Tracker) SetClock(c clock.Clock) { t.clock = c }
+
+// Observe records one call to name that took d.
+func (t *Tracker) Observe(name string, d time.Duration, failed bool) {
+	now := t.clock.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.samples[name]
+	if !ok && len(t.samples) >= maxNames {
+		t.prune(now)
+		if len(t.samples) >= maxNames {
+			return
+		}
+	}
+	s = append(s, sample{at: now, d: d, failed: failed})
+	if len(s) > maxSamples {
+		s = s[len(s)-maxSamples:]
+	}
+	t.samples[name] = s
+}
+
+// prune drops samples older than the window, and names left without any.
+func (t *Tracker) prune(now time.Time) {
+	cutoff := now.Add(-t.window)
+	for name, s := range t.samples {
+		i := sort.Search(len(s), func(i int) bool { return s[i].at.After(cutoff) })
+		if i == len(s) {
+			delete(t.samples, name)
+			continue
+		}
+		t.samples[name] = s[i:]
+	}
+}
+
+// Snapshot returns the stats of every name with calls in the window, sorted by name.
+func (t *Tracker) Snapshot() []Stats {
+	t.mu.Lock()
+	t.prune(t.clock.Now())
+	out := make([]Stats, 0, len(t.samples))
+	for name, s := range t.samples {
+		out = append(out, summarize(name, s))
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func summarize(name string, s []sample) Stats {
+	st := Stats{Name: name, Calls: len(s)}
+	ms := make([]float64, len(s))
+	for i, x := range s {
+		ms[i] = float64(x.d) / float64(time.Millisecond)
+		if x.failed {
+			st.Errors++
+		}
+	}
+	sort.Float64s(ms)
+	st.ErrorRate = float64(st.Errors) / float64(st.Calls)
+	st.P50MS, st.P95MS, st.MaxMS = quantile(ms, 0.5), quantile(ms, 0.95), ms[len(ms)-1]
+	return st
+}
+
+// quantile is the nearest-rank q quantile of sorted, which is not empty.
+func quantile(sorted []float64, q float64) float64 {
+	i := int(q*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// Export registers t on reg as <prefix>_latency_seconds (with a quantile label),
+// <prefix>_error_ratio and <prefix>_window_calls, one series per name under label.
+func (t *Tracker) Export(reg *metrics.Registry, prefix, label string) {
+	reg.GaugeVecFunc(prefix+"_latency_seconds", "p50 and p95 call latency over the stats window, by "+label+".", func() []metrics.LabeledValue {
+		var out []metrics.LabeledValue
+		for _, s := range t.Snapshot() {
+			out = append(out,
+				metrics.LabeledValue{Labels: map[string]string{label: s.Name, "quantile": "0.5"}, Value: s.P50MS / 1000},
+				metrics.LabeledValue{Labels: map[string]string{label: s.Name, "quantile": "0.95"}, Value: s.P95MS / 1000},
+			)
+		}
+		return out
+	})
+	reg.GaugeVecFunc(prefix+"_error_ratio", "Share of calls that failed over the stats window, by "+label+".", func() []metrics.LabeledValue {
+		var out []metrics.LabeledValue
+		for _, s := range t.Snapshot() {
+			out = append(out, metrics.LabeledValue{Labels: map[string]string{label: s.Name}, Value: s.ErrorRate})
+		}
+		return out
+	})
+	reg.GaugeVecFunc(prefix+"_window_calls", "Calls over the stats window, by "+label+".", func() []metrics.LabeledValue {
+		var out []metrics.LabeledValue
+		for _, s := range t.Snapshot() {
+			out = append(out, metrics.LabeledValue{Labels: map[string]string{label: s.Name}, Value: float64(s.Calls)})
+		}
+		return out
+	})
+}
+
+// idSegment matches a numeric path segment.
+var idSegment = regexp.MustCompile(`/\d+(/|$)`)
+
+// graphPath matches the analytics graph data endpoint, whose IDs name the graph.
+var graphPath = regexp.MustCompile(`/analytics/groups/\d+/graph/\d+/data$`)
+
+// Endpoint names the analytics endpoint req calls: its method and path, with numeric IDs
+// replaced by ":id" except in graph data paths, which keep them so each graph has its own
+// stats.
+func Endpoint(req *http.Request) string {
+	path := req.URL.Path
+	if !graphPath.MatchString(path) {
+		for idSegment.MatchString(path) {
+			path = idSegment.ReplaceAllString(path, "/:id$1")
+		}
+	}
+	return req.Method + " " + path
+}
+
+// Transport wraps base so every request is observed on Upstream under its Endpoint. A
+// transport error, 429 or 5xx counts as a failure.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	failed := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	Upstream.Observe(Endpoint(req), time.Since(started), failed)
+	return resp, err
+}
@@ -0,0 +1,87 @@
+package latency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+)
+
+func TestSnapshotPercentilesAndErrorRate(t *testing.T) {
+	tr := NewTracker(time.Minute)
+	for i := 1; i <= 100; i++ {
+		tr.Observe("payram_daily_stats", time.Duration(i)*time.Millisecond, i%10 == 0)
+	}
+	tr.Observe("payram_numbers_summary", 5*time.Millisecond, false)
+
+	stats := tr.Snapshot()
+	if len(stats) != 2 || stats[0].Name != "payram_daily_stats" {
+		t.Fatalf("snapshot = %+v", stats)
+	}
+	s := stats[0]
+	if s.Calls != 100 || s.Errors != 10 || s.ErrorRate != 0.1 {
+		t.Fatalf("counts = %+v", s)
+	}
+	if s.P50MS != 50 || s.P95MS != 95 || s.MaxMS != 100 {
+		t.Fatalf("percentiles = %+v", s)
+	}
+}
+
+func TestSnapshotDropsCallsOutsideWindow(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tr := NewTracker(time.Minute)
+	tr.SetClock(clock.Fixed(start))
+	tr.Observe("old", time.Second, true)
+	tr.Observe("both", time.Second, true)
+
+	tr.SetClock(clock.Fixed(start.Add(50 * time.Second)))
+	tr.Observe("both", time.Millisecond, false)
+
+	tr.SetClock(clock.Fixed(start.Add(90 * time.Second)))
+	stats := tr.Snapshot()
+	if len(stats) != 1 || stats[0].Name != "both" || stats[0].Calls != 1 || stats[0].Errors != 0 {
+		t.Fatalf("snapshot = %+v", stats)
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"/api/v1/analytics/groups":                  "GET /api/v1/analytics/groups",
+		"/api/v1/analytics/groups/12/graph/7/data":  "GET /api/v1/analytics/groups/12/graph/7/data",
+		"/api/v1/payments/991/refunds/4":            "GET /api/v1/payments/:id/refunds/:id",
+		"/api/v1/external-platform/all/payment/123": "GET /api/v1/external-platform/all/payment/:id",
+	}
+	for path, want := range cases {
+		if got := Endpoint(httptest.NewRequest(http.MethodGet, path, nil)); got != want {
+			t.Errorf("Endpoint(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestTransportCountsUpstreamFailures(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	saved := Upstream
+	Upstream = NewTracker(time.Minute)
+	defer func() { Upstream = saved }()
+
+	client := &http.Client{Transport: Transport(nil)}
+	for _, status = range []int{http.StatusOK, http.StatusNotFound, http.StatusTooManyRequests, http.StatusBadGateway} {
+		resp, err := client.Get(srv.URL + "/api/v1/payments/5")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	stats := Upstream.Snapshot()
+	if len(stats) != 1 || stats[0].Name != "GET /api/v1/payments/:id" || stats[0].Calls != 4 || stats[0].Errors != 2 {
+		t.Fatalf("snapshot = %+v", stats)
+	}
+}
@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ControlTokenEnv carries the per-agent secret a child requires on its level endpoint.
+	ControlTokenEnv = "PAYRAM_CHILD_CONTROL_TOKEN"
+	// ControlTokenHeader is the request header holding the control token.
+	ControlTokenHeader = "X-Payram-Control-Token"
+	// LevelPath is where children mount LevelHandler.
+	LevelPath = "/internal/loglevel"
+)
+
+var (
+	mu      sync.Mutex
+	loggers = map[string][]*logrus.Logger{}
+)
+
+// DefaultLevel resolves the startup level for a component from PAYRAM_LOG_LEVEL_<COMPONENT>
+// (upper-cased, dashes as underscores, e.g. PAYRAM_LOG_LEVEL_CHAT_API), then PAYRAM_LOG_LEVEL,
+// falling back to info.
+func DefaultLevel(component string) logrus.Level {
+	key := "PAYRAM_LOG_LEVEL_" + strings.ToUpper(strings.ReplaceAll(component, "-", "_"))
+	for _, raw := range []string{os.Getenv(key), os.Getenv("PAYRAM_LOG_LEVEL")} {
+		if lvl, err := logrus.ParseLevel(strings.TrimSpace(raw)); err == nil && raw != "" {
+			return lvl
+		}
+	}
+	return logrus.InfoLevel
+}
+
+func register(component string, l *logrus.Logger) {
+	l.SetLevel(DefaultLevel(component))
+
+	mu.Lock()
+	loggers[component] = append(loggers[component], l)
+	mu.Unlock()
+}
+
+// SetLevel changes the level of every logger created for component; an empty component
+// changes all loggers in the process. It reports how many loggers were changed.
+func SetLevel(component string, level logrus.Level) int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	n := 0
+	for name, ls := range loggers {
+		if component != "" && name != component {
+			continue
+		}
+		for _, l := range ls {
+			l.SetLevel(level)
+			n++
+		}
+	}
+	return n
+}
+
+// Levels reports the current level per component created in this process.
+func Levels() map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	out := make(map[string]string, len(loggers))
+	for name, ls := range loggers {
+		if len(ls) > 0 {
+			out[name] = ls[len(ls)-1].GetLevel().String()
+		}
+	}
+	return out
+}
+
+// LevelHandler lets the supervising agent read (GET) and change (PUT {"level": "debug"}) the
+// levels of every logger in this process. Requests must carry the control token the agent
+// passed in PAYRAM_CHILD_CONTROL_TOKEN; without one the endpoint is disabled.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv(ControlTokenEnv)
+		got := r.Header.Get(ControlTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var req struct {
+				Level string `json:"level"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeLevelJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid JSON"})
+				return
+			}
+			lvl, err := logrus.ParseLevel(req.Level)
+			if err != nil {
+				writeLevelJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+				return
+			}
+			SetLevel("", lvl)
+		default:
+			writeLevelJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "only GET and PUT allowed"})
+			return
+		}
+
+		writeLevelJSON(w, http.StatusOK, map[string]any{"levels": Levels()})
+	})
+}
+
+func writeLevelJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
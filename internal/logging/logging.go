@@ -8,9 +8,11 @@ import (
 )
 
 // New creates a logger that writes to logs/<component>.log and returns it with a cleanup.
+// Its level starts at DefaultLevel(component) and can be changed at runtime with SetLevel.
 func New(component string) (*logrus.Entry, func(), error) {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	register(component, logger)
 
 	if err := os.MkdirAll("logs", 0o755); err != nil {
 		return nil, nil, err
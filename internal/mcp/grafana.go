@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/events"
+	"github.com/payram/payram-analytics-mcp-server/internal/grafana"
+	"github.com/payram/payram-analytics-mcp-server/internal/pii"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+const grafanaPath = "/grafana"
+
+// SetGrafana serves src as a Grafana JSON datasource under /grafana. Payment webhooks, when
+// enabled (see SetWebhooks), are its annotations.
+func (s *Server) SetGrafana(src grafana.Source) {
+	s.grafana = http.StripPrefix(grafanaPath, grafana.NewHandler(grafanaMasker{server: s, src: src}, eventAnnotator{server: s}))
+}
+
+// serveGrafana applies tenancy as on "/" and counts each /query against the tenant's
+// tools/call quota before handing the request to the datasource.
+func (s *Server) serveGrafana(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.grafana == nil {
+		writeAPIError(w, http.StatusNotFound, "the Grafana datasource is not enabled")
+		return
+	}
+	ctx := r.Context()
+	if s.tenants != nil {
+		t, err := s.tenants.Resolve(r)
+		if err != nil {
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized: "+err.Error())
+			return
+		}
+		ctx = tenant.WithTenant(ctx, t)
+	}
+	if strings.TrimSuffix(r.URL.Path, "/") == grafanaPath+"/query" {
+		if errResp := s.takeQuota(ctx, protocol.Request{Method: "tools/call"}); errResp != nil {
+			writeAPIError(w, http.StatusTooManyRequests, errResp.Message)
+			return
+		}
+	}
+	s.grafana.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// grafanaMasker masks identifiers in the datasource's tables when the server masks PII.
+type grafanaMasker struct {
+	server *Server
+	src    grafana.Source
+}
+
+func (m grafanaMasker) Targets(ctx context.Context) ([]grafana.Target, *protocol.ResponseError) {
+	return m.src.Targets(ctx)
+}
+
+func (m grafanaMasker) Table(ctx context.Context, target string, from, to time.Time) (grafana.Table, *protocol.ResponseError) {
+	t, errResp := m.src.Table(ctx, target, from, to)
+	if errResp != nil || !m.server.maskPII {
+		return t, errResp
+	}
+	rows := make([][]string, len(t.Rows))
+	for i, row := range t.Rows {
+		rows[i] = make([]string, len(row))
+		for j, c := range row {
+			rows[i][j] = pii.Mask(c)
+		}
+	}
+	t.Rows = rows
+	return t, nil
+}
+
+// eventAnnotator annotates dashboards with the caller's payment webhooks. A query selects a
+// payment state, e.g. FILLED.
+type eventAnnotator struct {
+	server *Server
+}
+
+func (a eventAnnotator) Annotations(ctx context.Context, from, to time.Time, query string) ([]grafana.Annotation, *protocol.ResponseError) {
+	s := a.server
+	if s.events == nil {
+		return nil, nil
+	}
+	var out []grafana.Annotation
+	for _, e := range s.events.Recent(events.Filter{Tenant: tenantID(ctx), State: query, Since: from}) {
+		if e.Received.After(to) {
+			continue
+		}
+		if s.maskPII {
+			e = maskEvent(e)
+		}
+		title := strings.TrimSpace(strings.Join([]string{e.State, e.Amount, e.Currency}, " "))
+		tags := []string{}
+		for _, t := range []string{e.State, e.Currency} {
+			if t != "" {
+				tags = append(tags, t)
+			}
+		}
+		out = append(out, grafana.Annotation{Time: e.Received, Title: title, Text: e.ReferenceID, Tags: tags})
+	}
+	return out, nil
+}
@@ -1,36 +1,100 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/deadline"
+	"github.com/payram/payram-analytics-mcp-server/internal/handover"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/logging"
+	"github.com/payram/payram-analytics-mcp-server/internal/metrics"
+	"github.com/payram/payram-analytics-mcp-server/internal/pii"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/recording"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
 	"github.com/payram/payram-analytics-mcp-server/internal/version"
 	"github.com/sirupsen/logrus"
 )
 
+// maxRequestBytes caps a JSON-RPC request body.
+const maxRequestBytes = 1 << 20
+
 // RunHTTP starts an HTTP server that serves MCP JSON-RPC requests via POST.
 // Expects a single JSON-RPC request per call. Clients should POST to the root path.
 func RunHTTP(server *Server, addr string) error {
+	return RunHTTPContext(context.Background(), server, addr)
+}
+
+// RunHTTPContext is RunHTTP with graceful shutdown once ctx is cancelled. Responses are
+// compressed for clients that accept it (see SetCompression), and the warm-up calls (see
+// SetWarmup) run in the background as it starts.
+// When started by the agent with an inherited listener, it serves on that socket instead of binding addr.
+func RunHTTPContext(ctx context.Context, server *Server, addr string) error {
 	logger, cleanup, err := logging.New("mcp-http")
 	if err != nil {
 		return err
 	}
 	defer cleanup()
 
-	http.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: server.compression.Handler(NewHTTPHandler(server, logger)),
+	}
+	server.limits.Apply(srv)
+
+	if server.tenants != nil {
+		logger.Infof("HTTP MCP server listening on %s (%d tenants)", addr, server.tenants.Len())
+	} else {
+		logger.Infof("HTTP MCP server listening on %s", addr)
+	}
+	if server.jobs != nil {
+		defer server.jobs.Close()
+	}
+	go server.warmUp(ctx, logger)
+	return handover.ListenAndServe(ctx, srv, 10*time.Second)
+}
+
+// NewHTTPHandler serves server's JSON-RPC endpoint at "/" alongside /health, /version, the
+// log level endpoint, /usage, /jobs, /stream, /webhooks/payram, /events, /grafana/, /metrics
+// and /admin/tools/stats, logging each request to logger.
+func NewHTTPHandler(server *Server, logger *logrus.Entry) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
 
-	http.HandleFunc("/version", func(w http.ResponseWriter, _ *http.Request) {
+	mux.HandleFunc("/version", func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(version.Get())
 	})
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle(logging.LevelPath, logging.LevelHandler())
+
+	mux.HandleFunc("/usage", func(w http.ResponseWriter, r *http.Request) {
+		server.serveUsage(w, r)
+	})
+	mux.HandleFunc(jobsPath, server.serveJobs)
+	mux.HandleFunc(jobsPath+"/", server.serveJobs)
+	mux.HandleFunc(webhooksPath, server.serveWebhook)
+	mux.HandleFunc(eventsPath, server.serveEvents)
+	mux.HandleFunc(grafanaPath+"/", server.serveGrafana)
+	mux.HandleFunc(streamPath, server.serveStream)
+	mux.Handle("/metrics", metrics.Default.Handler())
+	mux.HandleFunc(toolStatsPath, server.serveToolStats)
+
+	mux.Handle("/", NewRPCHandler(server, logger))
+	return mux
+}
+
+// NewRPCHandler serves only server's JSON-RPC endpoint, on any path: one request per POST,
+// resolved to a tenant when the server has a registry and logged to logger. A request with
+// deadline.Header is cancelled, analytics calls included, once its budget runs out.
+func NewRPCHandler(server *Server, logger *logrus.Entry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
 		start := time.Now()
 
@@ -40,28 +104,95 @@ func RunHTTP(server *Server, addr string) error {
 			return
 		}
 
+		ctx := r.Context()
+		reqLogger := logger
+		if server.tenants != nil {
+			t, err := server.tenants.Resolve(r)
+			if err != nil {
+				reqLogger.WithError(err).Warn("tenant rejected")
+				writeJSON(rec, protocol.Response{Error: protocol.NewError(protocol.CategoryAuth, "unauthorized: "+err.Error())}, http.StatusUnauthorized)
+				logRequest(reqLogger, r, rec, start)
+				return
+			}
+			ctx = tenant.WithTenant(ctx, t)
+			reqLogger = logger.WithField("tenant", t.ID)
+		}
+		if lang := requestLanguage(ctx, r); lang != "" {
+			ctx = i18n.WithLanguage(ctx, lang)
+		}
+		ctx, cancel, err := deadline.Apply(ctx, r)
+		defer cancel()
+		if err != nil {
+			reqLogger.WithError(err).Warn("invalid deadline")
+			writeJSON(rec, protocol.Response{Error: protocol.InvalidArgs(err.Error())}, http.StatusBadRequest)
+			logRequest(reqLogger, r, rec, start)
+			return
+		}
+
 		var req protocol.Request
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			logger.WithError(err).Warn("invalid JSON")
+		if err := json.NewDecoder(http.MaxBytesReader(rec, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			reqLogger.WithError(err).Warn("invalid JSON")
 			writeJSON(rec, protocol.Response{Error: &protocol.ResponseError{Code: -32700, Message: "invalid JSON"}}, http.StatusBadRequest)
-			logRequest(logger, r, rec, start)
+			logRequest(reqLogger, r, rec, start)
 			return
 		}
 
-		resp, err := server.Handle(r.Context(), req)
+		if errResp := server.takeQuota(ctx, req); errResp != nil {
+			reqLogger.Warn(errResp.Message)
+			writeJSON(rec, protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Error: errResp}, http.StatusTooManyRequests)
+			logRequest(reqLogger, r, rec, start)
+			return
+		}
+
+		var capture *recording.Capture
+		if r.Header.Get(recording.Header) != "" {
+			ctx, capture = recording.WithCapture(ctx)
+		}
+
+		resp, err := server.Handle(ctx, req)
+		if capture != nil {
+			exchanges := capture.Exchanges()
+			if server.maskPII {
+				exchanges = maskExchanges(exchanges)
+			}
+			attachExchanges(&resp, exchanges)
+		}
 		if err != nil {
-			logger.WithError(err).Error("mcp handler error")
+			reqLogger.WithError(err).Error("mcp handler error")
 			writeJSON(rec, WriteError(req.ID, -32603, "internal error", err), http.StatusInternalServerError)
-			logRequest(logger, r, rec, start)
+			logRequest(reqLogger, r, rec, start)
 			return
 		}
 
 		writeJSON(rec, resp, http.StatusOK)
-		logRequest(logger, r, rec, start)
+		logRequest(reqLogger, r, rec, start)
 	})
+}
 
-	logger.Infof("HTTP MCP server listening on %s", addr)
-	return http.ListenAndServe(addr, nil)
+// attachExchanges returns recorded upstream calls in result._meta, or in error.data for failed calls.
+func attachExchanges(resp *protocol.Response, exchanges []protocol.HTTPExchange) {
+	if len(exchanges) == 0 {
+		return
+	}
+	if result, ok := resp.Result.(protocol.CallResult); ok {
+		meta := protocol.CallMeta{}
+		if result.Meta != nil {
+			meta = *result.Meta
+		}
+		meta.Exchanges = exchanges
+		result.Meta = &meta
+		resp.Result = result
+		return
+	}
+	if resp.Error == nil {
+		return
+	}
+	switch data := resp.Error.Data.(type) {
+	case *protocol.ErrorData:
+		data.Exchanges = exchanges
+	case nil:
+		resp.Error.Data = protocol.CallMeta{Exchanges: exchanges}
+	}
 }
 
 func writeJSON(w http.ResponseWriter, resp protocol.Response, status int) {
@@ -98,3 +229,27 @@ func logRequest(logger *logrus.Entry, r *http.Request, rec *responseRecorder, st
 		"dur":    time.Since(start).Round(time.Millisecond),
 	}).Info("request")
 }
+
+// requestLanguage is the language tools answer in: the X-Language header, else the resolved
+// tenant's language. An invalid header is ignored.
+func requestLanguage(ctx context.Context, r *http.Request) string {
+	if lang, ok := i18n.Parse(r.Header.Get(i18n.Header)); ok {
+		return lang
+	}
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Language
+	}
+	return ""
+}
+
+// maskExchanges masks identifiers in recorded upstream requests and responses.
+func maskExchanges(exchanges []protocol.HTTPExchange) []protocol.HTTPExchange {
+	out := make([]protocol.HTTPExchange, len(exchanges))
+	for i, ex := range exchanges {
+		ex.URL = pii.Mask(ex.URL)
+		ex.RequestBody = pii.Mask(ex.RequestBody)
+		ex.ResponseBody = pii.Mask(ex.ResponseBody)
+		out[i] = ex
+	}
+	return out
+}
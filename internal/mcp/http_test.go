@@ -0,0 +1,344 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/deadline"
+	"github.com/payram/payram-analytics-mcp-server/internal/degraded"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/latency"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+type echoTool struct{}
+
+func (echoTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{Name: "echo"}
+}
+
+func (echoTool) Invoke(_ context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	var args map[string]any
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
+	}
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: string(raw)}}}, nil
+}
+
+// languageTool answers with the language its context carries.
+type languageTool struct{}
+
+func (languageTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{Name: "language"}
+}
+
+func (languageTool) Invoke(ctx context.Context, _ json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: i18n.FromContext(ctx)}}}, nil
+}
+
+func TestHTTPHandlerLanguage(t *testing.T) {
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	tenants, err := tenant.New([]tenant.Tenant{{ID: "acme", BaseURL: "https://acme", Token: "t", APIKeys: []string{"k"}, Language: "de"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(NewToolbox(languageTool{}))
+	server.SetTenants(tenants, nil)
+	h := NewHTTPHandler(server, logrus.NewEntry(quiet))
+
+	cases := []struct{ header, want string }{
+		{"", "de"},
+		{"pt_BR", "pt-br"},
+		{"not a language", "de"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"language","arguments":{}}}`))
+		req.Header.Set(tenant.KeyHeader, "k")
+		if tc.header != "" {
+			req.Header.Set(i18n.Header, tc.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var resp struct {
+			Result protocol.CallResult `json:"result"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Result.Content) != 1 {
+			t.Fatalf("header %q: response %s", tc.header, rec.Body)
+		}
+		if got := resp.Result.Content[0].Text; got != tc.want {
+			t.Errorf("header %q: language %q, want %q", tc.header, got, tc.want)
+		}
+	}
+}
+
+// deadlineTool answers with how long its context has left, in whole seconds.
+type deadlineTool struct{}
+
+func (deadlineTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{Name: "deadline"}
+}
+
+func (deadlineTool) Invoke(ctx context.Context, _ json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	text := "none"
+	if d, ok := ctx.Deadline(); ok {
+		text = time.Until(d).Round(time.Second).String()
+	}
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: text}}}, nil
+}
+
+func TestHTTPHandlerDeadline(t *testing.T) {
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	h := NewHTTPHandler(NewServer(NewToolbox(deadlineTool{})), logrus.NewEntry(quiet))
+
+	cases := []struct {
+		header string
+		status int
+		want   string
+	}{
+		{"", http.StatusOK, "none"},
+		{"5000", http.StatusOK, "5s"},
+		{"later", http.StatusBadRequest, ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"deadline","arguments":{}}}`))
+		if tc.header != "" {
+			req.Header.Set(deadline.Header, tc.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("header %q: status %d, want %d: %s", tc.header, rec.Code, tc.status, rec.Body)
+		}
+		if tc.want == "" {
+			continue
+		}
+		var resp struct {
+			Result protocol.CallResult `json:"result"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Result.Content) != 1 {
+			t.Fatalf("header %q: response %s", tc.header, rec.Body)
+		}
+		if got := resp.Result.Content[0].Text; got != tc.want {
+			t.Errorf("header %q: deadline in %s, want %s", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestHTTPHandlerMasksPII(t *testing.T) {
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	server := NewServer(NewToolbox(echoTool{}))
+	server.SetMaskPII(true)
+	h := NewHTTPHandler(server, logrus.NewEntry(quiet))
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"customer":"jane@example.com","wallet":"0x52908400098527886E0F7030069857D2E4169EE7"}}}`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	got := rec.Body.String()
+	if strings.Contains(got, "jane@") || strings.Contains(got, "0x5290") {
+		t.Fatalf("identifiers not masked: %s", got)
+	}
+	if !strings.Contains(got, "****.com") || !strings.Contains(got, "****9EE7") {
+		t.Fatalf("masked identifiers lost their last characters: %s", got)
+	}
+}
+
+func TestMaskResultMasksCharts(t *testing.T) {
+	chart := protocol.ChartData{Kind: protocol.ChartPie, Title: "jane@example.com", Labels: []string{"0x52908400098527886E0F7030069857D2E4169EE7"}, Series: []protocol.ChartSeries{{Name: "jane@example.com", Values: []float64{3}}}}
+	in := protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: "ok"}}, Meta: &protocol.CallMeta{Charts: []protocol.ChartData{chart}}}
+	out := maskResult(in)
+	got := out.Meta.Charts[0]
+	if got.Title != "****.com" || got.Labels[0] != "****9EE7" || got.Series[0].Name != "****.com" || got.Series[0].Values[0] != 3 {
+		t.Fatalf("masked chart %+v", got)
+	}
+	if in.Meta.Charts[0].Title != "jane@example.com" {
+		t.Fatal("maskResult changed the charts it was given")
+	}
+}
+
+// resetTool stands in for a tool that changes state.
+type resetTool struct{ echoTool }
+
+func (resetTool) Descriptor() protocol.ToolDescriptor { return protocol.ToolDescriptor{Name: "reset"} }
+func (resetTool) Mutating() bool                      { return true }
+
+func TestReadOnlyRefusesMutatingTools(t *testing.T) {
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	server := NewServer(NewToolbox(echoTool{}, resetTool{}))
+	server.SetReadOnly(true)
+
+	resp, err := server.Handle(context.Background(), protocol.Request{JSONRPC: "2.0", ID: 1, Method: "tools/list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools := resp.Result.(protocol.ListResult).Tools
+	if len(tools) != 1 || tools[0].Name != "echo" {
+		t.Fatalf("tools/list = %+v, want only echo", tools)
+	}
+
+	resp, err = server.Handle(context.Background(), protocol.Request{JSONRPC: "2.0", ID: 2, Method: "tools/call", Params: json.RawMessage(`{"name":"reset"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := protocol.DataOf(resp.Error); d.Category != protocol.CategoryReadOnly || resp.Error.Code != protocol.CodeReadOnly {
+		t.Fatalf("tools/call reset = %+v, want a READ_ONLY error", resp)
+	}
+
+	rec := httptest.NewRecorder()
+	NewHTTPHandler(server, logrus.NewEntry(quiet)).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/stream", strings.NewReader(`{"tool":"reset"}`)))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("/stream reset: status %d, want 403: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHTTPHandlerRejectsOversizedBody(t *testing.T) {
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	h := NewHTTPHandler(NewServer(NewToolbox(echoTool{})), logrus.NewEntry(quiet))
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"pad":"` +
+		strings.Repeat("x", maxRequestBytes) + `"}}}`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// FuzzHTTPHandler posts arbitrary bodies to the JSON-RPC endpoint and checks every reply is a
+// well-formed JSON-RPC response carrying either a result or an error.
+func FuzzHTTPHandler(f *testing.F) {
+	f.Add(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"a":1}}}`)
+	f.Add(`{"jsonrpc":"2.0","id":"x","method":"tools/list"}`)
+	f.Add(`{"jsonrpc":"1.0","id":null,"method":"initialize"}`)
+	f.Add(`{"jsonrpc":"2.0","id":[1],"method":"tools/call","params":"nope"}`)
+	f.Add(`{"jsonrpc":"2.0","id":1.5,"method":"tools/call","params":{"name":"missing"}}`)
+	f.Add(`{"jsonrpc":"2.0","method":"ping"}{"trailing":true}`)
+	f.Add(`[`)
+	f.Add(``)
+
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	h := NewHTTPHandler(NewServer(NewToolbox(echoTool{})), logrus.NewEntry(quiet))
+
+	f.Fuzz(func(t *testing.T, body string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+		var resp struct {
+			Result json.RawMessage         `json:"result"`
+			Error  *protocol.ResponseError `json:"error"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("status %d with non-JSON reply %q: %v", rec.Code, rec.Body.String(), err)
+		}
+		if (resp.Error == nil) == (len(resp.Result) == 0) {
+			t.Fatalf("reply must carry exactly one of result and error: %s", rec.Body.String())
+		}
+		if rec.Code == http.StatusOK && resp.Error != nil && resp.Error.Code == -32700 {
+			t.Fatalf("parse error reported with status 200")
+		}
+	})
+}
+
+func TestToolStatsEndpoint(t *testing.T) {
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	h := NewHTTPHandler(NewServer(NewToolbox(echoTool{})), logrus.NewEntry(quiet))
+
+	for _, args := range []string{`{}`, `"not an object"`} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":`+args+`}}`))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, toolStatsPath, nil))
+	var stats ToolStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: %d %s", toolStatsPath, rec.Code, rec.Body)
+	}
+	var echo *latency.Stats
+	for i := range stats.Tools {
+		if stats.Tools[i].Name == "echo" {
+			echo = &stats.Tools[i]
+		}
+	}
+	if echo == nil || echo.Calls < 2 || echo.Errors < 1 || stats.WindowSeconds <= 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, toolStatsPath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST %s: status %d, want 405", toolStatsPath, rec.Code)
+	}
+}
+
+// upstreamTool reads url through a degraded transport and returns the body.
+type upstreamTool struct {
+	client *http.Client
+	url    string
+}
+
+func (upstreamTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{Name: "upstream"}
+}
+
+func (u upstreamTool) Invoke(ctx context.Context, _ json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u.url, nil)
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return protocol.CallResult{}, protocol.NewError(protocol.CategoryUpstreamUnavailable, err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return protocol.CallResult{}, protocol.UpstreamStatusError(resp.StatusCode, resp.Status)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: string(body)}}}, nil
+}
+
+func TestStaleAnswersAreLabeled(t *testing.T) {
+	down := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "12 payments")
+	}))
+	defer srv.Close()
+	tool := upstreamTool{client: &http.Client{Transport: degraded.Transport(nil, degraded.NewStore(time.Hour))}, url: srv.URL}
+	server := NewServer(NewToolbox(tool))
+	call := func() protocol.CallResult {
+		t.Helper()
+		result, errResp := server.call(context.Background(), "upstream", nil)
+		if errResp != nil {
+			t.Fatalf("call failed: %+v", errResp)
+		}
+		return result
+	}
+
+	if r := call(); len(r.Content) != 1 || r.Meta != nil {
+		t.Fatalf("fresh answer: %+v", r)
+	}
+	down = true
+	r := call()
+	if len(r.Content) != 2 || !strings.Contains(r.Content[0].Text, "cached from") || r.Content[1].Text != "12 payments" {
+		t.Fatalf("stale answer not labeled: %+v", r.Content)
+	}
+	if r.Meta == nil || r.Meta.Degraded == nil || r.Meta.Degraded.Responses != 1 {
+		t.Fatalf("stale answer meta: %+v", r.Meta)
+	}
+}
@@ -0,0 +1,233 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/jobs"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+const jobsPath = "/jobs"
+
+// SetJobs makes tools/call run tools that implement Background() (such as payram_export)
+// on q, returning a job handle right away, and adds the payram_job_status tool to fetch
+// the result. Without a queue those tools run inline like any other.
+func (s *Server) SetJobs(q *jobs.Queue) {
+	s.jobs = q
+	s.toolbox.Register(jobStatusTool{server: s})
+}
+
+// submitJob queues a tool call and describes the job handle to the caller.
+func (s *Server) submitJob(ctx context.Context, name string, args json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	job, errResp := s.enqueue(ctx, name, args)
+	if errResp != nil {
+		return protocol.CallResult{}, errResp
+	}
+	text := i18n.Sprintf(ctx, "Started %s as background job %s. Check on it with payram_job_status (job_id %s); large exports take a few minutes.", name, job.ID, job.ID)
+	return protocol.CallResult{
+		Content: []protocol.ContentPart{{Type: "text", Text: text}},
+		Meta:    &protocol.CallMeta{Job: &protocol.JobRef{ID: job.ID, Status: job.Status}},
+	}, nil
+}
+
+func (s *Server) enqueue(ctx context.Context, name string, args json.RawMessage) (jobs.Job, *protocol.ResponseError) {
+	args = append(json.RawMessage(nil), args...)
+	job, err := s.jobs.Submit(ctx, name, tenantID(ctx), func(ctx context.Context) (protocol.CallResult, *protocol.ResponseError) {
+		return s.call(ctx, name, args)
+	})
+	switch {
+	case errors.Is(err, jobs.ErrFull):
+		return job, protocol.NewError(protocol.CategoryUpstreamUnavailable, "Too many background jobs are waiting to run. Try again in a few minutes.")
+	case err != nil:
+		return job, protocol.Errorf(protocol.CategoryInternal, "queue job: %v", err)
+	}
+	return job, nil
+}
+
+func tenantID(ctx context.Context) string {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.ID
+	}
+	return ""
+}
+
+// lookupJob returns the job with id if the caller's tenant owns it.
+func (s *Server) lookupJob(ctx context.Context, id string) (jobs.Job, bool) {
+	job, ok := s.jobs.Get(id)
+	if !ok || job.Tenant != tenantID(ctx) {
+		return jobs.Job{}, false
+	}
+	return job, true
+}
+
+// jobStatusTool reports on a background job and returns its result once finished.
+type jobStatusTool struct {
+	server *Server
+}
+
+func (jobStatusTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{
+		Name:        "payram_job_status",
+		Description: "Check a background job started by a slow tool such as payram_export. Returns the job's result once it has finished, or its status while it is still running.",
+		InputSchema: &protocol.JSONSchema{
+			Type: "object",
+			Properties: map[string]protocol.JSONSchema{
+				"job_id": {Type: "string", Description: "Job ID returned when the job was started"},
+			},
+			Required: []string{"job_id"},
+		},
+	}
+}
+
+func (t jobStatusTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	var args struct {
+		JobID string `json:"job_id"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil || strings.TrimSpace(args.JobID) == "" {
+		return protocol.CallResult{}, protocol.InvalidArgs("job_id is required")
+	}
+	job, ok := t.server.lookupJob(ctx, strings.TrimSpace(args.JobID))
+	if !ok {
+		return protocol.CallResult{}, protocol.NotFound("No such job. Finished jobs are only kept for a day; start it again if needed.")
+	}
+	ref := &protocol.JobRef{ID: job.ID, Status: job.Status}
+	switch job.Status {
+	case jobs.StatusDone:
+		result := *job.Result
+		meta := protocol.CallMeta{Job: ref}
+		if job.Result.Meta != nil {
+			meta.Sources, meta.Charts = job.Result.Meta.Sources, job.Result.Meta.Charts
+		}
+		result.Meta = &meta
+		return result, nil
+	case jobs.StatusFailed:
+		return protocol.CallResult{}, job.Error
+	default:
+		text := i18n.Sprintf(ctx, "Job %s (%s) is %s. Check again in a minute.", job.ID, job.Tool, job.Status)
+		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: text}}, Meta: &protocol.CallMeta{Job: ref}}, nil
+	}
+}
+
+type submitJobRequest struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// serveJobs is the HTTP API for background jobs, for callers that skip JSON-RPC:
+//
+//	POST /jobs              {"tool", "arguments"}; 202 with the job
+//	GET  /jobs/{id}         the job, with its result once finished
+//	GET  /jobs/{id}/result  the finished result's text, e.g. a CSV export
+//
+// Tenancy applies as on "/": a tenant only sees its own jobs.
+func (s *Server) serveJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.jobs == nil {
+		writeAPIError(w, http.StatusNotFound, "background jobs are not enabled")
+		return
+	}
+	ctx := r.Context()
+	if s.tenants != nil {
+		t, err := s.tenants.Resolve(r)
+		if err != nil {
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized: "+err.Error())
+			return
+		}
+		ctx = tenant.WithTenant(ctx, t)
+	}
+	if lang := requestLanguage(ctx, r); lang != "" {
+		ctx = i18n.WithLanguage(ctx, lang)
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, jobsPath), "/")
+	if rest == "" {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		s.serveSubmitJob(ctx, w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	id, sub, _ := strings.Cut(rest, "/")
+	job, ok := s.lookupJob(ctx, id)
+	if !ok || (sub != "" && sub != "result") {
+		writeAPIError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if sub == "" {
+		_ = json.NewEncoder(w).Encode(job)
+		return
+	}
+	switch job.Status {
+	case jobs.StatusDone:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(resultText(*job.Result)))
+	case jobs.StatusFailed:
+		writeAPIError(w, http.StatusConflict, "job failed: "+job.Error.Message)
+	default:
+		writeAPIError(w, http.StatusConflict, "job is "+job.Status)
+	}
+}
+
+func (s *Server) serveSubmitJob(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req submitJobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if _, ok := s.toolbox.tools[req.Tool]; !ok {
+		writeAPIError(w, http.StatusNotFound, "tool not found")
+		return
+	}
+	if errResp := s.refuseMutation(req.Tool); errResp != nil {
+		writeAPIError(w, http.StatusForbidden, errResp.Message)
+		return
+	}
+	if len(req.Arguments) == 0 {
+		req.Arguments = json.RawMessage(`{}`)
+	}
+	if errResp := s.takeQuota(ctx, protocol.Request{Method: "tools/call"}); errResp != nil {
+		writeAPIError(w, http.StatusTooManyRequests, errResp.Message)
+		return
+	}
+	job, errResp := s.enqueue(ctx, req.Tool, req.Arguments)
+	if errResp != nil {
+		status := http.StatusInternalServerError
+		if d, _ := protocol.DataOf(errResp); d.Retryable {
+			status = http.StatusServiceUnavailable
+		}
+		writeAPIError(w, status, errResp.Message)
+		return
+	}
+	w.Header().Set("Location", jobsPath+"/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// resultText joins the text parts of a result.
+func resultText(r protocol.CallResult) string {
+	parts := make([]string, 0, len(r.Content))
+	for _, p := range r.Content {
+		if p.Type == "text" {
+			parts = append(parts, p.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/jobs"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// slowTool asks to run in the background and answers with a fixed CSV.
+type slowTool struct{}
+
+func (slowTool) Descriptor() protocol.ToolDescriptor { return protocol.ToolDescriptor{Name: "slow"} }
+func (slowTool) Background() bool                    { return true }
+
+func (slowTool) Invoke(context.Context, json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: "date,count\n2026-01-01,3"}}}, nil
+}
+
+func jobServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	q, err := jobs.Open("", jobs.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(q.Close)
+	server := NewServer(NewToolbox(slowTool{}, echoTool{}))
+	server.SetJobs(q)
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	return server, NewHTTPHandler(server, logrus.NewEntry(quiet))
+}
+
+func callTool(t *testing.T, s *Server, name, args string) protocol.Response {
+	t.Helper()
+	resp, err := s.Handle(context.Background(), protocol.Request{JSONRPC: "2.0", ID: 1, Method: "tools/call",
+		Params: json.RawMessage(`{"name":"` + name + `","arguments":` + args + `}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestBackgroundToolReturnsJobHandle(t *testing.T) {
+	server, _ := jobServer(t)
+
+	resp := callTool(t, server, "slow", `{}`)
+	result, ok := resp.Result.(protocol.CallResult)
+	if !ok || result.Meta == nil || result.Meta.Job == nil {
+		t.Fatalf("tools/call returned %+v, want a job handle", resp)
+	}
+	id := result.Meta.Job.ID
+	if !strings.Contains(result.Content[0].Text, id) {
+		t.Errorf("handle text %q does not name the job", result.Content[0].Text)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp = callTool(t, server, "payram_job_status", `{"job_id":"`+id+`"}`)
+		if resp.Error != nil {
+			t.Fatalf("job status: %+v", resp.Error)
+		}
+		result = resp.Result.(protocol.CallResult)
+		if result.Meta.Job.Status == jobs.StatusDone {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s", result.Meta.Job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if result.Content[0].Text != "date,count\n2026-01-01,3" {
+		t.Errorf("job result %q", result.Content[0].Text)
+	}
+
+	if resp := callTool(t, server, "echo", `{"a":1}`); resp.Result.(protocol.CallResult).Meta != nil {
+		t.Errorf("foreground tool was queued: %+v", resp.Result)
+	}
+	if resp := callTool(t, server, "payram_job_status", `{"job_id":"job_missing"}`); resp.Error == nil || resp.Error.Code != protocol.CodeNotFound {
+		t.Errorf("unknown job: %+v", resp.Error)
+	}
+}
+
+func TestJobsHTTPEndpoints(t *testing.T) {
+	_, h := jobServer(t)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"tool":"slow"}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("submit: %d %s", rec.Code, rec.Body)
+	}
+	var job jobs.Job
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil || rec.Header().Get("Location") != "/jobs/"+job.ID {
+		t.Fatalf("submit response %s, location %q", rec.Body, rec.Header().Get("Location"))
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+job.ID+"/result", nil))
+		if rec.Code != http.StatusConflict || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "date,count\n2026-01-01,3" {
+		t.Fatalf("result: %d %q", rec.Code, rec.Body)
+	}
+
+	for path, want := range map[string]int{"/jobs/job_missing": http.StatusNotFound, "/jobs/" + job.ID + "/other": http.StatusNotFound} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s: %d, want %d", path, rec.Code, want)
+		}
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"tool":"nope"}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown tool: %d", rec.Code)
+	}
+}
@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+// takeQuota counts a tools/call against the request's tenant and returns an error response
+// once the tenant's daily tool call quota is used up.
+func (s *Server) takeQuota(ctx context.Context, req protocol.Request) *protocol.ResponseError {
+	t, ok := tenant.FromContext(ctx)
+	if !ok || s.usage == nil || req.Method != "tools/call" {
+		return nil
+	}
+	_, err := s.usage.Take(t.ID, tenant.KindToolCalls, t.Quotas.ToolCallsPerDay)
+	var qe *tenant.QuotaError
+	if errors.As(err, &qe) {
+		return &protocol.ResponseError{Code: protocol.CodeQuotaExceeded, Message: qe.Error(), Data: qe.Counter}
+	}
+	return nil
+}
+
+// serveUsage reports the calling tenant's usage and quotas for today: GET /usage.
+func (s *Server) serveUsage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.tenants == nil || s.usage == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "usage tracking requires tenants"})
+		return
+	}
+	t, err := s.tenants.Resolve(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized: " + err.Error()})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"tenant": t.ID,
+		"usage":  map[string]tenant.Counter{tenant.KindToolCalls: s.usage.Get(t.ID, t.Quotas)[tenant.KindToolCalls]},
+	})
+}
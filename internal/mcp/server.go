@@ -4,18 +4,100 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/compress"
+	"github.com/payram/payram-analytics-mcp-server/internal/degraded"
+	"github.com/payram/payram-analytics-mcp-server/internal/events"
+	"github.com/payram/payram-analytics-mcp-server/internal/httplimits"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/jobs"
+	"github.com/payram/payram-analytics-mcp-server/internal/pii"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/provenance"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
 )
 
 // Server handles MCP JSON-RPC requests against a toolbox.
 type Server struct {
-	toolbox *Toolbox
+	toolbox     *Toolbox
+	tenants     *tenant.Registry
+	usage       *tenant.UsageTracker
+	limits      httplimits.Limits
+	compression compress.Options
+	maskPII     bool
+	readOnly    bool
+	jobs        *jobs.Queue
+
+	name    string
+	version string
+
+	events     *events.Store
+	webhookKey string
+	grafana    http.Handler
+
+	warmup []WarmupCall
 }
 
 // NewServer wires a toolbox into an MCP server.
 func NewServer(tb *Toolbox) *Server {
-	return &Server{toolbox: tb}
+	return &Server{toolbox: tb, limits: httplimits.Default, compression: compress.Default, name: "payram-analytics-mcp-server", version: "0.1.0"}
+}
+
+// SetInfo replaces the serverInfo name and version reported by initialize.
+func (s *Server) SetInfo(name, version string) {
+	s.name = name
+	s.version = version
+}
+
+// SetTenants makes the HTTP transport resolve every request to a tenant, whose deployment the
+// tools then query, and count tools/call against the tenant's daily quota in usage.
+// Stdio sessions are unaffected.
+func (s *Server) SetTenants(r *tenant.Registry, usage *tenant.UsageTracker) {
+	s.tenants = r
+	s.usage = usage
+}
+
+// SetLimits replaces the connection timeouts and header cap of the HTTP transport.
+func (s *Server) SetLimits(l httplimits.Limits) {
+	s.limits = l
+}
+
+// SetCompression replaces how the HTTP transport compresses its responses.
+func (s *Server) SetCompression(o compress.Options) {
+	s.compression = o
+}
+
+// SetMaskPII makes tools/call mask customer emails, wallet addresses and transaction hashes
+// in tool output and errors, leaving their last four characters (see pii.Mask).
+func (s *Server) SetMaskPII(on bool) {
+	s.maskPII = on
+}
+
+// SetReadOnly hides tools that change state (see Toolbox.mutating) from tools/list and
+// refuses calls to them with a READ_ONLY error.
+func (s *Server) SetReadOnly(on bool) {
+	s.readOnly = on
+}
+
+// refuseMutation returns the error for calling the named tool in read-only mode, or nil.
+func (s *Server) refuseMutation(name string) *protocol.ResponseError {
+	if !s.readOnly || !s.toolbox.mutating(name) {
+		return nil
+	}
+	return protocol.Errorf(protocol.CategoryReadOnly, "%s changes state and is disabled in read-only mode (READ_ONLY=true)", name)
+}
+
+// describe lists the tools callers may use.
+func (s *Server) describe() []protocol.ToolDescriptor {
+	list := s.toolbox.Describe()
+	if !s.readOnly {
+		return list
+	}
+	return slices.DeleteFunc(list, func(d protocol.ToolDescriptor) bool { return s.toolbox.mutating(d.Name) })
 }
 
 // Handle routes a single request.
@@ -29,8 +111,8 @@ func (s *Server) Handle(ctx context.Context, req protocol.Request) (protocol.Res
 		return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Result: map[string]any{
 			"protocolVersion": "2024-11-05",
 			"serverInfo": map[string]string{
-				"name":    "payram-analytics-mcp-server",
-				"version": "0.1.0",
+				"name":    s.name,
+				"version": s.version,
 			},
 			"capabilities": map[string]any{
 				"tools": map[string]any{},
@@ -39,16 +121,26 @@ func (s *Server) Handle(ctx context.Context, req protocol.Request) (protocol.Res
 	case "ping":
 		return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Result: map[string]any{}}, nil
 	case "tools/list":
-		return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Result: protocol.ListResult{Tools: s.toolbox.Describe()}}, nil
+		return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Result: protocol.ListResult{Tools: s.describe()}}, nil
 	case "tools/call":
 		var params protocol.CallParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
-			return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Error: &protocol.ResponseError{Code: -32602, Message: "invalid params"}}, nil
+			return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Error: protocol.InvalidArgs("invalid params")}, nil
 		}
 		if params.Name == "" {
-			return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Error: &protocol.ResponseError{Code: -32602, Message: "tool name required"}}, nil
+			return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Error: protocol.InvalidArgs("tool name required")}, nil
+		}
+		if errResp := s.refuseMutation(params.Name); errResp != nil {
+			return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Error: errResp}, nil
 		}
-		result, toolErr := s.toolbox.Call(ctx, params.Name, params.Args)
+		if s.jobs != nil && s.toolbox.background(params.Name) {
+			result, errResp := s.submitJob(ctx, params.Name, params.Args)
+			if errResp != nil {
+				return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Error: errResp}, nil
+			}
+			return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Result: result}, nil
+		}
+		result, toolErr := s.call(ctx, params.Name, params.Args)
 		if toolErr != nil {
 			return protocol.Response{JSONRPC: "2.0", ID: normalizeID(req.ID), Error: toolErr}, nil
 		}
@@ -58,6 +150,84 @@ func (s *Server) Handle(ctx context.Context, req protocol.Request) (protocol.Res
 	}
 }
 
+// call invokes a tool, masking its output when SetMaskPII is on.
+func (s *Server) call(ctx context.Context, name string, args json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	ctx, trail := provenance.WithTrail(ctx)
+	ctx, notice := degraded.WithNotice(ctx)
+	result, errResp := s.toolbox.Call(ctx, name, args)
+	if sources := trail.Sources(); errResp == nil && len(sources) > 0 {
+		meta := protocol.CallMeta{}
+		if result.Meta != nil {
+			meta = *result.Meta
+		}
+		meta.Sources = sources
+		result.Meta = &meta
+	}
+	if n, asOf := notice.Stale(); errResp == nil && n > 0 {
+		result = labelStale(ctx, result, n, asOf)
+	}
+	if s.maskPII {
+		result, errResp = maskResult(result), maskError(errResp)
+	}
+	return result, errResp
+}
+
+// labelStale marks a result partly answered from cached analytics responses: a leading
+// note says how old the figures are, and _meta carries the same for clients.
+func labelStale(ctx context.Context, r protocol.CallResult, n int, asOf time.Time) protocol.CallResult {
+	age := time.Since(asOf).Round(time.Minute)
+	if age < time.Minute {
+		age = time.Minute
+	}
+	note := i18n.Sprintf(ctx, "The PayRam analytics API is unreachable, so these figures are cached from %s (%s ago) and may be out of date.", asOf.UTC().Format("2006-01-02 15:04 UTC"), strings.TrimSuffix(age.String(), "0s"))
+	r.Content = append([]protocol.ContentPart{{Type: "text", Text: note}}, r.Content...)
+	meta := protocol.CallMeta{}
+	if r.Meta != nil {
+		meta = *r.Meta
+	}
+	meta.Degraded = &protocol.Degraded{AsOf: asOf.UTC(), Responses: n}
+	r.Meta = &meta
+	return r
+}
+
+// maskResult masks identifiers in every text a client or LLM sees: content, chart titles,
+// labels and series names.
+func maskResult(r protocol.CallResult) protocol.CallResult {
+	for i, part := range r.Content {
+		part.Text = pii.Mask(part.Text)
+		r.Content[i] = part
+	}
+	if r.Meta == nil || len(r.Meta.Charts) == 0 {
+		return r
+	}
+	meta := *r.Meta
+	meta.Charts = make([]protocol.ChartData, len(r.Meta.Charts))
+	for i, c := range r.Meta.Charts {
+		masked := c
+		masked.Title = pii.Mask(c.Title)
+		masked.Labels = make([]string, len(c.Labels))
+		for j, l := range c.Labels {
+			masked.Labels[j] = pii.Mask(l)
+		}
+		masked.Series = make([]protocol.ChartSeries, len(c.Series))
+		for j, s := range c.Series {
+			masked.Series[j] = protocol.ChartSeries{Name: pii.Mask(s.Name), Values: s.Values}
+		}
+		meta.Charts[i] = masked
+	}
+	r.Meta = &meta
+	return r
+}
+
+func maskError(e *protocol.ResponseError) *protocol.ResponseError {
+	if e == nil {
+		return nil
+	}
+	masked := *e
+	masked.Message = pii.Mask(e.Message)
+	return &masked
+}
+
 // WriteError builds a response with an error and wraps encode issues.
 func WriteError(id any, code int, message string, err error) protocol.Response {
 	detail := message
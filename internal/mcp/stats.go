@@ -0,0 +1,32 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/latency"
+)
+
+const toolStatsPath = "/admin/tools/stats"
+
+// ToolStats is the body of GET /admin/tools/stats: latency and errors per tool, and per
+// analytics API endpoint the tools called, over the last WindowSeconds.
+type ToolStats struct {
+	WindowSeconds float64         `json:"window_seconds"`
+	Tools         []latency.Stats `json:"tools"`
+	Upstream      []latency.Stats `json:"upstream"`
+}
+
+// serveToolStats reports the latency stats: GET /admin/tools/stats.
+func (s *Server) serveToolStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, "only GET allowed")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(ToolStats{
+		WindowSeconds: latency.Tools.Window().Seconds(),
+		Tools:         latency.Tools.Snapshot(),
+		Upstream:      latency.Upstream.Snapshot(),
+	})
+}
@@ -0,0 +1,48 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// ServeStdio runs an MCP stdio session: it reads one JSON-RPC request per line from in and
+// writes each response as one line to out, until in ends or ctx is done. Notifications
+// (requests without an id, such as notifications/initialized) get no response. ctx is
+// checked between requests and passed to the tools.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64*1024), maxRequestBytes)
+	enc := json.NewEncoder(out)
+	for sc.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var req protocol.Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			if err := enc.Encode(protocol.Response{JSONRPC: "2.0", Error: &protocol.ResponseError{Code: -32700, Message: "invalid JSON"}}); err != nil {
+				return err
+			}
+			continue
+		}
+		resp, err := s.Handle(ctx, req)
+		if err != nil {
+			resp = WriteError(req.ID, -32603, "internal error", err)
+		}
+		if req.ID == nil {
+			continue
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
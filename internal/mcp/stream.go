@@ -0,0 +1,220 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/deadline"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/pii"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+const streamPath = "/stream"
+
+// streamErrorTrailer carries the error of a tool that failed after its output had started.
+const streamErrorTrailer = "X-Tool-Error"
+
+const (
+	// streamChunkBytes is how much output is gathered before it is sent and flushed.
+	streamChunkBytes = 32 << 10
+	// streamWriteTimeout is how long a client may take to accept one chunk before the call
+	// is abandoned. It replaces the server's write timeout, which a long export would outlast.
+	streamWriteTimeout = 30 * time.Second
+	// maxMaskedLine caps the unfinished line held back for masking.
+	maxMaskedLine = 8 * streamChunkBytes
+)
+
+// serveStream runs a tool and sends its text as it is produced, for large results such as a
+// CSV export:
+//
+//	POST /stream  {"tool", "arguments"}; 200 with the text
+//
+// Tools that implement StreamTool write into the response in chunks, and a slow client holds
+// up the tool rather than letting its output pile up in memory. Other tools run as usual and
+// have their text sent in chunks. An error before any output answers with its status; after
+// that, it is sent in the X-Tool-Error trailer. Tenancy, deadlines and the tool call quota
+// apply as on "/". Background tools run inline, since a stream is not cut off by the write
+// timeout.
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	ctx := r.Context()
+	if s.tenants != nil {
+		t, err := s.tenants.Resolve(r)
+		if err != nil {
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized: "+err.Error())
+			return
+		}
+		ctx = tenant.WithTenant(ctx, t)
+	}
+	if lang := requestLanguage(ctx, r); lang != "" {
+		ctx = i18n.WithLanguage(ctx, lang)
+	}
+	ctx, cancel, err := deadline.Apply(ctx, r)
+	defer cancel()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req submitJobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	tool, ok := s.toolbox.tools[req.Tool]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "tool not found")
+		return
+	}
+	if errResp := s.refuseMutation(req.Tool); errResp != nil {
+		writeAPIError(w, http.StatusForbidden, errResp.Message)
+		return
+	}
+	if len(req.Arguments) == 0 {
+		req.Arguments = json.RawMessage(`{}`)
+	}
+	if errResp := s.takeQuota(ctx, protocol.Request{Method: "tools/call"}); errResp != nil {
+		writeAPIError(w, http.StatusTooManyRequests, errResp.Message)
+		return
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	sw := &streamWriter{w: w, rc: http.NewResponseController(w), cancel: stop}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Trailer", streamErrorTrailer)
+
+	var errResp *protocol.ResponseError
+	if st, ok := tool.(StreamTool); ok {
+		sw.mask = s.maskPII
+		errResp = st.Stream(ctx, req.Arguments, sw)
+		if s.maskPII {
+			errResp = maskError(errResp)
+		}
+	} else {
+		var result protocol.CallResult
+		if result, errResp = s.call(ctx, req.Tool, req.Arguments); errResp == nil {
+			_, _ = io.WriteString(sw, resultText(result))
+		}
+	}
+	if errResp != nil && !sw.sent {
+		w.Header().Del("Trailer")
+		w.Header().Set("Content-Type", "application/json")
+		writeAPIError(w, streamErrorStatus(errResp), errResp.Message)
+		return
+	}
+	sw.Close()
+	if errResp != nil {
+		w.Header().Set(streamErrorTrailer, errResp.Message)
+	}
+}
+
+// streamErrorStatus is the HTTP status of a tool error reported before any output.
+func streamErrorStatus(e *protocol.ResponseError) int {
+	d, _ := protocol.DataOf(e)
+	switch {
+	case d.Category == protocol.CategoryInvalidArgs:
+		return http.StatusBadRequest
+	case d.Category == protocol.CategoryAuth:
+		return http.StatusUnauthorized
+	case d.Category == protocol.CategoryNotFound:
+		return http.StatusNotFound
+	case d.Category == protocol.CategoryQuotaExceeded:
+		return http.StatusTooManyRequests
+	case d.Category == protocol.CategoryReadOnly:
+		return http.StatusForbidden
+	case d.Retryable:
+		return http.StatusServiceUnavailable
+	case d.Category == protocol.CategoryUpstreamUnavailable:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// streamWriter sends output in flushed chunks of streamChunkBytes, so a request holds at
+// most one chunk. Each chunk blocks until the client has taken it, which is the flow control:
+// a client that stops reading stalls the writer, and after streamWriteTimeout every later
+// write fails and the tool's context is cancelled. With mask set, output is masked (see
+// pii.Mask) a line at a time, so an identifier is never split across chunks.
+type streamWriter struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	cancel context.CancelFunc
+	mask   bool
+
+	buf  []byte // output ready to send
+	line []byte // with mask, the last line until it is complete
+	sent bool
+	err  error
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if !s.mask {
+		for rest := p; len(rest) > 0 && s.err == nil; {
+			n := min(len(rest), streamChunkBytes-len(s.buf))
+			s.buf, rest = append(s.buf, rest[:n]...), rest[n:]
+			if len(s.buf) >= streamChunkBytes {
+				s.flush()
+			}
+		}
+	} else {
+		s.line = append(s.line, p...)
+		if i := bytes.LastIndexByte(s.line, '\n'); i >= 0 {
+			s.buf = append(s.buf, pii.Mask(string(s.line[:i+1]))...)
+			s.line = append(s.line[:0], s.line[i+1:]...)
+		} else if len(s.line) > maxMaskedLine {
+			s.buf = append(s.buf, pii.Mask(string(s.line))...)
+			s.line = s.line[:0]
+		}
+	}
+	if len(s.buf) >= streamChunkBytes {
+		s.flush()
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	return len(p), nil
+}
+
+// Close sends what is left.
+func (s *streamWriter) Close() error {
+	if len(s.line) > 0 {
+		s.buf = append(s.buf, pii.Mask(string(s.line))...)
+		s.line = nil
+	}
+	s.flush()
+	return s.err
+}
+
+func (s *streamWriter) flush() {
+	if s.err != nil || len(s.buf) == 0 {
+		return
+	}
+	// Not every ResponseWriter supports deadlines; the server's write timeout then applies.
+	_ = s.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	s.sent = true
+	if _, err := s.w.Write(s.buf); err != nil {
+		s.err = err
+	} else if err := s.rc.Flush(); err != nil {
+		s.err = err
+	}
+	s.buf = s.buf[:0]
+	if s.err != nil {
+		s.cancel()
+	}
+}
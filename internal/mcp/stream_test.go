@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// rowsTool streams rows lines of CSV naming a wallet, in writes that split each line, and
+// fails with failAfter set once that many rows are out.
+type rowsTool struct{}
+
+func (rowsTool) Descriptor() protocol.ToolDescriptor { return protocol.ToolDescriptor{Name: "rows"} }
+
+func (rowsTool) Invoke(context.Context, json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	return protocol.CallResult{}, protocol.Errorf(protocol.CategoryInternal, "rows only streams")
+}
+
+func (rowsTool) Stream(ctx context.Context, raw json.RawMessage, w io.Writer) *protocol.ResponseError {
+	var args struct{ Rows, FailAfter int }
+	_ = json.Unmarshal(raw, &args)
+	if args.Rows == 0 {
+		return protocol.InvalidArgs("rows is required")
+	}
+	for i := range args.Rows {
+		if args.FailAfter > 0 && i == args.FailAfter {
+			return protocol.NewError(protocol.CategoryUpstreamUnavailable, "analytics went away")
+		}
+		if _, err := fmt.Fprintf(w, "%d,0x52908400098527886e0f", i); err != nil {
+			return protocol.Errorf(protocol.CategoryInternal, "write: %v", err)
+		}
+		if _, err := io.WriteString(w, "7030069857d2e4169ee7\n"); err != nil {
+			return protocol.Errorf(protocol.CategoryInternal, "write: %v", err)
+		}
+	}
+	return nil
+}
+
+func streamHandler(mask bool) http.Handler {
+	server := NewServer(NewToolbox(rowsTool{}, echoTool{}))
+	server.SetMaskPII(mask)
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	return NewHTTPHandler(server, logrus.NewEntry(quiet))
+}
+
+func postStream(h http.Handler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/stream", strings.NewReader(body)))
+	return rec
+}
+
+func TestStreamSendsToolOutputInChunks(t *testing.T) {
+	rec := postStream(streamHandler(false), `{"tool":"rows","arguments":{"rows":5000}}`)
+	if rec.Code != http.StatusOK || !rec.Flushed || rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("stream: %d, flushed %v, %q", rec.Code, rec.Flushed, rec.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+	if len(lines) != 5000 || lines[4999] != "4999,0x52908400098527886e0f7030069857d2e4169ee7" {
+		t.Fatalf("got %d lines, last %q", len(lines), lines[len(lines)-1])
+	}
+	if got := rec.Result().Trailer.Get(streamErrorTrailer); got != "" {
+		t.Errorf("error trailer %q on success", got)
+	}
+
+	// Tools that do not stream are run and sent as usual.
+	rec = postStream(streamHandler(false), `{"tool":"echo","arguments":{"a":1}}`)
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"a":1}` {
+		t.Errorf("echo: %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestStreamMasksLinesSplitAcrossWrites(t *testing.T) {
+	rec := postStream(streamHandler(true), `{"tool":"rows","arguments":{"rows":3}}`)
+	if rec.Body.String() != "0,****9ee7\n1,****9ee7\n2,****9ee7\n" {
+		t.Fatalf("masked stream %q", rec.Body)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	h := streamHandler(false)
+	for body, want := range map[string]int{
+		`{"tool":"rows","arguments":{}}`: http.StatusBadRequest,
+		`{"tool":"nope"}`:                http.StatusNotFound,
+		`not json`:                       http.StatusBadRequest,
+	} {
+		rec := postStream(h, body)
+		if rec.Code != want || rec.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: %d %q, want %d", body, rec.Code, rec.Header().Get("Content-Type"), want)
+		}
+	}
+	// An error after output has started fits no status, so it comes as a trailer.
+	rec := postStream(h, `{"tool":"rows","arguments":{"rows":5000,"failafter":4000}}`)
+	if rec.Code != http.StatusOK || rec.Result().Trailer.Get(streamErrorTrailer) != "analytics went away" {
+		t.Fatalf("late failure: %d, trailer %q", rec.Code, rec.Result().Trailer.Get(streamErrorTrailer))
+	}
+	if n := strings.Count(rec.Body.String(), "\n"); n != 4000 {
+		t.Errorf("sent %d rows before failing, want 4000", n)
+	}
+}
@@ -3,7 +3,11 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+	"github.com/payram/payram-analytics-mcp-server/internal/latency"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
@@ -13,6 +17,14 @@ type Tool interface {
 	Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError)
 }
 
+// StreamTool is a tool whose output can be too large to hold in memory, such as a CSV
+// export. Stream writes the text Invoke would return, its parts joined by blank lines, to w
+// as it is produced. Once the client stops reading, writes fail and ctx is cancelled.
+type StreamTool interface {
+	Tool
+	Stream(ctx context.Context, raw json.RawMessage, w io.Writer) *protocol.ResponseError
+}
+
 // Toolbox stores and dispatches tools by name.
 type Toolbox struct {
 	tools map[string]Tool
@@ -28,6 +40,34 @@ func NewToolbox(tools ...Tool) *Toolbox {
 	return &Toolbox{tools: m}
 }
 
+// SetClock sets the clock that tools with relative date ranges compute them from.
+func (tb *Toolbox) SetClock(c clock.Clock) {
+	for _, t := range tb.tools {
+		if ct, ok := t.(interface{ SetClock(clock.Clock) }); ok {
+			ct.SetClock(c)
+		}
+	}
+}
+
+// Register adds t, replacing any tool of the same name. Register tools before the server
+// starts serving; the toolbox is not locked.
+func (tb *Toolbox) Register(t Tool) {
+	tb.tools[t.Descriptor().Name] = t
+}
+
+// background reports whether the named tool asks to run as a background job.
+func (tb *Toolbox) background(name string) bool {
+	bt, ok := tb.tools[name].(interface{ Background() bool })
+	return ok && bt.Background()
+}
+
+// mutating reports whether the named tool changes state rather than only reading analytics,
+// which tools declare by implementing Mutating() bool.
+func (tb *Toolbox) mutating(name string) bool {
+	mt, ok := tb.tools[name].(interface{ Mutating() bool })
+	return ok && mt.Mutating()
+}
+
 // Describe returns all tool descriptors.
 func (tb *Toolbox) Describe() []protocol.ToolDescriptor {
 	list := make([]protocol.ToolDescriptor, 0, len(tb.tools))
@@ -37,11 +77,14 @@ func (tb *Toolbox) Describe() []protocol.ToolDescriptor {
 	return list
 }
 
-// Call invokes a named tool.
+// Call invokes a named tool, timing it for the per-tool latency stats.
 func (tb *Toolbox) Call(ctx context.Context, name string, args json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
 	tool, ok := tb.tools[name]
 	if !ok {
 		return protocol.CallResult{}, &protocol.ResponseError{Code: -32601, Message: "tool not found"}
 	}
-	return tool.Invoke(ctx, args)
+	started := time.Now()
+	result, errResp := tool.Invoke(ctx, args)
+	latency.Tools.Observe(name, time.Since(started), errResp != nil)
+	return result, errResp
 }
@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// warmupTimeout bounds each warm-up call.
+const warmupTimeout = time.Minute
+
+// WarmupCall is one tool call the server makes when it starts, so the analytics API, the
+// connections to it and the schema models are warm before the first real question.
+type WarmupCall struct {
+	Tool      string
+	Arguments json.RawMessage
+}
+
+// DefaultWarmupCalls discovers the analytics groups and reads the last 30 days summary.
+var DefaultWarmupCalls = []WarmupCall{
+	{Tool: "payram_discover_analytics", Arguments: json.RawMessage(`{}`)},
+	{Tool: "payram_payments_summary", Arguments: json.RawMessage(`{"date_filter":"last_30_days"}`)},
+}
+
+// ParseWarmupCalls reads a semicolon-separated list of tool names, each optionally followed
+// by a colon and its JSON arguments:
+//
+//	payram_discover_analytics;payram_payments_summary:{"date_filter":"last_30_days"}
+func ParseWarmupCalls(s string) ([]WarmupCall, error) {
+	var calls []WarmupCall
+	for _, item := range strings.Split(s, ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		name, args, _ := strings.Cut(item, ":")
+		c := WarmupCall{Tool: strings.TrimSpace(name), Arguments: json.RawMessage(`{}`)}
+		if args = strings.TrimSpace(args); args != "" {
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(args), &obj); err != nil {
+				return nil, fmt.Errorf("warm-up call %s: arguments must be a JSON object: %w", c.Tool, err)
+			}
+			c.Arguments = json.RawMessage(args)
+		}
+		calls = append(calls, c)
+	}
+	return calls, nil
+}
+
+// SetWarmup makes the HTTP transport run calls once it starts listening: in order, for every
+// tenant when the server has a registry and with the PAYRAM_ANALYTICS_* credentials
+// otherwise. Failures are logged and do not stop the server. Every call must name a tool
+// that does not change state.
+func (s *Server) SetWarmup(calls []WarmupCall) error {
+	for _, c := range calls {
+		if _, ok := s.toolbox.tools[c.Tool]; !ok {
+			return fmt.Errorf("warm-up call: unknown tool %q", c.Tool)
+		}
+		if s.toolbox.mutating(c.Tool) || s.toolbox.background(c.Tool) {
+			return fmt.Errorf("warm-up call: %s cannot be prefetched", c.Tool)
+		}
+	}
+	s.warmup = calls
+	return nil
+}
+
+// warmUp runs the warm-up calls, stopping early when ctx is done.
+func (s *Server) warmUp(ctx context.Context, logger *logrus.Entry) {
+	if len(s.warmup) == 0 {
+		return
+	}
+	subjects := []context.Context{ctx}
+	if s.tenants != nil {
+		subjects = subjects[:0]
+		for _, t := range s.tenants.All() {
+			subjects = append(subjects, tenant.WithTenant(ctx, t))
+		}
+	}
+
+	started := time.Now()
+	failed := 0
+	for _, subject := range subjects {
+		for _, c := range s.warmup {
+			if ctx.Err() != nil {
+				return
+			}
+			callCtx, cancel := context.WithTimeout(subject, warmupTimeout)
+			callStarted := time.Now()
+			_, errResp := s.toolbox.Call(callCtx, c.Tool, c.Arguments)
+			cancel()
+			entry := logger.WithField("tool", c.Tool).WithField("duration_ms", time.Since(callStarted).Milliseconds())
+			if t, ok := tenant.FromContext(subject); ok {
+				entry = entry.WithField("tenant", t.ID)
+			}
+			if errResp != nil {
+				failed++
+				entry.Warnf("[warmup] %s failed: %s", c.Tool, errResp.Message)
+				continue
+			}
+			entry.Debugf("[warmup] %s done", c.Tool)
+		}
+	}
+	logger.Infof("[warmup] %d calls in %s, %d failed", len(subjects)*len(s.warmup), time.Since(started).Round(time.Millisecond), failed)
+}
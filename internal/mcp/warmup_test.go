@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// recordTool notes the tenant and arguments of every call.
+type recordTool struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (*recordTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{Name: "record"}
+}
+
+func (r *recordTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	id := "-"
+	if t, ok := tenant.FromContext(ctx); ok {
+		id = t.ID
+	}
+	r.mu.Lock()
+	r.calls = append(r.calls, id+" "+string(raw))
+	r.mu.Unlock()
+	return protocol.CallResult{}, nil
+}
+
+func TestParseWarmupCalls(t *testing.T) {
+	calls, err := ParseWarmupCalls(` payram_discover_analytics ; payram_payments_summary:{"date_filter":"last_30_days"};`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[0].Tool != "payram_discover_analytics" || string(calls[0].Arguments) != `{}` ||
+		calls[1].Tool != "payram_payments_summary" || string(calls[1].Arguments) != `{"date_filter":"last_30_days"}` {
+		t.Fatalf("calls = %+v", calls)
+	}
+	if _, err := ParseWarmupCalls(`payram_payments_summary:[1]`); err == nil {
+		t.Fatalf("expected an error for arguments that are not an object")
+	}
+}
+
+func TestSetWarmupRejectsUnknownAndMutatingTools(t *testing.T) {
+	server := NewServer(NewToolbox(echoTool{}, resetTool{}))
+	if err := server.SetWarmup([]WarmupCall{{Tool: "echo"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"missing", "reset"} {
+		if err := server.SetWarmup([]WarmupCall{{Tool: name}}); err == nil {
+			t.Errorf("SetWarmup(%s) succeeded", name)
+		}
+	}
+}
+
+func TestWarmUpCallsEveryTenant(t *testing.T) {
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	rec := &recordTool{}
+	server := NewServer(NewToolbox(rec))
+	if err := server.SetWarmup([]WarmupCall{{Tool: "record", Arguments: json.RawMessage(`{"date_filter":"last_30_days"}`)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.warmUp(context.Background(), logrus.NewEntry(quiet))
+	if len(rec.calls) != 1 || rec.calls[0] != `- {"date_filter":"last_30_days"}` {
+		t.Fatalf("without tenants: %v", rec.calls)
+	}
+
+	tenants, err := tenant.New([]tenant.Tenant{
+		{ID: "beta", BaseURL: "https://beta", Token: "t", APIKeys: []string{"kb"}},
+		{ID: "acme", BaseURL: "https://acme", Token: "t", APIKeys: []string{"ka"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetTenants(tenants, nil)
+	rec.calls = nil
+	server.warmUp(context.Background(), logrus.NewEntry(quiet))
+	if got := strings.Join(rec.calls, "|"); got != `acme {"date_filter":"last_30_days"}|beta {"date_filter":"last_30_days"}` {
+		t.Fatalf("with tenants: %s", got)
+	}
+}
@@ -0,0 +1,251 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/events"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/pii"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+const (
+	webhooksPath = "/webhooks/payram"
+	eventsPath   = "/events"
+	// webhookKeyHeader carries the project API key PayRam signs its webhooks with.
+	webhookKeyHeader = "API-Key"
+
+	defaultLiveMinutes = 60
+	maxLiveMinutes     = 7 * 24 * 60
+	defaultLiveLimit   = 20
+	maxLiveLimit       = 100
+)
+
+// SetWebhooks accepts PayRam payment webhooks at /webhooks/payram into store, lists them at
+// /events, and adds the payram_live_events tool to search them. Without tenants a webhook must carry key in its
+// API-Key header; with tenants the header selects the tenant by its webhook_key.
+func (s *Server) SetWebhooks(store *events.Store, key string) {
+	s.events = store
+	s.webhookKey = strings.TrimSpace(key)
+	s.toolbox.Register(liveEventsTool{store: store})
+}
+
+// serveWebhook records one PayRam webhook. PayRam sends them as GET requests with a JSON
+// body; POST is accepted too.
+func (s *Server) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.events == nil {
+		writeAPIError(w, http.StatusNotFound, "webhooks are not enabled")
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	tenantID, ok := s.webhookTenant(r.Header.Get(webhookKeyHeader))
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized: invalid webhook key")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		writeAPIError(w, http.StatusRequestEntityTooLarge, "webhook body too large")
+		return
+	}
+	event, err := events.Parse(body, tenantID)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := s.events.Add(event); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "store webhook: "+err.Error())
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+}
+
+// serveEvents lists the caller's recent webhook events as JSON, newest first:
+// GET /events?minutes=60&limit=100. Tenancy applies as on "/".
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.events == nil {
+		writeAPIError(w, http.StatusNotFound, "webhooks are not enabled")
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	ctx := r.Context()
+	if s.tenants != nil {
+		t, err := s.tenants.Resolve(r)
+		if err != nil {
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized: "+err.Error())
+			return
+		}
+		ctx = tenant.WithTenant(ctx, t)
+	}
+	minutes, ok := intParam(r, "minutes", defaultLiveMinutes, maxLiveMinutes)
+	if !ok {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("minutes must be between 1 and %d", maxLiveMinutes))
+		return
+	}
+	limit, ok := intParam(r, "limit", maxLiveLimit, maxLiveLimit)
+	if !ok {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxLiveLimit))
+		return
+	}
+	list := s.events.Recent(events.Filter{
+		Tenant: tenantID(ctx),
+		Since:  s.events.Now().Add(-time.Duration(minutes) * time.Minute),
+		Limit:  limit,
+	})
+	if s.maskPII {
+		for i := range list {
+			list[i] = maskEvent(list[i])
+		}
+	}
+	if list == nil {
+		list = []events.Event{}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"events": list})
+}
+
+// intParam reads a query parameter in [1, max], or def when it is absent.
+func intParam(r *http.Request, name string, def, max int) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > max {
+		return 0, false
+	}
+	return n, true
+}
+
+// maskEvent masks identifiers in an event's reference and payload strings.
+func maskEvent(e events.Event) events.Event {
+	e.ReferenceID = pii.Mask(e.ReferenceID)
+	payload := make(map[string]any, len(e.Payload))
+	for k, v := range e.Payload {
+		if s, ok := v.(string); ok {
+			v = pii.Mask(s)
+		}
+		payload[k] = v
+	}
+	e.Payload = payload
+	return e
+}
+
+// webhookTenant checks a webhook's key and returns the tenant it belongs to (empty without
+// tenancy).
+func (s *Server) webhookTenant(key string) (string, bool) {
+	if s.tenants != nil {
+		t, ok := s.tenants.ResolveWebhook(key)
+		if !ok {
+			return "", false
+		}
+		return t.ID, true
+	}
+	key = strings.TrimSpace(key)
+	if s.webhookKey == "" || subtle.ConstantTimeCompare([]byte(s.webhookKey), []byte(key)) != 1 {
+		return "", false
+	}
+	return "", true
+}
+
+// liveEventsTool searches the payment webhooks received recently.
+type liveEventsTool struct {
+	store *events.Store
+}
+
+func (liveEventsTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{
+		Name:        "payram_live_events",
+		Description: "Search the payment webhooks PayRam sent in the last minutes, newest first. Use this for questions like 'did invoice X just get paid?': it sees payments as they happen, before they show up in analytics.",
+		InputSchema: &protocol.JSONSchema{
+			Type: "object",
+			Properties: map[string]protocol.JSONSchema{
+				"reference_id":  {Type: "string", Description: "Payment or invoice reference ID to look for"},
+				"payment_state": {Type: "string", Description: "Only events in this state, e.g. FILLED, PARTIALLY_FILLED, OPEN, CANCELLED"},
+				"minutes":       {Type: "integer", Description: "How far back to look (default 60, max 10080)"},
+				"limit":         {Type: "integer", Description: "Most events to return (default 20, max 100)"},
+			},
+		},
+	}
+}
+
+func (t liveEventsTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	var args struct {
+		ReferenceID  string `json:"reference_id"`
+		PaymentState string `json:"payment_state"`
+		Minutes      int    `json:"minutes"`
+		Limit        int    `json:"limit"`
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
+		}
+	}
+	switch {
+	case args.Minutes == 0:
+		args.Minutes = defaultLiveMinutes
+	case args.Minutes < 0 || args.Minutes > maxLiveMinutes:
+		return protocol.CallResult{}, protocol.InvalidArgs(fmt.Sprintf("minutes must be between 1 and %d", maxLiveMinutes))
+	}
+	switch {
+	case args.Limit == 0:
+		args.Limit = defaultLiveLimit
+	case args.Limit < 0 || args.Limit > maxLiveLimit:
+		return protocol.CallResult{}, protocol.InvalidArgs(fmt.Sprintf("limit must be between 1 and %d", maxLiveLimit))
+	}
+	ref := strings.TrimSpace(args.ReferenceID)
+	found := t.store.Recent(events.Filter{
+		Tenant:      tenantID(ctx),
+		ReferenceID: ref,
+		State:       strings.TrimSpace(args.PaymentState),
+		Since:       t.store.Now().Add(-time.Duration(args.Minutes) * time.Minute),
+		Limit:       args.Limit,
+	})
+
+	var b strings.Builder
+	b.WriteString(i18n.Sprintf(ctx, "# Live Payment Events (last %d minutes)", args.Minutes))
+	b.WriteString("\n\n")
+	if len(found) == 0 {
+		if ref != "" {
+			b.WriteString(i18n.Sprintf(ctx, "No payment webhook for reference_id %s in the last %d minutes. PayRam sends one whenever a payment changes state, so it has not been paid yet, or its webhook has not arrived.", ref, args.Minutes))
+		} else {
+			b.WriteString(i18n.Sprintf(ctx, "No payment webhooks received in the last %d minutes.", args.Minutes))
+		}
+		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: b.String()}}}, nil
+	}
+	for _, e := range found {
+		b.WriteString(formatEvent(e))
+		b.WriteString("\n")
+	}
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimRight(b.String(), "\n")}}}, nil
+}
+
+// formatEvent is one line per event: the time and common fields, then the rest of the
+// payload.
+func formatEvent(e events.Event) string {
+	parts := []string{"- " + e.Received.Format(time.RFC3339)}
+	for _, f := range [][2]string{{"reference_id", e.ReferenceID}, {"payment_state", e.State}, {"amount", e.Amount}, {"currency", e.Currency}} {
+		if f[1] != "" {
+			parts = append(parts, f[0]+"="+f[1])
+		}
+	}
+	return strings.Join(append(parts, e.Details()...), " ")
+}
@@ -0,0 +1,69 @@
+package mcp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/events"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+func TestWebhooksFeedLiveEvents(t *testing.T) {
+	store, err := events.Open("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(NewToolbox())
+	server.SetWebhooks(store, "whk")
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	h := NewHTTPHandler(server, logrus.NewEntry(quiet))
+
+	send := func(method, key, body string) int {
+		req := httptest.NewRequest(method, webhooksPath, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(webhookKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := send(http.MethodGet, "wrong", `{"reference_id":"INV-1"}`); code != http.StatusUnauthorized {
+		t.Errorf("wrong key: status %d, want 401", code)
+	}
+	if code := send(http.MethodGet, "whk", `not json`); code != http.StatusBadRequest {
+		t.Errorf("bad body: status %d, want 400", code)
+	}
+	if code := send(http.MethodGet, "whk", `{"reference_id":"INV-1","payment_state":"FILLED","amount":"25","currency_code":"USDC"}`); code != http.StatusOK {
+		t.Fatalf("webhook: status %d, want 200", code)
+	}
+
+	resp := callTool(t, server, "payram_live_events", `{"reference_id":"inv-1"}`)
+	if resp.Error != nil {
+		t.Fatalf("payram_live_events: %+v", resp.Error)
+	}
+	text := resp.Result.(protocol.CallResult).Content[0].Text
+	if !strings.Contains(text, "reference_id=INV-1 payment_state=FILLED amount=25 currency=USDC") {
+		t.Errorf("live events = %q", text)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, eventsPath+"?minutes=5", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"reference_id":"INV-1"`) {
+		t.Errorf("GET /events = %d %s", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, eventsPath+"?minutes=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /events?minutes=0 = %d, want 400", rec.Code)
+	}
+
+	resp = callTool(t, server, "payram_live_events", `{"reference_id":"INV-2"}`)
+	if text := resp.Result.(protocol.CallResult).Content[0].Text; !strings.Contains(text, "No payment webhook for reference_id INV-2") {
+		t.Errorf("missing event = %q", text)
+	}
+}
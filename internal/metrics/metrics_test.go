@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritesPrometheusText(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("b_total", "things counted").Add(3)
+	r.Counter("b_total", "ignored duplicate").Inc()
+	r.Gauge("a_gauge", "a gauge").Set(-1)
+	r.GaugeFunc("c_func", "computed", func() float64 { return 2.5 })
+	h := r.Histogram("d_seconds", "durations", []float64{1, 0.1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	var sb strings.Builder
+	r.Write(&sb)
+	out := sb.String()
+
+	for _, want := range []string{
+		"# TYPE a_gauge gauge\na_gauge -1\n",
+		"# HELP b_total things counted\n# TYPE b_total counter\nb_total 4\n",
+		"c_func 2.5\n",
+		`d_seconds_bucket{le="0.1"} 1`,
+		`d_seconds_bucket{le="1"} 2`,
+		`d_seconds_bucket{le="+Inf"} 3`,
+		"d_seconds_sum 5.55\nd_seconds_count 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "a_gauge") > strings.Index(out, "b_total") {
+		t.Fatalf("expected metrics sorted by name:\n%s", out)
+	}
+}
+
+func TestGaugeVecFuncWritesLabeledSeries(t *testing.T) {
+	r := NewRegistry()
+	r.GaugeVecFunc("e_ratio", "labeled", func() []LabeledValue {
+		return []LabeledValue{
+			{Labels: map[string]string{"tool": "b", "kind": "x"}, Value: 0.5},
+			{Labels: map[string]string{"tool": `a"1`}, Value: 1},
+		}
+	})
+
+	var sb strings.Builder
+	r.Write(&sb)
+	want := "# TYPE e_ratio gauge\ne_ratio{kind=\"x\",tool=\"b\"} 0.5\ne_ratio{tool=\"a\\\"1\"} 1\n"
+	if !strings.Contains(sb.String(), want) {
+		t.Fatalf("missing %q in:\n%s", want, sb.String())
+	}
+}
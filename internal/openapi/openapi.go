@@ -0,0 +1,56 @@
+// Package openapi serves embedded OpenAPI 3 documents at /openapi.json, stamped with the
+// running build's version so generated clients and contract tests can tell releases apart.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/version"
+)
+
+// Path is where services serve their document.
+const Path = "/openapi.json"
+
+// Paths returns the paths doc describes.
+func Paths(doc []byte) ([]string, error) {
+	var d struct {
+		Paths map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(doc, &d); err != nil {
+		return nil, fmt.Errorf("parse openapi document: %w", err)
+	}
+	out := make([]string, 0, len(d.Paths))
+	for p := range d.Paths {
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Handler serves doc with info.version set to the build version. It panics if doc is not a
+// JSON object with an info object, which for an embedded document is a build mistake.
+func Handler(doc []byte) http.Handler {
+	var d map[string]any
+	if err := json.Unmarshal(doc, &d); err != nil {
+		panic(fmt.Sprintf("openapi: invalid document: %v", err))
+	}
+	info, ok := d["info"].(map[string]any)
+	if !ok {
+		panic("openapi: document has no info object")
+	}
+	info["version"] = version.Get().Version
+	body, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("openapi: encode document: %v", err))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
@@ -0,0 +1,51 @@
+// Package pii finds customer identifiers (emails, wallet addresses, transaction hashes) in
+// text and masks them.
+package pii
+
+import (
+	"regexp"
+	"strings"
+)
+
+// keep is how many trailing characters Mask leaves visible.
+const keep = 4
+
+// patterns match the identifiers Mask hides, by name. Transaction hashes are matched before
+// EVM addresses, which are a shorter run of the same characters.
+var patterns = []struct {
+	name    string
+	pattern string
+}{
+	{"email", `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+	{"tx_hash", `\b0x[0-9a-fA-F]{64}\b`},
+	{"evm_address", `\b0x[0-9a-fA-F]{40}\b`},
+	{"btc_address", `\b(bc1[02-9ac-hj-np-z]{11,71}|[13][1-9A-HJ-NP-Za-km-z]{25,34})\b`},
+	{"tron_address", `\bT[1-9A-HJ-NP-Za-km-z]{33}\b`},
+}
+
+var all = func() *regexp.Regexp {
+	alts := make([]string, len(patterns))
+	for i, p := range patterns {
+		alts[i] = "(?:" + p.pattern + ")"
+	}
+	return regexp.MustCompile(strings.Join(alts, "|"))
+}()
+
+// Pattern returns the regular expression for a named kind of identifier: "email", "tx_hash",
+// "evm_address", "btc_address" or "tron_address".
+func Pattern(name string) (string, bool) {
+	for _, p := range patterns {
+		if p.name == name {
+			return p.pattern, true
+		}
+	}
+	return "", false
+}
+
+// Mask replaces every identifier in s with asterisks followed by its last four characters,
+// e.g. "0x52908400098527886e0f7030069857d2e4169ee7" becomes "****9ee7".
+func Mask(s string) string {
+	return all.ReplaceAllStringFunc(s, func(m string) string {
+		return "****" + m[len(m)-keep:]
+	})
+}
@@ -0,0 +1,19 @@
+package pii
+
+import "testing"
+
+func TestMask(t *testing.T) {
+	cases := map[string]string{
+		"from jane.doe@example.com today":                                                       "from ****.com today",
+		"to 0x52908400098527886E0F7030069857D2E4169EE7.":                                        "to ****9EE7.",
+		"tx 0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b":                 "tx ****944b",
+		"btc bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq and 1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2": "btc ****5mdq and ****NVN2",
+		"tron TJRabPrwbZy45sbavfcjinPJC18kjpRTv8":                                               "tron ****RTv8",
+		"amount 1200.50 USDT, order 0x1234":                                                     "amount 1200.50 USDT, order 0x1234",
+	}
+	for in, want := range cases {
+		if got := Mask(in); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ErrorCategory classifies a tool failure so clients can react to it without parsing messages.
+type ErrorCategory string
+
+// Error categories.
+const (
+	// CategoryAuth means the analytics credentials are missing or were rejected.
+	CategoryAuth ErrorCategory = "AUTH"
+	// CategoryUpstreamUnavailable means the analytics API could not be reached or failed.
+	CategoryUpstreamUnavailable ErrorCategory = "UPSTREAM_UNAVAILABLE"
+	// CategoryNotFound means the requested group, graph, or document does not exist.
+	CategoryNotFound ErrorCategory = "NOT_FOUND"
+	// CategoryInvalidArgs means the call's arguments were rejected.
+	CategoryInvalidArgs ErrorCategory = "INVALID_ARGS"
+	// CategoryQuotaExceeded means the caller's daily quota is used up.
+	CategoryQuotaExceeded ErrorCategory = "QUOTA_EXCEEDED"
+	// CategoryReadOnly means the tool changes state and the server runs in read-only mode.
+	CategoryReadOnly ErrorCategory = "READ_ONLY"
+	// CategoryInternal is any other failure.
+	CategoryInternal ErrorCategory = "INTERNAL"
+)
+
+// JSON-RPC error codes, one per category. Invalid arguments and internal errors use the
+// standard JSON-RPC codes; the rest sit in the implementation-defined server error range.
+const (
+	CodeInvalidArgs         = -32602
+	CodeInternal            = -32603
+	CodeAuth                = -32001
+	CodeQuotaExceeded       = -32002
+	CodeUpstreamUnavailable = -32003
+	CodeNotFound            = -32004
+	CodeReadOnly            = -32005
+)
+
+var categoryCodes = map[ErrorCategory]int{
+	CategoryAuth:                CodeAuth,
+	CategoryUpstreamUnavailable: CodeUpstreamUnavailable,
+	CategoryNotFound:            CodeNotFound,
+	CategoryInvalidArgs:         CodeInvalidArgs,
+	CategoryQuotaExceeded:       CodeQuotaExceeded,
+	CategoryReadOnly:            CodeReadOnly,
+	CategoryInternal:            CodeInternal,
+}
+
+// Code returns the JSON-RPC error code for c.
+func (c ErrorCategory) Code() int {
+	if code, ok := categoryCodes[c]; ok {
+		return code
+	}
+	return CodeInternal
+}
+
+// ErrorData is the machine-readable data of a tool error.
+type ErrorData struct {
+	Category ErrorCategory `json:"category"`
+	// Retryable reports whether the same call may succeed later without changes.
+	Retryable bool `json:"retryable"`
+	// UpstreamStatus is the analytics API's HTTP status when it caused the failure.
+	UpstreamStatus int `json:"upstream_status,omitempty"`
+	// Exchanges are the upstream HTTP calls made before the failure, when recording was asked for.
+	Exchanges []HTTPExchange `json:"payram/exchanges,omitempty"`
+}
+
+// NewError builds a tool error of category c. Only upstream outages are retryable by default.
+func NewError(c ErrorCategory, message string) *ResponseError {
+	return &ResponseError{
+		Code:    c.Code(),
+		Message: message,
+		Data:    &ErrorData{Category: c, Retryable: c == CategoryUpstreamUnavailable},
+	}
+}
+
+// Errorf is NewError with a formatted message.
+func Errorf(c ErrorCategory, format string, args ...any) *ResponseError {
+	return NewError(c, fmt.Sprintf(format, args...))
+}
+
+// InvalidArgs reports rejected call arguments.
+func InvalidArgs(message string) *ResponseError { return NewError(CategoryInvalidArgs, message) }
+
+// NotFound reports a missing group, graph, or document.
+func NotFound(message string) *ResponseError { return NewError(CategoryNotFound, message) }
+
+// UpstreamStatusCategory maps an analytics API status to the category of the failure it
+// reports, and whether retrying can help.
+func UpstreamStatusCategory(status int) (ErrorCategory, bool) {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return CategoryAuth, false
+	case status == http.StatusNotFound:
+		return CategoryNotFound, false
+	case status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500:
+		return CategoryUpstreamUnavailable, true
+	case status >= 400:
+		return CategoryInvalidArgs, false
+	default:
+		return CategoryUpstreamUnavailable, false
+	}
+}
+
+// UpstreamStatusError reports a non-2xx analytics API response with status as its
+// upstream_status.
+func UpstreamStatusError(status int, message string) *ResponseError {
+	c, retryable := UpstreamStatusCategory(status)
+	return &ResponseError{
+		Code:    c.Code(),
+		Message: message,
+		Data:    &ErrorData{Category: c, Retryable: retryable, UpstreamStatus: status},
+	}
+}
+
+// DataOf returns the error data of e, also when e was decoded from JSON and Data is a map.
+func DataOf(e *ResponseError) (ErrorData, bool) {
+	if e == nil || e.Data == nil {
+		return ErrorData{}, false
+	}
+	if d, ok := e.Data.(*ErrorData); ok {
+		return *d, true
+	}
+	raw, err := json.Marshal(e.Data)
+	if err != nil {
+		return ErrorData{}, false
+	}
+	var d ErrorData
+	if json.Unmarshal(raw, &d) != nil || d.Category == "" {
+		return ErrorData{}, false
+	}
+	return d, true
+}
@@ -0,0 +1,50 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUpstreamStatusError(t *testing.T) {
+	cases := []struct {
+		status    int
+		category  ErrorCategory
+		code      int
+		retryable bool
+	}{
+		{401, CategoryAuth, CodeAuth, false},
+		{403, CategoryAuth, CodeAuth, false},
+		{404, CategoryNotFound, CodeNotFound, false},
+		{400, CategoryInvalidArgs, CodeInvalidArgs, false},
+		{422, CategoryInvalidArgs, CodeInvalidArgs, false},
+		{429, CategoryUpstreamUnavailable, CodeUpstreamUnavailable, true},
+		{502, CategoryUpstreamUnavailable, CodeUpstreamUnavailable, true},
+		{302, CategoryUpstreamUnavailable, CodeUpstreamUnavailable, false},
+	}
+	for _, tc := range cases {
+		e := UpstreamStatusError(tc.status, "unexpected status")
+		d, ok := DataOf(e)
+		if !ok || e.Code != tc.code || d.Category != tc.category || d.Retryable != tc.retryable || d.UpstreamStatus != tc.status {
+			t.Errorf("status %d: code %d data %+v", tc.status, e.Code, d)
+		}
+	}
+}
+
+func TestDataOfDecodedError(t *testing.T) {
+	raw, err := json.Marshal(NewError(CategoryUpstreamUnavailable, "http error: refused"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ResponseError
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	d, ok := DataOf(&decoded)
+	if !ok || d.Category != CategoryUpstreamUnavailable || !d.Retryable || decoded.Code != CodeUpstreamUnavailable {
+		t.Fatalf("decoded %s as code %d data %+v", raw, decoded.Code, d)
+	}
+
+	if _, ok := DataOf(&ResponseError{Code: CodeQuotaExceeded, Data: map[string]any{"used": 3}}); ok {
+		t.Fatal("data without a category reported as error data")
+	}
+}
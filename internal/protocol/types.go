@@ -1,6 +1,9 @@
 package protocol
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Request represents a minimal JSON-RPC 2.0 request.
 type Request struct {
@@ -60,7 +63,87 @@ type ContentPart struct {
 	Text string `json:"text"`
 }
 
+// Chart kinds.
+const (
+	ChartTimeSeries = "timeseries"
+	ChartPie        = "pie"
+	ChartBar        = "bar"
+)
+
+// ChartData is structured series data a client can plot. Every series has one value per label.
+type ChartData struct {
+	Kind   string        `json:"kind"`
+	Title  string        `json:"title,omitempty"`
+	Labels []string      `json:"labels"`
+	Series []ChartSeries `json:"series"`
+}
+
+// ChartSeries is one named line, slice set, or bar group.
+type ChartSeries struct {
+	Name   string    `json:"name"`
+	Values []float64 `json:"values"`
+}
+
 // CallResult is the payload for a successful tool invocation.
 type CallResult struct {
 	Content []ContentPart `json:"content"`
+	Meta    *CallMeta     `json:"_meta,omitempty"`
+}
+
+// CallMeta is out-of-band data attached to a tool result.
+type CallMeta struct {
+	// Exchanges are the upstream HTTP calls the tool made, returned when the caller asked
+	// for recording (see internal/recording).
+	Exchanges []HTTPExchange `json:"payram/exchanges,omitempty"`
+	// Job is set when the call was queued as a background job instead of run.
+	Job *JobRef `json:"payram/job,omitempty"`
+	// Sources are the analytics graphs the result's figures were read from (see
+	// internal/provenance).
+	Sources []DataSource `json:"payram/sources,omitempty"`
+	// Degraded is set when some of the result was answered from cached analytics responses
+	// because the PayRam core was unreachable (see internal/degraded).
+	Degraded *Degraded `json:"payram/degraded,omitempty"`
+	// Charts are plottable versions of figures already in the text content, for clients
+	// that draw them. MCP has no chart content type, so they travel out of band.
+	Charts []ChartData `json:"payram/charts,omitempty"`
+}
+
+// Degraded says how stale a result answered from cached analytics responses is.
+type Degraded struct {
+	// AsOf is when the oldest cached response used was fetched.
+	AsOf time.Time `json:"as_of"`
+	// Responses counts the cached responses used.
+	Responses int `json:"responses"`
+}
+
+// DataSource is one analytics graph a tool read: which graph, the date range and filters it
+// was asked for, and when.
+type DataSource struct {
+	GroupID    int    `json:"group_id"`
+	GraphID    int    `json:"graph_id"`
+	DateFilter string `json:"date_filter,omitempty"`
+	StartDate  string `json:"start_date,omitempty"`
+	EndDate    string `json:"end_date,omitempty"`
+	// Filters are the other fields of the request body, such as currency_codes.
+	Filters   map[string]any `json:"filters,omitempty"`
+	Pages     int            `json:"pages"`
+	FetchedAt time.Time      `json:"fetched_at"`
+}
+
+// JobRef identifies a background job (see internal/jobs).
+type JobRef struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// HTTPExchange is one recorded upstream HTTP request and its response, with credentials redacted.
+type HTTPExchange struct {
+	Method       string `json:"method"`
+	URL          string `json:"url"`
+	RequestBody  string `json:"request_body,omitempty"`
+	Status       int    `json:"status,omitempty"`
+	ResponseBody string `json:"response_body,omitempty"`
+	Truncated    bool   `json:"truncated,omitempty"`
+	DurationMs   int64  `json:"duration_ms"`
+	Error        string `json:"error,omitempty"`
 }
@@ -0,0 +1,176 @@
+// Package provenance notes which analytics graphs tools read while serving a request, with
+// the date range and filters asked for and when, so every figure a tool returns can be traced
+// back to its source.
+package provenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/degraded"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// graphPath matches the analytics graph data endpoint, capturing the group and graph IDs.
+var graphPath = regexp.MustCompile(`/analytics/groups/(\d+)/graph/(\d+)/data$`)
+
+// pageKeys are request fields that select a page rather than the data; the pages of one
+// graph are noted as a single source.
+var pageKeys = []string{"page", "cursor", "page_size", "limit", "offset"}
+
+// maxRequestBody caps how much of a request body is read to learn its filters.
+const maxRequestBody = 64 << 10
+
+// Trail collects the sources read under one context.
+type Trail struct {
+	mu      sync.Mutex
+	sources []protocol.DataSource
+	index   map[string]int
+}
+
+type trailKey struct{}
+
+// WithTrail returns ctx under which Transport notes sources into the returned Trail.
+func WithTrail(ctx context.Context) (context.Context, *Trail) {
+	t := &Trail{index: map[string]int{}}
+	return context.WithValue(ctx, trailKey{}, t), t
+}
+
+// Sources returns what has been noted so far, in the order the graphs were first read.
+func (t *Trail) Sources() []protocol.DataSource {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]protocol.DataSource(nil), t.sources...)
+}
+
+func (t *Trail) add(key string, s protocol.DataSource) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i, ok := t.index[key]; ok {
+		t.sources[i].Pages++
+		return
+	}
+	t.index[key] = len(t.sources)
+	t.sources = append(t.sources, s)
+}
+
+// Transport wraps base so successful graph data requests made under a WithTrail context are
+// noted. Without a trail it is a pass-through.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trail, _ := req.Context().Value(trailKey{}).(*Trail)
+	if trail == nil || req.Method != http.MethodPost {
+		return t.base.RoundTrip(req)
+	}
+	m := graphPath.FindStringSubmatch(req.URL.Path)
+	if m == nil {
+		return t.base.RoundTrip(req)
+	}
+	group, _ := strconv.Atoi(m[1])
+	graph, _ := strconv.Atoi(m[2])
+	var payload map[string]any
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			raw, _ := io.ReadAll(io.LimitReader(body, maxRequestBody))
+			body.Close()
+			_ = json.Unmarshal(raw, &payload)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, err
+	}
+	s := source(group, graph, payload)
+	s.FetchedAt = time.Now().UTC()
+	if at, err := time.Parse(time.RFC3339, resp.Header.Get(degraded.StaleHeader)); err == nil {
+		// Answered from the last good copy while the core was down.
+		s.FetchedAt = at.UTC()
+	}
+	trail.add(key(s), s)
+	return resp, nil
+}
+
+// source describes a request for graph with payload, the analytics request body.
+func source(group, graph int, payload map[string]any) protocol.DataSource {
+	s := protocol.DataSource{GroupID: group, GraphID: graph, Pages: 1}
+	for k, v := range payload {
+		switch k {
+		case "analytics_date_filter":
+			s.DateFilter, _ = v.(string)
+		case "custom":
+			if custom, ok := v.(map[string]any); ok {
+				s.DateFilter = "custom"
+				s.StartDate, _ = custom["start_date"].(string)
+				s.EndDate, _ = custom["end_date"].(string)
+			}
+		default:
+			if !slices.Contains(pageKeys, k) {
+				if s.Filters == nil {
+					s.Filters = map[string]any{}
+				}
+				s.Filters[k] = v
+			}
+		}
+	}
+	return s
+}
+
+// key identifies the graph and filters of s, so its pages share one entry.
+func key(s protocol.DataSource) string {
+	return fmt.Sprintf("%d/%d/%s/%s/%s/%s", s.GroupID, s.GraphID, s.DateFilter, s.StartDate, s.EndDate, strings.Join(filters(s), "/"))
+}
+
+// filters lists the filters of s as name=JSON value, sorted by name.
+func filters(s protocol.DataSource) []string {
+	out := make([]string, 0, len(s.Filters))
+	for k, v := range s.Filters {
+		raw, _ := json.Marshal(v)
+		out = append(out, k+"="+string(raw))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Describe renders s on one line for people, e.g.
+// "group 3, graph 12: last_7_days, currency_codes=["USDC"] (fetched 2026-01-02T15:04:05Z)".
+func Describe(s protocol.DataSource) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "group %d, graph %d: ", s.GroupID, s.GraphID)
+	switch {
+	case s.StartDate != "" || s.EndDate != "":
+		fmt.Fprintf(&b, "%s to %s", s.StartDate, s.EndDate)
+	case s.DateFilter != "":
+		b.WriteString(s.DateFilter)
+	default:
+		b.WriteString("default date range")
+	}
+	for _, f := range filters(s) {
+		b.WriteString(", " + f)
+	}
+	if s.Pages > 1 {
+		fmt.Fprintf(&b, ", %d pages", s.Pages)
+	}
+	fmt.Fprintf(&b, " (fetched %s)", s.FetchedAt.UTC().Format(time.RFC3339))
+	return b.String()
+}
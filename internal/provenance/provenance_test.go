@@ -0,0 +1,63 @@
+package provenance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransportNotesGraphReads(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/graph/9/") {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: Transport(nil)}
+	post := func(ctx context.Context, path, body string) {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+path, strings.NewReader(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		resp.Body.Close()
+	}
+	graph := "/api/v1/external-platform/all/analytics/groups/3/graph/12/data"
+
+	ctx, trail := WithTrail(context.Background())
+	post(ctx, graph, `{"analytics_date_filter":"last_7_days","currency_codes":["USDC"]}`)
+	post(ctx, graph, `{"analytics_date_filter":"last_7_days","currency_codes":["USDC"],"page":2}`)
+	post(ctx, graph, `{"custom":{"start_date":"2026-01-01","end_date":"2026-01-31"}}`)
+	post(ctx, "/api/v1/external-platform/all/analytics/groups/3/graph/9/data", `{}`)
+	post(ctx, "/api/v1/external-platform/all/analytics/groups", `{}`)
+	post(context.Background(), graph, `{}`)
+
+	got := trail.Sources()
+	if len(got) != 2 {
+		t.Fatalf("noted %d sources, want 2: %+v", len(got), got)
+	}
+	first := got[0]
+	if first.GroupID != 3 || first.GraphID != 12 || first.DateFilter != "last_7_days" || first.Pages != 2 || time.Since(first.FetchedAt) > time.Minute {
+		t.Fatalf("first source: %+v", first)
+	}
+	if _, ok := first.Filters["page"]; ok || len(first.Filters) != 1 {
+		t.Fatalf("filters: %v", first.Filters)
+	}
+	if s := got[1]; s.DateFilter != "custom" || s.StartDate != "2026-01-01" || s.EndDate != "2026-01-31" || s.Filters != nil {
+		t.Fatalf("custom range source: %+v", s)
+	}
+
+	line := Describe(first)
+	if !strings.HasPrefix(line, `group 3, graph 12: last_7_days, currency_codes=["USDC"], 2 pages (fetched `) {
+		t.Fatalf("Describe = %q", line)
+	}
+	if line := Describe(got[1]); !strings.HasPrefix(line, "group 3, graph 12: 2026-01-01 to 2026-01-31 (fetched ") {
+		t.Fatalf("Describe = %q", line)
+	}
+}
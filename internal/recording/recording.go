@@ -0,0 +1,274 @@
+// Package recording captures the upstream HTTP calls tools make while serving a request, so a
+// debugging bundle can show exactly what the analytics API returned, and replays them later.
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// Header asks the MCP HTTP server to return a tools/call's upstream exchanges in result._meta.
+const Header = "X-Record-Exchanges"
+
+// maxBody caps each recorded request or response body.
+const maxBody = 256 << 10
+
+// Capture collects exchanges made under one context.
+type Capture struct {
+	mu        sync.Mutex
+	exchanges []protocol.HTTPExchange
+}
+
+type captureKey struct{}
+
+// WithCapture returns ctx under which Transport records exchanges into the returned Capture.
+func WithCapture(ctx context.Context) (context.Context, *Capture) {
+	c := &Capture{}
+	return context.WithValue(ctx, captureKey{}, c), c
+}
+
+// Exchanges returns what has been recorded so far.
+func (c *Capture) Exchanges() []protocol.HTTPExchange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]protocol.HTTPExchange(nil), c.exchanges...)
+}
+
+func (c *Capture) add(e protocol.HTTPExchange) {
+	c.mu.Lock()
+	c.exchanges = append(c.exchanges, e)
+	c.mu.Unlock()
+}
+
+// Transport wraps base so requests made under a WithCapture context are recorded. Without
+// a capture it is a pass-through.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rp, _ := req.Context().Value(replayKey{}).(*Replay); rp != nil {
+		return rp.respond(req)
+	}
+	c, _ := req.Context().Value(captureKey{}).(*Capture)
+	if c == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	ex := protocol.HTTPExchange{Method: req.Method, URL: redactURL(req.URL)}
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			raw, truncated := readCapped(body)
+			ex.RequestBody, ex.Truncated = RedactBody(raw), truncated
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	ex.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		ex.Error = err.Error()
+		c.add(ex)
+		return nil, err
+	}
+
+	// Buffer the body so both the recording and the caller see it in full.
+	full, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(full))
+	ex.Status = resp.StatusCode
+	if len(full) > maxBody {
+		full, ex.Truncated = full[:maxBody], true
+	}
+	ex.ResponseBody = RedactBody(string(full))
+	if readErr != nil {
+		ex.Error = readErr.Error()
+	}
+	c.add(ex)
+	return resp, nil
+}
+
+// Replay answers requests from previously recorded exchanges instead of the network.
+type Replay struct {
+	mu        sync.Mutex
+	exchanges []protocol.HTTPExchange
+	used      []bool
+	misses    []string
+}
+
+type replayKey struct{}
+
+// NewReplay serves exchanges, each at most once.
+func NewReplay(exchanges []protocol.HTTPExchange) *Replay {
+	return &Replay{exchanges: exchanges, used: make([]bool, len(exchanges))}
+}
+
+// WithReplay returns ctx under which Transport answers from r. Replay takes precedence over capture.
+func WithReplay(ctx context.Context, r *Replay) context.Context {
+	return context.WithValue(ctx, replayKey{}, r)
+}
+
+// Misses lists requests that had no recorded exchange, as "METHOD URL".
+func (r *Replay) Misses() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.misses...)
+}
+
+// Unused reports how many recorded exchanges were never requested.
+func (r *Replay) Unused() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, u := range r.used {
+		if !u {
+			n++
+		}
+	}
+	return n
+}
+
+// respond returns the first unused exchange with the same method, redacted URL and request
+// body, falling back to one that only matches method and URL.
+func (r *Replay) respond(req *http.Request) (*http.Response, error) {
+	u := redactURL(req.URL)
+	var body string
+	if req.Body != nil && req.GetBody != nil {
+		if b, err := req.GetBody(); err == nil {
+			raw, _ := readCapped(b)
+			body = RedactBody(raw)
+		}
+	}
+
+	r.mu.Lock()
+	match := -1
+	for i, ex := range r.exchanges {
+		if r.used[i] || ex.Method != req.Method || ex.URL != u {
+			continue
+		}
+		if ex.RequestBody == body {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		r.misses = append(r.misses, req.Method+" "+u)
+		r.mu.Unlock()
+		return nil, fmt.Errorf("replay: no recorded response for %s %s", req.Method, u)
+	}
+	r.used[match] = true
+	ex := r.exchanges[match]
+	r.mu.Unlock()
+
+	if ex.Error != "" && ex.Status == 0 {
+		return nil, errors.New(ex.Error)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+		StatusCode:    ex.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(ex.ResponseBody)),
+		ContentLength: int64(len(ex.ResponseBody)),
+		Request:       req,
+	}, nil
+}
+
+func readCapped(r io.ReadCloser) (string, bool) {
+	defer r.Close()
+	raw, _ := io.ReadAll(io.LimitReader(r, maxBody+1))
+	if len(raw) > maxBody {
+		return string(raw[:maxBody]), true
+	}
+	return string(raw), false
+}
+
+// sensitiveKeys are JSON keys and query parameters whose values are never recorded. Matching is
+// exact (case-insensitive) so analytics fields such as token_symbol survive.
+var sensitiveKeys = map[string]bool{
+	"token": true, "access_token": true, "refresh_token": true, "id_token": true,
+	"password": true, "secret": true, "client_secret": true, "api_key": true, "apikey": true,
+	"authorization": true, "private_key": true,
+}
+
+// IsSensitive reports whether a key names a credential.
+func IsSensitive(key string) bool {
+	return sensitiveKeys[strings.ToLower(key)]
+}
+
+func redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	q := c.Query()
+	changed := false
+	for k := range q {
+		if IsSensitive(k) {
+			q.Set(k, "[redacted]")
+			changed = true
+		}
+	}
+	if changed {
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
+
+// RedactBody replaces credential values in a JSON body. Bodies without any, and non-JSON
+// bodies, are returned byte for byte.
+func RedactBody(body string) string {
+	var v any
+	if json.Unmarshal([]byte(body), &v) != nil {
+		return body
+	}
+	if !redactValue(v) {
+		return body
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return string(out)
+}
+
+func redactValue(v any) bool {
+	changed := false
+	switch x := v.(type) {
+	case map[string]any:
+		for k, val := range x {
+			if IsSensitive(k) {
+				x[k] = "[redacted]"
+				changed = true
+				continue
+			}
+			changed = redactValue(val) || changed
+		}
+	case []any:
+		for _, val := range x {
+			changed = redactValue(val) || changed
+		}
+	}
+	return changed
+}
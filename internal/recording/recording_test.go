@@ -0,0 +1,66 @@
+package recording
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactBodyKeepsAnalyticsFields(t *testing.T) {
+	in := `{"token":"t-1","data":[{"token_symbol":"USDT","password":"p"}]}`
+	got := RedactBody(in)
+	if strings.Contains(got, "t-1") || strings.Contains(got, `"p"`) {
+		t.Fatalf("credentials survived: %s", got)
+	}
+	if !strings.Contains(got, `"token_symbol":"USDT"`) {
+		t.Fatalf("analytics field was redacted: %s", got)
+	}
+
+	plain := `{"total": 12.50, "count": 3}`
+	if got := RedactBody(plain); got != plain {
+		t.Fatalf("body without credentials changed: %s", got)
+	}
+}
+
+func TestTransportRecordsOnlyUnderCapture(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"ok":true,"access_token":"leak"}`)
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	ctx, capture := WithCapture(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/x?api_key=k&days=7", strings.NewReader(`{"days":7}`))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "leak") {
+		t.Fatalf("caller should see the unredacted body, got %s", body)
+	}
+
+	ex := capture.Exchanges()
+	if len(ex) != 1 {
+		t.Fatalf("recorded %d exchanges, want 1", len(ex))
+	}
+	e := ex[0]
+	if e.Status != 200 || e.RequestBody != `{"days":7}` || strings.Contains(e.ResponseBody, "leak") {
+		t.Fatalf("unexpected exchange: %+v", e)
+	}
+	if strings.Contains(e.URL, "api_key=k") || !strings.Contains(e.URL, "days=7") {
+		t.Fatalf("url not redacted as expected: %s", e.URL)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	if n := len(capture.Exchanges()); n != 1 {
+		t.Fatalf("request without capture was recorded (%d exchanges)", n)
+	}
+}
@@ -0,0 +1,193 @@
+// Package tenant maps MCP callers to the PayRam deployment they may query, so one hosted MCP
+// server can serve several merchants.
+package tenant
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+)
+
+// Request headers that select a tenant.
+const (
+	IDHeader  = "X-Tenant-ID"
+	KeyHeader = "X-Tenant-Key"
+)
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// Tenant is one PayRam deployment and the API keys that select it.
+type Tenant struct {
+	ID      string   `json:"id"`
+	BaseURL string   `json:"base_url"`
+	Token   string   `json:"token"`
+	APIKeys []string `json:"api_keys"`
+	Quotas  Quotas   `json:"quotas,omitzero"`
+	// Language is the default answer language for the tenant's callers, e.g. "es".
+	Language string `json:"language,omitempty"`
+	// WebhookKey is the API-Key PayRam sends with the tenant's payment webhooks.
+	WebhookKey string `json:"webhook_key,omitempty"`
+}
+
+// Registry holds the configured tenants.
+type Registry struct {
+	byID  map[string]*Tenant
+	byKey map[[sha256.Size]byte]*Tenant
+}
+
+type registryFile struct {
+	Tenants []Tenant `json:"tenants"`
+}
+
+// Load reads a registry file: {"tenants": [{"id", "base_url", "token", "api_keys": [...], "quotas": {...}}]}.
+// Every tenant needs at least one API key; keys must be unique across tenants. An optional
+// "language" sets the tenant's default answer language, and "webhook_key" the key its
+// payment webhooks are accepted with.
+func Load(file string) (*Registry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read tenants: %w", err)
+	}
+	var f registryFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("decode tenants: %w", err)
+	}
+	return New(f.Tenants)
+}
+
+// New validates tenants and builds a registry.
+func New(tenants []Tenant) (*Registry, error) {
+	if len(tenants) == 0 {
+		return nil, fmt.Errorf("no tenants configured")
+	}
+	r := &Registry{byID: map[string]*Tenant{}, byKey: map[[sha256.Size]byte]*Tenant{}}
+	for i := range tenants {
+		t := tenants[i]
+		t.BaseURL = strings.TrimSuffix(strings.TrimSpace(t.BaseURL), "/")
+		t.Token = strings.TrimSpace(t.Token)
+		switch {
+		case !idPattern.MatchString(t.ID):
+			return nil, fmt.Errorf("tenants[%d]: invalid id %q", i, t.ID)
+		case r.byID[t.ID] != nil:
+			return nil, fmt.Errorf("tenants[%d]: duplicate id %q", i, t.ID)
+		case t.BaseURL == "":
+			return nil, fmt.Errorf("tenant %s: base_url is required", t.ID)
+		case t.Token == "":
+			return nil, fmt.Errorf("tenant %s: token is required", t.ID)
+		case len(t.APIKeys) == 0:
+			return nil, fmt.Errorf("tenant %s: at least one api key is required", t.ID)
+		}
+		if t.Language != "" {
+			lang, ok := i18n.Parse(t.Language)
+			if !ok {
+				return nil, fmt.Errorf("tenant %s: invalid language %q", t.ID, t.Language)
+			}
+			t.Language = lang
+		}
+		t.WebhookKey = strings.TrimSpace(t.WebhookKey)
+		for _, other := range r.byID {
+			if t.WebhookKey != "" && t.WebhookKey == other.WebhookKey {
+				return nil, fmt.Errorf("tenant %s: webhook key already used by tenant %s", t.ID, other.ID)
+			}
+		}
+		r.byID[t.ID] = &t
+		for _, k := range t.APIKeys {
+			k = strings.TrimSpace(k)
+			if k == "" {
+				return nil, fmt.Errorf("tenant %s: empty api key", t.ID)
+			}
+			sum := sha256.Sum256([]byte(k))
+			if other := r.byKey[sum]; other != nil {
+				return nil, fmt.Errorf("tenant %s: api key already used by tenant %s", t.ID, other.ID)
+			}
+			r.byKey[sum] = &t
+		}
+	}
+	return r, nil
+}
+
+// Len reports the number of tenants.
+func (r *Registry) Len() int { return len(r.byID) }
+
+// All returns every tenant, sorted by ID.
+func (r *Registry) All() []*Tenant {
+	out := make([]*Tenant, 0, len(r.byID))
+	for _, t := range r.byID {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Resolve picks the tenant for an HTTP request from its API key (X-Tenant-Key or a bearer
+// token). X-Tenant-ID is optional; when present it must name the key's tenant.
+func (r *Registry) Resolve(req *http.Request) (*Tenant, error) {
+	key := strings.TrimSpace(req.Header.Get(KeyHeader))
+	if key == "" {
+		if v := req.Header.Get("Authorization"); len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+			key = strings.TrimSpace(v[7:])
+		}
+	}
+	if key == "" {
+		return nil, fmt.Errorf("missing tenant key")
+	}
+	t := r.lookupKey(key)
+	if t == nil {
+		return nil, fmt.Errorf("invalid tenant key")
+	}
+	if id := strings.TrimSpace(req.Header.Get(IDHeader)); id != "" && id != t.ID {
+		return nil, fmt.Errorf("tenant key does not belong to tenant %q", id)
+	}
+	return t, nil
+}
+
+func (r *Registry) lookupKey(key string) *Tenant {
+	sum := sha256.Sum256([]byte(key))
+	t := r.byKey[sum]
+	if t == nil {
+		return nil
+	}
+	// The map lookup is on a hash; compare the key itself in constant time as well.
+	for _, k := range t.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(k)), []byte(key)) == 1 {
+			return t
+		}
+	}
+	return nil
+}
+
+// ResolveWebhook returns the tenant whose webhook key is key.
+func (r *Registry) ResolveWebhook(key string) (*Tenant, bool) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, false
+	}
+	for _, t := range r.byID {
+		if t.WebhookKey != "" && subtle.ConstantTimeCompare([]byte(t.WebhookKey), []byte(key)) == 1 {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+type ctxKey struct{}
+
+// WithTenant returns ctx carrying t.
+func WithTenant(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// FromContext returns the tenant a request was resolved to, if any.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(ctxKey{}).(*Tenant)
+	return t, ok && t != nil
+}
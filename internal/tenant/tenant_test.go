@@ -0,0 +1,76 @@
+package tenant
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r, err := New([]Tenant{
+		{ID: "acme", BaseURL: "https://acme.example/", Token: "acme-token", APIKeys: []string{"k-acme"}},
+		{ID: "globex", BaseURL: "https://globex.example", Token: "globex-token", APIKeys: []string{"k-globex", "k-globex-2"}},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return r
+}
+
+func TestResolveSelectsTenantByKey(t *testing.T) {
+	r := testRegistry(t)
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set(KeyHeader, "k-globex-2")
+	got, err := r.Resolve(req)
+	if err != nil || got.ID != "globex" {
+		t.Fatalf("Resolve by key = %v, %v; want globex", got, err)
+	}
+
+	req = httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Authorization", "Bearer k-acme")
+	req.Header.Set(IDHeader, "acme")
+	got, err = r.Resolve(req)
+	if err != nil || got.ID != "acme" || got.BaseURL != "https://acme.example" {
+		t.Fatalf("Resolve by bearer = %+v, %v; want acme with trimmed base", got, err)
+	}
+}
+
+func TestResolveRejectsMissingWrongAndMismatchedKeys(t *testing.T) {
+	r := testRegistry(t)
+	cases := map[string]map[string]string{
+		"missing":  {},
+		"unknown":  {KeyHeader: "nope"},
+		"mismatch": {KeyHeader: "k-acme", IDHeader: "globex"},
+		"id only":  {IDHeader: "acme"},
+	}
+	for name, headers := range cases {
+		req := httptest.NewRequest("POST", "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		if got, err := r.Resolve(req); err == nil {
+			t.Errorf("%s: Resolve = %v, want error", name, got.ID)
+		}
+	}
+}
+
+func TestNewValidatesTenants(t *testing.T) {
+	cases := map[string][]Tenant{
+		"no key":             {{ID: "a", BaseURL: "https://a", Token: "t"}},
+		"no token":           {{ID: "a", BaseURL: "https://a", APIKeys: []string{"k"}}},
+		"bad id":             {{ID: "a/b", BaseURL: "https://a", Token: "t", APIKeys: []string{"k"}}},
+		"shared key":         {{ID: "a", BaseURL: "https://a", Token: "t", APIKeys: []string{"k"}}, {ID: "b", BaseURL: "https://b", Token: "t", APIKeys: []string{"k"}}},
+		"duplicate id":       {{ID: "a", BaseURL: "https://a", Token: "t", APIKeys: []string{"k1"}}, {ID: "a", BaseURL: "https://b", Token: "t", APIKeys: []string{"k2"}}},
+		"bad language":       {{ID: "a", BaseURL: "https://a", Token: "t", APIKeys: []string{"k"}, Language: "spanish please"}},
+		"shared webhook key": {{ID: "a", BaseURL: "https://a", Token: "t", APIKeys: []string{"k1"}, WebhookKey: "w"}, {ID: "b", BaseURL: "https://b", Token: "t", APIKeys: []string{"k2"}, WebhookKey: "w"}},
+	}
+	for name, tenants := range cases {
+		if _, err := New(tenants); err == nil {
+			t.Errorf("%s: New succeeded, want error", name)
+		} else if !strings.Contains(err.Error(), "tenant") {
+			t.Errorf("%s: error %q does not name the tenant", name, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package tenant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Usage kinds counted against daily quotas.
+const (
+	KindToolCalls = "tool_calls"
+	KindLLMCalls  = "llm_calls"
+)
+
+// Quotas caps a tenant's daily usage of expensive calls; zero means unlimited.
+type Quotas struct {
+	ToolCallsPerDay int `json:"tool_calls_per_day,omitempty"`
+	LLMCallsPerDay  int `json:"llm_calls_per_day,omitempty"`
+}
+
+// Limit returns the daily limit for kind.
+func (q Quotas) Limit(kind string) int {
+	switch kind {
+	case KindToolCalls:
+		return q.ToolCallsPerDay
+	case KindLLMCalls:
+		return q.LLMCallsPerDay
+	}
+	return 0
+}
+
+// Counter is one subject's usage of one kind for the current UTC day.
+type Counter struct {
+	Used     int       `json:"used"`
+	Limit    int       `json:"limit,omitempty"`
+	Day      string    `json:"day"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+// QuotaError reports a call refused because the subject's daily quota is used up.
+type QuotaError struct {
+	Subject string
+	Kind    string
+	Counter Counter
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s used %d of %d %s today; resets at %s",
+		e.Subject, e.Counter.Used, e.Counter.Limit, strings.ReplaceAll(e.Kind, "_", " "), e.Counter.ResetsAt.Format(time.RFC3339))
+}
+
+type usageFile struct {
+	Day    string                    `json:"day"`
+	Counts map[string]map[string]int `json:"counts"`
+}
+
+// UsageTracker counts calls per subject (tenant ID or caller) per UTC day, optionally
+// persisted to a JSON file so restarts do not reset quotas.
+type UsageTracker struct {
+	mu   sync.Mutex
+	path string
+	now  func() time.Time
+	data usageFile
+}
+
+// OpenUsage loads usage from file, or tracks in memory only when file is empty.
+func OpenUsage(file string) (*UsageTracker, error) {
+	u := &UsageTracker{path: strings.TrimSpace(file), now: time.Now, data: usageFile{Counts: map[string]map[string]int{}}}
+	if u.path == "" {
+		return u, nil
+	}
+	raw, err := os.ReadFile(u.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read usage: %w", err)
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &u.data); err != nil {
+			return nil, fmt.Errorf("decode usage: %w", err)
+		}
+		if u.data.Counts == nil {
+			u.data.Counts = map[string]map[string]int{}
+		}
+	}
+	return u, nil
+}
+
+// Take records one call of kind for subject unless that would exceed limit (0 = unlimited).
+// A refused call is not counted and returns a *QuotaError; any other error means the call was
+// counted but could not be persisted.
+func (u *UsageTracker) Take(subject, kind string, limit int) (Counter, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	day, resets := u.rollover()
+	counts := u.data.Counts[subject]
+	c := Counter{Used: counts[kind], Limit: limit, Day: day, ResetsAt: resets}
+	if limit > 0 && c.Used >= limit {
+		return c, &QuotaError{Subject: subject, Kind: kind, Counter: c}
+	}
+	if counts == nil {
+		counts = map[string]int{}
+		u.data.Counts[subject] = counts
+	}
+	counts[kind]++
+	c.Used++
+	if err := u.save(); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// Get returns subject's counters for today, with limits taken from quotas.
+func (u *UsageTracker) Get(subject string, quotas Quotas) map[string]Counter {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	day, resets := u.rollover()
+	out := map[string]Counter{}
+	for _, kind := range []string{KindToolCalls, KindLLMCalls} {
+		out[kind] = Counter{Used: u.data.Counts[subject][kind], Limit: quotas.Limit(kind), Day: day, ResetsAt: resets}
+	}
+	return out
+}
+
+// rollover clears counts from previous days. Callers hold u.mu.
+func (u *UsageTracker) rollover() (day string, resetsAt time.Time) {
+	now := u.now().UTC()
+	day = now.Format(time.DateOnly)
+	if u.data.Day != day {
+		u.data.Day = day
+		u.data.Counts = map[string]map[string]int{}
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return day, start.AddDate(0, 0, 1)
+}
+
+func (u *UsageTracker) save() error {
+	if u.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(u.data)
+	if err != nil {
+		return fmt.Errorf("encode usage: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(u.path), 0o755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(u.path), err)
+	}
+	tmp := u.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write usage: %w", err)
+	}
+	if err := os.Rename(tmp, u.path); err != nil {
+		return fmt.Errorf("rename usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package tenant
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageTakeEnforcesDailyLimit(t *testing.T) {
+	u, err := OpenUsage("")
+	if err != nil {
+		t.Fatalf("OpenUsage: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	u.now = func() time.Time { return now }
+
+	for i := 1; i <= 2; i++ {
+		c, err := u.Take("acme", KindToolCalls, 2)
+		if err != nil || c.Used != i {
+			t.Fatalf("take %d = %+v, %v", i, c, err)
+		}
+	}
+	_, err = u.Take("acme", KindToolCalls, 2)
+	var qe *QuotaError
+	if !errors.As(err, &qe) || qe.Counter.Used != 2 || !qe.Counter.ResetsAt.Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("third take err = %v, want quota error resetting at midnight", err)
+	}
+	if _, err := u.Take("acme", KindLLMCalls, 2); err != nil {
+		t.Fatalf("other kind should have its own counter: %v", err)
+	}
+	if _, err := u.Take("globex", KindToolCalls, 2); err != nil {
+		t.Fatalf("other subject should have its own counter: %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if c, err := u.Take("acme", KindToolCalls, 2); err != nil || c.Used != 1 || c.Day != "2024-05-02" {
+		t.Fatalf("after midnight take = %+v, %v; want fresh counter", c, err)
+	}
+}
+
+func TestUsagePersistsAcrossReopen(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "usage.json")
+	u, err := OpenUsage(file)
+	if err != nil {
+		t.Fatalf("OpenUsage: %v", err)
+	}
+	if _, err := u.Take("acme", KindLLMCalls, 0); err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+
+	reopened, err := OpenUsage(file)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got := reopened.Get("acme", Quotas{LLMCallsPerDay: 5})
+	if got[KindLLMCalls].Used != 1 || got[KindLLMCalls].Limit != 5 || got[KindToolCalls].Used != 0 {
+		t.Fatalf("Get after reopen = %+v", got)
+	}
+}
@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// labelFields are the keys, in order of preference, that name a data point's label.
+var labelFields = []string{"date", "day", "label", "name", "currency_code", "code", "currency", "network", "period", "x"}
+
+// chartFromGraph turns graph data JSON into chart data when its shape is recognizable:
+// a list of points ({"date": ..., "count": 3}), an object with labels plus datasets/series,
+// or a flat {"label": number} map. It returns nil for anything else.
+func chartFromGraph(kind, title, data string) *protocol.ChartData {
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return nil
+	}
+	if obj, ok := v.(map[string]any); ok {
+		if c := chartFromLabeled(obj); c != nil {
+			return finishChart(c, kind, title)
+		}
+		if inner, ok := obj["data"]; ok {
+			v = inner
+		} else if c := chartFromFlatMap(obj); c != nil {
+			return finishChart(c, kind, title)
+		}
+	}
+	if arr, ok := v.([]any); ok {
+		if c := chartFromPoints(arr); c != nil {
+			return finishChart(c, kind, title)
+		}
+	}
+	return nil
+}
+
+func finishChart(c *protocol.ChartData, kind, title string) *protocol.ChartData {
+	if len(c.Labels) == 0 || len(c.Series) == 0 {
+		return nil
+	}
+	c.Kind = kind
+	c.Title = title
+	if kind == protocol.ChartPie && len(c.Series) > 1 {
+		c.Series = c.Series[:1]
+	}
+	return c
+}
+
+// chartFromLabeled handles {"labels": [...], "datasets"|"series": [{"label"|"name": ..., "data"|"values": [...]}]}.
+func chartFromLabeled(obj map[string]any) *protocol.ChartData {
+	rawLabels, ok := obj["labels"].([]any)
+	if !ok {
+		return nil
+	}
+	sets, _ := obj["datasets"].([]any)
+	if sets == nil {
+		sets, _ = obj["series"].([]any)
+	}
+	c := &protocol.ChartData{}
+	for _, l := range rawLabels {
+		c.Labels = append(c.Labels, fmt.Sprint(l))
+	}
+	for i, s := range sets {
+		m, ok := s.(map[string]any)
+		if !ok {
+			continue
+		}
+		name := firstString(m, "label", "name")
+		if name == "" {
+			name = fmt.Sprintf("series %d", i+1)
+		}
+		vals, _ := m["data"].([]any)
+		if vals == nil {
+			vals, _ = m["values"].([]any)
+		}
+		series := protocol.ChartSeries{Name: name}
+		for _, x := range vals {
+			f, _ := toFloat(x)
+			series.Values = append(series.Values, f)
+		}
+		if len(series.Values) == len(c.Labels) {
+			c.Series = append(c.Series, series)
+		}
+	}
+	return c
+}
+
+// chartFromPoints handles [{"date": "2024-01-01", "count": 3, "amount_usd": 10.5}, ...]:
+// the label comes from a well-known key and every numeric key becomes a series.
+func chartFromPoints(arr []any) *protocol.ChartData {
+	if len(arr) == 0 {
+		return nil
+	}
+	first, ok := arr[0].(map[string]any)
+	if !ok {
+		return nil
+	}
+	labelKey := ""
+	for _, k := range labelFields {
+		if _, ok := first[k]; ok {
+			labelKey = k
+			break
+		}
+	}
+	if labelKey == "" {
+		return nil
+	}
+	var numeric []string
+	for k, v := range first {
+		if k == labelKey || strings.HasSuffix(k, "_id") || k == "id" {
+			continue
+		}
+		if _, ok := toFloat(v); ok {
+			numeric = append(numeric, k)
+		}
+	}
+	if len(numeric) == 0 {
+		return nil
+	}
+	sort.Strings(numeric)
+
+	c := &protocol.ChartData{}
+	series := make([]protocol.ChartSeries, len(numeric))
+	for i, k := range numeric {
+		series[i].Name = k
+	}
+	for _, item := range arr {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil
+		}
+		c.Labels = append(c.Labels, fmt.Sprint(m[labelKey]))
+		for i, k := range numeric {
+			f, _ := toFloat(m[k])
+			series[i].Values = append(series[i].Values, f)
+		}
+	}
+	c.Series = series
+	return c
+}
+
+// chartFromFlatMap handles {"USDC": 120.5, "BTC": 3}.
+func chartFromFlatMap(obj map[string]any) *protocol.ChartData {
+	keys := make([]string, 0, len(obj))
+	for k, v := range obj {
+		if _, ok := toFloat(v); !ok {
+			return nil
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	c := &protocol.ChartData{Labels: keys, Series: []protocol.ChartSeries{{Name: "value"}}}
+	for _, k := range keys {
+		f, _ := toFloat(obj[k])
+		c.Series[0].Values = append(c.Series[0].Values, f)
+	}
+	return c
+}
+
+func toFloat(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func firstString(m map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// chartResult is a text result with charts attached in its _meta.
+func chartResult(text string, charts []*protocol.ChartData) protocol.CallResult {
+	result := protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: text}}}
+	if len(charts) == 0 {
+		return result
+	}
+	meta := &protocol.CallMeta{Charts: make([]protocol.ChartData, len(charts))}
+	for i, c := range charts {
+		meta.Charts[i] = *c
+	}
+	result.Meta = meta
+	return result
+}
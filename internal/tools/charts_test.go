@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+func TestChartFromGraph(t *testing.T) {
+	series := func(name string, values ...float64) protocol.ChartSeries {
+		return protocol.ChartSeries{Name: name, Values: values}
+	}
+	cases := []struct {
+		name string
+		kind string
+		data string
+		want *protocol.ChartData
+	}{
+		{"points", protocol.ChartTimeSeries,
+			`[{"date":"2024-01-01","count":3,"amount_usd":"10.5","project_id":9},{"date":"2024-01-02","count":1,"amount_usd":2}]`,
+			&protocol.ChartData{Labels: []string{"2024-01-01", "2024-01-02"}, Series: []protocol.ChartSeries{series("amount_usd", 10.5, 2), series("count", 3, 1)}}},
+		{"points wrapped in data", protocol.ChartBar,
+			`{"data":[{"currency_code":"USDC","total":7}],"meta":{"total":1}}`,
+			&protocol.ChartData{Labels: []string{"USDC"}, Series: []protocol.ChartSeries{series("total", 7)}}},
+		{"labels and datasets", protocol.ChartBar,
+			`{"labels":["Mon","Tue"],"datasets":[{"label":"paid","data":[1,2]},{"data":[3,4]},{"label":"short","data":[1]}]}`,
+			&protocol.ChartData{Labels: []string{"Mon", "Tue"}, Series: []protocol.ChartSeries{series("paid", 1, 2), series("series 2", 3, 4)}}},
+		{"labels and series", protocol.ChartBar,
+			`{"labels":[1,2],"series":[{"name":"n","values":[5,6]}]}`,
+			&protocol.ChartData{Labels: []string{"1", "2"}, Series: []protocol.ChartSeries{series("n", 5, 6)}}},
+		{"flat map, sorted", protocol.ChartPie,
+			`{"USDT":4,"BTC":"1.5"}`,
+			&protocol.ChartData{Labels: []string{"BTC", "USDT"}, Series: []protocol.ChartSeries{series("value", 1.5, 4)}}},
+		{"pie keeps one series", protocol.ChartPie,
+			`[{"name":"a","x1":1,"x2":2}]`,
+			&protocol.ChartData{Labels: []string{"a"}, Series: []protocol.ChartSeries{series("x1", 1)}}},
+		{"invalid json", protocol.ChartBar, `{"data":`, nil},
+		{"empty list", protocol.ChartBar, `[]`, nil},
+		{"points without a label", protocol.ChartBar, `[{"count":3}]`, nil},
+		{"points without numbers", protocol.ChartBar, `[{"date":"2024-01-01","status":"paid"}]`, nil},
+		{"mixed flat map", protocol.ChartBar, `{"USDT":4,"note":"x"}`, nil},
+		{"labels without matching series", protocol.ChartBar, `{"labels":["a","b"],"datasets":[{"data":[1]}]}`, nil},
+		{"scalar", protocol.ChartBar, `42`, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := chartFromGraph(c.kind, "Graph", c.data)
+			if c.want != nil {
+				c.want.Kind, c.want.Title = c.kind, "Graph"
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				g, _ := json.Marshal(got)
+				w, _ := json.Marshal(c.want)
+				t.Fatalf("got %s\nwant %s", g, w)
+			}
+		})
+	}
+}
+
+func TestChartResultCarriesChartsInMeta(t *testing.T) {
+	chart := chartFromGraph(protocol.ChartPie, "By currency", `{"USDC":2}`)
+	raw, err := json.Marshal(chartResult("text", []*protocol.ChartData{chart}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Content []map[string]any                `json:"content"`
+		Meta    map[string][]protocol.ChartData `json:"_meta"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Content) != 1 || decoded.Content[0]["type"] != "text" {
+		t.Fatalf("content %s", raw)
+	}
+	if got := decoded.Meta["payram/charts"]; len(got) != 1 || !reflect.DeepEqual(got[0], *chart) {
+		t.Fatalf("_meta %s", raw)
+	}
+	if r := chartResult("text", nil); r.Meta != nil {
+		t.Fatalf("meta without charts: %+v", r.Meta)
+	}
+}
@@ -0,0 +1,23 @@
+package tools
+
+import (
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+)
+
+// clocked gives a tool the clock its relative date ranges ("last 7 days") are computed from.
+// The zero value uses the system clock.
+type clocked struct {
+	clock clock.Clock
+}
+
+// SetClock replaces the tool's clock, e.g. with clock.Fixed in tests and replays.
+func (c *clocked) SetClock(k clock.Clock) { c.clock = k }
+
+func (c *clocked) now() time.Time {
+	if c.clock == nil {
+		return clock.System.Now()
+	}
+	return c.clock.Now()
+}
@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// comparePreviousSchema is the compare_previous argument shared by the summary tools.
+var comparePreviousSchema = protocol.JSONSchema{
+	Type:        "boolean",
+	Description: "Also fetch the preceding period of equal length and report the change of each figure, for growth questions such as 'how did payments change vs the previous week'.",
+}
+
+// periodWindow resolves a date filter to the [start, end) it covers, for filters with a
+// previous period. "forever" has none.
+func periodWindow(now time.Time, dateFilter, customStart, customEnd string) (time.Time, time.Time, *protocol.ResponseError) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	switch dateFilter {
+	case "today":
+		return day, day.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return day.AddDate(0, 0, -1), day, nil
+	case "last_7_days":
+		return now.AddDate(0, 0, -7), now, nil
+	case "last_30_days":
+		return now.AddDate(0, 0, -30), now, nil
+	case "this_month":
+		return month, now, nil
+	case "last_month":
+		return month.AddDate(0, -1, 0), month, nil
+	case "last_6_months":
+		return now.AddDate(0, -6, 0), now, nil
+	case "custom":
+		start, err1 := parseRangeTime(customStart)
+		end, err2 := parseRangeTime(customEnd)
+		if err1 != nil || err2 != nil || !end.After(start) {
+			return time.Time{}, time.Time{}, protocol.InvalidArgs("compare_previous needs custom_start_date before custom_end_date, as RFC3339 or YYYY-MM-DD")
+		}
+		return start, end, nil
+	}
+	return time.Time{}, time.Time{}, protocol.InvalidArgs(fmt.Sprintf("compare_previous is not supported for date_filter %s", dateFilter))
+}
+
+func parseRangeTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// previousRange is the period of equal length ending where [start, end) begins, as RFC3339.
+func previousRange(start, end time.Time) (string, string) {
+	return start.Add(-end.Sub(start)).Format(time.RFC3339), start.Format(time.RFC3339)
+}
+
+// graphTotals extracts the headline figures of graph data: a {"value": n} number, the
+// total of each numeric series of a table or chart, or each entry of a flat number map.
+func graphTotals(data string) map[string]float64 {
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return nil
+	}
+	if obj, ok := v.(map[string]any); ok {
+		if f, ok := toFloat(obj["value"]); ok {
+			return map[string]float64{"value": f}
+		}
+		_, labeled := obj["labels"]
+		_, wrapped := obj["data"]
+		if !labeled && !wrapped {
+			if c := chartFromFlatMap(obj); c != nil {
+				totals := map[string]float64{}
+				for i, l := range c.Labels {
+					totals[l] = c.Series[0].Values[i]
+				}
+				return totals
+			}
+		}
+	}
+	c := chartFromGraph(protocol.ChartBar, "", data)
+	if c == nil {
+		return nil
+	}
+	totals := map[string]float64{}
+	for _, s := range c.Series {
+		sum := 0.0
+		for _, f := range s.Values {
+			sum += f
+		}
+		totals[s.Name] = sum
+	}
+	return totals
+}
+
+// writeChange compares the figures of a graph between the current and previous period.
+// schema is the graph's schemaKey; a drifted graph is not compared.
+func writeChange(ctx context.Context, b *strings.Builder, schema, name, current, previous string) {
+	b.WriteString(i18n.Sprintf(ctx, "### Change in %s\n", name))
+	if drift := checkGraphSchema(schema, name, current); drift != nil {
+		b.WriteString(i18n.Sprintf(ctx, "Warning: %s no longer has the structure this tool expects (%s), so its change is not computed.", name, strings.Join(drift.Changes, "; ")) + "\n\n")
+		return
+	}
+	now, before := graphTotals(current), graphTotals(previous)
+	keys := make([]string, 0, len(now))
+	for k := range now {
+		if _, ok := before[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		b.WriteString(i18n.Sprintf(ctx, "No numeric values to compare.\n\n"))
+		return
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cur, prev := now[k], before[k]
+		pct := "n/a"
+		if prev != 0 {
+			pct = signed((cur-prev)/math.Abs(prev)*100) + "%"
+		}
+		fmt.Fprintf(b, "- %s: %s vs %s (%s, %s)\n", k, formatFigure(cur), formatFigure(prev), signed(cur-prev), pct)
+	}
+	b.WriteString("\n")
+}
+
+func formatFigure(f float64) string {
+	r := math.Round(f*100) / 100
+	if r == 0 {
+		r = 0 // drop the sign of -0
+	}
+	return strconv.FormatFloat(r, 'f', -1, 64)
+}
+
+func signed(f float64) string {
+	s := formatFigure(f)
+	if !strings.HasPrefix(s, "-") {
+		s = "+" + s
+	}
+	return s
+}
@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
+)
+
+// resolveCredentials picks the analytics token and base URL for a call. A request resolved to
+// a tenant always uses that tenant's deployment and ignores overrides, so one tenant cannot
+// point the server at another's API; otherwise arguments win over the PAYRAM_ANALYTICS_* env.
+func resolveCredentials(ctx context.Context, argToken, argBase string) (token, base string, errResp *protocol.ResponseError) {
+	if t, ok := tenant.FromContext(ctx); ok {
+		return t.Token, t.BaseURL, nil
+	}
+	token = strings.TrimSpace(argToken)
+	if token == "" {
+		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
+	}
+	if token == "" {
+		return "", "", protocol.NewError(protocol.CategoryAuth, "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token")
+	}
+	base = strings.TrimSpace(argBase)
+	if base == "" {
+		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
+	}
+	base = strings.TrimSuffix(base, "/")
+	if base == "" {
+		return "", "", protocol.InvalidArgs("Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url")
+	}
+	return token, base, nil
+}
@@ -0,0 +1,20 @@
+package tools
+
+import (
+	"encoding/json"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/glossary"
+)
+
+// currencyCodes is a currency_codes argument. Names are turned into codes on decoding, so
+// "bitcoin" and "btc" both filter by BTC.
+type currencyCodes []string
+
+func (c *currencyCodes) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*c = glossary.Default().CurrencyCodes(names)
+	return nil
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// maxUpstreamDetail caps the upstream error text quoted back to the user.
+const maxUpstreamDetail = 200
+
+// upstreamError reports a non-2xx analytics API response, categorized by its status, with a
+// message telling the user what to do about it.
+func upstreamError(resp *http.Response) *protocol.ResponseError {
+	status := resp.StatusCode
+	var msg string
+	switch {
+	case status == http.StatusUnauthorized:
+		msg = "The analytics token was rejected (HTTP 401); it has probably expired or been revoked. Create a new API token in the PayRam dashboard and update PAYRAM_ANALYTICS_TOKEN or the token in chat settings."
+	case status == http.StatusForbidden:
+		msg = "The analytics token is not allowed to read analytics (HTTP 403). Use a token from a PayRam account with analytics access."
+	case status == http.StatusNotFound:
+		msg = "The analytics API has no such group or graph (HTTP 404). Check that PAYRAM_ANALYTICS_BASE_URL points at the PayRam server, and list the available graphs with payram_discover_analytics."
+	case status == http.StatusTooManyRequests:
+		msg = "The analytics API is rate limiting requests (HTTP 429). Wait a minute and try again."
+	case status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout:
+		msg = fmt.Sprintf("The PayRam analytics API is unavailable (HTTP %d); it may be restarting or overloaded. Try again in a few minutes.", status)
+	case status >= 500:
+		msg = fmt.Sprintf("The PayRam analytics API failed (HTTP %d). Try again; if it keeps failing, check the PayRam server logs.", status)
+	case status >= 400:
+		msg = fmt.Sprintf("The analytics API rejected the request (HTTP %d).", status)
+		if detail := upstreamDetail(resp.Body); detail != "" {
+			msg += " It said: " + detail
+		}
+	default:
+		msg = fmt.Sprintf("unexpected status: %d", status)
+	}
+	return protocol.UpstreamStatusError(status, msg)
+}
+
+// upstreamDetail extracts the message of a JSON error body ("message" or "error"), or the
+// start of a plain text one.
+func upstreamDetail(body io.Reader) string {
+	raw, _ := io.ReadAll(io.LimitReader(body, 4<<10))
+	var payload struct {
+		Message string `json:"message"`
+		Error   any    `json:"error"`
+	}
+	text := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &payload) == nil {
+		text = payload.Message
+		if s, ok := payload.Error.(string); ok && text == "" {
+			text = s
+		}
+	} else if strings.HasPrefix(text, "<") {
+		return "" // an HTML error page says nothing useful
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > maxUpstreamDetail {
+		text = string([]rune(text)[:maxUpstreamDetail]) + "..."
+	}
+	return text
+}
+
+// transportError reports an analytics API request that got no response at all.
+func transportError(err error) *protocol.ResponseError {
+	host := "the analytics API"
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		if u, perr := url.Parse(uerr.URL); perr == nil && u.Host != "" {
+			host = u.Host
+		}
+	}
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return protocol.Errorf(protocol.CategoryUpstreamUnavailable, "The request to %s was cancelled.", host)
+	case errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout():
+		return protocol.Errorf(protocol.CategoryUpstreamUnavailable, "Timed out waiting for %s. It may be overloaded; try again, or ask for a shorter date range.", host)
+	case errors.As(err, &dnsErr):
+		return protocol.Errorf(protocol.CategoryUpstreamUnavailable, "Could not resolve %s. Check PAYRAM_ANALYTICS_BASE_URL (or base_url).", host)
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return protocol.Errorf(protocol.CategoryUpstreamUnavailable, "Could not connect to %s. Check that the PayRam server is running and that PAYRAM_ANALYTICS_BASE_URL (or base_url) is right.", host)
+	default:
+		return protocol.Errorf(protocol.CategoryUpstreamUnavailable, "http error: %v", err)
+	}
+}
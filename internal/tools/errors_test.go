@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+func TestUpstreamErrorMessages(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusForbidden, "", "not allowed to read analytics"},
+		{http.StatusTooManyRequests, "", "rate limiting"},
+		{http.StatusServiceUnavailable, "<html>down</html>", "unavailable (HTTP 503)"},
+		{http.StatusInternalServerError, "", "check the PayRam server logs"},
+		{http.StatusBadRequest, `{"message":"invalid  date\nrange"}`, "It said: invalid date range"},
+		{http.StatusUnprocessableEntity, `{"error":"graph_id must be numeric"}`, "It said: graph_id must be numeric"},
+		{http.StatusBadRequest, "<html>bad</html>", "rejected the request (HTTP 400)."},
+	}
+	for _, tc := range cases {
+		resp := httptest.NewRecorder()
+		resp.WriteHeader(tc.status)
+		resp.WriteString(tc.body)
+		e := upstreamError(resp.Result())
+		if !strings.Contains(e.Message, tc.want) {
+			t.Errorf("status %d: message %q does not contain %q", tc.status, e.Message, tc.want)
+		}
+		if strings.Contains(e.Message, "<html>") {
+			t.Errorf("status %d: HTML page quoted: %q", tc.status, e.Message)
+		}
+	}
+}
+
+func TestTransportErrorMessages(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().String()
+	ln.Close()
+
+	_, err = http.Get("http://" + closed + "/api")
+	if e := transportError(err); !strings.Contains(e.Message, "Could not connect to "+closed) {
+		t.Errorf("refused connection: %q", e.Message)
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+	client := &http.Client{Timeout: 20 * time.Millisecond}
+	_, err = client.Get(slow.URL)
+	e := transportError(err)
+	if !strings.Contains(e.Message, "Timed out waiting for") {
+		t.Errorf("timeout: %q", e.Message)
+	}
+	if d, _ := protocol.DataOf(e); d.Category != protocol.CategoryUpstreamUnavailable || !d.Retryable {
+		t.Errorf("timeout data: %+v", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, slow.URL, nil)
+	_, err = http.DefaultClient.Do(req)
+	if e := transportError(err); !strings.Contains(e.Message, "cancelled") {
+		t.Errorf("cancelled: %q", e.Message)
+	}
+}
@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/payram/payram-analytics-mcp-server/internal/provenance"
+)
+
+// Run `go test ./internal/tools -run TestGolden -update` after an intended output change and
+// review the diff in testdata/golden.
+var update = flag.Bool("update", false, "rewrite testdata/golden from current tool output")
+
+type tool interface {
+	Descriptor() protocol.ToolDescriptor
+	Invoke(context.Context, json.RawMessage) (protocol.CallResult, *protocol.ResponseError)
+}
+
+const goldenToken = "golden-token"
+
+type goldenCase struct {
+	name string
+	tool func() tool
+	args string
+}
+
+// goldenCases invoke every payram_* tool against the fixtures in testdata/analytics.
+// Arguments avoid relative day counts so the output does not depend on the clock.
+var goldenCases = []goldenCase{
+	{"intro", func() tool { return PayramIntro() }, `{}`},
+	{"docs_search", func() tool { return PayramDocs() }, `{"action":"search","query":"webhook"}`},
+	{"docs_search_glossary", func() tool { return PayramDocs() }, `{"action":"search","query":"invoice"}`},
+	{"docs_list_index", func() tool { return PayramDocs() }, `{"action":"list_index"}`},
+	{"docs_search_es", func() tool { return PayramDocs() }, `{"action":"search","query":"webhook","lang":"es"}`},
+	{"docs_search_es_falls_back", func() tool { return localized{PayramDocs(), "es-mx"} }, `{"action":"search","query":"dashboard"}`},
+	{"docs_get_section_pt_falls_back", func() tool { return PayramDocs() }, `{"action":"get_section","path":"faqs/api-integration-faqs.md","lang":"pt-br"}`},
+	{"analytics_list_groups", func() tool { return PayramAnalytics() }, `{"action":"list_groups"}`},
+	{"analytics_graph_data", func() tool { return PayramAnalytics() }, `{"action":"graph_data","group_id":2,"graph_id":22}`},
+	{"discover_analytics", func() tool { return PayramDiscoverAnalytics() }, `{}`},
+	{"fetch_graph_data", func() tool { return PayramFetchGraphData() }, `{"group_id":3,"graph_id":31,"date_filter":"this_month","group_by":"currency_code"}`},
+	{"payments_summary", func() tool { return PayramPaymentsSummary() }, `{}`},
+	{"payments_summary_range", func() tool { return PayramPaymentsSummary() }, `{"date_filter":"last_month"}`},
+	{"payments_summary_compare", func() tool { return PayramPaymentsSummary() }, `{"date_filter":"custom","custom_start_date":"2026-01-01","custom_end_date":"2026-01-08","compare_previous":true}`},
+	{"payments_summary_compare_forever", func() tool { return PayramPaymentsSummary() }, `{"date_filter":"forever","compare_previous":true}`},
+	{"numbers_summary", func() tool { return PayramNumbersSummary() }, `{}`},
+	{"numbers_summary_compare", func() tool { return PayramNumbersSummary() }, `{"compare_previous":true}`},
+	{"transaction_counts", func() tool { return PayramTransactionCounts() }, `{"date_filter":"last_7_days"}`},
+	{"daily_stats", func() tool { return PayramDailyStats() }, `{"date_filter":"last_7_days"}`},
+	{"daily_stats_counts_only", func() tool { return PayramDailyStats() }, `{"include_amounts":false}`},
+	{"deposit_distribution", func() tool { return PayramDepositDistribution() }, `{"date_filter":"this_month"}`},
+	{"currency_breakdown", func() tool { return PayramCurrencyBreakdown() }, `{"date_filter":"last_30_days"}`},
+	{"currency_breakdown_usdt", func() tool { return PayramCurrencyBreakdown() }, `{"currency_code":"usdt"}`},
+	{"currency_breakdown_by_name", func() tool { return PayramCurrencyBreakdown() }, `{"currency_code":"Tether"}`},
+	{"currency_breakdown_missing", func() tool { return PayramCurrencyBreakdown() }, `{"currency_code":"SOL"}`},
+	{"paying_users", func() tool { return PayramPayingUsers() }, `{"date_filter":"last_30_days"}`},
+	{"user_growth", func() tool { return PayramUserGrowth() }, `{"date_filter":"last_6_months"}`},
+	{"retention_weekly", func() tool { return PayramRetention() }, `{"periods":4,"until":"2026-02-04"}`},
+	{"retention_monthly", func() tool { return PayramRetention() }, `{"period":"month","periods":3,"until":"2026-02-04","currency_codes":["USDT"]}`},
+	{"retention_bad_period", func() tool { return PayramRetention() }, `{"period":"day"}`},
+	{"recent_transactions", func() tool { return PayramRecentTransactions() }, `{"limit":2,"currency_codes":["USDT"]}`},
+	{"projects_summary", func() tool { return PayramProjectsSummary() }, `{"date_filter":"forever"}`},
+	{"compare_periods", func() tool { return PayramComparePeriods() }, `{"period1":"this_month","period2":"last_month"}`},
+	{"changes_baseline", func() tool {
+		c := PayramChanges()
+		c.SetClock(clock.Fixed(time.Date(2026, 2, 4, 8, 0, 0, 0, time.UTC)))
+		return c
+	}, `{"date_filter":"this_month"}`},
+	{"changes_bad_window", func() tool { return PayramChanges() }, `{"date_filter":"custom"}`},
+	{"compare_periods_missing_period", func() tool { return PayramComparePeriods() }, `{"period1":"this_month"}`},
+	{"daily_stats_rejected_token", func() tool { return PayramDailyStats() }, `{"date_filter":"last_7_days","token":"expired"}`},
+	{"fetch_graph_data_missing_graph", func() tool { return PayramFetchGraphData() }, `{"group_id":9,"graph_id":99,"date_filter":"this_month"}`},
+	{"fetch_graph_data_by_name", func() tool { return PayramFetchGraphData() }, `{"graph_name":"deposits by currency","date_filter":"this_month","group_by":"currency_code"}`},
+	{"fetch_graph_data_fuzzy_group", func() tool { return PayramFetchGraphData() }, `{"group_name":"transaction sumary","graph_name":"Number of Transactions","date_filter":"this_month"}`},
+	{"fetch_graph_data_ambiguous_name", func() tool { return PayramFetchGraphData() }, `{"graph_name":"payments"}`},
+	{"fetch_graph_data_unknown_name", func() tool { return PayramFetchGraphData() }, `{"group_name":"Numbers","graph_name":"refunds"}`},
+	{"export_year", func() tool { return PayramExport() }, `{"graphs":[{"group_id":2,"graph_id":21}],"group_ids":[4],"year":2025}`},
+	{"export_unknown_graph", func() tool { return PayramExport() }, `{"graphs":[{"group_id":2,"graph_id":99}]}`},
+	{"debug_request_groups", func() tool { return PayramDebugRequest(DefaultDebugPaths) }, `{"path":"/api/v1/external-platform/all/analytics/groups"}`},
+	{"debug_request_graph_data", func() tool { return PayramDebugRequest(DefaultDebugPaths) }, `{"method":"POST","path":"/api/v1/external-platform/all/analytics/groups/3/graph/31/data","body":{"analytics_date_filter":"this_month"}}`},
+	{"debug_request_not_found", func() tool { return PayramDebugRequest(DefaultDebugPaths) }, `{"method":"POST","path":"/api/v1/external-platform/all/analytics/groups/9/graph/99/data"}`},
+	{"debug_request_path_denied", func() tool { return PayramDebugRequest(DefaultDebugPaths) }, `{"path":"/api/v1/external-platform/all/analytics/groups/../../users"}`},
+	{"daily_stats_es", func() tool { return localized{PayramDailyStats(), "es"} }, `{"date_filter":"last_7_days"}`},
+	{"discover_analytics_de", func() tool { return localized{PayramDiscoverAnalytics(), "de"} }, `{}`},
+	{"numbers_summary_pt_br", func() tool { return localized{PayramNumbersSummary(), "pt-br"} }, `{}`},
+}
+
+// localized invokes a tool as a caller asking for answers in lang.
+type localized struct {
+	tool
+	lang string
+}
+
+func (l localized) Invoke(ctx context.Context, args json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	return l.tool.Invoke(i18n.WithLanguage(ctx, l.lang), args)
+}
+
+func TestGolden(t *testing.T) {
+	srv := analyticsFixtureServer(t)
+	t.Setenv("PAYRAM_ANALYTICS_BASE_URL", srv.URL)
+	t.Setenv("PAYRAM_ANALYTICS_TOKEN", goldenToken)
+	t.Setenv("PAYRAM_DOCS_ROOT", filepath.Join("testdata", "docs"))
+
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, errResp := tc.tool().Invoke(context.Background(), json.RawMessage(tc.args))
+			got := renderGolden(result, errResp)
+
+			path := filepath.Join("testdata", "golden", tc.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("output differs from %s (run with -update if intended)\n--- want\n%s\n--- got\n%s", path, want, got)
+			}
+		})
+	}
+}
+
+// TestToolsNoteTheirSources checks that the analytics graphs a tool reads are noted for the
+// result's provenance.
+func TestToolsNoteTheirSources(t *testing.T) {
+	srv := analyticsFixtureServer(t)
+	t.Setenv("PAYRAM_ANALYTICS_BASE_URL", srv.URL)
+	t.Setenv("PAYRAM_ANALYTICS_TOKEN", goldenToken)
+
+	ctx, trail := provenance.WithTrail(context.Background())
+	args := `{"group_id":3,"graph_id":31,"date_filter":"this_month","group_by":"currency_code"}`
+	if _, errResp := PayramFetchGraphData().Invoke(ctx, json.RawMessage(args)); errResp != nil {
+		t.Fatalf("invoke: %v", errResp.Message)
+	}
+	sources := trail.Sources()
+	if len(sources) != 1 {
+		t.Fatalf("noted %d sources, want 1: %+v", len(sources), sources)
+	}
+	if s := sources[0]; s.GroupID != 3 || s.GraphID != 31 || s.DateFilter != "this_month" || s.Filters["group_by_only_network_currency_filter"] == nil {
+		t.Fatalf("source: %+v", s)
+	}
+}
+
+// TestExportStreamMatchesInvoke checks that a streamed export is the text of the export's
+// parts, as /jobs/{id}/result returns it.
+func TestExportStreamMatchesInvoke(t *testing.T) {
+	srv := analyticsFixtureServer(t)
+	t.Setenv("PAYRAM_ANALYTICS_BASE_URL", srv.URL)
+	t.Setenv("PAYRAM_ANALYTICS_TOKEN", goldenToken)
+
+	args := json.RawMessage(`{"graphs":[{"group_id":2,"graph_id":21}],"group_ids":[4],"year":2025}`)
+	result, errResp := PayramExport().Invoke(context.Background(), args)
+	if errResp != nil {
+		t.Fatal(errResp.Message)
+	}
+	texts := make([]string, len(result.Content))
+	for i, p := range result.Content {
+		texts[i] = p.Text
+	}
+	var streamed strings.Builder
+	if errResp := PayramExport().Stream(context.Background(), args, &streamed); errResp != nil {
+		t.Fatal(errResp.Message)
+	}
+	if want := strings.Join(texts, "\n\n"); streamed.String() != want {
+		t.Errorf("streamed export differs\n--- invoke\n%s\n--- stream\n%s", want, streamed.String())
+	}
+}
+
+// TestGoldenFilesHaveCases catches golden files left behind by renamed or removed cases.
+func TestGoldenFilesHaveCases(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "golden", "*.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		name := strings.TrimSuffix(filepath.Base(f), ".golden")
+		if !slices.ContainsFunc(goldenCases, func(c goldenCase) bool { return c.name == name }) {
+			t.Errorf("%s has no golden case", f)
+		}
+	}
+}
+
+// renderGolden writes a call result the way a reviewer wants to read it: text parts verbatim,
+// charts as indented JSON, errors as code, category and message.
+func renderGolden(result protocol.CallResult, errResp *protocol.ResponseError) string {
+	if errResp != nil {
+		data, _ := protocol.DataOf(errResp)
+		return fmt.Sprintf("error %d %s retryable=%t: %s\n", errResp.Code, data.Category, data.Retryable, errResp.Message)
+	}
+	var b strings.Builder
+	for _, part := range result.Content {
+		fmt.Fprintf(&b, "--- %s\n%s\n", part.Type, part.Text)
+	}
+	if result.Meta != nil {
+		for _, c := range result.Meta.Charts {
+			chart, _ := json.MarshalIndent(c, "", "  ")
+			fmt.Fprintf(&b, "--- chart: [%s chart: %s]\n%s\n", c.Kind, c.Title, chart)
+		}
+	}
+	return b.String()
+}
+
+var graphDataPath = regexp.MustCompile(`^/api/v1/external-platform/all/analytics/groups/\d+/graph/(\d+)/data$`)
+
+// analyticsFixtureServer serves testdata/analytics: groups.json for the group list and
+// graph_<id>.json for each graph's data.
+func analyticsFixtureServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+goldenToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var file string
+		switch m := graphDataPath.FindStringSubmatch(r.URL.Path); {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/external-platform/all/analytics/groups":
+			file = "groups.json"
+		case r.Method == http.MethodPost && m != nil:
+			file = "graph_" + m[1] + ".json"
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "analytics", file))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/grafana"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// grafanaSource serves analytics graphs to the Grafana datasource, through the same
+// analytics calls and credentials as payram_export. Targets are "<group_id>/<graph_id>".
+type grafanaSource struct {
+	export *payramExportTool
+}
+
+// GrafanaSource constructs the source.
+func GrafanaSource() *grafanaSource {
+	return &grafanaSource{export: PayramExport()}
+}
+
+// Targets lists every graph of every analytics group.
+func (s *grafanaSource) Targets(ctx context.Context) ([]grafana.Target, *protocol.ResponseError) {
+	token, base, errResp := resolveCredentials(ctx, "", "")
+	if errResp != nil {
+		return nil, errResp
+	}
+	groups, errResp := s.export.listGroups(ctx, base, token)
+	if errResp != nil {
+		return nil, errResp
+	}
+	var out []grafana.Target
+	for _, g := range groups {
+		for _, gr := range g.AnalyticsGroup.Graphs {
+			out = append(out, grafana.Target{
+				Text:  g.AnalyticsGroup.Name + " / " + gr.Name,
+				Value: fmt.Sprintf("%d/%d", g.AnalyticsGroup.ID, gr.ID),
+			})
+		}
+	}
+	return out, nil
+}
+
+// Table fetches target's data for [from, to].
+func (s *grafanaSource) Table(ctx context.Context, target string, from, to time.Time) (grafana.Table, *protocol.ResponseError) {
+	ref, ok := parseGrafanaTarget(target)
+	if !ok {
+		return grafana.Table{}, protocol.InvalidArgs(fmt.Sprintf("invalid target %q; use <group_id>/<graph_id> as listed by search", target))
+	}
+	token, base, errResp := resolveCredentials(ctx, "", "")
+	if errResp != nil {
+		return grafana.Table{}, errResp
+	}
+	groups, errResp := s.export.listGroups(ctx, base, token)
+	if errResp != nil {
+		return grafana.Table{}, errResp
+	}
+	refs, errResp := exportRefs(groups, []exportGraphRef{ref}, nil)
+	if errResp != nil {
+		return grafana.Table{}, errResp
+	}
+	payload := map[string]any{"custom": map[string]any{
+		"start_date": from.UTC().Format(time.RFC3339),
+		"end_date":   to.UTC().Format(time.RFC3339),
+	}}
+	data, _, errResp := s.export.graphData(ctx, base, token, ref.GroupID, ref.GraphID, payload)
+	if errResp != nil {
+		return grafana.Table{}, errResp
+	}
+	rows, err := graphRows(data)
+	if err != nil {
+		return grafana.Table{}, protocol.Errorf(protocol.CategoryInternal, "convert graph %d: %v", ref.GraphID, err)
+	}
+	return grafana.Table{Name: refs[0].name, Columns: rows[0], Rows: rows[1:]}, nil
+}
+
+func parseGrafanaTarget(target string) (exportGraphRef, bool) {
+	group, graph, ok := strings.Cut(strings.TrimSpace(target), "/")
+	if !ok {
+		return exportGraphRef{}, false
+	}
+	g, err1 := strconv.Atoi(group)
+	h, err2 := strconv.Atoi(graph)
+	if err1 != nil || err2 != nil || g <= 0 || h <= 0 {
+		return exportGraphRef{}, false
+	}
+	return exportGraphRef{GroupID: g, GraphID: h}, true
+}
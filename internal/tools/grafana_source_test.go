@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGrafanaSourceReadsGraphs(t *testing.T) {
+	srv := analyticsFixtureServer(t)
+	t.Setenv("PAYRAM_ANALYTICS_TOKEN", goldenToken)
+	t.Setenv("PAYRAM_ANALYTICS_BASE_URL", srv.URL)
+	src := GrafanaSource()
+	ctx := context.Background()
+
+	targets, errResp := src.Targets(ctx)
+	if errResp != nil {
+		t.Fatalf("Targets: %s", errResp.Message)
+	}
+	found := false
+	for _, tg := range targets {
+		found = found || tg.Value == "2/21"
+	}
+	if !found {
+		t.Errorf("Targets = %+v, want 2/21 listed", targets)
+	}
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	table, errResp := src.Table(ctx, "2/21", from, from.AddDate(0, 0, 3))
+	if errResp != nil {
+		t.Fatalf("Table: %s", errResp.Message)
+	}
+	if want := []string{"date", "BTC", "USDT"}; !reflect.DeepEqual(table.Columns, want) || len(table.Rows) != 3 {
+		t.Errorf("Table = %+v, want columns %v and 3 rows", table, want)
+	}
+
+	if _, errResp := src.Table(ctx, "21", from, from.AddDate(0, 0, 3)); errResp == nil {
+		t.Error("Table accepted a target without a group")
+	}
+	if _, errResp := src.Table(ctx, "2/99", from, from.AddDate(0, 0, 3)); errResp == nil {
+		t.Error("Table accepted an unknown graph")
+	}
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// discoverGroups lists the analytics groups with their graphs. The group list goes through
+// the group metadata cache when one is configured.
+func discoverGroups(ctx context.Context, client *http.Client, base, token string) ([]discoverGroupWrapper, *protocol.ResponseError) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+groupsPath, nil)
+	if err != nil {
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, transportError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, upstreamError(resp)
+	}
+
+	var data []discoverGroupWrapper
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
+	}
+	return data, nil
+}
+
+// graphRef is one graph found by name, with the names it was found under.
+type graphRef struct {
+	GroupID   int
+	GraphID   int
+	GroupName string
+	GraphName string
+}
+
+// Match quality of a name against a query, best first.
+const (
+	matchNone = iota
+	matchFuzzy
+	matchWords
+	matchContains
+	matchExact
+)
+
+// normalizeName lower-cases s and turns every run of other characters than letters and
+// digits into one space, so "Payments in USD", "payments_in_usd" and "payments-in usd"
+// are the same name.
+func normalizeName(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// nameMatch rates how well name matches query, both normalized.
+func nameMatch(query, name string) int {
+	switch {
+	case query == "" || name == "":
+		return matchNone
+	case query == name:
+		return matchExact
+	case strings.Contains(name, query) || strings.Contains(query, name):
+		return matchContains
+	}
+	words := strings.Fields(name)
+	all := true
+	for _, w := range strings.Fields(query) {
+		found := false
+		for _, nw := range words {
+			if nw == w || (len(w) >= 4 && strings.HasPrefix(nw, w)) {
+				found = true
+				break
+			}
+		}
+		all = all && found
+	}
+	if all {
+		return matchWords
+	}
+	// A typo or two: at most one edit per four characters.
+	if d := editDistance(query, name); d <= max(1, len(name)/4) {
+		return matchFuzzy
+	}
+	return matchNone
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(min(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// resolveGraph finds the graph a caller named. The group is given by groupID or groupName,
+// or left out to search every group; the graph by graphID or graphName. Only the best match
+// counts, and more than one graph matching equally well is an error that lists them.
+func resolveGraph(groups []discoverGroupWrapper, groupID int, groupName string, graphID int, graphName string) (graphRef, *protocol.ResponseError) {
+	var candidates []discoverGroupWrapper
+	if groupID != 0 || groupName != "" {
+		best, q := matchNone, normalizeName(groupName)
+		for _, g := range groups {
+			m := matchNone
+			if groupID != 0 {
+				if g.AnalyticsGroup.ID == groupID {
+					m = matchExact
+				}
+			} else {
+				m = nameMatch(q, normalizeName(g.AnalyticsGroup.Name))
+			}
+			switch {
+			case m == matchNone || m < best:
+			case m > best:
+				best, candidates = m, []discoverGroupWrapper{g}
+			default:
+				candidates = append(candidates, g)
+			}
+		}
+		switch {
+		case len(candidates) == 0 && groupID != 0:
+			return graphRef{}, protocol.Errorf(protocol.CategoryNotFound, "No analytics group with group_id %d. Groups: %s.", groupID, groupNames(groups))
+		case len(candidates) == 0:
+			return graphRef{}, protocol.Errorf(protocol.CategoryNotFound, "No analytics group matches group_name %q. Groups: %s.", groupName, groupNames(groups))
+		case len(candidates) > 1:
+			return graphRef{}, protocol.Errorf(protocol.CategoryInvalidArgs, "group_name %q matches several groups: %s. Use a longer name or group_id.", groupName, groupNames(candidates))
+		}
+	} else {
+		candidates = groups
+	}
+
+	var found []graphRef
+	best, q := matchNone, normalizeName(graphName)
+	for _, g := range candidates {
+		for _, gr := range g.AnalyticsGroup.Graphs {
+			m := matchNone
+			if graphID != 0 {
+				if gr.ID == graphID {
+					m = matchExact
+				}
+			} else {
+				m = nameMatch(q, normalizeName(gr.Name))
+			}
+			ref := graphRef{GroupID: g.AnalyticsGroup.ID, GraphID: gr.ID, GroupName: g.AnalyticsGroup.Name, GraphName: gr.Name}
+			switch {
+			case m == matchNone || m < best:
+			case m > best:
+				best, found = m, []graphRef{ref}
+			default:
+				found = append(found, ref)
+			}
+		}
+	}
+	where := "any group"
+	if len(candidates) == 1 {
+		where = fmt.Sprintf("group %q", candidates[0].AnalyticsGroup.Name)
+	}
+	switch {
+	case len(found) == 0 && graphID != 0:
+		return graphRef{}, protocol.Errorf(protocol.CategoryNotFound, "No graph with graph_id %d in %s. Graphs: %s.", graphID, where, graphNames(candidates))
+	case len(found) == 0:
+		return graphRef{}, protocol.Errorf(protocol.CategoryNotFound, "No graph in %s matches graph_name %q. Graphs: %s.", where, graphName, graphNames(candidates))
+	case len(found) > 1:
+		names := make([]string, len(found))
+		for i, f := range found {
+			names[i] = fmt.Sprintf("%q in %q", f.GraphName, f.GroupName)
+		}
+		return graphRef{}, protocol.Errorf(protocol.CategoryInvalidArgs, "graph_name %q matches several graphs: %s. Add group_name or use a longer name.", graphName, strings.Join(names, ", "))
+	}
+	return found[0], nil
+}
+
+func groupNames(groups []discoverGroupWrapper) string {
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		names[i] = fmt.Sprintf("%q", g.AnalyticsGroup.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func graphNames(groups []discoverGroupWrapper) string {
+	var names []string
+	for _, g := range groups {
+		for _, gr := range g.AnalyticsGroup.Graphs {
+			names = append(names, fmt.Sprintf("%q", gr.Name))
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
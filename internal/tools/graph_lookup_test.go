@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func lookupGroups() []discoverGroupWrapper {
+	group := func(id int, name string, graphs ...discoverGraph) discoverGroupWrapper {
+		var g discoverGroupWrapper
+		g.AnalyticsGroup.ID, g.AnalyticsGroup.Name, g.AnalyticsGroup.Graphs = id, name, graphs
+		return g
+	}
+	return []discoverGroupWrapper{
+		group(1, "Numbers", discoverGraph{ID: 11, Name: "Total Payments in USD"}, discoverGraph{ID: 12, Name: "Total Transactions"}),
+		group(2, "Transaction Summary", discoverGraph{ID: 21, Name: "Payments in USD"}, discoverGraph{ID: 22, Name: "Number of Transactions"}),
+	}
+}
+
+func TestResolveGraph(t *testing.T) {
+	cases := []struct {
+		group, graph string
+		groupID      int
+		want         int
+		err          string
+	}{
+		{graph: "payments_in_usd", want: 21},
+		{graph: "total trans", want: 12},
+		{graph: "numb trans", want: 22},
+		{graph: "totl transactions", want: 12},
+		{group: "transaction sumary", graph: "payments", want: 21},
+		{groupID: 1, graph: "payments", want: 11},
+		{graph: "payments", err: "matches several graphs"},
+		{graph: "refunds", err: "No graph in any group"},
+		{group: "statistics", graph: "payments", err: `group_name "statistics"`},
+	}
+	for _, c := range cases {
+		ref, rerr := resolveGraph(lookupGroups(), c.groupID, c.group, 0, c.graph)
+		if c.err != "" {
+			if rerr == nil || !strings.Contains(rerr.Message, c.err) {
+				t.Errorf("%q/%q: want error containing %q, got %v", c.group, c.graph, c.err, rerr)
+			}
+			continue
+		}
+		if rerr != nil || ref.GraphID != c.want {
+			t.Errorf("%q/%q: got %+v, %v; want graph %d", c.group, c.graph, ref, rerr, c.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/metrics"
+)
+
+var groupsCacheHits = metrics.Default.Counter("analytics_groups_cache_hits_total",
+	"Analytics group lists served from the group metadata cache.")
+
+const (
+	// groupsPath is the analytics group list every tool starts from.
+	groupsPath = "/api/v1/external-platform/all/analytics/groups"
+	// groupsRevalidateAfter is how long a cached group list is served before the next call
+	// also refreshes it in the background.
+	groupsRevalidateAfter = time.Minute
+	// groupsRevalidateTimeout bounds a background refresh.
+	groupsRevalidateTimeout = 30 * time.Second
+	// groupsCacheMax caps the deployments kept, oldest first.
+	groupsCacheMax = 100
+)
+
+// groupsEntry is the last group list one deployment and token returned. Key hashes the URL
+// and the Authorization header, so the token is never written to disk.
+type groupsEntry struct {
+	Key       string    `json:"key"`
+	URL       string    `json:"url"`
+	Body      string    `json:"body"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// groupsDiskCache keeps the group lists in PAYRAM_ANALYTICS_GROUPS_FILE. Group metadata
+// rarely changes, so a cached list is answered at once, from the first call after a restart
+// on, and refreshed in the background. A slow or briefly unreachable PayRam core then does
+// not hold up cold starts. Without the file nothing is cached.
+type groupsDiskCache struct {
+	mu         sync.Mutex
+	loaded     bool
+	path       string
+	entries    map[string]*groupsEntry
+	checked    map[string]time.Time
+	refreshing map[string]bool
+}
+
+var groupsCache = &groupsDiskCache{}
+
+func (c *groupsDiskCache) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	c.entries = map[string]*groupsEntry{}
+	c.checked = map[string]time.Time{}
+	c.refreshing = map[string]bool{}
+	c.path = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_GROUPS_FILE"))
+	if c.path == "" {
+		return
+	}
+	raw, err := os.ReadFile(c.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[groups] reading %s: %v", c.path, err)
+		}
+		return
+	}
+	var entries []*groupsEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		log.Printf("[groups] reading %s: %v", c.path, err)
+		return
+	}
+	for _, e := range entries {
+		if e.Key != "" && json.Valid([]byte(e.Body)) {
+			c.entries[e.Key] = e
+		}
+	}
+}
+
+// save writes the entries to the groups file; called with c.mu held.
+func (c *groupsDiskCache) save() {
+	entries := make([]*groupsEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].FetchedAt.After(entries[j].FetchedAt) })
+	for _, e := range entries[min(len(entries), groupsCacheMax):] {
+		delete(c.entries, e.Key)
+	}
+	entries = entries[:min(len(entries), groupsCacheMax)]
+
+	raw, _ := json.MarshalIndent(entries, "", "  ")
+	tmp := c.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err == nil {
+		err = os.WriteFile(tmp, raw, 0o600)
+		if err == nil {
+			err = os.Rename(tmp, c.path)
+		}
+		if err == nil {
+			return
+		}
+	}
+	log.Printf("[groups] could not save %s", c.path)
+}
+
+// store records a fresh group list under key.
+func (c *groupsDiskCache) store(key, url string, body []byte) {
+	if !json.Valid(body) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &groupsEntry{Key: key, URL: url, Body: string(body), FetchedAt: time.Now().UTC()}
+	c.checked[key] = time.Now()
+	c.save()
+}
+
+// groupsCacheKey identifies the deployment and caller a group list request is for.
+func groupsCacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Authorization")))
+	return hex.EncodeToString(sum[:])
+}
+
+// groupsCacheTransport answers group list requests from groupsCache when it can.
+type groupsCacheTransport struct {
+	base http.RoundTripper
+}
+
+func (t groupsCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || !strings.HasSuffix(req.URL.Path, groupsPath) {
+		return t.base.RoundTrip(req)
+	}
+	c := groupsCache
+	c.mu.Lock()
+	c.load()
+	if c.path == "" {
+		c.mu.Unlock()
+		return t.base.RoundTrip(req)
+	}
+	key := groupsCacheKey(req)
+	e := c.entries[key]
+	if e == nil {
+		c.mu.Unlock()
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			return resp, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		c.store(key, redactedURL(req), body)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+	body := e.Body
+	if !c.refreshing[key] && time.Since(c.checked[key]) >= groupsRevalidateAfter {
+		c.refreshing[key] = true
+		go t.revalidate(c, key, req)
+	}
+	c.mu.Unlock()
+
+	groupsCacheHits.Inc()
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// revalidate fetches the group list again and replaces the cached copy when it succeeds. A
+// failure keeps the cached copy and is retried after groupsRevalidateAfter, except that a
+// rejected token drops it.
+func (t groupsCacheTransport) revalidate(c *groupsDiskCache, key string, req *http.Request) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), groupsRevalidateTimeout)
+	defer cancel()
+
+	var body []byte
+	status := 0
+	resp, err := t.base.RoundTrip(req.Clone(ctx))
+	if err == nil {
+		status = resp.StatusCode
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil && status != http.StatusOK {
+			err = fmt.Errorf("status %d", status)
+		}
+	}
+	if err == nil {
+		c.store(key, redactedURL(req), body)
+	} else {
+		log.Printf("[groups] refreshing %s: %v", redactedURL(req), err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checked[key] = time.Now()
+	delete(c.refreshing, key)
+	// A token the core no longer accepts must not keep reading the cached list.
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		delete(c.entries, key)
+		c.save()
+	}
+}
+
+// redactedURL is req's URL without credentials or query, as kept in the groups file.
+func redactedURL(req *http.Request) string {
+	u := *req.URL
+	u.User, u.RawQuery = nil, ""
+	return u.String()
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupsCacheServesLastListAcrossRestarts(t *testing.T) {
+	t.Setenv("PAYRAM_ANALYTICS_GROUPS_FILE", filepath.Join(t.TempDir(), "groups.json"))
+	prev := groupsCache
+	defer func() { groupsCache = prev }()
+	groupsCache = &groupsDiskCache{}
+
+	var body atomic.Value
+	body.Store(`{"data":[{"name":"Numbers"}]}`)
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(int(status.Load()))
+		_, _ = io.WriteString(w, body.Load().(string))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: groupsCacheTransport{http.DefaultTransport}}
+	get := func(token string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+groupsPath, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return string(out)
+	}
+
+	if got := get("a"); got != `{"data":[{"name":"Numbers"}]}` || hits.Load() != 1 {
+		t.Fatalf("first call: %s (%d hits)", got, hits.Load())
+	}
+
+	// After a restart the list is served at once even while the core fails, and the
+	// failed refresh keeps it.
+	groupsCache = &groupsDiskCache{}
+	status.Store(http.StatusBadGateway)
+	if got := get("a"); got != `{"data":[{"name":"Numbers"}]}` {
+		t.Fatalf("after restart: %s", got)
+	}
+	waitFor(t, func() bool { return hits.Load() == 2 && !refreshing() })
+	if got := get("a"); got != `{"data":[{"name":"Numbers"}]}` {
+		t.Fatalf("after a failed refresh: %s", got)
+	}
+
+	// Another token is another caller.
+	status.Store(http.StatusOK)
+	body.Store(`{"data":[{"name":"Numbers"},{"name":"Payments"}]}`)
+	if got := get("b"); got != `{"data":[{"name":"Numbers"},{"name":"Payments"}]}` || hits.Load() != 3 {
+		t.Fatalf("other token: %s (%d hits)", got, hits.Load())
+	}
+
+	// A refresh picks up changes for the next call.
+	groupsCache.mu.Lock()
+	for k := range groupsCache.checked {
+		groupsCache.checked[k] = time.Time{}
+	}
+	groupsCache.mu.Unlock()
+	get("a")
+	waitFor(t, func() bool { return hits.Load() == 4 && !refreshing() })
+	if got := get("a"); got != `{"data":[{"name":"Numbers"},{"name":"Payments"}]}` {
+		t.Fatalf("after refresh: %s", got)
+	}
+}
+
+func TestGroupsCacheOffWithoutFile(t *testing.T) {
+	t.Setenv("PAYRAM_ANALYTICS_GROUPS_FILE", "")
+	prev := groupsCache
+	defer func() { groupsCache = prev }()
+	groupsCache = &groupsDiskCache{}
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = io.WriteString(w, `{"data":[]}`)
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: groupsCacheTransport{http.DefaultTransport}}
+	for range 2 {
+		resp, err := client.Get(srv.URL + groupsPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("expected every call upstream, got %d", hits.Load())
+	}
+}
+
+func refreshing() bool {
+	groupsCache.mu.Lock()
+	defer groupsCache.mu.Unlock()
+	return len(groupsCache.refreshing) > 0
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
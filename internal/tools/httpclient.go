@@ -0,0 +1,24 @@
+package tools
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/coalesce"
+	"github.com/payram/payram-analytics-mcp-server/internal/degraded"
+	"github.com/payram/payram-analytics-mcp-server/internal/latency"
+	"github.com/payram/payram-analytics-mcp-server/internal/provenance"
+	"github.com/payram/payram-analytics-mcp-server/internal/recording"
+)
+
+// upstream is the transport every tool shares for analytics calls. It records exchanges and
+// notes the graphs read when the request context asks for it, answers the group list from
+// the group metadata cache when there is one, falls back to the last good response when the
+// core is down, shares one round trip between concurrent identical requests from any tool,
+// and times every round trip for the per-endpoint latency stats.
+var upstream = provenance.Transport(recording.Transport(groupsCacheTransport{degraded.Transport(coalesce.Transport(latency.Transport(nil)), nil)}))
+
+// newHTTPClient returns the client tools use for analytics calls.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: upstream}
+}
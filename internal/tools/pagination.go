@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// defaultMaxPages is how many pages of one graph are fetched when PAYRAM_ANALYTICS_MAX_PAGES
+// is unset.
+const defaultMaxPages = 20
+
+// maxPages resolves the page cap from PAYRAM_ANALYTICS_MAX_PAGES.
+func maxPages() int {
+	if v := strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_MAX_PAGES")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxPages
+}
+
+// pageInfo reports how a graph's rows were collected. Total is the row count the API
+// reported, or 0 when it did not say.
+type pageInfo struct {
+	Paged     bool
+	Pages     int
+	Rows      int
+	Total     int
+	MaxPages  int
+	Truncated bool
+}
+
+// rowKeys are the fields a paginated response may hold its rows in.
+var rowKeys = []string{"data", "rows", "items", "results", "records"}
+
+// metaKeys are the fields a paginated response may nest its pagination metadata in.
+var metaKeys = []string{"pagination", "meta", "page_info", "pageInfo"}
+
+// pageMeta is the pagination metadata of one response, under any of the common spellings.
+type pageMeta struct {
+	Page       int
+	TotalPages int
+	Total      int
+	HasMore    bool
+	NextPage   int
+	NextCursor string
+}
+
+func (m pageMeta) more() bool {
+	return m.HasMore || m.NextCursor != "" || m.NextPage > m.Page || (m.TotalPages > 0 && m.Page < m.TotalPages)
+}
+
+// fetchGraphPages posts payload to a graph's data endpoint and, when the response carries
+// pagination metadata, requests the following pages (by page number or cursor) until there
+// are no more or PAYRAM_ANALYTICS_MAX_PAGES is reached. The rows of a paginated response are
+// returned as one array; other responses are returned as received.
+func fetchGraphPages(ctx context.Context, client *http.Client, base, token string, groupID, graphID int, payload map[string]any) (json.RawMessage, pageInfo, *protocol.ResponseError) {
+	info := pageInfo{MaxPages: maxPages()}
+	var rows []json.RawMessage
+	next := payload
+	for {
+		raw, headerTotal, errResp := postGraphData(ctx, client, base, token, groupID, graphID, next)
+		if errResp != nil {
+			return nil, info, errResp
+		}
+		info.Pages++
+		pageRows, meta, paged := parsePage(raw)
+		if !paged {
+			if info.Pages > 1 {
+				// A later page without metadata: keep what was collected.
+				break
+			}
+			if err := json.Unmarshal(raw, &pageRows); err == nil {
+				info.Rows = len(pageRows)
+				info.Total = headerTotal
+				info.Truncated = headerTotal > len(pageRows)
+			}
+			return raw, info, nil
+		}
+
+		info.Paged = true
+		rows = append(rows, pageRows...)
+		info.Total = max(meta.Total, headerTotal)
+		if !meta.more() || len(pageRows) == 0 {
+			break
+		}
+		if info.Pages >= info.MaxPages {
+			info.Truncated = true
+			break
+		}
+		next = nextPagePayload(payload, meta, info.Pages)
+	}
+
+	info.Rows = len(rows)
+	if info.Total > info.Rows {
+		info.Truncated = true
+	}
+	if rows == nil {
+		rows = []json.RawMessage{}
+	}
+	out, _ := json.Marshal(rows)
+	return out, info, nil
+}
+
+// postGraphData makes one graph data request. It also returns the X-Total-Count header, which
+// some endpoints use to say a response was cut short.
+func postGraphData(ctx context.Context, client *http.Client, base, token string, groupID, graphID int, payload map[string]any) (json.RawMessage, int, *protocol.ResponseError) {
+	body, _ := json.Marshal(payload)
+	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, transportError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, 0, upstreamError(resp)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, 0, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
+	}
+	total, _ := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	return raw, total, nil
+}
+
+// parsePage reports whether raw is one page of a paginated response: an object holding its
+// rows in one of rowKeys next to pagination metadata, at the top level or under metaKeys.
+func parsePage(raw json.RawMessage) ([]json.RawMessage, pageMeta, bool) {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(raw, &obj) != nil {
+		return nil, pageMeta{}, false
+	}
+	var rows []json.RawMessage
+	found := false
+	for _, k := range rowKeys {
+		if v, ok := obj[k]; ok && json.Unmarshal(v, &rows) == nil {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, pageMeta{}, false
+	}
+
+	meta, ok := readPageMeta(obj)
+	for _, k := range metaKeys {
+		if ok {
+			break
+		}
+		var nested map[string]json.RawMessage
+		if v, has := obj[k]; has && json.Unmarshal(v, &nested) == nil {
+			meta, ok = readPageMeta(nested)
+		}
+	}
+	return rows, meta, ok
+}
+
+// readPageMeta reads pagination fields from obj, reporting whether it had any.
+func readPageMeta(obj map[string]json.RawMessage) (pageMeta, bool) {
+	var m pageMeta
+	found := false
+	intField := func(dst *int, keys ...string) {
+		for _, k := range keys {
+			if v, ok := obj[k]; ok {
+				var n json.Number
+				if json.Unmarshal(v, &n) == nil {
+					if i, err := n.Int64(); err == nil {
+						*dst, found = int(i), true
+						return
+					}
+				}
+			}
+		}
+	}
+	intField(&m.Page, "page", "current_page", "currentPage", "page_number", "pageNumber")
+	intField(&m.TotalPages, "total_pages", "totalPages", "last_page", "lastPage", "page_count", "pageCount")
+	intField(&m.Total, "total", "total_count", "totalCount", "total_rows", "totalRows", "total_items", "totalItems")
+	intField(&m.NextPage, "next_page", "nextPage")
+	for _, k := range []string{"has_more", "hasMore", "has_next", "hasNext", "has_next_page", "hasNextPage"} {
+		if v, ok := obj[k]; ok && json.Unmarshal(v, &m.HasMore) == nil {
+			found = true
+			break
+		}
+	}
+	for _, k := range []string{"next_cursor", "nextCursor", "cursor_next"} {
+		if v, ok := obj[k]; ok && json.Unmarshal(v, &m.NextCursor) == nil {
+			found = true
+			break
+		}
+	}
+	return m, found
+}
+
+// nextPagePayload asks for the page after meta, the fetched-th page: by cursor when the API
+// gave one, else by page number.
+func nextPagePayload(payload map[string]any, meta pageMeta, fetched int) map[string]any {
+	next := make(map[string]any, len(payload)+1)
+	for k, v := range payload {
+		next[k] = v
+	}
+	switch {
+	case meta.NextCursor != "":
+		next["cursor"] = meta.NextCursor
+	case meta.NextPage > 0:
+		next["page"] = meta.NextPage
+	case meta.Page > 0:
+		next["page"] = meta.Page + 1
+	default:
+		next["page"] = fetched + 1
+	}
+	return next
+}
+
+// pageNote says whether a graph's rows are complete, for tools to append to their output.
+// It is empty for responses that were neither paginated nor cut short.
+func pageNote(ctx context.Context, info pageInfo) string {
+	var note string
+	switch {
+	case info.Truncated && info.Total > 0:
+		note = i18n.Sprintf(ctx, "Truncated: %d of %d rows fetched. Narrow the date range or filters to see the rest.", info.Rows, info.Total)
+	case info.Truncated:
+		note = i18n.Sprintf(ctx, "Truncated: %d rows fetched, and more exist. Narrow the date range or filters to see the rest.", info.Rows)
+	case info.Paged:
+		return i18n.Sprintf(ctx, "Complete: all %d rows fetched.", info.Rows)
+	default:
+		return ""
+	}
+	if info.Paged && info.Pages >= info.MaxPages {
+		note += " " + i18n.Sprintf(ctx, "Stopped at the limit of %d pages.", info.MaxPages)
+	}
+	return note
+}
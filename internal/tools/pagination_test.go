@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// pagedServer answers graph data requests with page(payload), checking that the payload is
+// kept between pages.
+func pagedServer(t *testing.T, page func(req map[string]any, w http.ResponseWriter) any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["analytics_date_filter"] != "last_7_days" {
+			t.Errorf("payload lost between pages: %v", req)
+		}
+		_ = json.NewEncoder(w).Encode(page(req, w))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// rowsFor returns page of 5 rows served two per page.
+func rowsFor(page int) []map[string]any {
+	var rows []map[string]any
+	for id := (page-1)*2 + 1; id <= min(page*2, 5); id++ {
+		rows = append(rows, map[string]any{"id": id})
+	}
+	return rows
+}
+
+func fetchIDs(t *testing.T, srv *httptest.Server) ([]int, pageInfo) {
+	t.Helper()
+	raw, info, errResp := fetchGraphPages(context.Background(), srv.Client(), srv.URL, "tok", 5, 51, map[string]any{"analytics_date_filter": "last_7_days"})
+	if errResp != nil {
+		t.Fatal(errResp.Message)
+	}
+	var rows []struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		t.Fatalf("rows: %v in %s", err, raw)
+	}
+	ids := make([]int, len(rows))
+	for i, r := range rows {
+		ids[i] = r.ID
+	}
+	return ids, info
+}
+
+func TestFetchGraphPagesFollowsPageNumbers(t *testing.T) {
+	srv := pagedServer(t, func(req map[string]any, _ http.ResponseWriter) any {
+		page := 1
+		if p, ok := req["page"].(float64); ok {
+			page = int(p)
+		}
+		return map[string]any{"data": rowsFor(page), "pagination": map[string]any{"page": page, "total_pages": 3, "total": 5}}
+	})
+
+	ids, info := fetchIDs(t, srv)
+	if fmt.Sprint(ids) != "[1 2 3 4 5]" || info.Pages != 3 || info.Truncated {
+		t.Fatalf("ids=%v info=%+v", ids, info)
+	}
+	if note := pageNote(context.Background(), info); note != "Complete: all 5 rows fetched." {
+		t.Fatalf("note = %q", note)
+	}
+
+	t.Setenv("PAYRAM_ANALYTICS_MAX_PAGES", "2")
+	ids, info = fetchIDs(t, srv)
+	if fmt.Sprint(ids) != "[1 2 3 4]" || !info.Truncated || info.Total != 5 {
+		t.Fatalf("capped: ids=%v info=%+v", ids, info)
+	}
+	note := pageNote(context.Background(), info)
+	if !strings.HasPrefix(note, "Truncated: 4 of 5 rows fetched.") || !strings.Contains(note, "limit of 2 pages") {
+		t.Fatalf("capped note = %q", note)
+	}
+}
+
+func TestFetchGraphPagesFollowsCursor(t *testing.T) {
+	srv := pagedServer(t, func(req map[string]any, _ http.ResponseWriter) any {
+		page := 1
+		if c, ok := req["cursor"].(string); ok {
+			fmt.Sscanf(c, "c%d", &page)
+		}
+		body := map[string]any{"items": rowsFor(page), "has_more": page < 3}
+		if page < 3 {
+			body["next_cursor"] = fmt.Sprintf("c%d", page+1)
+		}
+		return body
+	})
+
+	ids, info := fetchIDs(t, srv)
+	if fmt.Sprint(ids) != "[1 2 3 4 5]" || info.Pages != 3 || info.Truncated || info.Total != 0 {
+		t.Fatalf("ids=%v info=%+v", ids, info)
+	}
+}
+
+func TestFetchGraphPagesDetectsTruncatedArray(t *testing.T) {
+	srv := pagedServer(t, func(_ map[string]any, w http.ResponseWriter) any {
+		w.Header().Set("X-Total-Count", "5")
+		return rowsFor(1)
+	})
+
+	ids, info := fetchIDs(t, srv)
+	if fmt.Sprint(ids) != "[1 2]" || info.Paged || !info.Truncated || info.Total != 5 {
+		t.Fatalf("ids=%v info=%+v", ids, info)
+	}
+	if note := pageNote(context.Background(), info); note != "Truncated: 2 of 5 rows fetched. Narrow the date range or filters to see the rest." {
+		t.Fatalf("note = %q", note)
+	}
+}
+
+func TestFetchGraphPagesLeavesOtherResponses(t *testing.T) {
+	srv := pagedServer(t, func(map[string]any, http.ResponseWriter) any {
+		return map[string]any{"data": []int{1, 2}, "labels": []string{"a", "b"}}
+	})
+	raw, info, errResp := fetchGraphPages(context.Background(), srv.Client(), srv.URL, "tok", 2, 21, map[string]any{"analytics_date_filter": "last_7_days"})
+	if errResp != nil {
+		t.Fatal(errResp.Message)
+	}
+	if !strings.Contains(string(raw), `"labels"`) || info.Paged || info.Truncated || pageNote(context.Background(), info) != "" {
+		t.Fatalf("raw=%s info=%+v", raw, info)
+	}
+}
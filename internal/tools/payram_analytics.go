@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -21,7 +20,7 @@ type payramAnalyticsTool struct {
 // PayramAnalytics constructs the analytics tool.
 func PayramAnalytics() *payramAnalyticsTool {
 	return &payramAnalyticsTool{
-		client: &http.Client{Timeout: 15 * time.Second},
+		client: newHTTPClient(15 * time.Second),
 	}
 }
 
@@ -78,36 +77,25 @@ func (t *payramAnalyticsTool) Invoke(ctx context.Context, raw json.RawMessage) (
 	var args analyticsArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
 	// Resolve credentials and base URL: arguments override env.
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token in arguments"}
-	}
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url in arguments"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 	switch args.Action {
 	case "list_groups":
 		return t.listGroups(ctx, base, token)
 	case "graph_data":
 		if args.GroupID == 0 || args.GraphID == 0 {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "group_id and graph_id are required for graph_data"}
+			return protocol.CallResult{}, protocol.InvalidArgs("group_id and graph_id are required for graph_data")
 		}
 		return t.graphData(ctx, base, token, args.GroupID, args.GraphID, args.Payload)
 	default:
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "action must be list_groups or graph_data"}
+		return protocol.CallResult{}, protocol.InvalidArgs("action must be list_groups or graph_data")
 	}
 }
 
@@ -115,29 +103,28 @@ func (t *payramAnalyticsTool) listGroups(ctx context.Context, base, token string
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return protocol.CallResult{}, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return protocol.CallResult{}, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return protocol.CallResult{}, upstreamError(resp)
 	}
 
 	var data []groupEntry
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return protocol.CallResult{}, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 
 	summary := summarizeGroups(data)
-	pretty, _ := json.MarshalIndent(data, "", "  ")
-	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: fmt.Sprintf("Groups (summary):\n%s\n\nRaw:\n%s", summary, string(pretty))}}}, nil
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: fmt.Sprintf("Groups (summary):\n%s\n\nRaw:\n%s", summary, indentJSON(data))}}}, nil
 }
 
 func (t *payramAnalyticsTool) graphData(ctx context.Context, base, token string, groupID, graphID int, payload map[string]json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
@@ -149,7 +136,7 @@ func (t *payramAnalyticsTool) graphData(ctx context.Context, base, token string,
 	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return protocol.CallResult{}, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -157,21 +144,20 @@ func (t *payramAnalyticsTool) graphData(ctx context.Context, base, token string,
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return protocol.CallResult{}, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return protocol.CallResult{}, upstreamError(resp)
 	}
 
 	var data json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return protocol.CallResult{}, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
-	pretty, _ := json.MarshalIndent(data, "", "  ")
 	header := fmt.Sprintf("Graph data for group %d graph %d:", groupID, graphID)
-	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: fmt.Sprintf("%s\n%s", header, string(pretty))}}}, nil
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: header + "\n" + prettyJSON(data)}}}, nil
 }
 
 type groupEntry struct {
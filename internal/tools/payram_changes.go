@@ -0,0 +1,289 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// defaultChangeThreshold is the move, in percent, below which a metric counts as steady.
+const defaultChangeThreshold = 10
+
+// payramChangesTool snapshots the headline metrics and reports what moved since the
+// previous day's snapshot.
+type payramChangesTool struct {
+	dist *payramDepositDistributionTool
+	clocked
+}
+
+// PayramChanges constructs the tool.
+func PayramChanges() *payramChangesTool {
+	return &payramChangesTool{dist: PayramDepositDistribution()}
+}
+
+func (t *payramChangesTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{
+		Name: "payram_changes",
+		Description: `List the PayRam metrics that moved since the previous snapshot, for a morning "what changed" answer.
+
+Use cases:
+- What changed since yesterday
+- Did payment volume or transaction counts jump or drop
+- Which currencies started receiving payments
+
+Every call takes a snapshot of payment volume, transaction count, and volume and count per
+currency over date_filter, and compares it with the latest snapshot from an earlier day.
+The first call has nothing to compare with and becomes the baseline.`,
+		InputSchema: &protocol.JSONSchema{
+			Type: "object",
+			Properties: map[string]protocol.JSONSchema{
+				"token":    {Type: "string", Description: "Bearer token override; defaults to PAYRAM_ANALYTICS_TOKEN env"},
+				"base_url": {Type: "string", Description: "API base override; required if PAYRAM_ANALYTICS_BASE_URL env is not set"},
+				"date_filter": {
+					Type:        "string",
+					Description: "Window the metrics are measured over: today, yesterday, last_7_days, last_30_days, this_month, last_month, last_6_months or forever. Default last_30_days.",
+				},
+				"threshold_pct": {Type: "number", Description: "Report metrics that moved by at least this many percent. Default 10."},
+			},
+			Required: []string{},
+		},
+	}
+}
+
+type changesArgs struct {
+	Token        string   `json:"token"`
+	BaseURL      string   `json:"base_url"`
+	DateFilter   string   `json:"date_filter"`
+	ThresholdPct *float64 `json:"threshold_pct"`
+}
+
+func (t *payramChangesTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	var args changesArgs
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
+		}
+	}
+
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
+	}
+
+	dateFilter := strings.ToLower(strings.TrimSpace(args.DateFilter))
+	if dateFilter == "" {
+		dateFilter = "last_30_days"
+	}
+	// Snapshots are compared by window, so the window must name the same span every day.
+	if dateFilter == "custom" || !isAllowedDateFilter(dateFilter) {
+		return protocol.CallResult{}, protocol.InvalidArgs(fmt.Sprintf("date_filter must be one of today, yesterday, last_7_days, last_30_days, this_month, last_month, last_6_months or forever, got %s", args.DateFilter))
+	}
+	threshold := float64(defaultChangeThreshold)
+	if args.ThresholdPct != nil {
+		threshold = *args.ThresholdPct
+	}
+	if threshold < 0 {
+		return protocol.CallResult{}, protocol.InvalidArgs("threshold_pct must not be negative")
+	}
+
+	groups, err := t.dist.listGroups(ctx, base, token)
+	if err != nil {
+		return protocol.CallResult{}, err
+	}
+
+	var b strings.Builder
+	snap := metricSnapshot{Key: base + " " + dateFilter, TakenAt: t.now().UTC(), Metrics: map[string]float64{}, Currencies: []string{}}
+	payload := buildPayload(dateFilter, "", "", nil, nil)
+	for _, m := range []struct {
+		metric string
+		sel    *graphSelection
+	}{
+		{"volume_usd", pickGraph(groups, amountGraphNames())},
+		{"transactions", pickGraph(groups, countGraphNames())},
+	} {
+		if m.sel == nil {
+			continue
+		}
+		data, graphErr := t.dist.graphData(ctx, base, token, m.sel.groupID, m.sel.graphID, payload)
+		if graphErr != nil {
+			return protocol.CallResult{}, graphErr
+		}
+		if drift := checkGraphSchema(schemaKey(base, m.sel.groupID, m.sel.graphID, ""), m.sel.name, data); drift != nil {
+			b.WriteString(drift.warning(ctx) + "\n")
+			continue
+		}
+		for _, v := range graphTotals(data) {
+			snap.Metrics[m.metric] += v
+		}
+	}
+	for _, g := range groups {
+		if !strings.Contains(strings.ToLower(g.AnalyticsGroup.Name), "distribution") {
+			continue
+		}
+		for _, gr := range g.AnalyticsGroup.Graphs {
+			data, graphErr := t.dist.graphData(ctx, base, token, g.AnalyticsGroup.ID, gr.ID, buildDistributionPayload(dateFilter, "", "", "currency_code"))
+			if graphErr != nil {
+				return protocol.CallResult{}, graphErr
+			}
+			if drift := checkGraphSchema(schemaKey(base, g.AnalyticsGroup.ID, gr.ID, "currency_code"), gr.Name, data); drift != nil {
+				b.WriteString(drift.warning(ctx) + "\n")
+				continue
+			}
+			addCurrencyMetrics(&snap, data)
+		}
+		break
+	}
+	if len(snap.Metrics) == 0 && b.Len() == 0 {
+		return protocol.CallResult{}, protocol.NotFound("no payment volume, transaction count or currency distribution graphs found")
+	}
+	sort.Strings(snap.Currencies)
+
+	prev, found := metricSnapshots.record(snap)
+	header := i18n.Sprintf(ctx, "# What Changed (%s)\n\n", dateFilter)
+	if !found {
+		text := header + b.String() + i18n.Sprintf(ctx, "No earlier snapshot to compare with. This one, taken %s, is the baseline for the next run.", snap.TakenAt.Format("2006-01-02 15:04 UTC"))
+		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(text)}}}, nil
+	}
+
+	out := strings.Builder{}
+	out.WriteString(header)
+	out.WriteString(i18n.Sprintf(ctx, "Compared with the snapshot taken %s; moves of at least %s%% are listed.\n\n", prev.TakenAt.UTC().Format("2006-01-02 15:04 UTC"), formatFigure(threshold)))
+	out.WriteString(b.String())
+
+	moved, steady := metricMoves(prev.Metrics, snap.Metrics, threshold)
+	out.WriteString(i18n.Sprintf(ctx, "## Moved\n"))
+	if len(moved) == 0 {
+		out.WriteString(i18n.Sprintf(ctx, "Nothing moved by %s%% or more.\n", formatFigure(threshold)))
+	}
+	for _, k := range moved {
+		cur, was := snap.Metrics[k], prev.Metrics[k]
+		pct := "n/a"
+		if was != 0 {
+			pct = signed((cur-was)/math.Abs(was)*100) + "%"
+		}
+		fmt.Fprintf(&out, "- %s: %s vs %s (%s, %s)\n", metricLabel(ctx, k), formatFigure(cur), formatFigure(was), signed(cur-was), pct)
+	}
+	if steady > 0 {
+		out.WriteString("\n" + i18n.Sprintf(ctx, "%d other metrics moved by less than %s%%.\n", steady, formatFigure(threshold)))
+	}
+
+	added, gone := currencyChanges(prev.Currencies, snap.Currencies)
+	if len(added) > 0 {
+		out.WriteString("\n" + i18n.Sprintf(ctx, "## New currencies\n"))
+		for _, c := range added {
+			out.WriteString(i18n.Sprintf(ctx, "- %s: %s in volume\n", c, formatFigure(snap.Metrics["volume:"+c])))
+		}
+	}
+	if len(gone) > 0 {
+		out.WriteString("\n" + i18n.Sprintf(ctx, "## Currencies no longer seen\n"))
+		for _, c := range gone {
+			fmt.Fprintf(&out, "- %s\n", c)
+		}
+	}
+
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(out.String())}}}, nil
+}
+
+// addCurrencyMetrics adds the volume and count of each currency in currency distribution
+// data, rows such as {"currency_code": "USDT", "amount": 5400.25, "count": 61}, to snap.
+// Currencies without payments are not counted as seen.
+func addCurrencyMetrics(snap *metricSnapshot, data string) {
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(data), &rows); err != nil {
+		return
+	}
+	for _, row := range rows {
+		code := strings.ToUpper(firstString(row, "currency_code", "currency", "code", "name"))
+		if code == "" {
+			continue
+		}
+		amount, hasAmount := toFloat(row["amount"])
+		count, hasCount := toFloat(row["count"])
+		if hasAmount {
+			snap.Metrics["volume:"+code] += amount
+		}
+		if hasCount {
+			snap.Metrics["transactions:"+code] += count
+		}
+		if (amount != 0 || count != 0) && !slices.Contains(snap.Currencies, code) {
+			snap.Currencies = append(snap.Currencies, code)
+		}
+	}
+}
+
+// metricMoves lists the metrics in both snapshots that moved by at least threshold
+// percent, largest move first, and counts the rest. A metric that was zero moved if it is
+// no longer zero.
+func metricMoves(before, now map[string]float64, threshold float64) ([]string, int) {
+	type move struct {
+		key string
+		pct float64
+	}
+	var moves []move
+	steady := 0
+	for k, cur := range now {
+		was, ok := before[k]
+		if !ok {
+			continue
+		}
+		pct := math.Inf(1)
+		if was != 0 {
+			pct = math.Abs(cur-was) / math.Abs(was) * 100
+		} else if cur == 0 {
+			pct = 0
+		}
+		if pct >= threshold && pct > 0 {
+			moves = append(moves, move{k, pct})
+		} else {
+			steady++
+		}
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].pct != moves[j].pct {
+			return moves[i].pct > moves[j].pct
+		}
+		return moves[i].key < moves[j].key
+	})
+	keys := make([]string, len(moves))
+	for i, m := range moves {
+		keys[i] = m.key
+	}
+	return keys, steady
+}
+
+// currencyChanges lists the currencies in now but not before, and those in before but not
+// now.
+func currencyChanges(before, now []string) (added, gone []string) {
+	for _, c := range now {
+		if !slices.Contains(before, c) {
+			added = append(added, c)
+		}
+	}
+	for _, c := range before {
+		if !slices.Contains(now, c) {
+			gone = append(gone, c)
+		}
+	}
+	return added, gone
+}
+
+func metricLabel(ctx context.Context, key string) string {
+	switch {
+	case key == "volume_usd":
+		return i18n.Sprintf(ctx, "Payment volume (USD)")
+	case key == "transactions":
+		return i18n.Sprintf(ctx, "Transactions")
+	case strings.HasPrefix(key, "volume:"):
+		return i18n.Sprintf(ctx, "%s volume", strings.TrimPrefix(key, "volume:"))
+	case strings.HasPrefix(key, "transactions:"):
+		return i18n.Sprintf(ctx, "%s transactions", strings.TrimPrefix(key, "transactions:"))
+	}
+	return key
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+)
+
+func TestChangesComparesWithYesterdaysSnapshot(t *testing.T) {
+	srv := analyticsFixtureServer(t)
+	t.Setenv("PAYRAM_ANALYTICS_BASE_URL", srv.URL)
+	t.Setenv("PAYRAM_ANALYTICS_TOKEN", goldenToken)
+	path := filepath.Join(t.TempDir(), "snapshots.json")
+	t.Setenv("PAYRAM_ANALYTICS_SNAPSHOT_FILE", path)
+	prev := metricSnapshots
+	defer func() { metricSnapshots = prev }()
+	metricSnapshots = &snapshotStore{}
+
+	yesterday := []metricSnapshot{{
+		Key:     srv.URL + " this_month",
+		TakenAt: time.Date(2026, 2, 3, 7, 30, 0, 0, time.UTC),
+		Metrics: map[string]float64{
+			"volume_usd": 100000, "transactions": 830,
+			"volume:USDT": 5400, "transactions:USDT": 61,
+			"volume:BTC": 1000, "transactions:BTC": 7,
+		},
+		Currencies: []string{"BTC", "SOL", "USDT"},
+	}}
+	raw, _ := json.Marshal(yesterday)
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := PayramChanges()
+	tool.SetClock(clock.Fixed(time.Date(2026, 2, 4, 8, 0, 0, 0, time.UTC)))
+	invoke := func() string {
+		t.Helper()
+		result, errResp := tool.Invoke(context.Background(), json.RawMessage(`{"date_filter":"this_month"}`))
+		if errResp != nil {
+			t.Fatalf("Invoke: %s", errResp.Message)
+		}
+		return result.Content[0].Text
+	}
+	want := []string{
+		"Compared with the snapshot taken 2026-02-03 07:30 UTC; moves of at least 10% are listed.",
+		"## Moved\n- BTC volume: 2100 vs 1000 (+1100, +110%)\n- Payment volume (USD): 125430.55 vs 100000 (+25430.55, +25.43%)\n",
+		"4 other metrics moved by less than 10%.",
+		"## New currencies\n- ETH: 830.1 in volume",
+		"## Currencies no longer seen\n- SOL",
+	}
+	out := invoke()
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Fatalf("missing %q in:\n%s", w, out)
+		}
+	}
+
+	// Asking again the same morning still compares with yesterday, and a restart keeps both.
+	metricSnapshots = &snapshotStore{}
+	if again := invoke(); again != out {
+		t.Fatalf("second run differs:\n%s", again)
+	}
+	var saved []metricSnapshot
+	raw, _ = os.ReadFile(path)
+	if err := json.Unmarshal(raw, &saved); err != nil || len(saved) != 2 || saved[1].Metrics["volume:ETH"] != 830.1 {
+		t.Fatalf("saved snapshots: %v %s", err, raw)
+	}
+}
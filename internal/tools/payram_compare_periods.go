@@ -6,10 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/glossary"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
@@ -21,7 +22,7 @@ type payramComparePeriodsTool struct {
 
 // PayramComparePeriods constructs the tool.
 func PayramComparePeriods() *payramComparePeriodsTool {
-	return &payramComparePeriodsTool{client: &http.Client{Timeout: 30 * time.Second}}
+	return &payramComparePeriodsTool{client: newHTTPClient(30 * time.Second)}
 }
 
 func (t *payramComparePeriodsTool) Descriptor() protocol.ToolDescriptor {
@@ -67,43 +68,32 @@ Returns data from both periods for comparison including:
 }
 
 type compareArgs struct {
-	Token         string   `json:"token"`
-	BaseURL       string   `json:"base_url"`
-	Period1       string   `json:"period1"`
-	Period2       string   `json:"period2"`
-	Metric        string   `json:"metric"`
-	CurrencyCodes []string `json:"currency_codes"`
+	Token         string        `json:"token"`
+	BaseURL       string        `json:"base_url"`
+	Period1       string        `json:"period1"`
+	Period2       string        `json:"period2"`
+	Metric        string        `json:"metric"`
+	CurrencyCodes currencyCodes `json:"currency_codes"`
 }
 
 func (t *payramComparePeriodsTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
 	var args compareArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
 	if args.Period1 == "" || args.Period2 == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "period1 and period2 are required"}
+		return protocol.CallResult{}, protocol.InvalidArgs("period1 and period2 are required")
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
-	metric := strings.ToLower(strings.TrimSpace(args.Metric))
+	metric := glossary.Default().Metric(args.Metric)
 	if metric == "" {
 		metric = "both"
 	}
@@ -123,11 +113,11 @@ func (t *payramComparePeriodsTool) Invoke(ctx context.Context, raw json.RawMessa
 		}
 	}
 	if txGroup == nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "Transaction Summary group not found"}
+		return protocol.CallResult{}, protocol.NotFound("Transaction Summary group not found")
 	}
 
 	respText := strings.Builder{}
-	respText.WriteString(fmt.Sprintf("# Period Comparison: %s vs %s\n\n", args.Period1, args.Period2))
+	respText.WriteString(i18n.Sprintf(ctx, "# Period Comparison: %s vs %s\n\n", args.Period1, args.Period2))
 
 	// Find amount and count graph IDs
 	var amountGraphID, countGraphID int
@@ -143,23 +133,23 @@ func (t *payramComparePeriodsTool) Invoke(ctx context.Context, raw json.RawMessa
 
 	// Fetch and compare data
 	if (metric == "amount" || metric == "both") && amountGraphID > 0 {
-		respText.WriteString("## Payments in USD\n\n")
+		respText.WriteString(i18n.Sprintf(ctx, "## Payments in USD\n\n"))
 
 		data1, _ := t.fetchPeriodData(ctx, base, token, txGroup.AnalyticsGroup.ID, amountGraphID, args.Period1, args.CurrencyCodes)
 		data2, _ := t.fetchPeriodData(ctx, base, token, txGroup.AnalyticsGroup.ID, amountGraphID, args.Period2, args.CurrencyCodes)
 
-		respText.WriteString(fmt.Sprintf("### %s:\n%s\n\n", args.Period1, data1))
-		respText.WriteString(fmt.Sprintf("### %s:\n%s\n\n", args.Period2, data2))
+		fmt.Fprintf(&respText, "### %s:\n%s\n\n", args.Period1, data1)
+		fmt.Fprintf(&respText, "### %s:\n%s\n\n", args.Period2, data2)
 	}
 
 	if (metric == "count" || metric == "both") && countGraphID > 0 {
-		respText.WriteString("## Number of Transactions\n\n")
+		respText.WriteString(i18n.Sprintf(ctx, "## Number of Transactions\n\n"))
 
 		data1, _ := t.fetchPeriodData(ctx, base, token, txGroup.AnalyticsGroup.ID, countGraphID, args.Period1, args.CurrencyCodes)
 		data2, _ := t.fetchPeriodData(ctx, base, token, txGroup.AnalyticsGroup.ID, countGraphID, args.Period2, args.CurrencyCodes)
 
-		respText.WriteString(fmt.Sprintf("### %s:\n%s\n\n", args.Period1, data1))
-		respText.WriteString(fmt.Sprintf("### %s:\n%s\n\n", args.Period2, data2))
+		fmt.Fprintf(&respText, "### %s:\n%s\n\n", args.Period1, data1)
+		fmt.Fprintf(&respText, "### %s:\n%s\n\n", args.Period2, data2)
 	}
 
 	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(respText.String())}}}, nil
@@ -178,7 +168,7 @@ func (t *payramComparePeriodsTool) fetchPeriodData(ctx context.Context, base, to
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -186,44 +176,43 @@ func (t *payramComparePeriodsTool) fetchPeriodData(ctx context.Context, base, to
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return "", transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return "", upstreamError(resp)
 	}
 
 	var raw json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
-	pretty, _ := json.MarshalIndent(raw, "", "  ")
-	return string(pretty), nil
+	return prettyJSON(raw), nil
 }
 
 func (t *payramComparePeriodsTool) listGroups(ctx context.Context, base, token string) ([]paymentsGroupWrapper, *protocol.ResponseError) {
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return nil, upstreamError(resp)
 	}
 
 	var data []paymentsGroupWrapper
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return data, nil
 }
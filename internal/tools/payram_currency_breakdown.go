@@ -7,21 +7,23 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/glossary"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
 // payramCurrencyBreakdownTool provides detailed payment breakdown by currency.
 type payramCurrencyBreakdownTool struct {
 	client *http.Client
+	clocked
 }
 
 // PayramCurrencyBreakdown constructs the tool.
 func PayramCurrencyBreakdown() *payramCurrencyBreakdownTool {
-	return &payramCurrencyBreakdownTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramCurrencyBreakdownTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramCurrencyBreakdownTool) Descriptor() protocol.ToolDescriptor {
@@ -75,33 +77,22 @@ func (t *payramCurrencyBreakdownTool) Invoke(ctx context.Context, raw json.RawMe
 	var args currencyBreakdownArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
 	var dateFilter, customStart, customEnd string
 	var errResp *protocol.ResponseError
 	if args.Days > 0 {
 		dateFilter = "custom"
-		customStart, customEnd = lastNDaysRange(args.Days)
+		customStart, customEnd = lastNDaysRange(t.now(), args.Days)
 	} else {
-		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(args.DateFilter, "", "")
+		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(t.now(), args.DateFilter, "", "")
 	}
 	if errResp != nil {
 		return protocol.CallResult{}, errResp
@@ -112,7 +103,10 @@ func (t *payramCurrencyBreakdownTool) Invoke(ctx context.Context, raw json.RawMe
 		groupBy = "currency_code"
 	}
 
-	currencyFilter := strings.ToUpper(strings.TrimSpace(args.CurrencyCode))
+	currencyFilter := ""
+	if strings.TrimSpace(args.CurrencyCode) != "" {
+		currencyFilter = glossary.Default().Currency(args.CurrencyCode)
+	}
 
 	groups, err := t.listGroups(ctx, base, token)
 	if err != nil {
@@ -129,14 +123,14 @@ func (t *payramCurrencyBreakdownTool) Invoke(ctx context.Context, raw json.RawMe
 		}
 	}
 	if distGroup == nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "Distribution analytics group not found"}
+		return protocol.CallResult{}, protocol.NotFound("Distribution analytics group not found")
 	}
 
 	respText := strings.Builder{}
 	if currencyFilter != "" {
-		respText.WriteString(fmt.Sprintf("# %s Payment Data (%s)\n\n", currencyFilter, dateFilter))
+		respText.WriteString(i18n.Sprintf(ctx, "# %s Payment Data (%s)\n\n", currencyFilter, dateFilter))
 	} else {
-		respText.WriteString(fmt.Sprintf("# Currency Breakdown (grouped by %s, %s)\n\n", groupBy, dateFilter))
+		respText.WriteString(i18n.Sprintf(ctx, "# Currency Breakdown (grouped by %s, %s)\n\n", groupBy, dateFilter))
 	}
 
 	payload := map[string]any{}
@@ -155,26 +149,30 @@ func (t *payramCurrencyBreakdownTool) Invoke(ctx context.Context, raw json.RawMe
 	for _, gr := range distGroup.AnalyticsGroup.Graphs {
 		data, graphErr := t.graphData(ctx, base, token, distGroup.AnalyticsGroup.ID, gr.ID, payload)
 		if graphErr != nil {
-			respText.WriteString(fmt.Sprintf("- %s: error (%s)\n", gr.Name, graphErr.Message))
+			fmt.Fprintf(&respText, "- %s: error (%s)\n", gr.Name, graphErr.Message)
 			continue
 		}
 
+		// The grouping decides the label field, so each one is its own model.
+		drift := checkGraphSchema(schemaKey(base, distGroup.AnalyticsGroup.ID, gr.ID, groupBy), gr.Name, data)
 		// If currency filter is set, extract only that currency's data
-		if currencyFilter != "" {
+		if currencyFilter != "" && drift != nil {
+			fmt.Fprintf(&respText, "## %s\n%s\n%s\n\n", gr.Name, drift.warning(ctx), data)
+		} else if currencyFilter != "" {
 			extracted, found := t.extractCurrencyData(data, currencyFilter)
 			if found {
-				respText.WriteString(fmt.Sprintf("## %s\n%s\n\n", gr.Name, extracted))
+				fmt.Fprintf(&respText, "## %s\n%s\n\n", gr.Name, extracted)
 			}
 			// If not found, we continue to next graph silently
 		} else {
-			respText.WriteString(fmt.Sprintf("## %s\n%s\n\n", gr.Name, data))
+			fmt.Fprintf(&respText, "## %s\n%s\n\n", gr.Name, data)
 		}
 	}
 
 	// If we have a currency filter but found nothing, return helpful message
 	result := strings.TrimSpace(respText.String())
 	if currencyFilter != "" && result == fmt.Sprintf("# %s Payment Data (%s)", currencyFilter, dateFilter) {
-		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: fmt.Sprintf("No %s transactions found in the selected period. The data might be grouped differently - try without currency_code to see all currencies.", currencyFilter)}}}, nil
+		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: i18n.Sprintf(ctx, "No %s transactions found in the selected period. The data might be grouped differently - try without currency_code to see all currencies.", currencyFilter)}}}, nil
 	}
 
 	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: result}}}, nil
@@ -201,8 +199,7 @@ func (t *payramCurrencyBreakdownTool) extractCurrencyData(jsonData, currencyCode
 					if code, exists := m[field]; exists {
 						codeStr := fmt.Sprint(code)
 						if strings.EqualFold(codeStr, currencyCode) {
-							pretty, _ := json.MarshalIndent(m, "", "  ")
-							return string(pretty), true
+							return indentJSON(m), true
 						}
 					}
 				}
@@ -215,14 +212,12 @@ func (t *payramCurrencyBreakdownTool) extractCurrencyData(jsonData, currencyCode
 		log.Printf("[payram_currency_breakdown] Response is object with keys: %v", getKeys(obj))
 		// Direct lookup
 		if val, exists := obj[currencyCode]; exists {
-			pretty, _ := json.MarshalIndent(val, "", "  ")
-			return string(pretty), true
+			return indentJSON(val), true
 		}
 		// Case-insensitive lookup
 		for key, val := range obj {
 			if strings.EqualFold(key, currencyCode) {
-				pretty, _ := json.MarshalIndent(val, "", "  ")
-				return string(pretty), true
+				return indentJSON(val), true
 			}
 		}
 		// Check if it's nested data with "data" key
@@ -233,8 +228,7 @@ func (t *payramCurrencyBreakdownTool) extractCurrencyData(jsonData, currencyCode
 						for _, field := range []string{"currency_code", "code", "name", "label", "currency"} {
 							if code, exists := m[field]; exists {
 								if strings.EqualFold(fmt.Sprint(code), currencyCode) {
-									pretty, _ := json.MarshalIndent(m, "", "  ")
-									return string(pretty), true
+									return indentJSON(m), true
 								}
 							}
 						}
@@ -266,24 +260,24 @@ func (t *payramCurrencyBreakdownTool) listGroups(ctx context.Context, base, toke
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return nil, upstreamError(resp)
 	}
 
 	var data []paymentsGroupWrapper
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return data, nil
 }
@@ -293,7 +287,7 @@ func (t *payramCurrencyBreakdownTool) graphData(ctx context.Context, base, token
 	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -301,18 +295,17 @@ func (t *payramCurrencyBreakdownTool) graphData(ctx context.Context, base, token
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return "", transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return "", upstreamError(resp)
 	}
 
 	var raw json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
-	pretty, _ := json.MarshalIndent(raw, "", "  ")
-	return string(pretty), nil
+	return prettyJSON(raw), nil
 }
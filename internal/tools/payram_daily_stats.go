@@ -6,21 +6,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
 // payramDailyStatsTool provides per-day statistics for a given period.
 type payramDailyStatsTool struct {
 	client *http.Client
+	clocked
 }
 
 // PayramDailyStats constructs the tool.
 func PayramDailyStats() *payramDailyStatsTool {
-	return &payramDailyStatsTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramDailyStatsTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramDailyStatsTool) Descriptor() protocol.ToolDescriptor {
@@ -68,50 +69,39 @@ Returns per-day data with:
 }
 
 type dailyStatsArgs struct {
-	Token          string   `json:"token"`
-	BaseURL        string   `json:"base_url"`
-	Days           int      `json:"days"`
-	DateFilter     string   `json:"date_filter"`
-	CurrencyCodes  []string `json:"currency_codes"`
-	IncludeAmounts *bool    `json:"include_amounts"`
-	IncludeCounts  *bool    `json:"include_counts"`
+	Token          string        `json:"token"`
+	BaseURL        string        `json:"base_url"`
+	Days           int           `json:"days"`
+	DateFilter     string        `json:"date_filter"`
+	CurrencyCodes  currencyCodes `json:"currency_codes"`
+	IncludeAmounts *bool         `json:"include_amounts"`
+	IncludeCounts  *bool         `json:"include_counts"`
 }
 
 func (t *payramDailyStatsTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
 	var args dailyStatsArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
 	var dateFilter, customStart, customEnd string
 	var errResp *protocol.ResponseError
 	if args.Days > 0 {
 		dateFilter = "custom"
-		customStart, customEnd = lastNDaysRange(args.Days)
+		customStart, customEnd = lastNDaysRange(t.now(), args.Days)
 	} else {
 		df := args.DateFilter
 		if df == "" {
 			df = "last_7_days"
 		}
-		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(df, "", "")
+		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(t.now(), df, "", "")
 	}
 	if errResp != nil {
 		return protocol.CallResult{}, errResp
@@ -134,14 +124,14 @@ func (t *payramDailyStatsTool) Invoke(ctx context.Context, raw json.RawMessage)
 		}
 	}
 	if txGroup == nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "Transaction Summary group not found"}
+		return protocol.CallResult{}, protocol.NotFound("Transaction Summary group not found")
 	}
 
 	respText := strings.Builder{}
 	if args.Days > 0 {
-		respText.WriteString(fmt.Sprintf("# Daily Statistics (Last %d Days)\n\n", args.Days))
+		respText.WriteString(i18n.Sprintf(ctx, "# Daily Statistics (Last %d Days)\n\n", args.Days))
 	} else {
-		respText.WriteString(fmt.Sprintf("# Daily Statistics (%s)\n\n", dateFilter))
+		respText.WriteString(i18n.Sprintf(ctx, "# Daily Statistics (%s)\n\n", dateFilter))
 	}
 
 	payload := map[string]any{}
@@ -157,6 +147,7 @@ func (t *payramDailyStatsTool) Invoke(ctx context.Context, raw json.RawMessage)
 		payload["currency_codes"] = args.CurrencyCodes
 	}
 
+	var charts []*protocol.ChartData
 	for _, gr := range txGroup.AnalyticsGroup.Graphs {
 		name := strings.ToLower(gr.Name)
 		isAmount := strings.Contains(name, "usd") || strings.Contains(name, "amount")
@@ -168,37 +159,44 @@ func (t *payramDailyStatsTool) Invoke(ctx context.Context, raw json.RawMessage)
 
 		data, graphErr := t.graphData(ctx, base, token, txGroup.AnalyticsGroup.ID, gr.ID, payload)
 		if graphErr != nil {
-			respText.WriteString(fmt.Sprintf("## %s\nError: %s\n\n", gr.Name, graphErr.Message))
+			respText.WriteString(i18n.Sprintf(ctx, "## %s\nError: %s\n\n", gr.Name, graphErr.Message))
 			continue
 		}
-		respText.WriteString(fmt.Sprintf("## %s\n%s\n\n", gr.Name, data))
+		if drift := checkGraphSchema(schemaKey(base, txGroup.AnalyticsGroup.ID, gr.ID, ""), gr.Name, data); drift != nil {
+			fmt.Fprintf(&respText, "## %s\n%s\n%s\n\n", gr.Name, drift.warning(ctx), data)
+			continue
+		}
+		fmt.Fprintf(&respText, "## %s\n%s\n\n", gr.Name, data)
+		if c := chartFromGraph(protocol.ChartTimeSeries, gr.Name, data); c != nil {
+			charts = append(charts, c)
+		}
 	}
 
-	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(respText.String())}}}, nil
+	return chartResult(strings.TrimSpace(respText.String()), charts), nil
 }
 
 func (t *payramDailyStatsTool) listGroups(ctx context.Context, base, token string) ([]paymentsGroupWrapper, *protocol.ResponseError) {
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return nil, upstreamError(resp)
 	}
 
 	var data []paymentsGroupWrapper
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return data, nil
 }
@@ -208,7 +206,7 @@ func (t *payramDailyStatsTool) graphData(ctx context.Context, base, token string
 	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -216,18 +214,17 @@ func (t *payramDailyStatsTool) graphData(ctx context.Context, base, token string
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return "", transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return "", upstreamError(resp)
 	}
 
 	var raw json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
-	pretty, _ := json.MarshalIndent(raw, "", "  ")
-	return string(pretty), nil
+	return prettyJSON(raw), nil
 }
@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// DebugRequestTool is the name of the raw analytics request tool, which the chat API only
+// offers to operators.
+const DebugRequestTool = "payram_debug_request"
+
+// maxDebugBody caps the response body returned by payram_debug_request.
+const maxDebugBody = 64 << 10
+
+// DefaultDebugPaths are the analytics API paths payram_debug_request may call when no
+// allowlist is configured: the group list and graph data.
+var DefaultDebugPaths = []string{
+	"/api/v1/external-platform/all/analytics/groups",
+	"/api/v1/external-platform/all/analytics/groups/*/graph/*/data",
+}
+
+// payramDebugRequestTool sends a raw request to the analytics API, for support staff
+// comparing what the API returns with what the other tools report.
+type payramDebugRequestTool struct {
+	client *http.Client
+	paths  []string
+}
+
+// PayramDebugRequest constructs the tool. It may only call paths matching one of the
+// path.Match globs in paths.
+func PayramDebugRequest(paths []string) *payramDebugRequestTool {
+	return &payramDebugRequestTool{client: newHTTPClient(30 * time.Second), paths: paths}
+}
+
+func (t *payramDebugRequestTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{
+		Name: DebugRequestTool,
+		Description: `Send a raw GET or POST to the PayRam analytics API with the configured token, and return the HTTP status, headers and body as received.
+
+Use only when debugging a discrepancy, e.g. when a summary tool and the dashboard disagree: the other tools interpret responses, this one shows them unchanged. Allowed paths: ` + strings.Join(t.paths, ", "),
+		InputSchema: &protocol.JSONSchema{
+			Type: "object",
+			Properties: map[string]protocol.JSONSchema{
+				"method": {Type: "string", Description: "GET or POST. Default: GET", Enum: []string{"GET", "POST"}},
+				"path":   {Type: "string", Description: "API path, e.g. /api/v1/external-platform/all/analytics/groups/2/graph/21/data"},
+				"query":  {Type: "object", Description: "Query string parameters, as an object of strings"},
+				"body":   {Type: "object", Description: "JSON body for POST, e.g. {\"analytics_date_filter\": \"last_7_days\"}"},
+			},
+			Required: []string{"path"},
+		},
+	}
+}
+
+type debugRequestArgs struct {
+	Method string            `json:"method"`
+	Path   string            `json:"path"`
+	Query  map[string]string `json:"query"`
+	Body   json.RawMessage   `json:"body"`
+}
+
+func (t *payramDebugRequestTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	var args debugRequestArgs
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
+		}
+	}
+	method := strings.ToUpper(strings.TrimSpace(args.Method))
+	switch method {
+	case "":
+		method = http.MethodGet
+	case http.MethodGet, http.MethodPost:
+	default:
+		return protocol.CallResult{}, protocol.InvalidArgs(fmt.Sprintf("method must be GET or POST, got %s", args.Method))
+	}
+	if !t.allowed(args.Path) {
+		return protocol.CallResult{}, protocol.InvalidArgs(fmt.Sprintf("path %q is not allowed; allowed paths: %s", args.Path, strings.Join(t.paths, ", ")))
+	}
+	if method == http.MethodGet && len(args.Body) > 0 {
+		return protocol.CallResult{}, protocol.InvalidArgs("body is only sent with POST")
+	}
+
+	// The configured credentials only: a debug request must not be pointed elsewhere.
+	token, base, credErr := resolveCredentials(ctx, "", "")
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
+	}
+	target := base + args.Path
+	if len(args.Query) > 0 {
+		q := url.Values{}
+		for k, v := range args.Query {
+			q.Set(k, v)
+		}
+		target += "?" + q.Encode()
+	}
+	var body io.Reader
+	if method == http.MethodPost {
+		if len(args.Body) == 0 {
+			args.Body = json.RawMessage("{}")
+		}
+		body = bytes.NewReader(args.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return protocol.CallResult{}, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return protocol.CallResult{}, transportError(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDebugBody+1))
+	if err != nil {
+		return protocol.CallResult{}, transportError(err)
+	}
+
+	// Non-2xx responses are what is being debugged, so they are results, not tool errors.
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s %s\n\n", method, args.Path)
+	fmt.Fprintf(&b, "Status: %s\n", resp.Status)
+	for _, h := range []string{"Content-Type", "Content-Length", "X-Request-Id"} {
+		if v := resp.Header.Get(h); v != "" {
+			fmt.Fprintf(&b, "%s: %s\n", h, v)
+		}
+	}
+	truncated := len(data) > maxDebugBody
+	if truncated {
+		data = data[:maxDebugBody]
+	}
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, data, "", "  ") == nil {
+		data = pretty.Bytes()
+	}
+	fmt.Fprintf(&b, "\n```\n%s\n```", strings.TrimSpace(string(data)))
+	if truncated {
+		fmt.Fprintf(&b, "\n\nBody truncated at %d bytes.", maxDebugBody)
+	}
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: b.String()}}}, nil
+}
+
+// allowed reports whether p is a clean absolute path matching the allowlist.
+func (t *payramDebugRequestTool) allowed(p string) bool {
+	if !strings.HasPrefix(p, "/") || path.Clean(p) != p || strings.ContainsAny(p, "?#%") {
+		return false
+	}
+	for _, pattern := range t.paths {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -5,10 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
@@ -20,7 +20,7 @@ type payramDiscoverAnalyticsTool struct {
 
 // PayramDiscoverAnalytics constructs the tool.
 func PayramDiscoverAnalytics() *payramDiscoverAnalyticsTool {
-	return &payramDiscoverAnalyticsTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramDiscoverAnalyticsTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramDiscoverAnalyticsTool) Descriptor() protocol.ToolDescriptor {
@@ -62,24 +62,13 @@ func (t *payramDiscoverAnalyticsTool) Invoke(ctx context.Context, raw json.RawMe
 	var args discoverArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
 	groups, err := t.listGroups(ctx, base, token)
@@ -89,30 +78,30 @@ func (t *payramDiscoverAnalyticsTool) Invoke(ctx context.Context, raw json.RawMe
 
 	// Format output as structured discovery info
 	respText := strings.Builder{}
-	respText.WriteString("# Available PayRam Analytics\n\n")
+	respText.WriteString(i18n.Sprintf(ctx, "# Available PayRam Analytics\n\n"))
 
 	for _, g := range groups {
 		ag := g.AnalyticsGroup
-		respText.WriteString(fmt.Sprintf("## Group: %s (ID: %d)\n", ag.Name, ag.ID))
+		respText.WriteString(i18n.Sprintf(ctx, "## Group: %s (ID: %d)\n", ag.Name, ag.ID))
 		if ag.Description != "" {
-			respText.WriteString(fmt.Sprintf("Description: %s\n", ag.Description))
+			respText.WriteString(i18n.Sprintf(ctx, "Description: %s\n", ag.Description))
 		}
 
 		// List filters
 		if len(ag.Filters) > 0 {
-			respText.WriteString("### Filters:\n")
+			respText.WriteString(i18n.Sprintf(ctx, "### Filters:\n"))
 			for _, f := range ag.Filters {
-				respText.WriteString(fmt.Sprintf("- %s (type: %s)\n", f.Name, f.Type))
+				fmt.Fprintf(&respText, "- %s (type: %s)\n", f.Name, f.Type)
 			}
 		}
 
 		// List graphs
 		if len(ag.Graphs) > 0 {
-			respText.WriteString("### Graphs:\n")
+			respText.WriteString(i18n.Sprintf(ctx, "### Graphs:\n"))
 			for _, gr := range ag.Graphs {
-				respText.WriteString(fmt.Sprintf("- **%s** (ID: %d, type: %s)\n", gr.Name, gr.ID, gr.GraphType))
+				fmt.Fprintf(&respText, "- **%s** (ID: %d, type: %s)\n", gr.Name, gr.ID, gr.GraphType)
 				if gr.Description != "" {
-					respText.WriteString(fmt.Sprintf("  Description: %s\n", gr.Description))
+					respText.WriteString("  " + i18n.Sprintf(ctx, "Description: %s\n", gr.Description))
 				}
 			}
 		}
@@ -120,35 +109,13 @@ func (t *payramDiscoverAnalyticsTool) Invoke(ctx context.Context, raw json.RawMe
 	}
 
 	respText.WriteString("---\n")
-	respText.WriteString("To fetch data from a specific graph, use `payram_fetch_graph_data` with the group_id and graph_id.\n")
+	respText.WriteString("To fetch data from a specific graph, use `payram_fetch_graph_data` with its graph_name (and group_name when several groups have a graph of that name).\n")
 
 	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(respText.String())}}}, nil
 }
 
 func (t *payramDiscoverAnalyticsTool) listGroups(ctx context.Context, base, token string) ([]discoverGroupWrapper, *protocol.ResponseError) {
-	url := base + "/api/v1/external-platform/all/analytics/groups"
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
-	}
-
-	var data []discoverGroupWrapper
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
-	}
-	return data, nil
+	return discoverGroups(ctx, t.client, base, token)
 }
 
 // Types for discovery (with graph type info)
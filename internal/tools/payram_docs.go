@@ -7,10 +7,13 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"unicode/utf8"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/glossary"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
@@ -19,6 +22,11 @@ import (
 //   - search: query markdown corpus, optional category filter, limit results
 //   - get_section: return a specific section (by path and optional heading) or whole file
 type payramDocsTool struct {
+	corpora map[string]*docsCorpus // language tag -> docs in that language
+}
+
+// docsCorpus is the indexed docs of one language.
+type docsCorpus struct {
 	sections       []docSection
 	sectionsByPath map[string][]docSection // path -> sections
 	files          map[string]string       // path -> full content
@@ -34,20 +42,64 @@ type docSection struct {
 }
 
 // PayramDocs builds the docs tool, indexing markdown under docs/payram-docs by default.
+// Subdirectories named by a language tag, such as es or pt-br, hold the docs in that
+// language; everything else, and an en subdirectory, is the English corpus.
 func PayramDocs() *payramDocsTool {
 	root := strings.TrimSpace(os.Getenv("PAYRAM_DOCS_ROOT"))
 	if root == "" {
 		root = filepath.Join("docs", "payram-docs")
 	}
 
-	sections, byPath, files := indexDocs(root)
+	var langs []string
+	entries, _ := os.ReadDir(root)
+	for _, e := range entries {
+		if tag, ok := i18n.Parse(e.Name()); ok && e.IsDir() && tag != i18n.Default {
+			langs = append(langs, e.Name())
+		}
+	}
+
+	corpora := map[string]*docsCorpus{i18n.Default: newDocsCorpus(filepath.Join(root, i18n.Default), nil)}
+	// Docs directly under root predate the language directories and are English.
+	if top := newDocsCorpus(root, append([]string{i18n.Default}, langs...)); len(top.sections) > 0 {
+		corpora[i18n.Default] = top.merge(corpora[i18n.Default])
+	}
+	for _, dir := range langs {
+		tag, _ := i18n.Parse(dir)
+		corpora[tag] = newDocsCorpus(filepath.Join(root, dir), nil)
+	}
+	return &payramDocsTool{corpora: corpora}
+}
+
+func newDocsCorpus(root string, skipDirs []string) *docsCorpus {
+	sections, byPath, files := indexDocs(root, skipDirs)
 	applyTopics(sections)
+	return &docsCorpus{sections: sections, sectionsByPath: byPath, files: files}
+}
+
+// merge adds the files of o that c does not have.
+func (c *docsCorpus) merge(o *docsCorpus) *docsCorpus {
+	for path, secs := range o.sectionsByPath {
+		if _, ok := c.sectionsByPath[path]; ok {
+			continue
+		}
+		c.sections = append(c.sections, secs...)
+		c.sectionsByPath[path] = secs
+		c.files[path] = o.files[path]
+	}
+	return c
+}
 
-	return &payramDocsTool{
-		sections:       sections,
-		sectionsByPath: byPath,
-		files:          files,
+// corpus returns the docs in lang, or else in its base language ("pt" for "pt-br"), or else
+// the English docs.
+func (t *payramDocsTool) corpus(lang string) *docsCorpus {
+	if c, ok := t.corpora[lang]; ok {
+		return c
+	}
+	base, _, _ := strings.Cut(lang, "-")
+	if c, ok := t.corpora[base]; ok {
+		return c
 	}
+	return t.corpora[i18n.Default]
 }
 
 // Descriptor describes the tool.
@@ -83,6 +135,10 @@ func (t *payramDocsTool) Descriptor() protocol.ToolDescriptor {
 					Type:        "string",
 					Description: "Heading within the file (optional; if omitted returns whole file)",
 				},
+				"lang": {
+					Type:        "string",
+					Description: "Docs language, e.g. es or pt-br (optional; defaults to the caller's language). Falls back to English when there are no docs in that language, or nothing matches in them",
+				},
 			},
 			Required: []string{"action"},
 		},
@@ -96,22 +152,32 @@ type docsArgs struct {
 	Limit    int    `json:"limit"`
 	Path     string `json:"path"`
 	Heading  string `json:"heading"`
+	Lang     string `json:"lang"`
 }
 
 // Invoke routes search and section fetch.
 func (t *payramDocsTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
-	_ = ctx
 	var args docsArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
+		}
+	}
+	lang := i18n.FromContext(ctx)
+	if strings.TrimSpace(args.Lang) != "" {
+		tag, ok := i18n.Parse(args.Lang)
+		if !ok {
+			return protocol.CallResult{}, protocol.InvalidArgs(fmt.Sprintf("lang must be a language tag such as es or pt-br, got %s", args.Lang))
 		}
+		lang = tag
 	}
+	docs := t.corpus(lang)
+	english := t.corpora[i18n.Default]
 
 	switch args.Action {
 	case "search":
 		if strings.TrimSpace(args.Query) == "" {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "query is required for search"}
+			return protocol.CallResult{}, protocol.InvalidArgs("query is required for search")
 		}
 		limit := args.Limit
 		if limit <= 0 {
@@ -120,25 +186,30 @@ func (t *payramDocsTool) Invoke(ctx context.Context, raw json.RawMessage) (proto
 		if limit > 10 {
 			limit = 10
 		}
-		return t.search(args.Query, args.Category, limit)
+		return search(ctx, []*docsCorpus{docs, english}, args.Query, args.Category, limit)
 	case "get_section":
 		if strings.TrimSpace(args.Path) == "" {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "path is required for get_section"}
+			return protocol.CallResult{}, protocol.InvalidArgs("path is required for get_section")
 		}
-		return t.getSection(args.Path, args.Heading)
+		result, errResp := docs.getSection(args.Path, args.Heading)
+		if errResp != nil && docs != english {
+			return english.getSection(args.Path, args.Heading)
+		}
+		return result, errResp
 	case "list_index":
-		return t.listIndex(), nil
+		return docs.listIndex(), nil
 	default:
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "action must be search or get_section"}
+		return protocol.CallResult{}, protocol.InvalidArgs("action must be search or get_section")
 	}
 }
 
-// search performs a simple keyword match over headings and bodies.
-func (t *payramDocsTool) search(query, category string, limit int) (protocol.CallResult, *protocol.ResponseError) {
-	q := strings.ToLower(strings.TrimSpace(query))
+// search performs a simple keyword match over headings and bodies, in the first of corpora
+// with any match.
+func search(ctx context.Context, corpora []*docsCorpus, query, category string, limit int) (protocol.CallResult, *protocol.ResponseError) {
+	q := strings.ToLower(strings.TrimSpace(glossary.Default().Expand(query)))
 	words := strings.Fields(q)
 	if len(words) == 0 {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "empty query"}
+		return protocol.CallResult{}, protocol.InvalidArgs("empty query")
 	}
 	cat := strings.TrimSpace(strings.ToLower(category))
 
@@ -148,18 +219,23 @@ func (t *payramDocsTool) search(query, category string, limit int) (protocol.Cal
 	}
 
 	hits := make([]hit, 0)
-	for _, sec := range t.sections {
-		if cat != "" && strings.ToLower(sec.Category) != cat {
-			continue
+	for _, c := range corpora {
+		for _, sec := range c.sections {
+			if cat != "" && strings.ToLower(sec.Category) != cat {
+				continue
+			}
+			hScore := scoreSection(sec, words)
+			if hScore > 0 {
+				hits = append(hits, hit{sec: sec, score: hScore})
+			}
 		}
-		hScore := scoreSection(sec, words)
-		if hScore > 0 {
-			hits = append(hits, hit{sec: sec, score: hScore})
+		if len(hits) > 0 {
+			break
 		}
 	}
 
 	if len(hits) == 0 {
-		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: "No results."}}}, nil
+		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: i18n.Sprintf(ctx, "No results.")}}}, nil
 	}
 
 	sort.Slice(hits, func(i, j int) bool {
@@ -181,7 +257,7 @@ func (t *payramDocsTool) search(query, category string, limit int) (protocol.Cal
 		if h.sec.Heading != "" {
 			fmtPath += "#" + h.sec.Heading
 		}
-		b.WriteString(fmt.Sprintf("%d) [%s] (%s)\n", i+1, fmtPath, h.sec.Category))
+		fmt.Fprintf(&b, "%d) [%s] (%s)\n", i+1, fmtPath, h.sec.Category)
 		b.WriteString(excerpt)
 		b.WriteString("\n\n")
 	}
@@ -190,19 +266,19 @@ func (t *payramDocsTool) search(query, category string, limit int) (protocol.Cal
 }
 
 // getSection returns a specific section or whole file.
-func (t *payramDocsTool) getSection(path, heading string) (protocol.CallResult, *protocol.ResponseError) {
+func (c *docsCorpus) getSection(path, heading string) (protocol.CallResult, *protocol.ResponseError) {
 	norm := filepath.ToSlash(strings.TrimSpace(path))
 	norm = strings.TrimPrefix(norm, "./")
 
-	sections, ok := t.sectionsByPath[norm]
+	sections, ok := c.sectionsByPath[norm]
 	if !ok {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "path not found"}
+		return protocol.CallResult{}, protocol.NotFound("path not found")
 	}
 
 	if strings.TrimSpace(heading) == "" {
-		full := strings.TrimSpace(t.files[norm])
+		full := strings.TrimSpace(c.files[norm])
 		if full == "" {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "content not found"}
+			return protocol.CallResult{}, protocol.NotFound("content not found")
 		}
 		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: full}}}, nil
 	}
@@ -215,14 +291,14 @@ func (t *payramDocsTool) getSection(path, heading string) (protocol.CallResult,
 		}
 	}
 
-	return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "heading not found in file"}
+	return protocol.CallResult{}, protocol.NotFound("heading not found in file")
 }
 
 // listIndex returns available categories, topics, and per-file headings (truncated).
-func (t *payramDocsTool) listIndex() protocol.CallResult {
+func (c *docsCorpus) listIndex() protocol.CallResult {
 	cats := make(map[string]struct{})
 	fileHeadings := make(map[string][]string)
-	for _, sec := range t.sections {
+	for _, sec := range c.sections {
 		cats[sec.Category] = struct{}{}
 		hs := fileHeadings[sec.Path]
 		if len(hs) < 8 { // cap to avoid huge payloads
@@ -251,7 +327,7 @@ func (t *payramDocsTool) listIndex() protocol.CallResult {
 	}
 	sort.Strings(keys)
 	for _, p := range keys {
-		b.WriteString(fmt.Sprintf("- %s : %s\n", p, strings.Join(topics[p], ", ")))
+		fmt.Fprintf(&b, "- %s : %s\n", p, strings.Join(topics[p], ", "))
 	}
 
 	b.WriteString("\nHeadings (truncated per file):\n")
@@ -262,7 +338,7 @@ func (t *payramDocsTool) listIndex() protocol.CallResult {
 	sort.Strings(paths)
 	for _, p := range paths {
 		hs := fileHeadings[p]
-		b.WriteString(fmt.Sprintf("- %s: %s\n", p, strings.Join(hs, "; ")))
+		fmt.Fprintf(&b, "- %s: %s\n", p, strings.Join(hs, "; "))
 	}
 
 	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(b.String())}}}
@@ -291,8 +367,9 @@ func scoreSection(sec docSection, words []string) int {
 	return score
 }
 
-// indexDocs walks root, parsing markdown files into sections.
-func indexDocs(root string) ([]docSection, map[string][]docSection, map[string]string) {
+// indexDocs walks root, parsing markdown files into sections. Top-level directories named
+// in skipDirs are left out.
+func indexDocs(root string, skipDirs []string) ([]docSection, map[string][]docSection, map[string]string) {
 	sections := make([]docSection, 0)
 	byPath := make(map[string][]docSection)
 	files := make(map[string]string)
@@ -304,6 +381,9 @@ func indexDocs(root string) ([]docSection, map[string][]docSection, map[string]s
 			return nil
 		}
 		if d.IsDir() {
+			if filepath.Dir(path) == root && slices.Contains(skipDirs, d.Name()) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		if !strings.HasSuffix(strings.ToLower(d.Name()), ".md") {
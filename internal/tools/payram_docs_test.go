@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+// FuzzParseSections checks that parseSections never drops or invents body text: every
+// non-heading line of the input ends up in exactly the section under its heading.
+func FuzzParseSections(f *testing.F) {
+	f.Add("# Title\nintro\n## Setup\nstep one\n\nstep two\n")
+	f.Add("no headings at all")
+	f.Add("#\n##\n###   \n# \t\n")
+	f.Add("#### deep\n\n\n# \xff\xfe\nbody\r\nmore\r\n")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, content string) {
+		sections := parseSections("a/b.md", "a", content)
+
+		var want []string
+		for _, line := range strings.Split(content, "\n") {
+			if parseHeading(strings.TrimSpace(line)) == "" && strings.TrimSpace(line) != "" {
+				want = append(want, strings.TrimSpace(line))
+			}
+		}
+		var got []string
+		for _, s := range sections {
+			if s.Path != "a/b.md" || s.Category != "a" {
+				t.Fatalf("section lost its path or category: %+v", s)
+			}
+			if s.Heading == "" || s.Body == "" || s.Body != strings.TrimSpace(s.Body) {
+				t.Fatalf("section with empty heading or untrimmed body: %+v", s)
+			}
+			for _, line := range strings.Split(s.Body, "\n") {
+				if strings.TrimSpace(line) != "" {
+					got = append(got, strings.TrimSpace(line))
+				}
+			}
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("body lines differ\nwant %q\ngot  %q", want, got)
+		}
+	})
+}
@@ -0,0 +1,469 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// maxExportGraphs caps the graphs one export may fetch.
+const maxExportGraphs = 50
+
+// payramExportTool exports analytics graphs as CSV. Exports over long ranges or many graphs
+// are slow, so the tool asks to run as a background job.
+type payramExportTool struct {
+	client *http.Client
+	clocked
+}
+
+// PayramExport constructs the tool.
+func PayramExport() *payramExportTool {
+	return &payramExportTool{client: newHTTPClient(2 * time.Minute)}
+}
+
+// Background makes the MCP server run exports as jobs when it has a job queue.
+func (t *payramExportTool) Background() bool { return true }
+
+func (t *payramExportTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{
+		Name: "payram_export",
+		Description: `Export analytics graphs as CSV, e.g. a full year of payments or a report covering several groups.
+Runs as a background job: the call returns a job ID, and payram_job_status returns the CSV once the export has finished.
+Pick graphs with payram_discover_analytics. Pass group_ids to export every graph in those groups.`,
+		InputSchema: &protocol.JSONSchema{
+			Type: "object",
+			Properties: map[string]protocol.JSONSchema{
+				"token":    {Type: "string", Description: "Bearer token override; defaults to PAYRAM_ANALYTICS_TOKEN env"},
+				"base_url": {Type: "string", Description: "API base override; required if PAYRAM_ANALYTICS_BASE_URL env is not set"},
+				"graphs": {
+					Type:        "array",
+					Description: "Graphs to export",
+					Items: &protocol.JSONSchema{
+						Type: "object",
+						Properties: map[string]protocol.JSONSchema{
+							"group_id": {Type: "integer"},
+							"graph_id": {Type: "integer"},
+						},
+						Required: []string{"group_id", "graph_id"},
+					},
+				},
+				"group_ids": {
+					Type:        "array",
+					Description: "Export every graph in these groups",
+					Items:       &protocol.JSONSchema{Type: "integer"},
+				},
+				"year": {Type: "integer", Description: "Export a calendar year (UTC), e.g. 2025; overrides date_filter"},
+				"days": {Type: "integer", Description: "Export the last N days; overrides date_filter"},
+				"date_filter": {
+					Type:        "string",
+					Description: "Date filter: today, yesterday, last_7_days, last_30_days, this_month, last_month, last_6_months, forever, custom. Default: last_30_days",
+				},
+				"custom_start_date": {Type: "string", Description: "ISO date/time (RFC3339) start when date_filter=custom"},
+				"custom_end_date":   {Type: "string", Description: "ISO date/time (RFC3339) end when date_filter=custom"},
+				"currency_codes": {
+					Type:        "array",
+					Description: "Optional currency filter: BTC, ETH, TRX, BASE, USDT, USDC, CBBTC",
+					Items:       &protocol.JSONSchema{Type: "string"},
+				},
+			},
+		},
+	}
+}
+
+type exportGraphRef struct {
+	GroupID int `json:"group_id"`
+	GraphID int `json:"graph_id"`
+}
+
+type exportArgs struct {
+	Token          string           `json:"token"`
+	BaseURL        string           `json:"base_url"`
+	Graphs         []exportGraphRef `json:"graphs"`
+	GroupIDs       []int            `json:"group_ids"`
+	Year           int              `json:"year"`
+	Days           int              `json:"days"`
+	DateFilter     string           `json:"date_filter"`
+	CustomStartISO string           `json:"custom_start_date"`
+	CustomEndISO   string           `json:"custom_end_date"`
+	CurrencyCodes  currencyCodes    `json:"currency_codes"`
+}
+
+// exportPlan is an export resolved from its arguments: the graphs to fetch and the payload
+// to fetch them with.
+type exportPlan struct {
+	base, token string
+	period      string
+	refs        []namedGraphRef
+	payload     map[string]any
+}
+
+func (t *payramExportTool) plan(ctx context.Context, raw json.RawMessage) (exportPlan, *protocol.ResponseError) {
+	var args exportArgs
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return exportPlan{}, protocol.InvalidArgs("invalid arguments")
+		}
+	}
+	if len(args.Graphs) == 0 && len(args.GroupIDs) == 0 {
+		return exportPlan{}, protocol.InvalidArgs("graphs or group_ids is required")
+	}
+
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return exportPlan{}, credErr
+	}
+
+	var dateFilter, customStart, customEnd string
+	var errResp *protocol.ResponseError
+	switch {
+	case args.Year > 0:
+		dateFilter = "custom"
+		customStart = time.Date(args.Year, time.January, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+		customEnd = time.Date(args.Year+1, time.January, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	case args.Days > 0:
+		dateFilter = "custom"
+		customStart, customEnd = lastNDaysRange(t.now(), args.Days)
+	default:
+		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(t.now(), args.DateFilter, args.CustomStartISO, args.CustomEndISO)
+	}
+	if errResp != nil {
+		return exportPlan{}, errResp
+	}
+	period := dateFilter
+	if dateFilter == "custom" {
+		period = customStart + " - " + customEnd
+	}
+
+	groups, errResp := t.listGroups(ctx, base, token)
+	if errResp != nil {
+		return exportPlan{}, errResp
+	}
+	refs, errResp := exportRefs(groups, args.Graphs, args.GroupIDs)
+	if errResp != nil {
+		return exportPlan{}, errResp
+	}
+
+	payload := map[string]any{}
+	if dateFilter == "custom" {
+		payload["custom"] = map[string]any{"start_date": customStart, "end_date": customEnd}
+	} else {
+		payload["analytics_date_filter"] = dateFilter
+	}
+	if len(args.CurrencyCodes) > 0 {
+		payload["currency_codes"] = args.CurrencyCodes
+		payload["in_query_currency_filter"] = args.CurrencyCodes
+	}
+	return exportPlan{base: base, token: token, period: period, refs: refs, payload: payload}, nil
+}
+
+func (t *payramExportTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	p, errResp := t.plan(ctx, raw)
+	if errResp != nil {
+		return protocol.CallResult{}, errResp
+	}
+	parts := make([]protocol.ContentPart, 0, len(p.refs))
+	for _, ref := range p.refs {
+		var b strings.Builder
+		if err := t.writeGraph(ctx, &b, p, ref); err != nil {
+			return protocol.CallResult{}, err
+		}
+		parts = append(parts, protocol.ContentPart{Type: "text", Text: b.String()})
+	}
+	return protocol.CallResult{Content: parts}, nil
+}
+
+// Stream writes the export to w one graph at a time, as the parts Invoke returns joined by
+// blank lines, so a large export never sits in memory whole.
+func (t *payramExportTool) Stream(ctx context.Context, raw json.RawMessage, w io.Writer) *protocol.ResponseError {
+	p, errResp := t.plan(ctx, raw)
+	if errResp != nil {
+		return errResp
+	}
+	for i, ref := range p.refs {
+		if err := ctx.Err(); err != nil {
+			return transportError(err)
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n\n"); err != nil {
+				return protocol.Errorf(protocol.CategoryInternal, "write export: %v", err)
+			}
+		}
+		if err := t.writeGraph(ctx, w, p, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeGraph writes one graph of the export: a title comment, then the CSV, or an error
+// comment when the graph could not be fetched. An export of a single graph fails instead.
+func (t *payramExportTool) writeGraph(ctx context.Context, w io.Writer, p exportPlan, ref namedGraphRef) *protocol.ResponseError {
+	title := i18n.Sprintf(ctx, "# %s (group %d, graph %d, %s)\n", ref.name, ref.GroupID, ref.GraphID, p.period)
+	data, pages, err := t.graphData(ctx, p.base, p.token, ref.GroupID, ref.GraphID, p.payload)
+	if err != nil {
+		if len(p.refs) == 1 {
+			return err
+		}
+		if _, werr := io.WriteString(w, title+i18n.Sprintf(ctx, "# error: %s", err.Message)); werr != nil {
+			return protocol.Errorf(protocol.CategoryInternal, "write export: %v", werr)
+		}
+		return nil
+	}
+	if _, werr := io.WriteString(w, title); werr != nil {
+		return protocol.Errorf(protocol.CategoryInternal, "write export: %v", werr)
+	}
+	if convErr := writeGraphCSV(w, data); convErr != nil {
+		return protocol.Errorf(protocol.CategoryInternal, "convert graph %d to CSV: %v", ref.GraphID, convErr)
+	}
+	if note := pageNote(ctx, pages); note != "" {
+		// A comment line, like the title, so the CSV still parses.
+		if _, werr := io.WriteString(w, "\n# "+note); werr != nil {
+			return protocol.Errorf(protocol.CategoryInternal, "write export: %v", werr)
+		}
+	}
+	return nil
+}
+
+type namedGraphRef struct {
+	exportGraphRef
+	name string
+}
+
+// exportRefs resolves the requested graphs and groups against the discovered groups, in the
+// order requested and without duplicates.
+func exportRefs(groups []discoverGroupWrapper, graphs []exportGraphRef, groupIDs []int) ([]namedGraphRef, *protocol.ResponseError) {
+	byGroup := map[int]discoverAnalyticsGroup{}
+	for _, g := range groups {
+		byGroup[g.AnalyticsGroup.ID] = g.AnalyticsGroup
+	}
+	var out []namedGraphRef
+	add := func(group discoverAnalyticsGroup, gr discoverGraph) {
+		ref := exportGraphRef{GroupID: group.ID, GraphID: gr.ID}
+		if !slices.ContainsFunc(out, func(r namedGraphRef) bool { return r.exportGraphRef == ref }) {
+			out = append(out, namedGraphRef{ref, group.Name + " / " + gr.Name})
+		}
+	}
+	for _, ref := range graphs {
+		group, ok := byGroup[ref.GroupID]
+		if !ok {
+			return nil, protocol.NotFound(fmt.Sprintf("analytics group %d not found; list groups with payram_discover_analytics", ref.GroupID))
+		}
+		i := slices.IndexFunc(group.Graphs, func(g discoverGraph) bool { return g.ID == ref.GraphID })
+		if i < 0 {
+			return nil, protocol.NotFound(fmt.Sprintf("graph %d not found in group %d; list graphs with payram_discover_analytics", ref.GraphID, ref.GroupID))
+		}
+		add(group, group.Graphs[i])
+	}
+	for _, id := range groupIDs {
+		group, ok := byGroup[id]
+		if !ok {
+			return nil, protocol.NotFound(fmt.Sprintf("analytics group %d not found; list groups with payram_discover_analytics", id))
+		}
+		for _, gr := range group.Graphs {
+			add(group, gr)
+		}
+	}
+	if len(out) > maxExportGraphs {
+		return nil, protocol.InvalidArgs(fmt.Sprintf("an export may cover at most %d graphs; %d requested", maxExportGraphs, len(out)))
+	}
+	return out, nil
+}
+
+func (t *payramExportTool) listGroups(ctx context.Context, base, token string) ([]discoverGroupWrapper, *protocol.ResponseError) {
+	url := base + "/api/v1/external-platform/all/analytics/groups"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, transportError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, upstreamError(resp)
+	}
+
+	var data []discoverGroupWrapper
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
+	}
+	return data, nil
+}
+
+func (t *payramExportTool) graphData(ctx context.Context, base, token string, groupID, graphID int, payload map[string]any) (json.RawMessage, pageInfo, *protocol.ResponseError) {
+	return fetchGraphPages(ctx, t.client, base, token, groupID, graphID, payload)
+}
+
+// leadingColumns come first in an exported table, in this order; other columns follow
+// alphabetically.
+var leadingColumns = []string{"date", "id", "name", "label", "currency_code", "blockchain_code"}
+
+// writeGraphCSV writes graph data as CSV (see graphRows), without a final newline.
+func writeGraphCSV(w io.Writer, raw json.RawMessage) error {
+	rows, err := graphRows(raw)
+	if err != nil {
+		return err
+	}
+	return csv.NewWriter(&heldNewline{w: w}).WriteAll(rows)
+}
+
+// heldNewline holds back a trailing newline until more text follows it, so that the newline
+// csv.Writer ends every row with is dropped after the last one.
+type heldNewline struct {
+	w    io.Writer
+	held bool
+}
+
+func (h *heldNewline) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if h.held {
+		if _, err := io.WriteString(h.w, "\n"); err != nil {
+			return 0, err
+		}
+		h.held = false
+	}
+	body := p
+	if body[len(body)-1] == '\n' {
+		body, h.held = body[:len(body)-1], true
+	}
+	if _, err := h.w.Write(body); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// graphRows flattens graph data into a table whose first row is the header: an array of
+// objects becomes one row per object, a labels/datasets object one row per label, any other
+// object field/value rows, and a bare value a single cell.
+func graphRows(raw json.RawMessage) ([][]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var data any
+	if err := dec.Decode(&data); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	switch v := data.(type) {
+	case []any:
+		var columns []string
+		for _, item := range v {
+			if obj, ok := item.(map[string]any); ok {
+				for k := range obj {
+					if !slices.Contains(columns, k) {
+						columns = append(columns, k)
+					}
+				}
+			}
+		}
+		sortColumns(columns)
+		if len(columns) == 0 {
+			columns = []string{"value"}
+		}
+		rows = append(rows, columns)
+		for _, item := range v {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				rows = append(rows, []string{csvCell(item)})
+				continue
+			}
+			row := make([]string, len(columns))
+			for i, c := range columns {
+				row[i] = csvCell(obj[c])
+			}
+			rows = append(rows, row)
+		}
+	case map[string]any:
+		if labels, ok := v["labels"].([]any); ok {
+			datasets, _ := v["datasets"].([]any)
+			header := []string{"label"}
+			for i, ds := range datasets {
+				name := fmt.Sprintf("series %d", i+1)
+				if obj, ok := ds.(map[string]any); ok && csvCell(obj["label"]) != "" {
+					name = csvCell(obj["label"])
+				}
+				header = append(header, name)
+			}
+			rows = append(rows, header)
+			for i, l := range labels {
+				row := []string{csvCell(l)}
+				for _, ds := range datasets {
+					cell := ""
+					if obj, ok := ds.(map[string]any); ok {
+						if values, ok := obj["data"].([]any); ok && i < len(values) {
+							cell = csvCell(values[i])
+						}
+					}
+					row = append(row, cell)
+				}
+				rows = append(rows, row)
+			}
+			break
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		rows = append(rows, []string{"field", "value"})
+		for _, k := range keys {
+			rows = append(rows, []string{k, csvCell(v[k])})
+		}
+	default:
+		rows = [][]string{{"value"}, {csvCell(v)}}
+	}
+	return rows, nil
+}
+
+func sortColumns(columns []string) {
+	rank := func(c string) int {
+		if i := slices.Index(leadingColumns, c); i >= 0 {
+			return i
+		}
+		return len(leadingColumns)
+	}
+	sort.Slice(columns, func(a, b int) bool {
+		ra, rb := rank(columns[a]), rank(columns[b])
+		if ra != rb {
+			return ra < rb
+		}
+		return columns[a] < columns[b]
+	})
+}
+
+func csvCell(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case bool:
+		if x {
+			return "true"
+		}
+		return "false"
+	default:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+}
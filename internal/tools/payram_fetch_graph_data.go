@@ -1,15 +1,13 @@
 package tools
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
@@ -17,11 +15,12 @@ import (
 // This is a generic tool that can query any graph discovered via payram_discover_analytics.
 type payramFetchGraphDataTool struct {
 	client *http.Client
+	clocked
 }
 
 // PayramFetchGraphData constructs the tool.
 func PayramFetchGraphData() *payramFetchGraphDataTool {
-	return &payramFetchGraphDataTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramFetchGraphDataTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramFetchGraphDataTool) Descriptor() protocol.ToolDescriptor {
@@ -29,6 +28,8 @@ func (t *payramFetchGraphDataTool) Descriptor() protocol.ToolDescriptor {
 		Name: "payram_fetch_graph_data",
 		Description: `Fetch data from a specific PayRam analytics graph. Use after discovering available graphs with 'payram_discover_analytics'.
 
+Name the graph with graph_name, and group_name when several groups have a graph of that name. Names are matched loosely: case, punctuation and small typos do not matter, and part of a name is enough when it is unique. IDs differ between PayRam environments, so group_id and graph_id are deprecated; they still work, and win over names.
+
 Graph types and their data formats:
 - number_graph: Returns a single numeric value (e.g., total payments count)
 - bar_graph: Returns time-series data with per-day/period breakdown (e.g., daily transaction counts)
@@ -36,22 +37,17 @@ Graph types and their data formats:
 - info_graph: Returns summary info cards
 - table_graph: Returns tabular transaction data
 
-Common graph IDs (may vary by environment):
-- Group 1 (Numbers): Graphs 1-6 for key metrics
-- Group 2 (Transaction Summary): Graph 7 (Payments in USD), Graph 8 (Number of Transactions per day)
-- Group 3 (Deposit Distribution): Graph 9 (Payment distribution pie chart)
-- Group 4 (Paying User Summary): Graphs 10-11 for user analytics
-- Group 5 (Recent Transactions): Graph 12 for transaction table
-
-For per-day transaction counts, use group_id=2, graph_id=8 with appropriate date_filter.`,
+For per-day transaction counts, use graph_name "Number of Transactions" with the date_filter asked for.`,
 		InputSchema: &protocol.JSONSchema{
 			Type: "object",
 			Properties: map[string]protocol.JSONSchema{
-				"token":    {Type: "string", Description: "Bearer token override; defaults to PAYRAM_ANALYTICS_TOKEN env"},
-				"base_url": {Type: "string", Description: "API base override; required if PAYRAM_ANALYTICS_BASE_URL env is not set"},
-				"group_id": {Type: "integer", Description: "Analytics group ID (required). Use payram_discover_analytics to find available groups."},
-				"graph_id": {Type: "integer", Description: "Graph ID within the group (required). Use payram_discover_analytics to find available graphs."},
-				"days":     {Type: "integer", Description: "If set, fetch last N days using a custom date range"},
+				"token":      {Type: "string", Description: "Bearer token override; defaults to PAYRAM_ANALYTICS_TOKEN env"},
+				"base_url":   {Type: "string", Description: "API base override; required if PAYRAM_ANALYTICS_BASE_URL env is not set"},
+				"graph_name": {Type: "string", Description: "Graph name, e.g. \"Payments in USD\". Use payram_discover_analytics to list the graphs."},
+				"group_name": {Type: "string", Description: "Group name, e.g. \"Transaction Summary\"; needed only when graph_name is in several groups."},
+				"group_id":   {Type: "integer", Description: "Deprecated: IDs vary by environment, use group_name. Analytics group ID."},
+				"graph_id":   {Type: "integer", Description: "Deprecated: IDs vary by environment, use graph_name. Graph ID within the group."},
+				"days":       {Type: "integer", Description: "If set, fetch last N days using a custom date range"},
 				"date_filter": {
 					Type:        "string",
 					Description: "Date filter: today, yesterday, last_7_days, last_30_days, this_month, last_month, last_6_months, forever, custom. Default: last_30_days",
@@ -68,59 +64,65 @@ For per-day transaction counts, use group_id=2, graph_id=8 with appropriate date
 					Description: "For distribution graphs: 'currency_code' or 'blockchain_code'",
 				},
 			},
-			Required: []string{"group_id", "graph_id"},
+			Required: []string{},
 		},
 	}
 }
 
 type fetchGraphArgs struct {
-	Token          string   `json:"token"`
-	BaseURL        string   `json:"base_url"`
-	GroupID        int      `json:"group_id"`
-	GraphID        int      `json:"graph_id"`
-	Days           int      `json:"days"`
-	DateFilter     string   `json:"date_filter"`
-	CustomStartISO string   `json:"custom_start_date"`
-	CustomEndISO   string   `json:"custom_end_date"`
-	CurrencyCodes  []string `json:"currency_codes"`
-	GroupBy        string   `json:"group_by"`
+	Token          string        `json:"token"`
+	BaseURL        string        `json:"base_url"`
+	GroupID        int           `json:"group_id"`
+	GraphID        int           `json:"graph_id"`
+	GroupName      string        `json:"group_name"`
+	GraphName      string        `json:"graph_name"`
+	Days           int           `json:"days"`
+	DateFilter     string        `json:"date_filter"`
+	CustomStartISO string        `json:"custom_start_date"`
+	CustomEndISO   string        `json:"custom_end_date"`
+	CurrencyCodes  currencyCodes `json:"currency_codes"`
+	GroupBy        string        `json:"group_by"`
 }
 
 func (t *payramFetchGraphDataTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
 	var args fetchGraphArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
-	if args.GroupID == 0 || args.GraphID == 0 {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "group_id and graph_id are required"}
+	args.GroupName, args.GraphName = strings.TrimSpace(args.GroupName), strings.TrimSpace(args.GraphName)
+	if args.GraphID == 0 && args.GraphName == "" {
+		return protocol.CallResult{}, protocol.InvalidArgs("graph_name is required")
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+
+	// Both IDs are used as given, as before names were accepted; anything else is looked up.
+	var named *graphRef
+	if args.GroupID == 0 || args.GraphID == 0 {
+		groups, errResp := discoverGroups(ctx, t.client, base, token)
+		if errResp != nil {
+			return protocol.CallResult{}, errResp
+		}
+		ref, errResp := resolveGraph(groups, args.GroupID, args.GroupName, args.GraphID, args.GraphName)
+		if errResp != nil {
+			return protocol.CallResult{}, errResp
+		}
+		args.GroupID, args.GraphID, named = ref.GroupID, ref.GraphID, &ref
 	}
 
 	var dateFilter, customStart, customEnd string
 	var errResp *protocol.ResponseError
 	if args.Days > 0 {
 		dateFilter = "custom"
-		customStart, customEnd = lastNDaysRange(args.Days)
+		customStart, customEnd = lastNDaysRange(t.now(), args.Days)
 	} else {
-		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(args.DateFilter, args.CustomStartISO, args.CustomEndISO)
+		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(t.now(), args.DateFilter, args.CustomStartISO, args.CustomEndISO)
 	}
 	if errResp != nil {
 		return protocol.CallResult{}, errResp
@@ -129,14 +131,20 @@ func (t *payramFetchGraphDataTool) Invoke(ctx context.Context, raw json.RawMessa
 	// Build flexible payload
 	payload := t.buildPayload(dateFilter, customStart, customEnd, args.CurrencyCodes, args.GroupBy)
 
-	data, err := t.graphData(ctx, base, token, args.GroupID, args.GraphID, payload)
+	data, pages, err := t.graphData(ctx, base, token, args.GroupID, args.GraphID, payload)
 	if err != nil {
 		return protocol.CallResult{}, err
 	}
 
 	respText := strings.Builder{}
-	respText.WriteString(fmt.Sprintf("Graph Data (group_id=%d, graph_id=%d, date_filter=%s):\n\n", args.GroupID, args.GraphID, dateFilter))
+	if named != nil {
+		respText.WriteString(i18n.Sprintf(ctx, "Graph: %s (group: %s)\n", named.GraphName, named.GroupName))
+	}
+	respText.WriteString(i18n.Sprintf(ctx, "Graph Data (group_id=%d, graph_id=%d, date_filter=%s):\n\n", args.GroupID, args.GraphID, dateFilter))
 	respText.WriteString(data)
+	if note := pageNote(ctx, pages); note != "" {
+		respText.WriteString("\n\n" + note)
+	}
 
 	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(respText.String())}}}, nil
 }
@@ -163,31 +171,10 @@ func (t *payramFetchGraphDataTool) buildPayload(dateFilter, customStart, customE
 	return payload
 }
 
-func (t *payramFetchGraphDataTool) graphData(ctx context.Context, base, token string, groupID, graphID int, payload map[string]any) (string, *protocol.ResponseError) {
-	body, _ := json.Marshal(payload)
-	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := t.client.Do(req)
+func (t *payramFetchGraphDataTool) graphData(ctx context.Context, base, token string, groupID, graphID int, payload map[string]any) (string, pageInfo, *protocol.ResponseError) {
+	raw, info, err := fetchGraphPages(ctx, t.client, base, token, groupID, graphID, payload)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
-	}
-
-	var raw json.RawMessage
-	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", info, err
 	}
-	pretty, _ := json.MarshalIndent(raw, "", "  ")
-	return string(pretty), nil
+	return prettyJSON(raw), info, nil
 }
@@ -6,10 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
@@ -17,22 +19,24 @@ import (
 // Graphs include: Total payments, Payments in last 30 days, Total paying users, etc.
 type payramNumbersSummaryTool struct {
 	client *http.Client
+	clocked
 }
 
 // PayramNumbersSummary constructs the tool.
 func PayramNumbersSummary() *payramNumbersSummaryTool {
-	return &payramNumbersSummaryTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramNumbersSummaryTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramNumbersSummaryTool) Descriptor() protocol.ToolDescriptor {
 	return protocol.ToolDescriptor{
 		Name:        "payram_numbers_summary",
-		Description: "Fetch key numeric metrics (all-time/static 'Numbers' group): total payments, payments in last 30 days, total paying users, users in last 30 days, total users requested, users attempted in last 30 days. Note: this group is static and may ignore date ranges; prefer date-aware tools for ranged queries.",
+		Description: "Fetch key numeric metrics (all-time/static 'Numbers' group): total payments, payments in last 30 days, total paying users, users in last 30 days, total users requested, users attempted in last 30 days. Note: this group is static and may ignore date ranges; prefer date-aware tools for ranged queries. With compare_previous, figures over the last N days are compared with the N days before.",
 		InputSchema: &protocol.JSONSchema{
 			Type: "object",
 			Properties: map[string]protocol.JSONSchema{
-				"token":    {Type: "string", Description: "Bearer token override; defaults to PAYRAM_ANALYTICS_TOKEN env"},
-				"base_url": {Type: "string", Description: "API base override; required if PAYRAM_ANALYTICS_BASE_URL env is not set"},
+				"token":            {Type: "string", Description: "Bearer token override; defaults to PAYRAM_ANALYTICS_TOKEN env"},
+				"base_url":         {Type: "string", Description: "API base override; required if PAYRAM_ANALYTICS_BASE_URL env is not set"},
+				"compare_previous": comparePreviousSchema,
 			},
 			Required: []string{},
 		},
@@ -40,32 +44,25 @@ func (t *payramNumbersSummaryTool) Descriptor() protocol.ToolDescriptor {
 }
 
 type numbersArgs struct {
-	Token   string `json:"token"`
-	BaseURL string `json:"base_url"`
+	Token           string `json:"token"`
+	BaseURL         string `json:"base_url"`
+	ComparePrevious bool   `json:"compare_previous"`
 }
 
+// windowedGraph matches the graphs of the Numbers group that cover a rolling window.
+var windowedGraph = regexp.MustCompile(`(?i)last (\d+) days`)
+
 func (t *payramNumbersSummaryTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
 	var args numbersArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
 	groups, err := t.listGroups(ctx, base, token)
@@ -82,47 +79,79 @@ func (t *payramNumbersSummaryTool) Invoke(ctx context.Context, raw json.RawMessa
 		}
 	}
 	if numbersGroup == nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "Numbers analytics group not found"}
+		return protocol.CallResult{}, protocol.NotFound("Numbers analytics group not found")
 	}
 
 	respText := strings.Builder{}
-	respText.WriteString(fmt.Sprintf("Numbers Summary (group %d):\n\n", numbersGroup.AnalyticsGroup.ID))
+	respText.WriteString(i18n.Sprintf(ctx, "Numbers Summary (group %d):\n\n", numbersGroup.AnalyticsGroup.ID))
 
 	// Fetch data for each graph in this group
+	changes := strings.Builder{}
 	for _, gr := range numbersGroup.AnalyticsGroup.Graphs {
 		data, err := t.graphData(ctx, base, token, numbersGroup.AnalyticsGroup.ID, gr.ID, map[string]any{})
 		if err != nil {
-			respText.WriteString(fmt.Sprintf("- %s: error fetching data\n", gr.Name))
+			respText.WriteString(i18n.Sprintf(ctx, "- %s: error fetching data\n", gr.Name))
 			continue
 		}
-		respText.WriteString(fmt.Sprintf("- %s:\n%s\n\n", gr.Name, data))
+		fmt.Fprintf(&respText, "- %s:\n%s\n\n", gr.Name, data)
+		if args.ComparePrevious {
+			t.compare(ctx, &changes, base, token, numbersGroup.AnalyticsGroup.ID, gr, data)
+		}
+	}
+	if args.ComparePrevious {
+		respText.WriteString(i18n.Sprintf(ctx, "## Compared with the previous period\n\n"))
+		respText.WriteString(changes.String())
 	}
 
 	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(respText.String())}}}, nil
 }
 
+// compare writes the change of a rolling-window graph from the window before it. All-time
+// figures have no previous period.
+func (t *payramNumbersSummaryTool) compare(ctx context.Context, b *strings.Builder, base, token string, groupID int, gr paymentsAnalyticsGraph, current string) {
+	days := 0
+	if m := windowedGraph.FindStringSubmatch(gr.Name); m != nil {
+		days, _ = strconv.Atoi(m[1])
+	}
+	if days <= 0 {
+		b.WriteString(i18n.Sprintf(ctx, "- %s: all-time figure, no previous period\n", gr.Name))
+		return
+	}
+	now := t.now().UTC()
+	start, stop := previousRange(now.AddDate(0, 0, -days), now)
+	previous, err := t.graphData(ctx, base, token, groupID, gr.ID, buildPayload("custom", start, stop, nil, nil))
+	switch {
+	case err != nil:
+		b.WriteString(i18n.Sprintf(ctx, "- %s: error fetching data (%s)\n", gr.Name, err.Message))
+	case previous == current:
+		b.WriteString(i18n.Sprintf(ctx, "- %s: the graph ignores date ranges, so there is no previous period\n", gr.Name))
+	default:
+		writeChange(ctx, b, schemaKey(base, groupID, gr.ID, ""), gr.Name, current, previous)
+	}
+}
+
 func (t *payramNumbersSummaryTool) listGroups(ctx context.Context, base, token string) ([]paymentsGroupWrapper, *protocol.ResponseError) {
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return nil, upstreamError(resp)
 	}
 
 	var data []paymentsGroupWrapper
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return data, nil
 }
@@ -132,7 +161,7 @@ func (t *payramNumbersSummaryTool) graphData(ctx context.Context, base, token st
 	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -140,18 +169,17 @@ func (t *payramNumbersSummaryTool) graphData(ctx context.Context, base, token st
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return "", transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return "", upstreamError(resp)
 	}
 
 	var raw json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
-	pretty, _ := json.MarshalIndent(raw, "", "  ")
-	return string(pretty), nil
+	return prettyJSON(raw), nil
 }
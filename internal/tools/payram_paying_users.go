@@ -6,21 +6,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
 // payramPayingUsersTool fetches paying user analytics: new vs recurring users breakdown.
 type payramPayingUsersTool struct {
 	client *http.Client
+	clocked
 }
 
 // PayramPayingUsers constructs the tool.
 func PayramPayingUsers() *payramPayingUsersTool {
-	return &payramPayingUsersTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramPayingUsersTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramPayingUsersTool) Descriptor() protocol.ToolDescriptor {
@@ -51,46 +52,35 @@ func (t *payramPayingUsersTool) Descriptor() protocol.ToolDescriptor {
 }
 
 type payingUsersArgs struct {
-	Token          string   `json:"token"`
-	BaseURL        string   `json:"base_url"`
-	Days           int      `json:"days"`
-	DateFilter     string   `json:"date_filter"`
-	CustomStartISO string   `json:"custom_start_date"`
-	CustomEndISO   string   `json:"custom_end_date"`
-	CurrencyCodes  []string `json:"currency_codes"`
+	Token          string        `json:"token"`
+	BaseURL        string        `json:"base_url"`
+	Days           int           `json:"days"`
+	DateFilter     string        `json:"date_filter"`
+	CustomStartISO string        `json:"custom_start_date"`
+	CustomEndISO   string        `json:"custom_end_date"`
+	CurrencyCodes  currencyCodes `json:"currency_codes"`
 }
 
 func (t *payramPayingUsersTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
 	var args payingUsersArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
 	var dateFilter, customStart, customEnd string
 	var errResp *protocol.ResponseError
 	if args.Days > 0 {
 		dateFilter = "custom"
-		customStart, customEnd = lastNDaysRange(args.Days)
+		customStart, customEnd = lastNDaysRange(t.now(), args.Days)
 	} else {
-		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(args.DateFilter, args.CustomStartISO, args.CustomEndISO)
+		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(t.now(), args.DateFilter, args.CustomStartISO, args.CustomEndISO)
 	}
 	if errResp != nil {
 		return protocol.CallResult{}, errResp
@@ -111,11 +101,11 @@ func (t *payramPayingUsersTool) Invoke(ctx context.Context, raw json.RawMessage)
 		}
 	}
 	if userGroup == nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "Paying User Summary analytics group not found"}
+		return protocol.CallResult{}, protocol.NotFound("Paying User Summary analytics group not found")
 	}
 
 	respText := strings.Builder{}
-	respText.WriteString(fmt.Sprintf("Paying User Summary (group %d):\n\n", userGroup.AnalyticsGroup.ID))
+	respText.WriteString(i18n.Sprintf(ctx, "Paying User Summary (group %d):\n\n", userGroup.AnalyticsGroup.ID))
 
 	// Build payload with currency filter if supported
 	payload := buildPayingUsersPayload(dateFilter, customStart, customEnd, args.CurrencyCodes, userGroup.AnalyticsGroup.Filters)
@@ -123,10 +113,10 @@ func (t *payramPayingUsersTool) Invoke(ctx context.Context, raw json.RawMessage)
 	for _, gr := range userGroup.AnalyticsGroup.Graphs {
 		data, err := t.graphData(ctx, base, token, userGroup.AnalyticsGroup.ID, gr.ID, payload)
 		if err != nil {
-			respText.WriteString(fmt.Sprintf("- %s: error fetching data\n", gr.Name))
+			respText.WriteString(i18n.Sprintf(ctx, "- %s: error fetching data\n", gr.Name))
 			continue
 		}
-		respText.WriteString(fmt.Sprintf("- %s (%s):\n%s\n\n", gr.Name, gr.Description, data))
+		fmt.Fprintf(&respText, "- %s (%s):\n%s\n\n", gr.Name, gr.Description, data)
 	}
 
 	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(respText.String())}}}, nil
@@ -161,24 +151,24 @@ func (t *payramPayingUsersTool) listGroups(ctx context.Context, base, token stri
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return nil, upstreamError(resp)
 	}
 
 	var data []paymentsGroupWrapper
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return data, nil
 }
@@ -188,7 +178,7 @@ func (t *payramPayingUsersTool) graphData(ctx context.Context, base, token strin
 	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -196,18 +186,17 @@ func (t *payramPayingUsersTool) graphData(ctx context.Context, base, token strin
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return "", transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return "", upstreamError(resp)
 	}
 
 	var raw json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
-	pretty, _ := json.MarshalIndent(raw, "", "  ")
-	return string(pretty), nil
+	return prettyJSON(raw), nil
 }
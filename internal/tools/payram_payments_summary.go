@@ -6,12 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
@@ -19,11 +19,12 @@ import (
 // It first lists analytics groups, locates suitable graphs by name, then fetches graph data.
 type payramPaymentsSummaryTool struct {
 	client *http.Client
+	clocked
 }
 
 // PayramPaymentsSummary constructs the tool.
 func PayramPaymentsSummary() *payramPaymentsSummaryTool {
-	return &payramPaymentsSummaryTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramPaymentsSummaryTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramPaymentsSummaryTool) Descriptor() protocol.ToolDescriptor {
@@ -47,6 +48,7 @@ func (t *payramPaymentsSummaryTool) Descriptor() protocol.ToolDescriptor {
 					Description: "Optional currency codes (e.g., BTC, ETH, USDT) when supported by the graph's filters",
 					Items:       &protocol.JSONSchema{Type: "string"},
 				},
+				"compare_previous": comparePreviousSchema,
 			},
 			Required: []string{},
 		},
@@ -54,51 +56,50 @@ func (t *payramPaymentsSummaryTool) Descriptor() protocol.ToolDescriptor {
 }
 
 type paymentsArgs struct {
-	Token          string   `json:"token"`
-	BaseURL        string   `json:"base_url"`
-	Days           int      `json:"days"`
-	DateFilter     string   `json:"date_filter"`
-	CustomStartISO string   `json:"custom_start_date"`
-	CustomEndISO   string   `json:"custom_end_date"`
-	CurrencyCodes  []string `json:"currency_codes"`
+	Token          string        `json:"token"`
+	BaseURL        string        `json:"base_url"`
+	Days           int           `json:"days"`
+	DateFilter     string        `json:"date_filter"`
+	CustomStartISO string        `json:"custom_start_date"`
+	CustomEndISO   string        `json:"custom_end_date"`
+	CurrencyCodes  currencyCodes `json:"currency_codes"`
+	// ComparePrevious adds the change from the preceding period of equal length.
+	ComparePrevious bool `json:"compare_previous"`
 }
 
 func (t *payramPaymentsSummaryTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
 	var args paymentsArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
 	// Resolve token/base
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
 	var dateFilter, customStart, customEnd string
 	var errResp *protocol.ResponseError
 	if args.Days > 0 {
 		dateFilter = "custom"
-		customStart, customEnd = lastNDaysRange(args.Days)
+		customStart, customEnd = lastNDaysRange(t.now(), args.Days)
 	} else {
-		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(args.DateFilter, args.CustomStartISO, args.CustomEndISO)
+		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(t.now(), args.DateFilter, args.CustomStartISO, args.CustomEndISO)
 	}
 	if errResp != nil {
 		return protocol.CallResult{}, errResp
 	}
+	var prevStart, prevEnd string
+	if args.ComparePrevious {
+		start, end, errResp := periodWindow(t.now(), dateFilter, customStart, customEnd)
+		if errResp != nil {
+			return protocol.CallResult{}, errResp
+		}
+		prevStart, prevEnd = previousRange(start, end)
+	}
 
 	groups, err := t.listGroups(ctx, base, token)
 	if err != nil {
@@ -117,10 +118,23 @@ func (t *payramPaymentsSummaryTool) Invoke(ctx context.Context, raw json.RawMess
 	countSel := pickGraph(groups, countGraphNames())
 
 	if amountSel == nil && countSel == nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "No matching graphs found for payments amount or count"}
+		return protocol.CallResult{}, protocol.NotFound("No matching graphs found for payments amount or count")
 	}
 
 	respText := strings.Builder{}
+	changes := strings.Builder{}
+	compare := func(sel *graphSelection, current string) *protocol.ResponseError {
+		if !args.ComparePrevious {
+			return nil
+		}
+		payload := buildPayload("custom", prevStart, prevEnd, args.CurrencyCodes, sel.filters)
+		previous, err := t.graphData(ctx, base, token, sel.groupID, sel.graphID, payload)
+		if err != nil {
+			return err
+		}
+		writeChange(ctx, &changes, schemaKey(base, sel.groupID, sel.graphID, ""), sel.name, current, previous)
+		return nil
+	}
 
 	if amountSel != nil {
 		payload := buildPayload(dateFilter, customStart, customEnd, args.CurrencyCodes, amountSel.filters)
@@ -128,9 +142,12 @@ func (t *payramPaymentsSummaryTool) Invoke(ctx context.Context, raw json.RawMess
 		if err != nil {
 			return protocol.CallResult{}, err
 		}
-		respText.WriteString(fmt.Sprintf("Amount graph: group %d graph %d (%s)\n", amountSel.groupID, amountSel.graphID, amountSel.name))
+		fmt.Fprintf(&respText, "Amount graph: group %d graph %d (%s)\n", amountSel.groupID, amountSel.graphID, amountSel.name)
 		respText.WriteString(data)
 		respText.WriteString("\n\n")
+		if err := compare(amountSel, data); err != nil {
+			return protocol.CallResult{}, err
+		}
 	}
 
 	if countSel != nil {
@@ -139,11 +156,18 @@ func (t *payramPaymentsSummaryTool) Invoke(ctx context.Context, raw json.RawMess
 		if err != nil {
 			return protocol.CallResult{}, err
 		}
-		respText.WriteString(fmt.Sprintf("Count graph: group %d graph %d (%s)\n", countSel.groupID, countSel.graphID, countSel.name))
+		fmt.Fprintf(&respText, "Count graph: group %d graph %d (%s)\n", countSel.groupID, countSel.graphID, countSel.name)
 		respText.WriteString(data)
+		if err := compare(countSel, data); err != nil {
+			return protocol.CallResult{}, err
+		}
 	} else {
 		respText.WriteString("Count graph not found with known name patterns. Tried: " + strings.Join(countGraphNames(), ", "))
 	}
+	if args.ComparePrevious {
+		respText.WriteString("\n\n" + i18n.Sprintf(ctx, "## Previous period: %s to %s\n\n", prevStart, prevEnd))
+		respText.WriteString(changes.String())
+	}
 
 	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(respText.String())}}}, nil
 }
@@ -153,24 +177,24 @@ func (t *payramPaymentsSummaryTool) listGroups(ctx context.Context, base, token
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return nil, upstreamError(resp)
 	}
 
 	var data []paymentsGroupWrapper
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return data, nil
 }
@@ -181,7 +205,7 @@ func (t *payramPaymentsSummaryTool) graphData(ctx context.Context, base, token s
 	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -189,20 +213,19 @@ func (t *payramPaymentsSummaryTool) graphData(ctx context.Context, base, token s
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return "", transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return "", upstreamError(resp)
 	}
 
 	var raw json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
-	pretty, _ := json.MarshalIndent(raw, "", "  ")
-	return string(pretty), nil
+	return prettyJSON(raw), nil
 }
 
 type paymentsGroupWrapper struct {
@@ -277,9 +300,10 @@ func isAllowedDateFilter(v string) bool {
 	}
 }
 
-// normalizeDateFilter validates or converts free-form ranges (e.g., "last 10 days") to a supported filter.
+// normalizeDateFilter validates or converts free-form ranges (e.g., "last 10 days", counted back
+// from now) to a supported filter.
 // If a custom range is needed and not provided, it computes it in UTC as [now-N days, now+1 day).
-func normalizeDateFilter(raw, customStart, customEnd string) (string, string, string, *protocol.ResponseError) {
+func normalizeDateFilter(now time.Time, raw, customStart, customEnd string) (string, string, string, *protocol.ResponseError) {
 	df := strings.ToLower(strings.TrimSpace(raw))
 	if df == "" {
 		df = "last_30_days"
@@ -289,7 +313,7 @@ func normalizeDateFilter(raw, customStart, customEnd string) (string, string, st
 			s := strings.TrimSpace(customStart)
 			e := strings.TrimSpace(customEnd)
 			if s == "" || e == "" {
-				return "", "", "", &protocol.ResponseError{Code: -32602, Message: "custom_start_date and custom_end_date are required when date_filter=custom"}
+				return "", "", "", protocol.InvalidArgs("custom_start_date and custom_end_date are required when date_filter=custom")
 			}
 			return df, s, e, nil
 		}
@@ -299,21 +323,21 @@ func normalizeDateFilter(raw, customStart, customEnd string) (string, string, st
 	// Try to parse patterns like "last 10 days", "last_10_days", "last-10-days".
 	n := extractDays(df)
 	if n > 0 {
-		now := time.Now().UTC()
+		now = now.UTC()
 		start := now.Add(-time.Duration(n) * 24 * time.Hour).Format(time.RFC3339Nano)
 		end := now.Format(time.RFC3339Nano)
 		return "custom", start, end, nil
 	}
 
-	return "", "", "", &protocol.ResponseError{Code: -32602, Message: fmt.Sprintf("invalid date_filter: %s", raw)}
+	return "", "", "", protocol.InvalidArgs(fmt.Sprintf("invalid date_filter: %s", raw))
 }
 
 // lastNDaysRange returns a UTC RFC3339 range for the last N days: [now-N days, now+1 day).
-func lastNDaysRange(n int) (string, string) {
+func lastNDaysRange(now time.Time, n int) (string, string) {
 	if n <= 0 {
 		n = 1
 	}
-	now := time.Now().UTC()
+	now = now.UTC()
 	start := now.Add(-time.Duration(n) * 24 * time.Hour).Format(time.RFC3339)
 	end := now.Add(24 * time.Hour).Format(time.RFC3339)
 	return start, end
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/clock"
+)
+
+func TestLastNDaysRangeAcrossMidnight(t *testing.T) {
+	// 23:30 on 31 March in UTC-5 is already 1 April in UTC; the range must follow UTC.
+	local := time.FixedZone("UTC-5", -5*60*60)
+	now := time.Date(2025, 3, 31, 23, 30, 0, 0, local)
+
+	start, end := lastNDaysRange(now, 7)
+	if start != "2025-03-25T04:30:00Z" || end != "2025-04-02T04:30:00Z" {
+		t.Fatalf("range = [%s, %s)", start, end)
+	}
+
+	start, _ = lastNDaysRange(now, 0)
+	if start != "2025-03-31T04:30:00Z" {
+		t.Fatalf("non-positive days should mean one day, got start %s", start)
+	}
+}
+
+func TestNormalizeDateFilterRelativeDays(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	df, start, end, errResp := normalizeDateFilter(now, "last 10 days", "", "")
+	if errResp != nil {
+		t.Fatalf("unexpected error: %v", errResp.Message)
+	}
+	if df != "custom" || start != "2024-12-22T00:00:00Z" || end != "2025-01-01T00:00:00Z" {
+		t.Fatalf("got %s [%s, %s]", df, start, end)
+	}
+
+	df, start, end, errResp = normalizeDateFilter(now, "last_7_days", "", "")
+	if errResp != nil || df != "last_7_days" || start != "" || end != "" {
+		t.Fatalf("supported filter should pass through, got %s [%s, %s] %v", df, start, end, errResp)
+	}
+
+	if _, _, _, errResp := normalizeDateFilter(now, "yesterday-ish", "", ""); errResp == nil {
+		t.Fatal("expected an error for an unknown filter")
+	}
+}
+
+func TestToolsUseInjectedClock(t *testing.T) {
+	tool := PayramDailyStats()
+	if got := tool.now(); got.IsZero() {
+		t.Fatal("default clock returned the zero time")
+	}
+	at := time.Date(2025, 6, 30, 23, 59, 0, 0, time.UTC)
+	tool.SetClock(clock.Fixed(at))
+	if got := tool.now(); !got.Equal(at) {
+		t.Fatalf("now = %v, want %v", got, at)
+	}
+}
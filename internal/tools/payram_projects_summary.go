@@ -6,10 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
@@ -17,11 +17,12 @@ import (
 // This group may not be available in all environments (e.g., testnet).
 type payramProjectsSummaryTool struct {
 	client *http.Client
+	clocked
 }
 
 // PayramProjectsSummary constructs the tool.
 func PayramProjectsSummary() *payramProjectsSummaryTool {
-	return &payramProjectsSummaryTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramProjectsSummaryTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramProjectsSummaryTool) Descriptor() protocol.ToolDescriptor {
@@ -59,33 +60,22 @@ func (t *payramProjectsSummaryTool) Invoke(ctx context.Context, raw json.RawMess
 	var args projectsArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
 	var dateFilter, customStart, customEnd string
 	var errResp *protocol.ResponseError
 	if args.Days > 0 {
 		dateFilter = "custom"
-		customStart, customEnd = lastNDaysRange(args.Days)
+		customStart, customEnd = lastNDaysRange(t.now(), args.Days)
 	} else {
-		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(args.DateFilter, args.CustomStartISO, args.CustomEndISO)
+		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(t.now(), args.DateFilter, args.CustomStartISO, args.CustomEndISO)
 	}
 	if errResp != nil {
 		return protocol.CallResult{}, errResp
@@ -106,11 +96,11 @@ func (t *payramProjectsSummaryTool) Invoke(ctx context.Context, raw json.RawMess
 		}
 	}
 	if projGroup == nil {
-		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: "Projects Summary analytics group not found. This group may not be available in the current environment."}}}, nil
+		return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: i18n.Sprintf(ctx, "Projects Summary analytics group not found. This group may not be available in the current environment.")}}}, nil
 	}
 
 	respText := strings.Builder{}
-	respText.WriteString(fmt.Sprintf("Projects Summary (group %d):\n\n", projGroup.AnalyticsGroup.ID))
+	respText.WriteString(i18n.Sprintf(ctx, "Projects Summary (group %d):\n\n", projGroup.AnalyticsGroup.ID))
 
 	// Build payload
 	payload := map[string]any{}
@@ -126,10 +116,10 @@ func (t *payramProjectsSummaryTool) Invoke(ctx context.Context, raw json.RawMess
 	for _, gr := range projGroup.AnalyticsGroup.Graphs {
 		data, err := t.graphData(ctx, base, token, projGroup.AnalyticsGroup.ID, gr.ID, payload)
 		if err != nil {
-			respText.WriteString(fmt.Sprintf("- %s: error fetching data\n", gr.Name))
+			respText.WriteString(i18n.Sprintf(ctx, "- %s: error fetching data\n", gr.Name))
 			continue
 		}
-		respText.WriteString(fmt.Sprintf("- %s:\n%s\n\n", gr.Name, data))
+		fmt.Fprintf(&respText, "- %s:\n%s\n\n", gr.Name, data)
 	}
 
 	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(respText.String())}}}, nil
@@ -139,24 +129,24 @@ func (t *payramProjectsSummaryTool) listGroups(ctx context.Context, base, token
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return nil, upstreamError(resp)
 	}
 
 	var data []paymentsGroupWrapper
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return data, nil
 }
@@ -166,7 +156,7 @@ func (t *payramProjectsSummaryTool) graphData(ctx context.Context, base, token s
 	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -174,18 +164,17 @@ func (t *payramProjectsSummaryTool) graphData(ctx context.Context, base, token s
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return "", transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return "", upstreamError(resp)
 	}
 
 	var raw json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
-	pretty, _ := json.MarshalIndent(raw, "", "  ")
-	return string(pretty), nil
+	return prettyJSON(raw), nil
 }
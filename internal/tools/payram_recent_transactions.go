@@ -1,15 +1,14 @@
 package tools
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
@@ -20,7 +19,7 @@ type payramRecentTransactionsTool struct {
 
 // PayramRecentTransactions constructs the tool.
 func PayramRecentTransactions() *payramRecentTransactionsTool {
-	return &payramRecentTransactionsTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramRecentTransactionsTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramRecentTransactionsTool) Descriptor() protocol.ToolDescriptor {
@@ -45,34 +44,23 @@ func (t *payramRecentTransactionsTool) Descriptor() protocol.ToolDescriptor {
 }
 
 type recentTxArgs struct {
-	Token         string   `json:"token"`
-	BaseURL       string   `json:"base_url"`
-	CurrencyCodes []string `json:"currency_codes"`
-	Limit         int      `json:"limit"`
+	Token         string        `json:"token"`
+	BaseURL       string        `json:"base_url"`
+	CurrencyCodes currencyCodes `json:"currency_codes"`
+	Limit         int           `json:"limit"`
 }
 
 func (t *payramRecentTransactionsTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
 	var args recentTxArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
 	groups, err := t.listGroups(ctx, base, token)
@@ -90,22 +78,25 @@ func (t *payramRecentTransactionsTool) Invoke(ctx context.Context, raw json.RawM
 		}
 	}
 	if txGroup == nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "Recent Transactions analytics group not found"}
+		return protocol.CallResult{}, protocol.NotFound("Recent Transactions analytics group not found")
 	}
 
 	respText := strings.Builder{}
-	respText.WriteString(fmt.Sprintf("Recent Transactions (group %d):\n\n", txGroup.AnalyticsGroup.ID))
+	respText.WriteString(i18n.Sprintf(ctx, "Recent Transactions (group %d):\n\n", txGroup.AnalyticsGroup.ID))
 
 	// Build payload with currency filter if supported
 	payload := buildRecentTxPayload(args.CurrencyCodes, args.Limit, txGroup.AnalyticsGroup.Filters)
 
 	for _, gr := range txGroup.AnalyticsGroup.Graphs {
-		data, err := t.graphData(ctx, base, token, txGroup.AnalyticsGroup.ID, gr.ID, payload)
+		data, pages, err := t.graphData(ctx, base, token, txGroup.AnalyticsGroup.ID, gr.ID, payload)
 		if err != nil {
-			respText.WriteString(fmt.Sprintf("- %s: error fetching data\n", gr.Name))
+			respText.WriteString(i18n.Sprintf(ctx, "- %s: error fetching data\n", gr.Name))
 			continue
 		}
-		respText.WriteString(fmt.Sprintf("- %s:\n%s\n\n", gr.Name, data))
+		if note := pageNote(ctx, pages); note != "" {
+			data += "\n" + note
+		}
+		fmt.Fprintf(&respText, "- %s:\n%s\n\n", gr.Name, data)
 	}
 
 	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(respText.String())}}}, nil
@@ -137,53 +128,32 @@ func (t *payramRecentTransactionsTool) listGroups(ctx context.Context, base, tok
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return nil, upstreamError(resp)
 	}
 
 	var data []paymentsGroupWrapper
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return data, nil
 }
 
-func (t *payramRecentTransactionsTool) graphData(ctx context.Context, base, token string, groupID, graphID int, payload map[string]any) (string, *protocol.ResponseError) {
-	body, _ := json.Marshal(payload)
-	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := t.client.Do(req)
+func (t *payramRecentTransactionsTool) graphData(ctx context.Context, base, token string, groupID, graphID int, payload map[string]any) (string, pageInfo, *protocol.ResponseError) {
+	raw, info, err := fetchGraphPages(ctx, t.client, base, token, groupID, graphID, payload)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
-	}
-
-	var raw json.RawMessage
-	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", info, err
 	}
-	pretty, _ := json.MarshalIndent(raw, "", "  ")
-	return string(pretty), nil
+	return prettyJSON(raw), info, nil
 }
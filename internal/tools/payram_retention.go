@@ -0,0 +1,265 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+)
+
+// payramRetentionTool estimates cohort retention from the paying user series.
+type payramRetentionTool struct {
+	growth *payramUserGrowthTool
+	clocked
+}
+
+// PayramRetention constructs the tool.
+func PayramRetention() *payramRetentionTool {
+	return &payramRetentionTool{growth: PayramUserGrowth()}
+}
+
+func (t *payramRetentionTool) Descriptor() protocol.ToolDescriptor {
+	return protocol.ToolDescriptor{
+		Name: "payram_retention",
+		Description: `Estimate weekly or monthly cohort retention of paying users.
+
+Use cases:
+- How many users who first paid in a week keep paying in later weeks
+- Compare retention of recent cohorts with older ones
+- Answer churn and repeat-customer questions
+
+Returns:
+- New and returning paying users per period
+- A retention matrix: share of each cohort still paying k periods later
+
+PayRam reports paying users as totals per period, not per user, so the matrix is an
+estimate; mention that when presenting it.`,
+		InputSchema: &protocol.JSONSchema{
+			Type: "object",
+			Properties: map[string]protocol.JSONSchema{
+				"token":    {Type: "string", Description: "Bearer token override; defaults to PAYRAM_ANALYTICS_TOKEN env"},
+				"base_url": {Type: "string", Description: "API base override; required if PAYRAM_ANALYTICS_BASE_URL env is not set"},
+				"period": {
+					Type:        "string",
+					Description: "Cohort granularity: week (weeks start on Monday) or month. Default: week",
+					Enum:        []string{"week", "month"},
+				},
+				"periods": {Type: "integer", Description: "Number of cohorts, 2-26 weeks or 2-12 months. Default: 8 weeks or 6 months"},
+				"until": {
+					Type:        "string",
+					Description: "YYYY-MM-DD; cohorts are the complete periods before the one containing this date. Default: today",
+				},
+				"currency_codes": {
+					Type:        "array",
+					Description: "Filter by currencies: BTC, ETH, TRX, BASE, USDT, USDC, CBBTC",
+					Items:       &protocol.JSONSchema{Type: "string"},
+				},
+			},
+			Required: []string{},
+		},
+	}
+}
+
+type retentionArgs struct {
+	Token         string        `json:"token"`
+	BaseURL       string        `json:"base_url"`
+	Period        string        `json:"period"`
+	Periods       int           `json:"periods"`
+	Until         string        `json:"until"`
+	CurrencyCodes currencyCodes `json:"currency_codes"`
+}
+
+func (t *payramRetentionTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
+	var args retentionArgs
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
+		}
+	}
+
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
+	}
+
+	until := t.now().UTC()
+	if args.Until != "" {
+		d, err := time.Parse("2006-01-02", strings.TrimSpace(args.Until))
+		if err != nil {
+			return protocol.CallResult{}, protocol.InvalidArgs("until must be a YYYY-MM-DD date")
+		}
+		until = d
+	}
+	windows, errResp := cohortWindows(until, args.Period, args.Periods)
+	if errResp != nil {
+		return protocol.CallResult{}, errResp
+	}
+
+	groups, err := t.growth.listGroups(ctx, base, token)
+	if err != nil {
+		return protocol.CallResult{}, err
+	}
+	var userGroup *paymentsGroupWrapper
+	for i, g := range groups {
+		if strings.Contains(strings.ToLower(g.AnalyticsGroup.Name), "paying user") {
+			userGroup = &groups[i]
+			break
+		}
+	}
+	if userGroup == nil {
+		return protocol.CallResult{}, protocol.NotFound("Paying User Summary group not found")
+	}
+	var newGraph, returningGraph *paymentsAnalyticsGraph
+	for i, gr := range userGroup.AnalyticsGroup.Graphs {
+		name := strings.ToLower(gr.Name)
+		switch {
+		case newGraph == nil && strings.Contains(name, "new"):
+			newGraph = &userGroup.AnalyticsGroup.Graphs[i]
+		case returningGraph == nil && (strings.Contains(name, "return") || strings.Contains(name, "recurring")):
+			returningGraph = &userGroup.AnalyticsGroup.Graphs[i]
+		}
+	}
+	if newGraph == nil || returningGraph == nil {
+		return protocol.CallResult{}, protocol.NotFound("Paying User Summary has no new and returning paying user graphs")
+	}
+
+	newUsers := make([]float64, len(windows))
+	returning := make([]float64, len(windows))
+	for i, w := range windows {
+		payload := map[string]any{
+			"custom": map[string]any{
+				"start_date": w.start.Format(time.RFC3339),
+				"end_date":   w.end.Format(time.RFC3339),
+			},
+		}
+		if len(args.CurrencyCodes) > 0 {
+			payload["in_query_currency_filter"] = args.CurrencyCodes
+		}
+		for _, s := range []struct {
+			graph *paymentsAnalyticsGraph
+			into  []float64
+		}{{newGraph, newUsers}, {returningGraph, returning}} {
+			data, graphErr := t.growth.graphData(ctx, base, token, userGroup.AnalyticsGroup.ID, s.graph.ID, payload)
+			if graphErr != nil {
+				return protocol.CallResult{}, graphErr
+			}
+			for _, v := range graphTotals(data) {
+				s.into[i] += v
+			}
+		}
+	}
+
+	var b strings.Builder
+	if args.Period == "month" {
+		b.WriteString(i18n.Sprintf(ctx, "# Monthly Cohort Retention (%d cohorts)\n\n", len(windows)))
+	} else {
+		b.WriteString(i18n.Sprintf(ctx, "# Weekly Cohort Retention (%d cohorts)\n\n", len(windows)))
+	}
+	b.WriteString(i18n.Sprintf(ctx, "Estimated: PayRam reports paying users as totals per period, so the returning users of each period are attributed to the earlier cohorts in proportion to their size. Returning users who first paid before the first cohort are attributed too, so early cohorts may read high.\n\n"))
+
+	b.WriteString(i18n.Sprintf(ctx, "## Paying users per period\n"))
+	for i, w := range windows {
+		b.WriteString(i18n.Sprintf(ctx, "- %s: %s new, %s returning\n", w.label, formatFigure(newUsers[i]), formatFigure(returning[i])))
+	}
+
+	b.WriteString("\n")
+	b.WriteString(i18n.Sprintf(ctx, "## Retention matrix\n"))
+	b.WriteString(i18n.Sprintf(ctx, "| Cohort | New users |"))
+	for k := 1; k < len(windows); k++ {
+		fmt.Fprintf(&b, " +%d |", k)
+	}
+	b.WriteString("\n|---|---|" + strings.Repeat("---|", len(windows)-1) + "\n")
+	for c, row := range retentionMatrix(newUsers, returning) {
+		fmt.Fprintf(&b, "| %s | %s |", windows[c].label, formatFigure(newUsers[c]))
+		for k := 1; k < len(windows); k++ {
+			switch {
+			case k >= len(row):
+				b.WriteString(" |")
+			case math.IsNaN(row[k]):
+				b.WriteString(" n/a |")
+			default:
+				fmt.Fprintf(&b, " %s%% |", formatFigure(row[k]*100))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	return protocol.CallResult{Content: []protocol.ContentPart{{Type: "text", Text: strings.TrimSpace(b.String())}}}, nil
+}
+
+// cohortWindow is one cohort period, [start, end).
+type cohortWindow struct {
+	start, end time.Time
+	label      string
+}
+
+// cohortWindows returns the n complete periods before the one containing until, oldest first.
+func cohortWindows(until time.Time, period string, n int) ([]cohortWindow, *protocol.ResponseError) {
+	day := time.Date(until.Year(), until.Month(), until.Day(), 0, 0, 0, 0, time.UTC)
+	var limit int
+	var step func(time.Time, int) time.Time
+	var current time.Time
+	switch period {
+	case "", "week":
+		period, limit = "week", 26
+		current = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		step = func(t time.Time, k int) time.Time { return t.AddDate(0, 0, 7*k) }
+	case "month":
+		limit = 12
+		current = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		step = func(t time.Time, k int) time.Time { return t.AddDate(0, k, 0) }
+	default:
+		return nil, protocol.InvalidArgs(fmt.Sprintf("period must be week or month, got %s", period))
+	}
+	if n == 0 {
+		n = map[string]int{"week": 8, "month": 6}[period]
+	}
+	if n < 2 || n > limit {
+		return nil, protocol.InvalidArgs(fmt.Sprintf("periods must be between 2 and %d for %s cohorts", limit, period))
+	}
+	windows := make([]cohortWindow, n)
+	for i := range windows {
+		start := step(current, i-n)
+		label := start.Format("2006-01-02")
+		if period == "month" {
+			label = start.Format("2006-01")
+		}
+		windows[i] = cohortWindow{start: start, end: step(start, 1), label: label}
+	}
+	return windows, nil
+}
+
+// retentionMatrix estimates, for each cohort c, the share of its newUsers still paying k
+// periods later (row[k], with row[0] = 1). The returning users of a period are split
+// across the earlier cohorts in proportion to their size, at most a whole cohort each.
+// Empty cohorts have NaN shares.
+func retentionMatrix(newUsers, returning []float64) [][]float64 {
+	matrix := make([][]float64, len(newUsers))
+	for c := range newUsers {
+		row := make([]float64, len(newUsers)-c)
+		for k := range row {
+			row[k] = math.NaN()
+		}
+		if newUsers[c] > 0 {
+			row[0] = 1
+		}
+		matrix[c] = row
+	}
+	pool := 0.0
+	for p := range newUsers {
+		if pool > 0 {
+			for c := 0; c < p; c++ {
+				if newUsers[c] > 0 {
+					matrix[c][p-c] = math.Min(1, returning[p]/pool)
+				}
+			}
+		}
+		pool += max(newUsers[p], 0)
+	}
+	return matrix
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRetentionMatrixSplitsReturningUsersByCohortSize(t *testing.T) {
+	// 10 and 30 users join; 20 return in period 1 (all from cohort 0, capped at its 10)
+	// and 20 in period 2, split 5:15 between cohorts of 10 and 30.
+	m := retentionMatrix([]float64{10, 30, 0}, []float64{0, 20, 20})
+	if len(m) != 3 || len(m[0]) != 3 || len(m[1]) != 2 || len(m[2]) != 1 {
+		t.Fatalf("shape = %v", m)
+	}
+	if m[0][0] != 1 || m[0][1] != 1 || m[0][2] != 0.5 {
+		t.Fatalf("cohort 0 = %v", m[0])
+	}
+	if m[1][0] != 1 || m[1][1] != 0.5 {
+		t.Fatalf("cohort 1 = %v", m[1])
+	}
+	if !math.IsNaN(m[2][0]) {
+		t.Fatalf("empty cohort should be n/a, got %v", m[2])
+	}
+}
+
+func TestCohortWindowsAreCompletePeriods(t *testing.T) {
+	// Wednesday 4 February 2026: the last complete week starts Monday 26 January.
+	until := time.Date(2026, 2, 4, 15, 0, 0, 0, time.UTC)
+	weeks, errResp := cohortWindows(until, "", 3)
+	if errResp != nil {
+		t.Fatal(errResp.Message)
+	}
+	if weeks[0].label != "2026-01-12" || weeks[2].label != "2026-01-26" || !weeks[2].end.Equal(time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("weeks = %+v", weeks)
+	}
+
+	months, errResp := cohortWindows(until, "month", 0)
+	if errResp != nil {
+		t.Fatal(errResp.Message)
+	}
+	if len(months) != 6 || months[0].label != "2025-08" || months[5].label != "2026-01" {
+		t.Fatalf("months = %+v", months)
+	}
+
+	if _, errResp := cohortWindows(until, "month", 13); errResp == nil {
+		t.Fatal("expected an error for 13 monthly cohorts")
+	}
+}
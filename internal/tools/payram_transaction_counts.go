@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
@@ -17,11 +19,12 @@ import (
 // Returns daily breakdown of transaction counts and amounts.
 type payramTransactionCountsTool struct {
 	client *http.Client
+	clocked
 }
 
 // PayramTransactionCounts constructs the tool.
 func PayramTransactionCounts() *payramTransactionCountsTool {
-	return &payramTransactionCountsTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramTransactionCountsTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramTransactionCountsTool) Descriptor() protocol.ToolDescriptor {
@@ -52,46 +55,35 @@ func (t *payramTransactionCountsTool) Descriptor() protocol.ToolDescriptor {
 }
 
 type txCountsArgs struct {
-	Token          string   `json:"token"`
-	BaseURL        string   `json:"base_url"`
-	Days           int      `json:"days"`
-	DateFilter     string   `json:"date_filter"`
-	CustomStartISO string   `json:"custom_start_date"`
-	CustomEndISO   string   `json:"custom_end_date"`
-	CurrencyCodes  []string `json:"currency_codes"`
+	Token          string        `json:"token"`
+	BaseURL        string        `json:"base_url"`
+	Days           int           `json:"days"`
+	DateFilter     string        `json:"date_filter"`
+	CustomStartISO string        `json:"custom_start_date"`
+	CustomEndISO   string        `json:"custom_end_date"`
+	CurrencyCodes  currencyCodes `json:"currency_codes"`
 }
 
 func (t *payramTransactionCountsTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
 	var args txCountsArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
 	var dateFilter, customStart, customEnd string
 	var errResp *protocol.ResponseError
 	if args.Days > 0 {
 		dateFilter = "custom"
-		customStart, customEnd = lastNDaysRange(args.Days)
+		customStart, customEnd = lastNDaysRange(t.now(), args.Days)
 	} else {
-		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(args.DateFilter, args.CustomStartISO, args.CustomEndISO)
+		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(t.now(), args.DateFilter, args.CustomStartISO, args.CustomEndISO)
 	}
 	if errResp != nil {
 		return protocol.CallResult{}, errResp
@@ -112,11 +104,11 @@ func (t *payramTransactionCountsTool) Invoke(ctx context.Context, raw json.RawMe
 		}
 	}
 	if txSummaryGroup == nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "Transaction Summary analytics group not found"}
+		return protocol.CallResult{}, protocol.NotFound("Transaction Summary analytics group not found")
 	}
 
 	respText := strings.Builder{}
-	respText.WriteString(fmt.Sprintf("Transaction Counts - Per Day Breakdown (group %d, date_filter: %s):\n\n", txSummaryGroup.AnalyticsGroup.ID, dateFilter))
+	respText.WriteString(i18n.Sprintf(ctx, "Transaction Counts - Per Day Breakdown (group %d, date_filter: %s):\n\n", txSummaryGroup.AnalyticsGroup.ID, dateFilter))
 
 	// Build payload
 	payload := buildPayload(dateFilter, customStart, customEnd, args.CurrencyCodes, txSummaryGroup.AnalyticsGroup.Filters)
@@ -125,12 +117,12 @@ func (t *payramTransactionCountsTool) Invoke(ctx context.Context, raw json.RawMe
 	for _, gr := range txSummaryGroup.AnalyticsGroup.Graphs {
 		data, graphErr := t.graphData(ctx, base, token, txSummaryGroup.AnalyticsGroup.ID, gr.ID, payload)
 		if graphErr != nil {
-			respText.WriteString(fmt.Sprintf("- %s: error fetching data (%s)\n", gr.Name, graphErr.Message))
+			respText.WriteString(i18n.Sprintf(ctx, "- %s: error fetching data (%s)\n", gr.Name, graphErr.Message))
 			continue
 		}
 
 		// Parse and format the bar graph data for better readability
-		formatted := t.formatBarGraphData(gr.Name, data)
+		formatted := t.formatBarGraphData(ctx, schemaKey(base, txSummaryGroup.AnalyticsGroup.ID, gr.ID, ""), gr.Name, data)
 		respText.WriteString(formatted)
 		respText.WriteString("\n")
 	}
@@ -139,9 +131,14 @@ func (t *payramTransactionCountsTool) Invoke(ctx context.Context, raw json.RawMe
 }
 
 // formatBarGraphData parses bar graph JSON and formats it as a readable per-day breakdown
-func (t *payramTransactionCountsTool) formatBarGraphData(graphName, jsonData string) string {
+func (t *payramTransactionCountsTool) formatBarGraphData(ctx context.Context, schema, graphName, jsonData string) string {
 	var result strings.Builder
-	result.WriteString(fmt.Sprintf("## %s\n", graphName))
+	fmt.Fprintf(&result, "## %s\n", graphName)
+	if drift := checkGraphSchema(schema, graphName, jsonData); drift != nil {
+		result.WriteString(drift.warning(ctx) + "\n")
+		result.WriteString(jsonData)
+		return result.String()
+	}
 
 	// Try to parse as array of data points
 	var dataPoints []map[string]interface{}
@@ -152,7 +149,7 @@ func (t *payramTransactionCountsTool) formatBarGraphData(graphName, jsonData str
 	}
 
 	if len(dataPoints) == 0 {
-		result.WriteString("No data available for this period.\n")
+		result.WriteString(i18n.Sprintf(ctx, "No data available for this period.\n"))
 		return result.String()
 	}
 
@@ -170,11 +167,11 @@ func (t *payramTransactionCountsTool) formatBarGraphData(graphName, jsonData str
 		// Build a line for this data point
 		line := fmt.Sprintf("- %s: ", timestamp)
 		parts := []string{}
-		for k, v := range dp {
+		for _, k := range slices.Sorted(maps.Keys(dp)) {
 			if k == "timestamp" || k == "date" || k == "x" {
 				continue
 			}
-			parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+			parts = append(parts, fmt.Sprintf("%s=%v", k, dp[k]))
 		}
 		line += strings.Join(parts, ", ")
 		result.WriteString(line + "\n")
@@ -187,24 +184,24 @@ func (t *payramTransactionCountsTool) listGroups(ctx context.Context, base, toke
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return nil, upstreamError(resp)
 	}
 
 	var data []paymentsGroupWrapper
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return data, nil
 }
@@ -214,7 +211,7 @@ func (t *payramTransactionCountsTool) graphData(ctx context.Context, base, token
 	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -222,17 +219,17 @@ func (t *payramTransactionCountsTool) graphData(ctx context.Context, base, token
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return "", transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return "", upstreamError(resp)
 	}
 
 	var raw json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return string(raw), nil
 }
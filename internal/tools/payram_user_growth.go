@@ -6,21 +6,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
 	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
 )
 
 // payramUserGrowthTool analyzes paying user growth and retention.
 type payramUserGrowthTool struct {
 	client *http.Client
+	clocked
 }
 
 // PayramUserGrowth constructs the tool.
 func PayramUserGrowth() *payramUserGrowthTool {
-	return &payramUserGrowthTool{client: &http.Client{Timeout: 15 * time.Second}}
+	return &payramUserGrowthTool{client: newHTTPClient(15 * time.Second)}
 }
 
 func (t *payramUserGrowthTool) Descriptor() protocol.ToolDescriptor {
@@ -61,44 +62,33 @@ Returns:
 }
 
 type userGrowthArgs struct {
-	Token         string   `json:"token"`
-	BaseURL       string   `json:"base_url"`
-	Days          int      `json:"days"`
-	DateFilter    string   `json:"date_filter"`
-	CurrencyCodes []string `json:"currency_codes"`
+	Token         string        `json:"token"`
+	BaseURL       string        `json:"base_url"`
+	Days          int           `json:"days"`
+	DateFilter    string        `json:"date_filter"`
+	CurrencyCodes currencyCodes `json:"currency_codes"`
 }
 
 func (t *payramUserGrowthTool) Invoke(ctx context.Context, raw json.RawMessage) (protocol.CallResult, *protocol.ResponseError) {
 	var args userGrowthArgs
 	if len(raw) > 0 {
 		if err := json.Unmarshal(raw, &args); err != nil {
-			return protocol.CallResult{}, &protocol.ResponseError{Code: -32602, Message: "invalid arguments"}
+			return protocol.CallResult{}, protocol.InvalidArgs("invalid arguments")
 		}
 	}
 
-	token := strings.TrimSpace(args.Token)
-	if token == "" {
-		token = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_TOKEN"))
-	}
-	if token == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing token: set PAYRAM_ANALYTICS_TOKEN env or pass token"}
-	}
-	base := strings.TrimSpace(args.BaseURL)
-	if base == "" {
-		base = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_BASE_URL"))
-	}
-	base = strings.TrimSuffix(base, "/")
-	if base == "" {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32000, Message: "Missing base_url: set PAYRAM_ANALYTICS_BASE_URL env or pass base_url"}
+	token, base, credErr := resolveCredentials(ctx, args.Token, args.BaseURL)
+	if credErr != nil {
+		return protocol.CallResult{}, credErr
 	}
 
 	var dateFilter, customStart, customEnd string
 	var errResp *protocol.ResponseError
 	if args.Days > 0 {
 		dateFilter = "custom"
-		customStart, customEnd = lastNDaysRange(args.Days)
+		customStart, customEnd = lastNDaysRange(t.now(), args.Days)
 	} else {
-		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(args.DateFilter, "", "")
+		dateFilter, customStart, customEnd, errResp = normalizeDateFilter(t.now(), args.DateFilter, "", "")
 	}
 	if errResp != nil {
 		return protocol.CallResult{}, errResp
@@ -119,11 +109,11 @@ func (t *payramUserGrowthTool) Invoke(ctx context.Context, raw json.RawMessage)
 		}
 	}
 	if userGroup == nil {
-		return protocol.CallResult{}, &protocol.ResponseError{Code: -32004, Message: "Paying User Summary group not found"}
+		return protocol.CallResult{}, protocol.NotFound("Paying User Summary group not found")
 	}
 
 	respText := strings.Builder{}
-	respText.WriteString(fmt.Sprintf("# User Growth Analysis (%s)\n\n", dateFilter))
+	respText.WriteString(i18n.Sprintf(ctx, "# User Growth Analysis (%s)\n\n", dateFilter))
 
 	payload := map[string]any{}
 	if dateFilter == "custom" {
@@ -141,12 +131,12 @@ func (t *payramUserGrowthTool) Invoke(ctx context.Context, raw json.RawMessage)
 	for _, gr := range userGroup.AnalyticsGroup.Graphs {
 		data, graphErr := t.graphData(ctx, base, token, userGroup.AnalyticsGroup.ID, gr.ID, payload)
 		if graphErr != nil {
-			respText.WriteString(fmt.Sprintf("- %s: error (%s)\n", gr.Name, graphErr.Message))
+			fmt.Fprintf(&respText, "- %s: error (%s)\n", gr.Name, graphErr.Message)
 			continue
 		}
-		respText.WriteString(fmt.Sprintf("## %s\n", gr.Name))
+		fmt.Fprintf(&respText, "## %s\n", gr.Name)
 		if gr.Description != "" {
-			respText.WriteString(fmt.Sprintf("*%s*\n\n", gr.Description))
+			fmt.Fprintf(&respText, "*%s*\n\n", gr.Description)
 		}
 		respText.WriteString(data)
 		respText.WriteString("\n\n")
@@ -159,24 +149,24 @@ func (t *payramUserGrowthTool) listGroups(ctx context.Context, base, token strin
 	url := base + "/api/v1/external-platform/all/analytics/groups"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return nil, upstreamError(resp)
 	}
 
 	var data []paymentsGroupWrapper
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return nil, protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
 	return data, nil
 }
@@ -186,7 +176,7 @@ func (t *payramUserGrowthTool) graphData(ctx context.Context, base, token string
 	url := fmt.Sprintf("%s/api/v1/external-platform/all/analytics/groups/%d/graph/%d/data", base, groupID, graphID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("build request: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "build request: %v", err)
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
@@ -194,18 +184,17 @@ func (t *payramUserGrowthTool) graphData(ctx context.Context, base, token string
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("http error: %v", err)}
+		return "", transportError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", &protocol.ResponseError{Code: resp.StatusCode, Message: fmt.Sprintf("unexpected status: %d", resp.StatusCode)}
+		return "", upstreamError(resp)
 	}
 
 	var raw json.RawMessage
 	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		return "", &protocol.ResponseError{Code: -32603, Message: fmt.Sprintf("decode response: %v", err)}
+		return "", protocol.Errorf(protocol.CategoryInternal, "decode response: %v", err)
 	}
-	pretty, _ := json.MarshalIndent(raw, "", "  ")
-	return string(pretty), nil
+	return prettyJSON(raw), nil
 }
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// renderBuffers are the scratch buffers JSON is formatted in. Graph responses run to
+// megabytes, so the buffers are kept between calls instead of being grown again for each.
+var renderBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// maxPooledBuffer keeps the odd huge response from pinning its buffer in the pool.
+const maxPooledBuffer = 4 << 20
+
+func getBuffer() *bytes.Buffer {
+	return renderBuffers.Get().(*bytes.Buffer)
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBuffer {
+		return
+	}
+	b.Reset()
+	renderBuffers.Put(b)
+}
+
+// prettyJSON indents a JSON document with two spaces for tool output. The result is what
+// json.MarshalIndent(json.RawMessage(raw), "", "  ") returns, HTML escaping included, but the
+// only allocation is the returned string. Invalid JSON yields "".
+func prettyJSON(raw []byte) string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	// Indent keeps trailing spaces, which Marshal drops.
+	if err := json.Indent(buf, bytes.TrimRight(raw, " \t\r\n"), "", "  "); err != nil {
+		return ""
+	}
+	return htmlEscaped(buf)
+}
+
+// indentJSON is prettyJSON for a value to be encoded, as json.MarshalIndent(v, "", "  ")
+// formats it.
+func indentJSON(v any) string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	enc := json.NewEncoder(buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return ""
+	}
+	// Encode ends the document with a newline, which MarshalIndent does not.
+	buf.Truncate(buf.Len() - 1)
+	return buf.String()
+}
+
+// htmlEscaped returns buf as a string with <, >, & and the line and paragraph separators in
+// strings escaped, as json.Marshal writes them. Most graph data has none, and goes through
+// unchanged.
+func htmlEscaped(buf *bytes.Buffer) string {
+	b := buf.Bytes()
+	// ContainsAny is only fast for ASCII sets, so the separators are looked for apart.
+	if !bytes.ContainsAny(b, "<>&") && !bytes.Contains(b, []byte("\u2028")) && !bytes.Contains(b, []byte("\u2029")) {
+		return string(b)
+	}
+	esc := getBuffer()
+	defer putBuffer(esc)
+	json.HTMLEscape(esc, b)
+	return esc.String()
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// largeGraph is a graph response of n daily points, the size a year of per-currency data
+// reaches.
+func largeGraph(n int) []byte {
+	var b strings.Builder
+	b.WriteString(`{"data":[`)
+	for i := range n {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"date":"2025-%02d-%02d","currency_code":"USDT","blockchain_code":"TRX","amount":%d.25,"count":%d,"project":"Shop %d"}`, i%12+1, i%28+1, i*37, i%50, i%7)
+	}
+	b.WriteString(`],"meta":{"total":` + fmt.Sprint(n) + `}}`)
+	return []byte(b.String())
+}
+
+func TestPrettyJSONMatchesMarshalIndent(t *testing.T) {
+	for _, raw := range []string{
+		string(largeGraph(20)),
+		`  [{"name": "Tom & Jerry <tools>", "note": "a` + "\u2028" + `b"}, null, 1.50, "x"]  `,
+		`{}`,
+		`"plain"`,
+	} {
+		want, _ := json.MarshalIndent(json.RawMessage(raw), "", "  ")
+		if got := prettyJSON([]byte(raw)); got != string(want) {
+			t.Errorf("prettyJSON(%.40s) differs\n--- want\n%s\n--- got\n%s", raw, want, got)
+		}
+	}
+	if got := prettyJSON([]byte(`{"a":`)); got != "" {
+		t.Errorf("invalid JSON rendered as %q", got)
+	}
+
+	v := map[string]any{"currency_code": "BTC", "amount": 2100.5, "tags": []any{"a&b", nil}}
+	want, _ := json.MarshalIndent(v, "", "  ")
+	if got := indentJSON(v); got != string(want) {
+		t.Errorf("indentJSON differs\n--- want\n%s\n--- got\n%s", want, got)
+	}
+}
+
+// BenchmarkRenderGraph compares rendering a large graph response for tool output with the
+// MarshalIndent call the tools used before; run with -benchmem to see the allocations.
+func BenchmarkRenderGraph(b *testing.B) {
+	raw := json.RawMessage(largeGraph(5000))
+	b.Run("MarshalIndent", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(raw)))
+		for b.Loop() {
+			pretty, _ := json.MarshalIndent(raw, "", "  ")
+			_ = string(pretty)
+		}
+	})
+	b.Run("prettyJSON", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(raw)))
+		for b.Loop() {
+			_ = prettyJSON(raw)
+		}
+	})
+}
+
+// BenchmarkGraphSection measures one "## name" section of a summary tool's output.
+func BenchmarkGraphSection(b *testing.B) {
+	data := prettyJSON(largeGraph(5000))
+	b.Run("WriteString+Sprintf", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var out strings.Builder
+			out.WriteString(fmt.Sprintf("## %s\n%s\n\n", "Payments in USD", data))
+			_ = out.String()
+		}
+	})
+	b.Run("Fprintf", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var out strings.Builder
+			fmt.Fprintf(&out, "## %s\n%s\n\n", "Payments in USD", data)
+			_ = out.String()
+		}
+	})
+}
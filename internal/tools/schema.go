@@ -0,0 +1,323 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/atomicfile"
+	"github.com/payram/payram-analytics-mcp-server/internal/i18n"
+	"github.com/payram/payram-analytics-mcp-server/internal/metrics"
+)
+
+var schemaDrifts = metrics.Default.Counter("analytics_schema_drift_total",
+	"Graph responses whose structure no longer matched the graph's expected model.")
+
+// graphShape is the field structure of a graph response, without its values. Kind is
+// "object", "array", "string", "number", "bool" or "null"; an array's Elem merges the shapes
+// of its items and is nil for an empty array.
+type graphShape struct {
+	Kind   string                 `json:"kind"`
+	Fields map[string]*graphShape `json:"fields,omitempty"`
+	Elem   *graphShape            `json:"elem,omitempty"`
+}
+
+func shapeOf(v any) *graphShape {
+	switch x := v.(type) {
+	case map[string]any:
+		s := &graphShape{Kind: "object", Fields: make(map[string]*graphShape, len(x))}
+		for k, fv := range x {
+			s.Fields[k] = shapeOf(fv)
+		}
+		return s
+	case []any:
+		s := &graphShape{Kind: "array"}
+		for _, item := range x {
+			s.Elem = mergeShapes(s.Elem, shapeOf(item))
+		}
+		return s
+	case string:
+		return &graphShape{Kind: "string"}
+	case float64, json.Number:
+		return &graphShape{Kind: "number"}
+	case bool:
+		return &graphShape{Kind: "bool"}
+	}
+	return &graphShape{Kind: "null"}
+}
+
+// mergeShapes combines the shapes of two items of one array: fields present in either, and
+// null giving way to whatever the other item holds.
+func mergeShapes(a, b *graphShape) *graphShape {
+	switch {
+	case a == nil:
+		return b
+	case b == nil || b.Kind == "null":
+		return a
+	case a.Kind == "null":
+		return b
+	case a.Kind != b.Kind:
+		return a
+	}
+	out := &graphShape{Kind: a.Kind, Elem: mergeShapes(a.Elem, b.Elem)}
+	if a.Kind == "object" {
+		out.Fields = make(map[string]*graphShape, len(a.Fields))
+		for k, f := range a.Fields {
+			out.Fields[k] = f
+		}
+		for k, f := range b.Fields {
+			out.Fields[k] = mergeShapes(out.Fields[k], f)
+		}
+	}
+	return out
+}
+
+// String renders the shape canonically, e.g. [{amount:number,date:string}].
+func (s *graphShape) String() string {
+	if s == nil {
+		return "?"
+	}
+	switch s.Kind {
+	case "object":
+		keys := make([]string, 0, len(s.Fields))
+		for k := range s.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k + ":" + s.Fields[k].String()
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	case "array":
+		if s.Elem == nil {
+			return "[]"
+		}
+		return "[" + s.Elem.String() + "]"
+	}
+	return s.Kind
+}
+
+// fingerprint is a short hash of String, to tell shapes apart in logs.
+func (s *graphShape) fingerprint() string {
+	sum := sha256.Sum256([]byte(s.String()))
+	return hex.EncodeToString(sum[:6])
+}
+
+// shapeChanges lists how observed departs from expected in ways that break parsing: fields
+// that went missing and values of another kind. New fields, nulls and empty arrays are not
+// changes.
+func shapeChanges(expected, observed *graphShape, path string) []string {
+	if expected == nil || observed == nil || expected.Kind == "null" || observed.Kind == "null" {
+		return nil
+	}
+	where := path
+	if where == "" {
+		where = "response"
+	}
+	if expected.Kind != observed.Kind {
+		return []string{where + " is " + observed.Kind + ", was " + expected.Kind}
+	}
+	var changes []string
+	switch expected.Kind {
+	case "object":
+		keys := make([]string, 0, len(expected.Fields))
+		for k := range expected.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			field := k
+			if path != "" {
+				field = path + "." + k
+			}
+			got, ok := observed.Fields[k]
+			if !ok {
+				changes = append(changes, field+" is missing")
+				continue
+			}
+			changes = append(changes, shapeChanges(expected.Fields[k], got, field)...)
+		}
+	case "array":
+		changes = shapeChanges(expected.Elem, observed.Elem, path+"[]")
+	}
+	return changes
+}
+
+// schemaModel is what is known about one graph's responses: the shape it is expected to
+// have, learned from its first response, and the shape last seen.
+type schemaModel struct {
+	Key                 string      `json:"key"`
+	Graph               string      `json:"graph"`
+	Expected            *graphShape `json:"expected"`
+	Fingerprint         string      `json:"fingerprint"`
+	FirstSeen           time.Time   `json:"first_seen"`
+	Observed            *graphShape `json:"observed,omitempty"`
+	ObservedFingerprint string      `json:"observed_fingerprint,omitempty"`
+	LastSeen            time.Time   `json:"last_seen"`
+	Drifts              int         `json:"drifts"`
+	LastDrift           []string    `json:"last_drift,omitempty"`
+	LastDriftAt         time.Time   `json:"last_drift_at,omitzero"`
+}
+
+// schemaRegistry tracks the models of every graph seen. With PAYRAM_ANALYTICS_SCHEMA_FILE
+// set, expected models are kept in that file, so a drift that happens while the server is
+// down is still caught when it comes back.
+type schemaRegistry struct {
+	mu     sync.Mutex
+	loaded bool
+	path   string
+	models map[string]*schemaModel
+}
+
+var graphSchemas = &schemaRegistry{}
+
+func (r *schemaRegistry) load() {
+	if r.loaded {
+		return
+	}
+	r.loaded = true
+	r.models = map[string]*schemaModel{}
+	r.path = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_SCHEMA_FILE"))
+	if r.path == "" {
+		return
+	}
+	raw, err := os.ReadFile(r.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[schema] reading %s: %v", r.path, err)
+		}
+		return
+	}
+	var models []*schemaModel
+	if err := json.Unmarshal(raw, &models); err != nil {
+		log.Printf("[schema] reading %s: %v", r.path, err)
+		return
+	}
+	for _, m := range models {
+		if m.Key != "" && m.Expected != nil {
+			r.models[m.Key] = m
+		}
+	}
+}
+
+// save writes the models to the schema file; called with r.mu held.
+func (r *schemaRegistry) save() {
+	if r.path == "" {
+		return
+	}
+	raw, err := json.MarshalIndent(r.list(), "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(r.path), 0o755)
+	}
+	if err == nil {
+		err = atomicfile.WriteFile(r.path, raw)
+	}
+	if err != nil {
+		log.Printf("[schema] could not save %s: %v", r.path, err)
+	}
+}
+
+func (r *schemaRegistry) list() []schemaModel {
+	out := make([]schemaModel, 0, len(r.models))
+	for _, m := range r.models {
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// observe records the shape of one response of the graph under key and returns how it
+// departs from the graph's expected model, or nil when it does not.
+func (r *schemaRegistry) observe(key, graph string, shape *graphShape) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.load()
+	now := time.Now().UTC()
+	m, ok := r.models[key]
+	if !ok {
+		r.models[key] = &schemaModel{Key: key, Graph: graph, Expected: shape, Fingerprint: shape.fingerprint(), FirstSeen: now, LastSeen: now}
+		r.save()
+		return nil
+	}
+	m.Observed, m.ObservedFingerprint, m.LastSeen = shape, shape.fingerprint(), now
+	if m.Fingerprint != m.ObservedFingerprint && isEmptyShape(m.Expected) && !isEmptyShape(shape) {
+		// Learned from an empty response: the first one with rows is the real model.
+		m.Expected, m.Fingerprint = shape, m.ObservedFingerprint
+		r.save()
+		return nil
+	}
+	changes := shapeChanges(m.Expected, shape, "")
+	if len(changes) > 0 {
+		m.Drifts++
+		m.LastDrift, m.LastDriftAt = changes, now
+	}
+	return changes
+}
+
+// isEmptyShape reports whether a shape says nothing about fields: null, or empty arrays.
+func isEmptyShape(s *graphShape) bool {
+	switch {
+	case s == nil || s.Kind == "null":
+		return true
+	case s.Kind == "array":
+		return isEmptyShape(s.Elem)
+	case s.Kind == "object":
+		for _, f := range s.Fields {
+			if !isEmptyShape(f) {
+				return false
+			}
+		}
+		return len(s.Fields) == 0
+	}
+	return false
+}
+
+// schemaKey names a graph's model on the analytics API at base. Group and graph IDs differ
+// between PayRam instances, so each base URL has its own models. variant is set when a
+// request option changes the fields a graph returns, such as the grouping of a distribution,
+// so each option has its own model.
+func schemaKey(base string, groupID, graphID int, variant string) string {
+	key := fmt.Sprintf("%s %d/%d", base, groupID, graphID)
+	if variant != "" {
+		key += "/" + variant
+	}
+	return key
+}
+
+// schemaDrift is a graph response that no longer has its expected structure.
+type schemaDrift struct {
+	Graph   string
+	Changes []string
+}
+
+// checkGraphSchema compares a graph's response with the expected model under key, logging
+// and counting a drift. Tools that parse the response show it raw instead, with
+// drift.warning, since their parsing would silently go wrong.
+func checkGraphSchema(key, graph, data string) *schemaDrift {
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return nil
+	}
+	shape := shapeOf(v)
+	changes := graphSchemas.observe(key, graph, shape)
+	if len(changes) == 0 {
+		return nil
+	}
+	schemaDrifts.Inc()
+	log.Printf("[schema] %s (%s) drifted from its expected model (now %s): %s", graph, key, shape.fingerprint(), strings.Join(changes, "; "))
+	return &schemaDrift{Graph: graph, Changes: changes}
+}
+
+func (d *schemaDrift) warning(ctx context.Context) string {
+	return i18n.Sprintf(ctx, "Warning: %s no longer has the structure this tool expects (%s), so it is shown unparsed.", d.Graph, strings.Join(d.Changes, "; "))
+}
@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func shapeFrom(t *testing.T, data string) *graphShape {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	return shapeOf(v)
+}
+
+func TestShapeChanges(t *testing.T) {
+	expected := `[{"date":"2026-01-01","count":3,"meta":{"currency":"USDT"}},{"date":"2026-01-02","count":null}]`
+	cases := []struct {
+		name, observed string
+		want           []string
+	}{
+		{"same values", `[{"date":"2026-02-01","count":7,"meta":{"currency":"BTC"}}]`, nil},
+		{"new field and nulls", `[{"date":"2026-02-01","count":7,"meta":null,"amount_usd":1.5}]`, nil},
+		{"no rows", `[]`, nil},
+		{"renamed field", `[{"day":"2026-02-01","count":7,"meta":{"currency":"BTC"}}]`, []string{"[].date is missing"}},
+		{"stringly number", `[{"date":"2026-02-01","count":"7","meta":{"currency":"BTC"}}]`, []string{"[].count is string, was number"}},
+		{"nested change", `[{"date":"2026-02-01","count":7,"meta":{"currency":["BTC"]}}]`, []string{"[].meta.currency is array, was string"}},
+		{"wrapped", `{"data":[{"date":"2026-02-01","count":7}]}`, []string{"response is object, was array"}},
+	}
+	for _, c := range cases {
+		got := shapeChanges(shapeFrom(t, expected), shapeFrom(t, c.observed), "")
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: changes %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSchemaRegistryLearnsAndPersistsModels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	t.Setenv("PAYRAM_ANALYTICS_SCHEMA_FILE", path)
+	prev := graphSchemas
+	defer func() { graphSchemas = prev }()
+	graphSchemas = &schemaRegistry{}
+
+	key := schemaKey("http://analytics.test", 2, 21, "")
+	// An empty first response says nothing, so the first one with rows becomes the model.
+	if d := checkGraphSchema(key, "Number of Transactions", `[]`); d != nil {
+		t.Fatalf("first response drifted: %v", d)
+	}
+	if d := checkGraphSchema(key, "Number of Transactions", `[{"date":"2026-01-01","count":3}]`); d != nil {
+		t.Fatalf("first rows drifted: %v", d)
+	}
+	before := schemaDrifts.Value()
+
+	// A restarted server still holds the model, and catches the change.
+	graphSchemas = &schemaRegistry{}
+	d := checkGraphSchema(key, "Number of Transactions", `[{"timestamp":"2026-01-01","total":3}]`)
+	if d == nil || !slices.Equal(d.Changes, []string{"[].count is missing", "[].date is missing"}) {
+		t.Fatalf("drift not detected: %+v", d)
+	}
+	if schemaDrifts.Value() != before+1 {
+		t.Fatalf("drift not counted")
+	}
+	if d := checkGraphSchema(schemaKey("http://analytics.test", 2, 21, "network"), "Number of Transactions", `{"BTC":1}`); d != nil {
+		t.Fatalf("another variant drifted: %v", d)
+	}
+
+	m := graphSchemas.models[key]
+	if m.Drifts != 1 || m.Expected.String() != "[{count:number,date:string}]" || m.Observed.String() != "[{timestamp:string,total:number}]" {
+		t.Fatalf("unexpected model %+v", m)
+	}
+}
+
+func TestDriftedGraphIsShownRaw(t *testing.T) {
+	t.Setenv("PAYRAM_ANALYTICS_SCHEMA_FILE", "")
+	prev := graphSchemas
+	defer func() { graphSchemas = prev }()
+	graphSchemas = &schemaRegistry{}
+
+	tool := PayramTransactionCounts()
+	key := schemaKey("http://analytics.test", 1, 11, "")
+	out := tool.formatBarGraphData(context.Background(), key, "Payments", `[{"date":"2026-01-01","count":3}]`)
+	if !strings.Contains(out, "- 2026-01-01: count=3") {
+		t.Fatalf("expected model not parsed:\n%s", out)
+	}
+
+	drifted := `[{"day":"2026-01-02","count":{"value":4}}]`
+	out = tool.formatBarGraphData(context.Background(), key, "Payments", drifted)
+	if !strings.Contains(out, "Warning: Payments no longer has the structure this tool expects ([].count is object, was number; [].date is missing)") || !strings.HasSuffix(out, drifted) {
+		t.Fatalf("drifted graph not passed through:\n%s", out)
+	}
+}
+
+func TestSchemaModelsAreKeptPerAnalyticsAPI(t *testing.T) {
+	t.Setenv("PAYRAM_ANALYTICS_SCHEMA_FILE", "")
+	prev := graphSchemas
+	defer func() { graphSchemas = prev }()
+	graphSchemas = &schemaRegistry{}
+
+	// Two PayRam instances number their graphs alike, but return different structures.
+	instance := func(data string) string {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.Method == http.MethodGet {
+				_, _ = io.WriteString(w, `[{"id":1,"analyticsGroup":{"id":1,"name":"Transaction Summary","graphs":[{"id":2,"name":"Number of Transactions"}]}}]`)
+				return
+			}
+			_, _ = io.WriteString(w, data)
+		}))
+		t.Cleanup(srv.Close)
+		return srv.URL
+	}
+	bases := []string{
+		instance(`[{"date":"2026-01-01","count":3}]`),
+		instance(`[{"day":"2026-01-01","total":{"value":3}}]`),
+	}
+
+	before := schemaDrifts.Value()
+	for range 2 {
+		for _, base := range bases {
+			args, _ := json.Marshal(map[string]any{"token": "t", "base_url": base, "days": 7})
+			res, rerr := PayramDailyStats().Invoke(context.Background(), args)
+			if rerr != nil {
+				t.Fatalf("%s: %v", base, rerr)
+			}
+			if text := res.Content[0].Text; strings.Contains(text, "no longer has the structure") {
+				t.Fatalf("%s reported drift:\n%s", base, text)
+			}
+		}
+	}
+	if n := schemaDrifts.Value() - before; n != 0 {
+		t.Fatalf("%v drifts counted", n)
+	}
+	if len(graphSchemas.models) != 2 {
+		t.Fatalf("%d models, want one per instance", len(graphSchemas.models))
+	}
+}
@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSnapshots caps how many daily snapshots are kept per instance and window; the oldest
+// are dropped first.
+const maxSnapshots = 30
+
+// metricSnapshot is the headline figures of one PayRam instance over one date window, as
+// they stood when it was taken.
+type metricSnapshot struct {
+	Key     string             `json:"key"`
+	TakenAt time.Time          `json:"taken_at"`
+	Metrics map[string]float64 `json:"metrics"`
+	// Currencies lists the currencies that had payments in the window.
+	Currencies []string `json:"currencies"`
+}
+
+// snapshotStore keeps the metric snapshots payram_changes compares against, one per day
+// and key. With PAYRAM_ANALYTICS_SNAPSHOT_FILE set they are kept in that file, so the
+// morning's answer can compare with yesterday's snapshot across restarts.
+type snapshotStore struct {
+	mu        sync.Mutex
+	loaded    bool
+	path      string
+	snapshots map[string][]metricSnapshot
+}
+
+var metricSnapshots = &snapshotStore{}
+
+func (s *snapshotStore) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.snapshots = map[string][]metricSnapshot{}
+	s.path = strings.TrimSpace(os.Getenv("PAYRAM_ANALYTICS_SNAPSHOT_FILE"))
+	if s.path == "" {
+		return
+	}
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[snapshots] reading %s: %v", s.path, err)
+		}
+		return
+	}
+	var list []metricSnapshot
+	if err := json.Unmarshal(raw, &list); err != nil {
+		log.Printf("[snapshots] reading %s: %v", s.path, err)
+		return
+	}
+	for _, snap := range list {
+		if snap.Key != "" && !snap.TakenAt.IsZero() {
+			s.snapshots[snap.Key] = append(s.snapshots[snap.Key], snap)
+		}
+	}
+	for _, list := range s.snapshots {
+		sort.Slice(list, func(i, j int) bool { return list[i].TakenAt.Before(list[j].TakenAt) })
+	}
+}
+
+// save writes the snapshots to the snapshot file; called with s.mu held.
+func (s *snapshotStore) save() {
+	if s.path == "" {
+		return
+	}
+	keys := make([]string, 0, len(s.snapshots))
+	for k := range s.snapshots {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var list []metricSnapshot
+	for _, k := range keys {
+		list = append(list, s.snapshots[k]...)
+	}
+	raw, _ := json.MarshalIndent(list, "", "  ")
+	tmp := s.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err == nil {
+		err = os.WriteFile(tmp, raw, 0o644)
+		if err == nil {
+			err = os.Rename(tmp, s.path)
+		}
+		if err == nil {
+			return
+		}
+	}
+	log.Printf("[snapshots] could not save %s", s.path)
+}
+
+// record stores snap and returns the latest snapshot under its key from an earlier UTC
+// day, if any. A snapshot from the same day replaces the one taken earlier that day, so
+// asking again later in the morning still compares with yesterday.
+func (s *snapshotStore) record(snap metricSnapshot) (metricSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	day := snap.TakenAt.UTC().Format(time.DateOnly)
+	var kept []metricSnapshot
+	for _, old := range s.snapshots[snap.Key] {
+		if old.TakenAt.UTC().Format(time.DateOnly) < day {
+			kept = append(kept, old)
+		}
+	}
+	var prev metricSnapshot
+	found := len(kept) > 0
+	if found {
+		prev = kept[len(kept)-1]
+	}
+	kept = append(kept, snap)
+	if len(kept) > maxSnapshots {
+		kept = kept[len(kept)-maxSnapshots:]
+	}
+	s.snapshots[snap.Key] = kept
+	s.save()
+	return prev, found
+}
@@ -1,17 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/payram/payram-analytics-mcp-server/internal/app"
 	"github.com/payram/payram-analytics-mcp-server/internal/chatapi"
+	"github.com/payram/payram-analytics-mcp-server/internal/compress"
+	"github.com/payram/payram-analytics-mcp-server/internal/httplimits"
+	"github.com/payram/payram-analytics-mcp-server/internal/tenant"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,12 +30,17 @@ func main() {
 	openaiKey := flag.String("openai-key", envOr("OPENAI_API_KEY", ""), "OpenAI API key")
 	openaiModel := flag.String("openai-model", envOr("OPENAI_MODEL", "gpt-4o-mini"), "OpenAI model")
 	openaiBase := flag.String("openai-base", envOr("OPENAI_BASE_URL", "https://api.openai.com/v1"), "OpenAI base URL")
+	modelPolicy := flag.String("model-policy", envOr("CHAT_API_MODEL_POLICY", ""), "JSON file with per-model parameter rules")
 	disableChat := flag.Bool("no-chat", false, "Disable chat API server")
 	flag.Parse()
 
 	if !*disableChat && strings.TrimSpace(*openaiKey) == "" {
 		log.Fatalf("OPENAI_API_KEY is required unless --no-chat is set")
 	}
+	policy, err := chatapi.LoadModelPolicy(*modelPolicy)
+	if err != nil {
+		log.Fatalf("model policy: %v", err)
+	}
 
 	// Launch MCP HTTP server
 	mcpErrCh := make(chan error, 1)
@@ -48,14 +58,190 @@ func main() {
 			logger := logrus.New().WithField("component", "chat-api")
 			mcpURL := envOr("MCP_SERVER_URL", fmt.Sprintf("http://localhost%s/", strings.TrimPrefix(*mcpAddr, "")))
 			h := chatapi.NewHandler(logger, *chatAPIKey, *openaiKey, *openaiModel, *openaiBase, mcpURL)
+			h.SetModelPolicy(policy)
+			fallbacks, err := chatapi.LoadLLMFallbacks(envOr("CHAT_API_LLM_FALLBACKS", ""))
+			if err != nil {
+				chatErrCh <- fmt.Errorf("llm fallbacks: %w", err)
+				return
+			}
+			h.SetLLMFallbacks(fallbacks)
+			llmProbe, err := time.ParseDuration(envOr("CHAT_API_LLM_PROBE_INTERVAL", "30s"))
+			if err != nil {
+				chatErrCh <- fmt.Errorf("invalid CHAT_API_LLM_PROBE_INTERVAL: %w", err)
+				return
+			}
+			if llmProbe > 0 {
+				go h.ProbeLLM(context.Background(), llmProbe)
+			}
+			caps, err := chatapi.LoadOutputLimits(envOr("CHAT_API_TOOL_OUTPUT_LIMITS", ""))
+			if err != nil {
+				chatErrCh <- fmt.Errorf("tool output limits: %w", err)
+				return
+			}
+			h.SetOutputLimits(caps)
+			h.SetMCPTenantKey(envOr("MCP_TENANT_KEY", ""))
+			if err := h.SetLanguage(envOr("CHAT_API_LANGUAGE", "")); err != nil {
+				chatErrCh <- fmt.Errorf("CHAT_API_LANGUAGE: %w", err)
+				return
+			}
+			mod, err := chatapi.LoadModeration(envOr("CHAT_API_MODERATION", ""))
+			if err != nil {
+				chatErrCh <- fmt.Errorf("moderation: %w", err)
+				return
+			}
+			h.SetModeration(mod)
+			experiment, err := chatapi.LoadPromptExperiment(envOr("CHAT_API_PROMPT_EXPERIMENT", ""))
+			if err != nil {
+				chatErrCh <- fmt.Errorf("prompt experiment: %w", err)
+				return
+			}
+			h.SetPromptExperiment(experiment)
+			brand, err := chatapi.LoadBranding(envOr("CHAT_API_BRANDING", ""))
+			if err != nil {
+				chatErrCh <- fmt.Errorf("branding: %w", err)
+				return
+			}
+			h.SetBranding(brand)
+			liveInterval, err := time.ParseDuration(envOr("CHAT_API_LIVE_INTERVAL", "15s"))
+			if err != nil {
+				chatErrCh <- fmt.Errorf("invalid CHAT_API_LIVE_INTERVAL: %w", err)
+				return
+			}
+			h.SetLiveInterval(liveInterval)
+			fastPath, err := strconv.ParseBool(envOr("CHAT_API_FAST_PATH", "false"))
+			if err != nil {
+				chatErrCh <- fmt.Errorf("invalid CHAT_API_FAST_PATH: %w", err)
+				return
+			}
+			h.SetFastPath(fastPath)
+			saved, err := chatapi.OpenSavedQueryStore(envOr("CHAT_API_SAVED_QUERIES", ""))
+			if err != nil {
+				chatErrCh <- fmt.Errorf("saved queries: %w", err)
+				return
+			}
+			h.SetSavedQueries(saved)
+			feedback, err := chatapi.OpenFeedbackStore(envOr("CHAT_API_FEEDBACK_FILE", ""))
+			if err != nil {
+				chatErrCh <- fmt.Errorf("feedback: %w", err)
+				return
+			}
+			h.SetFeedback(feedback)
+			llmQuota, err := strconv.Atoi(envOr("CHAT_API_DAILY_LLM_CALLS", "0"))
+			if err != nil || llmQuota < 0 {
+				chatErrCh <- fmt.Errorf("invalid CHAT_API_DAILY_LLM_CALLS")
+				return
+			}
+			usage, err := tenant.OpenUsage(envOr("CHAT_API_USAGE_FILE", ""))
+			if err != nil {
+				chatErrCh <- err
+				return
+			}
+			var tenants *tenant.Registry
+			if file := envOr("CHAT_API_TENANTS", ""); file != "" {
+				if tenants, err = tenant.Load(file); err != nil {
+					chatErrCh <- fmt.Errorf("tenants: %w", err)
+					return
+				}
+			}
+			h.SetUsage(usage, tenants, llmQuota)
+			if dir := envOr("CHAT_API_RECORD_DIR", ""); dir != "" {
+				keep, err := strconv.Atoi(envOr("CHAT_API_RECORD_MAX", "200"))
+				if err != nil || keep <= 0 {
+					chatErrCh <- fmt.Errorf("invalid CHAT_API_RECORD_MAX")
+					return
+				}
+				rec, err := chatapi.OpenRecorder(dir, keep)
+				if err != nil {
+					chatErrCh <- fmt.Errorf("recorder: %w", err)
+					return
+				}
+				h.SetRecorder(rec)
+			}
+			if file := envOr("CHAT_API_USERS_FILE", ""); file != "" {
+				ttl, err := time.ParseDuration(envOr("CHAT_API_SESSION_TTL", "12h"))
+				if err != nil {
+					chatErrCh <- fmt.Errorf("session ttl: %w", err)
+					return
+				}
+				users, err := chatapi.LoadUsers(file, ttl)
+				if err != nil {
+					chatErrCh <- fmt.Errorf("users: %w", err)
+					return
+				}
+				h.SetUsers(users)
+			}
+			if issuer := envOr("CHAT_API_OIDC_ISSUER", ""); issuer != "" {
+				cfg := chatapi.OIDCConfig{
+					Issuer:        issuer,
+					Audience:      envOr("CHAT_API_OIDC_AUDIENCE", ""),
+					UsernameClaim: envOr("CHAT_API_OIDC_USERNAME_CLAIM", ""),
+					ScopesClaim:   envOr("CHAT_API_OIDC_SCOPES_CLAIM", ""),
+				}
+				if file := envOr("CHAT_API_OIDC_TOOL_SCOPES", ""); file != "" {
+					if cfg.ToolScopes, err = chatapi.LoadToolScopes(file); err != nil {
+						chatErrCh <- fmt.Errorf("oidc tool scopes: %w", err)
+						return
+					}
+				}
+				verifier, err := chatapi.NewOIDCVerifier(cfg)
+				if err != nil {
+					chatErrCh <- fmt.Errorf("oidc: %w", err)
+					return
+				}
+				h.SetOIDC(verifier)
+			}
+			if model := envOr("CHAT_API_SUMMARY_MODEL", ""); model != "" {
+				store, err := chatapi.OpenSummaryStore(envOr("CHAT_API_SUMMARY_STORE", ""))
+				if err != nil {
+					chatErrCh <- fmt.Errorf("summary store: %w", err)
+					return
+				}
+				h.SetSummarizer(model, store)
+			}
+			if v := envOr("CHAT_API_CONVERSATION_BUDGET_USD", ""); v != "" {
+				limit, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					chatErrCh <- fmt.Errorf("invalid CHAT_API_CONVERSATION_BUDGET_USD: %w", err)
+					return
+				}
+				costs, err := chatapi.OpenCostTracker(envOr("CHAT_API_BUDGET_FILE", ""), limit)
+				if err != nil {
+					chatErrCh <- fmt.Errorf("conversation budget: %w", err)
+					return
+				}
+				h.SetConversationBudget(costs)
+			}
+			if model := envOr("CHAT_API_TOOL_ROUTER_MODEL", ""); model != "" {
+				topK, err := strconv.Atoi(envOr("CHAT_API_TOOL_ROUTER_TOP_K", "5"))
+				if err != nil {
+					chatErrCh <- fmt.Errorf("invalid CHAT_API_TOOL_ROUTER_TOP_K: %w", err)
+					return
+				}
+				router, err := chatapi.NewToolRouter(model, topK)
+				if err != nil {
+					chatErrCh <- fmt.Errorf("tool router: %w", err)
+					return
+				}
+				h.SetToolRouter(router)
+			}
 			mux := http.NewServeMux()
 			h.Register(mux)
 
+			limits, err := httplimits.FromEnv("CHAT_API", httplimits.Default)
+			if err != nil {
+				chatErrCh <- fmt.Errorf("server limits: %w", err)
+				return
+			}
+			compression, err := compress.FromEnv("CHAT_API", compress.Default)
+			if err != nil {
+				chatErrCh <- fmt.Errorf("compression: %w", err)
+				return
+			}
 			srv := &http.Server{
-				Addr:              ":" + strings.TrimPrefix(*chatPort, ":"),
-				Handler:           mux,
-				ReadHeaderTimeout: 5 * time.Second,
+				Addr:    ":" + strings.TrimPrefix(*chatPort, ":"),
+				Handler: compression.Handler(mux),
 			}
+			limits.Apply(srv)
 			logger.Infof("Chat API listening on :%s (model=%s mcp=%s)", strings.TrimPrefix(*chatPort, ":"), *openaiModel, mcpURL)
 			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 				chatErrCh <- fmt.Errorf("chat api: %w", err)
@@ -0,0 +1,308 @@
+// Package chatclient is a Go client for the PayRam chat API (cmd/chat-api), for backends
+// that embed the analytics assistant:
+//
+//	c := chatclient.New("https://chat.example.com", os.Getenv("CHAT_API_KEY"))
+//	resp, err := c.CreateChatCompletion(ctx, chatclient.ChatCompletionRequest{
+//		Messages: []chatclient.Message{{Role: "user", Content: "How were payments yesterday?"}},
+//	})
+//
+// Requests that fail with a network error, a rate limit or an unavailable upstream are
+// retried with exponential backoff; see Client.MaxRetries. A deadline on ctx is sent as the
+// request's time budget (X-Request-Deadline-Ms), so the server stops working on it in time.
+package chatclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls one chat API server. Set the exported fields before the first call.
+type Client struct {
+	BaseURL string
+	// APIKey is the server's CHAT_API_KEY, sent as X-MCP-Key.
+	APIKey string
+	// Token is sent as a bearer token: an ID token from the server's OIDC issuer, or a
+	// PayRam analytics token for the tools to use.
+	Token string
+	// TenantID and TenantKey select a tenant on a multi-tenant server.
+	TenantID  string
+	TenantKey string
+	// Language asks for answers in a language such as "pt-BR".
+	Language string
+	HTTP     *http.Client
+
+	// MaxRetries is how often a request is retried after a network error, a 429 rate limit
+	// or a 502, 503 or 504. A used-up daily quota or time budget is not retried. New sets 2.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the jittered exponential delay between attempts; a
+	// Retry-After header takes precedence. New sets 500ms and 8s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// New returns a Client for the server at baseURL.
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		APIKey:     apiKey,
+		MaxRetries: 2,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 8 * time.Second,
+	}
+}
+
+// APIError is an error answer from the server.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Code       string
+	Message    string
+	Param      string
+	// RetryAfter is the server's Retry-After, when it sent one.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("chat API: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("chat API: %d %s", e.StatusCode, e.Message)
+}
+
+// CreateChatCompletion asks the assistant to answer req.
+func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/v1/chat/completions", req, req.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode chat completion: %w", err)
+	}
+	return &out, nil
+}
+
+// CreateChatCompletionStream is CreateChatCompletion with the answer delivered as a
+// stream of chunks. Close the stream when done.
+func (c *Client) CreateChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (*Stream, error) {
+	body := struct {
+		ChatCompletionRequest
+		Stream bool `json:"stream"`
+	}{req, true}
+	resp, err := c.do(ctx, http.MethodPost, "/v1/chat/completions", body, req.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		resp.Body.Close()
+		return nil, fmt.Errorf("chat API answered %q instead of an event stream", ct)
+	}
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 0, 64*1024), 8<<20)
+	return &Stream{body: resp.Body, scanner: sc}, nil
+}
+
+// CreateStructuredAnswer is CreateChatCompletion with the answer constrained to a
+// normalized metric, value, period and currency breakdown, returned parsed.
+func (c *Client) CreateStructuredAnswer(ctx context.Context, req ChatCompletionRequest) (*StructuredResponse, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/v1/chat/structured", req, req.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out StructuredResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode structured answer: %w", err)
+	}
+	return &out, nil
+}
+
+// GetUsage reports the caller's LLM calls and quota for today. It fails with a 404 APIError
+// when the server does not track usage.
+func (c *Client) GetUsage(ctx context.Context) (*Usage, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/v1/usage", nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out Usage
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode usage: %w", err)
+	}
+	return &out, nil
+}
+
+// Stream reads the chunks of a streamed answer.
+type Stream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// Recv returns the next chunk, or io.EOF once the server has sent the last one.
+func (s *Stream) Recv() (*ChatCompletionChunk, error) {
+	for s.scanner.Scan() {
+		data, ok := strings.CutPrefix(s.scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return nil, io.EOF
+		}
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("decode chunk: %w", err)
+		}
+		return &chunk, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// Close releases the connection.
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
+
+// do sends the request in conversation, if any, retrying as MaxRetries allows, and returns
+// a 2xx response.
+func (c *Client) do(ctx context.Context, method, path string, body any, conversation string) (*http.Response, error) {
+	var raw []byte
+	if body != nil {
+		var err error
+		if raw, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		c.setHeaders(req, body != nil, conversation)
+		resp, err := client.Do(req)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			return resp, nil
+		}
+		if err == nil {
+			err = readAPIError(resp)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt >= c.MaxRetries || !retryable(err) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.backoff(attempt, err)):
+		}
+	}
+}
+
+func (c *Client) setHeaders(req *http.Request, hasBody bool, conversation string) {
+	if hasBody {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	headers := map[string]string{
+		"X-MCP-Key":         c.APIKey,
+		"X-Tenant-ID":       c.TenantID,
+		"X-Tenant-Key":      c.TenantKey,
+		"X-Language":        c.Language,
+		"X-Conversation-ID": conversation,
+	}
+	if c.Token != "" {
+		headers["Authorization"] = "Bearer " + c.Token
+	}
+	if d, ok := req.Context().Deadline(); ok {
+		headers["X-Request-Deadline-Ms"] = strconv.FormatInt(max(time.Until(d).Milliseconds(), 1), 10)
+	}
+	for k, v := range headers {
+		if v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+}
+
+// readAPIError consumes an error response.
+func readAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var payload struct {
+		Error struct {
+			Message string  `json:"message"`
+			Type    string  `json:"type"`
+			Param   *string `json:"param"`
+			Code    *string `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &payload) == nil && payload.Error.Message != "" {
+		apiErr.Message, apiErr.Type = payload.Error.Message, payload.Error.Type
+		if payload.Error.Code != nil {
+			apiErr.Code = *payload.Error.Code
+		}
+		if payload.Error.Param != nil {
+			apiErr.Param = *payload.Error.Param
+		}
+	} else {
+		apiErr.Message = strings.TrimSpace(string(raw))
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		apiErr.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return apiErr
+}
+
+// retryable reports whether a failed attempt may succeed when repeated: transport errors,
+// rate limits other than a used-up quota, and unavailable upstreams.
+func retryable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests:
+		return apiErr.Code != "quota_exceeded"
+	case http.StatusGatewayTimeout:
+		return apiErr.Code != "deadline_exceeded"
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// backoff is the delay before retrying after attempt: Retry-After when the server sent one,
+// else MinBackoff doubled per attempt, capped at MaxBackoff, with up to half of it jittered.
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+	d := c.MinBackoff << attempt
+	if d > c.MaxBackoff || d <= 0 {
+		d = c.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
@@ -0,0 +1,165 @@
+package chatclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c := New(srv.URL+"/", "key")
+	c.MinBackoff, c.MaxBackoff = time.Millisecond, 5*time.Millisecond
+	return c
+}
+
+func TestCreateChatCompletionRetriesUnavailableUpstream(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("X-MCP-Key") != "key" || r.Header.Get("X-Language") != "pt-BR" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req["include_tool_trace"] != true {
+			t.Errorf("unexpected body %v (%v)", req, err)
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":{"message":"openai error","type":"api_error","param":null,"code":"upstream_unavailable"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"c1","object":"chat.completion","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"42 payments"},"finish_reason":"stop"}],"tool_trace":[{"name":"payram_daily_stats","arguments":{},"duration_ms":3}]}`))
+	})
+	c.Language = "pt-BR"
+
+	resp, err := c.CreateChatCompletion(t.Context(), ChatCompletionRequest{Messages: []Message{{Role: "user", Content: "hi"}}, IncludeToolTrace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 || resp.Text() != "42 payments" || len(resp.ToolTrace) != 1 {
+		t.Fatalf("unexpected result after %d calls: %+v", calls.Load(), resp)
+	}
+
+	c.MaxRetries = 1
+	calls.Store(0)
+	_, err = c.CreateChatCompletion(t.Context(), ChatCompletionRequest{IncludeToolTrace: true})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway || apiErr.Code != "upstream_unavailable" || calls.Load() != 2 {
+		t.Fatalf("expected a 502 after 2 calls, got %v after %d", err, calls.Load())
+	}
+}
+
+func TestQuotaErrorsAreNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"message":"quota used up","type":"rate_limit_error","param":null,"code":"quota_exceeded"}}`))
+	})
+	_, err := c.GetUsage(t.Context())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "quota_exceeded" || calls.Load() != 1 {
+		t.Fatalf("expected one quota error, got %v after %d calls", err, calls.Load())
+	}
+	if retryable(&APIError{StatusCode: http.StatusTooManyRequests, Code: "rate_limit_exceeded"}) != true {
+		t.Fatal("rate limits should be retried")
+	}
+	if d := c.backoff(0, &APIError{RetryAfter: 3 * time.Second}); d != 3*time.Second {
+		t.Fatalf("Retry-After should win, got %s", d)
+	}
+}
+
+func TestDeadlineIsSentAndNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		ms, err := strconv.Atoi(r.Header.Get("X-Request-Deadline-Ms"))
+		if err != nil || ms <= 0 || ms > 5000 {
+			t.Errorf("deadline header = %q", r.Header.Get("X-Request-Deadline-Ms"))
+		}
+		w.WriteHeader(http.StatusGatewayTimeout)
+		w.Write([]byte(`{"error":{"message":"the request deadline passed before an answer was ready","type":"api_error","code":"deadline_exceeded"}}`))
+	})
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	_, err := c.CreateChatCompletion(ctx, ChatCompletionRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "deadline_exceeded" {
+		t.Fatalf("err = %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("deadline_exceeded was retried: %d calls", n)
+	}
+}
+
+func TestCreateChatCompletionStream(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["stream"] != true {
+			t.Errorf("stream not requested: %v", req)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"id":"c1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant"},"finish_reason":null}]}`,
+			`{"id":"c1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"content":"line one\n"},"finish_reason":null}]}`,
+			`{"id":"c1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"content":"line two"},"finish_reason":null}]}`,
+			`{"id":"c1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"total_tokens":9}}`,
+		} {
+			io.WriteString(w, "data: "+ev+"\n\n")
+		}
+		io.WriteString(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := c.CreateChatCompletionStream(t.Context(), ChatCompletionRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var text strings.Builder
+	var last *ChatCompletionChunk
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		text.WriteString(chunk.Choices[0].Delta.Content)
+		last = chunk
+	}
+	if text.String() != "line one\nline two" || last == nil || *last.Choices[0].FinishReason != "stop" || last.Usage["total_tokens"] != float64(9) {
+		t.Fatalf("unexpected stream: %q %+v", text.String(), last)
+	}
+}
+
+func TestCreateStructuredAnswer(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/structured" || r.Header.Get("X-Conversation-ID") != "conv-1" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		w.Write([]byte(`{"id":"c1","object":"chat.structured","model":"m","finish_reason":"stop","answer":{"metric":"total payments","value":120.5,"unit":"USD","period":{"label":"last 7 days","start":"2026-10-07","end":"2026-10-13"},"currency_breakdown":[{"currency":"USDT","value":100},{"currency":"ETH","value":20.5}],"summary":"120.5 USD"},"budget":{"conversation":"conv-1","spent_usd":0.02,"limit_usd":0.5,"exceeded":false}}`))
+	})
+	resp, err := c.CreateStructuredAnswer(t.Context(), ChatCompletionRequest{Messages: []Message{{Role: "user", Content: "payments last week"}}, ConversationID: "conv-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := resp.Answer
+	if a == nil || *a.Value != 120.5 || *a.Period.Start != "2026-10-07" || len(a.CurrencyBreakdown) != 2 || a.CurrencyBreakdown[1].Currency != "ETH" {
+		t.Fatalf("unexpected answer: %+v", a)
+	}
+	if resp.Budget == nil || resp.Budget.Conversation != "conv-1" || resp.Budget.Exceeded {
+		t.Fatalf("unexpected budget: %+v", resp.Budget)
+	}
+}
@@ -0,0 +1,190 @@
+package chatclient
+
+import "time"
+
+// Message is one conversation turn.
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// ToolCall is a tool the model asked to call; Arguments is JSON.
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// ChatCompletionRequest asks the assistant to answer Messages. Model defaults to the
+// server's model; the sampling parameters are checked against the server's model policy.
+type ChatCompletionRequest struct {
+	Model               string    `json:"model,omitempty"`
+	Messages            []Message `json:"messages"`
+	Temperature         *float64  `json:"temperature,omitempty"`
+	TopP                *float64  `json:"top_p,omitempty"`
+	MaxTokens           *int      `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int      `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string    `json:"reasoning_effort,omitempty"`
+	// IncludeToolTrace attaches the tools the assistant called to the response.
+	IncludeToolTrace bool `json:"include_tool_trace,omitempty"`
+	// ConversationID is sent as X-Conversation-ID, so the server's conversation budget
+	// counts the request against it; see Budget.Conversation.
+	ConversationID string `json:"-"`
+}
+
+// ChatCompletionResponse is the assistant's answer.
+type ChatCompletionResponse struct {
+	ID         string         `json:"id"`
+	Object     string         `json:"object"`
+	Model      string         `json:"model"`
+	Choices    []Choice       `json:"choices"`
+	Usage      map[string]any `json:"usage,omitempty"`
+	ToolTrace  []ToolTrace    `json:"tool_trace,omitempty"`
+	Charts     []Chart        `json:"charts,omitempty"`
+	Provenance []Provenance   `json:"provenance,omitempty"`
+	Budget     *Budget        `json:"budget,omitempty"`
+}
+
+// Text is the content of the first choice, or "" when there is none.
+func (r *ChatCompletionResponse) Text() string {
+	if len(r.Choices) == 0 {
+		return ""
+	}
+	return r.Choices[0].Message.Content
+}
+
+// Choice is one candidate answer.
+type Choice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+// ChatCompletionChunk is one event of a streamed answer. The last one carries the finish
+// reason, usage, tool trace, charts and provenance.
+type ChatCompletionChunk struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Role    string `json:"role,omitempty"`
+			Content string `json:"content,omitempty"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage      map[string]any `json:"usage,omitempty"`
+	ToolTrace  []ToolTrace    `json:"tool_trace,omitempty"`
+	Charts     []Chart        `json:"charts,omitempty"`
+	Provenance []Provenance   `json:"provenance,omitempty"`
+	Budget     *Budget        `json:"budget,omitempty"`
+}
+
+// StructuredResponse is a structured answer. Answer is nil when the reply was withheld by
+// the server's content filter or conversation budget, or the model refused; Refusal then
+// says why.
+type StructuredResponse struct {
+	ID           string            `json:"id"`
+	Object       string            `json:"object"`
+	Model        string            `json:"model"`
+	Answer       *StructuredAnswer `json:"answer"`
+	FinishReason string            `json:"finish_reason"`
+	Refusal      string            `json:"refusal,omitempty"`
+	Usage        map[string]any    `json:"usage,omitempty"`
+	ToolTrace    []ToolTrace       `json:"tool_trace,omitempty"`
+	Charts       []Chart           `json:"charts,omitempty"`
+	Provenance   []Provenance      `json:"provenance,omitempty"`
+	Budget       *Budget           `json:"budget,omitempty"`
+}
+
+// StructuredAnswer is a normalized analytics answer. Value is nil when the question has no
+// single number; Period.Start and Period.End are YYYY-MM-DD or nil.
+type StructuredAnswer struct {
+	Metric string   `json:"metric"`
+	Value  *float64 `json:"value"`
+	Unit   string   `json:"unit"`
+	Period struct {
+		Label string  `json:"label"`
+		Start *string `json:"start"`
+		End   *string `json:"end"`
+	} `json:"period"`
+	CurrencyBreakdown []struct {
+		Currency string  `json:"currency"`
+		Value    float64 `json:"value"`
+	} `json:"currency_breakdown"`
+	Summary string `json:"summary"`
+}
+
+// Budget is a conversation's estimated LLM spend, sent by servers with a conversation
+// budget. Once Exceeded, answers are a notice with finish reason "budget_exceeded".
+type Budget struct {
+	Conversation string  `json:"conversation"`
+	SpentUSD     float64 `json:"spent_usd"`
+	LimitUSD     float64 `json:"limit_usd"`
+	Exceeded     bool    `json:"exceeded"`
+}
+
+// ToolTrace describes one tool call made while answering.
+type ToolTrace struct {
+	Name       string         `json:"name"`
+	Arguments  map[string]any `json:"arguments"`
+	DurationMS int64          `json:"duration_ms"`
+	Output     string         `json:"output,omitempty"`
+	Truncated  bool           `json:"truncated,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Provenance says where the figures of one tool call in an answer came from. Send the
+// message "/provenance" to get the previous answer's provenance as text.
+type Provenance struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+	Sources   []Source       `json:"sources"`
+}
+
+// Source is one analytics graph a tool read. DateFilter is "custom" when StartDate and
+// EndDate are set.
+type Source struct {
+	GroupID    int            `json:"group_id"`
+	GraphID    int            `json:"graph_id"`
+	DateFilter string         `json:"date_filter,omitempty"`
+	StartDate  string         `json:"start_date,omitempty"`
+	EndDate    string         `json:"end_date,omitempty"`
+	Filters    map[string]any `json:"filters,omitempty"`
+	Pages      int            `json:"pages"`
+	FetchedAt  time.Time      `json:"fetched_at"`
+}
+
+// Chart is plottable data returned by a tool: Kind is "timeseries", "pie" or "bar", and
+// every series has one value per label.
+type Chart struct {
+	Kind   string   `json:"kind"`
+	Title  string   `json:"title,omitempty"`
+	Labels []string `json:"labels"`
+	Series []struct {
+		Name   string    `json:"name"`
+		Values []float64 `json:"values"`
+	} `json:"series"`
+}
+
+// Usage is the caller's usage for today, keyed by kind ("llm_calls").
+type Usage struct {
+	Object  string             `json:"object"`
+	Subject string             `json:"subject"`
+	Usage   map[string]Counter `json:"usage"`
+}
+
+// Counter is one kind of usage. Limit is 0 when unlimited.
+type Counter struct {
+	Used     int       `json:"used"`
+	Limit    int       `json:"limit,omitempty"`
+	Day      string    `json:"day"`
+	ResetsAt time.Time `json:"resets_at"`
+}
@@ -0,0 +1,157 @@
+// Package mcpserver embeds the PayRam MCP dispatcher in another Go program. A Server holds a
+// set of tools, which can be the PayRam analytics tools (PayramTools), your own, or both,
+// and serves MCP JSON-RPC over HTTP (ServeHTTP) or stdio (ServeStdio).
+//
+//	srv := mcpserver.New(mcpserver.PayramTools()...)
+//	srv.RegisterTool(myTool)
+//	http.Handle("/mcp", srv)
+//
+// The PayRam tools read PAYRAM_ANALYTICS_TOKEN and PAYRAM_ANALYTICS_BASE_URL unless a call
+// passes token and base_url. The types below are the wire types the PayRam server uses;
+// they are aliases, so values move between this package and a Tool without conversion.
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/payram/payram-analytics-mcp-server/internal/app"
+	"github.com/payram/payram-analytics-mcp-server/internal/mcp"
+	"github.com/payram/payram-analytics-mcp-server/internal/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// Protocol types.
+type (
+	// Request is a JSON-RPC 2.0 request.
+	Request = protocol.Request
+	// Response is a JSON-RPC 2.0 response.
+	Response = protocol.Response
+	// ResponseError is a JSON-RPC error; tools return it for failed calls.
+	ResponseError = protocol.ResponseError
+	// ToolDescriptor describes a tool in tools/list.
+	ToolDescriptor = protocol.ToolDescriptor
+	// JSONSchema describes a tool's arguments.
+	JSONSchema = protocol.JSONSchema
+	// CallResult is the output of a successful tool call.
+	CallResult = protocol.CallResult
+	// ContentPart is one piece of a CallResult.
+	ContentPart = protocol.ContentPart
+	// CallMeta is out-of-band data of a CallResult, such as its Charts.
+	CallMeta = protocol.CallMeta
+	// ChartData is plottable series data carried in a CallResult's Meta.
+	ChartData = protocol.ChartData
+	// ChartSeries is one series of a ChartData.
+	ChartSeries = protocol.ChartSeries
+	// ErrorCategory classifies a tool failure; see NewError.
+	ErrorCategory = protocol.ErrorCategory
+)
+
+// Error categories, each with its own JSON-RPC code.
+const (
+	CategoryAuth                = protocol.CategoryAuth
+	CategoryUpstreamUnavailable = protocol.CategoryUpstreamUnavailable
+	CategoryNotFound            = protocol.CategoryNotFound
+	CategoryInvalidArgs         = protocol.CategoryInvalidArgs
+	CategoryQuotaExceeded       = protocol.CategoryQuotaExceeded
+	CategoryReadOnly            = protocol.CategoryReadOnly
+	CategoryInternal            = protocol.CategoryInternal
+)
+
+// Tool is a single MCP tool. Invoke receives the raw tools/call arguments.
+type Tool = mcp.Tool
+
+// NewError builds a tool error with the category's code and the category in its data.
+func NewError(c ErrorCategory, message string) *ResponseError {
+	return protocol.NewError(c, message)
+}
+
+// TextResult is a CallResult with a single text part.
+func TextResult(text string) CallResult {
+	return CallResult{Content: []ContentPart{{Type: "text", Text: text}}}
+}
+
+// NewTool builds a Tool from its descriptor and a function that runs it.
+func NewTool(desc ToolDescriptor, invoke func(ctx context.Context, args json.RawMessage) (CallResult, *ResponseError)) Tool {
+	return funcTool{desc: desc, invoke: invoke}
+}
+
+type funcTool struct {
+	desc   ToolDescriptor
+	invoke func(ctx context.Context, args json.RawMessage) (CallResult, *ResponseError)
+}
+
+func (t funcTool) Descriptor() ToolDescriptor { return t.desc }
+
+func (t funcTool) Invoke(ctx context.Context, args json.RawMessage) (CallResult, *ResponseError) {
+	return t.invoke(ctx, args)
+}
+
+// PayramTools returns a fresh instance of every PayRam analytics tool.
+func PayramTools() []Tool {
+	return app.Tools()
+}
+
+// Server dispatches MCP requests to its tools. Register tools and apply settings before
+// serving; once serving, a Server is safe for concurrent use.
+type Server struct {
+	toolbox *mcp.Toolbox
+	inner   *mcp.Server
+	logger  *logrus.Entry
+}
+
+// New returns a Server with the given tools and no logging.
+func New(tools ...Tool) *Server {
+	tb := mcp.NewToolbox(tools...)
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	return &Server{toolbox: tb, inner: mcp.NewServer(tb), logger: logrus.NewEntry(quiet)}
+}
+
+// RegisterTool adds t, replacing any tool with the same name.
+func (s *Server) RegisterTool(t Tool) {
+	s.toolbox.Register(t)
+}
+
+// SetServerInfo sets the name and version reported by initialize.
+func (s *Server) SetServerInfo(name, version string) {
+	s.inner.SetInfo(name, version)
+}
+
+// SetMaskPII masks customer emails, wallet addresses and transaction hashes in tool output
+// and errors, leaving their last four characters.
+func (s *Server) SetMaskPII(on bool) {
+	s.inner.SetMaskPII(on)
+}
+
+// SetReadOnly hides tools whose Mutating method returns true and refuses calls to them.
+func (s *Server) SetReadOnly(on bool) {
+	s.inner.SetReadOnly(on)
+}
+
+// SetLogger logs each HTTP request to logger.
+func (s *Server) SetLogger(logger *logrus.Entry) {
+	s.logger = logger
+}
+
+// Handle dispatches one request, for callers with their own transport.
+func (s *Server) Handle(ctx context.Context, req Request) Response {
+	resp, err := s.inner.Handle(ctx, req)
+	if err != nil {
+		return mcp.WriteError(req.ID, -32603, "internal error", err)
+	}
+	return resp
+}
+
+// ServeHTTP serves one JSON-RPC request per POST, on whatever path the Server is mounted at.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mcp.NewRPCHandler(s.inner, s.logger).ServeHTTP(w, r)
+}
+
+// ServeStdio runs an MCP stdio session: one JSON-RPC request per line from in, one response
+// per line to out, until in ends or ctx is done. Notifications get no response.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	return s.inner.ServeStdio(ctx, in, out)
+}
@@ -0,0 +1,99 @@
+package mcpserver_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/payram/payram-analytics-mcp-server/pkg/mcpserver"
+)
+
+func greetTool() mcpserver.Tool {
+	return mcpserver.NewTool(mcpserver.ToolDescriptor{
+		Name:        "greet",
+		Description: "Greets someone",
+		InputSchema: &mcpserver.JSONSchema{Type: "object", Properties: map[string]mcpserver.JSONSchema{"name": {Type: "string"}}, Required: []string{"name"}},
+	}, func(_ context.Context, raw json.RawMessage) (mcpserver.CallResult, *mcpserver.ResponseError) {
+		var args struct{ Name string }
+		if err := json.Unmarshal(raw, &args); err != nil || args.Name == "" {
+			return mcpserver.CallResult{}, mcpserver.NewError(mcpserver.CategoryInvalidArgs, "name required")
+		}
+		return mcpserver.TextResult("hello " + args.Name), nil
+	})
+}
+
+func TestServeHTTPDispatchesRegisteredTools(t *testing.T) {
+	srv := mcpserver.New(mcpserver.PayramTools()...)
+	srv.RegisterTool(greetTool())
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", srv)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	post := func(body string) mcpserver.Response {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/mcp", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out mcpserver.Response
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	list := post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	raw, _ := json.Marshal(list.Result)
+	if !strings.Contains(string(raw), `"name":"greet"`) || !strings.Contains(string(raw), `"name":"payram_intro"`) {
+		t.Fatalf("tools/list missing tools: %s", raw)
+	}
+
+	call := post(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"greet","arguments":{"name":"Ada"}}}`)
+	raw, _ = json.Marshal(call.Result)
+	if call.Error != nil || !strings.Contains(string(raw), "hello Ada") {
+		t.Fatalf("unexpected call response: %+v %s", call.Error, raw)
+	}
+
+	bad := post(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"greet","arguments":{}}}`)
+	if bad.Error == nil || bad.Error.Code != -32602 {
+		t.Fatalf("expected invalid args error, got %+v", bad)
+	}
+}
+
+func TestServeStdio(t *testing.T) {
+	srv := mcpserver.New(greetTool())
+	srv.SetServerInfo("embedded", "1.2.3")
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		``,
+		`not json`,
+		`{"jsonrpc":"2.0","id":"b","method":"tools/call","params":{"name":"greet","arguments":{"name":"Bo"}}}`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := srv.ServeStdio(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 responses, got %d:\n%s", len(lines), out.String())
+	}
+	if !strings.Contains(lines[0], `"serverInfo":{"name":"embedded","version":"1.2.3"}`) {
+		t.Fatalf("unexpected initialize response: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"code":-32700`) {
+		t.Fatalf("expected parse error, got %s", lines[1])
+	}
+	if !strings.Contains(lines[2], `"id":"b"`) || !strings.Contains(lines[2], "hello Bo") {
+		t.Fatalf("unexpected call response: %s", lines[2])
+	}
+}
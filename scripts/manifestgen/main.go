@@ -26,10 +26,18 @@ type Options struct {
 	ChatSHA    string
 	MCPURL     string
 	MCPSHA     string
-	CoreMin    string
-	CoreMax    string
-	OutputDir  string
-	PrivKeyB64 string
+	// ChatImage/MCPImage are container images for the agent's docker child driver.
+	ChatImage string
+	MCPImage  string
+	CoreMin   string
+	CoreMax   string
+	AgentMin  string
+	AgentMax  string
+	// ChatRequires/MCPRequires hold per-component constraints keyed by agent, chat or mcp.
+	ChatRequires map[string]update.Range
+	MCPRequires  map[string]update.Range
+	OutputDir    string
+	PrivKeyB64   string
 }
 
 func main() {
@@ -62,8 +70,15 @@ func parseFlags() (*Options, error) {
 		chatSHA    = flag.String("chat_sha", "", "chat artifact sha256 (hex)")
 		mcpURL     = flag.String("mcp_url", "", "mcp artifact URL")
 		mcpSHA     = flag.String("mcp_sha", "", "mcp artifact sha256 (hex)")
+		chatImage  = flag.String("chat_image", "", "chat container image, pinned by digest (name@sha256:...)")
+		mcpImage   = flag.String("mcp_image", "", "mcp container image, pinned by digest (name@sha256:...)")
 		coreMin    = flag.String("core_min", "", "payram-core minimum version")
 		coreMax    = flag.String("core_max", "", "payram-core maximum version")
+		agentMin   = flag.String("agent_min", "", "minimum agent version able to install the release")
+		agentMax   = flag.String("agent_max", "", "maximum agent version able to install the release")
+		chatAgent  = flag.String("chat_min_agent", "", "minimum agent version required by the chat component")
+		mcpAgent   = flag.String("mcp_min_agent", "", "minimum agent version required by the mcp component")
+		chatMCP    = flag.String("chat_min_mcp", "", "minimum mcp version required by the chat component")
 		name       = flag.String("name", "payram-analytics", "manifest name")
 		outDir     = flag.String("output_dir", ".", "output directory for manifest files")
 		privB64    = flag.String("privkey_b64", "", "ed25519 private key (base64, 64 bytes)")
@@ -74,11 +89,11 @@ func parseFlags() (*Options, error) {
 	if *version == "" {
 		return nil, errors.New("version is required")
 	}
-	if *chatURL == "" || *chatSHA == "" {
-		return nil, errors.New("chat_url and chat_sha are required")
+	if (*chatURL == "" || *chatSHA == "") && *chatImage == "" {
+		return nil, errors.New("chat_url and chat_sha (or chat_image) are required")
 	}
-	if *mcpURL == "" || *mcpSHA == "" {
-		return nil, errors.New("mcp_url and mcp_sha are required")
+	if (*mcpURL == "" || *mcpSHA == "") && *mcpImage == "" {
+		return nil, errors.New("mcp_url and mcp_sha (or mcp_image) are required")
 	}
 
 	ts := *releasedAt
@@ -99,19 +114,25 @@ func parseFlags() (*Options, error) {
 	}
 
 	return &Options{
-		Name:       *name,
-		Channel:    *channel,
-		Version:    trimVersionPrefix(*version),
-		Notes:      *notes,
-		ReleasedAt: parsed,
-		ChatURL:    *chatURL,
-		ChatSHA:    strings.ToLower(*chatSHA),
-		MCPURL:     *mcpURL,
-		MCPSHA:     strings.ToLower(*mcpSHA),
-		CoreMin:    *coreMin,
-		CoreMax:    *coreMax,
-		OutputDir:  *outDir,
-		PrivKeyB64: priv,
+		Name:         *name,
+		Channel:      *channel,
+		Version:      trimVersionPrefix(*version),
+		Notes:        *notes,
+		ReleasedAt:   parsed,
+		ChatURL:      *chatURL,
+		ChatSHA:      strings.ToLower(*chatSHA),
+		MCPURL:       *mcpURL,
+		MCPSHA:       strings.ToLower(*mcpSHA),
+		ChatImage:    *chatImage,
+		MCPImage:     *mcpImage,
+		CoreMin:      *coreMin,
+		CoreMax:      *coreMax,
+		AgentMin:     *agentMin,
+		AgentMax:     *agentMax,
+		ChatRequires: requires(map[string]string{"agent": *chatAgent, "mcp": *chatMCP}),
+		MCPRequires:  requires(map[string]string{"agent": *mcpAgent}),
+		OutputDir:    *outDir,
+		PrivKeyB64:   priv,
 	}, nil
 }
 
@@ -140,11 +161,14 @@ func Generate(opts Options) ([]byte, []byte, string, error) {
 		ReleasedAt: opts.ReleasedAt.UTC(),
 		Notes:      opts.Notes,
 		Artifacts: update.Artifacts{
-			Chat: update.Artifact{URL: opts.ChatURL, SHA256: opts.ChatSHA},
-			MCP:  update.Artifact{URL: opts.MCPURL, SHA256: opts.MCPSHA},
+			Chat: update.Artifact{URL: opts.ChatURL, SHA256: opts.ChatSHA, Requires: opts.ChatRequires, Image: opts.ChatImage},
+			MCP:  update.Artifact{URL: opts.MCPURL, SHA256: opts.MCPSHA, Requires: opts.MCPRequires, Image: opts.MCPImage},
 		},
-		Compatibility: update.Compatibility{PayramCore: update.Range{Min: opts.CoreMin, Max: opts.CoreMax}},
-		Revoked:       false,
+		Compatibility: update.Compatibility{
+			PayramCore: update.Range{Min: opts.CoreMin, Max: opts.CoreMax},
+			Agent:      update.Range{Min: trimVersionPrefix(opts.AgentMin), Max: trimVersionPrefix(opts.AgentMax)},
+		},
+		Revoked: false,
 	}
 
 	raw, err := json.MarshalIndent(manifest, "", "  ")
@@ -168,6 +192,20 @@ func Generate(opts Options) ([]byte, []byte, string, error) {
 	return raw, sig, pubB64, nil
 }
 
+// requires builds a Requires map from minimum versions, dropping empty entries.
+func requires(mins map[string]string) map[string]update.Range {
+	out := map[string]update.Range{}
+	for name, min := range mins {
+		if min != "" {
+			out[name] = update.Range{Min: trimVersionPrefix(min)}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
 func normalizeChannel(ch string) string {
 	ch = strings.ToLower(strings.TrimSpace(ch))
 	if ch == "" {